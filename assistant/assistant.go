@@ -2,9 +2,18 @@ package assistant
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
-
+	"sync"
+	"time"
+
+	"github.com/henomis/lingoose/budget"
+	"github.com/henomis/lingoose/groundedness"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/language"
+	"github.com/henomis/lingoose/logger"
 	obs "github.com/henomis/lingoose/observer"
 	"github.com/henomis/lingoose/thread"
 	"github.com/henomis/lingoose/types"
@@ -25,6 +34,15 @@ type observer interface {
 
 const (
 	DefaultMaxIterations = 3
+	// MetadataGroundedness is the answer metadata key holding the *groundedness.Report.
+	MetadataGroundedness = "groundedness"
+	// MetadataMemories marks the system message holding the memories retrieved for the turn.
+	MetadataMemories = "memories"
+)
+
+var (
+	ErrNothingToRegenerate = errors.New("nothing to regenerate")
+	ErrAborted             = errors.New("assistant run aborted")
 )
 
 type Assistant struct {
@@ -33,6 +51,34 @@ type Assistant struct {
 	thread        *thread.Thread
 	parameters    Parameters
 	maxIterations uint
+
+	groundednessChecker    GroundednessChecker
+	maxGroundednessRetries uint
+
+	memory Memory
+
+	budget *budget.Budget
+	// historyStart is the number of leading thread messages left out of the LLM
+	// requests to fit the token budget. The thread itself keeps them.
+	historyStart int
+
+	retrievalGate RetrievalGate
+
+	languageDetector LanguageDetector
+	languageTarget   language.Language
+	languageAction   LanguageAction
+
+	generateTitle bool
+	titleLLM      LLM
+	title         string
+	followUpLLM   LLM
+	followUpCount int
+
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	aborted bool
 }
 
 type LLM interface {
@@ -43,6 +89,15 @@ type RAG interface {
 	Retrieve(ctx context.Context, query string) ([]string, error)
 }
 
+type Memory interface {
+	Retrieve(ctx context.Context, query string) ([]string, error)
+	Observe(ctx context.Context, observation string) error
+}
+
+type GroundednessChecker interface {
+	Check(ctx context.Context, answer string, context []string) (*groundedness.Report, error)
+}
+
 func New(llm LLM) *Assistant {
 	assistant := &Assistant{
 		llm:    llm,
@@ -75,36 +130,128 @@ func (a *Assistant) WithParameters(parameters Parameters) *Assistant {
 	return a
 }
 
+// WithGroundednessCheck verifies the answers generated with RAG against the retrieved
+// context. The report is set in the MetadataGroundedness metadata of the answer; when
+// some claims are unsupported the answer is generated again with stricter instructions,
+// up to maxRetries times. Discarded answers are kept as alternative branches.
+func (a *Assistant) WithGroundednessCheck(checker GroundednessChecker, maxRetries uint) *Assistant {
+	a.groundednessChecker = checker
+	a.maxGroundednessRetries = maxRetries
+	return a
+}
+
+// WithMemory sets a long-term memory: the memories relevant to the user query are added
+// to the system prompt at each turn, and each exchange is stored as an observation.
+func (a *Assistant) WithMemory(memory Memory) *Assistant {
+	a.memory = memory
+	return a
+}
+
+// WithTokenBudget fits the RAG prompts in the budget: the retrieved context is truncated
+// and the oldest messages are left out of the request when the prompt would overflow
+// the model context window. The thread keeps the whole conversation.
+func (a *Assistant) WithTokenBudget(budget *budget.Budget) *Assistant {
+	a.budget = budget
+	return a
+}
+
+// WithLogger sets the logger of the runs, logged at debug level with the retrieval and
+// generation durations.
+func (a *Assistant) WithLogger(logger *slog.Logger) *Assistant {
+	a.logger = logger
+	return a
+}
+
 func (a *Assistant) Run(ctx context.Context) error {
 	if a.thread == nil {
 		return nil
 	}
 
+	return a.abortable(ctx, a.run)
+}
+
+func (a *Assistant) run(ctx context.Context) error {
+	// share query embeddings between the RAG retrieval and the LLM cache
+	ctx = index.ContextWithEmbeddingMemo(ctx)
+	a.historyStart = 0
+
 	ctx, spanAssistant, err := a.startObserveSpan(ctx, "assistant")
 	if err != nil {
 		return err
 	}
 
-	if a.rag != nil {
-		errGenerate := a.generateRAGMessage(ctx)
-		if errGenerate != nil {
-			return errGenerate
+	var query string
+	if a.memory != nil || a.languageDetector != nil {
+		query = strings.Join(a.thread.UserQuery(), "\n")
+	}
+
+	retrieve := a.rag != nil
+	if retrieve {
+		retrieve, err = a.needsRetrieval(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	var searchResults []string
+	if retrieve {
+		start := time.Now()
+		searchResults, err = a.generateRAGMessage(ctx)
+		if err != nil {
+			return err
 		}
+		logger.Or(a.logger).DebugContext(ctx, "assistant retrieval",
+			slog.Int("results", len(searchResults)),
+			slog.Int("history_start", a.historyStart),
+			slog.Duration("duration", time.Since(start)),
+		)
 	} else {
 		a.injectSystemMessage()
 	}
 
-	for i := 0; i < int(a.maxIterations); i++ {
-		err = a.runIteration(ctx, i)
+	if a.memory != nil {
+		err = a.injectMemories(ctx, query)
 		if err != nil {
 			return err
 		}
+	}
 
-		if a.thread.LastMessage().Role != thread.RoleTool {
-			break
+	start := time.Now()
+	err = a.runIterations(ctx, a.llm)
+	if err != nil {
+		return err
+	}
+	logger.Or(a.logger).DebugContext(ctx, "assistant generation",
+		slog.Int("messages", len(a.thread.Messages)),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	if a.groundednessChecker != nil && len(searchResults) > 0 {
+		err = a.checkGroundedness(ctx, searchResults)
+		if err != nil {
+			return err
 		}
 	}
 
+	if a.languageDetector != nil && query != "" {
+		err = a.enforceLanguage(ctx, query)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.memory != nil {
+		err = a.observe(ctx, query)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = a.suggest(ctx)
+	if err != nil {
+		return err
+	}
+
 	err = a.stopObserveSpan(ctx, spanAssistant)
 	if err != nil {
 		return err
@@ -113,13 +260,152 @@ func (a *Assistant) Run(ctx context.Context) error {
 	return nil
 }
 
-func (a *Assistant) runIteration(ctx context.Context, iteration int) error {
+// Regenerate discards the answer to the last user message, keeping it as an alternative
+// branch of the thread, and generates a new one. If llm is not nil it is used in place of
+// the assistant LLM, allowing to try again with different parameters (e.g. temperature or model).
+func (a *Assistant) Regenerate(ctx context.Context, llm LLM) error {
+	if a.thread == nil {
+		return nil
+	}
+
+	if llm == nil {
+		llm = a.llm
+	}
+
+	return a.abortable(ctx, func(ctx context.Context) error {
+		return a.regenerate(ctx, llm)
+	})
+}
+
+func (a *Assistant) regenerate(ctx context.Context, llm LLM) error {
+	userMessageIndex := a.lastUserMessageIndex()
+	if userMessageIndex < 0 || userMessageIndex == len(a.thread.Messages)-1 {
+		return ErrNothingToRegenerate
+	}
+
+	ctx, spanAssistant, err := a.startObserveSpan(ctx, "assistant-regenerate")
+	if err != nil {
+		return err
+	}
+
+	a.thread.Fork(userMessageIndex + 1)
+
+	err = a.runIterations(ctx, llm)
+	if err != nil {
+		return err
+	}
+
+	err = a.suggest(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.stopObserveSpan(ctx, spanAssistant)
+}
+
+// EditLastUserMessage replaces the contents of the last user message and runs the assistant
+// again. The previous user message and the following answers are kept as an alternative
+// branch of the thread.
+func (a *Assistant) EditLastUserMessage(ctx context.Context, contents ...*thread.Content) error {
+	if a.thread == nil {
+		return nil
+	}
+
+	userMessageIndex := a.lastUserMessageIndex()
+	if userMessageIndex < 0 {
+		return ErrNothingToRegenerate
+	}
+
+	return a.abortable(ctx, func(ctx context.Context) error {
+		a.thread.Fork(userMessageIndex)
+		a.thread.AddMessage(thread.NewUserMessage())
+		a.thread.LastMessage().Contents = contents
+
+		return a.run(ctx)
+	})
+}
+
+// Abort cancels the in-flight run, if any. Streams and pending tool calls are cancelled
+// through the run context and the thread is restored to its state before the run.
+func (a *Assistant) Abort() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cancel != nil {
+		a.aborted = true
+		a.cancel()
+	}
+}
+
+func (a *Assistant) abortable(ctx context.Context, fn func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	a.mu.Lock()
+	a.cancel = cancel
+	a.aborted = false
+	a.mu.Unlock()
+
+	messages := append([]*thread.Message{}, a.thread.Messages...)
+	branches := a.thread.Branches
+
+	err := fn(ctx)
+
+	a.mu.Lock()
+	aborted := a.aborted
+	a.cancel = nil
+	a.mu.Unlock()
+	cancel()
+
+	if aborted {
+		a.thread.Messages = messages
+		a.thread.Branches = branches
+		return ErrAborted
+	}
+
+	return err
+}
+
+// Alternatives returns the previous answers to the last user message.
+func (a *Assistant) Alternatives() []*thread.Branch {
+	if a.thread == nil {
+		return nil
+	}
+
+	return a.thread.BranchesAt(a.lastUserMessageIndex() + 1)
+}
+
+func (a *Assistant) lastUserMessageIndex() int {
+	for i := len(a.thread.Messages) - 1; i >= 0; i-- {
+		if a.thread.Messages[i].Role == thread.RoleUser {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (a *Assistant) runIterations(ctx context.Context, llm LLM) error {
+	for i := 0; i < int(a.maxIterations); i++ {
+		err := a.runIteration(ctx, llm, i)
+		if err != nil {
+			return err
+		}
+
+		if a.thread.LastMessage().Role != thread.RoleTool {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (a *Assistant) runIteration(ctx context.Context, llm LLM, iteration int) error {
 	ctx, spanIteration, err := a.startObserveSpan(ctx, fmt.Sprintf("iteration-%d", iteration+1))
 	if err != nil {
 		return err
 	}
 
-	err = a.llm.Generate(ctx, a.thread)
+	err = a.generate(ctx, llm)
 	if err != nil {
 		return err
 	}
@@ -132,6 +418,22 @@ func (a *Assistant) runIteration(ctx context.Context, iteration int) error {
 	return nil
 }
 
+// generate sends the thread to the LLM without the messages left out by the token
+// budget, then puts them back in front of the resulting messages.
+func (a *Assistant) generate(ctx context.Context, llm LLM) error {
+	if a.historyStart <= 0 || a.historyStart > len(a.thread.Messages) {
+		return llm.Generate(ctx, a.thread)
+	}
+
+	history := a.thread.Messages[:a.historyStart:a.historyStart]
+	request := thread.New().AddMessages(a.thread.Messages[a.historyStart:]...)
+
+	err := llm.Generate(ctx, request)
+	a.thread.Messages = append(history, request.Messages...)
+
+	return err
+}
+
 func (a *Assistant) RunWithThread(ctx context.Context, thread *thread.Thread) error {
 	a.thread = thread
 	return a.Run(ctx)
@@ -141,10 +443,10 @@ func (a *Assistant) Thread() *thread.Thread {
 	return a.thread
 }
 
-func (a *Assistant) generateRAGMessage(ctx context.Context) error {
+func (a *Assistant) generateRAGMessage(ctx context.Context) ([]string, error) {
 	lastMessage := a.thread.LastMessage()
 	if lastMessage.Role != thread.RoleUser || len(lastMessage.Contents) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	query := strings.Join(a.thread.UserQuery(), "\n")
@@ -152,21 +454,36 @@ func (a *Assistant) generateRAGMessage(ctx context.Context) error {
 
 	searchResults, err := a.rag.Retrieve(ctx, query)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	system := thread.NewTextContent(
+		systemPrompt,
+	).Format(
+		types.M{
+			"assistantName":      a.parameters.AssistantName,
+			"assistantIdentity":  a.parameters.AssistantIdentity,
+			"assistantScope":     a.parameters.AssistantScope,
+			"companyName":        a.parameters.CompanyName,
+			"companyDescription": a.parameters.CompanyDescription,
+		},
+	).AsString()
+
+	if a.budget != nil {
+		question := thread.NewTextContent(baseRAGPrompt).Format(types.M{"question": query}).AsString()
+
+		allocation, errBudget := a.budget.Allocate(system, question, a.thread.Messages, searchResults)
+		if errBudget != nil {
+			return nil, errBudget
+		}
+
+		system = allocation.System
+		searchResults = allocation.Context
+		a.historyStart = len(a.thread.Messages) - len(allocation.History)
 	}
 
 	a.thread.AddMessage(thread.NewSystemMessage().AddContent(
-		thread.NewTextContent(
-			systemPrompt,
-		).Format(
-			types.M{
-				"assistantName":      a.parameters.AssistantName,
-				"assistantIdentity":  a.parameters.AssistantIdentity,
-				"assistantScope":     a.parameters.AssistantScope,
-				"companyName":        a.parameters.CompanyName,
-				"companyDescription": a.parameters.CompanyDescription,
-			},
-		),
+		thread.NewTextContent(system),
 	)).AddMessage(thread.NewUserMessage().AddContent(
 		thread.NewTextContent(
 			baseRAGPrompt,
@@ -178,7 +495,52 @@ func (a *Assistant) generateRAGMessage(ctx context.Context) error {
 		),
 	))
 
-	return nil
+	return searchResults, nil
+}
+
+func (a *Assistant) checkGroundedness(ctx context.Context, searchResults []string) error {
+	for retry := uint(0); ; retry++ {
+		answer := a.thread.LastMessage()
+		if answer.Role != thread.RoleAssistant {
+			return nil
+		}
+
+		var text string
+		for _, content := range answer.Contents {
+			if content.Type == thread.ContentTypeText {
+				text += content.AsString()
+			}
+		}
+
+		report, err := a.groundednessChecker.Check(ctx, text, searchResults)
+		if err != nil {
+			return err
+		}
+		answer.AddMetadata(MetadataGroundedness, report)
+
+		if report.Grounded() || retry >= a.maxGroundednessRetries {
+			return nil
+		}
+
+		var unsupported []string
+		for _, claim := range report.Unsupported() {
+			unsupported = append(unsupported, claim.Sentence)
+		}
+
+		a.thread.Fork(a.lastUserMessageIndex() + 1)
+		a.thread.AddMessage(thread.NewSystemMessage().AddContent(
+			thread.NewTextContent(groundedRetryPrompt).Format(
+				types.M{
+					"unsupported": unsupported,
+				},
+			),
+		))
+
+		err = a.runIterations(ctx, a.llm)
+		if err != nil {
+			return err
+		}
+	}
 }
 
 func (a *Assistant) WithMaxIterations(maxIterations uint) *Assistant {
@@ -246,3 +608,66 @@ func (a *Assistant) injectSystemMessage() {
 
 	a.thread.Messages = append([]*thread.Message{systemMessage}, a.thread.Messages...)
 }
+
+// injectMemories sets the memories relevant to the query in a system message placed after
+// the main system prompt. The message is reused across turns, so memories don't pile up.
+func (a *Assistant) injectMemories(ctx context.Context, query string) error {
+	if query == "" {
+		return nil
+	}
+
+	memories, err := a.memory.Retrieve(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	var memoryMessage *thread.Message
+	for _, message := range a.thread.Messages {
+		if _, ok := message.Metadata[MetadataMemories]; ok {
+			memoryMessage = message
+			break
+		}
+	}
+
+	if memoryMessage == nil {
+		if len(memories) == 0 {
+			return nil
+		}
+
+		memoryMessage = thread.NewSystemMessage().AddMetadata(MetadataMemories, true)
+		position := 0
+		if len(a.thread.Messages) > 0 && a.thread.Messages[0].Role == thread.RoleSystem {
+			position = 1
+		}
+		a.thread.Messages = append(
+			a.thread.Messages[:position],
+			append([]*thread.Message{memoryMessage}, a.thread.Messages[position:]...)...,
+		)
+	}
+
+	memoryMessage.Contents = []*thread.Content{
+		thread.NewTextContent(memoryPrompt).Format(
+			types.M{
+				"memories": memories,
+			},
+		),
+	}
+
+	return nil
+}
+
+func (a *Assistant) observe(ctx context.Context, query string) error {
+	answer := a.thread.LastMessage()
+	if query == "" || answer.Role != thread.RoleAssistant {
+		return nil
+	}
+
+	var text string
+	for _, content := range answer.Contents {
+		if content.Type == thread.ContentTypeText {
+			text += content.AsString()
+		}
+	}
+
+	return a.memory.Observe(ctx, "User: "+query+"\nAssistant: "+text)
+}
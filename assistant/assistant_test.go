@@ -0,0 +1,337 @@
+package assistant
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/henomis/lingoose/budget"
+	"github.com/henomis/lingoose/groundedness"
+	"github.com/henomis/lingoose/language"
+	"github.com/henomis/lingoose/thread"
+)
+
+// scriptedLLM answers with the given answers in turn, recording the threads it receives.
+type scriptedLLM struct {
+	answers  []string
+	requests [][]*thread.Message
+}
+
+func (s *scriptedLLM) Generate(_ context.Context, t *thread.Thread) error {
+	s.requests = append(s.requests, append([]*thread.Message{}, t.Messages...))
+
+	answer := s.answers[0]
+	if len(s.answers) > 1 {
+		s.answers = s.answers[1:]
+	}
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent(answer)))
+
+	return nil
+}
+
+type staticRAG []string
+
+func (s staticRAG) Retrieve(context.Context, string) ([]string, error) {
+	return s, nil
+}
+
+// countingRAG returns the results, recording the queries it receives.
+type countingRAG struct {
+	results []string
+	queries []string
+}
+
+func (r *countingRAG) Retrieve(_ context.Context, query string) ([]string, error) {
+	r.queries = append(r.queries, query)
+	return r.results, nil
+}
+
+// blockingLLM adds a partial answer and blocks until the generation is cancelled.
+type blockingLLM struct {
+	started chan struct{}
+}
+
+func (b *blockingLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("partial")))
+	close(b.started)
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+// phraseChecker marks the answers containing the phrase as unsupported by the context.
+type phraseChecker string
+
+func (p phraseChecker) Check(_ context.Context, answer string, _ []string) (*groundedness.Report, error) {
+	return &groundedness.Report{Claims: []groundedness.Claim{
+		{Sentence: answer, Supported: !strings.Contains(answer, string(p))},
+	}}, nil
+}
+
+func textMessage(role thread.Role, text string) *thread.Message {
+	return &thread.Message{Role: role, Contents: []*thread.Content{thread.NewTextContent(text)}}
+}
+
+func TestAssistant_RunTokenBudgetKeepsThread(t *testing.T) {
+	history := []*thread.Message{
+		textMessage(thread.RoleUser, strings.Repeat("old question ", 1000)),
+		textMessage(thread.RoleAssistant, strings.Repeat("old answer ", 1000)),
+		textMessage(thread.RoleUser, "recent question"),
+		textMessage(thread.RoleAssistant, "recent answer"),
+	}
+
+	th := thread.New().AddMessages(history...).AddMessage(textMessage(thread.RoleUser, "new question"))
+	llm := &scriptedLLM{answers: []string{"new answer"}}
+
+	err := New(llm).WithThread(th).WithRAG(staticRAG{"some context"}).
+		WithTokenBudget(budget.New(2000).WithOutputTokens(100)).
+		Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := llm.requests[0]
+	if request[0] != history[2] {
+		t.Fatalf("expected the request to start with the recent messages, got %v", request[0])
+	}
+
+	for i, message := range history {
+		if th.Messages[i] != message {
+			t.Fatalf("message %d of the history was removed from the thread", i)
+		}
+	}
+	if th.LastMessage().Contents[0].AsString() != "new answer" {
+		t.Fatalf("unexpected last message %s", th)
+	}
+}
+
+func TestAssistant_AbortRestoresThread(t *testing.T) {
+	question := textMessage(thread.RoleUser, "question")
+	th := thread.New().AddMessage(question)
+	llm := &blockingLLM{started: make(chan struct{})}
+	a := New(llm).WithThread(th).WithRAG(staticRAG{"some context"})
+
+	go func() {
+		<-llm.started
+		a.Abort()
+	}()
+
+	err := a.Run(context.Background())
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("expected ErrAborted, got %v", err)
+	}
+
+	if len(th.Messages) != 1 || th.Messages[0] != question || len(th.Branches) != 0 {
+		t.Fatalf("expected the thread to be restored, got %s", th)
+	}
+}
+
+func TestAssistant_EditLastUserMessage(t *testing.T) {
+	th := thread.New().AddMessage(textMessage(thread.RoleUser, "first question"))
+	llm := &scriptedLLM{answers: []string{"first answer", "second answer"}}
+	a := New(llm).WithThread(th)
+
+	err := a.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.EditLastUserMessage(context.Background(), thread.NewTextContent("second question"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// system prompt, edited question and new answer
+	if len(th.Messages) != 3 || th.Messages[0].Role != thread.RoleSystem {
+		t.Fatalf("unexpected thread %s", th)
+	}
+	if th.Messages[1].Contents[0].AsString() != "second question" || th.LastMessage().Contents[0].AsString() != "second answer" {
+		t.Fatalf("unexpected thread %s", th)
+	}
+
+	branches := th.BranchesAt(1)
+	if len(branches) != 1 || len(branches[0].Messages) != 2 ||
+		branches[0].Messages[0].Contents[0].AsString() != "first question" ||
+		branches[0].Messages[1].Contents[0].AsString() != "first answer" {
+		t.Fatalf("expected the previous question and answer as a branch, got %v", th.Branches)
+	}
+}
+
+func TestAssistant_RunRetrievalGate(t *testing.T) {
+	rag := &countingRAG{results: []string{"some context"}}
+	needsRetrieval := false
+	gate := RetrievalGateFunc(func(_ context.Context, query string, history []*thread.Message) (bool, error) {
+		if query != "hello" || len(history) != 0 {
+			t.Fatalf("unexpected gate input %q %v", query, history)
+		}
+		return needsRetrieval, nil
+	})
+
+	llm := &scriptedLLM{answers: []string{"answer"}}
+	th := thread.New().AddMessage(textMessage(thread.RoleUser, "hello"))
+
+	err := New(llm).WithThread(th).WithRAG(rag).WithRetrievalGate(gate).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rag.queries) != 0 {
+		t.Fatalf("expected the retrieval to be skipped, got %v", rag.queries)
+	}
+	request := llm.requests[0]
+	if len(request) != 2 || request[0].Role != thread.RoleSystem || request[1].Contents[0].AsString() != "hello" {
+		t.Fatalf("expected the system prompt and the plain query, got %v", request)
+	}
+
+	needsRetrieval = true
+	th = thread.New().AddMessage(textMessage(thread.RoleUser, "hello"))
+
+	err = New(llm).WithThread(th).WithRAG(rag).WithRetrievalGate(gate).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rag.queries) != 1 || rag.queries[0] != "hello" {
+		t.Fatalf("expected the query to be retrieved, got %v", rag.queries)
+	}
+	if request = llm.requests[1]; !strings.Contains(request[len(request)-1].Contents[0].AsString(), "some context") {
+		t.Fatalf("expected the retrieved context in the prompt, got %v", request)
+	}
+}
+
+func TestAssistant_RunEnforcesLanguage(t *testing.T) {
+	const (
+		question       = "Che tempo fa a Roma oggi? Vorrei sapere se è una bella giornata per una passeggiata."
+		englishAnswer  = "The weather in Rome is sunny and the temperature is twenty degrees."
+		italianAnswer  = "Il tempo a Roma è soleggiato e la temperatura è di venti gradi."
+		expectedBranch = 2
+	)
+
+	for _, action := range []LanguageAction{LanguageReprompt, LanguageTranslate} {
+		t.Run(string(action), func(t *testing.T) {
+			th := thread.New().AddMessage(textMessage(thread.RoleUser, question))
+			llm := &scriptedLLM{answers: []string{englishAnswer, italianAnswer}}
+
+			err := New(llm).WithThread(th).
+				WithLanguage(language.NewStopwordDetector(), language.Unknown, action).
+				Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			answer := th.LastMessage()
+			if answer.Contents[0].AsString() != italianAnswer || answer.Metadata[MetadataLanguage] != language.Italian {
+				t.Fatalf("expected the Italian answer, got %s", th)
+			}
+
+			branches := th.BranchesAt(expectedBranch)
+			if len(branches) != 1 || branches[0].Messages[0].Contents[0].AsString() != englishAnswer ||
+				branches[0].Messages[0].Metadata[MetadataLanguage] != language.English {
+				t.Fatalf("expected the English answer as a branch, got %v", th.Branches)
+			}
+
+			if action == LanguageReprompt {
+				retry := llm.requests[1]
+				if prompt := retry[len(retry)-1]; prompt.Role != thread.RoleSystem ||
+					!strings.Contains(prompt.Contents[0].AsString(), language.Italian.Name()) {
+					t.Fatalf("expected the language retry prompt, got %v", prompt)
+				}
+			}
+		})
+	}
+}
+
+func TestAssistant_RunGroundedness(t *testing.T) {
+	const (
+		ungrounded = "The store opens at 7am, invented."
+		grounded   = "The store opens at 9am."
+	)
+
+	t.Run("flagged", func(t *testing.T) {
+		th := thread.New().AddMessage(textMessage(thread.RoleUser, "when does the store open?"))
+		llm := &scriptedLLM{answers: []string{ungrounded}}
+
+		err := New(llm).WithThread(th).WithRAG(staticRAG{"The store opens at 9am."}).
+			WithGroundednessCheck(phraseChecker("invented"), 0).
+			Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report, ok := th.LastMessage().Metadata[MetadataGroundedness].(*groundedness.Report)
+		if !ok || report.Grounded() || len(th.Branches) != 0 {
+			t.Fatalf("expected the answer to be flagged without retries, got %s", th)
+		}
+	})
+
+	t.Run("retried", func(t *testing.T) {
+		th := thread.New().AddMessage(textMessage(thread.RoleUser, "when does the store open?"))
+		llm := &scriptedLLM{answers: []string{ungrounded, grounded}}
+		a := New(llm).WithThread(th).WithRAG(staticRAG{"The store opens at 9am."}).
+			WithGroundednessCheck(phraseChecker("invented"), 1)
+
+		err := a.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		answer := th.LastMessage()
+		report, ok := answer.Metadata[MetadataGroundedness].(*groundedness.Report)
+		if answer.Contents[0].AsString() != grounded || !ok || !report.Grounded() {
+			t.Fatalf("expected the grounded answer, got %s", th)
+		}
+
+		retry := llm.requests[1]
+		if prompt := retry[len(retry)-1]; prompt.Role != thread.RoleSystem ||
+			!strings.Contains(prompt.Contents[0].AsString(), ungrounded) {
+			t.Fatalf("expected the unsupported claims in the retry prompt, got %v", prompt)
+		}
+
+		alternatives := a.Alternatives()
+		if len(alternatives) != 1 || alternatives[0].Messages[0].Contents[0].AsString() != ungrounded {
+			t.Fatalf("expected the ungrounded answer as an alternative, got %v", alternatives)
+		}
+	})
+}
+
+func TestAssistant_RunSuggestions(t *testing.T) {
+	llm := &scriptedLLM{answers: []string{"Paris is the capital of France.", "About 2 million people."}}
+	cheapLLM := &scriptedLLM{answers: []string{
+		"\"Capital of France.\"",
+		"1. What is the population of Paris?\n2) What is there to see?\n\n- How old is Paris?\n4. Extra question?",
+		"- Which is the largest city?\n- Is it growing?",
+	}}
+	th := thread.New().AddMessage(textMessage(thread.RoleUser, "What is the capital of France?"))
+
+	a := New(llm).WithThread(th).WithTitleGeneration(cheapLLM).WithFollowUpSuggestions(cheapLLM, 3)
+	err := a.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Title() != "Capital of France" {
+		t.Errorf("unexpected title %q", a.Title())
+	}
+	followUps := []string{"What is the population of Paris?", "What is there to see?", "How old is Paris?"}
+	if got := a.FollowUps(); strings.Join(got, "|") != strings.Join(followUps, "|") {
+		t.Errorf("unexpected follow-ups %q", got)
+	}
+	if titleRequest := cheapLLM.requests[0][0].Contents[0].AsString(); !strings.Contains(titleRequest, "capital of France?") {
+		t.Errorf("expected the first exchange in the title prompt, got %q", titleRequest)
+	}
+
+	th.AddMessage(textMessage(thread.RoleUser, "How many people live there?"))
+	err = a.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the title is generated once, the follow-ups after every answer
+	if len(cheapLLM.requests) != 3 || a.Title() != "Capital of France" {
+		t.Errorf("expected the title to be kept, got %q after %d requests", a.Title(), len(cheapLLM.requests))
+	}
+	if got := th.LastMessage().Metadata[MetadataFollowUps]; len(got.([]string)) != 2 {
+		t.Errorf("unexpected follow-ups %q", got)
+	}
+}
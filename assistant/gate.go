@@ -0,0 +1,99 @@
+package assistant
+
+import (
+	"context"
+	"strings"
+
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultGateHistoryMessages = 6
+)
+
+// RetrievalGate decides whether the user query needs the RAG retrieval. Greetings,
+// chit-chat and follow-ups answerable from the conversation history don't, and skipping
+// the search saves its latency and cost.
+type RetrievalGate interface {
+	NeedsRetrieval(ctx context.Context, query string, history []*thread.Message) (bool, error)
+}
+
+type RetrievalGateFunc func(ctx context.Context, query string, history []*thread.Message) (bool, error)
+
+func (f RetrievalGateFunc) NeedsRetrieval(ctx context.Context, query string, history []*thread.Message) (bool, error) {
+	return f(ctx, query, history)
+}
+
+// WithRetrievalGate asks the gate whether the user query needs the RAG retrieval before
+// running it. When it doesn't, the query is answered with the system prompt and the
+// conversation history only.
+func (a *Assistant) WithRetrievalGate(gate RetrievalGate) *Assistant {
+	a.retrievalGate = gate
+	return a
+}
+
+func (a *Assistant) needsRetrieval(ctx context.Context) (bool, error) {
+	if a.retrievalGate == nil {
+		return true, nil
+	}
+
+	query := strings.Join(a.thread.UserQuery(), "\n")
+	if query == "" {
+		return true, nil
+	}
+
+	// the history ends before the user messages of the current turn
+	start := len(a.thread.Messages)
+	for start > 0 && a.thread.Messages[start-1].Role == thread.RoleUser {
+		start--
+	}
+
+	return a.retrievalGate.NeedsRetrieval(ctx, query, a.thread.Messages[:start])
+}
+
+// LLMRetrievalGate classifies the user query with an LLM, looking at the last messages
+// of the conversation. The query is retrieved when the LLM answer is not understood.
+type LLMRetrievalGate struct {
+	llm             LLM
+	historyMessages int
+}
+
+func NewLLMRetrievalGate(llm LLM) *LLMRetrievalGate {
+	return &LLMRetrievalGate{
+		llm:             llm,
+		historyMessages: defaultGateHistoryMessages,
+	}
+}
+
+// WithHistoryMessages sets how many of the last user and assistant messages are shown to
+// the LLM, 6 by default.
+func (g *LLMRetrievalGate) WithHistoryMessages(historyMessages int) *LLMRetrievalGate {
+	g.historyMessages = historyMessages
+	return g
+}
+
+func (g *LLMRetrievalGate) NeedsRetrieval(ctx context.Context, query string, history []*thread.Message) (bool, error) {
+	conversation := conversationOf(history)
+	if len(conversation) > g.historyMessages {
+		conversation = conversation[len(conversation)-g.historyMessages:]
+	}
+
+	t := thread.New().AddMessage(thread.NewUserMessage().AddContent(
+		thread.NewTextContent(retrievalGatePrompt).Format(
+			types.M{
+				"conversation": conversation,
+				"query":        query,
+			},
+		),
+	))
+
+	err := g.llm.Generate(ctx, t)
+	if err != nil {
+		return false, err
+	}
+
+	answer := strings.ToUpper(strings.TrimSpace(messageText(t.LastMessage())))
+
+	return !strings.HasPrefix(answer, "NO"), nil
+}
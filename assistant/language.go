@@ -0,0 +1,115 @@
+package assistant
+
+import (
+	"context"
+
+	"github.com/henomis/lingoose/language"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	// MetadataLanguage is the answer metadata key holding the detected language.Language.
+	MetadataLanguage = "language"
+)
+
+// LanguageAction is what the assistant does when the answer is in the wrong language.
+type LanguageAction string
+
+const (
+	// LanguageReprompt generates the answer again, asking to use the expected language.
+	LanguageReprompt LanguageAction = "reprompt"
+	// LanguageTranslate asks the LLM to translate the answer.
+	LanguageTranslate LanguageAction = "translate"
+)
+
+type LanguageDetector interface {
+	Detect(ctx context.Context, text string) (language.Language, error)
+}
+
+// WithLanguage checks the language of the answers: when it doesn't match the target, or
+// the language of the user query if target is language.Unknown, the answer is generated
+// again or translated according to action. The discarded answer is kept as an
+// alternative branch and the detected language is set in the MetadataLanguage metadata.
+func (a *Assistant) WithLanguage(detector LanguageDetector, target language.Language, action LanguageAction) *Assistant {
+	a.languageDetector = detector
+	a.languageTarget = target
+	a.languageAction = action
+	return a
+}
+
+func (a *Assistant) enforceLanguage(ctx context.Context, query string) error {
+	answer := a.thread.LastMessage()
+	if answer.Role != thread.RoleAssistant {
+		return nil
+	}
+
+	target := a.languageTarget
+	if target == language.Unknown {
+		var err error
+		target, err = a.languageDetector.Detect(ctx, query)
+		if err != nil || target == language.Unknown {
+			return err
+		}
+	}
+
+	detected, err := a.languageDetector.Detect(ctx, messageText(answer))
+	if err != nil {
+		return err
+	}
+	answer.AddMetadata(MetadataLanguage, detected)
+
+	if detected == language.Unknown || detected == target {
+		return nil
+	}
+
+	if a.languageAction == LanguageTranslate {
+		translation, errTranslate := language.Translate(ctx, a.llm, messageText(answer), target)
+		if errTranslate != nil {
+			return errTranslate
+		}
+
+		a.thread.Fork(a.lastUserMessageIndex() + 1)
+		a.thread.AddMessage(thread.NewAssistantMessage().AddContent(
+			thread.NewTextContent(translation),
+		).AddMetadata(MetadataLanguage, target))
+
+		return nil
+	}
+
+	a.thread.Fork(a.lastUserMessageIndex() + 1)
+	a.thread.AddMessage(thread.NewSystemMessage().AddContent(
+		thread.NewTextContent(languageRetryPrompt).Format(
+			types.M{
+				"language": target.Name(),
+			},
+		),
+	))
+
+	err = a.runIterations(ctx, a.llm)
+	if err != nil {
+		return err
+	}
+
+	answer = a.thread.LastMessage()
+	if answer.Role == thread.RoleAssistant {
+		detected, err = a.languageDetector.Detect(ctx, messageText(answer))
+		if err != nil {
+			return err
+		}
+		answer.AddMetadata(MetadataLanguage, detected)
+	}
+
+	return nil
+}
+
+func messageText(message *thread.Message) string {
+	var text string
+	for _, content := range message.Contents {
+		if content.Type == thread.ContentTypeText {
+			text += content.AsString()
+		}
+	}
+
+	return text
+}
@@ -4,6 +4,17 @@ const (
 	//nolint:lll
 	baseRAGPrompt = "Use the following pieces of retrieved context to answer the question.\n\nQuestion: {{.question}}\nContext:\n{{range .results}}{{.}}\n\n{{end}}"
 	//nolint:lll
+	groundedRetryPrompt = "Your previous answer contained statements not supported by the retrieved context:\n{{range .unsupported}}- {{.}}\n{{end}}Answer again using only information explicitly stated in the context. If the context doesn't contain the answer, say that you don't know."
+	//nolint:lll
+	retrievalGatePrompt = "Decide whether answering the last user message requires searching the knowledge base. Answer NO only for greetings, chit-chat, thanks and follow-ups fully answered by the conversation; otherwise answer YES.\n\n{{if .conversation}}Conversation:\n{{range .conversation}}{{.role}}: {{.text}}\n{{end}}\n{{end}}Last user message: {{.query}}\n\nAnswer only YES or NO."
+	languageRetryPrompt = "Your previous answer was not written in {{.language}}. Answer again in {{.language}}."
+	//nolint:lll
+	memoryPrompt = "{{if .memories}}What you remember from past conversations with the user:\n{{range .memories}}- {{.}}\n{{end}}Use these memories only when relevant.{{else}}You don't remember anything relevant from past conversations.{{end}}"
+	//nolint:lll
+	titlePrompt = "Write a short title, at most six words, for the following conversation. Answer only with the title, without quotes.\n\n{{range .conversation}}{{.role}}: {{.text}}\n{{end}}"
+	//nolint:lll
+	followUpsPrompt = "Suggest {{.count}} short follow-up questions the user could ask next in the following conversation, written as the user would ask them. Answer only with the questions, one per line.\n\n{{range .conversation}}{{.role}}: {{.text}}\n{{end}}"
+	//nolint:lll
 	systemPrompt = "{{if ne .assistantName \"\"}}You name is {{.assistantName}}, {{end}}{{if ne .assistantIdentity \"\"}}you are {{.assistantIdentity}}.{{end}} {{if ne .companyName \"\" }}at {{.companyName}}{{end}}{{if ne .companyDescription \"\" }}, {{.companyDescription}}.{{end}} Your task is to assist humans {{.assistantScope}}."
 
 	defaultAssistantName      = "AI assistant"
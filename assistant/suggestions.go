@@ -0,0 +1,199 @@
+package assistant
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	// MetadataFollowUps is the answer metadata key holding the suggested follow-up
+	// questions, as a []string.
+	MetadataFollowUps = "follow_ups"
+
+	DefaultFollowUps = 3
+
+	defaultFollowUpHistoryMessages = 6
+	maxTitleLength                 = 80
+)
+
+// listMarker matches the bullet or the number starting a list item.
+var listMarker = regexp.MustCompile(`^([-*•]|\d+[.)])\s*`)
+
+// WithTitleGeneration generates a short title of the session with llm after the first
+// exchange, usually a cheaper model than the assistant one. If llm is nil the assistant
+// LLM is used. The title is returned by Title.
+func (a *Assistant) WithTitleGeneration(llm LLM) *Assistant {
+	a.generateTitle = true
+	a.titleLLM = llm
+	return a
+}
+
+// WithTitle sets the title of a restored session, so that it isn't generated again.
+func (a *Assistant) WithTitle(title string) *Assistant {
+	a.title = title
+	return a
+}
+
+// Title returns the title of the session, empty until it is generated.
+func (a *Assistant) Title() string {
+	return a.title
+}
+
+// WithFollowUpSuggestions generates count follow-up questions the user could ask after
+// each answer with llm, usually a cheaper model than the assistant one. If llm is nil
+// the assistant LLM is used, if count is not positive DefaultFollowUps are generated.
+// The questions are set in the MetadataFollowUps metadata of the answer and returned by
+// FollowUps.
+func (a *Assistant) WithFollowUpSuggestions(llm LLM, count int) *Assistant {
+	if count <= 0 {
+		count = DefaultFollowUps
+	}
+	a.followUpCount = count
+	a.followUpLLM = llm
+	return a
+}
+
+// FollowUps returns the follow-up questions suggested for the last answer.
+func (a *Assistant) FollowUps() []string {
+	if a.thread == nil || len(a.thread.Messages) == 0 {
+		return nil
+	}
+
+	answer := a.thread.LastMessage()
+	if answer.Role != thread.RoleAssistant {
+		return nil
+	}
+
+	followUps, _ := answer.Metadata[MetadataFollowUps].([]string)
+
+	return followUps
+}
+
+func (a *Assistant) suggest(ctx context.Context) error {
+	answer := a.thread.LastMessage()
+	if answer.Role != thread.RoleAssistant {
+		return nil
+	}
+
+	if a.generateTitle && a.title == "" {
+		title, err := GenerateTitle(ctx, orLLM(a.titleLLM, a.llm), a.thread.Messages)
+		if err != nil {
+			return err
+		}
+		a.title = title
+	}
+
+	if a.followUpCount > 0 {
+		followUps, err := SuggestFollowUps(ctx, orLLM(a.followUpLLM, a.llm), a.thread.Messages, a.followUpCount)
+		if err != nil {
+			return err
+		}
+		answer.AddMetadata(MetadataFollowUps, followUps)
+	}
+
+	return nil
+}
+
+// GenerateTitle asks the LLM a short title for the conversation, based on its first
+// exchange.
+func GenerateTitle(ctx context.Context, llm LLM, messages []*thread.Message) (string, error) {
+	conversation := conversationOf(messages)
+	for i, message := range conversation {
+		if message["role"] == thread.RoleAssistant {
+			conversation = conversation[:i+1]
+			break
+		}
+	}
+
+	t := thread.New().AddMessage(thread.NewUserMessage().AddContent(
+		thread.NewTextContent(titlePrompt).Format(
+			types.M{
+				"conversation": conversation,
+			},
+		),
+	))
+
+	err := llm.Generate(ctx, t)
+	if err != nil {
+		return "", err
+	}
+
+	title := strings.TrimSpace(messageText(t.LastMessage()))
+	title, _, _ = strings.Cut(title, "\n")
+	title = strings.TrimRight(strings.Trim(title, "\"'` "), ".")
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		// cut at the last word fitting the length
+		title = string(runes[:maxTitleLength])
+		if end := strings.LastIndex(title, " "); end > 0 {
+			title = strings.TrimSpace(title[:end])
+		}
+	}
+
+	return title, nil
+}
+
+// SuggestFollowUps asks the LLM count follow-up questions the user could ask next,
+// based on the last messages of the conversation.
+func SuggestFollowUps(ctx context.Context, llm LLM, messages []*thread.Message, count int) ([]string, error) {
+	conversation := conversationOf(messages)
+	if len(conversation) > defaultFollowUpHistoryMessages {
+		conversation = conversation[len(conversation)-defaultFollowUpHistoryMessages:]
+	}
+
+	t := thread.New().AddMessage(thread.NewUserMessage().AddContent(
+		thread.NewTextContent(followUpsPrompt).Format(
+			types.M{
+				"conversation": conversation,
+				"count":        count,
+			},
+		),
+	))
+
+	err := llm.Generate(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	var followUps []string
+	for _, line := range strings.Split(messageText(t.LastMessage()), "\n") {
+		line = strings.TrimSpace(listMarker.ReplaceAllString(strings.TrimSpace(line), ""))
+		if line == "" {
+			continue
+		}
+
+		followUps = append(followUps, line)
+		if len(followUps) == count {
+			break
+		}
+	}
+
+	return followUps, nil
+}
+
+// conversationOf returns the role and the text of the user and assistant messages.
+func conversationOf(messages []*thread.Message) []types.M {
+	var conversation []types.M
+	for _, message := range messages {
+		if message.Role != thread.RoleUser && message.Role != thread.RoleAssistant {
+			continue
+		}
+
+		if text := messageText(message); text != "" {
+			conversation = append(conversation, types.M{"role": message.Role, "text": text})
+		}
+	}
+
+	return conversation
+}
+
+func orLLM(llm LLM, fallback LLM) LLM {
+	if llm != nil {
+		return llm
+	}
+
+	return fallback
+}
@@ -0,0 +1,247 @@
+// Package budget divides the context window of a model among the sections of a RAG
+// prompt (system prompt, conversation history, retrieved context and expected output),
+// truncating each section so that the prompt never overflows as conversations grow.
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/henomis/lingoose/ratelimit"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/tokenizer"
+)
+
+const (
+	defaultOutputTokens = 1024
+	// messageOverheadTokens is the rough number of tokens used by the chat format for
+	// each message.
+	messageOverheadTokens = 4
+)
+
+var ErrBudgetExceeded = errors.New("prompt exceeds the token budget")
+
+type Section string
+
+const (
+	SectionSystem  Section = "system"
+	SectionOutput  Section = "output"
+	SectionContext Section = "context"
+	SectionHistory Section = "history"
+)
+
+// TokenCounter returns the number of tokens of the text.
+type TokenCounter func(string) int
+
+// Budget allocates the tokens of the context window to the sections in priority order,
+// each section getting at most its limit. The question is always kept.
+type Budget struct {
+	contextWindow int
+	outputTokens  int
+	priority      []Section
+	limits        map[Section]int
+	counter       TokenCounter
+	model         string
+}
+
+// Allocation is the content of each section fitting the budget.
+type Allocation struct {
+	System       string
+	History      []*thread.Message
+	Context      []string
+	OutputTokens int
+	// Tokens is the number of tokens used by each section.
+	Tokens map[Section]int
+}
+
+// New creates a budget for a model with a context window of contextWindow tokens. By
+// default 1024 tokens are reserved for the output, the retrieved context gets at most
+// half the window and the priority is system, output, context and history. Tokens are
+// estimated from the text length.
+func New(contextWindow int) *Budget {
+	return &Budget{
+		contextWindow: contextWindow,
+		outputTokens:  defaultOutputTokens,
+		priority:      []Section{SectionSystem, SectionOutput, SectionContext, SectionHistory},
+		limits: map[Section]int{
+			SectionContext: contextWindow / 2,
+		},
+		counter: func(text string) int {
+			return ratelimit.EstimateTokens(text)
+		},
+	}
+}
+
+// WithOutputTokens sets the tokens reserved for the model answer.
+func (b *Budget) WithOutputTokens(outputTokens int) *Budget {
+	b.outputTokens = outputTokens
+	return b
+}
+
+// WithLimit sets the maximum number of tokens of the section, zero removes the limit.
+func (b *Budget) WithLimit(section Section, tokens int) *Budget {
+	b.limits[section] = tokens
+	return b
+}
+
+// WithPriority sets the order in which the sections get their tokens. Sections not
+// listed get no tokens.
+func (b *Budget) WithPriority(sections ...Section) *Budget {
+	b.priority = sections
+	return b
+}
+
+func (b *Budget) WithTokenCounter(counter TokenCounter) *Budget {
+	b.counter = counter
+	b.model = ""
+	return b
+}
+
+// WithModel counts the tokens with the tokenizer of the model: the texts with
+// tokenizer.CountString and the history messages with tokenizer.CountMessageTokens,
+// including their tool calls, tool results and images.
+func (b *Budget) WithModel(model string) *Budget {
+	b.counter = tokenizer.Counter(model)
+	b.model = model
+	return b
+}
+
+// Allocate fits the sections in the budget. The system prompt is truncated, the context
+// results are kept by rank, truncating the last one that fits partially, and the oldest
+// history messages are dropped. It fails if the question alone doesn't fit.
+func (b *Budget) Allocate(
+	system string,
+	question string,
+	history []*thread.Message,
+	context []string,
+) (*Allocation, error) {
+	questionTokens := b.counter(question) + messageOverheadTokens
+	remaining := b.contextWindow - questionTokens
+	if remaining < 0 {
+		return nil, fmt.Errorf("%w: the question needs %d tokens of %d", ErrBudgetExceeded, questionTokens, b.contextWindow)
+	}
+
+	allocation := &Allocation{
+		Tokens: make(map[Section]int),
+	}
+
+	for _, section := range b.priority {
+		available := remaining
+		if limit := b.limits[section]; limit > 0 && limit < available {
+			available = limit
+		}
+
+		var used int
+		switch section {
+		case SectionSystem:
+			allocation.System, used = b.fitText(system, available-messageOverheadTokens)
+			if allocation.System != "" {
+				used += messageOverheadTokens
+			}
+		case SectionOutput:
+			used = min(b.outputTokens, available)
+			allocation.OutputTokens = used
+		case SectionContext:
+			allocation.Context, used = b.fitContext(context, available)
+		case SectionHistory:
+			allocation.History, used = b.fitHistory(history, available)
+		}
+
+		allocation.Tokens[section] = used
+		remaining -= used
+	}
+
+	return allocation, nil
+}
+
+// Count returns the tokens of the text contents of the messages, including the chat
+// format overhead. With WithModel all the contents are counted by the model tokenizer.
+func (b *Budget) Count(messages ...*thread.Message) int {
+	tokens := 0
+	for _, message := range messages {
+		if b.model != "" {
+			tokens += tokenizer.CountMessageTokens(b.model, message)
+			continue
+		}
+
+		tokens += messageOverheadTokens
+		for _, content := range message.Contents {
+			if text, ok := content.Data.(string); ok && content.Type == thread.ContentTypeText {
+				tokens += b.counter(text)
+			}
+		}
+	}
+
+	return tokens
+}
+
+func (b *Budget) fitContext(context []string, available int) ([]string, int) {
+	var fitted []string
+	used := 0
+	for _, result := range context {
+		tokens := b.counter(result)
+		if used+tokens <= available {
+			fitted = append(fitted, result)
+			used += tokens
+			continue
+		}
+
+		if truncated, truncatedTokens := b.fitText(result, available-used); truncated != "" {
+			fitted = append(fitted, truncated)
+			used += truncatedTokens
+		}
+		break
+	}
+
+	return fitted, used
+}
+
+// fitHistory keeps the most recent messages fitting the budget. Tool results whose tool
+// call has been dropped are dropped as well.
+func (b *Budget) fitHistory(history []*thread.Message, available int) ([]*thread.Message, int) {
+	used := 0
+	start := len(history)
+	for start > 0 {
+		tokens := b.Count(history[start-1])
+		if used+tokens > available {
+			break
+		}
+		used += tokens
+		start--
+	}
+
+	for start < len(history) && history[start].Role == thread.RoleTool {
+		used -= b.Count(history[start])
+		start++
+	}
+
+	return history[start:], used
+}
+
+// fitText returns the longest prefix of the text fitting the tokens.
+func (b *Budget) fitText(text string, tokens int) (string, int) {
+	if tokens <= 0 {
+		return "", 0
+	}
+
+	textTokens := b.counter(text)
+	if textTokens <= tokens {
+		return text, textTokens
+	}
+
+	runes := []rune(text)
+	low, high := 0, len(runes)
+	for low < high {
+		middle := (low + high + 1) / 2
+		if b.counter(string(runes[:middle])) <= tokens {
+			low = middle
+		} else {
+			high = middle - 1
+		}
+	}
+
+	prefix := strings.TrimRightFunc(string(runes[:low]), unicode.IsSpace)
+	return prefix, b.counter(prefix)
+}
@@ -0,0 +1,100 @@
+package budget
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/tokenizer"
+)
+
+// words counts a token per word, to make the budget easy to follow.
+func words(text string) int {
+	return len(strings.Fields(text))
+}
+
+func textMessage(role thread.Role, text string) *thread.Message {
+	return &thread.Message{Role: role, Contents: []*thread.Content{thread.NewTextContent(text)}}
+}
+
+func TestBudget_Allocate(t *testing.T) {
+	history := []*thread.Message{
+		textMessage(thread.RoleUser, "one two three four five six"),
+		textMessage(thread.RoleAssistant, "one two"),
+		textMessage(thread.RoleUser, "one two three"),
+	}
+	context := []string{"a b c d", "e f g h i j", "k l"}
+
+	b := New(40).WithTokenCounter(words).WithOutputTokens(5).WithLimit(SectionContext, 8)
+
+	allocation, err := b.Allocate("you are helpful", "what is it", history, context)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+
+	// 40 - question (3+4) = 33; system 3+4 = 7; output 5; context 8; history 13 (7+6)
+	if want := []string{"a b c d", "e f g h"}; !reflect.DeepEqual(allocation.Context, want) {
+		t.Errorf("Context = %v, want %v", allocation.Context, want)
+	}
+	if len(allocation.History) != 2 || allocation.History[0] != history[1] {
+		t.Errorf("History = %v, want the last 2 messages", allocation.History)
+	}
+	if allocation.System != "you are helpful" || allocation.OutputTokens != 5 {
+		t.Errorf("System = %q, OutputTokens = %d", allocation.System, allocation.OutputTokens)
+	}
+}
+
+func TestBudget_AllocateDropsOrphanToolResults(t *testing.T) {
+	history := []*thread.Message{
+		textMessage(thread.RoleAssistant, "call call call call"),
+		textMessage(thread.RoleTool, "result"),
+		textMessage(thread.RoleAssistant, "done"),
+	}
+
+	allocation, err := New(30).WithTokenCounter(words).WithOutputTokens(0).Allocate("", "q", history, nil)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	// 30 - 5 = 25 tokens but history limited to 12 tokens
+	allocation2, _ := New(30).WithTokenCounter(words).WithOutputTokens(0).WithLimit(SectionHistory, 12).
+		Allocate("", "q", history, nil)
+
+	if len(allocation.History) != 3 {
+		t.Errorf("History = %d messages, want 3", len(allocation.History))
+	}
+	if len(allocation2.History) != 1 || allocation2.History[0] != history[2] {
+		t.Errorf("History = %v, want only the last message", allocation2.History)
+	}
+}
+
+func TestBudget_AllocateQuestionTooLong(t *testing.T) {
+	_, err := New(5).WithTokenCounter(words).Allocate("", "a b c d e f", nil, nil)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Allocate() error = %v, want ErrBudgetExceeded", err)
+	}
+}
+
+func TestBudget_WithModel(t *testing.T) {
+	tokenizer.Register(tokenizer.EncodingCL100K, tokenizer.EncoderFunc(words))
+
+	toolCall := thread.NewAssistantMessage().AddContent(thread.NewToolCallContent([]thread.ToolCallData{
+		{Name: "search", Arguments: "a b c d e f g h i j"},
+	}))
+	history := []*thread.Message{toolCall, textMessage(thread.RoleAssistant, "done")}
+
+	// the arguments of the tool call are counted by the tokenizer
+	b := New(30).WithModel("gpt-4").WithOutputTokens(0).WithLimit(SectionHistory, 12)
+	if got := b.Count(toolCall); got != 3+1+1+10 {
+		t.Errorf("Count() = %d, want 15", got)
+	}
+
+	allocation, err := b.Allocate("", "q", history, nil)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if len(allocation.History) != 1 || allocation.History[0] != history[1] {
+		t.Errorf("History = %v, want only the last message", allocation.History)
+	}
+}
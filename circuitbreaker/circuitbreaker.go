@@ -0,0 +1,252 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureRate      = 0.5
+	defaultMinRequests      = 5
+	defaultWindow           = time.Minute
+	defaultOpenTimeout      = 30 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	// StateClosed lets every call through while tracking the failure rate.
+	StateClosed State = iota
+	// StateOpen rejects every call until the open timeout expires.
+	StateOpen
+	// StateHalfOpen lets a limited number of probe calls through to decide whether
+	// the provider recovered.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type StateChangeCallback func(from, to State)
+
+// Breaker opens when the failure rate in the current window reaches the threshold, then
+// rejects calls with ErrOpen until the open timeout expires. After that a few probe calls
+// are allowed (half-open state): the breaker closes if they all succeed and opens again
+// on the first failure.
+type Breaker struct {
+	mu sync.Mutex
+
+	failureRate         float64
+	minRequests         int
+	window              time.Duration
+	openTimeout         time.Duration
+	halfOpenRequests    int
+	stateChangeCallback StateChangeCallback
+
+	state             State
+	requests          int
+	failures          int
+	windowStart       time.Time
+	openedAt          time.Time
+	halfOpenInFlight  int
+	halfOpenSuccesses int
+	stateChanges      [][2]State
+	now               func() time.Time
+}
+
+func New() *Breaker {
+	return &Breaker{
+		failureRate:      defaultFailureRate,
+		minRequests:      defaultMinRequests,
+		window:           defaultWindow,
+		openTimeout:      defaultOpenTimeout,
+		halfOpenRequests: defaultHalfOpenRequests,
+		now:              time.Now,
+	}
+}
+
+// WithFailureRate sets the failure rate (0-1) that opens the breaker.
+func (b *Breaker) WithFailureRate(failureRate float64) *Breaker {
+	b.failureRate = failureRate
+	return b
+}
+
+// WithMinRequests sets the number of calls in the window required before the failure
+// rate is evaluated.
+func (b *Breaker) WithMinRequests(minRequests int) *Breaker {
+	b.minRequests = minRequests
+	return b
+}
+
+// WithWindow sets the duration after which the closed state counters are reset.
+func (b *Breaker) WithWindow(window time.Duration) *Breaker {
+	b.window = window
+	return b
+}
+
+// WithOpenTimeout sets how long the breaker stays open before allowing probe calls.
+func (b *Breaker) WithOpenTimeout(openTimeout time.Duration) *Breaker {
+	b.openTimeout = openTimeout
+	return b
+}
+
+// WithHalfOpenRequests sets the number of successful probe calls needed to close the breaker.
+func (b *Breaker) WithHalfOpenRequests(halfOpenRequests int) *Breaker {
+	b.halfOpenRequests = halfOpenRequests
+	return b
+}
+
+func (b *Breaker) WithStateChangeCallback(callback StateChangeCallback) *Breaker {
+	b.stateChangeCallback = callback
+	return b
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.lock()
+	defer b.unlock()
+
+	b.expireOpenState()
+
+	return b.state
+}
+
+// Execute runs fn if the breaker allows it and records its outcome. It returns ErrOpen
+// without calling fn when the breaker is open. Errors caused by the caller cancelling
+// the context are not counted as failures.
+func (b *Breaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	state, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn(ctx)
+
+	failed := err != nil && !errors.Is(ctx.Err(), context.Canceled)
+	b.record(state, failed, err == nil)
+
+	return err
+}
+
+func (b *Breaker) allow() (State, error) {
+	b.lock()
+	defer b.unlock()
+
+	b.expireOpenState()
+
+	switch b.state {
+	case StateOpen:
+		return b.state, ErrOpen
+	case StateHalfOpen:
+		if b.halfOpenInFlight+b.halfOpenSuccesses >= b.halfOpenRequests {
+			return b.state, ErrOpen
+		}
+		b.halfOpenInFlight++
+	case StateClosed:
+		if b.now().Sub(b.windowStart) > b.window {
+			b.resetCounters()
+		}
+	}
+
+	return b.state, nil
+}
+
+func (b *Breaker) record(state State, failed, succeeded bool) {
+	b.lock()
+	defer b.unlock()
+
+	// the breaker changed state while the call was running
+	if state != b.state {
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+
+		if b.requests >= b.minRequests && float64(b.failures)/float64(b.requests) >= b.failureRate {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		b.halfOpenInFlight--
+		if failed {
+			b.setState(StateOpen)
+			return
+		}
+
+		if succeeded {
+			b.halfOpenSuccesses++
+		}
+
+		if b.halfOpenSuccesses >= b.halfOpenRequests {
+			b.setState(StateClosed)
+		}
+	case StateOpen:
+	}
+}
+
+func (b *Breaker) expireOpenState() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		b.setState(StateHalfOpen)
+	}
+}
+
+func (b *Breaker) setState(state State) {
+	from := b.state
+	b.state = state
+	b.resetCounters()
+
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+
+	if from != state {
+		b.stateChanges = append(b.stateChanges, [2]State{from, state})
+	}
+}
+
+func (b *Breaker) lock() {
+	b.mu.Lock()
+}
+
+// unlock releases the breaker and notifies the state changes, so the callback can
+// safely use the breaker.
+func (b *Breaker) unlock() {
+	stateChanges := b.stateChanges
+	b.stateChanges = nil
+	b.mu.Unlock()
+
+	if b.stateChangeCallback == nil {
+		return
+	}
+
+	for _, stateChange := range stateChanges {
+		b.stateChangeCallback(stateChange[0], stateChange[1])
+	}
+}
+
+func (b *Breaker) resetCounters() {
+	b.requests = 0
+	b.failures = 0
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+	b.windowStart = b.now()
+}
@@ -0,0 +1,79 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Now()
+	b := New().WithMinRequests(2).WithFailureRate(0.5).WithOpenTimeout(time.Second)
+	b.now = func() time.Time { return now }
+
+	errFailure := errors.New("failure")
+	fail := func(context.Context) error { return errFailure }
+	succeed := func(context.Context) error { return nil }
+
+	ctx := context.Background()
+	_ = b.Execute(ctx, succeed)
+	_ = b.Execute(ctx, fail)
+	if b.State() != StateOpen {
+		t.Fatalf("expected open state, got %s", b.State())
+	}
+
+	if err := b.Execute(ctx, succeed); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half-open state, got %s", b.State())
+	}
+
+	if err := b.Execute(ctx, fail); !errors.Is(err, errFailure) {
+		t.Fatalf("expected probe failure, got %v", err)
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("expected open state after failed probe, got %s", b.State())
+	}
+
+	now = now.Add(time.Second)
+	if err := b.Execute(ctx, succeed); err != nil {
+		t.Fatalf("expected probe success, got %v", err)
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed state, got %s", b.State())
+	}
+}
+
+type partialLLM struct {
+	err error
+}
+
+func (p *partialLLM) Generate(_ context.Context, t *thread.Thread) error {
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("partial")))
+	if p.err != nil {
+		return p.err
+	}
+
+	t.LastMessage().Contents[0].Data = "answer"
+	return nil
+}
+
+func TestLLMBreakerFallbackDropsPartialMessages(t *testing.T) {
+	llm := NewLLM(&partialLLM{err: errors.New("503")}, New()).WithFallback(&partialLLM{})
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(th.Messages) != 2 || th.LastMessage().Contents[0].AsString() != "answer" {
+		t.Fatalf("unexpected thread %s", th)
+	}
+}
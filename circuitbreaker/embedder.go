@@ -0,0 +1,56 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/henomis/lingoose/embedder"
+)
+
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error)
+}
+
+// EmbedderBreaker protects an embedder with a circuit breaker. The fallback embedder must
+// produce vectors compatible with the protected one (same model, different vendor/region).
+type EmbedderBreaker struct {
+	embedder Embedder
+	breaker  *Breaker
+	fallback Embedder
+}
+
+func NewEmbedder(embedder Embedder, breaker *Breaker) *EmbedderBreaker {
+	return &EmbedderBreaker{
+		embedder: embedder,
+		breaker:  breaker,
+	}
+}
+
+// WithFallback sets the embedder to use when the protected embedder is unavailable.
+func (e *EmbedderBreaker) WithFallback(fallback Embedder) *EmbedderBreaker {
+	e.fallback = fallback
+	return e
+}
+
+func (e *EmbedderBreaker) Breaker() *Breaker {
+	return e.breaker
+}
+
+func (e *EmbedderBreaker) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	var embeddings []embedder.Embedding
+	err := e.breaker.Execute(ctx, func(ctx context.Context) error {
+		var err error
+		embeddings, err = e.embedder.Embed(ctx, texts)
+		return err
+	})
+	if err == nil || ctx.Err() != nil || e.fallback == nil {
+		return embeddings, err
+	}
+
+	embeddings, fallbackErr := e.fallback.Embed(ctx, texts)
+	if fallbackErr != nil {
+		return nil, errors.Join(err, fallbackErr)
+	}
+
+	return embeddings, nil
+}
@@ -0,0 +1,97 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/option"
+)
+
+var _ index.VectorDB = &VectorDBBreaker{}
+
+// VectorDBBreaker protects a vector database with a circuit breaker. Reads are served by
+// the fallback (e.g. a read replica) when the protected database is unavailable, writes
+// always go to the protected database.
+type VectorDBBreaker struct {
+	vectorDB index.VectorDB
+	breaker  *Breaker
+	fallback index.VectorDB
+}
+
+func NewVectorDB(vectorDB index.VectorDB, breaker *Breaker) *VectorDBBreaker {
+	return &VectorDBBreaker{
+		vectorDB: vectorDB,
+		breaker:  breaker,
+	}
+}
+
+// WithFallback sets the vector database used for reads when the protected one is unavailable.
+func (v *VectorDBBreaker) WithFallback(fallback index.VectorDB) *VectorDBBreaker {
+	v.fallback = fallback
+	return v
+}
+
+func (v *VectorDBBreaker) Breaker() *Breaker {
+	return v.breaker
+}
+
+func (v *VectorDBBreaker) Insert(ctx context.Context, datas []index.Data) error {
+	return v.breaker.Execute(ctx, func(ctx context.Context) error {
+		return v.vectorDB.Insert(ctx, datas)
+	})
+}
+
+func (v *VectorDBBreaker) IsEmpty(ctx context.Context) (bool, error) {
+	var isEmpty bool
+	err := v.breaker.Execute(ctx, func(ctx context.Context) error {
+		var err error
+		isEmpty, err = v.vectorDB.IsEmpty(ctx)
+		return err
+	})
+	if err == nil || ctx.Err() != nil || v.fallback == nil {
+		return isEmpty, err
+	}
+
+	isEmpty, fallbackErr := v.fallback.IsEmpty(ctx)
+	if fallbackErr != nil {
+		return isEmpty, errors.Join(err, fallbackErr)
+	}
+
+	return isEmpty, nil
+}
+
+func (v *VectorDBBreaker) Search(
+	ctx context.Context,
+	values []float64,
+	options *option.Options,
+) (index.SearchResults, error) {
+	var searchResults index.SearchResults
+	err := v.breaker.Execute(ctx, func(ctx context.Context) error {
+		var err error
+		searchResults, err = v.vectorDB.Search(ctx, values, options)
+		return err
+	})
+	if err == nil || ctx.Err() != nil || v.fallback == nil {
+		return searchResults, err
+	}
+
+	searchResults, fallbackErr := v.fallback.Search(ctx, values, options)
+	if fallbackErr != nil {
+		return nil, errors.Join(err, fallbackErr)
+	}
+
+	return searchResults, nil
+}
+
+func (v *VectorDBBreaker) Drop(ctx context.Context) error {
+	return v.breaker.Execute(ctx, func(ctx context.Context) error {
+		return v.vectorDB.Drop(ctx)
+	})
+}
+
+func (v *VectorDBBreaker) Delete(ctx context.Context, ids []string) error {
+	return v.breaker.Execute(ctx, func(ctx context.Context) error {
+		return v.vectorDB.Delete(ctx, ids)
+	})
+}
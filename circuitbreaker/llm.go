@@ -0,0 +1,95 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/llm/middleware"
+	"github.com/henomis/lingoose/thread"
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// LLMBreaker protects an LLM with a circuit breaker. When the call fails or the breaker
+// is open the fallback LLM is used, otherwise the cached answer, if any.
+type LLMBreaker struct {
+	llm      LLM
+	breaker  *Breaker
+	fallback LLM
+	cache    *cache.Cache
+}
+
+func NewLLM(llm LLM, breaker *Breaker) *LLMBreaker {
+	return &LLMBreaker{
+		llm:     llm,
+		breaker: breaker,
+	}
+}
+
+// WithFallback sets the LLM to use when the protected LLM is unavailable.
+func (l *LLMBreaker) WithFallback(fallback LLM) *LLMBreaker {
+	l.fallback = fallback
+	return l
+}
+
+// WithCache sets the cache to answer from when the protected LLM is unavailable. It is
+// usually the same cache configured on the protected LLM, which fills it.
+func (l *LLMBreaker) WithCache(cache *cache.Cache) *LLMBreaker {
+	l.cache = cache
+	return l
+}
+
+func (l *LLMBreaker) Breaker() *Breaker {
+	return l.breaker
+}
+
+// Generate calls the protected LLM through the breaker, failing over to the fallback
+// LLM with middleware.Failover. The messages added by a failed call are removed from
+// the thread before falling back.
+func (l *LLMBreaker) Generate(ctx context.Context, t *thread.Thread) error {
+	nMessagesBeforeGeneration := len(t.Messages)
+
+	var llm LLM = middleware.GenerateFunc(func(ctx context.Context, t *thread.Thread) error {
+		return l.breaker.Execute(ctx, func(ctx context.Context) error {
+			return l.llm.Generate(ctx, t)
+		})
+	})
+	if l.fallback != nil {
+		llm = middleware.Failover(middleware.FailoverPolicy{}).WithLLM("", llm).WithLLM("", l.fallback)
+	}
+
+	err := llm.Generate(ctx, t)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	if l.cache != nil {
+		t.Messages = t.Messages[:nMessagesBeforeGeneration]
+
+		cacheErr := l.answerFromCache(ctx, t)
+		if cacheErr == nil {
+			return nil
+		}
+		err = errors.Join(err, cacheErr)
+	}
+
+	return err
+}
+
+func (l *LLMBreaker) answerFromCache(ctx context.Context, t *thread.Thread) error {
+	cacheResult, err := l.cache.Get(ctx, strings.Join(t.UserQuery(), "\n"))
+	if err != nil {
+		return fmt.Errorf("cache fallback: %w", err)
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(strings.Join(cacheResult.Answer, "\n")),
+	))
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/henomis/lingoose/assistant"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/rag"
+)
+
+type componentKey struct {
+	kind Kind
+	name string
+}
+
+// App builds the components of a document on demand, sharing the instances referenced
+// by more than one component.
+type App struct {
+	document   *Document
+	registry   *Registry
+	mu         sync.Mutex
+	components map[componentKey]any
+	building   map[componentKey]bool
+}
+
+func New(document *Document) *App {
+	return &App{
+		document:   document,
+		registry:   DefaultRegistry(),
+		components: make(map[componentKey]any),
+		building:   make(map[componentKey]bool),
+	}
+}
+
+func (a *App) WithRegistry(registry *Registry) *App {
+	a.registry = registry
+	return a
+}
+
+// Component returns the named component of the given kind, building it if required.
+func (a *App) Component(kind Kind, name string) (any, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.component(kind, name)
+}
+
+func (a *App) component(kind Kind, name string) (any, error) {
+	key := componentKey{kind: kind, name: name}
+	if component, ok := a.components[key]; ok {
+		return component, nil
+	}
+
+	if a.building[key] {
+		return nil, fmt.Errorf("%w: %s %s", ErrCycle, kind, name)
+	}
+
+	description, ok := a.document.section(kind)[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s", ErrUnknownComponent, kind, name)
+	}
+
+	constructor, err := a.registry.constructor(kind, description.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	a.building[key] = true
+	defer delete(a.building, key)
+
+	params := description.Params
+	if params == nil {
+		params = Params{}
+	}
+
+	// constructors get a view of the app sharing its components, since the app lock
+	// is already held while building
+	component, err := constructor(&App{
+		document:   a.document,
+		registry:   a.registry,
+		components: a.components,
+		building:   a.building,
+	}, params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %s: %w", ErrConfig, kind, name, err)
+	}
+
+	a.components[key] = component
+
+	return component, nil
+}
+
+func resolve[T any](a *App, kind Kind, name string) (T, error) {
+	var zero T
+
+	component, err := a.component(kind, name)
+	if err != nil {
+		return zero, err
+	}
+
+	typed, ok := component.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s %s has type %T", ErrConfig, kind, name, component)
+	}
+
+	return typed, nil
+}
+
+func get[T any](a *App, kind Kind, name string) (T, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return resolve[T](a, kind, name)
+}
+
+func (a *App) LLM(name string) (assistant.LLM, error) {
+	return get[assistant.LLM](a, KindLLM, name)
+}
+
+func (a *App) Embedder(name string) (index.Embedder, error) {
+	return get[index.Embedder](a, KindEmbedder, name)
+}
+
+func (a *App) Index(name string) (*index.Index, error) {
+	return get[*index.Index](a, KindIndex, name)
+}
+
+func (a *App) Loader(name string) (rag.Loader, error) {
+	return get[rag.Loader](a, KindLoader, name)
+}
+
+func (a *App) RAG(name string) (*rag.RAG, error) {
+	return get[*rag.RAG](a, KindRAG, name)
+}
+
+func (a *App) Assistant(name string) (*assistant.Assistant, error) {
+	return get[*assistant.Assistant](a, KindAssistant, name)
+}
@@ -0,0 +1,476 @@
+package config
+
+import (
+	"regexp"
+
+	"github.com/henomis/lingoose/assistant"
+	cohereembedder "github.com/henomis/lingoose/embedder/cohere"
+	googleaiembedder "github.com/henomis/lingoose/embedder/googleai"
+	nomicembedder "github.com/henomis/lingoose/embedder/nomic"
+	ollamaembedder "github.com/henomis/lingoose/embedder/ollama"
+	openaiembedder "github.com/henomis/lingoose/embedder/openai"
+	togetherembedder "github.com/henomis/lingoose/embedder/together"
+	voyageembedder "github.com/henomis/lingoose/embedder/voyage"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+	"github.com/henomis/lingoose/llm/anthropic"
+	"github.com/henomis/lingoose/llm/fireworks"
+	"github.com/henomis/lingoose/llm/gemini"
+	"github.com/henomis/lingoose/llm/groq"
+	"github.com/henomis/lingoose/llm/mistral"
+	"github.com/henomis/lingoose/llm/ollama"
+	"github.com/henomis/lingoose/llm/openai"
+	"github.com/henomis/lingoose/llm/together"
+	"github.com/henomis/lingoose/llm/xai"
+	"github.com/henomis/lingoose/loader"
+	"github.com/henomis/lingoose/rag"
+)
+
+type llmParams struct {
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature"`
+	MaxTokens   *int     `json:"maxTokens"`
+	Endpoint    string   `json:"endpoint"`
+	APIKey      string   `json:"apiKey"`
+}
+
+type embedderParams struct {
+	Model    string `json:"model"`
+	Endpoint string `json:"endpoint"`
+	APIKey   string `json:"apiKey"`
+}
+
+type indexParams struct {
+	Embedder        string `json:"embedder"`
+	IncludeContents bool   `json:"includeContents"`
+	// jsondb
+	Path string `json:"path"`
+	// qdrant
+	Collection string `json:"collection"`
+	Dimension  uint64 `json:"dimension"`
+	Distance   string `json:"distance"`
+}
+
+type loaderParams struct {
+	Path string `json:"path"`
+}
+
+type ragParams struct {
+	Index        string            `json:"index"`
+	ChunkSize    uint              `json:"chunkSize"`
+	ChunkOverlap uint              `json:"chunkOverlap"`
+	TopK         uint              `json:"topK"`
+	Loaders      map[string]string `json:"loaders"`
+}
+
+type assistantParams struct {
+	LLM           string                `json:"llm"`
+	RAG           string                `json:"rag"`
+	MaxIterations uint                  `json:"maxIterations"`
+	Parameters    *assistant.Parameters `json:"parameters"`
+}
+
+//nolint:funlen
+func registerBuiltins(r *Registry) *Registry {
+	r.Register(KindLLM, "openai", func(_ *App, params Params) (any, error) {
+		var p llmParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm := openai.New()
+		if p.Model != "" {
+			llm.WithModel(openai.Model(p.Model))
+		}
+		if p.Temperature != nil {
+			llm.WithTemperature(float32(*p.Temperature))
+		}
+		if p.MaxTokens != nil {
+			llm.WithMaxTokens(*p.MaxTokens)
+		}
+		return llm, nil
+	})
+
+	r.Register(KindLLM, "anthropic", func(_ *App, params Params) (any, error) {
+		var p llmParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm := anthropic.New()
+		if p.Model != "" {
+			llm.WithModel(p.Model)
+		}
+		if p.Temperature != nil {
+			llm.WithTemperature(*p.Temperature)
+		}
+		if p.MaxTokens != nil {
+			llm.WithMaxTokens(*p.MaxTokens)
+		}
+		return llm, nil
+	})
+
+	registerCohere(r)
+
+	r.Register(KindLLM, "gemini", func(_ *App, params Params) (any, error) {
+		var p llmParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm := gemini.New()
+		if p.APIKey != "" {
+			llm.WithAPIKey(p.APIKey)
+		}
+		if p.Model != "" {
+			llm.WithModel(p.Model)
+		}
+		if p.Temperature != nil {
+			llm.WithTemperature(*p.Temperature)
+		}
+		if p.MaxTokens != nil {
+			llm.WithMaxTokens(*p.MaxTokens)
+		}
+		return llm, nil
+	})
+
+	r.Register(KindLLM, "fireworks", func(_ *App, params Params) (any, error) {
+		var p llmParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm := fireworks.New()
+		if p.APIKey != "" {
+			llm.WithAPIKey(p.APIKey)
+		}
+		if p.Model != "" {
+			llm.WithModel(openai.Model(p.Model))
+		}
+		if p.Temperature != nil {
+			llm.WithTemperature(float32(*p.Temperature))
+		}
+		if p.MaxTokens != nil {
+			llm.WithMaxTokens(*p.MaxTokens)
+		}
+		return llm, nil
+	})
+
+	r.Register(KindLLM, "groq", func(_ *App, params Params) (any, error) {
+		var p llmParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm := groq.New()
+		if p.APIKey != "" {
+			llm.WithAPIKey(p.APIKey)
+		}
+		if p.Endpoint != "" {
+			llm.WithEndpoint(p.Endpoint)
+		}
+		if p.Model != "" {
+			llm.WithModel(p.Model)
+		}
+		if p.Temperature != nil {
+			llm.WithTemperature(*p.Temperature)
+		}
+		if p.MaxTokens != nil {
+			llm.WithMaxTokens(*p.MaxTokens)
+		}
+		return llm, nil
+	})
+
+	r.Register(KindLLM, "mistral", func(_ *App, params Params) (any, error) {
+		var p llmParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm := mistral.New()
+		if p.APIKey != "" {
+			llm.WithAPIKey(p.APIKey)
+		}
+		if p.Endpoint != "" {
+			llm.WithBaseURL(p.Endpoint)
+		}
+		if p.Model != "" {
+			llm.WithModel(openai.Model(p.Model))
+		}
+		if p.Temperature != nil {
+			llm.WithTemperature(float32(*p.Temperature))
+		}
+		if p.MaxTokens != nil {
+			llm.WithMaxTokens(*p.MaxTokens)
+		}
+		return llm, nil
+	})
+
+	r.Register(KindLLM, "ollama", func(_ *App, params Params) (any, error) {
+		var p llmParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm := ollama.New()
+		if p.Endpoint != "" {
+			llm.WithEndpoint(p.Endpoint)
+		}
+		if p.Model != "" {
+			llm.WithModel(p.Model)
+		}
+		if p.Temperature != nil {
+			llm.WithTemperature(*p.Temperature)
+		}
+		return llm, nil
+	})
+
+	r.Register(KindLLM, "together", func(_ *App, params Params) (any, error) {
+		var p llmParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm := together.New()
+		if p.APIKey != "" {
+			llm.WithAPIKey(p.APIKey)
+		}
+		if p.Model != "" {
+			llm.WithModel(openai.Model(p.Model))
+		}
+		if p.Temperature != nil {
+			llm.WithTemperature(float32(*p.Temperature))
+		}
+		if p.MaxTokens != nil {
+			llm.WithMaxTokens(*p.MaxTokens)
+		}
+		return llm, nil
+	})
+
+	r.Register(KindLLM, "xai", func(_ *App, params Params) (any, error) {
+		var p llmParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm := xai.New()
+		if p.APIKey != "" {
+			llm.WithAPIKey(p.APIKey)
+		}
+		if p.Model != "" {
+			llm.WithModel(openai.Model(p.Model))
+		}
+		if p.Temperature != nil {
+			llm.WithTemperature(float32(*p.Temperature))
+		}
+		if p.MaxTokens != nil {
+			llm.WithMaxTokens(*p.MaxTokens)
+		}
+		return llm, nil
+	})
+
+	r.Register(KindEmbedder, "openai", func(_ *App, params Params) (any, error) {
+		var p embedderParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		model := openaiembedder.SmallEmbedding3
+		if p.Model != "" {
+			model = openaiembedder.Model(p.Model)
+		}
+		return openaiembedder.New(model), nil
+	})
+
+	r.Register(KindEmbedder, "cohere", func(_ *App, params Params) (any, error) {
+		var p embedderParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		embedder := cohereembedder.New()
+		if p.APIKey != "" {
+			embedder.WithAPIKey(p.APIKey)
+		}
+		if p.Model != "" {
+			embedder.WithModel(cohereembedder.EmbedderModel(p.Model))
+		}
+		return embedder, nil
+	})
+
+	r.Register(KindEmbedder, "googleai", func(_ *App, params Params) (any, error) {
+		var p embedderParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		embedder := googleaiembedder.New()
+		if p.APIKey != "" {
+			embedder.WithAPIKey(p.APIKey)
+		}
+		if p.Endpoint != "" {
+			embedder.WithEndpoint(p.Endpoint)
+		}
+		if p.Model != "" {
+			embedder.WithModel(googleaiembedder.Model(p.Model))
+		}
+		return embedder, nil
+	})
+
+	r.Register(KindEmbedder, "nomic", func(_ *App, params Params) (any, error) {
+		var p embedderParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		embedder := nomicembedder.New()
+		if p.APIKey != "" {
+			embedder.WithAPIKey(p.APIKey)
+		}
+		if p.Model != "" {
+			embedder.WithModel(nomicembedder.Model(p.Model))
+		}
+		return embedder, nil
+	})
+
+	r.Register(KindEmbedder, "ollama", func(_ *App, params Params) (any, error) {
+		var p embedderParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		embedder := ollamaembedder.New()
+		if p.Endpoint != "" {
+			embedder.WithEndpoint(p.Endpoint)
+		}
+		if p.Model != "" {
+			embedder.WithModel(p.Model)
+		}
+		return embedder, nil
+	})
+
+	r.Register(KindEmbedder, "together", func(_ *App, params Params) (any, error) {
+		var p embedderParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		model := togetherembedder.ModelM2Bert80M8KRetrieval
+		if p.Model != "" {
+			model = openaiembedder.Model(p.Model)
+		}
+		embedder := togetherembedder.New(model)
+		if p.APIKey != "" {
+			embedder.WithAPIKey(p.APIKey)
+		}
+		return embedder, nil
+	})
+
+	r.Register(KindEmbedder, "voyage", func(_ *App, params Params) (any, error) {
+		var p embedderParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		embedder := voyageembedder.New()
+		if p.Model != "" {
+			embedder.WithModel(p.Model)
+		}
+		return embedder, nil
+	})
+
+	r.Register(KindIndex, "jsondb", func(app *App, params Params) (any, error) {
+		var p indexParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		embedder, err := resolve[index.Embedder](app, KindEmbedder, p.Embedder)
+		if err != nil {
+			return nil, err
+		}
+		db := jsondb.New()
+		if p.Path != "" {
+			db.WithPersist(p.Path)
+		}
+		return index.New(db, embedder).WithIncludeContents(p.IncludeContents), nil
+	})
+
+	registerQdrant(r)
+
+	r.Register(KindLoader, "text", func(_ *App, _ Params) (any, error) {
+		return loader.NewText(), nil
+	})
+
+	r.Register(KindLoader, "csv", func(_ *App, _ Params) (any, error) {
+		return loader.NewCSV(), nil
+	})
+
+	r.Register(KindLoader, "pdftotext", func(_ *App, params Params) (any, error) {
+		var p loaderParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		l := loader.NewPDFToText()
+		if p.Path != "" {
+			l.WithPDFToTextPath(p.Path)
+		}
+		return l, nil
+	})
+
+	r.Register(KindLoader, "libreoffice", func(_ *App, params Params) (any, error) {
+		var p loaderParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		l := loader.NewLibreOffice()
+		if p.Path != "" {
+			l.WithLibreOfficePath(p.Path)
+		}
+		return l, nil
+	})
+
+	r.Register(KindRAG, "rag", func(app *App, params Params) (any, error) {
+		var p ragParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		idx, err := resolve[*index.Index](app, KindIndex, p.Index)
+		if err != nil {
+			return nil, err
+		}
+		retriever := rag.New(idx)
+		if p.ChunkSize > 0 {
+			retriever.WithChunkSize(p.ChunkSize)
+		}
+		if p.ChunkOverlap > 0 {
+			retriever.WithChunkOverlap(p.ChunkOverlap)
+		}
+		if p.TopK > 0 {
+			retriever.WithTopK(p.TopK)
+		}
+		for sourceRegexp, loaderName := range p.Loaders {
+			re, errCompile := regexp.Compile(sourceRegexp)
+			if errCompile != nil {
+				return nil, errCompile
+			}
+			l, errLoader := resolve[rag.Loader](app, KindLoader, loaderName)
+			if errLoader != nil {
+				return nil, errLoader
+			}
+			retriever.WithLoader(re, l)
+		}
+		return retriever, nil
+	})
+
+	r.Register(KindAssistant, "assistant", func(app *App, params Params) (any, error) {
+		var p assistantParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm, err := resolve[assistant.LLM](app, KindLLM, p.LLM)
+		if err != nil {
+			return nil, err
+		}
+		a := assistant.New(llm)
+		if p.RAG != "" {
+			retriever, errRAG := resolve[*rag.RAG](app, KindRAG, p.RAG)
+			if errRAG != nil {
+				return nil, errRAG
+			}
+			a.WithRAG(retriever)
+		}
+		if p.MaxIterations > 0 {
+			a.WithMaxIterations(p.MaxIterations)
+		}
+		if p.Parameters != nil {
+			a.WithParameters(*p.Parameters)
+		}
+		return a, nil
+	})
+
+	return r
+}
@@ -0,0 +1,32 @@
+//go:build !lingoose_no_cohere
+
+package config
+
+import (
+	"github.com/henomis/lingoose/llm/cohere"
+)
+
+// registerCohere registers the Cohere LLM, built on the cohere-go client. Build with the
+// lingoose_no_cohere tag to leave it out.
+func registerCohere(r *Registry) {
+	r.Register(KindLLM, "cohere", func(_ *App, params Params) (any, error) {
+		var p llmParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		llm := cohere.New()
+		if p.APIKey != "" {
+			llm.WithAPIKey(p.APIKey)
+		}
+		if p.Model != "" {
+			llm.WithModel(cohere.Model(p.Model))
+		}
+		if p.Temperature != nil {
+			llm.WithTemperature(*p.Temperature)
+		}
+		if p.MaxTokens != nil {
+			llm.WithMaxTokens(*p.MaxTokens)
+		}
+		return llm, nil
+	})
+}
@@ -0,0 +1,5 @@
+//go:build lingoose_no_cohere
+
+package config
+
+func registerCohere(*Registry) {}
@@ -0,0 +1,5 @@
+//go:build lingoose_no_qdrant
+
+package config
+
+func registerQdrant(*Registry) {}
@@ -0,0 +1,37 @@
+//go:build !lingoose_no_qdrant
+
+package config
+
+import (
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/vectordb/qdrant"
+)
+
+// registerQdrant registers the Qdrant index, built on the Qdrant REST and gRPC clients.
+// Build with the lingoose_no_qdrant tag to leave it out.
+func registerQdrant(r *Registry) {
+	r.Register(KindIndex, "qdrant", func(app *App, params Params) (any, error) {
+		var p indexParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		embedder, err := resolve[index.Embedder](app, KindEmbedder, p.Embedder)
+		if err != nil {
+			return nil, err
+		}
+		options := qdrant.Options{
+			CollectionName: p.Collection,
+		}
+		if p.Dimension > 0 {
+			distance := qdrant.DistanceCosine
+			if p.Distance != "" {
+				distance = qdrant.Distance(p.Distance)
+			}
+			options.CreateCollection = &qdrant.CreateCollectionOptions{
+				Dimension: p.Dimension,
+				Distance:  distance,
+			}
+		}
+		return index.New(qdrant.New(options), embedder).WithIncludeContents(p.IncludeContents), nil
+	})
+}
@@ -0,0 +1,125 @@
+// Package config builds LLMs, embedders, indexes, loaders, RAGs and assistants from a
+// single YAML or JSON document, so an application can be reconfigured without code changes.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrConfig           = errors.New("config error")
+	ErrUnknownType      = errors.New("unknown component type")
+	ErrUnknownComponent = errors.New("unknown component")
+	ErrCycle            = errors.New("component dependency cycle")
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// Component describes a component by the type registered for its kind and the
+// parameters passed to the constructor.
+type Component struct {
+	Type   string `json:"type" yaml:"type"`
+	Params Params `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// Document is the application configuration. Each section maps a component name
+// to its description; components reference each other by name.
+type Document struct {
+	LLMs       map[string]Component `json:"llms,omitempty" yaml:"llms,omitempty"`
+	Embedders  map[string]Component `json:"embedders,omitempty" yaml:"embedders,omitempty"`
+	Indexes    map[string]Component `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+	Loaders    map[string]Component `json:"loaders,omitempty" yaml:"loaders,omitempty"`
+	RAGs       map[string]Component `json:"rags,omitempty" yaml:"rags,omitempty"`
+	Assistants map[string]Component `json:"assistants,omitempty" yaml:"assistants,omitempty"`
+}
+
+func (d *Document) section(kind Kind) map[string]Component {
+	switch kind {
+	case KindLLM:
+		return d.LLMs
+	case KindEmbedder:
+		return d.Embedders
+	case KindIndex:
+		return d.Indexes
+	case KindLoader:
+		return d.Loaders
+	case KindRAG:
+		return d.RAGs
+	case KindAssistant:
+		return d.Assistants
+	}
+
+	return nil
+}
+
+// Params are the constructor parameters of a component.
+type Params map[string]any
+
+// Decode decodes the parameters into v, matching the fields by their json tag.
+func (p Params) Decode(v any) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          "json",
+		WeaklyTypedInput: true,
+		Result:           v,
+	})
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(map[string]any(p))
+}
+
+// Parse parses the document. Environment variables in the form ${NAME} are expanded
+// before parsing, so secrets don't need to be stored in the document.
+func Parse(data []byte, format Format) (*Document, error) {
+	data = []byte(os.ExpandEnv(string(data)))
+
+	var document Document
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal(data, &document)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &document)
+	default:
+		err = fmt.Errorf("unsupported format %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+
+	return &document, nil
+}
+
+// Load reads the document from file, the format is detected from the file extension.
+func Load(path string) (*App, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+
+	format := FormatYAML
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = FormatJSON
+	}
+
+	document, err := Parse(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(document), nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+type testLLM struct {
+	model string
+}
+
+type testAssistant struct {
+	llm *testLLM
+}
+
+func TestAppResolvesReferences(t *testing.T) {
+	t.Setenv("CONFIG_TEST_MODEL", "model-from-env")
+
+	document, err := Parse([]byte(`
+llms:
+  main:
+    type: test
+    params:
+      model: ${CONFIG_TEST_MODEL}
+assistants:
+  first:
+    type: test
+    params:
+      llm: main
+  second:
+    type: test
+    params:
+      llm: main
+  loop:
+    type: loop
+`), FormatYAML)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	registry := NewRegistry().
+		Register(KindLLM, "test", func(_ *App, params Params) (any, error) {
+			var p struct {
+				Model string `json:"model"`
+			}
+			if errDecode := params.Decode(&p); errDecode != nil {
+				return nil, errDecode
+			}
+			return &testLLM{model: p.Model}, nil
+		}).
+		Register(KindAssistant, "test", func(app *App, params Params) (any, error) {
+			var p struct {
+				LLM string `json:"llm"`
+			}
+			if errDecode := params.Decode(&p); errDecode != nil {
+				return nil, errDecode
+			}
+			llm, errLLM := resolve[*testLLM](app, KindLLM, p.LLM)
+			if errLLM != nil {
+				return nil, errLLM
+			}
+			return &testAssistant{llm: llm}, nil
+		}).
+		Register(KindAssistant, "loop", func(app *App, _ Params) (any, error) {
+			return resolve[*testAssistant](app, KindAssistant, "loop")
+		})
+
+	app := New(document).WithRegistry(registry)
+
+	first, err := get[*testAssistant](app, KindAssistant, "first")
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	second, err := get[*testAssistant](app, KindAssistant, "second")
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+
+	if first.llm != second.llm {
+		t.Errorf("shared LLM built twice")
+	}
+	if first.llm.model != "model-from-env" {
+		t.Errorf("model = %q, want %q", first.llm.model, "model-from-env")
+	}
+
+	_, err = get[*testAssistant](app, KindAssistant, "loop")
+	if !errors.Is(err, ErrCycle) {
+		t.Errorf("get() error = %v, want %v", err, ErrCycle)
+	}
+
+	_, err = get[*testAssistant](app, KindAssistant, "missing")
+	if !errors.Is(err, ErrUnknownComponent) {
+		t.Errorf("get() error = %v, want %v", err, ErrUnknownComponent)
+	}
+}
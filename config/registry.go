@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+)
+
+type Kind string
+
+const (
+	KindLLM       Kind = "llm"
+	KindEmbedder  Kind = "embedder"
+	KindIndex     Kind = "index"
+	KindLoader    Kind = "loader"
+	KindRAG       Kind = "rag"
+	KindAssistant Kind = "assistant"
+)
+
+// Constructor builds a component from its parameters. Other components can be resolved
+// by name through the app.
+type Constructor func(app *App, params Params) (any, error)
+
+type Registry struct {
+	mu           sync.RWMutex
+	constructors map[Kind]map[string]Constructor
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		constructors: make(map[Kind]map[string]Constructor),
+	}
+}
+
+var defaultRegistry = registerBuiltins(NewRegistry())
+
+// DefaultRegistry returns the registry with the components shipped with LinGoose. Custom
+// components registered here are available to every app.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+func (r *Registry) Register(kind Kind, componentType string, constructor Constructor) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.constructors[kind] == nil {
+		r.constructors[kind] = make(map[string]Constructor)
+	}
+	r.constructors[kind][componentType] = constructor
+
+	return r
+}
+
+func (r *Registry) constructor(kind Kind, componentType string) (Constructor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	constructor, ok := r.constructors[kind][componentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s", ErrUnknownType, kind, componentType)
+	}
+
+	return constructor, nil
+}
@@ -0,0 +1,424 @@
+// Package costtracker accumulates the dollar cost of LLM calls per model, per trace and
+// per session, from the provider usage callbacks or, estimating the tokens, from the
+// observed generations.
+package costtracker
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/secret"
+	"github.com/henomis/lingoose/tokenizer"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	tokensPerPrice = 1_000_000
+)
+
+// Price is the cost in USD per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// Pricing maps model names to their price. Models are matched by the longest prefix, so
+// that dated versions (e.g. gpt-4o-2024-08-06) get the price of their family.
+type Pricing map[string]Price
+
+// DefaultPricing returns the list prices of common models. Prices change: set the
+// current ones with WithPricing.
+func DefaultPricing() Pricing {
+	return Pricing{
+		"gpt-5":                  {Input: 1.25, Output: 10},
+		"gpt-5-mini":             {Input: 0.25, Output: 2},
+		"gpt-5-nano":             {Input: 0.05, Output: 0.4},
+		"gpt-4.1":                {Input: 2, Output: 8},
+		"gpt-4.1-mini":           {Input: 0.4, Output: 1.6},
+		"gpt-4.1-nano":           {Input: 0.1, Output: 0.4},
+		"gpt-4o":                 {Input: 2.5, Output: 10},
+		"gpt-4o-mini":            {Input: 0.15, Output: 0.6},
+		"gpt-4-turbo":            {Input: 10, Output: 30},
+		"gpt-4":                  {Input: 30, Output: 60},
+		"gpt-3.5-turbo":          {Input: 0.5, Output: 1.5},
+		"o1":                     {Input: 15, Output: 60},
+		"o1-mini":                {Input: 1.1, Output: 4.4},
+		"o3":                     {Input: 2, Output: 8},
+		"o3-mini":                {Input: 1.1, Output: 4.4},
+		"o4-mini":                {Input: 1.1, Output: 4.4},
+		"text-embedding-3-small": {Input: 0.02},
+		"text-embedding-3-large": {Input: 0.13},
+		"text-embedding-ada-002": {Input: 0.1},
+		"claude-opus-4":          {Input: 15, Output: 75},
+		"claude-sonnet-4":        {Input: 3, Output: 15},
+		"claude-3-7-sonnet":      {Input: 3, Output: 15},
+		"claude-3-5-sonnet":      {Input: 3, Output: 15},
+		"claude-3-5-haiku":       {Input: 0.8, Output: 4},
+		"claude-3-opus":          {Input: 15, Output: 75},
+		"claude-3-haiku":         {Input: 0.25, Output: 1.25},
+		"gemini-2.5-pro":         {Input: 1.25, Output: 10},
+		"gemini-2.5-flash":       {Input: 0.3, Output: 2.5},
+		"gemini-2.0-flash":       {Input: 0.1, Output: 0.4},
+		"gemini-1.5-pro":         {Input: 1.25, Output: 5},
+		"gemini-1.5-flash":       {Input: 0.075, Output: 0.3},
+		"mistral-large":          {Input: 2, Output: 6},
+		"mistral-small":          {Input: 0.2, Output: 0.6},
+		"deepseek-chat":          {Input: 0.27, Output: 1.1},
+		"deepseek-reasoner":      {Input: 0.55, Output: 2.19},
+	}
+}
+
+// Usage is the token usage of an LLM call.
+type Usage struct {
+	Model            string
+	TraceID          string
+	SessionID        string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Cost is the accumulated usage and cost of a group of calls.
+type Cost struct {
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	USD              float64
+}
+
+type Summary struct {
+	Total     Cost
+	ByModel   map[string]Cost
+	ByTrace   map[string]Cost
+	BySession map[string]Cost
+	// UnpricedModels lists the models without a price, counted with a zero cost.
+	UnpricedModels []string
+}
+
+type spanObserver interface {
+	Span(*observer.Span) (*observer.Span, error)
+	SpanEnd(*observer.Span) (*observer.Span, error)
+}
+
+type generationObserver interface {
+	Generation(*observer.Generation) (*observer.Generation, error)
+	GenerationEnd(*observer.Generation) (*observer.Generation, error)
+}
+
+type embeddingObserver interface {
+	Embedding(*observer.Embedding) (*observer.Embedding, error)
+	EmbeddingEnd(*observer.Embedding) (*observer.Embedding, error)
+}
+
+type traceObserver interface {
+	Trace(*observer.Trace) (*observer.Trace, error)
+}
+
+type eventObserver interface {
+	Event(*observer.Event) (*observer.Event, error)
+}
+
+type artifactObserver interface {
+	Artifact(*observer.Artifact) (*observer.Artifact, error)
+}
+
+type scorer interface {
+	Score(*observer.Score) (*observer.Score, error)
+}
+
+// Tracker accumulates the cost of the recorded usages. It is also an observer: the
+// tokens of the ended generations are counted with the tokenizer package and recorded,
+// and every observation is forwarded to the wrapped observer, if any. Use either the
+// observer or the usage callbacks for the same LLM, not both.
+type Tracker struct {
+	next any
+
+	mu             sync.Mutex
+	pricing        Pricing
+	traceSessions  map[string]string
+	summary        Summary
+	unpricedModels map[string]bool
+}
+
+// New returns a tracker wrapping the next observer (e.g. Langfuse), which can be nil.
+func New(next any) *Tracker {
+	return &Tracker{
+		next:          next,
+		pricing:       DefaultPricing(),
+		traceSessions: make(map[string]string),
+		summary: Summary{
+			ByModel:   make(map[string]Cost),
+			ByTrace:   make(map[string]Cost),
+			BySession: make(map[string]Cost),
+		},
+		unpricedModels: make(map[string]bool),
+	}
+}
+
+// WithPricing adds or replaces the prices of the models.
+func (t *Tracker) WithPricing(pricing Pricing) *Tracker {
+	for model, price := range pricing {
+		t.pricing[model] = price
+	}
+	return t
+}
+
+// SetSession attributes the calls of the trace to the session.
+func (t *Tracker) SetSession(traceID, sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.traceSessions[traceID] = sessionID
+}
+
+// UsageCallback returns a usage callback for an LLM using the model, recording the calls
+// in the session, which can be empty. It reads the PromptTokens and CompletionTokens
+// usage metadata set by the LinGoose providers. Without a session, the calls made for a
+// tenant (see secret.WithTenant) are recorded in a session named after the tenant.
+func (t *Tracker) UsageCallback(model, sessionID string) func(types.Meta) {
+	return func(usage types.Meta) {
+		session := sessionID
+		if tenant, ok := usage[secret.UsageKeyTenant].(string); ok && session == "" {
+			session = tenant
+		}
+
+		t.Record(Usage{
+			Model:            model,
+			SessionID:        session,
+			PromptTokens:     metaInt(usage, "PromptTokens"),
+			CompletionTokens: metaInt(usage, "CompletionTokens"),
+		})
+	}
+}
+
+// Record adds the usage to the totals.
+func (t *Tracker) Record(usage Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	price, ok := t.pricing.Price(usage.Model)
+	if !ok {
+		t.unpricedModels[usage.Model] = true
+	}
+
+	cost := Cost{
+		Calls:            1,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		USD:              price.Cost(usage.PromptTokens, usage.CompletionTokens),
+	}
+
+	sessionID := usage.SessionID
+	if sessionID == "" {
+		sessionID = t.traceSessions[usage.TraceID]
+	}
+
+	t.summary.Total = t.summary.Total.add(cost)
+	t.summary.ByModel[usage.Model] = t.summary.ByModel[usage.Model].add(cost)
+	if usage.TraceID != "" {
+		t.summary.ByTrace[usage.TraceID] = t.summary.ByTrace[usage.TraceID].add(cost)
+	}
+	if sessionID != "" {
+		t.summary.BySession[sessionID] = t.summary.BySession[sessionID].add(cost)
+	}
+}
+
+// Summary returns the accumulated costs.
+func (t *Tracker) Summary() Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	summary := Summary{
+		Total:     t.summary.Total,
+		ByModel:   copyCosts(t.summary.ByModel),
+		ByTrace:   copyCosts(t.summary.ByTrace),
+		BySession: copyCosts(t.summary.BySession),
+	}
+	for model := range t.unpricedModels {
+		summary.UnpricedModels = append(summary.UnpricedModels, model)
+	}
+	sort.Strings(summary.UnpricedModels)
+
+	return summary
+}
+
+// Reset clears the accumulated costs.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.summary = Summary{
+		ByModel:   make(map[string]Cost),
+		ByTrace:   make(map[string]Cost),
+		BySession: make(map[string]Cost),
+	}
+	t.unpricedModels = make(map[string]bool)
+}
+
+// Price returns the price of the model with the longest matching prefix, ignoring the
+// provider prefix (e.g. openai/gpt-4o).
+func (p Pricing) Price(model string) (Price, bool) {
+	model = strings.ToLower(model)
+	// strip the provider prefix, e.g. openai/gpt-4o
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+
+	var price Price
+	matched := -1
+	for name, namePrice := range p {
+		if strings.HasPrefix(model, strings.ToLower(name)) && len(name) > matched {
+			price = namePrice
+			matched = len(name)
+		}
+	}
+
+	return price, matched >= 0
+}
+
+// Cost returns the cost in USD of the tokens with the price.
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / tokensPerPrice
+}
+
+func (t *Tracker) Trace(trace *observer.Trace) (*observer.Trace, error) {
+	if o, ok := t.next.(traceObserver); ok {
+		return o.Trace(trace)
+	}
+
+	if trace.ID == "" {
+		trace.ID = uuid.New().String()
+	}
+	return trace, nil
+}
+
+func (t *Tracker) Span(s *observer.Span) (*observer.Span, error) {
+	if o, ok := t.next.(spanObserver); ok {
+		return o.Span(s)
+	}
+
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return s, nil
+}
+
+func (t *Tracker) SpanEnd(s *observer.Span) (*observer.Span, error) {
+	if o, ok := t.next.(spanObserver); ok {
+		return o.SpanEnd(s)
+	}
+	return s, nil
+}
+
+func (t *Tracker) Generation(g *observer.Generation) (*observer.Generation, error) {
+	if o, ok := t.next.(generationObserver); ok {
+		return o.Generation(g)
+	}
+
+	if g.ID == "" {
+		g.ID = uuid.New().String()
+	}
+	return g, nil
+}
+
+func (t *Tracker) GenerationEnd(g *observer.Generation) (*observer.Generation, error) {
+	usage := Usage{
+		Model:   g.Model,
+		TraceID: g.TraceID,
+	}
+	for _, message := range g.Input {
+		usage.PromptTokens += tokenizer.CountMessageTokens(g.Model, message)
+	}
+	for _, message := range g.Output {
+		usage.CompletionTokens += tokenizer.CountMessageTokens(g.Model, message)
+	}
+	t.Record(usage)
+
+	if o, ok := t.next.(generationObserver); ok {
+		return o.GenerationEnd(g)
+	}
+	return g, nil
+}
+
+func (t *Tracker) Embedding(e *observer.Embedding) (*observer.Embedding, error) {
+	if o, ok := t.next.(embeddingObserver); ok {
+		return o.Embedding(e)
+	}
+
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return e, nil
+}
+
+func (t *Tracker) EmbeddingEnd(e *observer.Embedding) (*observer.Embedding, error) {
+	usage := Usage{
+		Model:   e.Model,
+		TraceID: e.TraceID,
+	}
+	for _, text := range e.Input {
+		usage.PromptTokens += tokenizer.CountString(e.Model, text)
+	}
+	t.Record(usage)
+
+	if o, ok := t.next.(embeddingObserver); ok {
+		return o.EmbeddingEnd(e)
+	}
+	return e, nil
+}
+
+func (t *Tracker) Event(e *observer.Event) (*observer.Event, error) {
+	if o, ok := t.next.(eventObserver); ok {
+		return o.Event(e)
+	}
+	return e, nil
+}
+
+func (t *Tracker) Artifact(a *observer.Artifact) (*observer.Artifact, error) {
+	if o, ok := t.next.(artifactObserver); ok {
+		return o.Artifact(a)
+	}
+	return a, nil
+}
+
+func (t *Tracker) Score(s *observer.Score) (*observer.Score, error) {
+	if o, ok := t.next.(scorer); ok {
+		return o.Score(s)
+	}
+	return s, nil
+}
+
+func (c Cost) add(other Cost) Cost {
+	return Cost{
+		Calls:            c.Calls + other.Calls,
+		PromptTokens:     c.PromptTokens + other.PromptTokens,
+		CompletionTokens: c.CompletionTokens + other.CompletionTokens,
+		USD:              c.USD + other.USD,
+	}
+}
+
+func copyCosts(costs map[string]Cost) map[string]Cost {
+	copied := make(map[string]Cost, len(costs))
+	for key, cost := range costs {
+		copied[key] = cost
+	}
+	return copied
+}
+
+func metaInt(meta types.Meta, key string) int {
+	switch value := meta[key].(type) {
+	case int:
+		return value
+	case int32:
+		return int(value)
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	}
+
+	return 0
+}
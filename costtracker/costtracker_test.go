@@ -0,0 +1,49 @@
+package costtracker
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/types"
+)
+
+func TestTracker_Summary(t *testing.T) {
+	tracker := New(nil).WithPricing(Pricing{"my-model": {Input: 1, Output: 2}})
+	tracker.SetSession("trace-1", "session-1")
+
+	tracker.UsageCallback("gpt-4o-mini-2024-07-18", "session-2")(types.Meta{
+		"PromptTokens":     1_000_000,
+		"CompletionTokens": 500_000,
+		"TotalTokens":      1_500_000,
+	})
+	tracker.Record(Usage{Model: "openai/gpt-4o", TraceID: "trace-1", PromptTokens: 1000, CompletionTokens: 100})
+	tracker.Record(Usage{Model: "my-model", TraceID: "trace-1", PromptTokens: 500_000, CompletionTokens: 250_000})
+	tracker.Record(Usage{Model: "unknown", PromptTokens: 10})
+
+	summary := tracker.Summary()
+
+	tests := []struct {
+		name string
+		cost Cost
+		want float64
+	}{
+		{"gpt-4o-mini", summary.ByModel["gpt-4o-mini-2024-07-18"], 0.15 + 0.3},
+		{"gpt-4o", summary.ByModel["openai/gpt-4o"], 0.0025 + 0.001},
+		{"trace", summary.ByTrace["trace-1"], 0.0035 + 1},
+		{"session", summary.BySession["session-1"], 0.0035 + 1},
+		{"total", summary.Total, 0.45 + 0.0035 + 1},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.cost.USD-tt.want) > 1e-9 {
+			t.Errorf("%s cost = %f, want %f", tt.name, tt.cost.USD, tt.want)
+		}
+	}
+
+	if summary.Total.Calls != 4 {
+		t.Errorf("Total.Calls = %d, want 4", summary.Total.Calls)
+	}
+	if !reflect.DeepEqual(summary.UnpricedModels, []string{"unknown"}) {
+		t.Errorf("UnpricedModels = %v, want [unknown]", summary.UnpricedModels)
+	}
+}
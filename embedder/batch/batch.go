@@ -0,0 +1,195 @@
+package batchembedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/ratelimit"
+)
+
+var (
+	ErrBatchEmbed = errors.New("batch embed error")
+)
+
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error)
+}
+
+type queryEmbedder interface {
+	EmbedQuery(ctx context.Context, query string) (embedder.Embedding, error)
+}
+
+// Batcher splits the texts to embed into batches complying with the limits of the
+// provider, on the number of texts and on the tokens per request. The batches are
+// submitted concurrently and the embeddings are returned in the order of the texts.
+type Batcher struct {
+	embedder     Embedder
+	maxItems     int
+	maxTokens    int
+	tokenCounter func(string) int
+	concurrency  int
+}
+
+// New returns a batcher sending every text in a single request until the limits are set.
+func New(embedder Embedder) *Batcher {
+	return &Batcher{
+		embedder: embedder,
+		tokenCounter: func(text string) int {
+			return ratelimit.EstimateTokens(text)
+		},
+		concurrency: 1,
+	}
+}
+
+// WithMaxItems sets the maximum number of texts per request.
+func (b *Batcher) WithMaxItems(maxItems int) *Batcher {
+	b.maxItems = maxItems
+	return b
+}
+
+// WithMaxTokens sets the maximum tokens per request. A text exceeding the limit alone is
+// sent in its own request.
+func (b *Batcher) WithMaxTokens(maxTokens int) *Batcher {
+	b.maxTokens = maxTokens
+	return b
+}
+
+// WithTokenCounter sets the function counting the tokens of a text, such as
+// tokenizer.Counter(model). The tokens are estimated from the length by default.
+func (b *Batcher) WithTokenCounter(tokenCounter func(string) int) *Batcher {
+	b.tokenCounter = tokenCounter
+	return b
+}
+
+// WithConcurrency sets the number of batches submitted at the same time, 1 by default.
+func (b *Batcher) WithConcurrency(concurrency int) *Batcher {
+	b.concurrency = concurrency
+	return b
+}
+
+// Embed returns the embeddings for the given texts. If a batch fails the pending ones
+// are cancelled and the first error is returned.
+func (b *Batcher) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	batches := b.split(texts)
+	if len(batches) <= 1 {
+		return b.embedder.Embed(ctx, texts)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	embeddings := make([]embedder.Embedding, len(texts))
+	semaphore := make(chan struct{}, max(b.concurrency, 1))
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		batchErr error
+	)
+	for _, batch := range batches {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(batch textRange) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			err := b.embedBatch(ctx, texts, batch, embeddings)
+			if err != nil {
+				errOnce.Do(func() {
+					batchErr = err
+					cancel()
+				})
+			}
+		}(batch)
+	}
+	wg.Wait()
+
+	if batchErr != nil {
+		return nil, batchErr
+	}
+
+	return embeddings, nil
+}
+
+// EmbedQuery embeds the query with the wrapped embedder, using its EmbedQuery when
+// available.
+func (b *Batcher) EmbedQuery(ctx context.Context, query string) (embedder.Embedding, error) {
+	if queryEmbedder, ok := b.embedder.(queryEmbedder); ok {
+		return queryEmbedder.EmbedQuery(ctx, query)
+	}
+
+	embeddings, err := b.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(embeddings) != 1 {
+		return nil, fmt.Errorf("%w: got %d embeddings for 1 text", ErrBatchEmbed, len(embeddings))
+	}
+
+	return embeddings[0], nil
+}
+
+func (b *Batcher) embedBatch(ctx context.Context, texts []string, batch textRange, embeddings []embedder.Embedding) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	batchEmbeddings, err := b.embedder.Embed(ctx, texts[batch.start:batch.end])
+	if err != nil {
+		return err
+	}
+
+	if len(batchEmbeddings) != batch.end-batch.start {
+		return fmt.Errorf(
+			"%w: got %d embeddings for %d texts",
+			ErrBatchEmbed,
+			len(batchEmbeddings),
+			batch.end-batch.start,
+		)
+	}
+
+	copy(embeddings[batch.start:batch.end], batchEmbeddings)
+
+	return nil
+}
+
+type textRange struct {
+	start int
+	end   int
+}
+
+// split returns consecutive batches of texts within the limits.
+func (b *Batcher) split(texts []string) []textRange {
+	var batches []textRange
+
+	start := 0
+	tokens := 0
+	for i, text := range texts {
+		textTokens := 0
+		if b.maxTokens > 0 {
+			textTokens = b.tokenCounter(text)
+		}
+
+		full := b.maxItems > 0 && i-start >= b.maxItems
+		full = full || (b.maxTokens > 0 && tokens+textTokens > b.maxTokens)
+		if i > start && full {
+			batches = append(batches, textRange{start: start, end: i})
+			start = i
+			tokens = 0
+		}
+
+		tokens += textTokens
+	}
+
+	if start < len(texts) {
+		batches = append(batches, textRange{start: start, end: len(texts)})
+	}
+
+	return batches
+}
@@ -0,0 +1,155 @@
+package batchembedder
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/embedder"
+)
+
+type fakeEmbedder struct {
+	mu       sync.Mutex
+	requests [][]string
+	inFlight int
+	maxIn    int
+	failOn   string
+}
+
+func (f *fakeEmbedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, texts)
+	f.inFlight++
+	f.maxIn = max(f.maxIn, f.inFlight)
+	request := len(f.requests)
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+
+	// later batches answer first to check the results are reassembled in order
+	time.Sleep(time.Duration(10-request) * time.Millisecond)
+
+	embeddings := make([]embedder.Embedding, len(texts))
+	for i, text := range texts {
+		if text == f.failOn {
+			return nil, errors.New("embed failed")
+		}
+
+		value, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, err
+		}
+		embeddings[i] = embedder.Embedding{float64(value)}
+	}
+
+	return embeddings, nil
+}
+
+func texts(n int) []string {
+	t := make([]string, n)
+	for i := range t {
+		t[i] = strconv.Itoa(i)
+	}
+
+	return t
+}
+
+func TestBatcherSplit(t *testing.T) {
+	tokens := func(text string) int { return len(text) }
+
+	tests := []struct {
+		name    string
+		batcher *Batcher
+		texts   []string
+		want    []textRange
+	}{
+		{
+			name:    "no limits",
+			batcher: New(nil),
+			texts:   texts(5),
+			want:    []textRange{{0, 5}},
+		},
+		{
+			name:    "max items",
+			batcher: New(nil).WithMaxItems(2),
+			texts:   texts(5),
+			want:    []textRange{{0, 2}, {2, 4}, {4, 5}},
+		},
+		{
+			name:    "max tokens",
+			batcher: New(nil).WithMaxTokens(4).WithTokenCounter(tokens),
+			texts:   []string{"a", "bbb", "cc", "dd", "eeeeee", "f"},
+			want:    []textRange{{0, 2}, {2, 4}, {4, 5}, {5, 6}},
+		},
+		{
+			name:    "max items and tokens",
+			batcher: New(nil).WithMaxItems(2).WithMaxTokens(3).WithTokenCounter(tokens),
+			texts:   []string{"a", "b", "c", "dd", "e"},
+			want:    []textRange{{0, 2}, {2, 4}, {4, 5}},
+		},
+		{
+			name:    "empty",
+			batcher: New(nil).WithMaxItems(2),
+			texts:   nil,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.batcher.split(tt.texts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatcherEmbed(t *testing.T) {
+	fake := &fakeEmbedder{}
+	b := New(fake).WithMaxItems(3).WithConcurrency(2)
+
+	embeddings, err := b.Embed(context.Background(), texts(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(embeddings) != 10 {
+		t.Fatalf("got %d embeddings, want 10", len(embeddings))
+	}
+	for i, embedding := range embeddings {
+		if embedding[0] != float64(i) {
+			t.Errorf("embedding %d = %v", i, embedding)
+		}
+	}
+
+	if len(fake.requests) != 4 {
+		t.Errorf("got %d requests, want 4", len(fake.requests))
+	}
+	if fake.maxIn > 2 {
+		t.Errorf("got %d concurrent requests, want at most 2", fake.maxIn)
+	}
+}
+
+func TestBatcherEmbedError(t *testing.T) {
+	fake := &fakeEmbedder{failOn: "4"}
+	b := New(fake).WithMaxItems(2)
+
+	_, err := b.Embed(context.Background(), texts(10))
+	if err == nil || err.Error() != "embed failed" {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	// batches are sent one at a time, the ones after the failure are skipped
+	if len(fake.requests) != 3 {
+		t.Errorf("got %d requests, want 3", len(fake.requests))
+	}
+}
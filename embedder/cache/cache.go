@@ -0,0 +1,170 @@
+package cacheembedder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/henomis/lingoose/embedder"
+)
+
+var (
+	ErrCacheEmbed   = errors.New("cache embed error")
+	ErrInvalidEntry = errors.New("invalid embedding entry")
+)
+
+const (
+	keyKindDocument = "document"
+	keyKindQuery    = "query"
+)
+
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error)
+}
+
+type queryEmbedder interface {
+	EmbedQuery(ctx context.Context, query string) (embedder.Embedding, error)
+}
+
+// Store persists the embeddings by key. Get returns only the keys found.
+type Store interface {
+	Get(ctx context.Context, keys []string) (map[string]embedder.Embedding, error)
+	Set(ctx context.Context, embeddings map[string]embedder.Embedding) error
+}
+
+// Cache memoizes the embeddings of an embedder, keyed by the hash of the model and of
+// the text, so that indexing again unchanged documents doesn't call the provider.
+type Cache struct {
+	embedder Embedder
+	model    string
+	store    Store
+}
+
+// New creates a cache of the embeddings of embedder, stored in memory. The model must
+// identify the embeddings, e.g. the provider model name and its dimensions, since it is
+// part of the key: a different model never returns the embeddings of another.
+func New(embedder Embedder, model string) *Cache {
+	return &Cache{
+		embedder: embedder,
+		model:    model,
+		store:    NewMemoryStore(),
+	}
+}
+
+// WithStore sets the store of the embeddings, such as a FileStore or a Redis store.
+func (c *Cache) WithStore(store Store) *Cache {
+	c.store = store
+	return c
+}
+
+// Embed returns the embeddings for the given texts, embedding only the ones not cached.
+func (c *Cache) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	keys := make([]string, len(texts))
+	for i, text := range texts {
+		keys[i] = c.key(keyKindDocument, text)
+	}
+
+	cached, err := c.store.Get(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCacheEmbed, err)
+	}
+
+	// the same text is embedded once even if repeated
+	var missing []string
+	missingKeys := make(map[string]bool)
+	for i, key := range keys {
+		if _, ok := cached[key]; !ok && !missingKeys[key] {
+			missingKeys[key] = true
+			missing = append(missing, texts[i])
+		}
+	}
+
+	if len(missing) > 0 {
+		embeddings, errEmbed := c.embedder.Embed(ctx, missing)
+		if errEmbed != nil {
+			return nil, errEmbed
+		}
+
+		if len(embeddings) != len(missing) {
+			return nil, fmt.Errorf("%w: got %d embeddings for %d texts", ErrCacheEmbed, len(embeddings), len(missing))
+		}
+
+		toStore := make(map[string]embedder.Embedding, len(missing))
+		for i, text := range missing {
+			toStore[c.key(keyKindDocument, text)] = embeddings[i]
+		}
+
+		err = c.store.Set(ctx, toStore)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCacheEmbed, err)
+		}
+
+		if cached == nil {
+			cached = make(map[string]embedder.Embedding, len(toStore))
+		}
+		for key, embedding := range toStore {
+			cached[key] = embedding
+		}
+	}
+
+	embeddings := make([]embedder.Embedding, len(texts))
+	for i, key := range keys {
+		embeddings[i] = cached[key]
+	}
+
+	return embeddings, nil
+}
+
+// EmbedQuery returns the embedding of the query, using the EmbedQuery of the wrapped
+// embedder when available. Queries are cached apart from the documents, since some
+// models embed them differently.
+func (c *Cache) EmbedQuery(ctx context.Context, query string) (embedder.Embedding, error) {
+	key := c.key(keyKindQuery, query)
+
+	cached, err := c.store.Get(ctx, []string{key})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCacheEmbed, err)
+	}
+
+	if embedding, ok := cached[key]; ok {
+		return embedding, nil
+	}
+
+	var embedding embedder.Embedding
+	if queryEmbedder, ok := c.embedder.(queryEmbedder); ok {
+		embedding, err = queryEmbedder.EmbedQuery(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		embeddings, errEmbed := c.embedder.Embed(ctx, []string{query})
+		if errEmbed != nil {
+			return nil, errEmbed
+		}
+
+		if len(embeddings) != 1 {
+			return nil, fmt.Errorf("%w: got %d embeddings for 1 text", ErrCacheEmbed, len(embeddings))
+		}
+		embedding = embeddings[0]
+	}
+
+	err = c.store.Set(ctx, map[string]embedder.Embedding{key: embedding})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCacheEmbed, err)
+	}
+
+	return embedding, nil
+}
+
+func (c *Cache) key(kind, text string) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.model))
+	hash.Write([]byte{0})
+	hash.Write([]byte(kind))
+	hash.Write([]byte{0})
+	hash.Write([]byte(text))
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
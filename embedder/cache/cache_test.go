@@ -0,0 +1,144 @@
+package cacheembedder
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/henomis/lingoose/embedder"
+)
+
+// lengthEmbedder embeds the texts as their length, recording the texts it receives.
+type lengthEmbedder struct {
+	texts []string
+}
+
+func (e *lengthEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	e.texts = append(e.texts, texts...)
+
+	embeddings := make([]embedder.Embedding, len(texts))
+	for i, text := range texts {
+		embeddings[i] = embedder.Embedding{float64(len(text))}
+	}
+
+	return embeddings, nil
+}
+
+// queryLengthEmbedder embeds the queries as their negative length.
+type queryLengthEmbedder struct {
+	lengthEmbedder
+}
+
+func (e *queryLengthEmbedder) EmbedQuery(_ context.Context, query string) (embedder.Embedding, error) {
+	e.texts = append(e.texts, query)
+	return embedder.Embedding{-float64(len(query))}, nil
+}
+
+func TestCache_Embed(t *testing.T) {
+	ctx := context.Background()
+	inner := &lengthEmbedder{}
+	store := NewMemoryStore()
+	cache := New(inner, "model").WithStore(store)
+
+	embeddings, err := cache.Embed(ctx, []string{"a", "bb", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []embedder.Embedding{{1}, {2}, {1}}
+	if !reflect.DeepEqual(embeddings, want) || !reflect.DeepEqual(inner.texts, []string{"a", "bb"}) {
+		t.Fatalf("Embed() = %v after embedding %v", embeddings, inner.texts)
+	}
+
+	embeddings, err = cache.Embed(ctx, []string{"ccc", "bb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []embedder.Embedding{{3}, {2}}
+	if !reflect.DeepEqual(embeddings, want) || !reflect.DeepEqual(inner.texts, []string{"a", "bb", "ccc"}) {
+		t.Fatalf("Embed() = %v after embedding %v", embeddings, inner.texts)
+	}
+
+	// another model doesn't share the embeddings
+	_, err = New(inner, "other").WithStore(store).Embed(ctx, []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.texts) != 4 {
+		t.Fatalf("expected the text to be embedded for another model, got %v", inner.texts)
+	}
+}
+
+func TestCache_EmbedQuery(t *testing.T) {
+	ctx := context.Background()
+	inner := &queryLengthEmbedder{}
+	cache := New(inner, "model")
+
+	for i := 0; i < 2; i++ {
+		embedding, err := cache.EmbedQuery(ctx, "query")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if embedding[0] != -5 {
+			t.Fatalf("EmbedQuery() = %v, want the query embedding", embedding)
+		}
+	}
+
+	// the document embedding of the same text is cached apart
+	embeddings, err := cache.Embed(ctx, []string{"query"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embeddings[0][0] != 5 || len(inner.texts) != 2 {
+		t.Fatalf("Embed() = %v after embedding %v", embeddings, inner.texts)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "embeddings.db")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &lengthEmbedder{}
+	_, err = New(inner, "model").WithStore(store).Embed(ctx, []string{"a", "bb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	embeddings, err := New(inner, "model").WithStore(store).Embed(ctx, []string{"bb", "ccc", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(embeddings, []embedder.Embedding{{2}, {3}, {1}}) || len(inner.texts) != 3 {
+		t.Fatalf("Embed() = %v after embedding %v", embeddings, inner.texts)
+	}
+
+	found, err := store.Get(ctx, []string{"missing"})
+	if err != nil || len(found) != 0 {
+		t.Fatalf("Get() = %v, %v for a missing key", found, err)
+	}
+
+	err = store.db.View(func(tx *bbolt.Tx) error {
+		if n := tx.Bucket(fileStoreBucket).Stats().KeyN; n != 3 {
+			t.Errorf("expected 3 stored embeddings, got %d", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	redigo "github.com/gomodule/redigo/redis"
+
+	"github.com/henomis/lingoose/embedder"
+	cacheembedder "github.com/henomis/lingoose/embedder/cache"
+)
+
+var _ cacheembedder.Store = &Store{}
+
+const (
+	defaultKeyPrefix = "lingoose:embedding:"
+	float64Size      = 8
+)
+
+// Store keeps the embeddings in Redis, shared by the processes using the same server.
+type Store struct {
+	pool      *redigo.Pool
+	keyPrefix string
+	ttl       time.Duration
+}
+
+func New(pool *redigo.Pool) *Store {
+	return &Store{
+		pool:      pool,
+		keyPrefix: defaultKeyPrefix,
+	}
+}
+
+// WithKeyPrefix sets the prefix of the Redis keys, "lingoose:embedding:" by default.
+func (s *Store) WithKeyPrefix(keyPrefix string) *Store {
+	s.keyPrefix = keyPrefix
+	return s
+}
+
+// WithTTL sets the expiration of the embeddings, they never expire by default.
+func (s *Store) WithTTL(ttl time.Duration) *Store {
+	s.ttl = ttl
+	return s
+}
+
+func (s *Store) Get(ctx context.Context, keys []string) (map[string]embedder.Embedding, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	args := make([]any, len(keys))
+	for i, key := range keys {
+		args[i] = s.keyPrefix + key
+	}
+
+	values, err := redigo.ByteSlices(redigo.DoContext(conn, ctx, "MGET", args...))
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]embedder.Embedding)
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+
+		embedding, errDecode := decode(value)
+		if errDecode != nil {
+			return nil, errDecode
+		}
+		found[keys[i]] = embedding
+	}
+
+	return found, nil
+}
+
+func (s *Store) Set(ctx context.Context, embeddings map[string]embedder.Embedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for key, embedding := range embeddings {
+		args := []any{s.keyPrefix + key, encode(embedding)}
+		if s.ttl > 0 {
+			args = append(args, "PX", s.ttl.Milliseconds())
+		}
+
+		if err = conn.Send("SET", args...); err != nil {
+			return err
+		}
+	}
+
+	// an empty command flushes the pipeline and receives all the replies
+	replies, err := redigo.Values(redigo.DoContext(conn, ctx, ""))
+	if err != nil {
+		return err
+	}
+
+	for _, reply := range replies {
+		if replyErr, ok := reply.(redigo.Error); ok {
+			return replyErr
+		}
+	}
+
+	return nil
+}
+
+func encode(embedding embedder.Embedding) []byte {
+	value := make([]byte, len(embedding)*float64Size)
+	for i, f := range embedding {
+		binary.LittleEndian.PutUint64(value[i*float64Size:], math.Float64bits(f))
+	}
+
+	return value
+}
+
+func decode(value []byte) (embedder.Embedding, error) {
+	if len(value)%float64Size != 0 {
+		return nil, fmt.Errorf("invalid embedding of %d bytes", len(value))
+	}
+
+	embedding := make(embedder.Embedding, len(value)/float64Size)
+	for i := range embedding {
+		embedding[i] = math.Float64frombits(binary.LittleEndian.Uint64(value[i*float64Size:]))
+	}
+
+	return embedding, nil
+}
@@ -0,0 +1,153 @@
+package cacheembedder
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/henomis/lingoose/embedder"
+)
+
+// MemoryStore keeps the embeddings in memory, for the lifetime of the process.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	embeddings map[string]embedder.Embedding
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		embeddings: make(map[string]embedder.Embedding),
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, keys []string) (map[string]embedder.Embedding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := make(map[string]embedder.Embedding)
+	for _, key := range keys {
+		if embedding, ok := s.embeddings[key]; ok {
+			found[key] = embedding
+		}
+	}
+
+	return found, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, embeddings map[string]embedder.Embedding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, embedding := range embeddings {
+		s.embeddings[key] = embedding
+	}
+
+	return nil
+}
+
+var fileStoreBucket = []byte("embeddings")
+
+const fileStoreOpenTimeout = time.Second
+
+// FileStore keeps the embeddings in a bbolt database file, so that the cache survives
+// restarts. Embeddings are read from the file by key, they aren't loaded in memory.
+type FileStore struct {
+	db *bbolt.DB
+}
+
+// NewFileStore opens the store file, creating it if missing. The file is locked while
+// open, a second process opening it fails after waiting one second.
+func NewFileStore(path string) (*FileStore, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: fileStoreOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, errBucket := tx.CreateBucketIfNotExists(fileStoreBucket)
+		return errBucket
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &FileStore{
+		db: db,
+	}, nil
+}
+
+func (s *FileStore) Get(_ context.Context, keys []string) (map[string]embedder.Embedding, error) {
+	found := make(map[string]embedder.Embedding)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(fileStoreBucket)
+		for _, key := range keys {
+			value := bucket.Get([]byte(key))
+			if value == nil {
+				continue
+			}
+
+			embedding, err := decodeEmbedding(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			found[key] = embedding
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func (s *FileStore) Set(_ context.Context, embeddings map[string]embedder.Embedding) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(fileStoreBucket)
+		for key, embedding := range embeddings {
+			err := bucket.Put([]byte(key), encodeEmbedding(embedding))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Close closes the store file.
+func (s *FileStore) Close() error {
+	return s.db.Close()
+}
+
+// encodeEmbedding encodes the embedding as little endian float64 values.
+func encodeEmbedding(embedding embedder.Embedding) []byte {
+	value := make([]byte, 0, len(embedding)*8)
+	for _, v := range embedding {
+		value = binary.LittleEndian.AppendUint64(value, math.Float64bits(v))
+	}
+
+	return value
+}
+
+// decodeEmbedding decodes a value written by encodeEmbedding. The value is only valid
+// during the transaction, so the embedding is a copy.
+func decodeEmbedding(value []byte) (embedder.Embedding, error) {
+	if len(value)%8 != 0 {
+		return nil, ErrInvalidEntry
+	}
+
+	embedding := make(embedder.Embedding, len(value)/8)
+	for i := range embedding {
+		embedding[i] = math.Float64frombits(binary.LittleEndian.Uint64(value[i*8:]))
+	}
+
+	return embedding, nil
+}
@@ -0,0 +1,123 @@
+package cohereembedder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/henomis/restclientgo"
+)
+
+type EmbedderModel string
+
+const (
+	EmbedderModelEnglishV20           EmbedderModel = "embed-english-v2.0"
+	EmbedderModelEnglishLightV20      EmbedderModel = "embed-english-light-v2.0"
+	EmbedderModelMultilingualV20      EmbedderModel = "embed-multilingual-v2.0"
+	EmbedderModelEnglishV30           EmbedderModel = "embed-english-v3.0"
+	EmbedderModelEnglishLightV30      EmbedderModel = "embed-english-light-v3.0"
+	EmbedderModelMultilingualV30      EmbedderModel = "embed-multilingual-v3.0"
+	EmbedderModelMultilingualLightV30 EmbedderModel = "embed-multilingual-light-v3.0"
+)
+
+// InputType tells the v3 models what the texts are used for. Documents and queries must
+// be embedded with the matching types for the best retrieval quality.
+type InputType string
+
+const (
+	InputTypeSearchDocument InputType = "search_document"
+	InputTypeSearchQuery    InputType = "search_query"
+	InputTypeClassification InputType = "classification"
+	InputTypeClustering     InputType = "clustering"
+)
+
+// EmbeddingType is the format of the returned embeddings, supported by the v3 models.
+type EmbeddingType string
+
+const (
+	EmbeddingTypeFloat EmbeddingType = "float"
+	// EmbeddingTypeInt8 returns the values quantized to signed 8 bit integers.
+	EmbeddingTypeInt8 EmbeddingType = "int8"
+	// EmbeddingTypeBinary returns one bit per dimension, decoded into 1 and -1 values.
+	EmbeddingTypeBinary EmbeddingType = "ubinary"
+)
+
+type request struct {
+	Texts          []string        `json:"texts"`
+	Model          EmbedderModel   `json:"model"`
+	InputType      InputType       `json:"input_type,omitempty"`
+	EmbeddingTypes []EmbeddingType `json:"embedding_types,omitempty"`
+	Truncate       string          `json:"truncate,omitempty"`
+}
+
+func (r *request) Path() (string, error) {
+	return "/embed", nil
+}
+
+func (r *request) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *request) ContentType() string {
+	return "application/json"
+}
+
+type response struct {
+	HTTPStatusCode    int    `json:"-"`
+	acceptContentType string `json:"-"`
+	ID                string `json:"id"`
+	// Embeddings is a list of float embeddings, or the embeddings by type when the
+	// embedding types are requested.
+	Embeddings json.RawMessage `json:"embeddings"`
+	Meta       meta            `json:"meta"`
+	RawBody    []byte          `json:"-"`
+}
+
+type embeddingsByType struct {
+	Float   [][]float64 `json:"float"`
+	Int8    [][]int8    `json:"int8"`
+	UBinary [][]int     `json:"ubinary"`
+}
+
+type meta struct {
+	BilledUnits struct {
+		InputTokens int `json:"input_tokens"`
+	} `json:"billed_units"`
+}
+
+func (r *response) SetAcceptContentType(contentType string) {
+	r.acceptContentType = contentType
+}
+
+func (r *response) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(r)
+}
+
+func (r *response) SetBody(body io.Reader) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	r.RawBody = b
+	return nil
+}
+
+func (r *response) AcceptContentType() string {
+	if r.acceptContentType != "" {
+		return r.acceptContentType
+	}
+	return "application/json"
+}
+
+func (r *response) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *response) SetHeaders(_ restclientgo.Headers) error { return nil }
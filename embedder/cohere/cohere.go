@@ -2,29 +2,31 @@ package cohereembedder
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"time"
 
-	coherego "github.com/henomis/cohere-go"
-	"github.com/henomis/cohere-go/model"
-	"github.com/henomis/cohere-go/request"
-	"github.com/henomis/cohere-go/response"
+	"github.com/henomis/restclientgo"
 
 	"github.com/henomis/lingoose/embedder"
 	embobserver "github.com/henomis/lingoose/embedder/observer"
+	"github.com/henomis/lingoose/llm/retry"
 )
 
-type EmbedderModel = model.EmbedModel
-
 const (
-	defaultEmbedderModel EmbedderModel = model.EmbedModelEnglishV20
-
-	EmbedderModelEnglishV20           EmbedderModel = model.EmbedModelEnglishV20
-	EmbedderModelEnglishLightV20      EmbedderModel = model.EmbedModelEnglishLightV20
-	EmbedderModelMultilingualV20      EmbedderModel = model.EmbedModelMultilingualV20
-	EmbedderModelEnglishV30           EmbedderModel = model.EmbedModelEnglishV30
-	EmbedderModelEnglishLightV30      EmbedderModel = model.EmbedModelEnglishLightV30
-	EmbedderModelMultilingualV30      EmbedderModel = model.EmbedModelMultilingualV30
-	EmbedderModelMultilingualLightV30 EmbedderModel = model.EmbedModelMultilingualLightV30
+	defaultEndpoint                    = "https://api.cohere.ai/v1"
+	defaultEmbedderModel EmbedderModel = EmbedderModelEnglishV20
+)
+
+// MaxBatchItems is the maximum number of texts of an embed request, to be used with a
+// batchembedder.Batcher.
+const MaxBatchItems = 96
+
+var (
+	ErrCohereEmbed = errors.New("cohere embed error")
 )
 
 var EmbedderModelsSize = map[EmbedderModel]int{
@@ -38,22 +40,33 @@ var EmbedderModelsSize = map[EmbedderModel]int{
 }
 
 type Embedder struct {
-	model  EmbedderModel
-	client *coherego.Client
-	name   string
+	model         EmbedderModel
+	inputType     InputType
+	embeddingType EmbeddingType
+	restClient    *restclientgo.RestClient
+	name          string
+	retryPolicy   retry.Policy
+	timeout       time.Duration
 }
 
 func New() *Embedder {
-	return &Embedder{
-		client: coherego.New(os.Getenv("COHERE_API_KEY")),
-		model:  defaultEmbedderModel,
-		name:   "cohere",
+	e := &Embedder{
+		restClient: restclientgo.New(defaultEndpoint),
+		model:      defaultEmbedderModel,
+		name:       "cohere",
 	}
+
+	return e.WithAPIKey(os.Getenv("COHERE_API_KEY"))
 }
 
 // WithAPIKey sets the API key to use for the embedder
 func (e *Embedder) WithAPIKey(apiKey string) *Embedder {
-	e.client = coherego.New(apiKey)
+	e.restClient.SetRequestModifier(
+		func(req *http.Request) *http.Request {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+			return req
+		},
+	)
 	return e
 }
 
@@ -63,8 +76,78 @@ func (e *Embedder) WithModel(model EmbedderModel) *Embedder {
 	return e
 }
 
+// WithInputType sets the input type of the texts embedded with Embed. The v3 models
+// require it; when not set, Embed uses InputTypeSearchDocument with v3 models and
+// EmbedQuery always uses InputTypeSearchQuery, as recommended by Cohere for retrieval.
+func (e *Embedder) WithInputType(inputType InputType) *Embedder {
+	e.inputType = inputType
+	return e
+}
+
+// WithEmbeddingType sets the format of the embeddings returned by the v3 models, float
+// by default. Quantized embeddings are returned as float64 values: int8 embeddings keep
+// their integer values, binary embeddings are made of 1 and -1 values.
+func (e *Embedder) WithEmbeddingType(embeddingType EmbeddingType) *Embedder {
+	e.embeddingType = embeddingType
+	return e
+}
+
+// WithHTTPClient sets the http client to use for the embedder
+func (e *Embedder) WithHTTPClient(httpClient *http.Client) *Embedder {
+	e.restClient.SetHTTPClient(httpClient)
+	return e
+}
+
+// WithRetry retries the requests failing with a rate limit, a server error or a timeout,
+// with the backoff of the policy.
+func (e *Embedder) WithRetry(policy retry.Policy) *Embedder {
+	e.retryPolicy = policy
+	return e
+}
+
+// WithTimeout bounds each request, a timed out request is retried as set by WithRetry.
+func (e *Embedder) WithTimeout(timeout time.Duration) *Embedder {
+	e.timeout = timeout
+	return e
+}
+
+// WithEndpoint sets the API endpoint, https://api.cohere.ai/v1 by default.
+func (e *Embedder) WithEndpoint(endpoint string) *Embedder {
+	e.restClient.SetEndpoint(endpoint)
+	return e
+}
+
 // Embed returns the embeddings for the given texts
 func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	inputType := e.inputType
+	if inputType == "" && e.isV3() {
+		inputType = InputTypeSearchDocument
+	}
+
+	return e.observedEmbed(ctx, texts, inputType)
+}
+
+// EmbedQuery returns the embedding of a search query, embedded with the search_query
+// input type by the v3 models. The index uses it to embed the queries.
+func (e *Embedder) EmbedQuery(ctx context.Context, query string) (embedder.Embedding, error) {
+	var inputType InputType
+	if e.isV3() {
+		inputType = InputTypeSearchQuery
+	}
+
+	embeddings, err := e.observedEmbed(ctx, []string{query}, inputType)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("%w: no embedding returned", ErrCohereEmbed)
+	}
+
+	return embeddings[0], nil
+}
+
+func (e *Embedder) observedEmbed(ctx context.Context, texts []string, inputType InputType) ([]embedder.Embedding, error) {
 	observerEmbedding, err := embobserver.StartObserveEmbedding(
 		ctx,
 		e.name,
@@ -76,7 +159,7 @@ func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedd
 		return nil, err
 	}
 
-	embeddings, err := e.embed(ctx, texts)
+	embeddings, err := e.embed(ctx, texts, inputType)
 	if err != nil {
 		return nil, err
 	}
@@ -93,24 +176,88 @@ func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedd
 	return embeddings, nil
 }
 
-func (e *Embedder) embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
-	resp := &response.Embed{}
-	err := e.client.Embed(
-		ctx,
-		&request.Embed{
-			Texts: texts,
-			Model: e.model,
-		},
-		resp,
-	)
+func (e *Embedder) embed(ctx context.Context, texts []string, inputType InputType) ([]embedder.Embedding, error) {
+	req := &request{
+		Texts:     texts,
+		Model:     e.model,
+		InputType: inputType,
+	}
+
+	// the embedding types are not supported by the v2 models
+	var embeddingType EmbeddingType
+	if e.isV3() {
+		embeddingType = e.embeddingType
+		if embeddingType == "" {
+			embeddingType = EmbeddingTypeFloat
+		}
+		req.EmbeddingTypes = []EmbeddingType{embeddingType}
+	}
+
+	resp, err := e.post(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrCohereEmbed, err)
+	}
+
+	embeddings, err := decodeEmbeddings(resp.Embeddings, embeddingType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCohereEmbed, err)
+	}
+
+	return embeddings, nil
+}
+
+func (e *Embedder) isV3() bool {
+	switch e.model {
+	case EmbedderModelEnglishV20, EmbedderModelEnglishLightV20, EmbedderModelMultilingualV20:
+		return false
+	default:
+		return true
+	}
+}
+
+func decodeEmbeddings(data []byte, embeddingType EmbeddingType) ([]embedder.Embedding, error) {
+	var embeddings []embedder.Embedding
+	if embeddingType == "" {
+		err := json.Unmarshal(data, &embeddings)
+		return embeddings, err
 	}
 
-	embeddings := make([]embedder.Embedding, len(resp.Embeddings))
+	var r embeddingsByType
+	err := json.Unmarshal(data, &r)
+	if err != nil {
+		return nil, err
+	}
 
-	for i, embedding := range resp.Embeddings {
-		embeddings[i] = embedding
+	switch embeddingType {
+	case EmbeddingTypeInt8:
+		for _, values := range r.Int8 {
+			embedding := make(embedder.Embedding, len(values))
+			for i, v := range values {
+				embedding[i] = float64(v)
+			}
+			embeddings = append(embeddings, embedding)
+		}
+	case EmbeddingTypeBinary:
+		for _, values := range r.UBinary {
+			packed := make([]byte, len(values))
+			for i, v := range values {
+				packed[i] = byte(v)
+			}
+			embeddings = append(embeddings, embedder.NewEmbeddingFromBinary(packed, len(packed)*8))
+		}
+	case EmbeddingTypeFloat:
+		for _, values := range r.Float {
+			embeddings = append(embeddings, values)
+		}
 	}
+
 	return embeddings, nil
 }
+
+// post sends the request with the retry policy, see retry.Post.
+func (e *Embedder) post(ctx context.Context, req *request) (*response, error) {
+	policy := e.retryPolicy
+	policy.AttemptTimeout = e.timeout
+
+	return retry.Post[response](ctx, e.restClient, policy, req)
+}
@@ -0,0 +1,125 @@
+package cohereembedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/index"
+)
+
+func newTestEmbedder(t *testing.T, handler func(req request) string) *Embedder {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embed" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req request
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(handler(req)))
+	}))
+	t.Cleanup(server.Close)
+
+	return New().WithAPIKey("key").WithEndpoint(server.URL)
+}
+
+func TestEmbedInputTypes(t *testing.T) {
+	var inputTypes []InputType
+	e := newTestEmbedder(t, func(req request) string {
+		inputTypes = append(inputTypes, req.InputType)
+		if !reflect.DeepEqual(req.EmbeddingTypes, []EmbeddingType{EmbeddingTypeFloat}) {
+			t.Errorf("embedding types = %v", req.EmbeddingTypes)
+		}
+		return `{"embeddings":{"float":[[0.1,0.2]]}}`
+	}).WithModel(EmbedderModelEnglishV30)
+
+	_, err := e.Embed(context.Background(), []string{"document"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the index embeds the queries as search queries
+	embedding, err := index.EmbedQuery(context.Background(), e, "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(embedding, embedder.Embedding{0.1, 0.2}) {
+		t.Errorf("embedding = %v", embedding)
+	}
+
+	want := []InputType{InputTypeSearchDocument, InputTypeSearchQuery}
+	if !reflect.DeepEqual(inputTypes, want) {
+		t.Errorf("input types = %v, want %v", inputTypes, want)
+	}
+}
+
+func TestEmbedV2(t *testing.T) {
+	e := newTestEmbedder(t, func(req request) string {
+		if req.InputType != "" || req.EmbeddingTypes != nil {
+			t.Errorf("v2 request with input type %q and embedding types %v", req.InputType, req.EmbeddingTypes)
+		}
+		return `{"embeddings":[[0.5,0.5]]}`
+	}).WithModel(EmbedderModelEnglishV20).WithEmbeddingType(EmbeddingTypeInt8)
+
+	embeddings, err := e.Embed(context.Background(), []string{"document"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(embeddings, []embedder.Embedding{{0.5, 0.5}}) {
+		t.Errorf("embeddings = %v", embeddings)
+	}
+}
+
+func TestEmbedQuantized(t *testing.T) {
+	tests := []struct {
+		embeddingType EmbeddingType
+		response      string
+		want          []embedder.Embedding
+	}{
+		{
+			embeddingType: EmbeddingTypeInt8,
+			response:      `{"embeddings":{"int8":[[-128,0,127]]}}`,
+			want:          []embedder.Embedding{{-128, 0, 127}},
+		},
+		{
+			embeddingType: EmbeddingTypeBinary,
+			response:      `{"embeddings":{"ubinary":[[160]]}}`,
+			want:          []embedder.Embedding{{1, -1, 1, -1, -1, -1, -1, -1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.embeddingType), func(t *testing.T) {
+			e := newTestEmbedder(t, func(req request) string {
+				if !reflect.DeepEqual(req.EmbeddingTypes, []EmbeddingType{tt.embeddingType}) {
+					t.Errorf("embedding types = %v", req.EmbeddingTypes)
+				}
+				return tt.response
+			}).WithModel(EmbedderModelMultilingualV30).WithEmbeddingType(tt.embeddingType)
+
+			embeddings, err := e.Embed(context.Background(), []string{"document"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(embeddings, tt.want) {
+				t.Errorf("embeddings = %v, want %v", embeddings, tt.want)
+			}
+		})
+	}
+}
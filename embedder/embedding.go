@@ -1,5 +1,10 @@
 package embedder
 
+import (
+	"math"
+	"math/bits"
+)
+
 var (
 	ErrCreateEmbedding = "unable to create embedding"
 )
@@ -15,3 +20,116 @@ func (e Embedding) ToFloat32() []float32 {
 
 	return vect
 }
+
+// ToFloat16 returns the embedding encoded as IEEE 754 half precision values.
+func (e Embedding) ToFloat16() []uint16 {
+	vect := make([]uint16, len(e))
+	for i, v := range e {
+		vect[i] = float32ToFloat16(float32(v))
+	}
+
+	return vect
+}
+
+// ToBinary returns the embedding quantized to one bit per dimension. A bit is set
+// when the related value is positive. Bits are packed most significant first.
+func (e Embedding) ToBinary() []byte {
+	vect := make([]byte, (len(e)+7)/8)
+	for i, v := range e {
+		if v > 0 {
+			vect[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+
+	return vect
+}
+
+// NewEmbeddingFromFloat16 decodes half precision values into an embedding.
+func NewEmbeddingFromFloat16(vect []uint16) Embedding {
+	e := make(Embedding, len(vect))
+	for i, v := range vect {
+		e[i] = float64(float16ToFloat32(v))
+	}
+
+	return e
+}
+
+// NewEmbeddingFromBinary decodes a binary quantized vector of the given dimension
+// into an embedding of 1 and -1 values.
+func NewEmbeddingFromBinary(vect []byte, dimension int) Embedding {
+	e := make(Embedding, dimension)
+	for i := range e {
+		if vect[i/8]&(0x80>>(i%8)) != 0 {
+			e[i] = 1
+		} else {
+			e[i] = -1
+		}
+	}
+
+	return e
+}
+
+// HammingDistance returns the number of different bits between two binary vectors.
+func HammingDistance(a, b []byte) int {
+	distance := 0
+	for i := 0; i < len(a) && i < len(b); i++ {
+		distance += bits.OnesCount8(a[i] ^ b[i])
+	}
+
+	return distance
+}
+
+func float32ToFloat16(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int((b>>23)&0xff) - 127 + 15
+	mant := b & 0x7fffff
+
+	switch {
+	case (b>>23)&0xff == 0xff:
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp >= 0x1f:
+		return sign | 0x7c00
+	case exp <= 0:
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint32(14 - exp)
+		half := mant >> shift
+		if (mant>>(shift-1))&1 != 0 {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	half := sign | uint16(exp<<10) | uint16(mant>>13)
+	if mant&0x1000 != 0 {
+		// rounding may carry into the exponent, which is still correct
+		half++
+	}
+
+	return half
+}
+
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+
+	return math.Float32frombits(sign | (exp+112)<<23 | mant<<13)
+}
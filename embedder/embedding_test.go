@@ -0,0 +1,50 @@
+package embedder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmbedding_Float16(t *testing.T) {
+	tests := []struct {
+		name string
+		e    Embedding
+		want Embedding
+	}{
+		{
+			name: "Test 1",
+			e:    Embedding{0, 1, -2, 0.5, 65504},
+			want: Embedding{0, 1, -2, 0.5, 65504},
+		},
+		{
+			name: "Test 2",
+			e:    Embedding{0.1, 1e-7, 1e6},
+			want: Embedding{0.0999755859375, 1.1920928955078125e-07, NewEmbeddingFromFloat16([]uint16{0x7c00})[0]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewEmbeddingFromFloat16(tt.e.ToFloat16()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewEmbeddingFromFloat16(ToFloat16()) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbedding_Binary(t *testing.T) {
+	e := Embedding{0.3, -0.1, 0, 0.7, 0.1, -1, -1, 1, 0.2}
+
+	binary := e.ToBinary()
+	if !reflect.DeepEqual(binary, []byte{0x99, 0x80}) {
+		t.Fatalf("ToBinary() = %x, want 9980", binary)
+	}
+
+	want := Embedding{1, -1, -1, 1, 1, -1, -1, 1, 1}
+	if got := NewEmbeddingFromBinary(binary, len(e)); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewEmbeddingFromBinary() = %v, want %v", got, want)
+	}
+
+	if got := HammingDistance(binary, Embedding{-1, -1, 0, 1, 1, -1, -1, 1, -1}.ToBinary()); got != 2 {
+		t.Errorf("HammingDistance() = %d, want 2", got)
+	}
+}
@@ -0,0 +1,114 @@
+package googleaiembedder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/restclientgo"
+)
+
+type Model string
+
+const (
+	ModelTextEmbedding004   Model = "text-embedding-004"
+	ModelGeminiEmbedding001 Model = "gemini-embedding-001"
+	ModelEmbedding001       Model = "embedding-001"
+	ModelGeminiEmbeddingExp Model = "gemini-embedding-exp-03-07"
+)
+
+// TaskType optimizes the embeddings for their downstream use. Documents and queries must
+// be embedded with the matching retrieval task types for the best retrieval quality.
+type TaskType string
+
+const (
+	TaskTypeRetrievalQuery     TaskType = "RETRIEVAL_QUERY"
+	TaskTypeRetrievalDocument  TaskType = "RETRIEVAL_DOCUMENT"
+	TaskTypeSemanticSimilarity TaskType = "SEMANTIC_SIMILARITY"
+	TaskTypeClassification     TaskType = "CLASSIFICATION"
+	TaskTypeClustering         TaskType = "CLUSTERING"
+	TaskTypeQuestionAnswering  TaskType = "QUESTION_ANSWERING"
+	TaskTypeFactVerification   TaskType = "FACT_VERIFICATION"
+	TaskTypeCodeRetrievalQuery TaskType = "CODE_RETRIEVAL_QUERY"
+)
+
+type request struct {
+	model    Model
+	Requests []embedContentRequest `json:"requests"`
+}
+
+type embedContentRequest struct {
+	Model                string   `json:"model"`
+	Content              content  `json:"content"`
+	TaskType             TaskType `json:"taskType,omitempty"`
+	OutputDimensionality int      `json:"outputDimensionality,omitempty"`
+}
+
+type content struct {
+	Parts []part `json:"parts"`
+}
+
+type part struct {
+	Text string `json:"text"`
+}
+
+func (r *request) Path() (string, error) {
+	return "/models/" + string(r.model) + ":batchEmbedContents", nil
+}
+
+func (r *request) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *request) ContentType() string {
+	return "application/json"
+}
+
+type response struct {
+	HTTPStatusCode    int                `json:"-"`
+	acceptContentType string             `json:"-"`
+	Embeddings        []contentEmbedding `json:"embeddings"`
+	RawBody           []byte             `json:"-"`
+}
+
+type contentEmbedding struct {
+	Values embedder.Embedding `json:"values"`
+}
+
+func (r *response) SetAcceptContentType(contentType string) {
+	r.acceptContentType = contentType
+}
+
+func (r *response) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(r)
+}
+
+func (r *response) SetBody(body io.Reader) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	r.RawBody = b
+	return nil
+}
+
+func (r *response) AcceptContentType() string {
+	if r.acceptContentType != "" {
+		return r.acceptContentType
+	}
+	return "application/json"
+}
+
+func (r *response) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *response) SetHeaders(_ restclientgo.Headers) error { return nil }
@@ -0,0 +1,205 @@
+package googleaiembedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/embedder"
+	embobserver "github.com/henomis/lingoose/embedder/observer"
+	"github.com/henomis/lingoose/llm/retry"
+)
+
+const (
+	defaultEndpoint       = "https://generativelanguage.googleapis.com/v1beta"
+	defaultModel          = ModelTextEmbedding004
+	maxBatchEmbedRequests = 100
+)
+
+var (
+	ErrGoogleAIEmbed = errors.New("google ai embed error")
+)
+
+type Embedder struct {
+	model                Model
+	taskType             TaskType
+	outputDimensionality int
+	restClient           *restclientgo.RestClient
+	name                 string
+	retryPolicy          retry.Policy
+	timeout              time.Duration
+}
+
+func New() *Embedder {
+	e := &Embedder{
+		restClient: restclientgo.New(defaultEndpoint),
+		model:      defaultModel,
+		name:       "googleai",
+	}
+
+	return e.WithAPIKey(os.Getenv("GEMINI_API_KEY"))
+}
+
+// WithAPIKey sets the API key to use for the embedder
+func (e *Embedder) WithAPIKey(apiKey string) *Embedder {
+	e.restClient.SetRequestModifier(
+		func(req *http.Request) *http.Request {
+			req.Header.Set("x-goog-api-key", apiKey)
+			return req
+		},
+	)
+	return e
+}
+
+// WithModel sets the model to use for the embedder
+func (e *Embedder) WithModel(model Model) *Embedder {
+	e.model = model
+	return e
+}
+
+// WithTaskType sets the task type of the texts embedded with Embed. When not set, Embed
+// uses TaskTypeRetrievalDocument and EmbedQuery always uses TaskTypeRetrievalQuery.
+func (e *Embedder) WithTaskType(taskType TaskType) *Embedder {
+	e.taskType = taskType
+	return e
+}
+
+// WithOutputDimensionality truncates the embeddings to the given number of dimensions,
+// supported by the models newer than embedding-001.
+func (e *Embedder) WithOutputDimensionality(outputDimensionality int) *Embedder {
+	e.outputDimensionality = outputDimensionality
+	return e
+}
+
+// WithHTTPClient sets the http client to use for the embedder
+func (e *Embedder) WithHTTPClient(httpClient *http.Client) *Embedder {
+	e.restClient.SetHTTPClient(httpClient)
+	return e
+}
+
+// WithRetry retries the requests failing with a rate limit, a server error or a timeout,
+// with the backoff of the policy.
+func (e *Embedder) WithRetry(policy retry.Policy) *Embedder {
+	e.retryPolicy = policy
+	return e
+}
+
+// WithTimeout bounds each request, a timed out request is retried as set by WithRetry.
+func (e *Embedder) WithTimeout(timeout time.Duration) *Embedder {
+	e.timeout = timeout
+	return e
+}
+
+// WithEndpoint sets the API endpoint, https://generativelanguage.googleapis.com/v1beta by
+// default.
+func (e *Embedder) WithEndpoint(endpoint string) *Embedder {
+	e.restClient.SetEndpoint(endpoint)
+	return e
+}
+
+// Embed returns the embeddings for the given texts
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	taskType := e.taskType
+	if taskType == "" {
+		taskType = TaskTypeRetrievalDocument
+	}
+
+	return e.observedEmbed(ctx, texts, taskType)
+}
+
+// EmbedQuery returns the embedding of a search query, embedded with the RETRIEVAL_QUERY
+// task type. The index uses it to embed the queries.
+func (e *Embedder) EmbedQuery(ctx context.Context, query string) (embedder.Embedding, error) {
+	embeddings, err := e.observedEmbed(ctx, []string{query}, TaskTypeRetrievalQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("%w: no embedding returned", ErrGoogleAIEmbed)
+	}
+
+	return embeddings[0], nil
+}
+
+func (e *Embedder) observedEmbed(ctx context.Context, texts []string, taskType TaskType) ([]embedder.Embedding, error) {
+	observerEmbedding, err := embobserver.StartObserveEmbedding(
+		ctx,
+		e.name,
+		string(e.model),
+		nil,
+		texts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var embeddings []embedder.Embedding
+	for start := 0; start < len(texts); start += maxBatchEmbedRequests {
+		end := start + maxBatchEmbedRequests
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		batchEmbeddings, embedErr := e.embed(ctx, texts[start:end], taskType)
+		if embedErr != nil {
+			return nil, embedErr
+		}
+		embeddings = append(embeddings, batchEmbeddings...)
+	}
+
+	err = embobserver.StopObserveEmbedding(
+		ctx,
+		observerEmbedding,
+		embeddings,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return embeddings, nil
+}
+
+func (e *Embedder) embed(ctx context.Context, texts []string, taskType TaskType) ([]embedder.Embedding, error) {
+	req := &request{
+		model:    e.model,
+		Requests: make([]embedContentRequest, len(texts)),
+	}
+	for i, text := range texts {
+		req.Requests[i] = embedContentRequest{
+			Model:                "models/" + string(e.model),
+			Content:              content{Parts: []part{{Text: text}}},
+			TaskType:             taskType,
+			OutputDimensionality: e.outputDimensionality,
+		}
+	}
+
+	resp, err := e.post(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGoogleAIEmbed, err)
+	}
+
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("%w: %d embeddings returned for %d texts", ErrGoogleAIEmbed, len(resp.Embeddings), len(texts))
+	}
+
+	embeddings := make([]embedder.Embedding, len(resp.Embeddings))
+	for i, embedding := range resp.Embeddings {
+		embeddings[i] = embedding.Values
+	}
+
+	return embeddings, nil
+}
+
+// post sends the request with the retry policy, see retry.Post.
+func (e *Embedder) post(ctx context.Context, req *request) (*response, error) {
+	policy := e.retryPolicy
+	policy.AttemptTimeout = e.timeout
+
+	return retry.Post[response](ctx, e.restClient, policy, req)
+}
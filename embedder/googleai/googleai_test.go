@@ -0,0 +1,109 @@
+package googleaiembedder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/index"
+)
+
+func newTestEmbedder(t *testing.T, handler func(req request)) *Embedder {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/"+string(ModelTextEmbedding004)+":batchEmbedContents" ||
+			r.Header.Get("x-goog-api-key") != "key" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"message":"not found"}}`))
+			return
+		}
+
+		var req request
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		handler(req)
+
+		embeddings := make([]string, len(req.Requests))
+		for j, embedRequest := range req.Requests {
+			embeddings[j] = fmt.Sprintf(`{"values":[%d,%d]}`, len(embedRequest.Content.Parts[0].Text), j)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"embeddings":[` + strings.Join(embeddings, ",") + `]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return New().WithAPIKey("key").WithEndpoint(server.URL)
+}
+
+func TestEmbedTaskTypes(t *testing.T) {
+	var taskTypes []TaskType
+	e := newTestEmbedder(t, func(req request) {
+		for _, embedRequest := range req.Requests {
+			if embedRequest.Model != "models/"+string(ModelTextEmbedding004) || embedRequest.OutputDimensionality != 256 {
+				t.Errorf("request = %+v", embedRequest)
+			}
+		}
+		taskTypes = append(taskTypes, req.Requests[0].TaskType)
+	}).WithOutputDimensionality(256)
+
+	embeddings, err := e.Embed(context.Background(), []string{"a", "bb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(embeddings, []embedder.Embedding{{1, 0}, {2, 1}}) {
+		t.Errorf("embeddings = %v", embeddings)
+	}
+
+	_, err = index.EmbedQuery(context.Background(), e, "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []TaskType{TaskTypeRetrievalDocument, TaskTypeRetrievalQuery}
+	if !reflect.DeepEqual(taskTypes, want) {
+		t.Errorf("task types = %v, want %v", taskTypes, want)
+	}
+}
+
+func TestEmbedBatches(t *testing.T) {
+	var batches []int
+	e := newTestEmbedder(t, func(req request) {
+		batches = append(batches, len(req.Requests))
+	})
+
+	texts := make([]string, 250)
+	for j := range texts {
+		texts[j] = "text"
+	}
+
+	embeddings, err := e.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(embeddings) != len(texts) || !reflect.DeepEqual(batches, []int{100, 100, 50}) {
+		t.Errorf("%d embeddings in batches %v", len(embeddings), batches)
+	}
+}
+
+func TestEmbedError(t *testing.T) {
+	e := newTestEmbedder(t, func(request) {}).WithModel(ModelGeminiEmbedding001)
+
+	_, err := e.Embed(context.Background(), []string{"text"})
+	if !errors.Is(err, ErrGoogleAIEmbed) {
+		t.Errorf("error = %v, want %v", err, ErrGoogleAIEmbed)
+	}
+}
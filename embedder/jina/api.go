@@ -0,0 +1,113 @@
+package jinaembedder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/restclientgo"
+)
+
+type EmbedderModel string
+
+const (
+	EmbedderModelEmbeddingsV3         EmbedderModel = "jina-embeddings-v3"
+	EmbedderModelEmbeddingsV2BaseEN   EmbedderModel = "jina-embeddings-v2-base-en"
+	EmbedderModelEmbeddingsV2BaseDE   EmbedderModel = "jina-embeddings-v2-base-de"
+	EmbedderModelEmbeddingsV2BaseES   EmbedderModel = "jina-embeddings-v2-base-es"
+	EmbedderModelEmbeddingsV2BaseZH   EmbedderModel = "jina-embeddings-v2-base-zh"
+	EmbedderModelEmbeddingsV2BaseCode EmbedderModel = "jina-embeddings-v2-base-code"
+	EmbedderModelClipV1               EmbedderModel = "jina-clip-v1"
+	EmbedderModelEmbeddingsV2SmallEN  EmbedderModel = "jina-embeddings-v2-small-en"
+)
+
+// Task adapts the v3 embeddings to their downstream use. Documents and queries must be
+// embedded with the matching retrieval tasks for the best retrieval quality.
+type Task string
+
+const (
+	TaskRetrievalQuery   Task = "retrieval.query"
+	TaskRetrievalPassage Task = "retrieval.passage"
+	TaskSeparation       Task = "separation"
+	TaskClassification   Task = "classification"
+	TaskTextMatching     Task = "text-matching"
+)
+
+type request struct {
+	Model        EmbedderModel `json:"model"`
+	Input        []string      `json:"input"`
+	Task         Task          `json:"task,omitempty"`
+	Dimensions   int           `json:"dimensions,omitempty"`
+	LateChunking bool          `json:"late_chunking,omitempty"`
+}
+
+func (r *request) Path() (string, error) {
+	return "/embeddings", nil
+}
+
+func (r *request) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *request) ContentType() string {
+	return "application/json"
+}
+
+type response struct {
+	HTTPStatusCode    int    `json:"-"`
+	acceptContentType string `json:"-"`
+	Model             string `json:"model"`
+	Object            string `json:"object"`
+	Data              []data `json:"data"`
+	Usage             usage  `json:"usage"`
+	RawBody           []byte `json:"-"`
+}
+
+type data struct {
+	Object    string             `json:"object"`
+	Index     int                `json:"index"`
+	Embedding embedder.Embedding `json:"embedding"`
+}
+
+type usage struct {
+	TotalTokens  int `json:"total_tokens"`
+	PromptTokens int `json:"prompt_tokens"`
+}
+
+func (r *response) SetAcceptContentType(contentType string) {
+	r.acceptContentType = contentType
+}
+
+func (r *response) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(r)
+}
+
+func (r *response) SetBody(body io.Reader) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	r.RawBody = b
+	return nil
+}
+
+func (r *response) AcceptContentType() string {
+	if r.acceptContentType != "" {
+		return r.acceptContentType
+	}
+	return "application/json"
+}
+
+func (r *response) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *response) SetHeaders(_ restclientgo.Headers) error { return nil }
@@ -0,0 +1,224 @@
+package jinaembedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/embedder"
+	embobserver "github.com/henomis/lingoose/embedder/observer"
+	"github.com/henomis/lingoose/llm/retry"
+)
+
+const (
+	defaultEndpoint                    = "https://api.jina.ai/v1"
+	defaultEmbedderModel EmbedderModel = EmbedderModelEmbeddingsV3
+)
+
+var (
+	ErrJinaEmbed = errors.New("jina embed error")
+)
+
+type Embedder struct {
+	model        EmbedderModel
+	task         Task
+	dimensions   int
+	lateChunking bool
+	restClient   *restclientgo.RestClient
+	name         string
+	retryPolicy  retry.Policy
+	timeout      time.Duration
+}
+
+func New() *Embedder {
+	e := &Embedder{
+		restClient: restclientgo.New(defaultEndpoint),
+		model:      defaultEmbedderModel,
+		name:       "jina",
+	}
+
+	return e.WithAPIKey(os.Getenv("JINA_API_KEY"))
+}
+
+// WithAPIKey sets the API key to use for the embedder
+func (e *Embedder) WithAPIKey(apiKey string) *Embedder {
+	e.restClient.SetRequestModifier(
+		func(req *http.Request) *http.Request {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+			return req
+		},
+	)
+	return e
+}
+
+// WithModel sets the model to use for the embedder
+func (e *Embedder) WithModel(model EmbedderModel) *Embedder {
+	e.model = model
+	return e
+}
+
+// WithTask sets the task of the texts embedded with Embed, supported by the v3 model.
+// When not set, Embed uses TaskRetrievalPassage with the v3 model and EmbedQuery always
+// uses TaskRetrievalQuery.
+func (e *Embedder) WithTask(task Task) *Embedder {
+	e.task = task
+	return e
+}
+
+// WithDimensions truncates the v3 embeddings to the given number of dimensions.
+func (e *Embedder) WithDimensions(dimensions int) *Embedder {
+	e.dimensions = dimensions
+	return e
+}
+
+// WithLateChunking enables the late chunking: the chunks passed to EmbedChunks are
+// concatenated and embedded as a whole document, then an embedding is returned for each
+// chunk, keeping the context of the surrounding text. The index uses EmbedChunks for the
+// chunks of the same document, see textsplitter.MetadataChunkDocument.
+func (e *Embedder) WithLateChunking(lateChunking bool) *Embedder {
+	e.lateChunking = lateChunking
+	return e
+}
+
+// WithHTTPClient sets the http client to use for the embedder
+func (e *Embedder) WithHTTPClient(httpClient *http.Client) *Embedder {
+	e.restClient.SetHTTPClient(httpClient)
+	return e
+}
+
+// WithRetry retries the requests failing with a rate limit, a server error or a timeout,
+// with the backoff of the policy.
+func (e *Embedder) WithRetry(policy retry.Policy) *Embedder {
+	e.retryPolicy = policy
+	return e
+}
+
+// WithTimeout bounds each request, a timed out request is retried as set by WithRetry.
+func (e *Embedder) WithTimeout(timeout time.Duration) *Embedder {
+	e.timeout = timeout
+	return e
+}
+
+// WithEndpoint sets the API endpoint, https://api.jina.ai/v1 by default.
+func (e *Embedder) WithEndpoint(endpoint string) *Embedder {
+	e.restClient.SetEndpoint(endpoint)
+	return e
+}
+
+// Embed returns the embeddings for the given texts
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	return e.observedEmbed(ctx, texts, e.passageTask(), false)
+}
+
+// EmbedQuery returns the embedding of a search query, embedded with the retrieval.query
+// task by the v3 model. The index uses it to embed the queries.
+func (e *Embedder) EmbedQuery(ctx context.Context, query string) (embedder.Embedding, error) {
+	var task Task
+	if e.model == EmbedderModelEmbeddingsV3 {
+		task = TaskRetrievalQuery
+	}
+
+	embeddings, err := e.observedEmbed(ctx, []string{query}, task, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("%w: no embedding returned", ErrJinaEmbed)
+	}
+
+	return embeddings[0], nil
+}
+
+// EmbedChunks returns the embeddings of the chunks of a document, embedded with late
+// chunking when enabled, otherwise it's the same as Embed.
+func (e *Embedder) EmbedChunks(ctx context.Context, chunks []string) ([]embedder.Embedding, error) {
+	return e.observedEmbed(ctx, chunks, e.passageTask(), e.lateChunking)
+}
+
+func (e *Embedder) passageTask() Task {
+	if e.task == "" && e.model == EmbedderModelEmbeddingsV3 {
+		return TaskRetrievalPassage
+	}
+
+	return e.task
+}
+
+func (e *Embedder) observedEmbed(
+	ctx context.Context,
+	texts []string,
+	task Task,
+	lateChunking bool,
+) ([]embedder.Embedding, error) {
+	observerEmbedding, err := embobserver.StartObserveEmbedding(
+		ctx,
+		e.name,
+		string(e.model),
+		nil,
+		texts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings, err := e.embed(ctx, texts, task, lateChunking)
+	if err != nil {
+		return nil, err
+	}
+
+	err = embobserver.StopObserveEmbedding(
+		ctx,
+		observerEmbedding,
+		embeddings,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return embeddings, nil
+}
+
+func (e *Embedder) embed(ctx context.Context, texts []string, task Task, lateChunking bool) ([]embedder.Embedding, error) {
+	resp, err := e.post(
+		ctx,
+		&request{
+			Model:        e.model,
+			Input:        texts,
+			Task:         task,
+			Dimensions:   e.dimensions,
+			LateChunking: lateChunking,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrJinaEmbed, err)
+	}
+
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("%w: %d embeddings returned for %d texts", ErrJinaEmbed, len(resp.Data), len(texts))
+	}
+
+	sort.Slice(resp.Data, func(i, j int) bool {
+		return resp.Data[i].Index < resp.Data[j].Index
+	})
+
+	embeddings := make([]embedder.Embedding, len(resp.Data))
+	for i, data := range resp.Data {
+		embeddings[i] = data.Embedding
+	}
+
+	return embeddings, nil
+}
+
+// post sends the request with the retry policy, see retry.Post.
+func (e *Embedder) post(ctx context.Context, req *request) (*response, error) {
+	policy := e.retryPolicy
+	policy.AttemptTimeout = e.timeout
+
+	return retry.Post[response](ctx, e.restClient, policy, req)
+}
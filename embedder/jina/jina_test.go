@@ -0,0 +1,144 @@
+package jinaembedder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/llm/retry"
+	"github.com/henomis/lingoose/textsplitter"
+)
+
+func newTestEmbedder(t *testing.T, handler func(req request)) *Embedder {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req request
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		handler(req)
+
+		// the data is returned in reverse order to check it's sorted by index
+		data := make([]string, len(req.Input))
+		for j := range req.Input {
+			data[len(req.Input)-1-j] = fmt.Sprintf(`{"index":%d,"embedding":[%d,1]}`, j, j)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[` + strings.Join(data, ",") + `]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return New().WithAPIKey("key").WithEndpoint(server.URL)
+}
+
+func TestEmbedTasks(t *testing.T) {
+	var tasks []Task
+	e := newTestEmbedder(t, func(req request) {
+		tasks = append(tasks, req.Task)
+		if req.LateChunking {
+			t.Errorf("late chunking not enabled")
+		}
+	})
+
+	embeddings, err := e.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(embeddings, []embedder.Embedding{{0, 1}, {1, 1}}) {
+		t.Errorf("embeddings = %v", embeddings)
+	}
+
+	_, err = index.EmbedQuery(context.Background(), e, "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Task{TaskRetrievalPassage, TaskRetrievalQuery}
+	if !reflect.DeepEqual(tasks, want) {
+		t.Errorf("tasks = %v, want %v", tasks, want)
+	}
+}
+
+func TestEmbedLateChunking(t *testing.T) {
+	var inputs [][]string
+	e := newTestEmbedder(t, func(req request) {
+		if !req.LateChunking {
+			t.Errorf("late chunking disabled")
+		}
+		inputs = append(inputs, req.Input)
+	}).WithLateChunking(true)
+
+	splitter := textsplitter.NewRecursiveCharacterTextSplitter(20, 0).WithDocumentIDs()
+	documents := splitter.SplitDocuments([]document.Document{
+		{Content: "Berlin is the capital of Germany. Its population is 3.85 million."},
+		{Content: "Rome is the capital of Italy."},
+	})
+
+	err := index.New(jsondb.New(), e).LoadFromDocuments(context.Background(), documents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inputs) != 2 || len(inputs[0]) < 2 || len(inputs[0])+len(inputs[1]) != len(documents) {
+		t.Errorf("late chunking requests = %v", inputs)
+	}
+}
+
+func TestEmbedRetry(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case 2:
+			// the first retry times out
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[{"index":0,"embedding":[1,2]}]}`))
+	}))
+	defer server.Close()
+
+	e := New().WithAPIKey("key").WithEndpoint(server.URL)
+
+	_, err := e.Embed(context.Background(), []string{"text"})
+	if httpErr, ok := httperror.As(err); !ok || httpErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("error = %v, want the bad gateway status", err)
+	}
+
+	requests.Store(0)
+	policy := retry.DefaultPolicy()
+	policy.InitialDelay = time.Millisecond
+
+	embeddings, err := e.WithRetry(policy).WithTimeout(50*time.Millisecond).Embed(context.Background(), []string{"text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 3 || !reflect.DeepEqual(embeddings, []embedder.Embedding{{1, 2}}) {
+		t.Errorf("requests = %d, embeddings = %v", requests.Load(), embeddings)
+	}
+}
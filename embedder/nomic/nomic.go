@@ -4,11 +4,13 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/henomis/restclientgo"
 
 	"github.com/henomis/lingoose/embedder"
 	embobserver "github.com/henomis/lingoose/embedder/observer"
+	"github.com/henomis/lingoose/llm/retry"
 )
 
 const (
@@ -17,10 +19,12 @@ const (
 )
 
 type Embedder struct {
-	taskType   TaskType
-	model      Model
-	restClient *restclientgo.RestClient
-	name       string
+	taskType    TaskType
+	model       Model
+	restClient  *restclientgo.RestClient
+	name        string
+	retryPolicy retry.Policy
+	timeout     time.Duration
 }
 
 func New() *Embedder {
@@ -39,7 +43,7 @@ func New() *Embedder {
 }
 
 func (e *Embedder) WithAPIKey(apiKey string) *Embedder {
-	e.restClient = restclientgo.New(defaultEndpoint).WithRequestModifier(
+	e.restClient.SetRequestModifier(
 		func(req *http.Request) *http.Request {
 			req.Header.Set("Authorization", "Bearer "+apiKey)
 			return req
@@ -58,6 +62,25 @@ func (e *Embedder) WithModel(model Model) *Embedder {
 	return e
 }
 
+// WithHTTPClient sets the http client to use for the embedder
+func (e *Embedder) WithHTTPClient(httpClient *http.Client) *Embedder {
+	e.restClient.SetHTTPClient(httpClient)
+	return e
+}
+
+// WithRetry retries the requests failing with a rate limit, a server error or a timeout,
+// with the backoff of the policy.
+func (e *Embedder) WithRetry(policy retry.Policy) *Embedder {
+	e.retryPolicy = policy
+	return e
+}
+
+// WithTimeout bounds each request, a timed out request is retried as set by WithRetry.
+func (e *Embedder) WithTimeout(timeout time.Duration) *Embedder {
+	e.timeout = timeout
+	return e
+}
+
 // Embed returns the embeddings for the given texts
 func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
 	observerEmbedding, err := embobserver.StartObserveEmbedding(
@@ -71,15 +94,13 @@ func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedd
 		return nil, err
 	}
 
-	var resp response
-	err = e.restClient.Post(
+	resp, err := e.post(
 		ctx,
 		&request{
 			Texts:    texts,
 			Model:    string(e.model),
 			TaskType: e.taskType,
 		},
-		&resp,
 	)
 	if err != nil {
 		return nil, err
@@ -96,3 +117,11 @@ func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedd
 
 	return resp.Embeddings, nil
 }
+
+// post sends the request with the retry policy, see retry.Post.
+func (e *Embedder) post(ctx context.Context, req *request) (*response, error) {
+	policy := e.retryPolicy
+	policy.AttemptTimeout = e.timeout
+
+	return retry.Post[response](ctx, e.restClient, policy, req)
+}
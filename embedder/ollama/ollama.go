@@ -2,14 +2,16 @@ package ollamaembedder
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/henomis/restclientgo"
 
 	"github.com/henomis/lingoose/embedder"
 	embobserver "github.com/henomis/lingoose/embedder/observer"
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/llm/retry"
 )
 
 const (
@@ -25,10 +27,16 @@ func (e *OllamaEmbedError) Error() string {
 	return fmt.Sprintf("Error embedding text: %v", e.Err)
 }
 
+func (e *OllamaEmbedError) Unwrap() error {
+	return e.Err
+}
+
 type Embedder struct {
-	model      string
-	restClient *restclientgo.RestClient
-	name       string
+	model       string
+	restClient  *restclientgo.RestClient
+	name        string
+	retryPolicy retry.Policy
+	timeout     time.Duration
 }
 
 func New() *Embedder {
@@ -49,6 +57,25 @@ func (e *Embedder) WithModel(model string) *Embedder {
 	return e
 }
 
+// WithHTTPClient sets the http client to use for the embedder
+func (e *Embedder) WithHTTPClient(httpClient *http.Client) *Embedder {
+	e.restClient.SetHTTPClient(httpClient)
+	return e
+}
+
+// WithRetry retries the requests failing with a rate limit, a server error or a timeout,
+// with the backoff of the policy.
+func (e *Embedder) WithRetry(policy retry.Policy) *Embedder {
+	e.retryPolicy = policy
+	return e
+}
+
+// WithTimeout bounds each request, a timed out request is retried as set by WithRetry.
+func (e *Embedder) WithTimeout(timeout time.Duration) *Embedder {
+	e.timeout = timeout
+	return e
+}
+
 // Embed returns the embeddings for the given texts
 func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
 	observerEmbedding, err := embobserver.StartObserveEmbedding(
@@ -86,24 +113,28 @@ func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedd
 
 // Embed returns the embeddings for the given texts
 func (e *Embedder) embed(ctx context.Context, text string) (embedder.Embedding, error) {
-	resp := &response{}
-	err := e.restClient.Post(
+	resp, err := e.post(
 		ctx,
 		&request{
 			Prompt: text,
 			Model:  e.model,
 		},
-		resp,
 	)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.HTTPStatusCode >= http.StatusBadRequest {
+	if _, ok := httperror.As(err); ok {
 		return nil, &OllamaEmbedError{
-			Err: errors.New(string(resp.RawBody)),
+			Err: err,
 		}
+	} else if err != nil {
+		return nil, err
 	}
 
 	return resp.Embedding, nil
 }
+
+// post sends the request with the retry policy, see retry.Post.
+func (e *Embedder) post(ctx context.Context, req *request) (*response, error) {
+	policy := e.retryPolicy
+	policy.AttemptTimeout = e.timeout
+
+	return retry.Post[response](ctx, e.restClient, policy, req)
+}
@@ -2,10 +2,12 @@ package openaiembedder
 
 import (
 	"context"
+	"net/http"
 	"os"
 
 	"github.com/henomis/lingoose/embedder"
 	embobserver "github.com/henomis/lingoose/embedder/observer"
+	"github.com/henomis/lingoose/ratelimit"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -17,9 +19,19 @@ const (
 	LargeEmbedding3 Model = openai.LargeEmbedding3
 )
 
+// Limits of an embeddings request, to be used with a batchembedder.Batcher.
+const (
+	MaxBatchItems  = 2048
+	MaxBatchTokens = 300000
+)
+
 type OpenAIEmbedder struct {
 	openAIClient *openai.Client
 	model        Model
+	apiKey       string
+	baseURL      string
+	headers      map[string]string
+	rateLimiter  *ratelimit.Limiter
 	Name         string
 }
 
@@ -29,6 +41,7 @@ func New(model Model) *OpenAIEmbedder {
 	return &OpenAIEmbedder{
 		openAIClient: openai.NewClient(openAIKey),
 		model:        model,
+		apiKey:       openAIKey,
 		Name:         "openai",
 	}
 }
@@ -39,8 +52,65 @@ func (o *OpenAIEmbedder) WithClient(client *openai.Client) *OpenAIEmbedder {
 	return o
 }
 
+// WithAPIKey sets the API key, OPENAI_API_KEY by default.
+func (o *OpenAIEmbedder) WithAPIKey(apiKey string) *OpenAIEmbedder {
+	o.apiKey = apiKey
+	return o.withCustomClient()
+}
+
+// WithRateLimit limits the requests and the estimated tokens per minute.
+func (o *OpenAIEmbedder) WithRateLimit(rpm, tpm int) *OpenAIEmbedder {
+	return o.WithRateLimiter(ratelimit.New(rpm, tpm))
+}
+
+// WithRateLimiter sets a rate limiter, it can be shared with other embedders and LLMs
+// using the same API key.
+func (o *OpenAIEmbedder) WithRateLimiter(rateLimiter *ratelimit.Limiter) *OpenAIEmbedder {
+	o.rateLimiter = rateLimiter
+	return o
+}
+
+// WithBaseURL sets the base URL of an OpenAI compatible server (e.g. vLLM, LM Studio,
+// LiteLLM proxy, Together AI), such as http://localhost:8000/v1.
+func (o *OpenAIEmbedder) WithBaseURL(baseURL string) *OpenAIEmbedder {
+	o.baseURL = baseURL
+	return o.withCustomClient()
+}
+
+// WithHeaders sets additional headers sent with every request.
+func (o *OpenAIEmbedder) WithHeaders(headers map[string]string) *OpenAIEmbedder {
+	o.headers = headers
+	return o.withCustomClient()
+}
+
+func (o *OpenAIEmbedder) withCustomClient() *OpenAIEmbedder {
+	config := openai.DefaultConfig(o.apiKey)
+	if o.baseURL != "" {
+		config.BaseURL = o.baseURL
+	}
+
+	if len(o.headers) > 0 {
+		config.HTTPClient = &http.Client{
+			Transport: &headerTransport{
+				base:    http.DefaultTransport,
+				headers: o.headers,
+			},
+		}
+	}
+
+	o.openAIClient = openai.NewClientWithConfig(config)
+	return o
+}
+
 // Embed returns the embeddings for the given texts
 func (o *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	if o.rateLimiter != nil {
+		err := o.rateLimiter.Wait(ctx, ratelimit.EstimateTokens(texts...))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	observerEmbedding, err := embobserver.StartObserveEmbedding(
 		ctx,
 		o.Name,
@@ -97,3 +167,17 @@ func float32ToFloat64(slice []float32) []float64 {
 	}
 	return newSlice
 }
+
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	return t.base.RoundTrip(req)
+}
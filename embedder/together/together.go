@@ -0,0 +1,33 @@
+// Package togetherembedder provides the Together AI embedding models through their
+// OpenAI compatible API.
+package togetherembedder
+
+import (
+	"os"
+
+	openaiembedder "github.com/henomis/lingoose/embedder/openai"
+)
+
+const (
+	defaultEndpoint = "https://api.together.xyz/v1"
+)
+
+const (
+	ModelM2Bert80M8KRetrieval  openaiembedder.Model = "togethercomputer/m2-bert-80M-8k-retrieval"
+	ModelM2Bert80M32KRetrieval openaiembedder.Model = "togethercomputer/m2-bert-80M-32k-retrieval"
+	ModelBGELargeENV1Dot5      openaiembedder.Model = "BAAI/bge-large-en-v1.5"
+	ModelBGEBaseENV1Dot5       openaiembedder.Model = "BAAI/bge-base-en-v1.5"
+	ModelGTEModernBERTBase     openaiembedder.Model = "Alibaba-NLP/gte-modernbert-base"
+	ModelMultilingualE5Large   openaiembedder.Model = "intfloat/multilingual-e5-large-instruct"
+)
+
+// New creates a Together AI embedder for the given model. The API key is read from the
+// TOGETHER_API_KEY environment variable.
+func New(model openaiembedder.Model) *openaiembedder.OpenAIEmbedder {
+	embedder := openaiembedder.New(model).
+		WithAPIKey(os.Getenv("TOGETHER_API_KEY")).
+		WithBaseURL(defaultEndpoint)
+	embedder.Name = "together"
+
+	return embedder
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/llm/cohere"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+func main() {
+	// The Cohere API key is expected to be set in the COHERE_API_KEY environment variable
+	coherellm := cohere.New().WithModel(cohere.ModelCommandR).WithDocuments(
+		document.Document{
+			Content:  "Emperor penguins are the tallest penguins, growing up to 122 cm.",
+			Metadata: types.Meta{"title": "Tall penguins"},
+		},
+		document.Document{
+			Content:  "Emperor penguins only live in Antarctica.",
+			Metadata: types.Meta{"title": "Penguin habitats"},
+		},
+	)
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Where do the tallest penguins live?"),
+		),
+	)
+
+	err := coherellm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(t)
+
+	citations, _ := t.LastMessage().Metadata[cohere.MetadataCitations].([]cohere.Citation)
+	for _, citation := range citations {
+		fmt.Printf("%q supported by documents %v\n", citation.Text, citation.DocumentIDs)
+	}
+}
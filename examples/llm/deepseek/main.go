@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/henomis/lingoose/llm/deepseek"
+	"github.com/henomis/lingoose/thread"
+)
+
+func main() {
+	// The DeepSeek API key is expected to be set in the DEEPSEEK_API_KEY environment variable
+	deepseekllm := deepseek.New().WithModel(deepseek.ModelDeepSeekReasoner).WithMaxTokens(4096)
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("How many r are in the word strawberry?"),
+		),
+	)
+
+	err := deepseekllm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(t)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/henomis/lingoose/llm/fireworks"
+	"github.com/henomis/lingoose/thread"
+)
+
+func main() {
+	// The Fireworks AI API key is expected to be set in the FIREWORKS_API_KEY environment variable
+	fireworksllm := fireworks.New().WithJSONSchema(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"city":    map[string]any{"type": "string"},
+			"country": map[string]any{"type": "string"},
+		},
+		"required": []string{"city", "country"},
+	})
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Which is the capital of Italy?"),
+		),
+	)
+
+	err := fireworksllm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(t)
+}
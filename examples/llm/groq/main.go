@@ -10,7 +10,7 @@ import (
 
 func main() {
 	// The Groq API key is expected to be set in the GROQ_API_KEY environment variable
-	groqllm := groq.New().WithModel("mixtral-8x7b-32768")
+	groqllm := groq.New().WithModel(groq.ModelMixtral8x7B)
 
 	t := thread.New().AddMessage(
 		thread.NewUserMessage().AddContent(
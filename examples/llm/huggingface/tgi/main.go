@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/henomis/lingoose/llm/huggingface"
+	"github.com/henomis/lingoose/thread"
+)
+
+func main() {
+	// Run a local TGI server, e.g.
+	// docker run -p 8080:80 ghcr.io/huggingface/text-generation-inference --model-id meta-llama/Meta-Llama-3-8B-Instruct
+	tgillm := huggingface.NewTGI("http://localhost:8080").
+		WithPromptFormatter(huggingface.Llama3PromptFormatter).
+		WithStop([]string{"<|eot_id|>"}).
+		WithTopK(50).
+		WithRepetitionPenalty(1.1).
+		WithStream(func(s string) {
+			if s != huggingface.EOS {
+				fmt.Print(s)
+			}
+		})
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("What's the NATO purpose?"),
+		),
+	)
+
+	err := tgillm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println()
+	fmt.Println(t)
+}
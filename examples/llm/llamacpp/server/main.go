@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/henomis/lingoose/llm/llamacpp"
+	"github.com/henomis/lingoose/thread"
+)
+
+func main() {
+	ctx := context.Background()
+
+	process, err := llamacpp.StartServer(ctx, "./llama.cpp/llama-server", "./models/qwen2.5-1.5b-instruct-q4_k_m.gguf")
+	if err != nil {
+		panic(err)
+	}
+	defer process.Stop()
+
+	llm := llamacpp.NewServer(process.Endpoint()).
+		WithMaxTokens(256).
+		WithStop([]string{"<|im_end|>"}).
+		WithStream(func(s string) {
+			if s != llamacpp.EOS {
+				fmt.Print(s)
+			}
+		})
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Where is Rome?"),
+		),
+	)
+
+	err = llm.Generate(ctx, t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println()
+}
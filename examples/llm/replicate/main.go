@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/henomis/lingoose/llm/replicate"
+	"github.com/henomis/lingoose/thread"
+)
+
+func main() {
+	// The Replicate API token is expected to be set in the REPLICATE_API_TOKEN environment variable
+	replicatellm := replicate.New(replicate.ModelLlama370BInstruct).
+		WithMaxTokens(512).
+		WithStream(func(s string) {
+			if s != replicate.EOS {
+				fmt.Print(s)
+			}
+		})
+
+	t := thread.New().AddMessage(
+		thread.NewSystemMessage().AddContent(
+			thread.NewTextContent("You are a helpful assistant, answer briefly."),
+		),
+	).AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("What's the NATO purpose?"),
+		),
+	)
+
+	err := replicatellm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println()
+	fmt.Println(t)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/henomis/lingoose/llm/together"
+	"github.com/henomis/lingoose/thread"
+)
+
+func main() {
+	// The Together AI API key is expected to be set in the TOGETHER_API_KEY environment variable
+	togetherllm := together.New().WithModel(together.ModelQwen2Dot572BInstructTurbo).WithStream(
+		true,
+		func(response string) {
+			fmt.Print(response)
+		},
+	)
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Write a haiku about open weights models."),
+		),
+	)
+
+	err := togetherllm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println()
+	fmt.Println(t)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/henomis/lingoose/llm/xai"
+	"github.com/henomis/lingoose/thread"
+)
+
+func main() {
+	// The xAI API key is expected to be set in the XAI_API_KEY environment variable
+	xaillm := xai.New().WithLiveSearch(xai.SearchParameters{
+		Mode:            xai.SearchModeAuto,
+		ReturnCitations: true,
+	})
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("What are the latest news about the Go programming language?"),
+		),
+	)
+
+	err := xaillm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(t)
+	fmt.Println(t.LastMessage().Metadata[xai.MetadataCitations])
+}
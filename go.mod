@@ -6,6 +6,10 @@ require github.com/mitchellh/mapstructure v1.5.0
 
 require (
 	github.com/RediSearch/redisearch-go/v2 v2.1.1
+	github.com/aws/aws-sdk-go-v2 v1.29.0
+	github.com/aws/aws-sdk-go-v2/config v1.27.18
+	github.com/aws/aws-sdk-go-v2/service/bedrockruntime v1.10.0
+	github.com/gomodule/redigo v1.8.9
 	github.com/google/uuid v1.6.0
 	github.com/henomis/cohere-go v1.1.2
 	github.com/henomis/langfuse-go v0.0.3
@@ -14,12 +18,34 @@ require (
 	github.com/henomis/qdrant-go v1.1.0
 	github.com/henomis/restclientgo v1.2.0
 	github.com/invopop/jsonschema v0.7.0
-	github.com/sashabaranov/go-openai v1.24.0
+	github.com/pkoukk/tiktoken-go v0.1.7
+	github.com/qdrant/go-client v1.8.0
+	github.com/sashabaranov/go-openai v1.40.5
+	go.etcd.io/bbolt v1.3.10
 	golang.org/x/net v0.25.0
+	google.golang.org/grpc v1.47.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/gomodule/redigo v1.8.9 // indirect
+	github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.6.2 // indirect
+	github.com/aws/aws-sdk-go-v2/credentials v1.17.18 // indirect
+	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.16.5 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.11 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.11 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/ini v1.8.0 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.11.2 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.11.11 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sso v1.20.11 // indirect
+	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.24.5 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sts v1.28.12 // indirect
+	github.com/aws/smithy-go v1.20.2 // indirect
+	github.com/dlclark/regexp2 v1.10.0 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/iancoleman/orderedmap v0.0.0-20190318233801-ac98e3ecb4b0 // indirect
 	github.com/stretchr/testify v1.8.2 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013 // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
 )
@@ -0,0 +1,90 @@
+package groundedness
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/henomis/lingoose/embedder"
+)
+
+const (
+	defaultMinSimilarity = 0.8
+)
+
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error)
+}
+
+// EmbeddingChecker considers a sentence supported when it's similar enough to a piece of
+// the context. It's cheap and fast, but it can't detect a contradiction worded like the
+// context.
+type EmbeddingChecker struct {
+	embedder      Embedder
+	minSimilarity float64
+}
+
+func NewEmbeddingChecker(embedder Embedder) *EmbeddingChecker {
+	return &EmbeddingChecker{
+		embedder:      embedder,
+		minSimilarity: defaultMinSimilarity,
+	}
+}
+
+// WithMinSimilarity sets the cosine similarity required to support a sentence, 0.8 by default.
+func (c *EmbeddingChecker) WithMinSimilarity(minSimilarity float64) *EmbeddingChecker {
+	c.minSimilarity = minSimilarity
+	return c
+}
+
+func (c *EmbeddingChecker) Check(ctx context.Context, answer string, contexts []string) (*Report, error) {
+	sentences := Sentences(answer)
+	if len(sentences) == 0 {
+		return &Report{}, nil
+	}
+
+	embeddings, err := c.embedder.Embed(ctx, append(append([]string{}, contexts...), sentences...))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGroundedness, err)
+	}
+
+	if len(embeddings) != len(contexts)+len(sentences) {
+		return nil, fmt.Errorf("%w: unexpected number of embeddings", ErrGroundedness)
+	}
+
+	contextEmbeddings := embeddings[:len(contexts)]
+	report := &Report{}
+	for i, sentence := range sentences {
+		var score float64
+		for _, contextEmbedding := range contextEmbeddings {
+			score = math.Max(score, cosineSimilarity(embeddings[len(contexts)+i], contextEmbedding))
+		}
+
+		report.Claims = append(report.Claims, Claim{
+			Sentence:  sentence,
+			Supported: score >= c.minSimilarity,
+			Score:     score,
+		})
+	}
+
+	return report, nil
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
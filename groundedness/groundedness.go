@@ -0,0 +1,78 @@
+// Package groundedness verifies that the sentences of an answer are supported by the
+// context it was generated from, to detect hallucinated claims in RAG answers.
+package groundedness
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrGroundedness = errors.New("groundedness check error")
+)
+
+// Claim is a sentence of the answer. Score is the support found in the context, in the
+// range [0, 1] for the embedding checker and either 0 or 1 for the LLM checker.
+type Claim struct {
+	Sentence  string
+	Supported bool
+	Score     float64
+}
+
+type Report struct {
+	Claims []Claim
+}
+
+// Grounded reports whether all the claims are supported by the context.
+func (r *Report) Grounded() bool {
+	return len(r.Unsupported()) == 0
+}
+
+func (r *Report) Unsupported() []Claim {
+	var unsupported []Claim
+	for _, claim := range r.Claims {
+		if !claim.Supported {
+			unsupported = append(unsupported, claim)
+		}
+	}
+
+	return unsupported
+}
+
+// Checker checks the answer sentences against the context.
+type Checker interface {
+	Check(ctx context.Context, answer string, context []string) (*Report, error)
+}
+
+// Sentences splits the text into sentences, on sentence-ending punctuation followed by
+// a space and on line breaks.
+func Sentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+
+	flush := func() {
+		sentence := strings.TrimSpace(current.String())
+		if sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		if r == '\n' {
+			flush()
+			continue
+		}
+
+		current.WriteRune(r)
+		if (r == '.' || r == '!' || r == '?') && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])) {
+			flush()
+		}
+	}
+	flush()
+
+	return sentences
+}
@@ -0,0 +1,57 @@
+package groundedness
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/embedder"
+)
+
+func TestSentences(t *testing.T) {
+	got := Sentences("Rome is the capital of Italy. It has 2.8 million inhabitants!\nIs it old? Yes")
+	want := []string{
+		"Rome is the capital of Italy.",
+		"It has 2.8 million inhabitants!",
+		"Is it old?",
+		"Yes",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+type fakeEmbedder map[string]embedder.Embedding
+
+func (f fakeEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	embeddings := make([]embedder.Embedding, len(texts))
+	for i, text := range texts {
+		embeddings[i] = f[text]
+	}
+	return embeddings, nil
+}
+
+func TestEmbeddingChecker(t *testing.T) {
+	fake := fakeEmbedder{
+		"Rome is the capital of Italy.": {1, 0},
+		"Rome is in Italy.":             {0.95, 0.05},
+		"Rome has a big airport.":       {0, 1},
+	}
+
+	report, err := NewEmbeddingChecker(fake).Check(
+		context.Background(),
+		"Rome is in Italy. Rome has a big airport.",
+		[]string{"Rome is the capital of Italy."},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Grounded() {
+		t.Fatal("expected unsupported claims")
+	}
+	unsupported := report.Unsupported()
+	if len(unsupported) != 1 || unsupported[0].Sentence != "Rome has a big airport." {
+		t.Fatalf("unexpected unsupported claims: %+v", unsupported)
+	}
+}
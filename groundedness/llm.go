@@ -0,0 +1,104 @@
+package groundedness
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	//nolint:lll
+	llmCheckerPrompt = "You are a fact checker. For each numbered statement, decide whether it is fully supported by the context: a statement is unsupported when the context doesn't mention it or contradicts it. Statements that don't make factual claims (greetings, questions, refusals) are supported.\n\nContext:\n{{range .context}}{{.}}\n\n{{end}}Statements:\n{{range $i, $s := .sentences}}{{$i}}. {{$s}}\n{{end}}\nAnswer only with a JSON object listing the numbers of the unsupported statements, e.g. {\"unsupported\": [1, 3]}."
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// LLMChecker asks an LLM to judge, NLI style, whether the context entails each sentence.
+type LLMChecker struct {
+	llm LLM
+}
+
+func NewLLMChecker(llm LLM) *LLMChecker {
+	return &LLMChecker{
+		llm: llm,
+	}
+}
+
+func (c *LLMChecker) Check(ctx context.Context, answer string, contexts []string) (*Report, error) {
+	sentences := Sentences(answer)
+	if len(sentences) == 0 {
+		return &Report{}, nil
+	}
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent(llmCheckerPrompt).Format(
+				types.M{
+					"context":   contexts,
+					"sentences": sentences,
+				},
+			),
+		),
+	)
+
+	err := c.llm.Generate(ctx, t)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGroundedness, err)
+	}
+
+	unsupported, err := parseUnsupported(t.LastMessage())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGroundedness, err)
+	}
+
+	report := &Report{}
+	for i, sentence := range sentences {
+		claim := Claim{
+			Sentence:  sentence,
+			Supported: !unsupported[i],
+		}
+		if claim.Supported {
+			claim.Score = 1
+		}
+		report.Claims = append(report.Claims, claim)
+	}
+
+	return report, nil
+}
+
+func parseUnsupported(message *thread.Message) (map[int]bool, error) {
+	var text string
+	for _, content := range message.Contents {
+		if content.Type == thread.ContentTypeText {
+			text += content.AsString()
+		}
+	}
+
+	// the model may wrap the JSON object in a code block or in some text
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid verdict %s", strconv.Quote(text))
+	}
+
+	var verdict struct {
+		Unsupported []int `json:"unsupported"`
+	}
+	err := json.Unmarshal([]byte(text[start:end+1]), &verdict)
+	if err != nil {
+		return nil, err
+	}
+
+	unsupported := make(map[int]bool, len(verdict.Unsupported))
+	for _, i := range verdict.Unsupported {
+		unsupported[i] = true
+	}
+
+	return unsupported, nil
+}
@@ -0,0 +1,217 @@
+// Package analytics records the outcome of the index queries to find the gaps of a
+// knowledge base from real traffic: queries with no results or only weak matches.
+package analytics
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/henomis/lingoose/index"
+)
+
+const (
+	defaultMaxRecords = 10000
+	defaultTopQueries = 10
+)
+
+// Record is the outcome of a single query. A query is a zero hit when it returned no
+// results or when its top score is below the collector minimum score.
+type Record struct {
+	Query       string
+	Time        time.Time
+	ResultCount int
+	TopScore    float64
+	ZeroHit     bool
+}
+
+// Store persists the query records.
+type Store interface {
+	Add(ctx context.Context, record Record) error
+	Records(ctx context.Context, since time.Time) ([]Record, error)
+}
+
+// ErrorCallback is called when a record can't be stored, since recording happens
+// outside of the query flow.
+type ErrorCallback func(err error)
+
+type Collector struct {
+	store         Store
+	minScore      *float64
+	topQueries    int
+	errorCallback ErrorCallback
+}
+
+// New creates a collector storing the records in memory. Pass its Record method to
+// index.WithQueryCallback.
+func New() *Collector {
+	return &Collector{
+		store:      NewMemoryStore(defaultMaxRecords),
+		topQueries: defaultTopQueries,
+	}
+}
+
+func (c *Collector) WithStore(store Store) *Collector {
+	c.store = store
+	return c
+}
+
+// WithMinScore sets the score below which the best result is considered not relevant
+// and the query is counted as a zero hit. Use it with similarity scores, where higher
+// is better.
+func (c *Collector) WithMinScore(minScore float64) *Collector {
+	c.minScore = &minScore
+	return c
+}
+
+// WithTopQueries sets how many zero hit queries are reported by the summary.
+func (c *Collector) WithTopQueries(topQueries int) *Collector {
+	c.topQueries = topQueries
+	return c
+}
+
+func (c *Collector) WithErrorCallback(callback ErrorCallback) *Collector {
+	c.errorCallback = callback
+	return c
+}
+
+// Record stores the outcome of a query. It matches the index.QueryCallback signature.
+func (c *Collector) Record(ctx context.Context, query string, results index.SearchResults) {
+	record := Record{
+		Query:       query,
+		Time:        time.Now(),
+		ResultCount: len(results),
+	}
+
+	for i, result := range results {
+		if i == 0 || result.Score > record.TopScore {
+			record.TopScore = result.Score
+		}
+	}
+
+	record.ZeroHit = len(results) == 0 || (c.minScore != nil && record.TopScore < *c.minScore)
+
+	err := c.store.Add(ctx, record)
+	if err != nil && c.errorCallback != nil {
+		c.errorCallback(err)
+	}
+}
+
+// QueryCount is a query with the number of times it has been asked.
+type QueryCount struct {
+	Query string
+	Count int
+}
+
+type Summary struct {
+	Queries            int
+	ZeroHits           int
+	ZeroHitRate        float64
+	AverageTopScore    float64
+	AverageResultCount float64
+	// TopZeroHitQueries are the most frequent zero hit queries, compared ignoring case
+	// and surrounding spaces.
+	TopZeroHitQueries []QueryCount
+}
+
+// Summary aggregates the queries recorded since the given time.
+func (c *Collector) Summary(ctx context.Context, since time.Time) (*Summary, error) {
+	records, err := c.store.Records(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		Queries: len(records),
+	}
+	if len(records) == 0 {
+		return summary, nil
+	}
+
+	var totalTopScore float64
+	var totalResultCount, scored int
+	zeroHitQueries := make(map[string]*QueryCount)
+	for _, record := range records {
+		totalResultCount += record.ResultCount
+		if record.ResultCount > 0 {
+			totalTopScore += record.TopScore
+			scored++
+		}
+
+		if !record.ZeroHit {
+			continue
+		}
+
+		summary.ZeroHits++
+		key := strings.ToLower(strings.TrimSpace(record.Query))
+		if queryCount, ok := zeroHitQueries[key]; ok {
+			queryCount.Count++
+		} else {
+			zeroHitQueries[key] = &QueryCount{Query: record.Query, Count: 1}
+		}
+	}
+
+	summary.ZeroHitRate = float64(summary.ZeroHits) / float64(len(records))
+	summary.AverageResultCount = float64(totalResultCount) / float64(len(records))
+	if scored > 0 {
+		summary.AverageTopScore = totalTopScore / float64(scored)
+	}
+
+	for _, queryCount := range zeroHitQueries {
+		summary.TopZeroHitQueries = append(summary.TopZeroHitQueries, *queryCount)
+	}
+	sort.Slice(summary.TopZeroHitQueries, func(i, j int) bool {
+		a, b := summary.TopZeroHitQueries[i], summary.TopZeroHitQueries[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Query < b.Query
+	})
+	if len(summary.TopZeroHitQueries) > c.topQueries {
+		summary.TopZeroHitQueries = summary.TopZeroHitQueries[:c.topQueries]
+	}
+
+	return summary, nil
+}
+
+// MemoryStore keeps the most recent records in memory.
+type MemoryStore struct {
+	mu         sync.Mutex
+	records    []Record
+	maxRecords int
+}
+
+// NewMemoryStore creates a store keeping up to maxRecords records, dropping the oldest.
+func NewMemoryStore(maxRecords int) *MemoryStore {
+	return &MemoryStore{
+		maxRecords: maxRecords,
+	}
+}
+
+func (s *MemoryStore) Add(_ context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, record)
+	if s.maxRecords > 0 && len(s.records) > s.maxRecords {
+		s.records = append(s.records[:0], s.records[len(s.records)-s.maxRecords:]...)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) Records(_ context.Context, since time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []Record
+	for _, record := range s.records {
+		if !record.Time.Before(since) {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
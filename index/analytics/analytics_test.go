@@ -0,0 +1,36 @@
+package analytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/index"
+)
+
+func TestSummary(t *testing.T) {
+	ctx := context.Background()
+	collector := New().WithMinScore(0.5)
+
+	collector.Record(ctx, "refund policy", index.SearchResults{{Score: 0.9}, {Score: 0.7}})
+	collector.Record(ctx, "Shipping to Mars", nil)
+	collector.Record(ctx, "shipping to mars ", nil)
+	collector.Record(ctx, "warranty", index.SearchResults{{Score: 0.3}})
+
+	summary, err := collector.Summary(ctx, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Queries != 4 || summary.ZeroHits != 3 || summary.ZeroHitRate != 0.75 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	if summary.AverageTopScore != 0.6 || summary.AverageResultCount != 0.75 {
+		t.Fatalf("unexpected averages %+v", summary)
+	}
+
+	if len(summary.TopZeroHitQueries) != 2 || summary.TopZeroHitQueries[0].Count != 2 {
+		t.Fatalf("unexpected zero hit queries %+v", summary.TopZeroHitQueries)
+	}
+}
@@ -0,0 +1,100 @@
+package index
+
+import (
+	"context"
+
+	"github.com/henomis/lingoose/document"
+)
+
+const (
+	// NormalizationNone is the normalization of the scores returned as computed by the
+	// vector database.
+	NormalizationNone = "none"
+
+	rerankPositionMetadataKey = "index-rerank-position"
+)
+
+// Explanation describes how the score of a search result was computed. It's populated
+// only when the search is run with option.WithExplain.
+type Explanation struct {
+	// Metric is the similarity or distance metric of the vector database, when known.
+	Metric string
+	// Distance is the raw similarity or distance computed by the vector database.
+	Distance float64
+	// Normalization describes how the distance was turned into the score.
+	Normalization string
+	// Fusion holds the components of a score fused from several result lists.
+	Fusion []FusionComponent
+	// RerankScore is the relevance score assigned by the reranker, nil if the results
+	// weren't reranked.
+	RerankScore *float64
+}
+
+// FusionComponent is the contribution of a result list to a fused score.
+type FusionComponent struct {
+	// Source identifies the result list, e.g. the query it was retrieved with.
+	Source string
+	// Rank is the position of the result in the list, starting from 0.
+	Rank int
+	// Score is the score of the result in the list.
+	Score float64
+	// Contribution is the part of the fused score coming from the list.
+	Contribution float64
+}
+
+// Reranker reorders the documents by their relevance to the query, e.g. the rerankers of
+// the transformer package.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []document.Document) ([]document.Document, error)
+}
+
+// Explain sets the explanation of the results whose score is the raw distance of the
+// metric, as returned by most vector databases. The results already explained are left
+// untouched.
+func (s SearchResults) Explain(metric string) {
+	for j := range s {
+		if s[j].Explanation == nil {
+			s[j].Explanation = &Explanation{
+				Metric:        metric,
+				Distance:      s[j].Score,
+				Normalization: NormalizationNone,
+			}
+		}
+	}
+}
+
+// Rerank reorders the results with the reranker, which stores the relevance scores in
+// the scoreKey metadata. The results dropped by the reranker are removed and the scores
+// are left untouched: the relevance scores are set in the explanation of the explained
+// results.
+func (s SearchResults) Rerank(ctx context.Context, query string, reranker Reranker, scoreKey string) (SearchResults, error) {
+	documents := s.ToDocuments()
+	for j := range documents {
+		documents[j].Metadata[rerankPositionMetadataKey] = j
+	}
+
+	reranked, err := reranker.Rerank(ctx, query, documents)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(SearchResults, 0, len(reranked))
+	for _, doc := range reranked {
+		position, ok := doc.Metadata[rerankPositionMetadataKey].(int)
+		if !ok || position < 0 || position >= len(s) {
+			continue
+		}
+
+		result := s[position]
+		if result.Explanation != nil {
+			explanation := *result.Explanation
+			if score, isFloat := doc.Metadata[scoreKey].(float64); isFloat {
+				explanation.RerankScore = &score
+			}
+			result.Explanation = &explanation
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,65 @@
+package index
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/index/option"
+	"github.com/henomis/lingoose/types"
+)
+
+// reverseReranker reverses the documents, scoring them by their new position.
+type reverseReranker struct{}
+
+func (reverseReranker) Rerank(_ context.Context, _ string, documents []document.Document) ([]document.Document, error) {
+	reranked := make([]document.Document, 0, len(documents))
+	for j := len(documents) - 1; j >= 0; j-- {
+		documents[j].Metadata["rerank-score"] = float64(len(reranked))
+		reranked = append(reranked, documents[j])
+	}
+
+	return reranked, nil
+}
+
+func TestIndexExplain(t *testing.T) {
+	idx := New(&staticDB{id: "a", metadata: types.Meta{DefaultKeyContent: "a"}}, staticEmbedder{})
+
+	results, err := idx.Query(context.Background(), "query", option.WithExplain())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Explanation{Distance: 1, Normalization: NormalizationNone}
+	if !reflect.DeepEqual(results[0].Explanation, want) {
+		t.Errorf("explanation = %+v, want %+v", results[0].Explanation, want)
+	}
+}
+
+func TestSearchResultsRerank(t *testing.T) {
+	results := SearchResults{
+		{Data: Data{ID: "a", Metadata: types.Meta{DefaultKeyContent: "a"}}, Score: 0.9},
+		{Data: Data{ID: "b", Metadata: types.Meta{DefaultKeyContent: "b"}}, Score: 0.8},
+	}
+	results.Explain("cosine")
+
+	reranked, err := results.Rerank(context.Background(), "query", reverseReranker{}, "rerank-score")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reranked) != 2 || reranked[0].ID != "b" || reranked[1].ID != "a" {
+		t.Fatalf("reranked = %v", reranked)
+	}
+
+	for j, result := range reranked {
+		if result.Explanation.RerankScore == nil || *result.Explanation.RerankScore != float64(j) {
+			t.Errorf("result %s rerank score = %v", result.ID, result.Explanation.RerankScore)
+		}
+	}
+
+	if results[0].Explanation.RerankScore != nil || len(results[0].Metadata) != 1 {
+		t.Errorf("original results modified")
+	}
+}
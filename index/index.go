@@ -4,11 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/henomis/lingoose/document"
 	"github.com/henomis/lingoose/embedder"
 	"github.com/henomis/lingoose/index/option"
+	"github.com/henomis/lingoose/logger"
+	"github.com/henomis/lingoose/textsplitter"
 	"github.com/henomis/lingoose/types"
 )
 
@@ -26,6 +30,10 @@ const (
 
 type AddDataCallback func(data *Data) error
 
+// QueryCallback is called after each query with the results returned by the vector
+// database, e.g. to collect retrieval analytics.
+type QueryCallback func(ctx context.Context, query string, results SearchResults)
+
 type Data struct {
 	ID       string
 	Values   []float64
@@ -36,6 +44,21 @@ type Embedder interface {
 	Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error)
 }
 
+// QueryEmbedder is implemented by the embedders embedding the search queries differently
+// from the documents, such as the Cohere v3 models. The queries are embedded with
+// EmbedQuery when available.
+type QueryEmbedder interface {
+	EmbedQuery(ctx context.Context, query string) (embedder.Embedding, error)
+}
+
+// ChunkEmbedder is implemented by the embedders supporting late chunking: the chunks of a
+// document, tagged by the text splitter with the same textsplitter.MetadataChunkDocument
+// ID, are embedded together with EmbedChunks, each chunk embedding keeping the context
+// of the whole document.
+type ChunkEmbedder interface {
+	EmbedChunks(ctx context.Context, chunks []string) ([]embedder.Embedding, error)
+}
+
 type VectorDB interface {
 	Insert(context.Context, []Data) error
 	IsEmpty(context.Context) (bool, error)
@@ -48,8 +71,12 @@ type Index struct {
 	vectorDB        VectorDB
 	embedder        Embedder
 	batchInsertSize int
+	concurrency     int
 	includeContent  bool
 	addDataCallback AddDataCallback
+	queryCallback   QueryCallback
+	logger          *slog.Logger
+	routing         *routing
 }
 
 func New(vectorDB VectorDB, embedder Embedder) *Index {
@@ -57,6 +84,7 @@ func New(vectorDB VectorDB, embedder Embedder) *Index {
 		vectorDB:        vectorDB,
 		embedder:        embedder,
 		batchInsertSize: defaultBatchInsertSize,
+		concurrency:     1,
 		includeContent:  defaultIncludeContent,
 		addDataCallback: nil,
 	}
@@ -72,6 +100,14 @@ func (i *Index) WithBatchInsertSize(batchInsertSize int) *Index {
 	return i
 }
 
+// WithConcurrency sets how many batches are embedded and inserted at the same time when
+// loading documents, 1 by default. The vector database must support concurrent inserts
+// and the batches may be inserted in any order.
+func (i *Index) WithConcurrency(concurrency int) *Index {
+	i.concurrency = concurrency
+	return i
+}
+
 // WithAddDataCallback allows to modify the data before it is added to the index.
 // This can be useful to add additional metadata to the vector.
 func (i *Index) WithAddDataCallback(callback AddDataCallback) *Index {
@@ -79,6 +115,18 @@ func (i *Index) WithAddDataCallback(callback AddDataCallback) *Index {
 	return i
 }
 
+// WithQueryCallback sets the callback called after each successful query.
+func (i *Index) WithQueryCallback(callback QueryCallback) *Index {
+	i.queryCallback = callback
+	return i
+}
+
+// WithLogger sets the logger of the queries and upserts, logged at debug level.
+func (i *Index) WithLogger(logger *slog.Logger) *Index {
+	i.logger = logger
+	return i
+}
+
 func (i *Index) LoadFromDocuments(ctx context.Context, documents []document.Document) error {
 	err := i.batchUpsert(ctx, documents)
 	if err != nil {
@@ -87,6 +135,65 @@ func (i *Index) LoadFromDocuments(ctx context.Context, documents []document.Docu
 	return nil
 }
 
+// AddStream indexes the documents as they are received, embedding and upserting them in
+// batches of the configured batch insert size, so that memory usage doesn't depend on the
+// number of documents. It returns when the channel is closed and the batches are
+// upserted, or the context is done.
+func (i *Index) AddStream(ctx context.Context, documents <-chan document.Document) error {
+	upserter := i.newUpserter(ctx)
+	err := i.addStream(ctx, upserter, documents)
+
+	upsertErr := upserter.wait()
+	if err != nil {
+		return err
+	}
+	if upsertErr != nil {
+		return fmt.Errorf("%w: %w", ErrInternal, upsertErr)
+	}
+
+	return nil
+}
+
+func (i *Index) addStream(ctx context.Context, upserter *upserter, documents <-chan document.Document) error {
+	batch := make([]document.Document, 0, i.batchInsertSize)
+
+	for {
+		select {
+		case <-upserter.ctx.Done():
+			return ctx.Err()
+		case doc, ok := <-documents:
+			if !ok {
+				if len(batch) > 0 {
+					upserter.upsert(batch)
+				}
+
+				return nil
+			}
+
+			// a full batch ending with a chunk is upserted with the next document, unless
+			// it's a chunk of the same document
+			if len(batch) >= i.batchInsertSize && !sameChunkDocument(batch[len(batch)-1], doc) {
+				if !upserter.upsert(batch) {
+					return ctx.Err()
+				}
+
+				batch = make([]document.Document, 0, i.batchInsertSize)
+			}
+
+			batch = append(batch, doc)
+			if len(batch) < i.batchInsertSize || chunkDocument(doc) != "" {
+				continue
+			}
+
+			if !upserter.upsert(batch) {
+				return ctx.Err()
+			}
+
+			batch = make([]document.Document, 0, i.batchInsertSize)
+		}
+	}
+}
+
 func (i *Index) Add(ctx context.Context, data *Data) error {
 	if data == nil {
 		return nil
@@ -118,15 +225,70 @@ func (i *Index) Search(ctx context.Context, values []float64, opts ...option.Opt
 	for _, opt := range opts {
 		opt(options)
 	}
-	return i.vectorDB.Search(ctx, values, options)
+
+	results, err := i.vectorDB.Search(ctx, values, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// the results not explained by the vector database keep an unknown metric
+	if options.Explain {
+		results.Explain("")
+	}
+
+	return results, nil
 }
 
 func (i *Index) Query(ctx context.Context, query string, opts ...option.Option) (SearchResults, error) {
-	embeddings, err := i.embedder.Embed(ctx, []string{query})
+	target, origin := i, ""
+	if i.routing != nil {
+		origin = OriginPrimary
+		if i.routing.routeToAlternate(ctx, query) {
+			target, origin = i.routing.alternate, OriginAlternate
+		}
+	}
+
+	results, err := target.query(ctx, query, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return i.Search(ctx, embeddings[0], opts...)
+
+	if origin != "" {
+		results = tagOrigin(results, origin)
+	}
+
+	if i.queryCallback != nil {
+		i.queryCallback(ctx, query, results)
+	}
+
+	return results, nil
+}
+
+func (i *Index) query(ctx context.Context, query string, opts ...option.Option) (SearchResults, error) {
+	start := time.Now()
+	embedding, err := EmbedQuery(ctx, i.embedder, query)
+	if err != nil {
+		return nil, err
+	}
+	embedDuration := time.Since(start)
+
+	results, err := i.Search(ctx, embedding, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := []any{
+		slog.Int("query_chars", len(query)),
+		slog.Int("results", len(results)),
+		slog.Duration("embed_duration", embedDuration),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if len(results) > 0 {
+		attrs = append(attrs, slog.Float64("top_score", results[0].Score))
+	}
+	logger.Or(i.logger).DebugContext(ctx, "index query", attrs...)
+
+	return results, nil
 }
 
 func (i *Index) Embedder() Embedder {
@@ -134,43 +296,117 @@ func (i *Index) Embedder() Embedder {
 }
 
 func (i *Index) batchUpsert(ctx context.Context, documents []document.Document) error {
-	for j := 0; j < len(documents); j += i.batchInsertSize {
+	upserter := i.newUpserter(ctx)
+	for j := 0; j < len(documents); {
 		batchEnd := j + i.batchInsertSize
 		if batchEnd > len(documents) {
 			batchEnd = len(documents)
 		}
 
-		texts := []string{}
-		for _, document := range documents[j:batchEnd] {
-			texts = append(texts, document.Content)
+		// don't split the chunks of a document, they are embedded together by the
+		// embedders supporting late chunking
+		for batchEnd < len(documents) && sameChunkDocument(documents[batchEnd-1], documents[batchEnd]) {
+			batchEnd++
 		}
 
-		embeddings, err := i.embedder.Embed(ctx, texts)
-		if err != nil {
-			return err
+		if !upserter.upsert(documents[j:batchEnd]) {
+			break
 		}
 
-		data, err := i.buildDataFromEmbeddingsAndDocuments(embeddings, documents, j)
-		if err != nil {
-			return err
-		}
+		j = batchEnd
+	}
 
-		if i.addDataCallback != nil {
-			for j := range data {
-				callbackErr := i.addDataCallback(&data[j])
-				if callbackErr != nil {
-					return fmt.Errorf("%w: %w", ErrInternal, callbackErr)
-				}
+	err := upserter.wait()
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (i *Index) upsert(ctx context.Context, documents []document.Document) error {
+	texts := []string{}
+	chars := 0
+	for _, document := range documents {
+		texts = append(texts, document.Content)
+		chars += len(document.Content)
+	}
+
+	start := time.Now()
+	embeddings, err := i.embedDocuments(ctx, documents, texts)
+	if err != nil {
+		return err
+	}
+	embedDuration := time.Since(start)
+
+	data, err := i.buildDataFromEmbeddingsAndDocuments(embeddings, documents, 0)
+	if err != nil {
+		return err
+	}
+
+	if i.addDataCallback != nil {
+		for j := range data {
+			callbackErr := i.addDataCallback(&data[j])
+			if callbackErr != nil {
+				return fmt.Errorf("%w: %w", ErrInternal, callbackErr)
 			}
 		}
+	}
+
+	err = i.vectorDB.Insert(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	logger.Or(i.logger).DebugContext(ctx, "index upsert",
+		slog.Int("documents", len(documents)),
+		slog.Int("chars", chars),
+		slog.Duration("embed_duration", embedDuration),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	return nil
+}
+
+// embedDocuments embeds the texts of the documents. The chunks of the same document are
+// embedded together when the embedder is a ChunkEmbedder.
+func (i *Index) embedDocuments(
+	ctx context.Context,
+	documents []document.Document,
+	texts []string,
+) ([]embedder.Embedding, error) {
+	chunkEmbedder, ok := i.embedder.(ChunkEmbedder)
+	if !ok {
+		return i.embedder.Embed(ctx, texts)
+	}
+
+	var embeddings []embedder.Embedding
+	for start := 0; start < len(documents); {
+		end := start + 1
+		for end < len(documents) && sameChunkDocument(documents[end-1], documents[end]) {
+			end++
+		}
 
-		err = i.vectorDB.Insert(ctx, data)
+		chunkEmbeddings, err := chunkEmbedder.EmbedChunks(ctx, texts[start:end])
 		if err != nil {
-			return err
+			return nil, err
 		}
+		embeddings = append(embeddings, chunkEmbeddings...)
+
+		start = end
 	}
 
-	return nil
+	return embeddings, nil
+}
+
+func chunkDocument(doc document.Document) string {
+	id, _ := doc.Metadata[textsplitter.MetadataChunkDocument].(string)
+	return id
+}
+
+func sameChunkDocument(a, b document.Document) bool {
+	id := chunkDocument(a)
+	return id != "" && chunkDocument(b) == id
 }
 
 func (i *Index) buildDataFromEmbeddingsAndDocuments(
@@ -209,6 +445,8 @@ func (i *Index) buildDataFromEmbeddingsAndDocuments(
 type SearchResult struct {
 	Data
 	Score float64
+	// Explanation describes how the score was computed, set only with option.WithExplain.
+	Explanation *Explanation
 }
 
 func (s *SearchResult) Content() string {
@@ -0,0 +1,204 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/textsplitter"
+	"github.com/henomis/lingoose/types"
+)
+
+// chunkEmbedder records the groups of texts embedded together.
+type chunkEmbedder struct {
+	staticEmbedder
+	groups [][]string
+}
+
+func (e *chunkEmbedder) EmbedChunks(ctx context.Context, chunks []string) ([]embedder.Embedding, error) {
+	e.groups = append(e.groups, chunks)
+	return e.Embed(ctx, chunks)
+}
+
+func chunks(id string, contents ...string) []document.Document {
+	documents := make([]document.Document, len(contents))
+	for j, content := range contents {
+		documents[j] = document.Document{
+			Content:  content,
+			Metadata: types.Meta{textsplitter.MetadataChunkDocument: id},
+		}
+	}
+
+	return documents
+}
+
+func TestIndexChunkEmbedder(t *testing.T) {
+	var documents []document.Document
+	documents = append(documents, chunks("doc-1", "a1", "a2", "a3")...)
+	documents = append(documents, document.Document{Content: "b", Metadata: types.Meta{}})
+	documents = append(documents, chunks("doc-2", "c1", "c2")...)
+
+	want := [][]string{{"a1", "a2", "a3"}, {"b"}, {"c1", "c2"}}
+
+	t.Run("LoadFromDocuments", func(t *testing.T) {
+		e := &chunkEmbedder{}
+		err := New(&staticDB{}, e).WithBatchInsertSize(2).LoadFromDocuments(context.Background(), documents)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(e.groups, want) {
+			t.Errorf("embedded groups = %v, want %v", e.groups, want)
+		}
+	})
+
+	t.Run("AddStream", func(t *testing.T) {
+		stream := make(chan document.Document, len(documents))
+		for _, doc := range documents {
+			stream <- doc
+		}
+		close(stream)
+
+		e := &chunkEmbedder{}
+		err := New(&staticDB{}, e).WithBatchInsertSize(2).AddStream(context.Background(), stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(e.groups, want) {
+			t.Errorf("embedded groups = %v, want %v", e.groups, want)
+		}
+	})
+}
+
+// slowEmbedder embeds slowly, recording the maximum number of concurrent calls, and fails
+// on the failOn text.
+type slowEmbedder struct {
+	staticEmbedder
+	mu       sync.Mutex
+	inFlight int
+	maxIn    int
+	calls    int
+	failOn   string
+}
+
+func (e *slowEmbedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	e.mu.Lock()
+	e.calls++
+	e.inFlight++
+	e.maxIn = max(e.maxIn, e.inFlight)
+	e.mu.Unlock()
+
+	defer func() {
+		e.mu.Lock()
+		e.inFlight--
+		e.mu.Unlock()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	for _, text := range texts {
+		if text == e.failOn {
+			return nil, errors.New("embed failed")
+		}
+	}
+
+	return e.staticEmbedder.Embed(ctx, texts)
+}
+
+// memoryDB stores the inserted contents, safe for concurrent inserts.
+type memoryDB struct {
+	staticDB
+	mu       sync.Mutex
+	contents []string
+}
+
+func (db *memoryDB) Insert(_ context.Context, datas []Data) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, data := range datas {
+		db.contents = append(db.contents, data.Metadata[DefaultKeyContent].(string))
+	}
+
+	return nil
+}
+
+func numberedDocuments(n int) []document.Document {
+	documents := make([]document.Document, n)
+	for j := range documents {
+		documents[j] = document.Document{
+			Content:  strconv.Itoa(j),
+			Metadata: types.Meta{},
+		}
+	}
+
+	return documents
+}
+
+func TestIndexConcurrency(t *testing.T) {
+	documents := numberedDocuments(20)
+
+	var want []string
+	for _, doc := range documents {
+		want = append(want, doc.Content)
+	}
+	sort.Strings(want)
+
+	load := map[string]func(*Index) error{
+		"LoadFromDocuments": func(idx *Index) error {
+			return idx.LoadFromDocuments(context.Background(), documents)
+		},
+		"AddStream": func(idx *Index) error {
+			stream := make(chan document.Document)
+			go func() {
+				for _, doc := range documents {
+					stream <- doc
+				}
+				close(stream)
+			}()
+
+			return idx.AddStream(context.Background(), stream)
+		},
+	}
+
+	for name, fn := range load {
+		t.Run(name, func(t *testing.T) {
+			e := &slowEmbedder{}
+			db := &memoryDB{}
+			err := fn(New(db, e).WithBatchInsertSize(2).WithConcurrency(4))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sort.Strings(db.contents)
+			if !reflect.DeepEqual(db.contents, want) {
+				t.Errorf("inserted %v, want %v", db.contents, want)
+			}
+			if e.calls != 10 || e.maxIn < 2 || e.maxIn > 4 {
+				t.Errorf("got %d calls, %d concurrent, want 10 calls and 2 to 4 concurrent", e.calls, e.maxIn)
+			}
+		})
+	}
+}
+
+func TestIndexConcurrencyError(t *testing.T) {
+	e := &slowEmbedder{failOn: "3"}
+	db := &memoryDB{}
+	err := New(db, e).WithBatchInsertSize(2).WithConcurrency(2).
+		LoadFromDocuments(context.Background(), numberedDocuments(100))
+	if !errors.Is(err, ErrInternal) {
+		t.Fatalf("expected ErrInternal, got %v", err)
+	}
+
+	// the batches after the failure are not started
+	if e.calls > 4 {
+		t.Errorf("got %d embed calls after the failure", e.calls)
+	}
+}
@@ -0,0 +1,108 @@
+package index
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/henomis/lingoose/embedder"
+)
+
+type embeddingMemoContextKey struct{}
+
+// NamedEmbedder is an embedder memoized by name, so that the embeddings it computes are
+// shared by all its instances. Other embedders are memoized by pointer identity.
+type NamedEmbedder interface {
+	Embedder
+	EmbedderName() string
+}
+
+type embeddingMemoKey struct {
+	name string
+	// embedder is set for pointer embedders only, compared by identity
+	embedder Embedder
+	query    string
+}
+
+type embeddingMemo struct {
+	mu         sync.Mutex
+	embeddings map[embeddingMemoKey]embedder.Embedding
+}
+
+// ContextWithEmbeddingMemo returns a context that memoizes query embeddings. Components
+// sharing the context (e.g. the LLM cache and the RAG retrieval) will embed the same query
+// with the same embedder only once. If the context already has a memo it is returned as is.
+func ContextWithEmbeddingMemo(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(embeddingMemoContextKey{}).(*embeddingMemo); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, embeddingMemoContextKey{}, &embeddingMemo{
+		embeddings: make(map[embeddingMemoKey]embedder.Embedding),
+	})
+}
+
+// EmbedQuery returns the embedding of the query, reusing the one memoized in the context if any.
+// Embedders are told apart by their EmbedderName if they are a NamedEmbedder, by pointer
+// identity otherwise; embedders that are neither named nor pointers are not memoized.
+// The returned embedding is a copy the caller is free to modify.
+func EmbedQuery(ctx context.Context, e Embedder, query string) (embedder.Embedding, error) {
+	memo, ok := ctx.Value(embeddingMemoContextKey{}).(*embeddingMemo)
+	if !ok {
+		return embedQuery(ctx, e, query)
+	}
+
+	key, ok := newEmbeddingMemoKey(e, query)
+	if !ok {
+		return embedQuery(ctx, e, query)
+	}
+
+	memo.mu.Lock()
+	embedding, ok := memo.embeddings[key]
+	memo.mu.Unlock()
+	if ok {
+		return slices.Clone(embedding), nil
+	}
+
+	embedding, err := embedQuery(ctx, e, query)
+	if err != nil {
+		return nil, err
+	}
+
+	memo.mu.Lock()
+	memo.embeddings[key] = slices.Clone(embedding)
+	memo.mu.Unlock()
+
+	return embedding, nil
+}
+
+func newEmbeddingMemoKey(e Embedder, query string) (embeddingMemoKey, bool) {
+	if named, ok := e.(NamedEmbedder); ok {
+		return embeddingMemoKey{name: named.EmbedderName(), query: query}, true
+	}
+
+	value := reflect.ValueOf(e)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return embeddingMemoKey{}, false
+	}
+
+	return embeddingMemoKey{embedder: e, query: query}, true
+}
+
+func embedQuery(ctx context.Context, e Embedder, query string) (embedder.Embedding, error) {
+	if queryEmbedder, ok := e.(QueryEmbedder); ok {
+		return queryEmbedder.EmbedQuery(ctx, query)
+	}
+
+	embeddings, err := e.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(embeddings) == 0 {
+		return nil, ErrInternal
+	}
+
+	return embeddings[0], nil
+}
@@ -0,0 +1,98 @@
+package index
+
+import (
+	"context"
+	"testing"
+
+	"github.com/henomis/lingoose/embedder"
+)
+
+// countingEmbedder counts the embedded texts.
+type countingEmbedder struct {
+	calls int
+}
+
+func (e *countingEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	e.calls += len(texts)
+
+	embeddings := make([]embedder.Embedding, len(texts))
+	for j := range texts {
+		embeddings[j] = embedder.Embedding{1, 0}
+	}
+
+	return embeddings, nil
+}
+
+// namedEmbedder is a value embedder memoized by name.
+type namedEmbedder struct {
+	name  string
+	calls *int
+}
+
+func (e namedEmbedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	*e.calls += len(texts)
+	return staticEmbedder{}.Embed(ctx, texts)
+}
+
+func (e namedEmbedder) EmbedderName() string { return e.name }
+
+func TestEmbedQueryMemo(t *testing.T) {
+	ctx := ContextWithEmbeddingMemo(context.Background())
+
+	first, second := &countingEmbedder{}, &countingEmbedder{}
+	for _, e := range []*countingEmbedder{first, first, second} {
+		if _, err := EmbedQuery(ctx, e, "query"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if first.calls != 1 || second.calls != 1 {
+		t.Errorf("embedder calls = %d, %d, want each embedder to embed the query once", first.calls, second.calls)
+	}
+}
+
+func TestEmbedQueryMemoReturnsCopy(t *testing.T) {
+	ctx := ContextWithEmbeddingMemo(context.Background())
+	e := &countingEmbedder{}
+
+	embedding, err := EmbedQuery(ctx, e, "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedding[0] = 42
+
+	embedding, err = EmbedQuery(ctx, e, "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedding[1] = 42
+
+	embedding, err = EmbedQuery(ctx, e, "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embedding[0] != 1 || embedding[1] != 0 || e.calls != 1 {
+		t.Errorf("EmbedQuery() = %v after %d calls, want the memoized embedding unchanged", embedding, e.calls)
+	}
+}
+
+func TestEmbedQueryMemoNamed(t *testing.T) {
+	ctx := ContextWithEmbeddingMemo(context.Background())
+
+	calls := 0
+	embedders := []Embedder{
+		namedEmbedder{name: "a", calls: &calls},
+		namedEmbedder{name: "a", calls: &calls},
+		namedEmbedder{name: "b", calls: &calls},
+		staticEmbedder{},
+	}
+	for _, e := range embedders {
+		if _, err := EmbedQuery(ctx, e, "query"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("named embedder calls = %d, want 2", calls)
+	}
+}
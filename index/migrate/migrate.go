@@ -0,0 +1,212 @@
+// Package migrate copies the points of a vector database into another one, embedding
+// their stored content again with a new embedder, e.g. when switching embedding model.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/henomis/lingoose/index"
+)
+
+var (
+	ErrMigration = errors.New("migration error")
+)
+
+const (
+	defaultBatchSize  = 32
+	defaultMaxRetries = 3
+	defaultRetryDelay = time.Second
+)
+
+// Source returns a page of up to limit points starting from offset, and the offset of
+// the next page. An empty offset is the first page; an empty next offset means there
+// are no more points.
+type Source interface {
+	Scroll(ctx context.Context, limit int, offset string) ([]index.Data, string, error)
+}
+
+// SourceFunc adapts a function to the Source interface, e.g. to pass a filter:
+//
+//	migrate.SourceFunc(func(ctx context.Context, limit int, offset string) ([]index.Data, string, error) {
+//		return qdrantDB.Scroll(ctx, nil, limit, offset)
+//	})
+type SourceFunc func(ctx context.Context, limit int, offset string) ([]index.Data, string, error)
+
+func (f SourceFunc) Scroll(ctx context.Context, limit int, offset string) ([]index.Data, string, error) {
+	return f(ctx, limit, offset)
+}
+
+// Progress is reported after each migrated page. Offset is the offset of the next page
+// to migrate: persisting it allows to resume an interrupted migration with WithResumeFrom.
+type Progress struct {
+	Migrated int
+	Skipped  int
+	Offset   string
+	Done     bool
+}
+
+type ProgressCallback func(Progress) error
+
+type Migration struct {
+	source           Source
+	destination      index.VectorDB
+	embedder         index.Embedder
+	contentKey       string
+	batchSize        int
+	interval         time.Duration
+	maxRetries       uint
+	retryDelay       time.Duration
+	offset           string
+	progressCallback ProgressCallback
+}
+
+// New creates a migration reading the points from source and inserting them in
+// destination, with the vectors computed by embedder from the content stored in the
+// index.DefaultKeyContent metadata. Points keep their ID and metadata, so a page
+// migrated twice is just overwritten.
+func New(source Source, destination index.VectorDB, embedder index.Embedder) *Migration {
+	return &Migration{
+		source:      source,
+		destination: destination,
+		embedder:    embedder,
+		contentKey:  index.DefaultKeyContent,
+		batchSize:   defaultBatchSize,
+		maxRetries:  defaultMaxRetries,
+		retryDelay:  defaultRetryDelay,
+	}
+}
+
+// WithBatchSize sets how many points are read and embedded at once.
+func (m *Migration) WithBatchSize(batchSize int) *Migration {
+	m.batchSize = batchSize
+	return m
+}
+
+// WithContentKey sets the metadata key holding the content to embed.
+func (m *Migration) WithContentKey(contentKey string) *Migration {
+	m.contentKey = contentKey
+	return m
+}
+
+// WithRateLimit limits the embedding requests per minute.
+func (m *Migration) WithRateLimit(requestsPerMinute int) *Migration {
+	if requestsPerMinute > 0 {
+		m.interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+	return m
+}
+
+// WithMaxRetries sets how many times a failed embedding request is retried, waiting
+// retryDelay, doubled at each attempt.
+func (m *Migration) WithMaxRetries(maxRetries uint, retryDelay time.Duration) *Migration {
+	m.maxRetries = maxRetries
+	m.retryDelay = retryDelay
+	return m
+}
+
+// WithResumeFrom resumes the migration from the offset reported by a previous run.
+func (m *Migration) WithResumeFrom(offset string) *Migration {
+	m.offset = offset
+	return m
+}
+
+// WithProgressCallback sets the callback called after each page. Returning an error
+// stops the migration.
+func (m *Migration) WithProgressCallback(callback ProgressCallback) *Migration {
+	m.progressCallback = callback
+	return m
+}
+
+// Run migrates all the points. Points without content are skipped.
+func (m *Migration) Run(ctx context.Context) error {
+	progress := Progress{Offset: m.offset}
+	var lastRequest time.Time
+
+	for {
+		datas, nextOffset, err := m.source.Scroll(ctx, m.batchSize, progress.Offset)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrMigration, err)
+		}
+
+		var texts []string
+		var toMigrate []index.Data
+		for _, data := range datas {
+			content, ok := data.Metadata[m.contentKey].(string)
+			if !ok || content == "" {
+				progress.Skipped++
+				continue
+			}
+			texts = append(texts, content)
+			toMigrate = append(toMigrate, data)
+		}
+
+		if len(texts) > 0 {
+			if err = wait(ctx, lastRequest.Add(m.interval)); err != nil {
+				return fmt.Errorf("%w: %w", ErrMigration, err)
+			}
+			lastRequest = time.Now()
+
+			if err = m.migrate(ctx, texts, toMigrate); err != nil {
+				return fmt.Errorf("%w: %w", ErrMigration, err)
+			}
+		}
+
+		progress.Migrated += len(toMigrate)
+		progress.Offset = nextOffset
+		progress.Done = nextOffset == ""
+
+		if m.progressCallback != nil {
+			if err = m.progressCallback(progress); err != nil {
+				return fmt.Errorf("%w: %w", ErrMigration, err)
+			}
+		}
+
+		if progress.Done {
+			return nil
+		}
+	}
+}
+
+func (m *Migration) migrate(ctx context.Context, texts []string, datas []index.Data) error {
+	for attempt := uint(0); ; attempt++ {
+		embeddings, err := m.embedder.Embed(ctx, texts)
+		if err == nil && len(embeddings) != len(texts) {
+			err = fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embeddings))
+		}
+
+		if err == nil {
+			for i := range datas {
+				datas[i].Values = embeddings[i]
+			}
+			return m.destination.Insert(ctx, datas)
+		}
+
+		if attempt >= m.maxRetries {
+			return err
+		}
+
+		if err = wait(ctx, time.Now().Add(m.retryDelay<<attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+func wait(ctx context.Context, until time.Time) error {
+	delay := time.Until(until)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/option"
+	"github.com/henomis/lingoose/types"
+)
+
+type memorySource []index.Data
+
+func (s memorySource) Scroll(_ context.Context, limit int, offset string) ([]index.Data, string, error) {
+	start := 0
+	if offset != "" {
+		start, _ = strconv.Atoi(offset)
+	}
+
+	end := start + limit
+	if end >= len(s) {
+		return s[start:], "", nil
+	}
+
+	return s[start:end], strconv.Itoa(end), nil
+}
+
+type memoryDB struct {
+	datas map[string]index.Data
+}
+
+func (d *memoryDB) Insert(_ context.Context, datas []index.Data) error {
+	for _, data := range datas {
+		d.datas[data.ID] = data
+	}
+	return nil
+}
+
+func (d *memoryDB) IsEmpty(context.Context) (bool, error)  { return len(d.datas) == 0, nil }
+func (d *memoryDB) Drop(context.Context) error             { return nil }
+func (d *memoryDB) Delete(context.Context, []string) error { return nil }
+func (d *memoryDB) Search(context.Context, []float64, *option.Options) (index.SearchResults, error) {
+	return nil, nil
+}
+
+type lengthEmbedder struct {
+	failures int
+}
+
+func (e *lengthEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	if e.failures > 0 {
+		e.failures--
+		return nil, errors.New("rate limited")
+	}
+
+	embeddings := make([]embedder.Embedding, len(texts))
+	for i, text := range texts {
+		embeddings[i] = embedder.Embedding{float64(len(text))}
+	}
+	return embeddings, nil
+}
+
+func newSource(n int) memorySource {
+	source := make(memorySource, n)
+	for i := range source {
+		source[i] = index.Data{
+			ID:       strconv.Itoa(i),
+			Values:   []float64{0, 0},
+			Metadata: types.Meta{index.DefaultKeyContent: "content " + strconv.Itoa(i)},
+		}
+	}
+	source[3].Metadata = types.Meta{}
+	return source
+}
+
+func TestMigrationResume(t *testing.T) {
+	source := newSource(10)
+	destination := &memoryDB{datas: map[string]index.Data{}}
+
+	errStop := errors.New("stop")
+	var offset string
+	err := New(source, destination, &lengthEmbedder{failures: 1}).
+		WithBatchSize(4).
+		WithMaxRetries(1, 0).
+		WithProgressCallback(func(p Progress) error {
+			offset = p.Offset
+			return errStop
+		}).
+		Run(context.Background())
+	if !errors.Is(err, errStop) || offset != "4" {
+		t.Fatalf("expected the migration to stop at offset 4, got %q, %v", offset, err)
+	}
+
+	var last Progress
+	err = New(source, destination, &lengthEmbedder{}).
+		WithBatchSize(4).
+		WithResumeFrom(offset).
+		WithProgressCallback(func(p Progress) error {
+			last = p
+			return nil
+		}).
+		Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !last.Done || last.Migrated != 6 || len(destination.datas) != 9 {
+		t.Fatalf("unexpected progress %+v with %d points", last, len(destination.datas))
+	}
+
+	if values := destination.datas["9"].Values; len(values) != 1 || values[0] != float64(len("content 9")) {
+		t.Fatalf("unexpected values %v", values)
+	}
+}
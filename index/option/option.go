@@ -3,8 +3,9 @@ package option
 type Option func(*Options)
 
 type Options struct {
-	TopK   int
-	Filter any
+	TopK    int
+	Filter  any
+	Explain bool
 }
 
 func WithTopK(topK int) Option {
@@ -18,3 +19,11 @@ func WithFilter(filter any) Option {
 		opts.Filter = filter
 	}
 }
+
+// WithExplain populates the Explanation of the search results, describing how their
+// score was computed.
+func WithExplain() Option {
+	return func(opts *Options) {
+		opts.Explain = true
+	}
+}
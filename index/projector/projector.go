@@ -0,0 +1,236 @@
+// Package projector exports the vectors and the metadata of an index in the formats read
+// by the embedding projectors, to visually inspect the coverage and the clusters of a
+// corpus with UMAP, t-SNE or PCA.
+package projector
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/migrate"
+)
+
+var (
+	ErrExport = errors.New("export error")
+)
+
+const (
+	defaultBatchSize = 100
+	idColumn         = "id"
+)
+
+// Exporter reads the points of a vector database, such as jsondb.DB or qdrant.DB, and
+// writes them for the TensorFlow Embedding Projector (vectors and metadata TSV files)
+// or as JSON lines for other tools, e.g. the Nomic Atlas client.
+type Exporter struct {
+	source       migrate.Source
+	metadataKeys []string
+	batchSize    int
+	limit        int
+}
+
+// New creates an exporter of the points of source, with the index.DefaultKeyContent
+// metadata.
+func New(source migrate.Source) *Exporter {
+	return &Exporter{
+		source:       source,
+		metadataKeys: []string{index.DefaultKeyContent},
+		batchSize:    defaultBatchSize,
+	}
+}
+
+// WithMetadataKeys sets the metadata exported with the vectors, e.g. the content and
+// the source of the documents to color the points by.
+func (e *Exporter) WithMetadataKeys(metadataKeys ...string) *Exporter {
+	e.metadataKeys = metadataKeys
+	return e
+}
+
+// WithBatchSize sets how many points are read at once.
+func (e *Exporter) WithBatchSize(batchSize int) *Exporter {
+	e.batchSize = batchSize
+	return e
+}
+
+// WithLimit sets the maximum number of points exported. The browser based projectors
+// get slow beyond a few tens of thousands points.
+func (e *Exporter) WithLimit(limit int) *Exporter {
+	e.limit = limit
+	return e
+}
+
+// WriteTSV writes the vectors, one per line with tab separated values, and the related
+// metadata, with a header line followed by the ID and the metadata values of each
+// point; without metadata keys only the IDs are written, without header. It returns
+// the number of points exported.
+func (e *Exporter) WriteTSV(ctx context.Context, vectors io.Writer, metadata io.Writer) (int, error) {
+	vectorsWriter := bufio.NewWriter(vectors)
+	metadataWriter := bufio.NewWriter(metadata)
+
+	// the projector expects a header only with more than one column
+	if len(e.metadataKeys) > 0 {
+		header := append([]string{idColumn}, e.metadataKeys...)
+		_, err := metadataWriter.WriteString(strings.Join(header, "\t") + "\n")
+		if err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrExport, err)
+		}
+	}
+
+	dimensions := 0
+	count, err := e.scroll(ctx, func(data index.Data) error {
+		if dimensions == 0 {
+			dimensions = len(data.Values)
+		}
+		if len(data.Values) != dimensions {
+			return fmt.Errorf("point %s has %d dimensions, expected %d", data.ID, len(data.Values), dimensions)
+		}
+
+		values := make([]string, len(data.Values))
+		for i, value := range data.Values {
+			values[i] = strconv.FormatFloat(value, 'g', -1, 64)
+		}
+		_, errWrite := vectorsWriter.WriteString(strings.Join(values, "\t") + "\n")
+		if errWrite != nil {
+			return errWrite
+		}
+
+		row := []string{tsvValue(data.ID)}
+		for _, key := range e.metadataKeys {
+			row = append(row, tsvValue(data.Metadata[key]))
+		}
+		_, errWrite = metadataWriter.WriteString(strings.Join(row, "\t") + "\n")
+
+		return errWrite
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if err = vectorsWriter.Flush(); err != nil {
+		return count, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+	if err = metadataWriter.Flush(); err != nil {
+		return count, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+
+	return count, nil
+}
+
+// SaveTSV writes the vectors and the metadata TSV files, to be loaded in the projector.
+func (e *Exporter) SaveTSV(ctx context.Context, vectorsPath, metadataPath string) (int, error) {
+	vectors, err := os.Create(vectorsPath)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+	defer vectors.Close()
+
+	metadata, err := os.Create(metadataPath)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+	defer metadata.Close()
+
+	count, err := e.WriteTSV(ctx, vectors, metadata)
+	if err != nil {
+		return count, err
+	}
+
+	if err = vectors.Close(); err != nil {
+		return count, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+	if err = metadata.Close(); err != nil {
+		return count, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+
+	return count, nil
+}
+
+type jsonPoint struct {
+	ID        string         `json:"id"`
+	Embedding []float64      `json:"embedding"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+// WriteJSONL writes a JSON object per point, with its ID, embedding and metadata. It
+// returns the number of points exported.
+func (e *Exporter) WriteJSONL(ctx context.Context, w io.Writer) (int, error) {
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+
+	count, err := e.scroll(ctx, func(data index.Data) error {
+		point := jsonPoint{
+			ID:        data.ID,
+			Embedding: data.Values,
+		}
+		for _, key := range e.metadataKeys {
+			if value, ok := data.Metadata[key]; ok {
+				if point.Metadata == nil {
+					point.Metadata = make(map[string]any)
+				}
+				point.Metadata[key] = value
+			}
+		}
+
+		return encoder.Encode(point)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if err = writer.Flush(); err != nil {
+		return count, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+
+	return count, nil
+}
+
+// scroll calls fn for each point up to the limit, returning the number of points.
+func (e *Exporter) scroll(ctx context.Context, fn func(index.Data) error) (int, error) {
+	count := 0
+	offset := ""
+	for {
+		datas, nextOffset, err := e.source.Scroll(ctx, e.batchSize, offset)
+		if err != nil {
+			return count, fmt.Errorf("%w: %w", ErrExport, err)
+		}
+
+		for _, data := range datas {
+			if e.limit > 0 && count >= e.limit {
+				return count, nil
+			}
+
+			if err = fn(data); err != nil {
+				return count, fmt.Errorf("%w: %w", ErrExport, err)
+			}
+			count++
+		}
+
+		if nextOffset == "" || (e.limit > 0 && count >= e.limit) {
+			return count, nil
+		}
+		offset = nextOffset
+	}
+}
+
+// tsvValue formats a metadata value on a single TSV cell.
+func tsvValue(value any) string {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		text = v
+	default:
+		text = fmt.Sprint(v)
+	}
+
+	return strings.Join(strings.Fields(text), " ")
+}
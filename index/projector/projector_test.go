@@ -0,0 +1,99 @@
+package projector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+	"github.com/henomis/lingoose/types"
+)
+
+func newTestDB(t *testing.T) *jsondb.DB {
+	t.Helper()
+
+	db := jsondb.New()
+	err := db.Insert(context.Background(), []index.Data{
+		{ID: "a", Values: []float64{1, 0.5}, Metadata: types.Meta{index.DefaultKeyContent: "first\tline\nsecond", "source": "x.txt"}},
+		{ID: "b", Values: []float64{0, -2}, Metadata: types.Meta{index.DefaultKeyContent: "other"}},
+		{ID: "c", Values: []float64{3, 1e-7}, Metadata: types.Meta{index.DefaultKeyContent: "last", "source": "y.txt"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestExporter_WriteTSV(t *testing.T) {
+	var vectors, metadata bytes.Buffer
+	count, err := New(newTestDB(t)).
+		WithMetadataKeys(index.DefaultKeyContent, "source").
+		WithBatchSize(2).
+		WriteTSV(context.Background(), &vectors, &metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 3 {
+		t.Errorf("exported %d points, want 3", count)
+	}
+	if want := "1\t0.5\n0\t-2\n3\t1e-07\n"; vectors.String() != want {
+		t.Errorf("vectors = %q, want %q", vectors.String(), want)
+	}
+	wantMetadata := "id\tcontent\tsource\na\tfirst line second\tx.txt\nb\tother\t\nc\tlast\ty.txt\n"
+	if metadata.String() != wantMetadata {
+		t.Errorf("metadata = %q, want %q", metadata.String(), wantMetadata)
+	}
+}
+
+func TestExporter_WriteTSVLimit(t *testing.T) {
+	var vectors, metadata bytes.Buffer
+	count, err := New(newTestDB(t)).
+		WithMetadataKeys().
+		WithLimit(2).
+		WriteTSV(context.Background(), &vectors, &metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 2 || strings.Count(vectors.String(), "\n") != 2 {
+		t.Errorf("exported %d points, want 2", count)
+	}
+	if metadata.String() != "a\nb\n" {
+		t.Errorf("metadata = %q, want only the IDs", metadata.String())
+	}
+}
+
+func TestExporter_WriteTSVDimensions(t *testing.T) {
+	db := newTestDB(t)
+	err := db.Insert(context.Background(), []index.Data{{ID: "d", Values: []float64{1, 2, 3}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vectors, metadata bytes.Buffer
+	_, err = New(db).WriteTSV(context.Background(), &vectors, &metadata)
+	if !errors.Is(err, ErrExport) {
+		t.Fatalf("expected ErrExport, got %v", err)
+	}
+}
+
+func TestExporter_WriteJSONL(t *testing.T) {
+	var out bytes.Buffer
+	count, err := New(newTestDB(t)).WithMetadataKeys("source").WriteJSONL(context.Background(), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"id":"a","embedding":[1,0.5],"metadata":{"source":"x.txt"}}
+{"id":"b","embedding":[0,-2]}
+{"id":"c","embedding":[3,1e-7],"metadata":{"source":"y.txt"}}
+`
+	if count != 3 || out.String() != want {
+		t.Errorf("WriteJSONL() = %d %q, want 3 %q", count, out.String(), want)
+	}
+}
@@ -0,0 +1,260 @@
+// Package router sends the documents to different indexes based on their metadata or on
+// a classification, e.g. code and prose or per-language collections, and fans out the
+// queries across them.
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/option"
+	"github.com/henomis/lingoose/language"
+)
+
+const (
+	// RouteMetadataKey is the metadata key tagging the query results with the route of
+	// the index they come from.
+	RouteMetadataKey = "index-route"
+
+	defaultTopK = 10
+)
+
+var (
+	ErrNoRoute = errors.New("no index for route")
+)
+
+// RouteFn returns the route of a document, the name of the index it's added to.
+type RouteFn func(ctx context.Context, doc document.Document) (string, error)
+
+// QueryRouteFn returns the routes of the indexes a query is sent to, all of them if
+// none is returned.
+type QueryRouteFn func(ctx context.Context, query string) ([]string, error)
+
+type Router struct {
+	routeFn      RouteFn
+	queryRouteFn QueryRouteFn
+	indexes      map[string]*index.Index
+	routes       []string
+	defaultRoute string
+	topK         int
+}
+
+func New(routeFn RouteFn) *Router {
+	return &Router{
+		routeFn: routeFn,
+		indexes: make(map[string]*index.Index),
+		topK:    defaultTopK,
+	}
+}
+
+// WithTopK sets the number of results returned by Retrieve.
+func (r *Router) WithTopK(topK int) *Router {
+	r.topK = topK
+	return r
+}
+
+// WithIndex adds the index of the route.
+func (r *Router) WithIndex(route string, idx *index.Index) *Router {
+	if _, ok := r.indexes[route]; !ok {
+		r.routes = append(r.routes, route)
+	}
+	r.indexes[route] = idx
+	return r
+}
+
+// WithDefault sets the route of the documents whose route has no index. Without a
+// default route they make LoadFromDocuments fail with ErrNoRoute.
+func (r *Router) WithDefault(route string) *Router {
+	r.defaultRoute = route
+	return r
+}
+
+// WithQueryRoute sets the function selecting the indexes a query is sent to, e.g. the
+// collection of the language of the query. Queries are sent to all the indexes by
+// default.
+func (r *Router) WithQueryRoute(queryRouteFn QueryRouteFn) *Router {
+	r.queryRouteFn = queryRouteFn
+	return r
+}
+
+// ByMetadata routes the documents by the value of a metadata key.
+func ByMetadata(key string) RouteFn {
+	return func(_ context.Context, doc document.Document) (string, error) {
+		value, ok := doc.Metadata[key]
+		if !ok || value == nil {
+			return "", nil
+		}
+
+		return fmt.Sprint(value), nil
+	}
+}
+
+// ByLanguage routes the documents by the ISO 639-1 code of their language. Documents
+// whose language can't be detected go to the default route.
+func ByLanguage(detector language.Detector) RouteFn {
+	return func(ctx context.Context, doc document.Document) (string, error) {
+		lang, err := detector.Detect(ctx, doc.Content)
+		if err != nil {
+			return "", err
+		}
+
+		return string(lang), nil
+	}
+}
+
+// QueryByLanguage sends the queries to the index of their language, or to all the
+// indexes if it can't be detected.
+func QueryByLanguage(detector language.Detector) QueryRouteFn {
+	return func(ctx context.Context, query string) ([]string, error) {
+		lang, err := detector.Detect(ctx, query)
+		if err != nil || lang == language.Unknown {
+			return nil, err
+		}
+
+		return []string{string(lang)}, nil
+	}
+}
+
+// LoadFromDocuments adds each document to the index of its route.
+func (r *Router) LoadFromDocuments(ctx context.Context, documents []document.Document) error {
+	byRoute := make(map[string][]document.Document)
+	for _, doc := range documents {
+		route, err := r.route(ctx, doc)
+		if err != nil {
+			return err
+		}
+
+		byRoute[route] = append(byRoute[route], doc)
+	}
+
+	for _, route := range r.routes {
+		if len(byRoute[route]) == 0 {
+			continue
+		}
+
+		err := r.indexes[route].LoadFromDocuments(ctx, byRoute[route])
+		if err != nil {
+			return fmt.Errorf("route %s: %w", route, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Router) route(ctx context.Context, doc document.Document) (string, error) {
+	route, err := r.routeFn(ctx, doc)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := r.indexes[route]; ok {
+		return route, nil
+	}
+
+	if _, ok := r.indexes[r.defaultRoute]; ok {
+		return r.defaultRoute, nil
+	}
+
+	return "", fmt.Errorf("%w %q", ErrNoRoute, route)
+}
+
+// Query sends the query to the indexes concurrently and returns the best results by
+// score, tagged with their route in the RouteMetadataKey metadata. Scores are comparable
+// only across indexes using the same embedder and distance.
+func (r *Router) Query(ctx context.Context, query string, opts ...option.Option) (index.SearchResults, error) {
+	routes, err := r.queryRoutes(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	routeResults := make([]index.SearchResults, len(routes))
+	errs := make([]error, len(routes))
+	for j, route := range routes {
+		wg.Add(1)
+		go func(j int, route string) {
+			defer wg.Done()
+
+			routeResults[j], errs[j] = r.indexes[route].Query(ctx, query, opts...)
+			if errs[j] != nil {
+				errs[j] = fmt.Errorf("route %s: %w", route, errs[j])
+			}
+		}(j, route)
+	}
+	wg.Wait()
+
+	err = errors.Join(errs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var results index.SearchResults
+	for j, route := range routes {
+		for _, result := range routeResults[j] {
+			result.Metadata = index.DeepCopyMetadata(result.Metadata)
+			result.Metadata[RouteMetadataKey] = route
+			results = append(results, result)
+		}
+	}
+
+	options := &option.Options{TopK: defaultTopK}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > options.TopK {
+		results = results[:options.TopK]
+	}
+
+	return results, nil
+}
+
+// Retrieve returns the contents of the best results, to be used as the RAG of an
+// assistant.
+func (r *Router) Retrieve(ctx context.Context, query string) ([]string, error) {
+	results, err := r.Query(ctx, query, option.WithTopK(r.topK))
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, 0, len(results))
+	for _, result := range results {
+		if content, ok := result.Metadata[index.DefaultKeyContent].(string); ok {
+			texts = append(texts, content)
+		}
+	}
+
+	return texts, nil
+}
+
+func (r *Router) queryRoutes(ctx context.Context, query string) ([]string, error) {
+	if r.queryRouteFn == nil {
+		return r.routes, nil
+	}
+
+	selected, err := r.queryRouteFn(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []string
+	for _, route := range selected {
+		if _, ok := r.indexes[route]; ok {
+			routes = append(routes, route)
+		}
+	}
+
+	if len(routes) == 0 {
+		return r.routes, nil
+	}
+
+	return routes, nil
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+	"github.com/henomis/lingoose/language"
+	"github.com/henomis/lingoose/types"
+)
+
+// keywordEmbedder embeds the texts by the keywords they contain.
+type keywordEmbedder struct {
+	keywords []string
+}
+
+func (e keywordEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	embeddings := make([]embedder.Embedding, len(texts))
+	for j, text := range texts {
+		embedding := make(embedder.Embedding, len(e.keywords)+1)
+		embedding[len(e.keywords)] = 0.1
+		for k, keyword := range e.keywords {
+			if strings.Contains(strings.ToLower(text), keyword) {
+				embedding[k] = 1
+			}
+		}
+		embeddings[j] = embedding
+	}
+
+	return embeddings, nil
+}
+
+func newIndex() *index.Index {
+	return index.New(jsondb.New(), keywordEmbedder{keywords: []string{"func", "gatto", "cat"}})
+}
+
+func contents(results index.SearchResults) map[string]string {
+	byContent := make(map[string]string)
+	for _, result := range results {
+		byContent[result.Content()] = result.Metadata[RouteMetadataKey].(string)
+	}
+
+	return byContent
+}
+
+func TestRouterByMetadata(t *testing.T) {
+	ctx := context.Background()
+	router := New(ByMetadata("type")).
+		WithIndex("code", newIndex()).
+		WithIndex("prose", newIndex()).
+		WithDefault("prose")
+
+	err := router.LoadFromDocuments(ctx, []document.Document{
+		{Content: "func main() {}", Metadata: types.Meta{"type": "code"}},
+		{Content: "the cat sleeps", Metadata: types.Meta{"type": "prose"}},
+		{Content: "a cat without type", Metadata: types.Meta{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := router.Query(ctx, "cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"func main() {}":     "code",
+		"the cat sleeps":     "prose",
+		"a cat without type": "prose",
+	}
+	if got := contents(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+
+	texts, err := router.WithTopK(2).Retrieve(ctx, "cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(texts)
+	if !reflect.DeepEqual(texts, []string{"a cat without type", "the cat sleeps"}) {
+		t.Errorf("Retrieve() = %v", texts)
+	}
+}
+
+func TestRouterNoRoute(t *testing.T) {
+	router := New(ByMetadata("type")).WithIndex("code", newIndex())
+
+	err := router.LoadFromDocuments(context.Background(), []document.Document{
+		{Content: "the cat sleeps", Metadata: types.Meta{"type": "prose"}},
+	})
+	if !errors.Is(err, ErrNoRoute) {
+		t.Errorf("error = %v, want %v", err, ErrNoRoute)
+	}
+}
+
+func TestRouterByLanguage(t *testing.T) {
+	ctx := context.Background()
+	detector := language.NewStopwordDetector()
+	router := New(ByLanguage(detector)).
+		WithIndex(string(language.English), newIndex()).
+		WithIndex(string(language.Italian), newIndex()).
+		WithQueryRoute(QueryByLanguage(detector))
+
+	err := router.LoadFromDocuments(ctx, []document.Document{
+		{Content: "The cat is sleeping on the sofa and it is happy", Metadata: types.Meta{}},
+		{Content: "Il gatto dorme sul divano ed è felice con la sua famiglia", Metadata: types.Meta{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := router.Query(ctx, "Dove dorme il gatto della famiglia?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"Il gatto dorme sul divano ed è felice con la sua famiglia": "it"}
+	if got := contents(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package index
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+)
+
+const (
+	// OriginMetadataKey is the metadata key tagging the results of the indexes with an
+	// alternate index, set to OriginPrimary or OriginAlternate.
+	OriginMetadataKey = "index-origin"
+	OriginPrimary     = "primary"
+	OriginAlternate   = "alternate"
+
+	routingBuckets = 10000
+)
+
+// RoutingKeyFn returns the key a query is routed by: queries with the same key always go
+// to the same index, e.g. to keep each user on the same side of the experiment. An empty
+// key routes the query randomly.
+type RoutingKeyFn func(ctx context.Context, query string) string
+
+type routing struct {
+	alternate  *Index
+	percentage float64
+	keyFn      RoutingKeyFn
+}
+
+// WithAlternate routes the given percentage (0-100) of the queries to the alternate
+// index, e.g. a collection embedded with a new model, to migrate the retrieval online.
+// The results of both indexes are tagged with their origin in the OriginMetadataKey
+// metadata, the query callback of this index sees both. Only Query is routed: Search,
+// Add and the other operations use this index.
+func (i *Index) WithAlternate(alternate *Index, percentage float64) *Index {
+	i.routing = &routing{
+		alternate:  alternate,
+		percentage: percentage,
+	}
+	return i
+}
+
+// WithRoutingKey sets the function returning the routing key of the queries, see
+// RoutingKeyFn. Queries are routed randomly by default.
+func (i *Index) WithRoutingKey(keyFn RoutingKeyFn) *Index {
+	if i.routing != nil {
+		i.routing.keyFn = keyFn
+	}
+	return i
+}
+
+// routeToAlternate reports whether the query goes to the alternate index.
+func (r *routing) routeToAlternate(ctx context.Context, query string) bool {
+	if r.percentage <= 0 {
+		return false
+	}
+
+	var key string
+	if r.keyFn != nil {
+		key = r.keyFn(ctx, query)
+	}
+
+	var bucket int
+	if key == "" {
+		//nolint:gosec
+		bucket = rand.Intn(routingBuckets)
+	} else {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(key))
+		bucket = int(hash.Sum32() % routingBuckets)
+	}
+
+	return float64(bucket) < r.percentage*routingBuckets/100
+}
+
+// tagOrigin sets the origin of the results, copying their metadata so that the vector
+// database records are left untouched.
+func tagOrigin(results SearchResults, origin string) SearchResults {
+	for j := range results {
+		results[j].Metadata = DeepCopyMetadata(results[j].Metadata)
+		results[j].Metadata[OriginMetadataKey] = origin
+	}
+
+	return results
+}
@@ -0,0 +1,99 @@
+package index
+
+import (
+	"context"
+	"testing"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/index/option"
+	"github.com/henomis/lingoose/types"
+)
+
+type staticEmbedder struct{}
+
+func (staticEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	embeddings := make([]embedder.Embedding, len(texts))
+	for j := range texts {
+		embeddings[j] = embedder.Embedding{1, 0}
+	}
+
+	return embeddings, nil
+}
+
+// staticDB returns a single record with the given ID.
+type staticDB struct {
+	id       string
+	metadata types.Meta
+	searches int
+}
+
+func (db *staticDB) Insert(context.Context, []Data) error { return nil }
+
+func (db *staticDB) IsEmpty(context.Context) (bool, error) { return false, nil }
+
+func (db *staticDB) Search(context.Context, []float64, *option.Options) (SearchResults, error) {
+	db.searches++
+	return SearchResults{{Data: Data{ID: db.id, Metadata: db.metadata}, Score: 1}}, nil
+}
+
+func (db *staticDB) Drop(context.Context) error { return nil }
+
+func (db *staticDB) Delete(context.Context, []string) error { return nil }
+
+func TestIndexAlternateRouting(t *testing.T) {
+	primaryDB := &staticDB{id: "primary", metadata: types.Meta{}}
+	alternateDB := &staticDB{id: "alternate", metadata: types.Meta{}}
+
+	origins := map[string]int{}
+	idx := New(primaryDB, staticEmbedder{}).
+		WithAlternate(New(alternateDB, staticEmbedder{}), 25).
+		WithQueryCallback(func(_ context.Context, _ string, results SearchResults) {
+			origins[results[0].Metadata[OriginMetadataKey].(string)]++
+		})
+
+	const queries = 2000
+	for j := 0; j < queries; j++ {
+		results, err := idx.Query(context.Background(), "query")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		origin := results[0].Metadata[OriginMetadataKey]
+		if results[0].ID != origin {
+			t.Fatalf("result %s tagged as %v", results[0].ID, origin)
+		}
+	}
+
+	if alternateDB.searches < queries/10 || alternateDB.searches > queries*4/10 {
+		t.Errorf("alternate searched %d times out of %d, want about 25%%", alternateDB.searches, queries)
+	}
+	if origins[OriginPrimary] != primaryDB.searches || origins[OriginAlternate] != alternateDB.searches {
+		t.Errorf("callback origins = %v", origins)
+	}
+	if len(primaryDB.metadata) != 0 || len(alternateDB.metadata) != 0 {
+		t.Errorf("stored metadata modified")
+	}
+}
+
+func TestIndexRoutingKey(t *testing.T) {
+	alternateDB := &staticDB{id: "alternate"}
+	idx := New(&staticDB{id: "primary"}, staticEmbedder{}).
+		WithAlternate(New(alternateDB, staticEmbedder{}), 50).
+		WithRoutingKey(func(_ context.Context, _ string) string {
+			return "user-1"
+		})
+
+	var first string
+	for j := 0; j < 20; j++ {
+		results, err := idx.Query(context.Background(), "query")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if j == 0 {
+			first = results[0].ID
+		} else if results[0].ID != first {
+			t.Fatalf("query %d routed to %s, previous ones to %s", j, results[0].ID, first)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package index
+
+import (
+	"context"
+	"sync"
+
+	"github.com/henomis/lingoose/document"
+)
+
+// upserter runs the upserts of the batches on up to concurrency workers. After the
+// first failure the context is cancelled and no more batches are started.
+type upserter struct {
+	index     *Index
+	ctx       context.Context
+	cancel    context.CancelFunc
+	semaphore chan struct{}
+	wg        sync.WaitGroup
+	errOnce   sync.Once
+	err       error
+}
+
+func (i *Index) newUpserter(ctx context.Context) *upserter {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &upserter{
+		index:     i,
+		ctx:       ctx,
+		cancel:    cancel,
+		semaphore: make(chan struct{}, max(i.concurrency, 1)),
+	}
+}
+
+// upsert starts the upsert of the documents, waiting for a free worker. It reports
+// false if a previous upsert failed or the context is done.
+func (u *upserter) upsert(documents []document.Document) bool {
+	select {
+	case <-u.ctx.Done():
+		return false
+	case u.semaphore <- struct{}{}:
+	}
+
+	if u.ctx.Err() != nil {
+		<-u.semaphore
+		return false
+	}
+
+	u.wg.Add(1)
+	go func() {
+		defer func() {
+			<-u.semaphore
+			u.wg.Done()
+		}()
+
+		err := u.index.upsert(u.ctx, documents)
+		if err != nil {
+			u.errOnce.Do(func() {
+				u.err = err
+				u.cancel()
+			})
+		}
+	}()
+
+	return true
+}
+
+// wait waits for the running upserts and returns the first error.
+func (u *upserter) wait() error {
+	u.wg.Wait()
+	u.cancel()
+
+	return u.err
+}
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/henomis/lingoose/embedder"
@@ -19,24 +22,44 @@ import (
 var _ index.VectorDB = &DB{}
 
 type data struct {
-	ID       string     `json:"id"`
-	Metadata types.Meta `json:"metadata"`
-	Values   []float64  `json:"values"`
+	ID        string     `json:"id"`
+	Metadata  types.Meta `json:"metadata"`
+	Values    []float64  `json:"values,omitempty"`
+	Float16   []uint16   `json:"float16,omitempty"`
+	Binary    []byte     `json:"binary,omitempty"`
+	Dimension int        `json:"dimension,omitempty"`
 }
 
+// Precision is the format used to store the vectors.
+type Precision string
+
+const (
+	PrecisionFloat64 Precision = "float64"
+	PrecisionFloat16 Precision = "float16"
+	// PrecisionBinary stores one bit per dimension and searches by Hamming distance.
+	PrecisionBinary Precision = "binary"
+)
+
 // DB is a simple in-memory vector database
 // that stores the data in a json file only
 // if the persist option is enabled.
+// It is safe for concurrent use.
 type DB struct {
-	data   []data
-	dbPath string
+	mu          sync.Mutex
+	data        []data
+	dbPath      string
+	vectorsPath string
+	vectors     *vectorFile
+	precision   Precision
+	objectSync  *objectSync
 }
 
 type FilterFn func([]index.SearchResult) []index.SearchResult
 
 func New() *DB {
 	index := &DB{
-		data: []data{},
+		data:      []data{},
+		precision: PrecisionFloat64,
 	}
 
 	return index
@@ -47,8 +70,45 @@ func (d *DB) WithPersist(dbPath string) *DB {
 	return d
 }
 
+// WithPrecision sets the format used to store the vectors. It must be set before
+// any data is inserted.
+func (d *DB) WithPrecision(precision Precision) *DB {
+	d.precision = precision
+	return d
+}
+
+// WithMemoryMappedVectors stores the vectors in a separate binary file that is
+// memory-mapped instead of being kept in the json database. This allows to use
+// indexes larger than the available memory and speeds up the database loading.
+func (d *DB) WithMemoryMappedVectors(vectorsPath string) *DB {
+	d.vectorsPath = vectorsPath
+	return d
+}
+
+// Close uploads the pending changes to the object store and releases the memory-mapped
+// vectors file, if any.
+func (d *DB) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.objectSync != nil {
+		err := d.objectSync.close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.vectors == nil {
+		return nil
+	}
+
+	err := d.vectors.Close()
+	d.vectors = nil
+	return err
+}
+
 func (d *DB) save() error {
-	if d.dbPath == "" {
+	if d.dbPath == "" && d.objectSync == nil {
 		return nil
 	}
 
@@ -57,10 +117,86 @@ func (d *DB) save() error {
 		return err
 	}
 
-	return os.WriteFile(d.dbPath, jsonContent, 0600)
+	if d.dbPath != "" {
+		err = writeFileAtomic(d.dbPath, jsonContent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.saveObject(jsonContent)
+}
+
+// writeFileAtomic writes the content to a temporary file renamed over the path, so a
+// failed write keeps the previous content.
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
 }
 
 func (d *DB) load() error {
+	err := d.loadVectors()
+	if err != nil {
+		return err
+	}
+
+	err = d.loadData()
+	if err != nil {
+		return err
+	}
+
+	if d.vectors != nil && d.vectors.Len() != len(d.data) {
+		return fmt.Errorf("%w: found %d vectors for %d records", ErrInvalidVectorFile, d.vectors.Len(), len(d.data))
+	}
+
+	return nil
+}
+
+func (d *DB) loadVectors() error {
+	if d.vectorsPath == "" || d.vectors != nil {
+		return nil
+	}
+
+	vectors, err := openVectorFile(d.vectorsPath)
+	if err != nil {
+		return err
+	}
+
+	// without persistence the records of a previous run are lost, so are its vectors
+	if d.dbPath == "" && vectors.Len() > 0 {
+		err = vectors.Rewrite(nil)
+		if err != nil {
+			_ = vectors.Close()
+			return err
+		}
+	}
+
+	d.vectors = vectors
+	return nil
+}
+
+func (d *DB) loadData() error {
+	err := d.loadObject()
+	if err != nil {
+		return err
+	}
+
 	if d.dbPath == "" {
 		return nil
 	}
@@ -82,6 +218,9 @@ func (d *DB) load() error {
 }
 
 func (d *DB) IsEmpty(_ context.Context) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	err := d.load()
 	if err != nil {
 		return true, fmt.Errorf("%w: %w", index.ErrInternal, err)
@@ -91,6 +230,9 @@ func (d *DB) IsEmpty(_ context.Context) (bool, error) {
 }
 
 func (d *DB) Insert(ctx context.Context, datas []index.Data) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	_ = ctx
 	err := d.load()
 	if err != nil {
@@ -98,6 +240,7 @@ func (d *DB) Insert(ctx context.Context, datas []index.Data) error {
 	}
 
 	var records []data
+	var vectors [][]float64
 	for _, item := range datas {
 		if item.ID == "" {
 			id, errUUID := uuid.NewUUID()
@@ -112,15 +255,57 @@ func (d *DB) Insert(ctx context.Context, datas []index.Data) error {
 			Values:   item.Values,
 			Metadata: item.Metadata,
 		}
+		d.quantize(&point)
+
+		if d.vectors != nil {
+			vectors = append(vectors, point.vector())
+			point = data{
+				ID:       point.ID,
+				Metadata: point.Metadata,
+			}
+		}
+
 		records = append(records, point)
 	}
 
+	recordsBefore, vectorsBefore := len(d.data), 0
+	if d.vectors != nil {
+		vectorsBefore = d.vectors.Len()
+		err = d.vectors.Append(vectors)
+		if err != nil {
+			return fmt.Errorf("%w: %w", index.ErrInternal, err)
+		}
+	}
+
 	d.data = append(d.data, records...)
 
-	return d.save()
+	err = d.save()
+	if err != nil {
+		return d.rollbackInsert(recordsBefore, vectorsBefore, err)
+	}
+
+	return nil
+}
+
+// rollbackInsert removes the records and the vectors of an insert that failed to be
+// saved, so that the vectors file keeps matching the saved records.
+func (d *DB) rollbackInsert(records int, vectors int, err error) error {
+	d.data = d.data[:records]
+
+	if d.vectors != nil {
+		err = errors.Join(err, d.vectors.Truncate(vectors))
+	}
+
+	// the database file may have been written before the object store failed
+	_ = d.save()
+
+	return fmt.Errorf("%w: %w", index.ErrInternal, err)
 }
 
 func (d *DB) Search(ctx context.Context, values []float64, options *option.Options) (index.SearchResults, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	err := d.load()
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", index.ErrInternal, err)
@@ -130,12 +315,26 @@ func (d *DB) Search(ctx context.Context, values []float64, options *option.Optio
 }
 
 func (d *DB) Drop(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	_ = ctx
 	d.data = []data{}
+
+	if d.vectors != nil {
+		err := d.vectors.Rewrite(nil)
+		if err != nil {
+			return fmt.Errorf("%w: %w", index.ErrInternal, err)
+		}
+	}
+
 	return d.save()
 }
 
 func (d *DB) Delete(ctx context.Context, ids []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	_ = ctx
 	err := d.load()
 	if err != nil {
@@ -143,7 +342,8 @@ func (d *DB) Delete(ctx context.Context, ids []string) error {
 	}
 
 	var newRecords []data
-	for _, record := range d.data {
+	var newVectors [][]float64
+	for j, record := range d.data {
 		found := false
 		for _, id := range ids {
 			if record.ID == id {
@@ -153,6 +353,16 @@ func (d *DB) Delete(ctx context.Context, ids []string) error {
 		}
 		if !found {
 			newRecords = append(newRecords, record)
+			if d.vectors != nil {
+				newVectors = append(newVectors, d.vectors.At(j))
+			}
+		}
+	}
+
+	if d.vectors != nil {
+		err = d.vectors.Rewrite(newVectors)
+		if err != nil {
+			return fmt.Errorf("%w: %w", index.ErrInternal, err)
 		}
 	}
 
@@ -161,6 +371,48 @@ func (d *DB) Delete(ctx context.Context, ids []string) error {
 	return d.save()
 }
 
+// Scroll returns a page of up to limit points, with their vectors, and the offset of the
+// next page, an empty offset meaning there are no more points. It makes the database a
+// migrate.Source.
+func (d *DB) Scroll(_ context.Context, limit int, offset string) ([]index.Data, string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	err := d.load()
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", index.ErrInternal, err)
+	}
+
+	start := 0
+	if offset != "" {
+		start, err = strconv.Atoi(offset)
+		if err != nil || start < 0 {
+			return nil, "", fmt.Errorf("%w: invalid offset %q", index.ErrInternal, offset)
+		}
+	}
+
+	end := len(d.data)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+
+	var datas []index.Data
+	for j := start; j < end; j++ {
+		datas = append(datas, index.Data{
+			ID:       d.data[j].ID,
+			Values:   d.vectorAt(j),
+			Metadata: index.DeepCopyMetadata(d.data[j].Metadata),
+		})
+	}
+
+	nextOffset := ""
+	if end < len(d.data) {
+		nextOffset = strconv.Itoa(end)
+	}
+
+	return datas, nextOffset, nil
+}
+
 func (d *DB) similaritySearch(
 	_ context.Context,
 	embedding embedder.Embedding,
@@ -170,7 +422,13 @@ func (d *DB) similaritySearch(
 		opts = index.GetDefaultOptions()
 	}
 
-	scores, err := d.cosineSimilarityBatch(embedding)
+	var scores []float64
+	var err error
+	if d.precision == PrecisionBinary {
+		scores = d.hammingSimilarityBatch(embedding)
+	} else {
+		scores, err = d.cosineSimilarityBatch(embedding)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", index.ErrInternal, err)
 	}
@@ -181,11 +439,15 @@ func (d *DB) similaritySearch(
 		searchResults[j] = index.SearchResult{
 			Data: index.Data{
 				ID:       d.data[j].ID,
-				Values:   d.data[j].Values,
+				Values:   d.vectorAt(j),
 				Metadata: d.data[j].Metadata,
 			},
 			Score: score,
 		}
+
+		if opts.Explain {
+			searchResults[j].Explanation = d.explain(score, len(embedding))
+		}
 	}
 
 	if opts.Filter != nil {
@@ -195,6 +457,24 @@ func (d *DB) similaritySearch(
 	return filterSearchResults(searchResults, opts.TopK), nil
 }
 
+// explain returns the explanation of a score: the cosine similarity, or the normalized
+// Hamming distance of the binary vectors.
+func (d *DB) explain(score float64, dimensions int) *index.Explanation {
+	if d.precision != PrecisionBinary {
+		return &index.Explanation{
+			Metric:        "cosine",
+			Distance:      score,
+			Normalization: index.NormalizationNone,
+		}
+	}
+
+	return &index.Explanation{
+		Metric:        "hamming",
+		Distance:      math.Round((1 - score) * float64(dimensions)),
+		Normalization: "1 - distance/dimensions",
+	}
+}
+
 func (d *DB) cosineSimilarity(a []float64, b []float64) (cosine float64, err error) {
 	var count int
 	lengthA := len(a)
@@ -231,7 +511,7 @@ func (d *DB) cosineSimilarityBatch(a embedder.Embedding) ([]float64, error) {
 	scores := make([]float64, len(d.data))
 
 	for j := range d.data {
-		scores[j], err = d.cosineSimilarity(a, d.data[j].Values)
+		scores[j], err = d.cosineSimilarity(a, d.vectorAt(j))
 		if err != nil {
 			return nil, err
 		}
@@ -240,6 +520,56 @@ func (d *DB) cosineSimilarityBatch(a embedder.Embedding) ([]float64, error) {
 	return scores, nil
 }
 
+// hammingSimilarityBatch returns 1 minus the normalized Hamming distance
+// between the binary quantized vectors.
+func (d *DB) hammingSimilarityBatch(a embedder.Embedding) []float64 {
+	query := a.ToBinary()
+	scores := make([]float64, len(d.data))
+
+	for j := range d.data {
+		stored := d.data[j].Binary
+		if stored == nil {
+			stored = embedder.Embedding(d.vectorAt(j)).ToBinary()
+		}
+
+		scores[j] = 1 - float64(embedder.HammingDistance(query, stored))/float64(len(a))
+	}
+
+	return scores
+}
+
+func (d *DB) quantize(point *data) {
+	switch d.precision {
+	case PrecisionFloat16:
+		point.Float16 = embedder.Embedding(point.Values).ToFloat16()
+		point.Values = nil
+	case PrecisionBinary:
+		point.Binary = embedder.Embedding(point.Values).ToBinary()
+		point.Dimension = len(point.Values)
+		point.Values = nil
+	case PrecisionFloat64:
+	}
+}
+
+func (d *DB) vectorAt(j int) []float64 {
+	if d.vectors != nil {
+		return d.vectors.At(j)
+	}
+
+	return d.data[j].vector()
+}
+
+func (r *data) vector() []float64 {
+	switch {
+	case r.Float16 != nil:
+		return embedder.NewEmbeddingFromFloat16(r.Float16)
+	case r.Binary != nil:
+		return embedder.NewEmbeddingFromBinary(r.Binary, r.Dimension)
+	}
+
+	return r.Values
+}
+
 func filterSearchResults(searchResults index.SearchResults, topK int) index.SearchResults {
 	//sort by similarity score
 	sort.Slice(searchResults, func(i, j int) bool {
@@ -0,0 +1,63 @@
+package jsondb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/option"
+	"github.com/henomis/lingoose/types"
+)
+
+func TestSearchExplain(t *testing.T) {
+	tests := []struct {
+		precision Precision
+		values    []float64
+		want      index.Explanation
+	}{
+		{
+			precision: PrecisionFloat64,
+			values:    []float64{1, 0, 0, 0},
+			want:      index.Explanation{Metric: "cosine", Distance: 0.5, Normalization: index.NormalizationNone},
+		},
+		{
+			precision: PrecisionBinary,
+			values:    []float64{1, 1, -1, -1},
+			want:      index.Explanation{Metric: "hamming", Distance: 1, Normalization: "1 - distance/dimensions"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.precision), func(t *testing.T) {
+			db := New().WithPrecision(tt.precision)
+			err := db.Insert(context.Background(), []index.Data{
+				{ID: "a", Values: []float64{1, -1, -1, -1}, Metadata: types.Meta{}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			idx := index.New(db, nil)
+			results, err := idx.Search(context.Background(), tt.values, option.WithExplain())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(results) != 1 || results[0].Explanation == nil {
+				t.Fatalf("results = %v", results)
+			}
+			if got := *results[0].Explanation; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("explanation = %+v, want %+v", got, tt.want)
+			}
+
+			results, err = idx.Search(context.Background(), tt.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if results[0].Explanation != nil {
+				t.Errorf("explanation without WithExplain")
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+package jsondb
+
+import (
+	"io"
+	"os"
+)
+
+// mmapFile falls back to reading the whole file on platforms without mmap support.
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	mapping := make([]byte, size)
+	_, err := io.ReadFull(io.NewSectionReader(file, 0, int64(size)), mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+func munmapFile(_ []byte) error {
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package jsondb
+
+import (
+	"os"
+	"syscall"
+)
+
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmapFile(mapping []byte) error {
+	return syscall.Munmap(mapping)
+}
@@ -0,0 +1,169 @@
+package jsondb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	ErrObjectStore    = errors.New("object store error")
+)
+
+// ObjectStore is an object storage service, such as S3 or GCS, the database is loaded
+// from and synced to. Get returns ErrObjectNotFound if the object doesn't exist.
+type ObjectStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+type objectSync struct {
+	store    ObjectStore
+	key      string
+	interval time.Duration
+	loaded   bool
+
+	mu      sync.Mutex
+	pending []byte
+	stop    chan struct{}
+	done    chan struct{}
+	err     error
+}
+
+// WithObjectStore loads the database from the object stored at key, when it exists, and
+// uploads it after each change, or every WithSyncInterval. The local file set by
+// WithPersist, if any, is kept as a cache of the object. Memory-mapped vectors are not
+// synced.
+func (d *DB) WithObjectStore(store ObjectStore, key string) *DB {
+	d.objectSync = &objectSync{
+		store: store,
+		key:   key,
+	}
+	return d
+}
+
+// WithSyncInterval batches the uploads to the object store, syncing the changes in the
+// background at most once per interval instead of after each change. Call Sync or
+// Close to upload the pending changes before exiting.
+func (d *DB) WithSyncInterval(interval time.Duration) *DB {
+	if d.objectSync != nil {
+		d.objectSync.interval = interval
+	}
+	return d
+}
+
+// Sync uploads the pending changes to the object store, returning the error of the
+// last failed background sync, if any.
+func (d *DB) Sync(ctx context.Context) error {
+	if d.objectSync == nil {
+		return nil
+	}
+
+	return d.objectSync.sync(ctx)
+}
+
+func (d *DB) loadObject() error {
+	s := d.objectSync
+	if s == nil || s.loaded || len(d.data) > 0 {
+		return nil
+	}
+
+	content, err := s.store.Get(context.Background(), s.key)
+	if errors.Is(err, ErrObjectNotFound) {
+		s.loaded = true
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%w: %w", ErrObjectStore, err)
+	}
+
+	err = json.Unmarshal(content, &d.data)
+	if err != nil {
+		return err
+	}
+	s.loaded = true
+
+	if d.dbPath != "" {
+		return os.WriteFile(d.dbPath, content, 0600)
+	}
+
+	return nil
+}
+
+func (d *DB) saveObject(jsonContent []byte) error {
+	s := d.objectSync
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.pending = jsonContent
+	s.mu.Unlock()
+
+	if s.interval <= 0 {
+		return s.sync(context.Background())
+	}
+
+	if s.stop == nil {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.loop()
+	}
+
+	return nil
+}
+
+func (s *objectSync) loop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			err := s.sync(context.Background())
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *objectSync) sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == nil {
+		err := s.err
+		s.err = nil
+		return err
+	}
+
+	err := s.store.Put(ctx, s.key, s.pending)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrObjectStore, err)
+	}
+
+	s.pending = nil
+	s.err = nil
+
+	return nil
+}
+
+// close stops the background sync and uploads the pending changes.
+func (s *objectSync) close() error {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+		s.stop = nil
+	}
+
+	return s.sync(context.Background())
+}
@@ -0,0 +1,161 @@
+// Package gcs stores the JsonDB database in a Google Cloud Storage bucket.
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+	"github.com/henomis/lingoose/llm/httperror"
+)
+
+const (
+	defaultEndpoint = "https://storage.googleapis.com"
+	// metadataTokenURL returns the access token of the service account of the Cloud Run,
+	// Cloud Functions or Compute Engine instance.
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	accessTokenEnv   = "GOOGLE_OAUTH_ACCESS_TOKEN"
+)
+
+var _ jsondb.ObjectStore = &Store{}
+
+// TokenFn returns the OAuth2 access token the requests are authorized with.
+type TokenFn func(ctx context.Context) (string, error)
+
+type Store struct {
+	bucket     string
+	endpoint   string
+	httpClient *http.Client
+	tokenFn    TokenFn
+}
+
+// New returns a store for the bucket. Requests are authorized with the access token in
+// the GOOGLE_OAUTH_ACCESS_TOKEN environment variable if set, otherwise with the token of
+// the service account from the metadata server, as available on Google Cloud.
+func New(bucket string) *Store {
+	s := &Store{
+		bucket:     bucket,
+		endpoint:   defaultEndpoint,
+		httpClient: http.DefaultClient,
+	}
+	s.tokenFn = s.defaultToken
+
+	return s
+}
+
+// WithEndpoint sets the storage endpoint, e.g. to use an emulator.
+func (s *Store) WithEndpoint(endpoint string) *Store {
+	s.endpoint = endpoint
+	return s
+}
+
+func (s *Store) WithHTTPClient(httpClient *http.Client) *Store {
+	s.httpClient = httpClient
+	return s
+}
+
+// WithTokenFn sets the function returning the access token. A nil function sends
+// unauthenticated requests, e.g. when the HTTP client already authorizes them.
+func (s *Store) WithTokenFn(tokenFn TokenFn) *Store {
+	s.tokenFn = tokenFn
+	return s
+}
+
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media",
+		s.endpoint, url.PathEscape(s.bucket), url.PathEscape(key))
+
+	body, statusCode, err := s.do(ctx, http.MethodGet, endpoint, nil)
+	if statusCode == http.StatusNotFound {
+		return nil, jsondb.ErrObjectNotFound
+	}
+
+	return body, err
+}
+
+func (s *Store) Put(ctx context.Context, key string, data []byte) error {
+	endpoint := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		s.endpoint, url.PathEscape(s.bucket), url.QueryEscape(key))
+
+	_, _, err := s.do(ctx, http.MethodPost, endpoint, data)
+
+	return err
+}
+
+func (s *Store) do(ctx context.Context, method, endpoint string, data []byte) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if s.tokenFn != nil {
+		token, errToken := s.tokenFn(ctx)
+		if errToken != nil {
+			return nil, 0, errToken
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, resp.StatusCode, httperror.New(resp.StatusCode, body).WithRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+func (s *Store) defaultToken(ctx context.Context) (string, error) {
+	if token := os.Getenv(accessTokenEnv); token != "" {
+		return token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", httperror.New(resp.StatusCode, body)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		return "", err
+	}
+
+	return token.AccessToken, nil
+}
@@ -0,0 +1,70 @@
+package gcs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+)
+
+func TestStore(t *testing.T) {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/bucket/o":
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Query().Get("name")] = data
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet && r.URL.Query().Get("alt") == "media":
+			data, ok := objects[r.URL.Path[len("/storage/v1/b/bucket/o/"):]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	store := New("bucket").WithEndpoint(server.URL).WithTokenFn(func(context.Context) (string, error) {
+		return "token", nil
+	})
+	ctx := context.Background()
+
+	_, err := store.Get(ctx, "indexes/db.json")
+	if !errors.Is(err, jsondb.ErrObjectNotFound) {
+		t.Fatalf("Get() error = %v, want %v", err, jsondb.ErrObjectNotFound)
+	}
+
+	err = store.Put(ctx, "indexes/db.json", []byte(`[]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := store.Get(ctx, "indexes/db.json")
+	if err != nil || string(data) != `[]` {
+		t.Errorf("Get() = %q, %v, want []", data, err)
+	}
+
+	_, err = store.WithTokenFn(nil).Get(ctx, "indexes/db.json")
+	if err == nil {
+		t.Errorf("Get() without token should fail")
+	}
+}
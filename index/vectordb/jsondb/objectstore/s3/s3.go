@@ -0,0 +1,164 @@
+// Package s3 stores the JsonDB database in an Amazon S3 bucket, or in any S3 compatible
+// storage such as MinIO or Cloudflare R2.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+	"github.com/henomis/lingoose/llm/httperror"
+)
+
+const (
+	service = "s3"
+)
+
+var _ jsondb.ObjectStore = &Store{}
+
+type Store struct {
+	bucket     string
+	region     string
+	endpoint   string
+	httpClient *http.Client
+	cfg        *aws.Config
+	signer     *v4.Signer
+}
+
+// New returns a store for the bucket. Credentials and region are resolved by the AWS SDK
+// default chain (environment variables, shared config files, SSO, instance roles) on
+// first use.
+func New(bucket string) *Store {
+	return &Store{
+		bucket:     bucket,
+		httpClient: http.DefaultClient,
+		signer:     v4.NewSigner(),
+	}
+}
+
+// WithConfig sets the AWS config providing the credentials and the region.
+func (s *Store) WithConfig(cfg aws.Config) *Store {
+	s.cfg = &cfg
+	return s
+}
+
+// WithRegion sets the region of the bucket.
+func (s *Store) WithRegion(region string) *Store {
+	s.region = region
+	return s
+}
+
+// WithEndpoint sets the endpoint of an S3 compatible storage, e.g. http://localhost:9000
+// for MinIO. Objects are addressed in path style.
+func (s *Store) WithEndpoint(endpoint string) *Store {
+	s.endpoint = strings.TrimSuffix(endpoint, "/")
+	return s
+}
+
+func (s *Store) WithHTTPClient(httpClient *http.Client) *Store {
+	s.httpClient = httpClient
+	return s
+}
+
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	body, statusCode, err := s.do(ctx, http.MethodGet, key, nil)
+	if statusCode == http.StatusNotFound {
+		return nil, jsondb.ErrObjectNotFound
+	}
+
+	return body, err
+}
+
+func (s *Store) Put(ctx context.Context, key string, data []byte) error {
+	_, _, err := s.do(ctx, http.MethodPut, key, data)
+	return err
+}
+
+func (s *Store) do(ctx context.Context, method, key string, data []byte) ([]byte, int, error) {
+	cfg, err := s.config(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(cfg.Region, key), bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	hash := sha256.Sum256(data)
+	payloadHash := hex.EncodeToString(hash[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	credentials, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = s.signer.SignHTTP(ctx, credentials, req, payloadHash, service, cfg.Region, time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, resp.StatusCode, httperror.New(resp.StatusCode, body).WithRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+func (s *Store) config(ctx context.Context) (aws.Config, error) {
+	if s.cfg == nil {
+		var opts []func(*config.LoadOptions) error
+		if s.region != "" {
+			opts = append(opts, config.WithRegion(s.region))
+		}
+
+		cfg, err := config.LoadDefaultConfig(ctx, opts...)
+		if err != nil {
+			return aws.Config{}, err
+		}
+		s.cfg = &cfg
+	}
+
+	cfg := *s.cfg
+	if s.region != "" {
+		cfg.Region = s.region
+	}
+
+	return cfg, nil
+}
+
+func (s *Store) objectURL(region, key string) string {
+	escapedKey := (&url.URL{Path: key}).EscapedPath()
+	if s.endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, escapedKey)
+	}
+
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, region, escapedKey)
+}
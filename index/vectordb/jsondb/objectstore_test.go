@@ -0,0 +1,120 @@
+package jsondb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/index"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	puts    int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: make(map[string][]byte)}
+}
+
+func (m *memoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+
+	return data, nil
+}
+
+func (m *memoryStore) Put(_ context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.objects[key] = data
+	m.puts++
+
+	return nil
+}
+
+func (m *memoryStore) putCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.puts
+}
+
+func TestObjectStore(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+
+	db := New().WithObjectStore(store, "index.json")
+	err := db.Insert(ctx, []index.Data{{ID: "a", Values: []float64{1, 0}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.putCount() != 1 {
+		t.Fatalf("puts = %d, want 1", store.putCount())
+	}
+
+	// a new instance, e.g. another function invocation, loads the synced database
+	reloaded := New().WithObjectStore(store, "index.json")
+	results, err := reloaded.Search(ctx, []float64{1, 0}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != "a" {
+		t.Errorf("results = %+v, want record a", results)
+	}
+}
+
+func TestObjectStoreSyncInterval(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+
+	db := New().WithObjectStore(store, "index.json").WithSyncInterval(time.Hour)
+	for _, id := range []string{"a", "b", "c"} {
+		err := db.Insert(ctx, []index.Data{{ID: id, Values: []float64{1, 0}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.putCount() != 0 {
+		t.Fatalf("puts = %d before the sync, want 0", store.putCount())
+	}
+
+	err := db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.putCount() != 1 {
+		t.Fatalf("puts = %d after Close, want 1", store.putCount())
+	}
+
+	empty, err := New().WithObjectStore(store, "index.json").IsEmpty(ctx)
+	if err != nil || empty {
+		t.Errorf("IsEmpty() = %v, %v, want false", empty, err)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) ([]byte, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (failingStore) Put(context.Context, string, []byte) error {
+	return errors.New("unavailable")
+}
+
+func TestObjectStoreError(t *testing.T) {
+	_, err := New().WithObjectStore(failingStore{}, "index.json").IsEmpty(context.Background())
+	if !errors.Is(err, ErrObjectStore) {
+		t.Errorf("error = %v, want %v", err, ErrObjectStore)
+	}
+}
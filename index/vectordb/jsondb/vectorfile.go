@@ -0,0 +1,265 @@
+package jsondb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const (
+	vectorFileMagic      = "LGVF"
+	vectorFileHeaderSize = 8
+	float64Size          = 8
+)
+
+var ErrInvalidVectorFile = errors.New("invalid vector file")
+
+// vectorFile is a flat binary file of fixed size float64 vectors that is
+// memory-mapped for reading. The file starts with a 4 bytes magic and the
+// vector dimension as little endian uint32, followed by the vectors.
+type vectorFile struct {
+	path      string
+	file      *os.File
+	mapping   []byte
+	dimension int
+}
+
+func openVectorFile(path string) (*vectorFile, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &vectorFile{
+		path: path,
+		file: file,
+	}
+
+	err = v.remap()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (v *vectorFile) remap() error {
+	err := v.unmap()
+	if err != nil {
+		return err
+	}
+
+	info, err := v.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	if size == 0 {
+		v.dimension = 0
+		return nil
+	}
+
+	if size < vectorFileHeaderSize {
+		return fmt.Errorf("%w: file too short", ErrInvalidVectorFile)
+	}
+
+	mapping, err := mmapFile(v.file, int(size))
+	if err != nil {
+		return err
+	}
+
+	if string(mapping[:len(vectorFileMagic)]) != vectorFileMagic {
+		_ = munmapFile(mapping)
+		return fmt.Errorf("%w: bad magic", ErrInvalidVectorFile)
+	}
+
+	dimension := int(binary.LittleEndian.Uint32(mapping[len(vectorFileMagic):vectorFileHeaderSize]))
+	if dimension == 0 || (len(mapping)-vectorFileHeaderSize)%(dimension*float64Size) != 0 {
+		_ = munmapFile(mapping)
+		return fmt.Errorf("%w: truncated vectors", ErrInvalidVectorFile)
+	}
+
+	v.mapping = mapping
+	v.dimension = dimension
+
+	return nil
+}
+
+func (v *vectorFile) unmap() error {
+	if v.mapping == nil {
+		return nil
+	}
+
+	err := munmapFile(v.mapping)
+	v.mapping = nil
+	return err
+}
+
+// Len returns the number of vectors stored in the file.
+func (v *vectorFile) Len() int {
+	if v.dimension == 0 || v.mapping == nil {
+		return 0
+	}
+
+	return (len(v.mapping) - vectorFileHeaderSize) / (v.dimension * float64Size)
+}
+
+// At returns a copy of the i-th vector.
+func (v *vectorFile) At(i int) []float64 {
+	offset := vectorFileHeaderSize + i*v.dimension*float64Size
+	values := make([]float64, v.dimension)
+	for k := range values {
+		start := offset + k*float64Size
+		values[k] = math.Float64frombits(binary.LittleEndian.Uint64(v.mapping[start : start+float64Size]))
+	}
+
+	return values
+}
+
+// Append writes the vectors at the end of the file and remaps it. The vectors are
+// validated before writing, so a failed append leaves the file unchanged.
+func (v *vectorFile) Append(vectors [][]float64) error {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	dimension := v.dimension
+	if dimension == 0 {
+		dimension = len(vectors[0])
+	}
+
+	buffer, err := encodeVectors(vectors, dimension, v.dimension == 0)
+	if err != nil {
+		return err
+	}
+
+	offset := int64(vectorFileHeaderSize + v.Len()*v.dimension*float64Size)
+	if v.dimension == 0 {
+		offset = 0
+	}
+
+	_, err = v.file.WriteAt(buffer, offset)
+	if err != nil {
+		return err
+	}
+
+	return v.remap()
+}
+
+// Truncate keeps the first n vectors, dropping the following ones, e.g. the vectors
+// appended for records that failed to be saved.
+func (v *vectorFile) Truncate(n int) error {
+	if n >= v.Len() {
+		return nil
+	}
+
+	size := int64(0)
+	if n > 0 {
+		size = int64(vectorFileHeaderSize + n*v.dimension*float64Size)
+	}
+
+	err := v.unmap()
+	if err != nil {
+		return err
+	}
+
+	err = v.file.Truncate(size)
+	if err != nil {
+		return err
+	}
+
+	return v.remap()
+}
+
+// Rewrite replaces the whole content of the file with the given vectors. The vectors
+// are written to a temporary file renamed over the current one, so a crash while
+// rewriting keeps the previous vectors.
+func (v *vectorFile) Rewrite(vectors [][]float64) error {
+	var buffer []byte
+	if len(vectors) > 0 {
+		var err error
+		buffer, err = encodeVectors(vectors, len(vectors[0]), true)
+		if err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), filepath.Base(v.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(buffer)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = v.unmap()
+	if err != nil {
+		return err
+	}
+
+	err = v.file.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), v.path)
+	if err != nil {
+		return err
+	}
+
+	v.file, err = os.OpenFile(v.path, os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+
+	return v.remap()
+}
+
+// encodeVectors returns the binary encoding of the vectors, preceded by the file header
+// when withHeader is true. Vectors of a different dimension are rejected.
+func encodeVectors(vectors [][]float64, dimension int, withHeader bool) ([]byte, error) {
+	if dimension == 0 {
+		return nil, fmt.Errorf("%w: empty vector", ErrInvalidVectorFile)
+	}
+
+	var buffer []byte
+	if withHeader {
+		buffer = append(buffer, vectorFileMagic...)
+		buffer = binary.LittleEndian.AppendUint32(buffer, uint32(dimension))
+	}
+
+	for _, vector := range vectors {
+		if len(vector) != dimension {
+			return nil, fmt.Errorf("%w: expected dimension %d, got %d", ErrInvalidVectorFile, dimension, len(vector))
+		}
+
+		for _, value := range vector {
+			buffer = binary.LittleEndian.AppendUint64(buffer, math.Float64bits(value))
+		}
+	}
+
+	return buffer, nil
+}
+
+func (v *vectorFile) Close() error {
+	err := v.unmap()
+	if err != nil {
+		return err
+	}
+
+	return v.file.Close()
+}
@@ -0,0 +1,159 @@
+package jsondb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/option"
+)
+
+func TestVectorFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.bin")
+
+	v, err := openVectorFile(path)
+	if err != nil {
+		t.Fatalf("openVectorFile() error = %v", err)
+	}
+
+	if v.Len() != 0 {
+		t.Fatalf("Len() = %d, want 0", v.Len())
+	}
+
+	vectors := [][]float64{{1, 2, 3}, {4, 5, 6}}
+	err = v.Append(vectors)
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	err = v.Append([][]float64{{7, 8}})
+	if err == nil {
+		t.Fatalf("Append() with wrong dimension should fail")
+	}
+
+	err = v.Append([][]float64{{7, 8, 9}})
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	err = v.Close()
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	v, err = openVectorFile(path)
+	if err != nil {
+		t.Fatalf("openVectorFile() error = %v", err)
+	}
+	defer v.Close()
+
+	if v.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", v.Len())
+	}
+
+	if got := v.At(2); !reflect.DeepEqual(got, []float64{7, 8, 9}) {
+		t.Errorf("At(2) = %v, want %v", got, []float64{7, 8, 9})
+	}
+
+	err = v.Rewrite([][]float64{{4, 5, 6}})
+	if err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+
+	if v.Len() != 1 || !reflect.DeepEqual(v.At(0), []float64{4, 5, 6}) {
+		t.Errorf("Rewrite() got %d vectors, first %v", v.Len(), v.At(0))
+	}
+}
+
+func TestVectorFileAppendValidatesFirst(t *testing.T) {
+	v, err := openVectorFile(filepath.Join(t.TempDir(), "vectors.bin"))
+	if err != nil {
+		t.Fatalf("openVectorFile() error = %v", err)
+	}
+	defer v.Close()
+
+	err = v.Append([][]float64{{1, 2, 3}, {4, 5}})
+	if !errors.Is(err, ErrInvalidVectorFile) {
+		t.Fatalf("Append() error = %v, want ErrInvalidVectorFile", err)
+	}
+	if v.Len() != 0 || v.dimension != 0 {
+		t.Fatalf("failed Append() changed the file: %d vectors of dimension %d", v.Len(), v.dimension)
+	}
+
+	err = v.Append([][]float64{{1, 2}})
+	if err != nil || v.Len() != 1 {
+		t.Fatalf("Append() after a failed append: %v, %d vectors", err, v.Len())
+	}
+}
+
+func TestDBMemoryMappedVectorsWithoutPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.bin")
+
+	for run := 0; run < 2; run++ {
+		db := New().WithMemoryMappedVectors(path)
+		err := db.Insert(context.Background(), []index.Data{{Values: []float64{1, 0}}})
+		if err != nil {
+			t.Fatalf("run %d: Insert() error = %v", run, err)
+		}
+
+		results, err := db.Search(context.Background(), []float64{1, 0}, &option.Options{TopK: 10})
+		if err != nil || len(results) != 1 {
+			t.Fatalf("run %d: Search() = %v, %v", run, results, err)
+		}
+
+		if err = db.Close(); err != nil {
+			t.Fatalf("run %d: Close() error = %v", run, err)
+		}
+	}
+}
+
+func TestDBInsertSaveFailureRollsBackVectors(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "db.json")
+	vectorsPath := filepath.Join(dir, "vectors.bin")
+
+	db := New().WithPersist(dbPath).WithMemoryMappedVectors(vectorsPath)
+	err := db.Insert(context.Background(), []index.Data{{Values: []float64{1, 0}}})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	// a directory in place of the database file makes the save fail
+	if err = os.Remove(dbPath); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Mkdir(dbPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Insert(context.Background(), []index.Data{{Values: []float64{0, 1}}, {Values: []float64{1, 1}}})
+	if !errors.Is(err, index.ErrInternal) {
+		t.Fatalf("Insert() error = %v, want ErrInternal", err)
+	}
+	if db.vectors.Len() != 1 || len(db.data) != 1 {
+		t.Fatalf("failed Insert() kept %d vectors and %d records", db.vectors.Len(), len(db.data))
+	}
+
+	if err = os.Remove(dbPath); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Insert(context.Background(), []index.Data{{Values: []float64{0, 1}}})
+	if err != nil {
+		t.Fatalf("Insert() after a failed insert: %v", err)
+	}
+	if err = db.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	db = New().WithPersist(dbPath).WithMemoryMappedVectors(vectorsPath)
+	defer db.Close()
+
+	results, err := db.Search(context.Background(), []float64{0, 1}, &option.Options{TopK: 10})
+	if err != nil || len(results) != 2 || !reflect.DeepEqual(results[0].Values, []float64{0, 1}) {
+		t.Fatalf("Search() after reopening = %v, %v", results, err)
+	}
+}
@@ -98,7 +98,12 @@ func (d *DB) Search(ctx context.Context, values []float64, options *option.Optio
 		return nil, fmt.Errorf("%w: %w", index.ErrInternal, err)
 	}
 
-	return buildSearchResultsFromMilvusMatches(matches), nil
+	results := buildSearchResultsFromMilvusMatches(matches)
+	if options.Explain && d.createCollection != nil {
+		results.Explain(string(d.createCollection.Metric))
+	}
+
+	return results, nil
 }
 
 func (d *DB) Drop(ctx context.Context) error {
@@ -146,7 +146,12 @@ func (d *DB) Search(ctx context.Context, values []float64, options *option.Optio
 		return nil, fmt.Errorf("%w: %w", index.ErrInternal, err)
 	}
 
-	return buildSearchResultsFromPineconeMatches(matches), nil
+	results := buildSearchResultsFromPineconeMatches(matches)
+	if options.Explain && d.createIndexOptions != nil {
+		results.Explain(d.createIndexOptions.Metric)
+	}
+
+	return results, nil
 }
 
 func (d *DB) Drop(ctx context.Context) error {
@@ -4,11 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/henomis/lingoose/embedder"
 	"github.com/henomis/lingoose/index"
 	"github.com/henomis/lingoose/index/option"
 	"github.com/henomis/lingoose/types"
@@ -16,10 +18,13 @@ import (
 
 var _ index.VectorDB = &DB{}
 
+var ErrDistance = errors.New("distance not supported by the precision")
+
 type DB struct {
 	db          *sql.DB
 	table       string
 	createIndex *CreateIndexOptions
+	err         error
 }
 
 type Distance string
@@ -28,11 +33,34 @@ const (
 	DistanceCosine       Distance = "<=>"
 	DistanceInnerProduct Distance = "<#>"
 	DistanceEuclidean    Distance = "<->"
+	// DistanceHamming and DistanceJaccard are only available with PrecisionBinary.
+	DistanceHamming Distance = "<~>"
+	DistanceJaccard Distance = "<%>"
+)
+
+var distanceNames = map[Distance]string{
+	DistanceCosine:       "cosine",
+	DistanceInnerProduct: "negative_inner_product",
+	DistanceEuclidean:    "euclidean",
+	DistanceHamming:      "hamming",
+	DistanceJaccard:      "jaccard",
+}
+
+// Precision is the pgvector column type used to store the embeddings.
+type Precision string
+
+const (
+	PrecisionFloat32 Precision = "vector"
+	PrecisionFloat16 Precision = "halfvec"
+	PrecisionBinary  Precision = "bit"
 )
 
 type CreateIndexOptions struct {
 	Dimension uint64
-	Distance  Distance
+	// Distance defaults to DistanceHamming with PrecisionBinary.
+	Distance Distance
+	// Precision defaults to PrecisionFloat32.
+	Precision Precision
 }
 
 type Options struct {
@@ -42,11 +70,41 @@ type Options struct {
 }
 
 func New(options Options) *DB {
+	createIndex := options.CreateIndex
+	if createIndex != nil {
+		createIndexCopy := *createIndex
+		if createIndexCopy.Precision == PrecisionBinary && createIndexCopy.Distance == "" {
+			createIndexCopy.Distance = DistanceHamming
+		}
+		createIndex = &createIndexCopy
+	}
+
 	return &DB{
 		db:          options.DB,
 		table:       options.Table,
-		createIndex: options.CreateIndex,
+		createIndex: createIndex,
+		err:         validateCreateIndex(createIndex),
+	}
+}
+
+// validateCreateIndex checks that the distance operator exists for the column type:
+// pgvector compares bit vectors only by Hamming or Jaccard distance and the other
+// types never by those.
+func validateCreateIndex(createIndex *CreateIndexOptions) error {
+	if createIndex == nil {
+		return nil
+	}
+
+	bitDistance := createIndex.Distance == DistanceHamming || createIndex.Distance == DistanceJaccard
+	if createIndex.Precision == PrecisionBinary && !bitDistance {
+		return fmt.Errorf("%w: %s requires Hamming or Jaccard distance", ErrDistance, PrecisionBinary)
+	}
+
+	if createIndex.Precision != PrecisionBinary && bitDistance {
+		return fmt.Errorf("%w: %s distance requires %s precision", ErrDistance, createIndex.Distance, PrecisionBinary)
 	}
+
+	return nil
 }
 
 func (d *DB) IsEmpty(ctx context.Context) (bool, error) {
@@ -90,9 +148,9 @@ func (d *DB) Insert(ctx context.Context, datas []index.Data) error {
 		values = append(
 			values,
 			fmt.Sprintf(
-				"('%s','%s', '%s')",
+				"('%s',%s, '%s')",
 				data.ID,
-				floatToValues(data.Values),
+				d.castValues(data.Values),
 				string(jsonMetadata),
 			),
 		)
@@ -143,6 +201,10 @@ func (d *DB) similaritySearch(
 	values []float64,
 	opts *option.Options,
 ) (index.SearchResults, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+
 	if opts == nil {
 		opts = index.GetDefaultOptions()
 	}
@@ -151,7 +213,7 @@ func (d *DB) similaritySearch(
 		opts.Filter = ""
 	}
 
-	queryVector := fmt.Sprintf("embedding %s '%s'", d.createIndex.Distance, floatToValues(values))
+	queryVector := fmt.Sprintf("embedding %s %s", d.createIndex.Distance, d.castValues(values))
 	//nolint:gosec
 	query := fmt.Sprintf(
 		"SELECT id, embedding, metadata, %s AS score FROM %s %s ORDER BY %s LIMIT %d",
@@ -186,7 +248,7 @@ func (d *DB) similaritySearch(
 		}
 
 		var embeddingValues []float64
-		embeddingValues, err = valuesToFloats(embedding)
+		embeddingValues, err = d.parseValues(embedding)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %w", index.ErrInternal, err)
 		}
@@ -207,10 +269,18 @@ func (d *DB) similaritySearch(
 		return nil, fmt.Errorf("%w: %w", index.ErrInternal, err)
 	}
 
+	if opts.Explain {
+		index.SearchResults(results).Explain(distanceNames[d.createIndex.Distance])
+	}
+
 	return results, nil
 }
 
 func (d *DB) createIndexIfRequired(ctx context.Context) error {
+	if d.err != nil {
+		return d.err
+	}
+
 	if d.createIndex == nil {
 		return nil
 	}
@@ -222,8 +292,8 @@ func (d *DB) createIndexIfRequired(ctx context.Context) error {
 
 	_, err = d.db.ExecContext(
 		ctx,
-		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id UUID PRIMARY KEY, metadata json, embedding vector(%d))",
-			d.table, d.createIndex.Dimension),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id UUID PRIMARY KEY, metadata json, embedding %s(%d))",
+			d.table, d.precision(), d.createIndex.Dimension),
 	)
 	if err != nil {
 		return fmt.Errorf("%w: %w", index.ErrInternal, err)
@@ -232,6 +302,52 @@ func (d *DB) createIndexIfRequired(ctx context.Context) error {
 	return nil
 }
 
+func (d *DB) precision() Precision {
+	if d.createIndex == nil || d.createIndex.Precision == "" {
+		return PrecisionFloat32
+	}
+
+	return d.createIndex.Precision
+}
+
+func (d *DB) castValues(floats []float64) string {
+	if d.precision() == PrecisionBinary {
+		return fmt.Sprintf("'%s'::bit(%d)", floatToBits(floats), len(floats))
+	}
+
+	return fmt.Sprintf("'%s'::%s", floatToValues(floats), d.precision())
+}
+
+func (d *DB) parseValues(s string) ([]float64, error) {
+	if d.precision() == PrecisionBinary {
+		return bitsToFloats(s), nil
+	}
+
+	return valuesToFloats(s)
+}
+
+func floatToBits(floats []float64) string {
+	var b strings.Builder
+	for _, f := range floats {
+		if f > 0 {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+func bitsToFloats(s string) []float64 {
+	bits := make([]byte, (len(s)+7)/8)
+	for i, c := range s {
+		if c == '1' {
+			bits[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return embedder.NewEmbeddingFromBinary(bits, len(s))
+}
+
 func floatToValues(floats []float64) string {
 	var b strings.Builder
 	b.WriteString("[")
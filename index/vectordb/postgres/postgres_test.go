@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNewValidatesBinaryDistance(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      CreateIndexOptions
+		wantErr      bool
+		wantDistance Distance
+	}{
+		{"binary defaults to hamming", CreateIndexOptions{Dimension: 8, Precision: PrecisionBinary}, false, DistanceHamming},
+		{"binary jaccard", CreateIndexOptions{Dimension: 8, Precision: PrecisionBinary, Distance: DistanceJaccard}, false, DistanceJaccard},
+		{"binary cosine", CreateIndexOptions{Dimension: 8, Precision: PrecisionBinary, Distance: DistanceCosine}, true, DistanceCosine},
+		{"float hamming", CreateIndexOptions{Dimension: 8, Distance: DistanceHamming}, true, DistanceHamming},
+		{"float cosine", CreateIndexOptions{Dimension: 8, Distance: DistanceCosine}, false, DistanceCosine},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			db := New(Options{Table: "test", CreateIndex: &options})
+
+			if db.createIndex.Distance != tt.wantDistance {
+				t.Fatalf("distance = %q, want %q", db.createIndex.Distance, tt.wantDistance)
+			}
+			if options.Distance != tt.options.Distance {
+				t.Fatalf("New modified the caller options")
+			}
+
+			if !tt.wantErr {
+				if db.err != nil {
+					t.Fatalf("unexpected validation error: %v", db.err)
+				}
+				return
+			}
+
+			// the validation error is returned before the database is used
+			_, err := db.Search(context.Background(), []float64{1, 0, 1, 0, 1, 0, 1, 0}, nil)
+			if !errors.Is(err, ErrDistance) {
+				t.Fatalf("Search() error = %v, want ErrDistance", err)
+			}
+			if _, err = db.IsEmpty(context.Background()); !errors.Is(err, ErrDistance) {
+				t.Fatalf("IsEmpty() error = %v, want ErrDistance", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package qdrant
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	qdrantrequest "github.com/henomis/qdrant-go/request"
+	"github.com/henomis/restclientgo"
+)
+
+const (
+	jsonContentType = "application/json"
+)
+
+// collectionCreateRequest is used in place of the qdrant-go one when the collection
+// requires vector datatype or quantization settings not exposed by the client.
+type collectionCreateRequest struct {
+	CollectionName     string              `json:"-"`
+	Vectors            vectorsParams       `json:"vectors"`
+	QuantizationConfig *quantizationConfig `json:"quantization_config,omitempty"`
+}
+
+type vectorsParams struct {
+	Size     uint64   `json:"size"`
+	Distance Distance `json:"distance"`
+	OnDisk   bool     `json:"on_disk"`
+	Datatype Datatype `json:"datatype,omitempty"`
+}
+
+type quantizationConfig struct {
+	Binary *binaryQuantization `json:"binary,omitempty"`
+}
+
+type binaryQuantization struct {
+	AlwaysRAM bool `json:"always_ram"`
+}
+
+func (r *collectionCreateRequest) Path() (string, error) {
+	return "/collections/" + r.CollectionName, nil
+}
+
+func (r *collectionCreateRequest) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *collectionCreateRequest) ContentType() string {
+	return jsonContentType
+}
+
+type collectionCreateResponse struct {
+	HTTPStatusCode int    `json:"-"`
+	Status         any    `json:"status"`
+	Result         bool   `json:"result"`
+	RawBody        []byte `json:"-"`
+}
+
+func (r *collectionCreateResponse) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(r)
+}
+
+func (r *collectionCreateResponse) SetBody(body io.Reader) error {
+	r.RawBody, _ = io.ReadAll(body)
+	return nil
+}
+
+func (r *collectionCreateResponse) AcceptContentType() string {
+	return jsonContentType
+}
+
+func (r *collectionCreateResponse) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *collectionCreateResponse) SetHeaders(_ restclientgo.Headers) error { return nil }
+
+type pointsScrollRequest struct {
+	CollectionName string                `json:"-"`
+	Filter         *qdrantrequest.Filter `json:"filter,omitempty"`
+	Limit          int                   `json:"limit"`
+	Offset         json.RawMessage       `json:"offset,omitempty"`
+	WithPayload    bool                  `json:"with_payload"`
+	WithVector     bool                  `json:"with_vector"`
+}
+
+func (r *pointsScrollRequest) Path() (string, error) {
+	return "/collections/" + r.CollectionName + "/points/scroll", nil
+}
+
+func (r *pointsScrollRequest) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *pointsScrollRequest) ContentType() string {
+	return jsonContentType
+}
+
+type pointsScrollResponse struct {
+	HTTPStatusCode int    `json:"-"`
+	Status         any    `json:"status"`
+	Result         scroll `json:"result"`
+	RawBody        []byte `json:"-"`
+}
+
+type scroll struct {
+	Points         []scrollPoint   `json:"points"`
+	NextPageOffset json.RawMessage `json:"next_page_offset"`
+}
+
+type scrollPoint struct {
+	ID      json.RawMessage `json:"id"`
+	Payload map[string]any  `json:"payload"`
+	Vector  []float64       `json:"vector"`
+}
+
+func (r *pointsScrollResponse) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(r)
+}
+
+func (r *pointsScrollResponse) SetBody(body io.Reader) error {
+	r.RawBody, _ = io.ReadAll(body)
+	return nil
+}
+
+func (r *pointsScrollResponse) AcceptContentType() string {
+	return jsonContentType
+}
+
+func (r *pointsScrollResponse) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *pointsScrollResponse) SetHeaders(_ restclientgo.Headers) error { return nil }
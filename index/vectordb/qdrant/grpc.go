@@ -0,0 +1,127 @@
+package qdrant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	qdrantgrpc "github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/henomis/lingoose/index"
+)
+
+func (d *DB) grpcUpsert(ctx context.Context, datas []index.Data) error {
+	points := make([]*qdrantgrpc.PointStruct, len(datas))
+	for i, data := range datas {
+		payload, err := toGRPCPayload(data.Metadata)
+		if err != nil {
+			return err
+		}
+
+		vector := make([]float32, len(data.Values))
+		for j, value := range data.Values {
+			vector[j] = float32(value)
+		}
+
+		points[i] = &qdrantgrpc.PointStruct{
+			Id:      toGRPCPointID(data.ID),
+			Payload: payload,
+			Vectors: &qdrantgrpc.Vectors{
+				VectorsOptions: &qdrantgrpc.Vectors_Vector{
+					Vector: &qdrantgrpc.Vector{Data: vector},
+				},
+			},
+		}
+	}
+
+	if d.apiKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "api-key", d.apiKey)
+	}
+
+	wait := true
+	_, err := d.grpcPointsClient.Upsert(ctx, &qdrantgrpc.UpsertPoints{
+		CollectionName: d.collectionName,
+		Wait:           &wait,
+		Points:         points,
+	})
+
+	return err
+}
+
+func toGRPCPointID(id string) *qdrantgrpc.PointId {
+	if num, err := strconv.ParseUint(id, 10, 64); err == nil {
+		return &qdrantgrpc.PointId{PointIdOptions: &qdrantgrpc.PointId_Num{Num: num}}
+	}
+
+	return &qdrantgrpc.PointId{PointIdOptions: &qdrantgrpc.PointId_Uuid{Uuid: id}}
+}
+
+func toGRPCPayload(metadata map[string]any) (map[string]*qdrantgrpc.Value, error) {
+	// normalize the metadata to the JSON types, as done by the REST API
+	jsonBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+	if err = decoder.Decode(&normalized); err != nil {
+		return nil, err
+	}
+
+	payload := make(map[string]*qdrantgrpc.Value, len(normalized))
+	for key, value := range normalized {
+		payload[key], err = toGRPCValue(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return payload, nil
+}
+
+func toGRPCValue(value any) (*qdrantgrpc.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return &qdrantgrpc.Value{Kind: &qdrantgrpc.Value_NullValue{}}, nil
+	case bool:
+		return &qdrantgrpc.Value{Kind: &qdrantgrpc.Value_BoolValue{BoolValue: v}}, nil
+	case string:
+		return &qdrantgrpc.Value{Kind: &qdrantgrpc.Value_StringValue{StringValue: v}}, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &qdrantgrpc.Value{Kind: &qdrantgrpc.Value_IntegerValue{IntegerValue: i}}, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &qdrantgrpc.Value{Kind: &qdrantgrpc.Value_DoubleValue{DoubleValue: f}}, nil
+	case []any:
+		list := &qdrantgrpc.ListValue{Values: make([]*qdrantgrpc.Value, len(v))}
+		for i, item := range v {
+			itemValue, err := toGRPCValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list.Values[i] = itemValue
+		}
+		return &qdrantgrpc.Value{Kind: &qdrantgrpc.Value_ListValue{ListValue: list}}, nil
+	case map[string]any:
+		fields := make(map[string]*qdrantgrpc.Value, len(v))
+		for key, item := range v {
+			itemValue, err := toGRPCValue(item)
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = itemValue
+		}
+		return &qdrantgrpc.Value{Kind: &qdrantgrpc.Value_StructValue{StructValue: &qdrantgrpc.Struct{Fields: fields}}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported payload value %T", value)
+}
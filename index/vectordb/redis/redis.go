@@ -107,7 +107,12 @@ func (d *DB) Search(ctx context.Context, values []float64, options *option.Optio
 		return nil, fmt.Errorf("%w: %w", index.ErrInternal, err)
 	}
 
-	return buildSearchResultsFromRedisDocuments(matches), nil
+	results := buildSearchResultsFromRedisDocuments(matches)
+	if options.Explain && d.createIndex != nil {
+		results.Explain(string(d.createIndex.Distance))
+	}
+
+	return results, nil
 }
 
 func (d *DB) Drop(_ context.Context) error {
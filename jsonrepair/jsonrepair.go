@@ -0,0 +1,360 @@
+// Package jsonrepair fixes the malformed JSON often written by LLMs: markdown code
+// fences and surrounding text, trailing or missing commas, unquoted keys, single quoted
+// strings, comments, Python literals and objects truncated by the max tokens limit.
+package jsonrepair
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrRepair = errors.New("unable to repair JSON")
+
+type state int
+
+const (
+	// stateKey expects an object key or the end of the object.
+	stateKey state = iota
+	// stateColon expects the colon following an object key.
+	stateColon
+	// stateValue expects a value, or the end of an array.
+	stateValue
+	// stateComma expects a comma or the end of the container.
+	stateComma
+)
+
+type frame struct {
+	object bool
+	state  state
+}
+
+type repairer struct {
+	input  string
+	pos    int
+	out    strings.Builder
+	frames []frame
+}
+
+// Unmarshal is json.Unmarshal falling back to the repaired data when data is not valid
+// JSON. The error of the original data is returned if it can't be repaired.
+func Unmarshal(data []byte, v any) error {
+	err := json.Unmarshal(data, v)
+	var syntaxErr *json.SyntaxError
+	if err == nil || !errors.As(err, &syntaxErr) {
+		return err
+	}
+
+	repaired, errRepair := Repair(string(data))
+	if errRepair != nil {
+		return err
+	}
+
+	if json.Unmarshal([]byte(repaired), v) != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Repair returns the first JSON object or array found in text, fixed to be valid JSON.
+// Valid JSON is returned as it is. An object or array truncated at the end of the text
+// is closed, an incomplete key-value pair is completed with null.
+func Repair(text string) (string, error) {
+	text = strings.TrimSpace(stripFences(text))
+	if json.Valid([]byte(text)) {
+		return text, nil
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return "", ErrRepair
+	}
+
+	r := &repairer{input: text, pos: start}
+	r.repair()
+
+	repaired := r.out.String()
+	if !json.Valid([]byte(repaired)) {
+		return "", ErrRepair
+	}
+
+	return repaired, nil
+}
+
+// stripFences returns the content of the first markdown code block, if any. The closing
+// fence may be missing when the answer is truncated.
+func stripFences(text string) string {
+	_, block, found := strings.Cut(text, "```")
+	if !found {
+		return text
+	}
+
+	// skip the language tag
+	if newline := strings.IndexByte(block, '\n'); newline >= 0 && !strings.ContainsAny(block[:newline], "{[") {
+		block = block[newline+1:]
+	}
+
+	block, _, _ = strings.Cut(block, "```")
+
+	return block
+}
+
+//nolint:gocognit
+func (r *repairer) repair() {
+	for r.pos < len(r.input) {
+		c := r.input[r.pos]
+		switch {
+		case c == '{' || c == '[':
+			r.beginValue()
+			r.out.WriteByte(c)
+			r.pos++
+			if c == '{' {
+				r.frames = append(r.frames, frame{object: true, state: stateKey})
+			} else {
+				r.frames = append(r.frames, frame{object: false, state: stateValue})
+			}
+		case c == '}' || c == ']':
+			r.pos++
+			if r.closeUntil(c == '}') && len(r.frames) == 0 {
+				return
+			}
+		case c == ',':
+			r.pos++
+			r.comma()
+		case c == ':':
+			r.pos++
+			if f := r.top(); f != nil && f.object && f.state == stateColon {
+				r.out.WriteByte(':')
+				f.state = stateValue
+			}
+		case c == '"' || c == '\'':
+			r.token(r.readString(c), true)
+		case c == '/' && strings.HasPrefix(r.input[r.pos:], "//"):
+			r.skipUntil("\n")
+		case c == '/' && strings.HasPrefix(r.input[r.pos:], "/*"):
+			r.skipUntil("*/")
+		case isWordByte(c):
+			r.token(r.readWord(), false)
+		default:
+			r.pos++
+		}
+	}
+
+	// the text is truncated: close the open containers
+	for len(r.frames) > 0 {
+		r.closeUntil(r.top().object)
+	}
+}
+
+func (r *repairer) top() *frame {
+	if len(r.frames) == 0 {
+		return nil
+	}
+
+	return &r.frames[len(r.frames)-1]
+}
+
+// beginValue writes the missing separators before a value. An object value without key
+// can't be repaired and is left invalid.
+func (r *repairer) beginValue() {
+	f := r.top()
+	if f == nil {
+		return
+	}
+
+	switch f.state {
+	case stateComma:
+		r.out.WriteByte(',')
+	case stateColon:
+		r.out.WriteByte(':')
+	case stateKey, stateValue:
+	}
+
+	f.state = stateComma
+}
+
+func (r *repairer) comma() {
+	f := r.top()
+	if f == nil || f.state != stateComma {
+		return
+	}
+
+	r.out.WriteByte(',')
+	if f.object {
+		f.state = stateKey
+	} else {
+		f.state = stateValue
+	}
+}
+
+// token writes a string or a bare word, as an object key or as a value.
+func (r *repairer) token(value string, quoted bool) {
+	f := r.top()
+	if f != nil && f.object && (f.state == stateKey || f.state == stateComma) {
+		if f.state == stateComma {
+			r.out.WriteByte(',')
+		}
+		r.out.WriteString(quote(value))
+		f.state = stateColon
+		return
+	}
+
+	r.beginValue()
+	if quoted {
+		r.out.WriteString(quote(value))
+	} else {
+		r.out.WriteString(literal(value))
+	}
+}
+
+// closeUntil closes the open containers up to the innermost one of the given kind,
+// reporting whether it was found. Incomplete key-value pairs are completed with null.
+func (r *repairer) closeUntil(object bool) bool {
+	found := false
+	for _, f := range r.frames {
+		if f.object == object {
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+
+	for len(r.frames) > 0 {
+		f := r.frames[len(r.frames)-1]
+		r.frames = r.frames[:len(r.frames)-1]
+
+		switch f.state {
+		case stateColon:
+			r.out.WriteString(":null")
+		case stateValue:
+			if f.object {
+				r.out.WriteString("null")
+			}
+		case stateKey, stateComma:
+		}
+
+		closing := strings.TrimSuffix(r.out.String(), ",")
+		r.out.Reset()
+		r.out.WriteString(closing)
+
+		if f.object {
+			r.out.WriteByte('}')
+		} else {
+			r.out.WriteByte(']')
+		}
+
+		if f.object == object {
+			return true
+		}
+	}
+
+	return true
+}
+
+// readString reads a string delimited by quote, up to the end of the text if it is not
+// terminated.
+func (r *repairer) readString(quote byte) string {
+	r.pos++
+
+	var s strings.Builder
+	for r.pos < len(r.input) {
+		c := r.input[r.pos]
+		r.pos++
+
+		switch {
+		case c == quote:
+			return s.String()
+		case c == '\\' && r.pos < len(r.input):
+			s.WriteString(unescape(r.input, &r.pos))
+		default:
+			s.WriteByte(c)
+		}
+	}
+
+	return s.String()
+}
+
+func unescape(input string, pos *int) string {
+	c := input[*pos]
+	*pos++
+
+	switch c {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case 'b':
+		return "\b"
+	case 'f':
+		return "\f"
+	case 'u':
+		if *pos+4 <= len(input) {
+			var decoded string
+			if json.Unmarshal([]byte(`"\u`+input[*pos:*pos+4]+`"`), &decoded) == nil {
+				*pos += 4
+				return decoded
+			}
+		}
+		return "u"
+	default:
+		// \" \\ \/ and the invalid escapes such as \'
+		return string(c)
+	}
+}
+
+func (r *repairer) readWord() string {
+	start := r.pos
+	for r.pos < len(r.input) && isWordByte(r.input[r.pos]) {
+		r.pos++
+	}
+
+	return r.input[start:r.pos]
+}
+
+func (r *repairer) skipUntil(end string) {
+	index := strings.Index(r.input[r.pos:], end)
+	if index < 0 {
+		r.pos = len(r.input)
+		return
+	}
+
+	r.pos += index + len(end)
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '$' || c == '-' || c == '+' || c == '.' || c >= 0x80
+}
+
+// literal converts a bare word into a JSON literal, quoting it if it's not a number or a
+// JSON or Python constant.
+func literal(word string) string {
+	switch word {
+	case "true", "True":
+		return "true"
+	case "false", "False":
+		return "false"
+	case "null", "None", "undefined":
+		return "null"
+	}
+
+	var number json.Number
+	if json.Unmarshal([]byte(word), &number) == nil {
+		return word
+	}
+
+	return quote(word)
+}
+
+func quote(s string) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,73 @@
+package jsonrepair
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"valid", `{"a": [1, 2]}`, `{"a": [1, 2]}`},
+		{"fences", "Here you are:\n```json\n{\"a\": 1}\n```\nHope it helps.", `{"a": 1}`},
+		{"surrounding text", `The answer is {"a": 1} as requested.`, `{"a":1}`},
+		{"trailing commas", `{"a": [1, 2,], "b": 3,}`, `{"a":[1,2],"b":3}`},
+		{"unquoted keys", `{name: "Rome", population_2020: 2800000}`, `{"name":"Rome","population_2020":2800000}`},
+		{"single quotes", `{'city': 'Rome', 'quote': 'say "ciao"', 'it\'s': 1}`, `{"city":"Rome","quote":"say \"ciao\"","it's":1}`},
+		{"missing commas", "{\"a\": 1\n\"b\": [1 2]}", `{"a":1,"b":[1,2]}`},
+		{"python literals", `{"a": True, "b": None, "c": False}`, `{"a":true,"b":null,"c":false}`},
+		{"comments", "{\"a\": 1, // the first\n/* the second */ \"b\": 2}", `{"a":1,"b":2}`},
+		{"raw newline in string", "{\"a\": \"line 1\nline 2\"}", `{"a":"line 1\nline 2"}`},
+		{"truncated string", `{"a": 1, "b": "some te`, `{"a":1,"b":"some te"}`},
+		{"truncated key", `{"a": [{"b": 1}, {"c"`, `{"a":[{"b":1},{"c":null}]}`},
+		{"truncated after colon", `{"a": {"b":`, `{"a":{"b":null}}`},
+		{"truncated array", "```json\n[1, 2, ", `[1,2]`},
+		{"mismatched closer", `{"a": [1, 2}`, `{"a":[1,2]}`},
+		{"unicode", `{"città": "Roma è bella",}`, `{"città":"Roma è bella"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Repair(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepairError(t *testing.T) {
+	for _, input := range []string{"", "no JSON here", `{"a" 1 {}}`} {
+		if got, err := Repair(input); !errors.Is(err, ErrRepair) {
+			t.Fatalf("expected an error for %q, got %s", input, got)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var v map[string]any
+	err := Unmarshal([]byte("```json\n{city: 'Rome', tags: ['a', 'b',]\n"), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"city": "Rome", "tags": []any{"a", "b"}}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("got %v, want %v", v, want)
+	}
+
+	var n int
+	if err = Unmarshal([]byte(`"text"`), &n); err == nil {
+		t.Fatal("expected the type error to be returned")
+	}
+	if err = Unmarshal([]byte(`not json`), &v); err == nil {
+		t.Fatal("expected the syntax error to be returned")
+	}
+}
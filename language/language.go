@@ -0,0 +1,191 @@
+// Package language detects the language of a text, to verify that answers are written in
+// the language of the user query or in a configured target language.
+package language
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+const (
+	// minStopwords is the minimum number of stopwords needed to detect a Latin script
+	// language.
+	minStopwords = 2
+)
+
+var (
+	ErrLanguage = errors.New("language detection error")
+)
+
+// Language is an ISO 639-1 language code. Unknown is returned when the language can't be
+// detected, e.g. for very short texts.
+type Language string
+
+const (
+	Unknown    Language = ""
+	English    Language = "en"
+	Italian    Language = "it"
+	Spanish    Language = "es"
+	French     Language = "fr"
+	German     Language = "de"
+	Portuguese Language = "pt"
+	Dutch      Language = "nl"
+	Chinese    Language = "zh"
+	Japanese   Language = "ja"
+	Korean     Language = "ko"
+	Russian    Language = "ru"
+	Arabic     Language = "ar"
+	Greek      Language = "el"
+	Hebrew     Language = "he"
+	Hindi      Language = "hi"
+	Thai       Language = "th"
+)
+
+var names = map[Language]string{
+	English:    "English",
+	Italian:    "Italian",
+	Spanish:    "Spanish",
+	French:     "French",
+	German:     "German",
+	Portuguese: "Portuguese",
+	Dutch:      "Dutch",
+	Chinese:    "Chinese",
+	Japanese:   "Japanese",
+	Korean:     "Korean",
+	Russian:    "Russian",
+	Arabic:     "Arabic",
+	Greek:      "Greek",
+	Hebrew:     "Hebrew",
+	Hindi:      "Hindi",
+	Thai:       "Thai",
+}
+
+// Name returns the English name of the language, the code itself if unknown.
+func (l Language) Name() string {
+	if name, ok := names[l]; ok {
+		return name
+	}
+
+	return string(l)
+}
+
+// Detector detects the language of a text.
+type Detector interface {
+	Detect(ctx context.Context, text string) (Language, error)
+}
+
+//nolint:lll
+var stopwords = map[Language][]string{
+	English:    {"the", "and", "is", "are", "of", "to", "in", "that", "it", "for", "with", "was", "this", "you", "what", "how", "have", "be", "on", "not", "can", "do", "my", "your", "which", "there", "will", "would"},
+	Italian:    {"il", "lo", "la", "gli", "le", "di", "che", "è", "e", "per", "non", "un", "una", "sono", "del", "della", "come", "con", "mi", "qual", "quale", "cosa", "anche", "ho", "hai", "perché", "questo", "nel"},
+	Spanish:    {"el", "la", "los", "las", "de", "que", "y", "es", "en", "por", "un", "una", "para", "con", "no", "como", "qué", "cómo", "del", "se", "lo", "su", "mi", "está", "son", "pero", "muy", "cuál"},
+	French:     {"le", "la", "les", "de", "des", "et", "est", "un", "une", "que", "qui", "pour", "pas", "dans", "ce", "il", "je", "vous", "sont", "avec", "sur", "du", "au", "comment", "quel", "quelle", "mon", "c'est"},
+	German:     {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "von", "ich", "sie", "es", "auf", "für", "wie", "was", "sind", "dem", "auch", "wir", "mein", "welche", "kann", "bitte"},
+	Portuguese: {"o", "a", "os", "as", "de", "que", "e", "é", "do", "da", "em", "um", "uma", "para", "não", "com", "como", "por", "mais", "meu", "minha", "você", "são", "qual", "está", "isso", "dos", "das"},
+	Dutch:      {"de", "het", "een", "en", "van", "is", "dat", "niet", "ik", "je", "op", "te", "zijn", "met", "voor", "wat", "hoe", "er", "maar", "ook", "mijn", "welke", "kan", "wij", "bij", "naar", "deze", "dit"},
+}
+
+var scripts = []struct {
+	table    *unicode.RangeTable
+	language Language
+}{
+	{unicode.Hiragana, Japanese},
+	{unicode.Katakana, Japanese},
+	{unicode.Han, Chinese},
+	{unicode.Hangul, Korean},
+	{unicode.Cyrillic, Russian},
+	{unicode.Arabic, Arabic},
+	{unicode.Greek, Greek},
+	{unicode.Hebrew, Hebrew},
+	{unicode.Devanagari, Hindi},
+	{unicode.Thai, Thai},
+}
+
+// StopwordDetector detects the language offline, from the script of the text and, for
+// Latin script languages, from the frequency of their most common words. It supports the
+// languages listed in this package.
+type StopwordDetector struct{}
+
+func NewStopwordDetector() *StopwordDetector {
+	return &StopwordDetector{}
+}
+
+func (d *StopwordDetector) Detect(_ context.Context, text string) (Language, error) {
+	return detect(text), nil
+}
+
+func detect(text string) Language {
+	if language := detectScript(text); language != Unknown {
+		return language
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+
+	best, bestCount, secondCount := Unknown, 0, 0
+	for language, list := range stopwords {
+		count := 0
+		for _, word := range words {
+			for _, stopword := range list {
+				if word == stopword {
+					count++
+					break
+				}
+			}
+		}
+
+		switch {
+		case count > bestCount:
+			best, bestCount, secondCount = language, count, bestCount
+		case count > secondCount:
+			secondCount = count
+		}
+	}
+
+	if bestCount < minStopwords || bestCount == secondCount {
+		return Unknown
+	}
+
+	return best
+}
+
+// detectScript returns the language of the prevailing non-Latin script, Japanese when
+// kana are present among Chinese characters.
+func detectScript(text string) Language {
+	counts := make(map[Language]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+
+		for _, script := range scripts {
+			if unicode.Is(script.table, r) {
+				counts[script.language]++
+				break
+			}
+		}
+	}
+
+	if counts[Japanese] > 0 {
+		counts[Japanese] += counts[Chinese]
+		counts[Chinese] = 0
+	}
+
+	best, bestCount := Unknown, 0
+	for language, count := range counts {
+		if count > bestCount {
+			best, bestCount = language, count
+		}
+	}
+
+	if bestCount*2 < letters {
+		return Unknown
+	}
+
+	return best
+}
@@ -0,0 +1,34 @@
+package language
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStopwordDetector_Detect(t *testing.T) {
+	tests := []struct {
+		text string
+		want Language
+	}{
+		{"What is the capital of France and how big is it?", English},
+		{"Qual è la capitale della Francia e quanto è grande?", Italian},
+		{"¿Cuál es la capital de Francia y qué tan grande es?", Spanish},
+		{"Quelle est la capitale de la France et est-elle grande ?", French},
+		{"Was ist die Hauptstadt von Frankreich und wie groß ist sie?", German},
+		{"フランスの首都はどこですか", Japanese},
+		{"法国的首都是哪里", Chinese},
+		{"Какая столица Франции?", Russian},
+		{"Paris", Unknown},
+	}
+
+	detector := NewStopwordDetector()
+	for _, tt := range tests {
+		got, err := detector.Detect(context.Background(), tt.text)
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
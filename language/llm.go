@@ -0,0 +1,76 @@
+package language
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	//nolint:lll
+	llmDetectorPrompt = "Which language is the following text written in? Answer only with its ISO 639-1 code (e.g. en, it, ja), or with NONE if the text has no recognizable language.\n\nText:\n{{.text}}"
+	//nolint:lll
+	translatePrompt = "Translate the following text to {{.language}}. Keep the formatting, code blocks, names and numbers unchanged. Answer only with the translation.\n\nText:\n{{.text}}"
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// LLMDetector asks an LLM for the language of the text, supporting any language.
+type LLMDetector struct {
+	llm LLM
+}
+
+func NewLLMDetector(llm LLM) *LLMDetector {
+	return &LLMDetector{
+		llm: llm,
+	}
+}
+
+func (d *LLMDetector) Detect(ctx context.Context, text string) (Language, error) {
+	answer, err := generate(ctx, d.llm, llmDetectorPrompt, types.M{"text": text})
+	if err != nil {
+		return Unknown, err
+	}
+
+	code := strings.ToLower(strings.TrimFunc(answer, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}))
+	if len(code) != 2 {
+		return Unknown, nil
+	}
+
+	return Language(code), nil
+}
+
+// Translate asks the LLM to translate the text to the language.
+func Translate(ctx context.Context, llm LLM, text string, language Language) (string, error) {
+	return generate(ctx, llm, translatePrompt, types.M{"text": text, "language": language.Name()})
+}
+
+func generate(ctx context.Context, llm LLM, prompt string, input types.M) (string, error) {
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent(prompt).Format(input),
+		),
+	)
+
+	err := llm.Generate(ctx, t)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrLanguage, err)
+	}
+
+	var answer string
+	for _, content := range t.LastMessage().Contents {
+		if content.Type == thread.ContentTypeText {
+			answer += content.AsString()
+		}
+	}
+
+	return strings.TrimSpace(answer), nil
+}
@@ -2,11 +2,11 @@
 package decoder
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
 
+	"github.com/henomis/lingoose/jsonrepair"
 	"github.com/henomis/lingoose/types"
 )
 
@@ -22,8 +22,10 @@ func NewJSONDecoder() *JSONDecoder {
 	return &JSONDecoder{}
 }
 
+// Decode decodes the JSON object in input. Malformed JSON, e.g. wrapped in a markdown
+// code block or with trailing commas, is repaired before failing.
 func (d *JSONDecoder) Decode(input string) (types.M, error) {
-	err := json.Unmarshal([]byte(input), &d.output)
+	err := jsonrepair.Unmarshal([]byte(input), &d.output)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrDecoding, err)
 	}
@@ -37,6 +37,32 @@ func TestJSONDecoder_Decode(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "TestJSONDecoder_DecodeMalformed",
+			fields: fields{
+				output: types.M{},
+			},
+			args: args{
+				input: "```json\n{test: 'test',}\n```",
+			},
+			want: types.M{
+				types.DefaultOutputKey: types.M{
+					"test": "test",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "TestJSONDecoder_DecodeInvalid",
+			fields: fields{
+				output: types.M{},
+			},
+			args: args{
+				input: "not json",
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
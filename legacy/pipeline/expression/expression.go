@@ -0,0 +1,317 @@
+// Package expression provides a small expression language to map the outputs of the
+// previous pipeline steps into the input of the next one.
+//
+// An expression is a path, optionally followed by a chain of functions:
+//
+//	steps.step1.output.items | first
+//	steps.search.output.results[0].title | default "untitled" | upper
+//	steps.tags.output | join ", "
+//
+// Paths access map keys with dots and list elements with a dot or square brackets. A
+// missing key evaluates to nil. Templates embed expressions in text between double
+// braces: "Summarize {{ steps.step1.output.title }}".
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrSyntax   = errors.New("expression syntax error")
+	ErrFunction = errors.New("expression function error")
+)
+
+const (
+	openDelimiter  = "{{"
+	closeDelimiter = "}}"
+)
+
+// Function transforms the value piped into it, args are the literal arguments that
+// follow the function name.
+type Function func(value any, args ...any) (any, error)
+
+var functions = map[string]Function{
+	"first":   first,
+	"last":    last,
+	"len":     length,
+	"index":   index,
+	"join":    join,
+	"split":   split,
+	"upper":   stringFunction(strings.ToUpper),
+	"lower":   stringFunction(strings.ToLower),
+	"trim":    stringFunction(strings.TrimSpace),
+	"default": defaultValue,
+	"json":    toJSON,
+}
+
+// Eval evaluates the expression against data.
+func Eval(expression string, data any) (any, error) {
+	stages, err := splitOutsideQuotes(expression, '|')
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := evalOperand(strings.TrimSpace(stages[0]), data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stage := range stages[1:] {
+		fields, errFields := splitOutsideQuotes(strings.TrimSpace(stage), ' ')
+		if errFields != nil {
+			return nil, errFields
+		}
+
+		fields = removeEmpty(fields)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%w: empty function in %q", ErrSyntax, expression)
+		}
+
+		fn, ok := functions[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown function %q", ErrFunction, fields[0])
+		}
+
+		args := make([]any, 0, len(fields)-1)
+		for _, field := range fields[1:] {
+			arg, errLiteral := parseLiteral(field)
+			if errLiteral != nil {
+				return nil, errLiteral
+			}
+			args = append(args, arg)
+		}
+
+		value, err = fn(value, args...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrFunction, fields[0], err)
+		}
+	}
+
+	return value, nil
+}
+
+// Render replaces the expressions of the template with their values.
+func Render(template string, data any) (string, error) {
+	var rendered strings.Builder
+	for {
+		start := strings.Index(template, openDelimiter)
+		if start < 0 {
+			rendered.WriteString(template)
+			return rendered.String(), nil
+		}
+
+		end := strings.Index(template[start:], closeDelimiter)
+		if end < 0 {
+			return "", fmt.Errorf("%w: unclosed %q", ErrSyntax, openDelimiter)
+		}
+		end += start
+
+		value, err := Eval(template[start+len(openDelimiter):end], data)
+		if err != nil {
+			return "", err
+		}
+
+		rendered.WriteString(template[:start])
+		rendered.WriteString(toString(value))
+		template = template[end+len(closeDelimiter):]
+	}
+}
+
+// Value evaluates a template keeping the type of the value when the template is a single
+// expression, e.g. a list stays a list. Otherwise the rendered string is returned.
+func Value(template string, data any) (any, error) {
+	trimmed := strings.TrimSpace(template)
+	if strings.HasPrefix(trimmed, openDelimiter) && strings.HasSuffix(trimmed, closeDelimiter) &&
+		strings.Count(trimmed, openDelimiter) == 1 {
+		return Eval(trimmed[len(openDelimiter):len(trimmed)-len(closeDelimiter)], data)
+	}
+
+	return Render(template, data)
+}
+
+func evalOperand(operand string, data any) (any, error) {
+	if operand == "" {
+		return nil, fmt.Errorf("%w: empty expression", ErrSyntax)
+	}
+
+	if operand[0] == '"' || operand[0] == '-' || unicode.IsDigit(rune(operand[0])) {
+		return parseLiteral(operand)
+	}
+
+	segments, err := parsePath(operand)
+	if err != nil {
+		return nil, err
+	}
+
+	value := data
+	for _, segment := range segments {
+		value = lookup(value, segment)
+		if value == nil {
+			return nil, nil
+		}
+	}
+
+	return value, nil
+}
+
+// parsePath splits a.b[0].c into its segments.
+func parsePath(path string) ([]string, error) {
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		name, rest, hasIndex := strings.Cut(part, "[")
+		if name == "" && !hasIndex {
+			return nil, fmt.Errorf("%w: invalid path %q", ErrSyntax, path)
+		}
+		if name != "" {
+			segments = append(segments, name)
+		}
+
+		for hasIndex {
+			var idx string
+			var ok bool
+			idx, rest, ok = strings.Cut(rest, "]")
+			if !ok || idx == "" {
+				return nil, fmt.Errorf("%w: invalid index in %q", ErrSyntax, path)
+			}
+			segments = append(segments, idx)
+
+			if rest == "" {
+				break
+			}
+			if rest[0] != '[' {
+				return nil, fmt.Errorf("%w: invalid path %q", ErrSyntax, path)
+			}
+			rest = rest[1:]
+		}
+	}
+
+	return segments, nil
+}
+
+func lookup(value any, segment string) any {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		item := v.MapIndex(reflect.ValueOf(segment).Convert(v.Type().Key()))
+		if !item.IsValid() {
+			return nil
+		}
+		return item.Interface()
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(segment)
+		if err != nil {
+			return nil
+		}
+		if i < 0 {
+			i += v.Len()
+		}
+		if i < 0 || i >= v.Len() {
+			return nil
+		}
+		return v.Index(i).Interface()
+	case reflect.Struct:
+		field := v.FieldByName(segment)
+		if !field.IsValid() || !field.CanInterface() {
+			return nil
+		}
+		return field.Interface()
+	default:
+		return nil
+	}
+}
+
+func parseLiteral(literal string) (any, error) {
+	if strings.HasPrefix(literal, "\"") {
+		s, err := strconv.Unquote(literal)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid string %s", ErrSyntax, literal)
+		}
+		return s, nil
+	}
+
+	if i, err := strconv.Atoi(literal); err == nil {
+		return i, nil
+	}
+
+	if f, err := strconv.ParseFloat(literal, 64); err == nil {
+		return f, nil
+	}
+
+	if b, err := strconv.ParseBool(literal); err == nil {
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("%w: invalid literal %s", ErrSyntax, literal)
+}
+
+// splitOutsideQuotes splits s on sep, ignoring the separators inside double quotes.
+func splitOutsideQuotes(s string, sep rune) ([]string, error) {
+	var parts []string
+	var current strings.Builder
+	inQuotes := false
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && inQuotes:
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == sep && !inQuotes:
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("%w: unterminated string in %q", ErrSyntax, s)
+	}
+
+	return append(parts, current.String()), nil
+}
+
+func removeEmpty(fields []string) []string {
+	var result []string
+	for _, field := range fields {
+		if field != "" {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		kind := reflect.ValueOf(value).Kind()
+		if kind == reflect.Map || kind == reflect.Slice || kind == reflect.Array || kind == reflect.Struct {
+			if s, err := toJSON(value); err == nil {
+				return s.(string)
+			}
+		}
+		return fmt.Sprint(value)
+	}
+}
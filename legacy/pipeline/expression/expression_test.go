@@ -0,0 +1,82 @@
+package expression
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/types"
+)
+
+func TestEval(t *testing.T) {
+	data := types.M{
+		"steps": types.M{
+			"step1": types.M{
+				"output": map[string]any{
+					"items": []any{"alpha", "beta", "gamma"},
+					"title": "",
+				},
+			},
+			"step2": types.M{
+				"output": []string{"x", "y"},
+			},
+		},
+	}
+
+	tests := []struct {
+		expression string
+		want       any
+		wantErr    error
+	}{
+		{expression: "steps.step1.output.items | first", want: "alpha"},
+		{expression: "steps.step1.output.items[1]", want: "beta"},
+		{expression: "steps.step1.output.items.2 | upper", want: "GAMMA"},
+		{expression: "steps.step1.output.items | last", want: "gamma"},
+		{expression: "steps.step1.output.items | len", want: 3},
+		{expression: "steps.step2.output | join \", \"", want: "x, y"},
+		{expression: "steps.step1.output.title | default \"untitled\"", want: "untitled"},
+		{expression: "steps.missing.output", want: nil},
+		{expression: `"a|b" | split "|" | index -1`, want: "b"},
+		{expression: "steps.step2.output | json", want: `["x","y"]`},
+		{expression: "steps.step1.output | nope", wantErr: ErrFunction},
+		{expression: "steps.step1.output.items[", wantErr: ErrSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := Eval(tt.expression, data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Eval() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Eval() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	data := types.M{"output": types.M{"items": []any{"a", "b"}}}
+
+	got, err := Value(" {{ output.items }} ", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []any{"a", "b"}) {
+		t.Errorf("Value() = %#v", got)
+	}
+
+	got, err = Value("items: {{ output.items | join \"+\" }}, first: {{output.items|first}}", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "items: a+b, first: a" {
+		t.Errorf("Value() = %#v", got)
+	}
+}
@@ -0,0 +1,164 @@
+package expression
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+var (
+	errNotAList     = errors.New("value is not a list")
+	errInvalidArgs  = errors.New("invalid arguments")
+	errNoLength     = errors.New("value has no length")
+	errNotAString   = errors.New("value is not a string")
+	errIndexMissing = errors.New("index out of range")
+)
+
+func list(value any) (reflect.Value, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return reflect.Value{}, errNotAList
+	}
+	return v, nil
+}
+
+func first(value any, _ ...any) (any, error) {
+	v, err := list(value)
+	if err != nil {
+		return nil, err
+	}
+	if v.Len() == 0 {
+		return nil, nil
+	}
+	return v.Index(0).Interface(), nil
+}
+
+func last(value any, _ ...any) (any, error) {
+	v, err := list(value)
+	if err != nil {
+		return nil, err
+	}
+	if v.Len() == 0 {
+		return nil, nil
+	}
+	return v.Index(v.Len() - 1).Interface(), nil
+}
+
+func length(value any, _ ...any) (any, error) {
+	if value == nil {
+		return 0, nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+		return v.Len(), nil
+	default:
+		return nil, errNoLength
+	}
+}
+
+func index(value any, args ...any) (any, error) {
+	if len(args) != 1 {
+		return nil, errInvalidArgs
+	}
+	i, ok := args[0].(int)
+	if !ok {
+		return nil, errInvalidArgs
+	}
+
+	v, err := list(value)
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 {
+		i += v.Len()
+	}
+	if i < 0 || i >= v.Len() {
+		return nil, errIndexMissing
+	}
+	return v.Index(i).Interface(), nil
+}
+
+func join(value any, args ...any) (any, error) {
+	separator := ""
+	if len(args) > 0 {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, errInvalidArgs
+		}
+		separator = s
+	}
+
+	v, err := list(value)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		items = append(items, toString(v.Index(i).Interface()))
+	}
+	return strings.Join(items, separator), nil
+}
+
+func split(value any, args ...any) (any, error) {
+	if len(args) != 1 {
+		return nil, errInvalidArgs
+	}
+	separator, ok := args[0].(string)
+	if !ok {
+		return nil, errInvalidArgs
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, errNotAString
+	}
+
+	parts := strings.Split(s, separator)
+	items := make([]any, 0, len(parts))
+	for _, part := range parts {
+		items = append(items, part)
+	}
+	return items, nil
+}
+
+func stringFunction(fn func(string) string) Function {
+	return func(value any, _ ...any) (any, error) {
+		if value == nil {
+			return "", nil
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, errNotAString
+		}
+		return fn(s), nil
+	}
+}
+
+// defaultValue returns its argument when the value is nil or empty.
+func defaultValue(value any, args ...any) (any, error) {
+	if len(args) != 1 {
+		return nil, errInvalidArgs
+	}
+
+	if value == nil {
+		return args[0], nil
+	}
+
+	if l, err := length(value); err == nil && l.(int) == 0 {
+		return args[0], nil
+	}
+
+	return value, nil
+}
+
+func toJSON(value any, _ ...any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -4,7 +4,11 @@ package pipeline
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/henomis/lingoose/logger"
 	"github.com/henomis/lingoose/types"
 )
 
@@ -12,11 +16,16 @@ var (
 	ErrDecoding       = errors.New("decoding input error")
 	ErrInvalidLmmMode = errors.New("invalid LLM mode")
 	ErrLLMExecution   = errors.New("llm execution error")
+	ErrInputMapping   = errors.New("input mapping error")
+	ErrOutputSchema   = errors.New("output schema error")
 )
 
 const (
 	NextTubeKey  = "next_tube"
 	NextTubeExit = -1
+	// StepsKey is the input key holding the outputs of the steps already executed, by
+	// step name, when enabled with WithSteps.
+	StepsKey = "steps"
 )
 
 type Memory interface {
@@ -35,12 +44,18 @@ type Pipe interface {
 	Run(ctx context.Context, input types.M) (types.M, error)
 }
 
+type namedPipe interface {
+	Name() string
+}
+
 type Callback func(ctx context.Context, values types.M) (types.M, error)
 
 type Pipeline struct {
 	pipes         map[int]Pipe
 	preCallbacks  map[int]Callback
 	postCallbacks map[int]Callback
+	steps         bool
+	logger        *slog.Logger
 }
 
 func New(pipes ...Pipe) *Pipeline {
@@ -72,10 +87,42 @@ func (p *Pipeline) WithPostCallbacks(callbacks ...Callback) *Pipeline {
 	return p
 }
 
+// WithSteps passes to each step the outputs of the previous ones in the StepsKey input,
+// named after the step name or "step<n>", counting from 1, as required by the input
+// mappings of the tubes. The pipeline input must not have the StepsKey key.
+func (p *Pipeline) WithSteps(enable bool) *Pipeline {
+	p.steps = enable
+	return p
+}
+
+// WithLogger sets the logger of the steps, logged at debug level with their duration.
+func (p *Pipeline) WithLogger(logger *slog.Logger) *Pipeline {
+	p.logger = logger
+	return p
+}
+
 // Run chains the steps of the pipeline and returns the output of the last step.
-//
-//nolint:gocognit
 func (p Pipeline) Run(ctx context.Context, input types.M) (types.M, error) {
+	output, _, err := p.run(ctx, input, p.runPipe)
+	return output, err
+}
+
+// RunWithLog runs the pipeline like Run, recording the run in a log that can be replayed,
+// see Replay. The log is returned also when a step fails, with the steps executed until
+// then.
+func (p Pipeline) RunWithLog(ctx context.Context, input types.M) (types.M, *RunLog, error) {
+	return p.run(ctx, input, p.runPipe)
+}
+
+// stepRunner returns the output of the step, executed or replayed.
+type stepRunner func(ctx context.Context, step int, input types.M) (types.M, error)
+
+func (p Pipeline) runPipe(ctx context.Context, step int, input types.M) (types.M, error) {
+	return p.pipes[step].Run(ctx, input)
+}
+
+//nolint:gocognit
+func (p Pipeline) run(ctx context.Context, input types.M, runStep stepRunner) (types.M, *RunLog, error) {
 	var err error
 	currentTube := 0
 
@@ -83,25 +130,46 @@ func (p Pipeline) Run(ctx context.Context, input types.M) (types.M, error) {
 		input = types.M{}
 	}
 
+	runLog := &RunLog{Input: input}
+
+	if _, ok := input[StepsKey]; ok && p.steps {
+		return nil, runLog, fmt.Errorf("%w: the input already has the %q key", ErrInputMapping, StepsKey)
+	}
+
 	output := input
+	steps := types.M{}
 
 	for {
 		if p.thereIsAValidPreCallbackForTube(currentTube) {
 			output, err = p.preCallbacks[currentTube](ctx, output)
 			if err != nil {
-				return nil, err
+				return nil, runLog, err
 			}
 		}
 
-		output, err = p.pipes[currentTube].Run(ctx, output)
+		stepInput := output
+		if p.steps {
+			stepInput = withSteps(output, steps)
+		}
+
+		start := time.Now()
+		output, err = runStep(ctx, currentTube, stepInput)
+		logger.Or(p.logger).DebugContext(ctx, "pipeline step",
+			slog.String("step", p.stepName(currentTube)),
+			slog.Duration("duration", time.Since(start)),
+			slog.Bool("failed", err != nil),
+		)
 		if err != nil {
-			return nil, err
+			return nil, runLog, err
 		}
+		steps[p.stepName(currentTube)] = output
+		// the output is copied, the post callbacks may change it
+		runLog.Steps = append(runLog.Steps, StepRecord{Step: p.stepName(currentTube), Output: mergeMaps(output, nil)})
 
 		if p.thereIsAValidPostCallbackForTube(currentTube) {
 			output, err = p.postCallbacks[currentTube](ctx, output)
 			if err != nil {
-				return nil, err
+				return nil, runLog, err
 			}
 
 			nextTube := p.getNextTube(output)
@@ -121,7 +189,24 @@ func (p Pipeline) Run(ctx context.Context, input types.M) (types.M, error) {
 		}
 	}
 
-	return output, nil
+	return output, runLog, nil
+}
+
+func (p *Pipeline) stepName(currentTube int) string {
+	if pipe, ok := p.pipes[currentTube].(namedPipe); ok && pipe.Name() != "" {
+		return pipe.Name()
+	}
+
+	return fmt.Sprintf("step%d", currentTube+1)
+}
+
+func withSteps(input types.M, steps types.M) types.M {
+	stepsCopy := make(types.M, len(steps))
+	for name, output := range steps {
+		stepsCopy[name] = output
+	}
+
+	return mergeMaps(input, types.M{StepsKey: stepsCopy})
 }
 
 func SetNextTube(output types.M, nextTube int) types.M {
@@ -1,9 +1,16 @@
 package pipeline
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/henomis/lingoose/legacy/chat"
+	"github.com/henomis/lingoose/legacy/prompt"
+	"github.com/henomis/lingoose/recovery"
 	"github.com/henomis/lingoose/types"
 )
 
@@ -45,3 +52,155 @@ func Test_mergeMaps(t *testing.T) {
 		})
 	}
 }
+
+type sequenceEngine struct {
+	responses []string
+	prompts   []string
+}
+
+func (e *sequenceEngine) Completion(_ context.Context, prompt string) (string, error) {
+	e.prompts = append(e.prompts, prompt)
+	response := e.responses[0]
+	e.responses = e.responses[1:]
+	return response, nil
+}
+
+func (e *sequenceEngine) Chat(_ context.Context, _ *chat.Chat) (string, error) {
+	return "", nil
+}
+
+type jsonDecoder struct{}
+
+func (d *jsonDecoder) Decode(input string) (types.M, error) {
+	var output types.M
+	err := json.Unmarshal([]byte(input), &output)
+	return output, err
+}
+
+func TestTube_WithDecoderRetry(t *testing.T) {
+	engine := &sequenceEngine{responses: []string{"not json", `{"answer": 42}`}}
+	tube := NewTube(Llm{
+		LlmEngine: engine,
+		LlmMode:   LlmModeCompletion,
+		Prompt:    prompt.New("Reply in JSON."),
+	}).WithDecoder(&jsonDecoder{})
+
+	_, err := tube.Run(context.Background(), nil)
+	if !errors.Is(err, ErrDecoding) {
+		t.Fatalf("expected decoding error, got %v", err)
+	}
+
+	engine.responses = []string{"not json", `{"answer": 42}`}
+	engine.prompts = nil
+	output, err := tube.WithDecoderRetry(1).Run(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output["answer"] != float64(42) {
+		t.Fatalf("unexpected output %v", output)
+	}
+	if len(engine.prompts) != 2 || !strings.Contains(engine.prompts[1], "not json") {
+		t.Fatalf("unexpected retry prompt %q", engine.prompts)
+	}
+}
+
+type panicDecoder struct{}
+
+func (d *panicDecoder) Decode(string) (types.M, error) {
+	panic("decoder bug")
+}
+
+func TestTube_DecoderPanic(t *testing.T) {
+	engine := &sequenceEngine{responses: []string{"output"}}
+	tube := NewTube(Llm{
+		LlmEngine: engine,
+		LlmMode:   LlmModeCompletion,
+		Prompt:    prompt.New("Reply."),
+	}).WithDecoder(&panicDecoder{})
+
+	_, err := tube.Run(context.Background(), nil)
+	if !errors.Is(err, ErrDecoding) || !errors.Is(err, recovery.ErrPanic) {
+		t.Fatalf("expected decoding panic error, got %v", err)
+	}
+}
+
+type answer struct {
+	Answer int      `json:"answer"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+func TestTube_WithOutputSchema(t *testing.T) {
+	responses := []string{`{"answer": "42"}`, `{"answer": 42, "tags": ["math"]}`}
+	engine := &sequenceEngine{responses: responses}
+	tube := NewTube(Llm{
+		LlmEngine: engine,
+		LlmMode:   LlmModeCompletion,
+		Prompt:    prompt.New("Reply in JSON."),
+	}).WithDecoder(decoderFunc(func(input string) (types.M, error) {
+		output, err := (&jsonDecoder{}).Decode(input)
+		return types.M{types.DefaultOutputKey: output}, err
+	})).WithOutputSchema(answer{})
+
+	_, err := tube.Run(context.Background(), nil)
+	if !errors.Is(err, ErrOutputSchema) || !strings.Contains(err.Error(), "output.answer") {
+		t.Fatalf("expected output schema error, got %v", err)
+	}
+
+	engine.responses = responses
+	output, err := tube.WithDecoderRetry(1).Run(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output[types.DefaultOutputKey].(types.M)["answer"] != float64(42) {
+		t.Fatalf("unexpected output %v", output)
+	}
+}
+
+type decoderFunc func(input string) (types.M, error)
+
+func (f decoderFunc) Decode(input string) (types.M, error) {
+	return f(input)
+}
+
+type pipeFunc func(ctx context.Context, input types.M) (types.M, error)
+
+func (f pipeFunc) Run(ctx context.Context, input types.M) (types.M, error) {
+	return f(ctx, input)
+}
+
+func TestPipeline_Steps(t *testing.T) {
+	var inputs []types.M
+	record := func(output types.M) Pipe {
+		return pipeFunc(func(_ context.Context, input types.M) (types.M, error) {
+			inputs = append(inputs, input)
+			return output, nil
+		})
+	}
+
+	// the input key named as StepsKey is left untouched by default
+	userSteps := []string{"mix", "bake"}
+	_, err := New(record(types.M{"output": "a"}), record(types.M{"output": "b"})).
+		Run(context.Background(), types.M{StepsKey: userSteps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(inputs[0][StepsKey], userSteps) {
+		t.Fatalf("expected the user steps, got %v", inputs[0][StepsKey])
+	}
+
+	inputs = nil
+	p := New(record(types.M{"output": "a"}), record(types.M{"output": "b"})).WithSteps(true)
+	_, err = p.Run(context.Background(), types.M{"question": "q"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := types.M{"step1": types.M{"output": "a"}}
+	if !reflect.DeepEqual(inputs[1][StepsKey], expected) {
+		t.Fatalf("expected the outputs of the previous steps, got %v", inputs[1][StepsKey])
+	}
+
+	_, err = p.Run(context.Background(), types.M{StepsKey: userSteps})
+	if !errors.Is(err, ErrInputMapping) {
+		t.Fatalf("expected ErrInputMapping for an input with the steps key, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/henomis/lingoose/types"
+)
+
+var ErrReplay = errors.New("replay error")
+
+// RunLog is the record of a pipeline run: its input and the output of each executed step,
+// in execution order. A step executed more than once, e.g. in a loop of the post
+// callbacks, has a record for each execution.
+type RunLog struct {
+	Input types.M      `json:"input"`
+	Steps []StepRecord `json:"steps"`
+}
+
+type StepRecord struct {
+	Step   string  `json:"step"`
+	Output types.M `json:"output"`
+}
+
+// Save writes the run log to a JSON file.
+func (r *RunLog) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// LoadRunLog reads a run log saved with Save. The values decoded from JSON have the JSON
+// types, e.g. numbers are float64.
+func LoadRunLog(path string) (*RunLog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var runLog RunLog
+	err = json.Unmarshal(data, &runLog)
+	if err != nil {
+		return nil, err
+	}
+
+	return &runLog, nil
+}
+
+// Replay re-executes a pipeline run from its log: the steps return their recorded outputs
+// instead of calling the LLMs, while the callbacks are executed as in the original run.
+// The steps set with WithLiveStep are executed for real, to debug or fix a single step
+// of an expensive run without running the others again.
+type Replay struct {
+	pipeline  *Pipeline
+	runLog    *RunLog
+	liveSteps map[string]bool
+}
+
+func NewReplay(pipeline *Pipeline, runLog *RunLog) *Replay {
+	return &Replay{
+		pipeline:  pipeline,
+		runLog:    runLog,
+		liveSteps: make(map[string]bool),
+	}
+}
+
+// WithLiveStep executes the named step, see WithSteps for the step names, instead of
+// replaying its recorded output. The steps following it still return their recorded
+// outputs, computed from the original output of the live step.
+func (r *Replay) WithLiveStep(step string) *Replay {
+	r.liveSteps[step] = true
+	return r
+}
+
+// Run replays the run with its recorded input and returns the output of the last step,
+// with the log of the replayed run.
+func (r *Replay) Run(ctx context.Context) (types.M, *RunLog, error) {
+	records := make(map[string][]types.M)
+	for _, record := range r.runLog.Steps {
+		records[record.Step] = append(records[record.Step], record.Output)
+	}
+
+	executions := make(map[string]int)
+	replayStep := func(ctx context.Context, step int, input types.M) (types.M, error) {
+		name := r.pipeline.stepName(step)
+		execution := executions[name]
+		executions[name]++
+
+		if r.liveSteps[name] {
+			return r.pipeline.runPipe(ctx, step, input)
+		}
+
+		if execution >= len(records[name]) {
+			return nil, fmt.Errorf("%w: no recorded output for execution %d of step %s", ErrReplay, execution+1, name)
+		}
+
+		// the output is copied, the post callbacks may change it
+		return mergeMaps(records[name][execution], nil), nil
+	}
+
+	return r.pipeline.run(ctx, mergeMaps(r.runLog.Input, nil), replayStep)
+}
@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/types"
+)
+
+func TestReplay(t *testing.T) {
+	calls := map[string]int{}
+	step := func(name string, output func(input types.M) types.M) Pipe {
+		return namedPipeFunc{name: name, fn: func(_ context.Context, input types.M) (types.M, error) {
+			calls[name]++
+			return output(input), nil
+		}}
+	}
+
+	// the review step loops back to the draft step once
+	reviews := 0
+	p := New(
+		step("draft", func(types.M) types.M { return types.M{"text": "draft"} }),
+		step("review", func(input types.M) types.M { return types.M{"text": input["text"].(string) + " reviewed"} }),
+		step("publish", func(input types.M) types.M { return types.M{"text": input["text"].(string) + " published"} }),
+	).WithPostCallbacks(nil, func(_ context.Context, output types.M) (types.M, error) {
+		reviews++
+		if reviews == 1 {
+			return SetNextTube(output, 0), nil
+		}
+		return output, nil
+	})
+
+	output, runLog, err := p.RunWithLog(context.Background(), types.M{"topic": "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output["text"] != "draft reviewed published" || len(runLog.Steps) != 5 {
+		t.Fatalf("output %v, log %v", output, runLog.Steps)
+	}
+
+	path := filepath.Join(t.TempDir(), "run.json")
+	err = runLog.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runLog, err = LoadRunLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls = map[string]int{}
+	reviews = 0
+	replayed, _, err := NewReplay(p, runLog).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replayed, output) || len(calls) != 0 {
+		t.Fatalf("replayed %v with calls %v", replayed, calls)
+	}
+
+	// fix the last step and run only that one
+	p.pipes[2] = step("publish", func(input types.M) types.M { return types.M{"text": input["text"].(string) + " fixed"} })
+	reviews = 0
+	replayed, replayLog, err := NewReplay(p, runLog).WithLiveStep("publish").Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed["text"] != "draft reviewed fixed" || !reflect.DeepEqual(calls, map[string]int{"publish": 1}) {
+		t.Fatalf("replayed %v with calls %v", replayed, calls)
+	}
+	if replayLog.Steps[4].Output["text"] != "draft reviewed fixed" {
+		t.Fatalf("replay log %v", replayLog.Steps)
+	}
+
+	// the replay fails when a step has no recorded output
+	reviews = 0
+	runLog.Steps = runLog.Steps[:4]
+	_, _, err = NewReplay(p, runLog).Run(context.Background())
+	if !errors.Is(err, ErrReplay) {
+		t.Fatalf("expected ErrReplay, got %v", err)
+	}
+}
+
+type namedPipeFunc struct {
+	name string
+	fn   func(ctx context.Context, input types.M) (types.M, error)
+}
+
+func (p namedPipeFunc) Run(ctx context.Context, input types.M) (types.M, error) {
+	return p.fn(ctx, input)
+}
+
+func (p namedPipeFunc) Name() string {
+	return p.name
+}
@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// validateSchema checks value against the subset of JSON schema describing data shapes:
+// type, properties, required, additionalProperties, items, enum, minItems and maxItems.
+//
+//nolint:gocognit,gocyclo
+func validateSchema(schema map[string]any, value any, path string) error {
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesAnyType(types, value) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonType(value))
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value %v not allowed", path, value)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, exists := v[fmt.Sprint(name)]; !exists {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			propertySchema, ok := properties[key].(map[string]any)
+			if !ok {
+				if additional, isBool := schema["additionalProperties"].(bool); isBool && !additional {
+					return fmt.Errorf("%s: unexpected property %q", path, key)
+				}
+				continue
+			}
+
+			err := validateSchema(propertySchema, v[key], path+"."+key)
+			if err != nil {
+				return err
+			}
+		}
+	case []any:
+		if minItems, ok := schema["minItems"].(float64); ok && float64(len(v)) < minItems {
+			return fmt.Errorf("%s: expected at least %v items", path, minItems)
+		}
+		if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(v)) > maxItems {
+			return fmt.Errorf("%s: expected at most %v items", path, maxItems)
+		}
+
+		if itemsSchema, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				err := validateSchema(itemsSchema, item, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func schemaTypes(schemaType any) []string {
+	switch t := schemaType.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			types = append(types, fmt.Sprint(item))
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+func matchesAnyType(types []string, value any) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// normalizeJSON converts value to the generic types produced by encoding/json, so
+// decoder outputs like types.M or []string can be validated.
+func normalizeJSON(value any) (any, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized any
+	err = json.Unmarshal(b, &normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
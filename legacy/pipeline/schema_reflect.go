@@ -0,0 +1,46 @@
+//go:build !tinygo
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/invopop/jsonschema"
+)
+
+// outputSchema returns the JSON schema of a struct value, or the schema itself when it's
+// already a JSON schema.
+func outputSchema(schema any) (map[string]any, error) {
+	if jsonSchema, ok := schema.(map[string]any); ok {
+		return jsonSchema, nil
+	}
+
+	t := reflect.TypeOf(schema)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema must be a struct or a JSON schema, got %T", schema)
+	}
+
+	r := new(jsonschema.Reflector)
+	r.DoNotReference = true
+	reflected := r.ReflectFromType(t)
+
+	b, err := json.Marshal(reflected)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonSchema map[string]any
+	err = json.Unmarshal(b, &jsonSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(jsonSchema, "$schema")
+
+	return jsonSchema, nil
+}
@@ -0,0 +1,17 @@
+//go:build tinygo
+
+package pipeline
+
+import (
+	"fmt"
+)
+
+// outputSchema returns the JSON schema itself: TinyGo builds can't reflect the schema of
+// struct values.
+func outputSchema(schema any) (map[string]any, error) {
+	if jsonSchema, ok := schema.(map[string]any); ok {
+		return jsonSchema, nil
+	}
+
+	return nil, fmt.Errorf("schema must be a JSON schema, got %T", schema)
+}
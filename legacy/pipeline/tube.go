@@ -5,16 +5,28 @@ import (
 	"fmt"
 
 	"github.com/henomis/lingoose/legacy/chat"
+	"github.com/henomis/lingoose/legacy/pipeline/expression"
+	"github.com/henomis/lingoose/legacy/prompt"
+	"github.com/henomis/lingoose/recovery"
 	"github.com/henomis/lingoose/types"
 	"github.com/mitchellh/mapstructure"
 )
 
+const (
+	decoderRetryPrompt = "Your previous output was invalid because: %s\n" +
+		"Reply again with the same content in a valid format, without any additional text."
+)
+
 type Tube struct {
-	llm       Llm
-	decoder   Decoder
-	namespace string
-	memory    Memory
-	history   History
+	name              string
+	llm               Llm
+	decoder           Decoder
+	decoderMaxRetries uint
+	namespace         string
+	memory            Memory
+	history           History
+	inputMapping      map[string]string
+	outputSchema      any
 }
 
 func NewTube(
@@ -30,6 +42,52 @@ func (t *Tube) Namespace() string {
 	return t.namespace
 }
 
+// WithName sets the name the step output is registered with in the pipeline StepsKey
+// input and in memory, the memory namespace by default.
+func (t *Tube) WithName(name string) *Tube {
+	t.name = name
+	return t
+}
+
+func (t *Tube) Name() string {
+	if t.name != "" {
+		return t.name
+	}
+
+	return t.namespace
+}
+
+// WithOutputSchema sets the schema the decoded output must satisfy, either a struct value
+// (e.g. Answer{}) or a JSON schema as map[string]any. The step fails with
+// ErrOutputSchema when the output doesn't match it.
+func (t *Tube) WithOutputSchema(schema any) *Tube {
+	t.outputSchema = schema
+	return t
+}
+
+// OutputSchema returns the JSON schema of the step output, nil if not set.
+func (t *Tube) OutputSchema() (map[string]any, error) {
+	if t.outputSchema == nil {
+		return nil, nil
+	}
+
+	jsonSchema, err := outputSchema(t.outputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOutputSchema, err)
+	}
+
+	return jsonSchema, nil
+}
+
+// WithInputMapping sets input values computed from the outputs of the previous steps.
+// Values are expression templates (see the expression package), for example
+// {"item": "{{ steps.step1.output.items | first }}"}; a single expression keeps the type
+// of its value. The pipeline must pass the step outputs, see Pipeline.WithSteps.
+func (t *Tube) WithInputMapping(inputMapping map[string]string) *Tube {
+	t.inputMapping = inputMapping
+	return t
+}
+
 func (t *Tube) WithMemory(namespace string, memory Memory) *Tube {
 	t.namespace = namespace
 	t.memory = memory
@@ -46,6 +104,14 @@ func (t *Tube) WithDecoder(decoder Decoder) *Tube {
 	return t
 }
 
+// WithDecoderRetry re-prompts the LLM up to maxRetries times when the decoder fails or
+// the output doesn't match the output schema, appending the previous output and the
+// error to the prompt.
+func (t *Tube) WithDecoderRetry(maxRetries uint) *Tube {
+	t.decoderMaxRetries = maxRetries
+	return t
+}
+
 // Run execute the step and return the output.
 // The prompt is formatted with the input and the output of the prompt is used as input for the LLM.
 // If the step has a memory, the output is stored in the memory.
@@ -63,18 +129,23 @@ func (t *Tube) Run(ctx context.Context, input types.M) (types.M, error) {
 		input = mergeMaps(input, t.memory.All())
 	}
 
+	input, err = t.mapInput(input)
+	if err != nil {
+		return nil, err
+	}
+
 	response, err := t.executeLLM(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrLLMExecution, err)
 	}
 
-	decodedOutput, err := t.decoder.Decode(response)
+	decodedOutput, err := t.decode(ctx, response)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrDecoding, err)
+		return nil, err
 	}
 
 	if t.memory != nil {
-		err = t.memory.Set(t.namespace, decodedOutput)
+		err = t.memory.Set(t.Name(), decodedOutput)
 		if err != nil {
 			return nil, err
 		}
@@ -83,6 +154,126 @@ func (t *Tube) Run(ctx context.Context, input types.M) (types.M, error) {
 	return decodedOutput, nil
 }
 
+func (t *Tube) mapInput(input types.M) (types.M, error) {
+	if len(t.inputMapping) == 0 {
+		return input, nil
+	}
+
+	mapped := types.M{}
+	for key, template := range t.inputMapping {
+		value, err := expression.Value(template, input)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInputMapping, key, err)
+		}
+		mapped[key] = value
+	}
+
+	return mergeMaps(input, mapped), nil
+}
+
+func (t *Tube) validateOutput(decodedOutput types.M) error {
+	jsonSchema, err := t.OutputSchema()
+	if err != nil || jsonSchema == nil {
+		return err
+	}
+
+	output, err := normalizeJSON(decodedOutput[types.DefaultOutputKey])
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOutputSchema, err)
+	}
+
+	err = validateSchema(jsonSchema, output, types.DefaultOutputKey)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOutputSchema, err)
+	}
+
+	return nil
+}
+
+// decode decodes and validates the response, re-prompting the LLM on failure when
+// decoder retries are enabled.
+func (t *Tube) decode(ctx context.Context, response string) (types.M, error) {
+	decodedOutput, decodeErr := t.decodeOutput(response)
+	for retry := uint(0); decodeErr != nil && retry < t.decoderMaxRetries; retry++ {
+		var err error
+		response, err = t.executeLLMRetry(ctx, response, decodeErr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrLLMExecution, err)
+		}
+
+		decodedOutput, decodeErr = t.decodeOutput(response)
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	return decodedOutput, nil
+}
+
+func (t *Tube) decodeOutput(response string) (types.M, error) {
+	var decodedOutput types.M
+	err := recovery.Do("decoder", func() error {
+		var err error
+		decodedOutput, err = t.decoder.Decode(response)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecoding, err)
+	}
+
+	err = t.validateOutput(decodedOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodedOutput, nil
+}
+
+// executeLLMRetry asks the LLM to correct its previous output. The prompts of the step
+// are left untouched, they are already formatted with the input.
+func (t *Tube) executeLLMRetry(ctx context.Context, previousOutput string, decodeErr error) (string, error) {
+	retryPrompt := fmt.Sprintf(decoderRetryPrompt, decodeErr)
+
+	var response string
+	var err error
+	if t.llm.LlmMode == LlmModeCompletion {
+		response, err = t.llm.LlmEngine.Completion(
+			ctx,
+			t.llm.Prompt.String()+"\n\n"+previousOutput+"\n\n"+retryPrompt,
+		)
+	} else {
+		retryChat := chat.New(t.llm.Chat.PromptMessages()...)
+		retryChat.AddPromptMessages([]chat.PromptMessage{
+			{
+				Type:   chat.MessageTypeAssistant,
+				Prompt: prompt.New(previousOutput),
+			},
+			{
+				Type:   chat.MessageTypeUser,
+				Prompt: prompt.New(retryPrompt),
+			},
+		})
+		response, err = t.llm.LlmEngine.Chat(ctx, retryChat)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if t.history != nil {
+		err = t.history.Add(retryPrompt, types.Meta{"role": chat.MessageTypeUser})
+		if err != nil {
+			return "", err
+		}
+
+		err = t.history.Add(response, types.Meta{"role": chat.MessageTypeAssistant})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return response, nil
+}
+
 func (t *Tube) executeLLM(ctx context.Context, input types.M) (string, error) {
 	if t.llm.LlmMode == LlmModeCompletion {
 		return t.executeLLMCompletion(ctx, input)
@@ -0,0 +1,90 @@
+package lifecycle
+
+import (
+	"context"
+
+	"github.com/henomis/lingoose/document"
+)
+
+// Streamer ingests the documents received from a channel, like index.Index.
+type Streamer interface {
+	AddStream(ctx context.Context, documents <-chan document.Document) error
+}
+
+// Ingest streams the documents into the streamer as a job of the manager. When the
+// manager starts draining no more documents are read: the ones already received are
+// ingested and Ingest returns ErrDraining. The returned count is the number of
+// documents read, i.e. the checkpoint to resume the ingestion from.
+func (m *Manager) Ingest(
+	ctx context.Context,
+	streamer Streamer,
+	name string,
+	documents <-chan document.Document,
+) (int, error) {
+	count := 0
+	err := m.Go(ctx, KindIngestion, name, func(ctx context.Context, job *Job) error {
+		forwarded := make(chan document.Document)
+		stopped := make(chan struct{})
+
+		var streamErr error
+		go func() {
+			defer close(stopped)
+			streamErr = streamer.AddStream(ctx, forwarded)
+		}()
+
+		err := forward(ctx, job, documents, forwarded, stopped, &count)
+		close(forwarded)
+		<-stopped
+
+		// the streamer error, if any, is the reason of the interruption
+		if streamErr != nil {
+			return streamErr
+		}
+
+		return err
+	})
+
+	return count, err
+}
+
+// forward sends the documents to the streamer until the channel is closed, the manager
+// drains or the streamer stops.
+func forward(
+	ctx context.Context,
+	job *Job,
+	documents <-chan document.Document,
+	forwarded chan<- document.Document,
+	stopped <-chan struct{},
+	count *int,
+) error {
+	for {
+		// documents ready to be read must not delay the drain
+		select {
+		case <-job.Draining():
+			return ErrDraining
+		default:
+		}
+
+		select {
+		case <-job.Draining():
+			return ErrDraining
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-stopped:
+			return nil
+		case doc, ok := <-documents:
+			if !ok {
+				return nil
+			}
+
+			select {
+			case forwarded <- doc:
+				*count++
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
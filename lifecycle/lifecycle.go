@@ -0,0 +1,239 @@
+// Package lifecycle tracks the in-flight generations, streams and ingestion jobs of a
+// process and drains them on shutdown: new work is rejected, running jobs are notified
+// so they can finish or checkpoint, and the ones still running at the deadline are
+// cancelled.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultGracePeriod = 5 * time.Second
+)
+
+var (
+	ErrDraining     = errors.New("draining: not accepting new work")
+	ErrDrainTimeout = errors.New("drain deadline exceeded")
+)
+
+type Kind string
+
+const (
+	KindGeneration Kind = "generation"
+	KindStream     Kind = "stream"
+	KindIngestion  Kind = "ingestion"
+)
+
+// JobInfo describes an in-flight job.
+type JobInfo struct {
+	ID        uint64
+	Kind      Kind
+	Name      string
+	StartedAt time.Time
+}
+
+// Job is an in-flight job registered with Start. Done must be called when it ends.
+type Job struct {
+	info     JobInfo
+	manager  *Manager
+	cancel   context.CancelCauseFunc
+	doneOnce sync.Once
+}
+
+func (j *Job) Info() JobInfo {
+	return j.info
+}
+
+// Draining is closed when the manager starts draining: long jobs, such as streams and
+// ingestions, should finish early or checkpoint their progress.
+func (j *Job) Draining() <-chan struct{} {
+	return j.manager.draining
+}
+
+// Done unregisters the job. It is safe to call it more than once.
+func (j *Job) Done() {
+	j.doneOnce.Do(func() {
+		j.manager.mu.Lock()
+		delete(j.manager.jobs, j.info.ID)
+		j.manager.mu.Unlock()
+
+		j.cancel(nil)
+		j.manager.wg.Done()
+	})
+}
+
+// Manager tracks the in-flight jobs and drains them on shutdown.
+type Manager struct {
+	mu           sync.Mutex
+	jobs         map[uint64]*Job
+	nextID       uint64
+	drainStarted bool
+	wg           sync.WaitGroup
+	draining     chan struct{}
+	drainOnce    sync.Once
+	idle         chan struct{}
+	gracePeriod  time.Duration
+	now          func() time.Time
+}
+
+func New() *Manager {
+	return &Manager{
+		jobs:        make(map[uint64]*Job),
+		draining:    make(chan struct{}),
+		idle:        make(chan struct{}),
+		gracePeriod: defaultGracePeriod,
+		now:         time.Now,
+	}
+}
+
+// WithGracePeriod sets how long Drain waits for the jobs cancelled at the deadline to
+// return, e.g. to save a checkpoint. It defaults to 5 seconds.
+func (m *Manager) WithGracePeriod(gracePeriod time.Duration) *Manager {
+	m.gracePeriod = gracePeriod
+	return m
+}
+
+// Start registers a job. The returned context is cancelled, with ErrDrainTimeout as its
+// cause, if the job is still running at the drain deadline. Once the manager is draining
+// Start returns ErrDraining.
+func (m *Manager) Start(ctx context.Context, kind Kind, name string) (context.Context, *Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.drainStarted {
+		return ctx, nil, ErrDraining
+	}
+
+	m.nextID++
+	ctx, cancel := context.WithCancelCause(ctx)
+	job := &Job{
+		info: JobInfo{
+			ID:        m.nextID,
+			Kind:      kind,
+			Name:      name,
+			StartedAt: m.now(),
+		},
+		manager: m,
+		cancel:  cancel,
+	}
+
+	m.jobs[job.info.ID] = job
+	m.wg.Add(1)
+
+	return ctx, job, nil
+}
+
+// Go runs fn as a job, returning its error or ErrDraining if the manager is draining.
+func (m *Manager) Go(ctx context.Context, kind Kind, name string, fn func(ctx context.Context, job *Job) error) error {
+	ctx, job, err := m.Start(ctx, kind, name)
+	if err != nil {
+		return err
+	}
+	defer job.Done()
+
+	return fn(ctx, job)
+}
+
+// InFlight returns the jobs running, oldest first.
+func (m *Manager) InFlight() []JobInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]JobInfo, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job.info)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID < jobs[j].ID
+	})
+
+	return jobs
+}
+
+// Draining is closed when the manager starts draining.
+func (m *Manager) Draining() <-chan struct{} {
+	return m.draining
+}
+
+// Drain stops accepting new jobs and waits for the running ones until the context is
+// done. The jobs still running are then cancelled and given the grace period to return;
+// the error matches ErrDrainTimeout and lists them.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.drainOnce.Do(func() {
+		m.mu.Lock()
+		m.drainStarted = true
+		m.mu.Unlock()
+
+		close(m.draining)
+
+		go func() {
+			m.wg.Wait()
+			close(m.idle)
+		}()
+	})
+
+	select {
+	case <-m.idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	jobs := m.InFlight()
+
+	m.mu.Lock()
+	for _, job := range m.jobs {
+		job.cancel(ErrDrainTimeout)
+	}
+	m.mu.Unlock()
+
+	timer := time.NewTimer(m.gracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-m.idle:
+	case <-timer.C:
+	}
+
+	return &DrainError{Jobs: jobs}
+}
+
+// DrainOnSignal waits for one of the signals, SIGINT and SIGTERM by default, or for the
+// context to be done, then drains the manager within the timeout.
+func (m *Manager) DrainOnSignal(ctx context.Context, timeout time.Duration, signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	signalCtx, stop := signal.NotifyContext(ctx, signals...)
+	<-signalCtx.Done()
+	stop()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	return m.Drain(ctx)
+}
+
+// DrainError is the error of a drain that reached its deadline. It matches ErrDrainTimeout.
+type DrainError struct {
+	// Jobs are the jobs running at the deadline.
+	Jobs []JobInfo
+}
+
+func (e *DrainError) Error() string {
+	return fmt.Sprintf("%s: %d jobs cancelled", ErrDrainTimeout, len(e.Jobs))
+}
+
+func (e *DrainError) Unwrap() error {
+	return ErrDrainTimeout
+}
@@ -0,0 +1,160 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/thread"
+)
+
+type blockingLLM struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (l *blockingLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	close(l.started)
+
+	select {
+	case <-l.release:
+		t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("done")))
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+func TestManagerDrain(t *testing.T) {
+	manager := New()
+	llm := &blockingLLM{started: make(chan struct{}), release: make(chan struct{})}
+	tracked := NewLLM(llm, manager).WithName("chat")
+
+	myThread := thread.New()
+	generateErr := make(chan error, 1)
+	go func() {
+		generateErr <- tracked.Generate(context.Background(), myThread)
+	}()
+	<-llm.started
+
+	inFlight := manager.InFlight()
+	if len(inFlight) != 1 || inFlight[0].Kind != KindGeneration || inFlight[0].Name != "chat" {
+		t.Fatalf("in flight = %+v", inFlight)
+	}
+
+	drainErr := make(chan error, 1)
+	go func() {
+		drainErr <- manager.Drain(context.Background())
+	}()
+	<-manager.Draining()
+
+	if err := tracked.Generate(context.Background(), thread.New()); !errors.Is(err, ErrDraining) {
+		t.Errorf("generate while draining = %v, want ErrDraining", err)
+	}
+
+	close(llm.release)
+	if err := <-generateErr; err != nil {
+		t.Fatalf("generate = %v", err)
+	}
+	if err := <-drainErr; err != nil {
+		t.Fatalf("drain = %v", err)
+	}
+	if len(myThread.Messages) != 1 || len(manager.InFlight()) != 0 {
+		t.Errorf("messages = %d, in flight = %d", len(myThread.Messages), len(manager.InFlight()))
+	}
+}
+
+func TestManagerDrainTimeout(t *testing.T) {
+	manager := New().WithGracePeriod(time.Second)
+	llm := &blockingLLM{started: make(chan struct{}), release: make(chan struct{})}
+
+	generateErr := make(chan error, 1)
+	go func() {
+		generateErr <- NewLLM(llm, manager).WithKind(KindStream).Generate(context.Background(), thread.New())
+	}()
+	<-llm.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := manager.Drain(ctx)
+	var drainErr *DrainError
+	if !errors.As(err, &drainErr) || !errors.Is(err, ErrDrainTimeout) {
+		t.Fatalf("drain = %v, want a drain timeout", err)
+	}
+	if len(drainErr.Jobs) != 1 || drainErr.Jobs[0].Kind != KindStream {
+		t.Errorf("cancelled jobs = %+v", drainErr.Jobs)
+	}
+	if err := <-generateErr; !errors.Is(err, ErrDrainTimeout) {
+		t.Errorf("generate = %v, want to be cancelled by the drain", err)
+	}
+}
+
+type fakeStreamer struct {
+	mu        sync.Mutex
+	documents []document.Document
+	received  chan struct{}
+}
+
+func (s *fakeStreamer) AddStream(ctx context.Context, documents <-chan document.Document) error {
+	for doc := range documents {
+		s.mu.Lock()
+		s.documents = append(s.documents, doc)
+		s.mu.Unlock()
+		s.received <- struct{}{}
+	}
+
+	return nil
+}
+
+func TestManagerIngest(t *testing.T) {
+	manager := New()
+	streamer := &fakeStreamer{received: make(chan struct{}, 10)}
+
+	documents := make(chan document.Document, 10)
+	for i := 0; i < 10; i++ {
+		documents <- document.Document{Content: "doc"}
+	}
+
+	type result struct {
+		count int
+		err   error
+	}
+	ingested := make(chan result, 1)
+	go func() {
+		count, err := manager.Ingest(context.Background(), streamer, "docs", documents)
+		ingested <- result{count, err}
+	}()
+
+	<-streamer.received
+	<-streamer.received
+	if err := manager.Drain(context.Background()); err != nil {
+		t.Fatalf("drain = %v", err)
+	}
+
+	r := <-ingested
+	if !errors.Is(r.err, ErrDraining) {
+		t.Fatalf("ingest = %v, want ErrDraining", r.err)
+	}
+	if r.count < 2 || r.count != len(streamer.documents) || r.count+len(documents) != 10 {
+		t.Errorf("count = %d, ingested = %d, left = %d", r.count, len(streamer.documents), len(documents))
+	}
+}
+
+func TestManagerIngestAll(t *testing.T) {
+	streamer := &fakeStreamer{received: make(chan struct{}, 3)}
+
+	documents := make(chan document.Document, 3)
+	for i := 0; i < 3; i++ {
+		documents <- document.Document{Content: "doc"}
+	}
+	close(documents)
+
+	count, err := New().Ingest(context.Background(), streamer, "docs", documents)
+	if err != nil || count != 3 || len(streamer.documents) != 3 {
+		t.Errorf("ingest = %d, %v, ingested = %d", count, err, len(streamer.documents))
+	}
+}
@@ -0,0 +1,47 @@
+package lifecycle
+
+import (
+	"context"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// TrackedLLM registers each generation as a job of the manager, so the generations
+// buried in assistants, agents and tool loops are drained on shutdown.
+type TrackedLLM struct {
+	llm     LLM
+	manager *Manager
+	kind    Kind
+	name    string
+}
+
+func NewLLM(llm LLM, manager *Manager) *TrackedLLM {
+	return &TrackedLLM{
+		llm:     llm,
+		manager: manager,
+		kind:    KindGeneration,
+	}
+}
+
+// WithKind sets the kind of the jobs, e.g. KindStream for an LLM configured to stream.
+func (l *TrackedLLM) WithKind(kind Kind) *TrackedLLM {
+	l.kind = kind
+	return l
+}
+
+// WithName sets the name of the jobs, shown by InFlight.
+func (l *TrackedLLM) WithName(name string) *TrackedLLM {
+	l.name = name
+	return l
+}
+
+// Generate returns ErrDraining without calling the LLM once the manager is draining.
+func (l *TrackedLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	return l.manager.Go(ctx, l.kind, l.name, func(ctx context.Context, _ *Job) error {
+		return l.llm.Generate(ctx, t)
+	})
+}
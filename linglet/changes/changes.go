@@ -0,0 +1,223 @@
+// Package changes summarizes the updates between two versions of documents, asking an
+// LLM to describe the diff, e.g. for changelog or compliance workflows.
+package changes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/loader"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultContextLines  = 2
+	defaultMaxDiffLength = 12000
+)
+
+var (
+	ErrChanges = errors.New("changes error")
+)
+
+type Status string
+
+const (
+	StatusUnchanged Status = "unchanged"
+	StatusModified  Status = "modified"
+	StatusAdded     Status = "added"
+	StatusRemoved   Status = "removed"
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// Pair is a document in its old and new version. Added documents have an empty old
+// version, removed documents an empty new version.
+type Pair struct {
+	Source string
+	Old    *document.Document
+	New    *document.Document
+}
+
+type Summary struct {
+	Source   string
+	Status   Status
+	Inserted int
+	Deleted  int
+	Diff     string
+	Summary  string
+}
+
+type CallbackFn func(summary *Summary, i, n int)
+
+type Changes struct {
+	llm           LLM
+	contextLines  int
+	maxDiffLength int
+	callbackFn    CallbackFn
+}
+
+func New(llm LLM) *Changes {
+	return &Changes{
+		llm:           llm,
+		contextLines:  defaultContextLines,
+		maxDiffLength: defaultMaxDiffLength,
+	}
+}
+
+// WithContextLines sets the number of unchanged lines shown around each change.
+func (c *Changes) WithContextLines(contextLines int) *Changes {
+	c.contextLines = contextLines
+	return c
+}
+
+// WithMaxDiffLength truncates the diffs longer than maxDiffLength characters in the prompt.
+func (c *Changes) WithMaxDiffLength(maxDiffLength int) *Changes {
+	c.maxDiffLength = maxDiffLength
+	return c
+}
+
+// WithCallback sets a function called after each summary of SummarizeAll.
+func (c *Changes) WithCallback(callbackFn CallbackFn) *Changes {
+	c.callbackFn = callbackFn
+	return c
+}
+
+// Summarize summarizes the changes from the old to the new version of a document.
+// Unchanged documents are not sent to the LLM.
+func (c *Changes) Summarize(ctx context.Context, pair Pair) (*Summary, error) {
+	var oldContent, newContent string
+	if pair.Old != nil {
+		oldContent = pair.Old.Content
+	}
+	if pair.New != nil {
+		newContent = pair.New.Content
+	}
+
+	edits := Diff(oldContent, newContent)
+	inserted, deleted := Stats(edits)
+
+	summary := &Summary{
+		Source:   pair.Source,
+		Status:   StatusModified,
+		Inserted: inserted,
+		Deleted:  deleted,
+	}
+
+	switch {
+	case pair.Old == nil:
+		summary.Status = StatusAdded
+	case pair.New == nil:
+		summary.Status = StatusRemoved
+	case inserted == 0 && deleted == 0:
+		summary.Status = StatusUnchanged
+		return summary, nil
+	}
+
+	summary.Diff = Unified(edits, c.contextLines)
+
+	diff := summary.Diff
+	if c.maxDiffLength > 0 && len(diff) > c.maxDiffLength {
+		diff = diff[:c.maxDiffLength] + "\n[diff truncated]\n"
+	}
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent(summaryPrompt).Format(
+				types.M{
+					"source": pair.Source,
+					"diff":   diff,
+				},
+			),
+		),
+	)
+
+	err := c.llm.Generate(ctx, t)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrChanges, err)
+	}
+
+	summary.Summary = strings.TrimSpace(t.LastMessage().Contents[0].AsString())
+
+	return summary, nil
+}
+
+// SummarizeAll summarizes the changes of every pair, e.g. returned by Pairs.
+func (c *Changes) SummarizeAll(ctx context.Context, pairs []Pair) ([]Summary, error) {
+	summaries := make([]Summary, 0, len(pairs))
+	for i, pair := range pairs {
+		summary, err := c.Summarize(ctx, pair)
+		if err != nil {
+			return summaries, err
+		}
+
+		if c.callbackFn != nil {
+			c.callbackFn(summary, i+1, len(pairs))
+		}
+
+		summaries = append(summaries, *summary)
+	}
+
+	return summaries, nil
+}
+
+// KeyFn returns the key identifying the versions of the same document.
+type KeyFn func(document.Document) string
+
+// Pairs matches the old and new versions of a corpus, e.g. loaded by the same loader at
+// different times, by the key returned by keyFn, the loader source metadata if nil.
+// Documents with the same key are concatenated.
+func Pairs(oldDocuments, newDocuments []document.Document, keyFn KeyFn) []Pair {
+	if keyFn == nil {
+		keyFn = sourceKey
+	}
+
+	oldBySource, oldSources := groupBySource(oldDocuments, keyFn)
+	newBySource, newSources := groupBySource(newDocuments, keyFn)
+
+	var pairs []Pair
+	for _, source := range oldSources {
+		pair := Pair{Source: source, Old: oldBySource[source]}
+		if newDocument, ok := newBySource[source]; ok {
+			pair.New = newDocument
+		}
+		pairs = append(pairs, pair)
+	}
+	for _, source := range newSources {
+		if _, ok := oldBySource[source]; !ok {
+			pairs = append(pairs, Pair{Source: source, New: newBySource[source]})
+		}
+	}
+
+	return pairs
+}
+
+func sourceKey(d document.Document) string {
+	source, _ := d.Metadata[loader.SourceMetadataKey].(string)
+	return source
+}
+
+func groupBySource(documents []document.Document, keyFn KeyFn) (map[string]*document.Document, []string) {
+	bySource := make(map[string]*document.Document)
+	var sources []string
+	for _, d := range documents {
+		source := keyFn(d)
+
+		grouped, ok := bySource[source]
+		if !ok {
+			grouped = &document.Document{Metadata: d.Metadata}
+			bySource[source] = grouped
+			sources = append(sources, source)
+		} else {
+			grouped.Content += "\n"
+		}
+		grouped.Content += d.Content
+	}
+
+	return bySource, sources
+}
@@ -0,0 +1,67 @@
+package changes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/loader"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+func TestUnified(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\n"
+	newText := "a\nb\nC\nd\ne\nf\ng\nh\n"
+
+	got := Unified(Diff(oldText, newText), 1)
+	want := "  b\n- c\n+ C\n  d\n...\n  g\n+ h\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+type fakeLLM struct {
+	prompts []string
+}
+
+func (f *fakeLLM) Generate(_ context.Context, t *thread.Thread) error {
+	f.prompts = append(f.prompts, t.LastMessage().Contents[0].AsString())
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("- the fee changed\n")))
+	return nil
+}
+
+func TestChanges_SummarizeAll(t *testing.T) {
+	source := func(name, content string) document.Document {
+		return document.Document{Content: content, Metadata: types.Meta{loader.SourceMetadataKey: name}}
+	}
+
+	pairs := Pairs(
+		[]document.Document{source("terms.txt", "The fee is 10 EUR."), source("faq.txt", "Q: A?")},
+		[]document.Document{source("terms.txt", "The fee is 12 EUR."), source("faq.txt", "Q: A?"), source("new.txt", "Hi")},
+		nil,
+	)
+
+	llm := &fakeLLM{}
+	summaries, err := New(llm).SummarizeAll(context.Background(), pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := map[string]Status{}
+	for _, summary := range summaries {
+		statuses[summary.Source] = summary.Status
+	}
+	if statuses["terms.txt"] != StatusModified || statuses["faq.txt"] != StatusUnchanged ||
+		statuses["new.txt"] != StatusAdded {
+		t.Fatalf("unexpected statuses %v", statuses)
+	}
+
+	if len(llm.prompts) != 2 || !strings.Contains(llm.prompts[0], "- The fee is 10 EUR.\n+ The fee is 12 EUR.") {
+		t.Fatalf("unexpected prompts %q", llm.prompts)
+	}
+	if summaries[0].Summary != "- the fee changed" {
+		t.Fatalf("unexpected summary %q", summaries[0].Summary)
+	}
+}
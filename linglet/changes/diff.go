@@ -0,0 +1,168 @@
+package changes
+
+import (
+	"strings"
+)
+
+// maxLCSCells bounds the memory used to diff the changed lines, beyond it the changed
+// lines are reported as deleted and inserted as a whole.
+const maxLCSCells = 4 << 20
+
+type Operation int
+
+const (
+	OperationEqual Operation = iota
+	OperationInsert
+	OperationDelete
+)
+
+// Edit is a line of the diff.
+type Edit struct {
+	Operation Operation
+	Line      string
+}
+
+// Diff returns the line by line edits turning oldText into newText, computed on the
+// longest common subsequence of the lines.
+func Diff(oldText, newText string) []Edit {
+	oldLines, newLines := splitLines(oldText), splitLines(newText)
+
+	// skip the common prefix and suffix, most updates touch a few lines
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(oldLines)+len(newLines))
+	for _, line := range oldLines[:prefix] {
+		edits = append(edits, Edit{Operation: OperationEqual, Line: line})
+	}
+	edits = append(edits, lcsDiff(oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix])...)
+	for _, line := range oldLines[len(oldLines)-suffix:] {
+		edits = append(edits, Edit{Operation: OperationEqual, Line: line})
+	}
+
+	return edits
+}
+
+func lcsDiff(a, b []string) []Edit {
+	if len(a)*len(b) > maxLCSCells {
+		edits := make([]Edit, 0, len(a)+len(b))
+		for _, line := range a {
+			edits = append(edits, Edit{Operation: OperationDelete, Line: line})
+		}
+		for _, line := range b {
+			edits = append(edits, Edit{Operation: OperationInsert, Line: line})
+		}
+		return edits
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []Edit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, Edit{Operation: OperationEqual, Line: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, Edit{Operation: OperationDelete, Line: a[i]})
+			i++
+		default:
+			edits = append(edits, Edit{Operation: OperationInsert, Line: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, Edit{Operation: OperationDelete, Line: a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, Edit{Operation: OperationInsert, Line: b[j]})
+	}
+
+	return edits
+}
+
+// Unified formats the edits as a unified diff, keeping contextLines unchanged lines
+// around each change.
+func Unified(edits []Edit, contextLines int) string {
+	var sb strings.Builder
+
+	lastPrinted := -1
+	for i, edit := range edits {
+		if edit.Operation == OperationEqual && !nearChange(edits, i, contextLines) {
+			continue
+		}
+
+		if lastPrinted >= 0 && i > lastPrinted+1 {
+			sb.WriteString("...\n")
+		}
+		lastPrinted = i
+
+		switch edit.Operation {
+		case OperationEqual:
+			sb.WriteString("  ")
+		case OperationInsert:
+			sb.WriteString("+ ")
+		case OperationDelete:
+			sb.WriteString("- ")
+		}
+		sb.WriteString(edit.Line)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// Stats returns the number of inserted and deleted lines.
+func Stats(edits []Edit) (inserted, deleted int) {
+	for _, edit := range edits {
+		switch edit.Operation {
+		case OperationInsert:
+			inserted++
+		case OperationDelete:
+			deleted++
+		case OperationEqual:
+		}
+	}
+
+	return inserted, deleted
+}
+
+func nearChange(edits []Edit, i, contextLines int) bool {
+	for j := i - contextLines; j <= i+contextLines; j++ {
+		if j >= 0 && j < len(edits) && edits[j].Operation != OperationEqual {
+			return true
+		}
+	}
+
+	return false
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
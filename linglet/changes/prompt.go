@@ -0,0 +1,9 @@
+package changes
+
+const (
+	//nolint:lll
+	summaryPrompt = `The following is a diff between two versions of the document "{{.source}}". Lines starting with "+" were added, lines starting with "-" were removed, the other lines are unchanged context.
+
+{{.diff}}
+Summarize what changed in the new version in a few bullet points, focusing on changes of meaning (obligations, figures, dates, names) rather than on wording or formatting. Don't describe the unchanged content.`
+)
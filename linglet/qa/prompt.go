@@ -22,3 +22,23 @@ The prompt is : {{.prompt}}
 
 Only return the refined prompt as output. Merge the final prompt into one sentence or paragraph.`
 )
+
+//nolint:lll
+const (
+	tableClassificationPrompt = `The following tables are available:
+{{.schema}}
+Can the question below be answered by filtering, aggregating or sorting the rows of these tables? Answer TABLE if it can, TEXT otherwise.
+
+Question: {{.question}}`
+
+	tableQueryPrompt = `The following tables are available:
+{{.schema}}
+Write the query answering the question below as a JSON object with these fields: "table" (the table name), "columns" (the columns to return), "filters" (a list of {"column", "operator", "value"}, with operator one of eq, ne, gt, gte, lt, lte, contains), "group_by" (a column), "aggregations" (a list of {"function", "column"}, with function one of count, sum, avg, min, max), "order_by" (a result column), "descending" and "limit". Omit the fields you don't need and only return the JSON object.
+
+Question: {{.question}}`
+
+	tableAnswerPrompt = `Answer the question using only the result of the table query below, in a short sentence.
+
+Question: {{.question}}
+Query result: {{.result}}`
+)
@@ -7,6 +7,7 @@ import (
 	"github.com/henomis/lingoose/index"
 	"github.com/henomis/lingoose/rag"
 	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/tool/table"
 	"github.com/henomis/lingoose/types"
 )
 
@@ -22,6 +23,7 @@ type QA struct {
 	llm            LLM
 	index          *index.Index
 	subDocumentRAG *rag.SubDocumentRAG
+	tableTool      *table.Tool
 }
 
 func New(
@@ -58,10 +60,21 @@ func (qa *QA) refinePrompt(ctx context.Context, prompt string) (string, error) {
 }
 
 func (qa *QA) AddSource(ctx context.Context, source string) error {
+	if qa.tableTool != nil {
+		err := qa.addTables(ctx, source)
+		if err != nil {
+			return err
+		}
+	}
+
 	return qa.subDocumentRAG.AddSources(ctx, source)
 }
 
 func (qa *QA) Run(ctx context.Context, prompt string) (string, error) {
+	if answer, ok := qa.runTable(ctx, prompt); ok {
+		return answer, nil
+	}
+
 	refinedPromt, err := qa.refinePrompt(ctx, prompt)
 	if err != nil {
 		return "", err
@@ -0,0 +1,105 @@
+package qa
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/henomis/lingoose/loader"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/tool/table"
+	"github.com/henomis/lingoose/types"
+)
+
+// WithTableTool enables the table mode: the tables of the HTML and PDF sources are added
+// to the tool, and questions about them are answered by querying the tool instead of
+// retrieving text.
+func (qa *QA) WithTableTool(tableTool *table.Tool) *QA {
+	qa.tableTool = tableTool
+	return qa
+}
+
+func (qa *QA) addTables(ctx context.Context, source string) error {
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".html", ".htm", ".pdf":
+	default:
+		return nil
+	}
+
+	documents, err := loader.NewTable().LoadFromSource(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	return qa.tableTool.AddDocuments(documents)
+}
+
+// runTable answers a table question. It returns false if the question is not about the
+// tables or the query could not be run, so that the caller falls back to RAG.
+func (qa *QA) runTable(ctx context.Context, prompt string) (string, bool) {
+	if qa.tableTool == nil || len(qa.tableTool.Tables()) == 0 {
+		return "", false
+	}
+
+	schema := qa.tableTool.Schema()
+
+	answer, err := qa.generate(ctx, tableClassificationPrompt, types.M{"schema": schema, "question": prompt})
+	if err != nil || !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(answer)), "TABLE") {
+		return "", false
+	}
+
+	answer, err = qa.generate(ctx, tableQueryPrompt, types.M{"schema": schema, "question": prompt})
+	if err != nil {
+		return "", false
+	}
+
+	var input table.Input
+	err = json.Unmarshal([]byte(jsonObject(answer)), &input)
+	if err != nil {
+		return "", false
+	}
+
+	output, err := qa.tableTool.Query(input)
+	if err != nil {
+		return "", false
+	}
+
+	result, err := json.Marshal(output)
+	if err != nil {
+		return "", false
+	}
+
+	answer, err = qa.generate(ctx, tableAnswerPrompt, types.M{"question": prompt, "result": string(result)})
+	if err != nil {
+		return "", false
+	}
+
+	return answer, true
+}
+
+func (qa *QA) generate(ctx context.Context, prompt string, input types.M) (string, error) {
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent(prompt).Format(input),
+		),
+	)
+
+	err := qa.llm.Generate(ctx, t)
+	if err != nil {
+		return "", err
+	}
+
+	return t.LastMessage().Contents[0].AsString(), nil
+}
+
+// jsonObject strips anything around the outermost JSON object, e.g. code fences.
+func jsonObject(text string) string {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return text
+	}
+
+	return text[start : end+1]
+}
@@ -0,0 +1,13 @@
+package topics
+
+const (
+	//nolint:lll
+	segmentPrompt = `The following is a conversation between a user and an assistant. Each message is prefixed by its number.
+
+{{range .messages}}[{{.Index}}] {{.Role}}: {{.Text}}
+{{end}}
+Split the conversation into consecutive segments, one for each topic discussed: a new segment starts when the user moves to a different subject. For each segment write a concise title (at most 8 words) and a one or two sentence summary. Then write a title and a summary for the whole conversation.
+Answer only with a JSON object like:
+{"title": "...", "summary": "...", "segments": [{"start": 0, "title": "...", "summary": "..."}]}
+where "start" is the number of the first message of the segment.`
+)
@@ -0,0 +1,210 @@
+// Package topics segments a conversation thread into topics, generating a title and a
+// summary for each segment and for the whole session.
+package topics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultMaxMessageLength = 2000
+
+	MetadataTitle = "title"
+	MetadataStart = "start"
+	MetadataEnd   = "end"
+)
+
+var (
+	ErrTopics = errors.New("topics error")
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// Segment is a topic of the conversation, spanning the thread messages [Start, End).
+type Segment struct {
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+}
+
+type Session struct {
+	Title    string    `json:"title"`
+	Summary  string    `json:"summary"`
+	Segments []Segment `json:"segments"`
+}
+
+type Topics struct {
+	llm              LLM
+	maxMessageLength int
+}
+
+func New(llm LLM) *Topics {
+	return &Topics{
+		llm:              llm,
+		maxMessageLength: defaultMaxMessageLength,
+	}
+}
+
+// WithMaxMessageLength truncates the messages longer than maxMessageLength characters
+// in the prompt, to keep long threads within the model context.
+func (t *Topics) WithMaxMessageLength(maxMessageLength int) *Topics {
+	t.maxMessageLength = maxMessageLength
+	return t
+}
+
+type transcriptMessage struct {
+	Index int
+	Role  thread.Role
+	Text  string
+}
+
+// Segment splits the user and assistant messages of the thread into topics. System and
+// tool messages are ignored but still belong to the segment they fall in.
+func (t *Topics) Segment(ctx context.Context, conversation *thread.Thread) (*Session, error) {
+	messages := t.transcript(conversation)
+	if len(messages) == 0 {
+		return &Session{}, nil
+	}
+
+	prompt := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent(segmentPrompt).Format(
+				types.M{
+					"messages": messages,
+				},
+			),
+		),
+	)
+
+	err := t.llm.Generate(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTopics, err)
+	}
+
+	session, err := parseSession(prompt.LastMessage())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTopics, err)
+	}
+
+	session.Segments = normalizeSegments(session.Segments, len(conversation.Messages))
+
+	return session, nil
+}
+
+func (t *Topics) transcript(conversation *thread.Thread) []transcriptMessage {
+	var messages []transcriptMessage
+	for i, message := range conversation.Messages {
+		if message.Role != thread.RoleUser && message.Role != thread.RoleAssistant {
+			continue
+		}
+
+		var text string
+		for _, content := range message.Contents {
+			if content.Type == thread.ContentTypeText {
+				text += content.AsString()
+			}
+		}
+
+		text = strings.Join(strings.Fields(text), " ")
+		if text == "" {
+			continue
+		}
+		if t.maxMessageLength > 0 && len(text) > t.maxMessageLength {
+			text = text[:t.maxMessageLength] + "..."
+		}
+
+		messages = append(messages, transcriptMessage{
+			Index: i,
+			Role:  message.Role,
+			Text:  text,
+		})
+	}
+
+	return messages
+}
+
+func parseSession(message *thread.Message) (*Session, error) {
+	var text string
+	for _, content := range message.Contents {
+		if content.Type == thread.ContentTypeText {
+			text += content.AsString()
+		}
+	}
+
+	// the model may wrap the JSON object in a code block or in some text
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid segmentation %s", strconv.Quote(text))
+	}
+
+	session := &Session{}
+	err := json.Unmarshal([]byte(text[start:end+1]), session)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// normalizeSegments sorts the segments, drops out of range or duplicate starts and makes
+// them contiguous, covering all the nMessages messages.
+func normalizeSegments(segments []Segment, nMessages int) []Segment {
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].Start < segments[j].Start
+	})
+
+	var normalized []Segment
+	for _, segment := range segments {
+		if segment.Start < 0 || segment.Start >= nMessages {
+			continue
+		}
+		if len(normalized) > 0 && normalized[len(normalized)-1].Start == segment.Start {
+			continue
+		}
+		normalized = append(normalized, segment)
+	}
+
+	for i := range normalized {
+		if i == 0 {
+			normalized[i].Start = 0
+		}
+		if i == len(normalized)-1 {
+			normalized[i].End = nMessages
+		} else {
+			normalized[i].End = normalized[i+1].Start
+		}
+	}
+
+	return normalized
+}
+
+// Documents returns a document per segment, with the summary as content and the title
+// and the message range as metadata, ready to be indexed.
+func (s *Session) Documents() []document.Document {
+	documents := make([]document.Document, 0, len(s.Segments))
+	for _, segment := range s.Segments {
+		documents = append(documents, document.Document{
+			Content: segment.Title + "\n" + segment.Summary,
+			Metadata: types.Meta{
+				MetadataTitle: segment.Title,
+				MetadataStart: segment.Start,
+				MetadataEnd:   segment.End,
+			},
+		})
+	}
+
+	return documents
+}
@@ -0,0 +1,54 @@
+package topics
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+type fakeLLM string
+
+func (f fakeLLM) Generate(_ context.Context, t *thread.Thread) error {
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent(string(f))))
+	return nil
+}
+
+func TestTopics_Segment(t *testing.T) {
+	conversation := thread.New().AddMessages(
+		thread.NewSystemMessage().AddContent(thread.NewTextContent("You are helpful.")),
+		thread.NewUserMessage().AddContent(thread.NewTextContent("How do I bake bread?")),
+		thread.NewAssistantMessage().AddContent(thread.NewTextContent("Mix flour, water and yeast.")),
+		thread.NewUserMessage().AddContent(thread.NewTextContent("Thanks. Now, what's a goroutine?")),
+		thread.NewAssistantMessage().AddContent(thread.NewTextContent("A lightweight thread managed by Go.")),
+	)
+
+	llm := fakeLLM("```json\n" + `{"title": "Bread and Go", "summary": "Baking, then Go.", "segments": [
+		{"start": 3, "title": "Goroutines", "summary": "Go concurrency."},
+		{"start": 1, "title": "Baking bread", "summary": "A bread recipe."},
+		{"start": 42, "title": "Hallucinated", "summary": ""}
+	]}` + "\n```")
+
+	session, err := New(llm).Segment(context.Background(), conversation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Session{
+		Title:   "Bread and Go",
+		Summary: "Baking, then Go.",
+		Segments: []Segment{
+			{Start: 0, End: 3, Title: "Baking bread", Summary: "A bread recipe."},
+			{Start: 3, End: 5, Title: "Goroutines", Summary: "Go concurrency."},
+		},
+	}
+	if !reflect.DeepEqual(session, want) {
+		t.Fatalf("got %+v, want %+v", session, want)
+	}
+
+	documents := session.Documents()
+	if len(documents) != 2 || documents[1].Metadata[MetadataStart] != 3 {
+		t.Fatalf("unexpected documents %+v", documents)
+	}
+}
@@ -12,8 +12,11 @@ import (
 	"github.com/henomis/restclientgo"
 
 	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/llm/httperror"
 	llmobserver "github.com/henomis/lingoose/llm/observer"
+	"github.com/henomis/lingoose/llm/usagereport"
 	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/secret"
 	"github.com/henomis/lingoose/thread"
 	"github.com/henomis/lingoose/types"
 )
@@ -43,14 +46,19 @@ const (
 
 type StreamCallbackFn func(string)
 
+type UsageCallback func(types.Meta)
+
 type Antropic struct {
 	model            string
 	temperature      float64
 	restClient       *restclientgo.RestClient
 	streamCallbackFn StreamCallbackFn
+	usageCallback    UsageCallback
 	cache            *cache.Cache
 	apiVersion       string
 	apiKey           string
+	apiKeyFunc       func(ctx context.Context) string
+	httpClient       *http.Client
 	maxTokens        int
 	name             string
 }
@@ -84,6 +92,13 @@ func (o *Antropic) WithStream(callbackFn StreamCallbackFn) *Antropic {
 	return o
 }
 
+// WithUsageCallback sets a callback receiving the token usage of each generation,
+// including the tokens written to and read from the prompt cache.
+func (o *Antropic) WithUsageCallback(callback UsageCallback) *Antropic {
+	o.usageCallback = callback
+	return o
+}
+
 func (o *Antropic) WithCache(cache *cache.Cache) *Antropic {
 	o.cache = cache
 	return o
@@ -99,6 +114,42 @@ func (o *Antropic) WithMaxTokens(maxTokens int) *Antropic {
 	return o
 }
 
+// WithAPIKeyFunc sets a function returning the API key of each request from its context,
+// so that multi-tenant applications send the requests of each tenant with its own key,
+// e.g. the key of the tenant set with secret.WithTenant. An empty key keeps the default.
+func (o *Antropic) WithAPIKeyFunc(apiKeyFunc func(ctx context.Context) string) *Antropic {
+	o.apiKeyFunc = apiKeyFunc
+	return o.setHTTPClient()
+}
+
+// WithHTTPClient sets the http client to use for the LLM
+func (o *Antropic) WithHTTPClient(httpClient *http.Client) *Antropic {
+	o.httpClient = httpClient
+	return o.setHTTPClient()
+}
+
+// setHTTPClient sets the http client of the rest client, injecting the API key of the
+// request when an API key function is set.
+func (o *Antropic) setHTTPClient() *Antropic {
+	httpClient := o.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	if o.apiKeyFunc != nil {
+		client := *httpClient
+		transport := client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		client.Transport = secret.NewKeyFuncTransport(o.apiKeyFunc, secret.HeaderAuth("x-api-key")).WithBase(transport)
+		httpClient = &client
+	}
+
+	o.restClient.SetHTTPClient(httpClient)
+	return o
+}
+
 func (o *Antropic) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
 	messages := t.UserQuery()
 	cacheQuery := strings.Join(messages, "\n")
@@ -198,6 +249,10 @@ func (o *Antropic) generate(ctx context.Context, t *thread.Thread, chatRequest *
 		return fmt.Errorf("%w: %w", ErrAnthropicChat, err)
 	}
 
+	if o.reportsUsage(ctx) {
+		o.setUsageMetadata(ctx, resp.Usage)
+	}
+
 	m := thread.NewAssistantMessage()
 
 	for _, content := range resp.Content {
@@ -216,6 +271,7 @@ func (o *Antropic) generate(ctx context.Context, t *thread.Thread, chatRequest *
 func (o *Antropic) stream(ctx context.Context, t *thread.Thread, chatRequest *request) error {
 	var resp response
 	var assistantMessage string
+	var streamUsage usage
 
 	resp.SetAcceptContentType(eventStreamContentType)
 	resp.SetStreamCallback(
@@ -230,12 +286,24 @@ func (o *Antropic) stream(ctx context.Context, t *thread.Thread, chatRequest *re
 			var e event
 			_ = json.Unmarshal([]byte(dataAsString), &e)
 
-			if e.Type == "content_block_delta" {
+			switch e.Type {
+			case "message_start":
+				if e.Message != nil {
+					streamUsage = e.Message.Usage
+				}
+			case "content_block_delta":
 				if e.Delta != nil {
 					assistantMessage += e.Delta.Text
 					o.streamCallbackFn(e.Delta.Text)
 				}
-			} else if e.Type == "message_stop" {
+			case "message_delta":
+				if e.Usage != nil {
+					streamUsage.OutputTokens = e.Usage.OutputTokens
+				}
+			case "message_stop":
+				if o.reportsUsage(ctx) {
+					o.setUsageMetadata(ctx, streamUsage)
+				}
 				o.streamCallbackFn(EOS)
 			}
 
@@ -255,7 +323,8 @@ func (o *Antropic) stream(ctx context.Context, t *thread.Thread, chatRequest *re
 	}
 
 	if resp.HTTPStatusCode >= http.StatusBadRequest {
-		return fmt.Errorf("%w: %s", ErrAnthropicChat, resp.RawBody)
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return fmt.Errorf("%w: %w", ErrAnthropicChat, httpErr)
 	}
 
 	t.AddMessage(thread.NewAssistantMessage().AddContent(
@@ -265,6 +334,33 @@ func (o *Antropic) stream(ctx context.Context, t *thread.Thread, chatRequest *re
 	return nil
 }
 
+// reportsUsage reports whether the usage of the request is passed to the usage callback
+// or to the usage reporter of the context.
+func (o *Antropic) reportsUsage(ctx context.Context) bool {
+	return o.usageCallback != nil || usagereport.Enabled(ctx)
+}
+
+// setUsageMetadata passes the usage to the usage callback and to the usage reporter of
+// the context, with the tenant of the request if any.
+func (o *Antropic) setUsageMetadata(ctx context.Context, u usage) {
+	usageMetadata := types.Meta{
+		"PromptTokens":             u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		"CompletionTokens":         u.OutputTokens,
+		"TotalTokens":              u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens + u.OutputTokens,
+		"CacheCreationInputTokens": u.CacheCreationInputTokens,
+		"CacheReadInputTokens":     u.CacheReadInputTokens,
+	}
+
+	if tenant := secret.Tenant(ctx); tenant != "" {
+		usageMetadata[secret.UsageKeyTenant] = tenant
+	}
+
+	usagereport.Report(ctx, usageMetadata)
+	if o.usageCallback != nil {
+		o.usageCallback(usageMetadata)
+	}
+}
+
 func (o *Antropic) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
 	return llmobserver.StartObserveGeneration(
 		ctx,
@@ -15,7 +15,7 @@ import (
 type request struct {
 	Model         string    `json:"model"`
 	Messages      []message `json:"messages"`
-	System        string    `json:"system"`
+	System        any       `json:"system,omitempty"`
 	MaxTokens     int       `json:"max_tokens"`
 	Metadata      metadata  `json:"metadata"`
 	StopSequences []string  `json:"stop_sequences"`
@@ -59,7 +59,8 @@ type response struct {
 	StopSequence      *string   `json:"stop_sequence"`
 	Usage             usage     `json:"usage"`
 	streamCallbackFn  restclientgo.StreamCallback
-	RawBody           []byte `json:"-"`
+	RawBody           []byte               `json:"-"`
+	Headers           restclientgo.Headers `json:"-"`
 }
 
 type aerror struct {
@@ -68,9 +69,14 @@ type aerror struct {
 }
 
 type content struct {
-	Type   contentType    `json:"type"`
-	Text   *string        `json:"text,omitempty"`
-	Source *contentSource `json:"source,omitempty"`
+	Type         contentType    `json:"type"`
+	Text         *string        `json:"text,omitempty"`
+	Source       *contentSource `json:"source,omitempty"`
+	CacheControl *cacheControl  `json:"cache_control,omitempty"`
+}
+
+type cacheControl struct {
+	Type string `json:"type"`
 }
 
 type contentSource struct {
@@ -80,8 +86,10 @@ type contentSource struct {
 }
 
 type usage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 }
 
 func (r *response) SetAcceptContentType(contentType string) {
@@ -109,7 +117,10 @@ func (r *response) SetStatusCode(code int) error {
 	return nil
 }
 
-func (r *response) SetHeaders(_ restclientgo.Headers) error { return nil }
+func (r *response) SetHeaders(headers restclientgo.Headers) error {
+	r.Headers = headers
+	return nil
+}
 
 func (r *response) SetStreamCallback(fn restclientgo.StreamCallback) {
 	r.streamCallbackFn = fn
@@ -132,9 +143,11 @@ const (
 )
 
 type event struct {
-	Type  string `json:"type"`
-	Index *int   `json:"index,omitempty"`
-	Delta *delta `json:"delta,omitempty"`
+	Type    string    `json:"type"`
+	Index   *int      `json:"index,omitempty"`
+	Delta   *delta    `json:"delta,omitempty"`
+	Message *response `json:"message,omitempty"`
+	Usage   *usage    `json:"usage,omitempty"`
 }
 
 type delta struct {
@@ -4,6 +4,12 @@ import (
 	"github.com/henomis/lingoose/thread"
 )
 
+const (
+	// maxCacheBreakpoints is the number of cache_control blocks allowed in a request.
+	maxCacheBreakpoints   = 4
+	cacheControlEphemeral = "ephemeral"
+)
+
 func (o *Antropic) buildChatCompletionRequest(t *thread.Thread) *request {
 	messages, systemPrompt := threadToChatMessages(t)
 
@@ -16,10 +22,15 @@ func (o *Antropic) buildChatCompletionRequest(t *thread.Thread) *request {
 	}
 }
 
+// threadToChatMessages returns the messages and the system prompt, as text blocks when
+// the system message is cacheable.
+//
 //nolint:gocognit
-func threadToChatMessages(t *thread.Thread) ([]message, string) {
+func threadToChatMessages(t *thread.Thread) ([]message, any) {
 	var systemPrompt string
+	var systemCacheable bool
 	var chatMessages []message
+	var cacheable []int
 	for _, m := range t.Messages {
 		switch m.Role {
 		case thread.RoleSystem:
@@ -31,6 +42,7 @@ func threadToChatMessages(t *thread.Thread) ([]message, string) {
 
 				systemPrompt += contentData
 			}
+			systemCacheable = systemCacheable || m.Cacheable()
 		case thread.RoleUser, thread.RoleAssistant:
 			chatMessage := message{
 				Role: threadRoleToAnthropicRole[m.Role],
@@ -70,11 +82,41 @@ func threadToChatMessages(t *thread.Thread) ([]message, string) {
 					continue
 				}
 			}
+			if m.Cacheable() && len(chatMessage.Content) > 0 {
+				cacheable = append(cacheable, len(chatMessages))
+			}
 			chatMessages = append(chatMessages, chatMessage)
 		case thread.RoleTool:
 			continue
 		}
 	}
 
-	return chatMessages, systemPrompt
+	if !systemCacheable || systemPrompt == "" {
+		setCacheBreakpoints(chatMessages, cacheable, maxCacheBreakpoints)
+		return chatMessages, systemPrompt
+	}
+
+	setCacheBreakpoints(chatMessages, cacheable, maxCacheBreakpoints-1)
+
+	return chatMessages, []content{
+		{
+			Type:         messageTypeText,
+			Text:         &systemPrompt,
+			CacheControl: &cacheControl{Type: cacheControlEphemeral},
+		},
+	}
+}
+
+// setCacheBreakpoints marks the last content of the cacheable messages with cache_control.
+// Only the latest ones are marked when they exceed the limit, since each breakpoint also
+// reads the cache of the shorter prefixes.
+func setCacheBreakpoints(chatMessages []message, cacheable []int, limit int) {
+	if len(cacheable) > limit {
+		cacheable = cacheable[len(cacheable)-limit:]
+	}
+
+	for _, i := range cacheable {
+		last := &chatMessages[i].Content[len(chatMessages[i].Content)-1]
+		last.CacheControl = &cacheControl{Type: cacheControlEphemeral}
+	}
 }
@@ -0,0 +1,490 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+
+	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/llm/function"
+	llmobserver "github.com/henomis/lingoose/llm/observer"
+	"github.com/henomis/lingoose/llm/usagereport"
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/thread"
+	lingoosetypes "github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultMaxTokens   = 1024
+	defaultTemperature = 0.7
+	EOS                = "\x00"
+)
+
+const (
+	ModelClaude3Sonnet  = "anthropic.claude-3-sonnet-20240229-v1:0"
+	ModelClaude3Haiku   = "anthropic.claude-3-haiku-20240307-v1:0"
+	ModelClaude35Sonnet = "anthropic.claude-3-5-sonnet-20240620-v1:0"
+	ModelLlama38B       = "meta.llama3-8b-instruct-v1:0"
+	ModelLlama370B      = "meta.llama3-70b-instruct-v1:0"
+	ModelTitanTextG1    = "amazon.titan-text-express-v1"
+	ModelMistralLarge   = "mistral.mistral-large-2402-v1:0"
+	defaultModel        = ModelClaude3Haiku
+)
+
+var (
+	ErrBedrockChat = fmt.Errorf("bedrock chat error")
+)
+
+type StreamCallbackFn func(string)
+
+type UsageCallback func(lingoosetypes.Meta)
+
+type Function = function.Function
+
+type Tool = function.Tool
+
+type Bedrock struct {
+	client           *bedrockruntime.Client
+	region           string
+	model            string
+	temperature      float32
+	maxTokens        int
+	topP             *float32
+	stop             []string
+	streamCallbackFn StreamCallbackFn
+	usageCallback    UsageCallback
+	cache            *cache.Cache
+	functions        map[string]Function
+	toolChoice       *string
+	name             string
+}
+
+// New creates a Bedrock LLM. Credentials and region are resolved by the AWS SDK default
+// chain (environment variables, shared config files, SSO, instance roles) on first use.
+func New() *Bedrock {
+	return &Bedrock{
+		model:       defaultModel,
+		temperature: defaultTemperature,
+		maxTokens:   defaultMaxTokens,
+		functions:   make(map[string]Function),
+		name:        "bedrock",
+	}
+}
+
+// WithClient sets a preconfigured Bedrock Runtime client.
+func (b *Bedrock) WithClient(client *bedrockruntime.Client) *Bedrock {
+	b.client = client
+	return b
+}
+
+// WithConfig creates the Bedrock Runtime client from the given AWS config.
+func (b *Bedrock) WithConfig(cfg aws.Config) *Bedrock {
+	b.client = bedrockruntime.NewFromConfig(cfg)
+	return b
+}
+
+// WithRegion sets the AWS region used when loading the default AWS config.
+func (b *Bedrock) WithRegion(region string) *Bedrock {
+	b.region = region
+	return b
+}
+
+// WithModel sets the Bedrock model ID (e.g. anthropic.claude-3-haiku-20240307-v1:0)
+// or inference profile ARN.
+func (b *Bedrock) WithModel(model string) *Bedrock {
+	b.model = model
+	return b
+}
+
+func (b *Bedrock) WithTemperature(temperature float32) *Bedrock {
+	b.temperature = temperature
+	return b
+}
+
+func (b *Bedrock) WithMaxTokens(maxTokens int) *Bedrock {
+	b.maxTokens = maxTokens
+	return b
+}
+
+func (b *Bedrock) WithTopP(topP float32) *Bedrock {
+	b.topP = &topP
+	return b
+}
+
+func (b *Bedrock) WithStop(stop []string) *Bedrock {
+	b.stop = stop
+	return b
+}
+
+func (b *Bedrock) WithStream(callbackFn StreamCallbackFn) *Bedrock {
+	b.streamCallbackFn = callbackFn
+	return b
+}
+
+func (b *Bedrock) WithUsageCallback(callback UsageCallback) *Bedrock {
+	b.usageCallback = callback
+	return b
+}
+
+func (b *Bedrock) WithCache(cache *cache.Cache) *Bedrock {
+	b.cache = cache
+	return b
+}
+
+// WithToolChoice sets the tool choice: nil disables tools, "auto" lets the model decide,
+// any other value forces the model to call the named function.
+func (b *Bedrock) WithToolChoice(toolChoice *string) *Bedrock {
+	b.toolChoice = toolChoice
+	return b
+}
+
+func (b *Bedrock) WithTools(tools ...Tool) *Bedrock {
+	for _, tool := range tools {
+		fn, err := function.NewFromTool(tool)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		b.functions[tool.Name()] = *fn
+	}
+
+	return b
+}
+
+func (b *Bedrock) BindFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...function.ParameterOption,
+) error {
+	f, err := function.New(fn, name, description, functionParameterOptions...)
+	if err != nil {
+		return err
+	}
+
+	b.functions[name] = *f
+
+	return nil
+}
+
+func (b *Bedrock) getClient(ctx context.Context) (*bedrockruntime.Client, error) {
+	if b.client != nil {
+		return b.client, nil
+	}
+
+	var optFns []func(*config.LoadOptions) error
+	if b.region != "" {
+		optFns = append(optFns, config.WithRegion(b.region))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	b.client = bedrockruntime.NewFromConfig(cfg)
+
+	return b.client, nil
+}
+
+func (b *Bedrock) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
+	messages := t.UserQuery()
+	cacheQuery := strings.Join(messages, "\n")
+	cacheResult, err := b.cache.Get(ctx, cacheQuery)
+	if err != nil {
+		return cacheResult, err
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(strings.Join(cacheResult.Answer, "\n")),
+	))
+
+	return cacheResult, nil
+}
+
+func (b *Bedrock) setCache(ctx context.Context, t *thread.Thread, cacheResult *cache.Result) error {
+	lastMessage := t.LastMessage()
+
+	if lastMessage.Role != thread.RoleAssistant || len(lastMessage.Contents) == 0 {
+		return nil
+	}
+
+	contents := make([]string, 0)
+	for _, content := range lastMessage.Contents {
+		if content.Type == thread.ContentTypeText {
+			contents = append(contents, content.Data.(string))
+		} else {
+			contents = make([]string, 0)
+			break
+		}
+	}
+
+	err := b.cache.Set(ctx, cacheResult.Embedding, strings.Join(contents, "\n"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (b *Bedrock) Generate(ctx context.Context, t *thread.Thread) error {
+	if t == nil {
+		return nil
+	}
+
+	var err error
+	var cacheResult *cache.Result
+	if b.cache != nil {
+		cacheResult, err = b.getCache(ctx, t)
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, cache.ErrCacheMiss) {
+			return fmt.Errorf("%w: %w", ErrBedrockChat, err)
+		}
+	}
+
+	client, err := b.getClient(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBedrockChat, err)
+	}
+
+	generation, err := b.startObserveGeneration(ctx, t)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBedrockChat, err)
+	}
+
+	nMessageBeforeGeneration := len(t.Messages)
+
+	if b.streamCallbackFn != nil {
+		err = b.stream(ctx, client, t)
+	} else {
+		err = b.generate(ctx, client, t)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = b.stopObserveGeneration(ctx, generation, t.Messages[nMessageBeforeGeneration:])
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBedrockChat, err)
+	}
+
+	if b.cache != nil {
+		err = b.setCache(ctx, t, cacheResult)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrBedrockChat, err)
+		}
+	}
+
+	return nil
+}
+
+func (b *Bedrock) generate(ctx context.Context, client *bedrockruntime.Client, t *thread.Thread) error {
+	output, err := client.Converse(ctx, b.buildConverseInput(t))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBedrockChat, err)
+	}
+
+	if b.reportsUsage(ctx) && output.Usage != nil {
+		b.setUsageMetadata(ctx, output.Usage)
+	}
+
+	message, ok := output.Output.(*types.ConverseOutputMemberMessage)
+	if !ok {
+		return fmt.Errorf("%w: no message returned", ErrBedrockChat)
+	}
+
+	var text string
+	var toolCalls []thread.ToolCallData
+	for _, contentBlock := range message.Value.Content {
+		switch block := contentBlock.(type) {
+		case *types.ContentBlockMemberText:
+			text += block.Value
+		case *types.ContentBlockMemberToolUse:
+			arguments, errMarshal := block.Value.Input.MarshalSmithyDocument()
+			if errMarshal != nil {
+				return fmt.Errorf("%w: %w", ErrBedrockChat, errMarshal)
+			}
+
+			toolCalls = append(toolCalls, thread.ToolCallData{
+				ID:        aws.ToString(block.Value.ToolUseId),
+				Name:      aws.ToString(block.Value.Name),
+				Arguments: string(arguments),
+			})
+		}
+	}
+
+	t.AddMessages(b.responseToMessages(ctx, text, toolCalls)...)
+
+	return nil
+}
+
+//nolint:gocognit
+func (b *Bedrock) stream(ctx context.Context, client *bedrockruntime.Client, t *thread.Thread) error {
+	output, err := client.ConverseStream(ctx, b.buildConverseStreamInput(t))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBedrockChat, err)
+	}
+
+	eventStream := output.GetStream()
+	defer eventStream.Close()
+
+	var text string
+	var toolCalls []thread.ToolCallData
+	// tool use inputs are streamed as partial JSON strings indexed by content block
+	toolCallIndexes := make(map[int32]int)
+
+	for event := range eventStream.Events() {
+		switch e := event.(type) {
+		case *types.ConverseStreamOutputMemberContentBlockStart:
+			toolUse, ok := e.Value.Start.(*types.ContentBlockStartMemberToolUse)
+			if !ok {
+				continue
+			}
+
+			toolCallIndexes[aws.ToInt32(e.Value.ContentBlockIndex)] = len(toolCalls)
+			toolCalls = append(toolCalls, thread.ToolCallData{
+				ID:   aws.ToString(toolUse.Value.ToolUseId),
+				Name: aws.ToString(toolUse.Value.Name),
+			})
+		case *types.ConverseStreamOutputMemberContentBlockDelta:
+			switch delta := e.Value.Delta.(type) {
+			case *types.ContentBlockDeltaMemberText:
+				text += delta.Value
+				b.streamCallbackFn(delta.Value)
+			case *types.ContentBlockDeltaMemberToolUse:
+				index, ok := toolCallIndexes[aws.ToInt32(e.Value.ContentBlockIndex)]
+				if ok {
+					toolCalls[index].Arguments += aws.ToString(delta.Value.Input)
+				}
+			}
+		case *types.ConverseStreamOutputMemberMetadata:
+			if b.reportsUsage(ctx) && e.Value.Usage != nil {
+				b.setUsageMetadata(ctx, e.Value.Usage)
+			}
+		case *types.ConverseStreamOutputMemberMessageStop:
+			b.streamCallbackFn(EOS)
+		}
+	}
+
+	err = eventStream.Err()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBedrockChat, err)
+	}
+
+	t.AddMessages(b.responseToMessages(ctx, text, toolCalls)...)
+
+	return nil
+}
+
+func (b *Bedrock) responseToMessages(ctx context.Context, text string, toolCalls []thread.ToolCallData) []*thread.Message {
+	if len(toolCalls) == 0 {
+		return []*thread.Message{
+			thread.NewAssistantMessage().AddContent(
+				thread.NewTextContent(text),
+			),
+		}
+	}
+
+	messages := []*thread.Message{
+		thread.NewAssistantMessage().AddContent(
+			thread.NewToolCallContent(toolCalls),
+		),
+	}
+
+	return append(messages, b.callTools(ctx, toolCalls)...)
+}
+
+func (b *Bedrock) callTool(toolCall thread.ToolCallData) (any, string, error) {
+	fn, ok := b.functions[toolCall.Name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown function %s", toolCall.Name)
+	}
+
+	return fn.CallValue(toolCall.Arguments)
+}
+
+func (b *Bedrock) callTools(ctx context.Context, toolCalls []thread.ToolCallData) []*thread.Message {
+	if len(b.functions) == 0 || len(toolCalls) == 0 {
+		return nil
+	}
+
+	var messages []*thread.Message
+	for _, toolCall := range toolCalls {
+		// skip pending tool calls if the generation has been cancelled
+		err := ctx.Err()
+		var value any
+		result := ""
+		if err == nil {
+			value, result, err = b.callTool(toolCall)
+		}
+		if err != nil {
+			result = fmt.Sprintf("error: %s", err)
+		}
+
+		messages = append(messages, thread.NewToolMessage().AddContent(
+			thread.NewToolResponseContent(
+				thread.ToolResponseData{
+					ID:     toolCall.ID,
+					Name:   toolCall.Name,
+					Result: result,
+					Value:  value,
+					Err:    err,
+				},
+			),
+		))
+	}
+
+	return messages
+}
+
+// reportsUsage reports whether the usage of the request is passed to the usage callback
+// or to the usage reporter of the context.
+func (b *Bedrock) reportsUsage(ctx context.Context) bool {
+	return b.usageCallback != nil || usagereport.Enabled(ctx)
+}
+
+func (b *Bedrock) setUsageMetadata(ctx context.Context, u *types.TokenUsage) {
+	usageMetadata := lingoosetypes.Meta{
+		"PromptTokens":     int(aws.ToInt32(u.InputTokens)),
+		"CompletionTokens": int(aws.ToInt32(u.OutputTokens)),
+		"TotalTokens":      int(aws.ToInt32(u.TotalTokens)),
+	}
+
+	usagereport.Report(ctx, usageMetadata)
+	if b.usageCallback != nil {
+		b.usageCallback(usageMetadata)
+	}
+}
+
+func (b *Bedrock) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
+	return llmobserver.StartObserveGeneration(
+		ctx,
+		b.name,
+		b.model,
+		lingoosetypes.M{
+			"maxTokens":   b.maxTokens,
+			"temperature": b.temperature,
+		},
+		t,
+	)
+}
+
+func (b *Bedrock) stopObserveGeneration(
+	ctx context.Context,
+	generation *observer.Generation,
+	messages []*thread.Message,
+) error {
+	return llmobserver.StopObserveGeneration(
+		ctx,
+		generation,
+		messages,
+	)
+}
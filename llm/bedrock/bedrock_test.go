@@ -0,0 +1,192 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+
+	"github.com/henomis/lingoose/thread"
+	lingoosetypes "github.com/henomis/lingoose/types"
+)
+
+func newTestBedrock(t *testing.T, handler http.HandlerFunc) *Bedrock {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := bedrockruntime.New(bedrockruntime.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		Credentials:      aws.AnonymousCredentials{},
+		RetryMaxAttempts: 1,
+	})
+
+	return New().WithClient(client)
+}
+
+func TestThreadToMessages(t *testing.T) {
+	th := thread.New().AddMessages(
+		thread.NewSystemMessage().AddContent(thread.NewTextContent("be kind")),
+		thread.NewUserMessage().AddContent(thread.NewTextContent("weather in Rome?")),
+		thread.NewAssistantMessage().AddContent(thread.NewToolCallContent([]thread.ToolCallData{
+			{ID: "t1", Name: "weather", Arguments: `{"city":"Rome"}`},
+		})),
+		thread.NewToolMessage().AddContent(thread.NewToolResponseContent(thread.ToolResponseData{
+			ID: "t1", Name: "weather", Result: "sunny",
+		})),
+		thread.NewUserMessage().AddContent(thread.NewTextContent("and tomorrow?")),
+	)
+
+	system, messages := threadToMessages(th)
+
+	if len(system) != 1 || system[0].(*types.SystemContentBlockMemberText).Value != "be kind" {
+		t.Fatalf("unexpected system blocks %+v", system)
+	}
+
+	// the tool response and the following user message must be merged
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 alternating messages, got %d", len(messages))
+	}
+	if messages[1].Role != types.ConversationRoleAssistant || messages[2].Role != types.ConversationRoleUser {
+		t.Fatalf("unexpected roles %s %s", messages[1].Role, messages[2].Role)
+	}
+
+	toolUse := messages[1].Content[0].(*types.ContentBlockMemberToolUse)
+	if aws.ToString(toolUse.Value.ToolUseId) != "t1" || aws.ToString(toolUse.Value.Name) != "weather" {
+		t.Fatalf("unexpected tool use %+v", toolUse.Value)
+	}
+
+	if len(messages[2].Content) != 2 {
+		t.Fatalf("expected the tool result and the user text, got %d blocks", len(messages[2].Content))
+	}
+	toolResult := messages[2].Content[0].(*types.ContentBlockMemberToolResult)
+	if aws.ToString(toolResult.Value.ToolUseId) != "t1" {
+		t.Fatalf("unexpected tool result %+v", toolResult.Value)
+	}
+}
+
+func TestBuildConverseInputToolChoice(t *testing.T) {
+	b := New()
+	err := b.BindFunction(func(string) string { return "" }, "weather", "get the weather")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+
+	if input := b.buildConverseInput(th); input.ToolConfig != nil {
+		t.Fatal("expected tools to be disabled without a tool choice")
+	}
+
+	auto := "auto"
+	input := b.WithToolChoice(&auto).buildConverseInput(th)
+	if _, ok := input.ToolConfig.ToolChoice.(*types.ToolChoiceMemberAuto); !ok || len(input.ToolConfig.Tools) != 1 {
+		t.Fatalf("unexpected tool config %+v", input.ToolConfig)
+	}
+
+	name := "weather"
+	input = b.WithToolChoice(&name).buildConverseInput(th)
+	choice, ok := input.ToolConfig.ToolChoice.(*types.ToolChoiceMemberTool)
+	if !ok || aws.ToString(choice.Value.Name) != "weather" {
+		t.Fatalf("unexpected tool choice %+v", input.ToolConfig.ToolChoice)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	var req map[string]any
+	b := newTestBedrock(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/model/"+ModelClaude3Haiku+"/converse" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[{"text":"Hello "},{"text":"there"}]}},` +
+			`"stopReason":"end_turn","usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5},"metrics":{"latencyMs":1}}`))
+	})
+
+	var usage lingoosetypes.Meta
+	b.WithUsageCallback(func(meta lingoosetypes.Meta) { usage = meta })
+
+	th := thread.New().AddMessages(
+		thread.NewSystemMessage().AddContent(thread.NewTextContent("be kind")),
+		thread.NewUserMessage().AddContent(thread.NewTextContent("hi")),
+	)
+	err := b.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := req["system"]; !ok {
+		t.Fatalf("expected the system prompt in the request, got %v", req)
+	}
+	if got := th.LastMessage().Contents[0].AsString(); got != "Hello there" {
+		t.Fatalf("unexpected answer %q", got)
+	}
+	if usage["TotalTokens"] != 5 {
+		t.Fatalf("unexpected usage %v", usage)
+	}
+}
+
+func TestGenerateTools(t *testing.T) {
+	b := newTestBedrock(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[` +
+			`{"toolUse":{"toolUseId":"t1","name":"weather","input":{"city":"Rome"}}}]}},` +
+			`"stopReason":"tool_use","usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5},"metrics":{"latencyMs":1}}`))
+	})
+
+	type weatherInput struct {
+		City string `json:"city"`
+	}
+
+	auto := "auto"
+	b.WithToolChoice(&auto)
+	err := b.BindFunction(func(input weatherInput) string { return "sunny in " + input.City }, "weather", "get the weather")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("weather in Rome?")))
+	err = b.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(th.Messages) != 3 {
+		t.Fatalf("expected the tool call and its response, got %d messages", len(th.Messages))
+	}
+	toolCalls := th.Messages[1].Contents[0].Data.([]thread.ToolCallData)
+	if toolCalls[0].ID != "t1" || toolCalls[0].Name != "weather" {
+		t.Fatalf("unexpected tool call %+v", toolCalls[0])
+	}
+	response := th.Messages[2].Contents[0].Data.(thread.ToolResponseData)
+	if response.ID != "t1" || !strings.Contains(response.Result, "sunny in Rome") {
+		t.Fatalf("unexpected tool response %+v", response)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	b := newTestBedrock(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-Errortype", "ThrottlingException")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message":"too many requests"}`))
+	})
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := b.WithModel(ModelClaude3Sonnet).Generate(context.Background(), th)
+
+	var throttling *types.ThrottlingException
+	if err == nil || !errors.As(err, &throttling) {
+		t.Fatalf("expected a throttling error, got %v", err)
+	}
+}
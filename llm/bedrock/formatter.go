@@ -0,0 +1,223 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func (b *Bedrock) buildConverseInput(t *thread.Thread) *bedrockruntime.ConverseInput {
+	system, messages := threadToMessages(t)
+
+	input := &bedrockruntime.ConverseInput{
+		ModelId:  aws.String(b.model),
+		Messages: messages,
+		System:   system,
+		InferenceConfig: &types.InferenceConfiguration{
+			MaxTokens:     aws.Int32(int32(b.maxTokens)),
+			Temperature:   aws.Float32(b.temperature),
+			TopP:          b.topP,
+			StopSequences: b.stop,
+		},
+	}
+
+	if len(b.functions) > 0 && b.toolChoice != nil {
+		input.ToolConfig = b.getToolConfig()
+	}
+
+	return input
+}
+
+func (b *Bedrock) buildConverseStreamInput(t *thread.Thread) *bedrockruntime.ConverseStreamInput {
+	input := b.buildConverseInput(t)
+
+	return &bedrockruntime.ConverseStreamInput{
+		ModelId:         input.ModelId,
+		Messages:        input.Messages,
+		System:          input.System,
+		InferenceConfig: input.InferenceConfig,
+		ToolConfig:      input.ToolConfig,
+	}
+}
+
+func (b *Bedrock) getToolConfig() *types.ToolConfiguration {
+	tools := make([]types.Tool, 0, len(b.functions))
+	for _, function := range b.functions {
+		tools = append(tools, &types.ToolMemberToolSpec{
+			Value: types.ToolSpecification{
+				Name:        aws.String(function.Name),
+				Description: aws.String(function.Description),
+				InputSchema: &types.ToolInputSchemaMemberJson{
+					Value: document.NewLazyDocument(function.Parameters),
+				},
+			},
+		})
+	}
+
+	var toolChoice types.ToolChoice = &types.ToolChoiceMemberAuto{}
+	if *b.toolChoice != "auto" {
+		toolChoice = &types.ToolChoiceMemberTool{
+			Value: types.SpecificToolChoice{Name: b.toolChoice},
+		}
+	}
+
+	return &types.ToolConfiguration{
+		Tools:      tools,
+		ToolChoice: toolChoice,
+	}
+}
+
+// threadToMessages splits the system prompt from the conversation. Bedrock requires
+// alternating roles, so consecutive messages with the same role are merged and tool
+// responses are sent as user messages.
+func threadToMessages(t *thread.Thread) ([]types.SystemContentBlock, []types.Message) {
+	var system []types.SystemContentBlock
+	var messages []types.Message
+
+	for _, m := range t.Messages {
+		if m.Role == thread.RoleSystem {
+			for _, c := range m.Contents {
+				if text, ok := c.Data.(string); ok && c.Type == thread.ContentTypeText {
+					system = append(system, &types.SystemContentBlockMemberText{Value: text})
+				}
+			}
+			continue
+		}
+
+		role := types.ConversationRoleUser
+		if m.Role == thread.RoleAssistant {
+			role = types.ConversationRoleAssistant
+		}
+
+		contentBlocks := contentsToContentBlocks(m.Contents)
+		if len(contentBlocks) == 0 {
+			continue
+		}
+
+		if len(messages) > 0 && messages[len(messages)-1].Role == role {
+			messages[len(messages)-1].Content = append(messages[len(messages)-1].Content, contentBlocks...)
+			continue
+		}
+
+		messages = append(messages, types.Message{
+			Role:    role,
+			Content: contentBlocks,
+		})
+	}
+
+	return system, messages
+}
+
+func contentsToContentBlocks(contents []*thread.Content) []types.ContentBlock {
+	var contentBlocks []types.ContentBlock
+
+	for _, c := range contents {
+		switch data := c.Data.(type) {
+		case string:
+			if c.Type == thread.ContentTypeText {
+				contentBlocks = append(contentBlocks, &types.ContentBlockMemberText{Value: data})
+				continue
+			}
+
+			if c.Type != thread.ContentTypeImage {
+				continue
+			}
+
+			imageData, format, err := getImageData(data)
+			if err != nil {
+				continue
+			}
+
+			contentBlocks = append(contentBlocks, &types.ContentBlockMemberImage{
+				Value: types.ImageBlock{
+					Format: format,
+					Source: &types.ImageSourceMemberBytes{Value: imageData},
+				},
+			})
+		case []thread.ToolCallData:
+			for _, toolCallData := range data {
+				var input map[string]any
+				_ = json.Unmarshal([]byte(toolCallData.Arguments), &input)
+
+				contentBlocks = append(contentBlocks, &types.ContentBlockMemberToolUse{
+					Value: types.ToolUseBlock{
+						ToolUseId: aws.String(toolCallData.ID),
+						Name:      aws.String(toolCallData.Name),
+						Input:     document.NewLazyDocument(input),
+					},
+				})
+			}
+		case thread.ToolResponseData:
+			contentBlocks = append(contentBlocks, &types.ContentBlockMemberToolResult{
+				Value: types.ToolResultBlock{
+					ToolUseId: aws.String(data.ID),
+					Content:   toolResultContentBlocks(data),
+				},
+			})
+		}
+	}
+
+	return contentBlocks
+}
+
+// toolResultContentBlocks sends the images returned by the tools as image blocks, the
+// other results as text.
+func toolResultContentBlocks(data thread.ToolResponseData) []types.ToolResultContentBlock {
+	content, ok := data.Value.(*thread.Content)
+	if !ok || content == nil || content.Type != thread.ContentTypeImage {
+		return []types.ToolResultContentBlock{
+			&types.ToolResultContentBlockMemberText{Value: data.Result},
+		}
+	}
+
+	imageData, format, err := getImageData(content.AsString())
+	if err != nil {
+		return []types.ToolResultContentBlock{
+			&types.ToolResultContentBlockMemberText{Value: data.Result},
+		}
+	}
+
+	return []types.ToolResultContentBlock{
+		&types.ToolResultContentBlockMemberImage{
+			Value: types.ImageBlock{
+				Format: format,
+				Source: &types.ImageSourceMemberBytes{Value: imageData},
+			},
+		},
+	}
+}
+
+func getImageData(imageURL string) ([]byte, types.ImageFormat, error) {
+	var imageData []byte
+	var err error
+
+	if strings.HasPrefix(imageURL, "http://") || strings.HasPrefix(imageURL, "https://") {
+		//nolint:gosec
+		resp, fetchErr := http.Get(imageURL)
+		if fetchErr != nil {
+			return nil, "", fetchErr
+		}
+		defer resp.Body.Close()
+
+		imageData, err = io.ReadAll(resp.Body)
+	} else {
+		imageData, err = os.ReadFile(imageURL)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Detect image type
+	mimeType := http.DetectContentType(imageData)
+
+	return imageData, types.ImageFormat(strings.TrimPrefix(mimeType, "image/")), nil
+}
@@ -0,0 +1,293 @@
+// Package bestofn generates several candidate answers and keeps the one a cross-encoder
+// reranker scores as the most relevant to the question.
+package bestofn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/transformer"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultN = 3
+
+	// MetadataScore is the answer metadata key holding the reranker score.
+	MetadataScore = "bestofn_score"
+	// MetadataCandidate is the reranked document metadata key holding the candidate index.
+	MetadataCandidate = "bestofn_candidate"
+)
+
+var (
+	ErrBestOfN = errors.New("best of n error")
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// MultiLLM is an LLM generating several answers in a single request, such as openai.OpenAI.
+type MultiLLM interface {
+	GenerateN(ctx context.Context, t *thread.Thread, n int) ([]*thread.Message, error)
+}
+
+// Selector returns the index of the best answer to the query and its score.
+type Selector interface {
+	Select(ctx context.Context, query string, answers []*thread.Message) (int, float64, error)
+}
+
+// Reranker is a cross-encoder scoring documents against a query, such as
+// transformer.CohereRerank or transformer.VoyageRerank.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []document.Document) ([]document.Document, error)
+}
+
+// BestOfN is an LLM generating n candidates, with a sampling temperature above zero,
+// and answering with the best one. The discarded candidates are kept as alternative
+// branches of the thread.
+type BestOfN struct {
+	llm      LLM
+	reranker Reranker
+	selector Selector
+	n        int
+	scoreKey string
+}
+
+// New returns a BestOfN generating the candidates with llm, which must be safe for
+// concurrent use. LLMs implementing MultiLLM generate all the candidates in a single
+// request. The reranker can be nil when a selector is set.
+func New(llm LLM, reranker Reranker) *BestOfN {
+	return &BestOfN{
+		llm:      llm,
+		reranker: reranker,
+		n:        defaultN,
+	}
+}
+
+func (b *BestOfN) WithN(n int) *BestOfN {
+	b.n = n
+	return b
+}
+
+// WithScoreKey sets the metadata key holding the score in the documents returned by
+// the reranker. By default the reranked documents are expected sorted by relevance.
+func (b *BestOfN) WithScoreKey(scoreKey string) *BestOfN {
+	b.scoreKey = scoreKey
+	return b
+}
+
+// WithSelector selects the best candidate with selector in place of the reranker, e.g.
+// with openai.LogProbSelector to keep the answer the model is most confident about.
+func (b *BestOfN) WithSelector(selector Selector) *BestOfN {
+	b.selector = selector
+	return b
+}
+
+type candidate struct {
+	messages []*thread.Message
+	err      error
+}
+
+// Generate generates the candidates and appends the best one to the thread. The query
+// used to score them is the last user message, which includes the retrieved context
+// when the thread is built by a RAG assistant.
+func (b *BestOfN) Generate(ctx context.Context, t *thread.Thread) error {
+	nMessagesBeforeGeneration := len(t.Messages)
+
+	candidates, err := b.generateCandidates(ctx, t)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBestOfN, err)
+	}
+
+	var documents []document.Document
+	var errs []error
+	for i, c := range candidates {
+		if c.err != nil {
+			errs = append(errs, c.err)
+			continue
+		}
+
+		answer := answerText(c.messages)
+		if answer == "" {
+			continue
+		}
+
+		documents = append(documents, document.Document{
+			Content:  answer,
+			Metadata: types.Meta{MetadataCandidate: i},
+		})
+	}
+
+	if len(documents) == 0 {
+		if len(errs) > 0 {
+			return fmt.Errorf("%w: %w", ErrBestOfN, errors.Join(errs...))
+		}
+		// no textual answer (e.g. tool calls): keep the first candidate. Tools are not
+		// called by GenerateN, so the answer is generated again.
+		if _, ok := b.llm.(MultiLLM); ok {
+			return b.llm.Generate(ctx, t)
+		}
+		t.AddMessages(candidates[0].messages...)
+		return nil
+	}
+
+	best, score, err := b.selectBest(ctx, strings.Join(t.UserQuery(), "\n"), documents, candidates)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBestOfN, err)
+	}
+
+	for i, c := range candidates {
+		if i == best || c.err != nil {
+			continue
+		}
+		t.Branches = append(t.Branches, &thread.Branch{
+			Index:    nMessagesBeforeGeneration,
+			Messages: c.messages,
+		})
+	}
+
+	t.AddMessages(candidates[best].messages...)
+	t.LastMessage().AddMetadata(MetadataScore, score)
+
+	return nil
+}
+
+// generateCandidates generates the candidates with a single request when the LLM
+// supports it, otherwise with n concurrent generations.
+func (b *BestOfN) generateCandidates(ctx context.Context, t *thread.Thread) ([]candidate, error) {
+	if multiLLM, ok := b.llm.(MultiLLM); ok {
+		answers, err := multiLLM.GenerateN(ctx, t, b.n)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates := make([]candidate, len(answers))
+		for i, answer := range answers {
+			candidates[i] = candidate{messages: []*thread.Message{answer}}
+		}
+
+		return candidates, nil
+	}
+
+	nMessagesBeforeGeneration := len(t.Messages)
+	candidates := make([]candidate, b.n)
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			candidateThread := &thread.Thread{
+				Messages: append([]*thread.Message{}, t.Messages...),
+			}
+			err := b.llm.Generate(ctx, candidateThread)
+			candidates[i] = candidate{
+				messages: candidateThread.Messages[nMessagesBeforeGeneration:],
+				err:      err,
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return candidates, nil
+}
+
+// selectBest returns the index of the best candidate, the documents holding the text of
+// the answers.
+func (b *BestOfN) selectBest(
+	ctx context.Context,
+	query string,
+	documents []document.Document,
+	candidates []candidate,
+) (int, float64, error) {
+	if b.selector == nil {
+		return b.rerank(ctx, query, documents)
+	}
+
+	answers := make([]*thread.Message, len(documents))
+	for i, document := range documents {
+		messages := candidates[document.Metadata[MetadataCandidate].(int)].messages
+		answers[i] = messages[len(messages)-1]
+	}
+
+	selected, score, err := b.selector.Select(ctx, query, answers)
+	if err != nil {
+		return 0, 0, err
+	}
+	if selected < 0 || selected >= len(documents) {
+		return 0, 0, errors.New("invalid selected candidate")
+	}
+
+	return documents[selected].Metadata[MetadataCandidate].(int), score, nil
+}
+
+func (b *BestOfN) rerank(ctx context.Context, query string, documents []document.Document) (int, float64, error) {
+	if len(documents) == 1 {
+		return documents[0].Metadata[MetadataCandidate].(int), 0, nil
+	}
+
+	reranked, err := b.reranker.Rerank(ctx, query, documents)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(reranked) == 0 {
+		return 0, 0, errors.New("no reranked candidates")
+	}
+
+	top := reranked[0]
+	if b.scoreKey != "" {
+		for _, document := range reranked[1:] {
+			if score(document, b.scoreKey) > score(top, b.scoreKey) {
+				top = document
+			}
+		}
+	}
+
+	best, ok := top.Metadata[MetadataCandidate].(int)
+	if !ok {
+		return 0, 0, errors.New("invalid reranked candidate")
+	}
+
+	for _, scoreKey := range []string{
+		b.scoreKey,
+		transformer.CohereRerankScoreMetdataKey,
+		transformer.VoyageRerankScoreMetdataKey,
+	} {
+		if value, isFloat := top.Metadata[scoreKey].(float64); isFloat {
+			return best, value, nil
+		}
+	}
+
+	return best, 0, nil
+}
+
+func score(document document.Document, scoreKey string) float64 {
+	value, _ := document.Metadata[scoreKey].(float64)
+	return value
+}
+
+func answerText(messages []*thread.Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+
+	answer := messages[len(messages)-1]
+	if answer.Role != thread.RoleAssistant {
+		return ""
+	}
+
+	var text string
+	for _, content := range answer.Contents {
+		if content.Type == thread.ContentTypeText {
+			text += content.AsString()
+		}
+	}
+
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,115 @@
+package bestofn
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/thread"
+)
+
+type fakeLLM struct {
+	mu      sync.Mutex
+	answers []string
+}
+
+func (f *fakeLLM) Generate(_ context.Context, t *thread.Thread) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	answer := f.answers[0]
+	f.answers = f.answers[1:]
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent(answer)))
+	return nil
+}
+
+// lengthReranker prefers the longest documents.
+type lengthReranker struct{}
+
+func (lengthReranker) Rerank(_ context.Context, _ string, documents []document.Document) ([]document.Document, error) {
+	reranked := append([]document.Document{}, documents...)
+	for i := range reranked {
+		reranked[i].Metadata["score"] = float64(len(reranked[i].Content))
+	}
+	sort.Slice(reranked, func(i, j int) bool {
+		return len(reranked[i].Content) < len(reranked[j].Content)
+	})
+	return reranked, nil
+}
+
+func TestBestOfN_Generate(t *testing.T) {
+	llm := &fakeLLM{answers: []string{"Rome.", "The capital of Italy is Rome.", "Rome, I think."}}
+	conversation := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("What is the capital of Italy?")),
+	)
+
+	err := New(llm, lengthReranker{}).WithN(3).WithScoreKey("score").Generate(context.Background(), conversation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conversation.Messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(conversation.Messages))
+	}
+	answer := conversation.LastMessage()
+	if answer.Contents[0].AsString() != "The capital of Italy is Rome." {
+		t.Fatalf("unexpected answer %q", answer.Contents[0].AsString())
+	}
+	if answer.Metadata[MetadataScore] != float64(29) {
+		t.Fatalf("unexpected score %v", answer.Metadata[MetadataScore])
+	}
+	if len(conversation.BranchesAt(1)) != 2 {
+		t.Fatalf("expected 2 alternative branches, got %d", len(conversation.BranchesAt(1)))
+	}
+}
+
+type fakeMultiLLM struct {
+	fakeLLM
+	n int
+}
+
+func (f *fakeMultiLLM) GenerateN(_ context.Context, _ *thread.Thread, n int) ([]*thread.Message, error) {
+	f.n = n
+	answers := make([]*thread.Message, 0, n)
+	for _, answer := range f.answers[:n] {
+		answers = append(answers, thread.NewAssistantMessage().AddContent(thread.NewTextContent(answer)))
+	}
+	return answers, nil
+}
+
+// shortestSelector prefers the shortest answer.
+type shortestSelector struct{}
+
+func (shortestSelector) Select(_ context.Context, _ string, answers []*thread.Message) (int, float64, error) {
+	best := 0
+	for i, answer := range answers {
+		if len(answer.Contents[0].AsString()) < len(answers[best].Contents[0].AsString()) {
+			best = i
+		}
+	}
+	return best, 1, nil
+}
+
+func TestBestOfN_GenerateN(t *testing.T) {
+	llm := &fakeMultiLLM{fakeLLM: fakeLLM{answers: []string{"The capital of Italy is Rome.", "Rome.", "Rome, I think."}}}
+	conversation := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("What is the capital of Italy?")),
+	)
+
+	err := New(llm, nil).WithN(3).WithSelector(shortestSelector{}).Generate(context.Background(), conversation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if llm.n != 3 {
+		t.Fatalf("expected a single request for 3 answers, got n=%d", llm.n)
+	}
+	if answer := conversation.LastMessage().Contents[0].AsString(); answer != "Rome." {
+		t.Fatalf("unexpected answer %q", answer)
+	}
+	if len(conversation.BranchesAt(1)) != 2 {
+		t.Fatalf("expected 2 alternative branches, got %d", len(conversation.BranchesAt(1)))
+	}
+}
@@ -0,0 +1,207 @@
+// Package broadcast fans out a streamed generation to many subscribers, e.g. a UI
+// websocket, a logger and a TTS synthesizer, each with its own buffer so that a slow
+// consumer doesn't hold back the others.
+package broadcast
+
+import (
+	"sync"
+)
+
+const (
+	DefaultBufferSize = 64
+)
+
+// SlowConsumerPolicy is what happens when the buffer of a subscriber is full.
+type SlowConsumerPolicy int
+
+const (
+	// Block waits for the subscriber, slowing down the stream and the other subscribers.
+	Block SlowConsumerPolicy = iota
+	// DropNewest discards the chunk being published.
+	DropNewest
+	// DropOldest discards the oldest buffered chunk to make room for the new one.
+	DropOldest
+	// Disconnect unsubscribes the subscriber, closing its channel.
+	Disconnect
+)
+
+// Broadcaster publishes the chunks of a stream to its subscribers. Its Publish method
+// can be used as the stream callback of the LLMs, e.g.
+// openai.New().WithStream(true, broadcaster.Publish).
+type Broadcaster[T any] struct {
+	mu          sync.Mutex
+	subscribers map[*Subscriber[T]]struct{}
+	closed      bool
+}
+
+func New[T any]() *Broadcaster[T] {
+	return &Broadcaster[T]{
+		subscribers: make(map[*Subscriber[T]]struct{}),
+	}
+}
+
+// Subscriber receives the published chunks from its channel, closed when the stream ends
+// or the subscriber is unsubscribed.
+type Subscriber[T any] struct {
+	broadcaster *Broadcaster[T]
+	policy      SlowConsumerPolicy
+	ch          chan T
+	done        chan struct{}
+	doneOnce    sync.Once
+	mu          sync.Mutex
+	closed      bool
+	dropped     int
+}
+
+// Subscribe adds a subscriber buffering up to bufferSize chunks, DefaultBufferSize if
+// zero, and handling a full buffer with the policy. Subscribing to an ended stream
+// returns a closed subscriber.
+func (b *Broadcaster[T]) Subscribe(bufferSize int, policy SlowConsumerPolicy) *Subscriber[T] {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+
+	s := &Subscriber[T]{
+		broadcaster: b,
+		policy:      policy,
+		ch:          make(chan T, bufferSize),
+		done:        make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		s.close()
+		return s
+	}
+
+	b.subscribers[s] = struct{}{}
+
+	return s
+}
+
+// Publish sends the chunk to every subscriber. Publishing to an ended stream is a no-op.
+func (b *Broadcaster[T]) Publish(chunk T) {
+	for _, s := range b.snapshot() {
+		if !s.send(chunk) {
+			b.remove(s)
+		}
+	}
+}
+
+// Close ends the stream, closing the channels of the subscribers once they have
+// received the buffered chunks.
+func (b *Broadcaster[T]) Close() {
+	b.mu.Lock()
+	subscribers := b.subscribers
+	b.subscribers = make(map[*Subscriber[T]]struct{})
+	b.closed = true
+	b.mu.Unlock()
+
+	for s := range subscribers {
+		s.close()
+	}
+}
+
+func (b *Broadcaster[T]) snapshot() []*Subscriber[T] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscribers := make([]*Subscriber[T], 0, len(b.subscribers))
+	for s := range b.subscribers {
+		subscribers = append(subscribers, s)
+	}
+
+	return subscribers
+}
+
+func (b *Broadcaster[T]) remove(s *Subscriber[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers, s)
+}
+
+// C returns the channel of the chunks.
+func (s *Subscriber[T]) C() <-chan T {
+	return s.ch
+}
+
+// Dropped returns the number of chunks discarded because the buffer was full.
+func (s *Subscriber[T]) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+// Unsubscribe stops receiving chunks and closes the channel.
+func (s *Subscriber[T]) Unsubscribe() {
+	s.broadcaster.remove(s)
+	s.close()
+}
+
+// send delivers the chunk according to the policy, it returns false if the subscriber
+// is closed or has been disconnected.
+func (s *Subscriber[T]) send(chunk T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	select {
+	case s.ch <- chunk:
+		return true
+	default:
+	}
+
+	switch s.policy {
+	case Block:
+		select {
+		case s.ch <- chunk:
+			return true
+		case <-s.done:
+			return false
+		}
+	case DropNewest:
+		s.dropped++
+	case DropOldest:
+		select {
+		case <-s.ch:
+			s.dropped++
+		default:
+		}
+		select {
+		case s.ch <- chunk:
+		default:
+			s.dropped++
+		}
+	case Disconnect:
+		s.doneOnce.Do(func() { close(s.done) })
+		s.closeLocked()
+		return false
+	}
+
+	return true
+}
+
+// close closes the done channel first, releasing a publisher blocked on a full buffer,
+// then the chunks channel.
+func (s *Subscriber[T]) close() {
+	s.doneOnce.Do(func() { close(s.done) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeLocked()
+}
+
+func (s *Subscriber[T]) closeLocked() {
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
@@ -0,0 +1,55 @@
+package broadcast
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBroadcaster(t *testing.T) {
+	b := New[string]()
+
+	blocking := b.Subscribe(1, Block)
+	dropping := b.Subscribe(2, DropOldest)
+	disconnected := b.Subscribe(1, Disconnect)
+
+	var wg sync.WaitGroup
+	var received strings.Builder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for chunk := range blocking.C() {
+			received.WriteString(chunk)
+		}
+	}()
+
+	for _, chunk := range []string{"a", "b", "c", "d"} {
+		b.Publish(chunk)
+	}
+	b.Close()
+	wg.Wait()
+
+	if got := received.String(); got != "abcd" {
+		t.Errorf("blocking subscriber received %q, want abcd", got)
+	}
+
+	var kept []string
+	for chunk := range dropping.C() {
+		kept = append(kept, chunk)
+	}
+	if strings.Join(kept, "") != "cd" || dropping.Dropped() != 2 {
+		t.Errorf("dropping subscriber kept %v, dropped %d", kept, dropping.Dropped())
+	}
+
+	var first []string
+	for chunk := range disconnected.C() {
+		first = append(first, chunk)
+	}
+	if len(first) != 1 || first[0] != "a" {
+		t.Errorf("disconnected subscriber received %v, want [a]", first)
+	}
+
+	if _, ok := <-b.Subscribe(0, Block).C(); ok {
+		t.Errorf("expected a closed subscriber after Close")
+	}
+}
@@ -3,9 +3,12 @@ package cache
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/henomis/lingoose/index"
 	indexoption "github.com/henomis/lingoose/index/option"
+	"github.com/henomis/lingoose/logger"
+	"github.com/henomis/lingoose/secret"
 	"github.com/henomis/lingoose/types"
 )
 
@@ -15,6 +18,7 @@ const (
 	defaultTopK            = 1
 	defaultScoreThreshold  = 0.9
 	cacheAnswerMetadataKey = "cache-answer"
+	cacheTenantMetadataKey = "cache-tenant"
 )
 
 type Cache struct {
@@ -22,6 +26,7 @@ type Cache struct {
 	index          *index.Index
 	topK           int
 	scoreThreshold float64
+	logger         *slog.Logger
 }
 
 type Result struct {
@@ -48,34 +53,51 @@ func (c *Cache) WithScoreThreshold(scoreThreshold float64) *Cache {
 	return c
 }
 
+// WithLogger sets the logger of the cache hits and misses, logged at debug level.
+func (c *Cache) WithLogger(logger *slog.Logger) *Cache {
+	c.logger = logger
+	return c
+}
+
 func (c *Cache) Get(ctx context.Context, query string) (*Result, error) {
-	embedding, err := c.embedder.Embed(ctx, []string{query})
+	embedding, err := index.EmbedQuery(ctx, c.embedder, query)
 	if err != nil {
 		return nil, err
 	}
 
-	results, err := c.index.Search(ctx, embedding[0], indexoption.WithTopK(c.topK))
+	results, err := c.index.Search(ctx, embedding, indexoption.WithTopK(c.topK))
 	if err != nil {
 		return nil, err
 	}
 
-	answers, cacheHit := c.extractResults(results)
+	answers, cacheHit := c.extractResults(results, secret.Tenant(ctx))
+	logger.Or(c.logger).DebugContext(ctx, "llm cache lookup",
+		slog.Bool("hit", cacheHit),
+		slog.Int("query_chars", len(query)),
+	)
 	if cacheHit {
 		return &Result{
 			Answer:    answers,
-			Embedding: embedding[0],
+			Embedding: embedding,
 		}, nil
 	}
 
-	return &Result{Embedding: embedding[0]}, ErrCacheMiss
+	return &Result{Embedding: embedding}, ErrCacheMiss
 }
 
+// Set stores the answer. Answers stored for a tenant set with secret.WithTenant are only
+// returned to the same tenant.
 func (c *Cache) Set(ctx context.Context, embedding []float64, answer string) error {
+	metadata := types.Meta{
+		cacheAnswerMetadataKey: answer,
+	}
+	if tenant := secret.Tenant(ctx); tenant != "" {
+		metadata[cacheTenantMetadataKey] = tenant
+	}
+
 	return c.index.Add(ctx, &index.Data{
-		Values: embedding,
-		Metadata: types.Meta{
-			cacheAnswerMetadataKey: answer,
-		},
+		Values:   embedding,
+		Metadata: metadata,
 	})
 }
 
@@ -83,10 +105,14 @@ func (c *Cache) Clear(ctx context.Context) error {
 	return c.index.Drop(ctx)
 }
 
-func (c *Cache) extractResults(results index.SearchResults) ([]string, bool) {
+func (c *Cache) extractResults(results index.SearchResults, tenant string) ([]string, bool) {
 	var output []string
 
 	for _, result := range results {
+		if resultTenant, _ := result.Metadata[cacheTenantMetadataKey].(string); resultTenant != tenant {
+			continue
+		}
+
 		if result.Score > c.scoreThreshold {
 			answer, ok := result.Metadata[cacheAnswerMetadataKey]
 			if !ok {
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+	"github.com/henomis/lingoose/secret"
+)
+
+type constantEmbedder struct{}
+
+func (constantEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	embeddings := make([]embedder.Embedding, len(texts))
+	for i := range texts {
+		embeddings[i] = embedder.Embedding{1, 0, 0}
+	}
+	return embeddings, nil
+}
+
+func TestCacheTenants(t *testing.T) {
+	c := New(index.New(jsondb.New(), constantEmbedder{}))
+
+	acme := secret.WithTenant(context.Background(), "acme")
+	result, err := c.Get(acme, "question")
+	if !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected a cache miss, got %v", err)
+	}
+	if err = c.Set(acme, result.Embedding, "acme answer"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = c.Get(acme, "question")
+	if err != nil || len(result.Answer) != 1 || result.Answer[0] != "acme answer" {
+		t.Fatalf("expected the acme answer, got %v %v", result, err)
+	}
+
+	for _, ctx := range []context.Context{secret.WithTenant(context.Background(), "globex"), context.Background()} {
+		_, err = c.Get(ctx, "question")
+		if !errors.Is(err, ErrCacheMiss) {
+			t.Fatalf("answer of another tenant returned: %v", err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cohere
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/henomis/cohere-go/model"
+	"github.com/henomis/restclientgo"
+)
+
+const (
+	defaultEndpoint       = "https://api.cohere.ai/v1"
+	jsonContentType       = "application/json"
+	streamJSONContentType = "application/stream+json"
+)
+
+// chatRequest is used in place of the cohere-go one to send documents as plain string
+// maps, as expected by the grounded generation.
+type chatRequest struct {
+	Message         string                `json:"message"`
+	Model           Model                 `json:"model,omitempty"`
+	Stream          bool                  `json:"stream,omitempty"`
+	Preamble        string                `json:"preamble,omitempty"`
+	ChatHistory     []model.ChatMessage   `json:"chat_history,omitempty"`
+	Documents       []map[string]string   `json:"documents,omitempty"`
+	CitationQuality model.CitationQuality `json:"citation_quality,omitempty"`
+	Temperature     *float64              `json:"temperature,omitempty"`
+	MaxTokens       *int                  `json:"max_tokens,omitempty"`
+	StopSequences   []string              `json:"stop_sequences,omitempty"`
+}
+
+func (r *chatRequest) Path() (string, error) {
+	return "/chat", nil
+}
+
+func (r *chatRequest) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *chatRequest) ContentType() string {
+	return jsonContentType
+}
+
+type chatResponse struct {
+	HTTPStatusCode    int                  `json:"-"`
+	RawBody           []byte               `json:"-"`
+	Headers           restclientgo.Headers `json:"-"`
+	acceptContentType string
+	streamCallbackFn  restclientgo.StreamCallback
+	chatResult
+}
+
+type chatResult struct {
+	Text         string              `json:"text"`
+	GenerationID string              `json:"generation_id"`
+	Citations    []Citation          `json:"citations"`
+	Documents    []map[string]string `json:"documents"`
+}
+
+// chatStreamEvent is a line of the streamed response.
+type chatStreamEvent struct {
+	EventType    model.EventType    `json:"event_type"`
+	Text         string             `json:"text"`
+	Citations    []Citation         `json:"citations"`
+	FinishReason model.FinishReason `json:"finish_reason"`
+	Response     *chatResult        `json:"response"`
+}
+
+func (r *chatResponse) SetAcceptContentType(contentType string) {
+	r.acceptContentType = contentType
+}
+
+func (r *chatResponse) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(&r.chatResult)
+}
+
+func (r *chatResponse) SetBody(body io.Reader) error {
+	r.RawBody, _ = io.ReadAll(body)
+	return nil
+}
+
+func (r *chatResponse) AcceptContentType() string {
+	if r.acceptContentType != "" {
+		return r.acceptContentType
+	}
+	return jsonContentType
+}
+
+func (r *chatResponse) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *chatResponse) SetHeaders(headers restclientgo.Headers) error {
+	r.Headers = headers
+	return nil
+}
+
+func (r *chatResponse) SetStreamCallback(fn restclientgo.StreamCallback) {
+	r.streamCallbackFn = fn
+}
+
+func (r *chatResponse) StreamCallback() restclientgo.StreamCallback {
+	return r.streamCallbackFn
+}
@@ -2,18 +2,26 @@ package cohere
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	coherego "github.com/henomis/cohere-go"
 	"github.com/henomis/cohere-go/model"
 	"github.com/henomis/cohere-go/request"
 	"github.com/henomis/cohere-go/response"
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/index"
 
 	"github.com/henomis/lingoose/legacy/chat"
 	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/llm/httperror"
 	llmobserver "github.com/henomis/lingoose/llm/observer"
 	"github.com/henomis/lingoose/observer"
 	"github.com/henomis/lingoose/thread"
@@ -31,6 +39,25 @@ const (
 	ModelCommandNightly      Model = model.ModelCommandNightly
 	ModelCommandLight        Model = model.ModelCommandLight
 	ModelCommandLightNightly Model = model.ModelCommandLightNightly
+	ModelCommandR            Model = "command-r"
+	ModelCommandRPlus        Model = "command-r-plus"
+)
+
+const (
+	// MetadataCitations is the assistant message metadata key holding the []Citation
+	// grounding the answer in the documents passed with WithDocuments.
+	MetadataCitations = "citations"
+)
+
+// Citation is a span of the answer (Start and End are character offsets in the text)
+// supported by the documents with the given IDs.
+type Citation = model.Citation
+
+type CitationQuality = model.CitationQuality
+
+const (
+	CitationQualityAccurate CitationQuality = model.CitationQualityAccurate
+	CitationQualityFast     CitationQuality = model.CitationQualityFast
 )
 
 const (
@@ -43,6 +70,9 @@ type StreamCallbackFn func(string)
 
 type Cohere struct {
 	client           *coherego.Client
+	restClient       *restclientgo.RestClient
+	documents        []map[string]string
+	citationQuality  CitationQuality
 	model            Model
 	temperature      float64
 	maxTokens        int
@@ -66,8 +96,11 @@ func NewCompletion() *Cohere {
 }
 
 func New() *Cohere {
+	apiKey := os.Getenv("COHERE_API_KEY")
+
 	return &Cohere{
-		client:      coherego.New(os.Getenv("COHERE_API_KEY")),
+		client:      coherego.New(apiKey),
+		restClient:  newRestClient(apiKey),
 		model:       DefaultModel,
 		temperature: DefaultTemperature,
 		maxTokens:   DefaultMaxTokens,
@@ -75,6 +108,15 @@ func New() *Cohere {
 	}
 }
 
+func newRestClient(apiKey string) *restclientgo.RestClient {
+	return restclientgo.New(defaultEndpoint).WithRequestModifier(
+		func(req *http.Request) *http.Request {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+			return req
+		},
+	)
+}
+
 // WithModel sets the model to use for the LLM
 func (c *Cohere) WithModel(model Model) *Cohere {
 	c.model = model
@@ -96,6 +138,41 @@ func (c *Cohere) WithMaxTokens(maxTokens int) *Cohere {
 // WithAPIKey sets the API key to use for the LLM
 func (c *Cohere) WithAPIKey(apiKey string) *Cohere {
 	c.client = coherego.New(apiKey)
+	c.restClient = newRestClient(apiKey)
+	return c
+}
+
+// WithHTTPClient sets the http client used by Generate
+func (c *Cohere) WithHTTPClient(httpClient *http.Client) *Cohere {
+	c.restClient.SetHTTPClient(httpClient)
+	return c
+}
+
+// WithDocuments sets the documents used by Command-R models to ground the answer. The
+// citations are set in the MetadataCitations metadata of the assistant message; they
+// reference the documents by the index.DefaultKeyID metadata, or by their position
+// ("0", "1", ...) when missing. Document metadata with string values is sent as well,
+// e.g. a title or an URL.
+func (c *Cohere) WithDocuments(documents ...document.Document) *Cohere {
+	c.documents = make([]map[string]string, len(documents))
+	for i, doc := range documents {
+		cohereDocument := map[string]string{
+			"id":      strconv.Itoa(i),
+			"snippet": doc.Content,
+		}
+		for key, value := range doc.Metadata {
+			if valueAsString, ok := value.(string); ok && key != index.DefaultKeyContent {
+				cohereDocument[key] = valueAsString
+			}
+		}
+		c.documents[i] = cohereDocument
+	}
+	return c
+}
+
+// WithCitationQuality sets the citation quality, accurate by default.
+func (c *Cohere) WithCitationQuality(citationQuality CitationQuality) *Cohere {
+	c.citationQuality = citationQuality
 	return c
 }
 
@@ -248,51 +325,72 @@ func (c *Cohere) Generate(ctx context.Context, t *thread.Thread) error {
 	return nil
 }
 
-func (c *Cohere) generate(ctx context.Context, t *thread.Thread, chatRequest *request.Chat) error {
-	var response response.Chat
-
-	err := c.client.Chat(
-		ctx,
-		chatRequest,
-		&response,
-	)
+func (c *Cohere) generate(ctx context.Context, t *thread.Thread, chatRequest *chatRequest) error {
+	resp := &chatResponse{}
+	err := c.restClient.Post(ctx, chatRequest, resp)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrCohereChat, err)
+	} else if resp.HTTPStatusCode >= http.StatusBadRequest {
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return fmt.Errorf("%w: %w", ErrCohereChat, httpErr)
 	}
 
-	t.AddMessage(thread.NewAssistantMessage().AddContent(
-		thread.NewTextContent(response.Text),
-	))
+	t.AddMessage(newAssistantMessage(resp.Text, resp.Citations))
 
 	return nil
 }
 
-func (c *Cohere) stream(ctx context.Context, t *thread.Thread, chatRequest *request.Chat) error {
-	chatResponse := &response.Chat{}
+func (c *Cohere) stream(ctx context.Context, t *thread.Thread, chatRequest *chatRequest) error {
 	var assistantMessage string
+	var citations []Citation
+
+	resp := &chatResponse{}
+	resp.SetAcceptContentType(streamJSONContentType)
+	resp.SetStreamCallback(
+		func(data []byte) error {
+			var event chatStreamEvent
+			err := json.Unmarshal(data, &event)
+			if err != nil {
+				return nil //nolint:nilerr
+			}
 
-	err := c.client.ChatStream(
-		ctx,
-		chatRequest,
-		chatResponse,
-		func(r *response.Chat) {
-			if r.Text != "" {
-				c.streamCallbackFn(r.Text)
-				assistantMessage += r.Text
+			switch event.EventType {
+			case model.EventTypeTextGeneration:
+				c.streamCallbackFn(event.Text)
+				assistantMessage += event.Text
+			case model.EventTypeCitationGeneration:
+				citations = append(citations, event.Citations...)
 			}
+
+			return nil
 		},
 	)
+
+	chatRequest.Stream = true
+	err := c.restClient.Post(ctx, chatRequest, resp)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrCohereChat, err)
+	} else if resp.HTTPStatusCode >= http.StatusBadRequest {
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return fmt.Errorf("%w: %w", ErrCohereChat, httpErr)
 	}
 
-	t.AddMessage(thread.NewAssistantMessage().AddContent(
-		thread.NewTextContent(assistantMessage),
-	))
+	t.AddMessage(newAssistantMessage(assistantMessage, citations))
 
 	return nil
 }
 
+func newAssistantMessage(text string, citations []Citation) *thread.Message {
+	message := thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(text),
+	)
+	if len(citations) > 0 {
+		message.AddMetadata(MetadataCitations, citations)
+	}
+
+	return message
+}
+
 func (c *Cohere) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
 	return llmobserver.StartObserveGeneration(
 		ctx,
@@ -2,7 +2,6 @@ package cohere
 
 import (
 	"github.com/henomis/cohere-go/model"
-	"github.com/henomis/cohere-go/request"
 	"github.com/henomis/lingoose/thread"
 )
 
@@ -12,13 +11,18 @@ var threadRoleToCohereRole = map[thread.Role]model.ChatMessageRole{
 	thread.RoleAssistant: model.ChatMessageRoleChatbot,
 }
 
-func (c *Cohere) buildChatCompletionRequest(t *thread.Thread) *request.Chat {
+func (c *Cohere) buildChatCompletionRequest(t *thread.Thread) *chatRequest {
 	message, history := threadToChatMessages(t)
 
-	return &request.Chat{
-		Model:       c.model,
-		ChatHistory: history,
-		Message:     message,
+	return &chatRequest{
+		Model:           c.model,
+		ChatHistory:     history,
+		Message:         message,
+		Documents:       c.documents,
+		CitationQuality: c.citationQuality,
+		Temperature:     &c.temperature,
+		MaxTokens:       &c.maxTokens,
+		StopSequences:   c.stop,
 	}
 }
 
@@ -0,0 +1,30 @@
+// Package deepseek provides the DeepSeek LLM through its OpenAI compatible API.
+package deepseek
+
+import (
+	"os"
+
+	"github.com/henomis/lingoose/llm/openai"
+)
+
+const (
+	defaultEndpoint = "https://api.deepseek.com"
+)
+
+const (
+	ModelDeepSeekChat     openai.Model = "deepseek-chat"
+	ModelDeepSeekReasoner openai.Model = "deepseek-reasoner"
+)
+
+// New creates a DeepSeek LLM using the deepseek-chat model. The API key is read from the
+// DEEPSEEK_API_KEY environment variable. With ModelDeepSeekReasoner the reasoning is
+// added to the assistant message as a thread.ContentTypeThinking content.
+func New() *openai.OpenAI {
+	openaillm := openai.New().
+		WithBaseURL(defaultEndpoint).
+		WithAPIKey(os.Getenv("DEEPSEEK_API_KEY")).
+		WithModel(ModelDeepSeekChat)
+	openaillm.Name = "deepseek"
+
+	return openaillm
+}
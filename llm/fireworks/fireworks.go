@@ -0,0 +1,61 @@
+// Package fireworks provides the Fireworks AI LLM through its OpenAI compatible API.
+package fireworks
+
+import (
+	"os"
+
+	"github.com/henomis/lingoose/llm/openai"
+)
+
+const (
+	defaultEndpoint   = "https://api.fireworks.ai/inference/v1"
+	defaultMaxRetries = 3
+)
+
+const (
+	ModelLlama3Dot370BInstruct  openai.Model = "accounts/fireworks/models/llama-v3p3-70b-instruct"
+	ModelLlama3Dot1405BInstruct openai.Model = "accounts/fireworks/models/llama-v3p1-405b-instruct"
+	ModelLlama3Dot18BInstruct   openai.Model = "accounts/fireworks/models/llama-v3p1-8b-instruct"
+	ModelQwen2Dot572BInstruct   openai.Model = "accounts/fireworks/models/qwen2p5-72b-instruct"
+	ModelMixtral8x22BInstruct   openai.Model = "accounts/fireworks/models/mixtral-8x22b-instruct"
+	ModelDeepSeekV3             openai.Model = "accounts/fireworks/models/deepseek-v3"
+)
+
+type Fireworks struct {
+	*openai.OpenAI
+}
+
+// New creates a Fireworks AI LLM using the Llama 3.3 70B instruct model. The API key is
+// read from the FIREWORKS_API_KEY environment variable. Rate limited requests are
+// retried three times, see WithMaxRetries.
+func New() *Fireworks {
+	openaillm := openai.New().
+		WithBaseURL(defaultEndpoint).
+		WithAPIKey(os.Getenv("FIREWORKS_API_KEY")).
+		WithModel(ModelLlama3Dot370BInstruct).
+		WithMaxRetries(defaultMaxRetries)
+	openaillm.Name = "fireworks"
+
+	return &Fireworks{
+		OpenAI: openaillm,
+	}
+}
+
+// WithGrammar constrains the output to the given GBNF grammar. The constraint is enforced
+// while decoding, so the answer always matches the grammar.
+func (f *Fireworks) WithGrammar(grammar string) *Fireworks {
+	f.OpenAI.WithBodyField("response_format", map[string]any{
+		"type":    "grammar",
+		"grammar": grammar,
+	})
+	return f
+}
+
+// WithJSONSchema constrains the output to a JSON object valid against the given JSON schema.
+func (f *Fireworks) WithJSONSchema(schema map[string]any) *Fireworks {
+	f.OpenAI.WithBodyField("response_format", map[string]any{
+		"type":   "json_object",
+		"schema": schema,
+	})
+	return f
+}
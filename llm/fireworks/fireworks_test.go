@@ -0,0 +1,69 @@
+package fireworks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestGenerateConstrainedOutput(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"city": map[string]any{"type": "string"}},
+	}
+
+	tests := []struct {
+		name           string
+		configure      func(*Fireworks) *Fireworks
+		responseFormat map[string]any
+	}{
+		{
+			name:           "grammar",
+			configure:      func(f *Fireworks) *Fireworks { return f.WithGrammar(`root ::= "yes" | "no"`) },
+			responseFormat: map[string]any{"type": "grammar", "grammar": `root ::= "yes" | "no"`},
+		},
+		{
+			name:           "json schema",
+			configure:      func(f *Fireworks) *Fireworks { return f.WithJSONSchema(schema) },
+			responseFormat: map[string]any{"type": "json_object", "schema": schema},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"yes"}}]}`))
+			}))
+			defer server.Close()
+
+			llm := tt.configure(New())
+			llm.WithBaseURL(server.URL)
+
+			th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("ok?")))
+			err := llm.Generate(context.Background(), th)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the JSON round trip turns the schema values into generic maps
+			var expected map[string]any
+			raw, _ := json.Marshal(tt.responseFormat)
+			_ = json.Unmarshal(raw, &expected)
+
+			if !reflect.DeepEqual(body["response_format"], expected) {
+				t.Fatalf("expected response_format %v, got %v", expected, body["response_format"])
+			}
+			if body["model"] != string(ModelLlama3Dot370BInstruct) {
+				t.Fatalf("unexpected model %v", body["model"])
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Package function binds Go functions to LLM tool definitions described by a JSON schema.
+package function
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/henomis/lingoose/jsonrepair"
+	"github.com/henomis/lingoose/recovery"
+	"github.com/henomis/lingoose/thread"
+)
+
+type Function struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+	Fn          interface{}
+}
+
+type ParameterOption func(map[string]interface{}) error
+
+type Tool interface {
+	Description() string
+	Name() string
+	Fn() any
+}
+
+// New returns a function whose parameters schema is extracted from the single struct
+// argument of fn.
+func New(
+	fn interface{},
+	name string,
+	description string,
+	parameterOptions ...ParameterOption,
+) (*Function, error) {
+	parameter, err := extractFunctionParameter(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range parameterOptions {
+		err = option(parameter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Function{
+		Name:        name,
+		Description: description,
+		Parameters:  parameter,
+		Fn:          fn,
+	}, nil
+}
+
+// NewFromTool returns a function bound to the tool.
+func NewFromTool(tool Tool) (*Function, error) {
+	return New(tool.Fn(), tool.Name(), tool.Description())
+}
+
+// Call calls the function with the JSON encoded arguments and returns the JSON encoded result.
+func (f *Function) Call(argumentsAsJSON string) (string, error) {
+	_, result, err := f.CallValue(argumentsAsJSON)
+	return result, err
+}
+
+// CallValue calls the function with the JSON encoded arguments and returns the value
+// returned by the function along with its serialization for the LLM, see SerializeResult.
+// A panic of the function is returned as a *recovery.PanicError.
+func (f *Function) CallValue(argumentsAsJSON string) (any, string, error) {
+	var value any
+	var result string
+	err := recovery.Do("tool "+f.Name, func() error {
+		var err error
+		value, result, err = f.callValue(argumentsAsJSON)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return value, result, nil
+}
+
+func (f *Function) callValue(argumentsAsJSON string) (any, string, error) {
+	value, err := callFnWithArgumentAsJSON(f.Fn, argumentsAsJSON)
+	if err != nil || reflect.TypeOf(f.Fn).NumOut() == 0 {
+		return nil, "", err
+	}
+
+	result, err := SerializeResult(value)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return value, result, nil
+}
+
+// SerializeResult converts the value returned by a function into the text sent to the
+// LLM. Text contents are sent as they are, image contents as a JSON object holding the
+// image URL and any other value is JSON encoded.
+func SerializeResult(value any) (string, error) {
+	if content, ok := value.(*thread.Content); ok && content != nil {
+		switch content.Type {
+		case thread.ContentTypeText:
+			return content.AsString(), nil
+		case thread.ContentTypeImage:
+			value = map[string]string{"type": string(content.Type), "url": content.AsString()}
+		default:
+			value = map[string]any{"type": string(content.Type), "data": content.Data}
+		}
+	}
+
+	var resultBytes bytes.Buffer
+	enc := json.NewEncoder(&resultBytes)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(value)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling result: %w", err)
+	}
+
+	return strings.TrimSpace(resultBytes.String()), nil
+}
+
+func extractFunctionParameter(f interface{}) (map[string]interface{}, error) {
+	// Get the type of the input function
+	fnType := reflect.TypeOf(f)
+
+	if fnType.Kind() != reflect.Func {
+		return nil, errors.New("input must be a function")
+	}
+
+	// Check that the function only has one argument
+	if fnType.NumIn() != 1 {
+		return nil, errors.New("function must have exactly one argument")
+	}
+
+	// Check that the argument is of type struct
+	argType := fnType.In(0)
+	if argType.Kind() != reflect.Struct {
+		return nil, errors.New("argument must be of type struct")
+	}
+
+	// Create a new instance of the argument type
+	argValue := reflect.New(argType).Elem().Interface()
+
+	parameter, err := structAsJSONSchema(argValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return parameter, nil
+}
+
+func structAsJSONSchema(v interface{}) (map[string]interface{}, error) {
+	r := new(jsonschema.Reflector)
+	r.DoNotReference = true
+	schema := r.Reflect(v)
+
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonSchema map[string]interface{}
+	err = json.Unmarshal(b, &jsonSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(jsonSchema, "$schema")
+
+	return jsonSchema, nil
+}
+
+func callFnWithArgumentAsJSON(fn interface{}, argumentAsJSON string) (any, error) {
+	// Get the type of the input function
+	fnType := reflect.TypeOf(fn)
+
+	// Check that the function has one argument
+	if fnType.NumIn() != 1 {
+		return nil, fmt.Errorf("function must have one argument")
+	}
+
+	// Check that the argument is a struct
+	argType := fnType.In(0)
+	if argType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("argument must be a struct")
+	}
+
+	// Create a slice to hold the function argument
+	args := make([]reflect.Value, 1)
+
+	// Unmarshal the JSON string into an interface{} value, repairing malformed arguments
+	var argValue interface{}
+	err := jsonrepair.Unmarshal([]byte(argumentAsJSON), &argValue)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling argument: %w", err)
+	}
+
+	// Convert the argument value to the correct type
+	argValueReflect := reflect.New(argType).Elem()
+	jsonData, err := json.Marshal(argValue)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling argument: %w", err)
+	}
+	err = json.Unmarshal(jsonData, argValueReflect.Addr().Interface())
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling argument: %w", err)
+	}
+
+	// Add the argument value to the slice
+	args[0] = argValueReflect
+
+	// Call the function with the argument
+	fnValue := reflect.ValueOf(fn)
+	result := fnValue.Call(args)
+
+	if len(result) > 0 {
+		return result[0].Interface(), nil
+	}
+
+	return nil, nil
+}
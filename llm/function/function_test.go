@@ -0,0 +1,110 @@
+package function
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/henomis/lingoose/recovery"
+	"github.com/henomis/lingoose/thread"
+)
+
+type weatherInput struct {
+	City string `json:"city"`
+}
+
+type forecast struct {
+	City        string  `json:"city"`
+	Temperature float64 `json:"temperature"`
+}
+
+func TestFunction_CallValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     any
+		value  any
+		result string
+	}{
+		{
+			name:   "struct",
+			fn:     func(input weatherInput) forecast { return forecast{City: input.City, Temperature: 21.5} },
+			value:  forecast{City: "Rome", Temperature: 21.5},
+			result: `{"city":"Rome","temperature":21.5}`,
+		},
+		{
+			name:   "string",
+			fn:     func(input weatherInput) string { return "sunny in " + input.City },
+			value:  "sunny in Rome",
+			result: `"sunny in Rome"`,
+		},
+		{
+			name:   "no result",
+			fn:     func(weatherInput) {},
+			value:  nil,
+			result: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.fn, "weather", "get the weather")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value, result, err := f.CallValue(`{"city":"Rome"}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != tt.value || result != tt.result {
+				t.Fatalf("got %v %q, want %v %q", value, result, tt.value, tt.result)
+			}
+
+			result, err = f.Call(`{"city":"Rome"}`)
+			if err != nil || result != tt.result {
+				t.Fatalf("Call returned %q %v, want %q", result, err, tt.result)
+			}
+		})
+	}
+}
+
+func TestFunction_CallValuePanic(t *testing.T) {
+	f, err := New(func(input weatherInput) string {
+		var cities map[string]string
+		cities[input.City] = "sunny"
+		return cities[input.City]
+	}, "weather", "get the weather")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = f.CallValue(`{"city":"Rome"}`)
+
+	var panicErr *recovery.PanicError
+	if !errors.As(err, &panicErr) || panicErr.Op != "tool weather" {
+		t.Fatalf("expected a panic error, got %v", err)
+	}
+}
+
+func TestSerializeResult(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"text", thread.NewTextContent("it's <sunny>"), "it's <sunny>"},
+		{"image", thread.NewImageContentFromURL("https://example.com/map.png"), `{"type":"image","url":"https://example.com/map.png"}`},
+		{"map", map[string]int{"a": 1}, `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SerializeResult(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
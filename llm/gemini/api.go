@@ -0,0 +1,178 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+type request struct {
+	model             string
+	stream            bool
+	Contents          []content         `json:"contents"`
+	SystemInstruction *content          `json:"systemInstruction,omitempty"`
+	Tools             []tool            `json:"tools,omitempty"`
+	ToolConfig        *toolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
+}
+
+func (r *request) Path() (string, error) {
+	if r.stream {
+		return "/models/" + r.model + ":streamGenerateContent?alt=sse", nil
+	}
+
+	return "/models/" + r.model + ":generateContent", nil
+}
+
+func (r *request) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *request) ContentType() string {
+	return jsonContentType
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+type part struct {
+	Text             *string           `json:"text,omitempty"`
+	InlineData       *inlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+}
+
+type inlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type functionCall struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+type functionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type tool struct {
+	FunctionDeclarations []functionDeclaration `json:"functionDeclarations"`
+}
+
+type functionDeclaration struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+type toolConfig struct {
+	FunctionCallingConfig functionCallingConfig `json:"functionCallingConfig"`
+}
+
+type functionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type generationConfig struct {
+	Temperature        *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens    int      `json:"maxOutputTokens,omitempty"`
+	StopSequences      []string `json:"stopSequences,omitempty"`
+	ResponseModalities []string `json:"responseModalities,omitempty"`
+}
+
+type response struct {
+	HTTPStatusCode    int             `json:"-"`
+	acceptContentType string          `json:"-"`
+	Candidates        []candidate     `json:"candidates"`
+	UsageMetadata     usageMetadata   `json:"usageMetadata"`
+	Error             *apiError       `json:"error,omitempty"`
+	PromptFeedback    *promptFeedback `json:"promptFeedback,omitempty"`
+	streamCallbackFn  restclientgo.StreamCallback
+	RawBody           []byte               `json:"-"`
+	Headers           restclientgo.Headers `json:"-"`
+}
+
+type candidate struct {
+	Content      content `json:"content"`
+	FinishReason string  `json:"finishReason"`
+	Index        int     `json:"index"`
+}
+
+type usageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+type promptFeedback struct {
+	BlockReason string `json:"blockReason"`
+}
+
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func (r *response) SetAcceptContentType(contentType string) {
+	r.acceptContentType = contentType
+}
+
+func (r *response) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(r)
+}
+
+func (r *response) SetBody(body io.Reader) error {
+	r.RawBody, _ = io.ReadAll(body)
+	return nil
+}
+
+func (r *response) AcceptContentType() string {
+	if r.acceptContentType != "" {
+		return r.acceptContentType
+	}
+	return jsonContentType
+}
+
+func (r *response) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *response) SetHeaders(headers restclientgo.Headers) error {
+	r.Headers = headers
+	return nil
+}
+
+func (r *response) SetStreamCallback(fn restclientgo.StreamCallback) {
+	r.streamCallbackFn = fn
+}
+
+func (r *response) StreamCallback() restclientgo.StreamCallback {
+	return r.streamCallbackFn
+}
+
+func getImageDataAsBase64(c *thread.Content) (string, string, error) {
+	imageData, mimeType, err := c.ImageData(context.Background())
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(imageData), mimeType, nil
+}
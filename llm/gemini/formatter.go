@@ -0,0 +1,226 @@
+package gemini
+
+import (
+	"encoding/json"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+var threadRoleToGeminiRole = map[thread.Role]string{
+	thread.RoleUser:      "user",
+	thread.RoleAssistant: "model",
+	thread.RoleTool:      "function",
+}
+
+// schemaKeys are the OpenAPI schema fields accepted by Gemini function declarations.
+var schemaKeys = map[string]bool{
+	"type":        true,
+	"format":      true,
+	"description": true,
+	"nullable":    true,
+	"enum":        true,
+	"properties":  true,
+	"required":    true,
+	"items":       true,
+}
+
+func (g *Gemini) buildRequest(t *thread.Thread) *request {
+	contents, systemInstruction := threadToContents(t)
+
+	req := &request{
+		model:             g.model,
+		Contents:          contents,
+		SystemInstruction: systemInstruction,
+		GenerationConfig: &generationConfig{
+			Temperature:     &g.temperature,
+			MaxOutputTokens: g.maxTokens,
+			StopSequences:   g.stop,
+		},
+	}
+
+	if g.imageOutput {
+		req.GenerationConfig.ResponseModalities = []string{"TEXT", "IMAGE"}
+	}
+
+	if len(g.functions) > 0 {
+		req.Tools = g.getTools()
+		req.ToolConfig = g.getToolConfig()
+	}
+
+	return req
+}
+
+func (g *Gemini) getTools() []tool {
+	declarations := make([]functionDeclaration, 0, len(g.functions))
+	for _, function := range g.functions {
+		declarations = append(declarations, functionDeclaration{
+			Name:        function.Name,
+			Description: function.Description,
+			Parameters:  cleanSchema(function.Parameters),
+		})
+	}
+
+	return []tool{{FunctionDeclarations: declarations}}
+}
+
+func (g *Gemini) getToolConfig() *toolConfig {
+	if g.toolChoice == nil {
+		return &toolConfig{FunctionCallingConfig: functionCallingConfig{Mode: "NONE"}}
+	}
+
+	if *g.toolChoice == "auto" {
+		return &toolConfig{FunctionCallingConfig: functionCallingConfig{Mode: "AUTO"}}
+	}
+
+	return &toolConfig{
+		FunctionCallingConfig: functionCallingConfig{
+			Mode:                 "ANY",
+			AllowedFunctionNames: []string{*g.toolChoice},
+		},
+	}
+}
+
+// cleanSchema removes the JSON schema fields Gemini does not understand
+// (e.g. additionalProperties) from the function parameters.
+func cleanSchema(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+
+	cleaned := make(map[string]any)
+	for key, value := range schema {
+		if !schemaKeys[key] {
+			continue
+		}
+
+		switch key {
+		case "properties":
+			properties, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			cleanedProperties := make(map[string]any)
+			for name, property := range properties {
+				if propertySchema, isMap := property.(map[string]any); isMap {
+					cleanedProperties[name] = cleanSchema(propertySchema)
+				}
+			}
+			cleaned[key] = cleanedProperties
+		case "items":
+			if itemsSchema, ok := value.(map[string]any); ok {
+				cleaned[key] = cleanSchema(itemsSchema)
+			}
+		default:
+			cleaned[key] = value
+		}
+	}
+
+	return cleaned
+}
+
+//nolint:gocognit
+func threadToContents(t *thread.Thread) ([]content, *content) {
+	var systemInstruction *content
+	var contents []content
+	for _, m := range t.Messages {
+		switch m.Role {
+		case thread.RoleSystem:
+			for _, c := range m.Contents {
+				contentData, ok := c.Data.(string)
+				if !ok {
+					continue
+				}
+
+				if systemInstruction == nil {
+					systemInstruction = &content{}
+				}
+				systemInstruction.Parts = append(systemInstruction.Parts, part{Text: &contentData})
+			}
+		case thread.RoleUser, thread.RoleAssistant, thread.RoleTool:
+			geminiContent := content{
+				Role: threadRoleToGeminiRole[m.Role],
+			}
+			for _, c := range m.Contents {
+				p, ok := contentToPart(c)
+				if !ok {
+					continue
+				}
+				geminiContent.Parts = append(geminiContent.Parts, p...)
+			}
+
+			if len(geminiContent.Parts) == 0 {
+				continue
+			}
+
+			// Gemini expects the responses of parallel function calls in a single turn
+			if n := len(contents); n > 0 && contents[n-1].Role == geminiContent.Role {
+				contents[n-1].Parts = append(contents[n-1].Parts, geminiContent.Parts...)
+				continue
+			}
+
+			contents = append(contents, geminiContent)
+		}
+	}
+
+	return contents, systemInstruction
+}
+
+func contentToPart(c *thread.Content) ([]part, bool) {
+	switch c.Type {
+	case thread.ContentTypeText:
+		text, ok := c.Data.(string)
+		if !ok {
+			return nil, false
+		}
+		return []part{{Text: &text}}, true
+	case thread.ContentTypeImage:
+		imageData, mimeType, err := getImageDataAsBase64(c)
+		if err != nil {
+			return nil, false
+		}
+
+		return []part{{InlineData: &inlineData{MimeType: mimeType, Data: imageData}}}, true
+	case thread.ContentTypeToolCall:
+		toolCalls, ok := c.Data.([]thread.ToolCallData)
+		if !ok {
+			return nil, false
+		}
+
+		parts := make([]part, 0, len(toolCalls))
+		for _, toolCall := range toolCalls {
+			args := make(map[string]any)
+			_ = json.Unmarshal([]byte(toolCall.Arguments), &args)
+			parts = append(parts, part{FunctionCall: &functionCall{Name: toolCall.Name, Args: args}})
+		}
+		return parts, true
+	case thread.ContentTypeToolResponse:
+		toolResponse, ok := c.Data.(thread.ToolResponseData)
+		if !ok {
+			return nil, false
+		}
+
+		return []part{{
+			FunctionResponse: &functionResponse{
+				Name:     toolResponse.Name,
+				Response: toolResultToResponse(toolResponse.Result),
+			},
+		}}, true
+	}
+
+	return nil, false
+}
+
+// toolResultToResponse converts the JSON encoded tool result into the object
+// expected by Gemini, wrapping non-object results.
+func toolResultToResponse(result string) map[string]any {
+	var value any
+	if err := json.Unmarshal([]byte(result), &value); err != nil {
+		value = result
+	}
+
+	if response, ok := value.(map[string]any); ok {
+		return response
+	}
+
+	return map[string]any{"result": value}
+}
@@ -0,0 +1,493 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/llm/function"
+	"github.com/henomis/lingoose/llm/httperror"
+	llmobserver "github.com/henomis/lingoose/llm/observer"
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	eventStreamContentType = "text/event-stream"
+	jsonContentType        = "application/json"
+	defaultEndpoint        = "https://generativelanguage.googleapis.com/v1beta"
+	defaultMaxTokens       = 1024
+	defaultTemperature     = 0.7
+	EOS                    = "\x00"
+)
+
+const (
+	ModelGemini15Pro   = "gemini-1.5-pro"
+	ModelGemini15Flash = "gemini-1.5-flash"
+	ModelGemini10Pro   = "gemini-1.0-pro"
+	defaultModel       = ModelGemini15Flash
+)
+
+var (
+	ErrGeminiChat = fmt.Errorf("gemini chat error")
+)
+
+type StreamCallbackFn func(string)
+
+type Function = function.Function
+
+type Tool = function.Tool
+
+type Gemini struct {
+	model            string
+	temperature      float64
+	maxTokens        int
+	stop             []string
+	restClient       *restclientgo.RestClient
+	streamCallbackFn StreamCallbackFn
+	cache            *cache.Cache
+	functions        map[string]Function
+	toolChoice       *string
+	imageOutput      bool
+	observer         llmobserver.LLMObserver
+	observerTraceID  string
+	name             string
+}
+
+func New() *Gemini {
+	apiKey := os.Getenv("GEMINI_API_KEY")
+
+	return &Gemini{
+		restClient: restclientgo.New(defaultEndpoint).WithRequestModifier(
+			func(req *http.Request) *http.Request {
+				req.Header.Set("x-goog-api-key", apiKey)
+				return req
+			},
+		),
+		model:       defaultModel,
+		temperature: defaultTemperature,
+		maxTokens:   defaultMaxTokens,
+		functions:   make(map[string]Function),
+		name:        "gemini",
+	}
+}
+
+func (g *Gemini) WithAPIKey(apiKey string) *Gemini {
+	g.restClient.SetRequestModifier(
+		func(req *http.Request) *http.Request {
+			req.Header.Set("x-goog-api-key", apiKey)
+			return req
+		},
+	)
+	return g
+}
+
+// WithHTTPClient sets the http client to use for the LLM
+func (g *Gemini) WithHTTPClient(httpClient *http.Client) *Gemini {
+	g.restClient.SetHTTPClient(httpClient)
+	return g
+}
+
+func (g *Gemini) WithModel(model string) *Gemini {
+	g.model = model
+	return g
+}
+
+func (g *Gemini) WithTemperature(temperature float64) *Gemini {
+	g.temperature = temperature
+	return g
+}
+
+func (g *Gemini) WithMaxTokens(maxTokens int) *Gemini {
+	g.maxTokens = maxTokens
+	return g
+}
+
+func (g *Gemini) WithStop(stop []string) *Gemini {
+	g.stop = stop
+	return g
+}
+
+func (g *Gemini) WithStream(callbackFn StreamCallbackFn) *Gemini {
+	g.streamCallbackFn = callbackFn
+	return g
+}
+
+// WithImageOutput lets models supporting image generation, such as
+// gemini-2.0-flash-preview-image-generation, answer with images. The generated images are
+// added to the assistant message as image contents.
+func (g *Gemini) WithImageOutput() *Gemini {
+	g.imageOutput = true
+	return g
+}
+
+func (g *Gemini) WithCache(cache *cache.Cache) *Gemini {
+	g.cache = cache
+	return g
+}
+
+// WithToolChoice sets the function calling mode: nil disables tools, "auto" lets the
+// model decide, any other value forces the call of the named function.
+func (g *Gemini) WithToolChoice(toolChoice *string) *Gemini {
+	g.toolChoice = toolChoice
+	return g
+}
+
+func (g *Gemini) WithTools(tools ...Tool) *Gemini {
+	for _, tool := range tools {
+		fn, err := function.NewFromTool(tool)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		g.functions[tool.Name()] = *fn
+	}
+
+	return g
+}
+
+// WithObserver sets the observer used when the generation context carries none.
+func (g *Gemini) WithObserver(observer llmobserver.LLMObserver, traceID string) *Gemini {
+	g.observer = observer
+	g.observerTraceID = traceID
+	return g
+}
+
+func (g *Gemini) BindFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...function.ParameterOption,
+) error {
+	f, err := function.New(fn, name, description, functionParameterOptions...)
+	if err != nil {
+		return err
+	}
+
+	g.functions[name] = *f
+
+	return nil
+}
+
+func (g *Gemini) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
+	messages := t.UserQuery()
+	cacheQuery := strings.Join(messages, "\n")
+	cacheResult, err := g.cache.Get(ctx, cacheQuery)
+	if err != nil {
+		return cacheResult, err
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(strings.Join(cacheResult.Answer, "\n")),
+	))
+
+	return cacheResult, nil
+}
+
+func (g *Gemini) setCache(ctx context.Context, t *thread.Thread, cacheResult *cache.Result) error {
+	lastMessage := t.LastMessage()
+
+	if lastMessage.Role != thread.RoleAssistant || len(lastMessage.Contents) == 0 {
+		return nil
+	}
+
+	contents := make([]string, 0)
+	for _, content := range lastMessage.Contents {
+		if content.Type == thread.ContentTypeText {
+			contents = append(contents, content.Data.(string))
+		} else {
+			contents = make([]string, 0)
+			break
+		}
+	}
+
+	err := g.cache.Set(ctx, cacheResult.Embedding, strings.Join(contents, "\n"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (g *Gemini) Generate(ctx context.Context, t *thread.Thread) error {
+	if t == nil {
+		return nil
+	}
+
+	var err error
+	var cacheResult *cache.Result
+	if g.cache != nil {
+		cacheResult, err = g.getCache(ctx, t)
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, cache.ErrCacheMiss) {
+			return fmt.Errorf("%w: %w", ErrGeminiChat, err)
+		}
+	}
+
+	ctx = g.contextWithObserver(ctx)
+	req := g.buildRequest(t)
+
+	generation, err := g.startObserveGeneration(ctx, t)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGeminiChat, err)
+	}
+
+	nMessageBeforeGeneration := len(t.Messages)
+
+	if g.streamCallbackFn != nil {
+		err = g.stream(ctx, t, req)
+	} else {
+		err = g.generate(ctx, t, req)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = g.stopObserveGeneration(ctx, generation, t.Messages[nMessageBeforeGeneration:])
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGeminiChat, err)
+	}
+
+	if g.cache != nil {
+		err = g.setCache(ctx, t, cacheResult)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrGeminiChat, err)
+		}
+	}
+
+	return nil
+}
+
+func (g *Gemini) generate(ctx context.Context, t *thread.Thread, req *request) error {
+	var resp response
+
+	err := g.restClient.Post(ctx, req, &resp)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGeminiChat, err)
+	}
+
+	if resp.HTTPStatusCode >= http.StatusBadRequest {
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return fmt.Errorf("%w: %w", ErrGeminiChat, httpErr)
+	}
+
+	if len(resp.Candidates) == 0 {
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			return fmt.Errorf("%w: prompt blocked: %s", ErrGeminiChat, resp.PromptFeedback.BlockReason)
+		}
+		return fmt.Errorf("%w: no candidates returned", ErrGeminiChat)
+	}
+
+	var text string
+	var images []*thread.Content
+	var functionCalls []*functionCall
+	for _, p := range resp.Candidates[0].Content.Parts {
+		if p.Text != nil {
+			text += *p.Text
+		}
+		if image := inlineDataToImage(p.InlineData); image != nil {
+			images = append(images, image)
+		}
+		if p.FunctionCall != nil {
+			functionCalls = append(functionCalls, p.FunctionCall)
+		}
+	}
+
+	t.AddMessages(g.responseToMessages(ctx, text, images, functionCalls)...)
+
+	return nil
+}
+
+func (g *Gemini) stream(ctx context.Context, t *thread.Thread, req *request) error {
+	var resp response
+	var text string
+	var images []*thread.Content
+	var functionCalls []*functionCall
+
+	resp.SetAcceptContentType(eventStreamContentType)
+	resp.SetStreamCallback(
+		func(data []byte) error {
+			dataAsString := string(data)
+			if !strings.HasPrefix(dataAsString, "data: ") {
+				return nil
+			}
+
+			dataAsString = strings.TrimPrefix(dataAsString, "data: ")
+
+			var chunk response
+			_ = json.Unmarshal([]byte(dataAsString), &chunk)
+			if len(chunk.Candidates) == 0 {
+				return nil
+			}
+
+			for _, p := range chunk.Candidates[0].Content.Parts {
+				if p.Text != nil {
+					text += *p.Text
+					g.streamCallbackFn(*p.Text)
+				}
+				if image := inlineDataToImage(p.InlineData); image != nil {
+					images = append(images, image)
+				}
+				if p.FunctionCall != nil {
+					functionCalls = append(functionCalls, p.FunctionCall)
+				}
+			}
+
+			return nil
+		},
+	)
+
+	req.stream = true
+
+	err := g.restClient.Post(ctx, req, &resp)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGeminiChat, err)
+	}
+
+	if resp.HTTPStatusCode >= http.StatusBadRequest {
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return fmt.Errorf("%w: %w", ErrGeminiChat, httpErr)
+	}
+
+	g.streamCallbackFn(EOS)
+
+	t.AddMessages(g.responseToMessages(ctx, text, images, functionCalls)...)
+
+	return nil
+}
+
+func (g *Gemini) responseToMessages(
+	ctx context.Context,
+	text string,
+	images []*thread.Content,
+	functionCalls []*functionCall,
+) []*thread.Message {
+	if len(functionCalls) == 0 {
+		message := thread.NewAssistantMessage()
+		if text != "" || len(images) == 0 {
+			message.AddContent(thread.NewTextContent(text))
+		}
+		for _, image := range images {
+			message.AddContent(image)
+		}
+
+		return []*thread.Message{message}
+	}
+
+	// Gemini does not assign ids to function calls
+	toolCalls := make([]thread.ToolCallData, 0, len(functionCalls))
+	for _, fc := range functionCalls {
+		arguments, _ := json.Marshal(fc.Args)
+		toolCalls = append(toolCalls, thread.ToolCallData{
+			ID:        uuid.New().String(),
+			Name:      fc.Name,
+			Arguments: string(arguments),
+		})
+	}
+
+	messages := []*thread.Message{
+		thread.NewAssistantMessage().AddContent(
+			thread.NewToolCallContent(toolCalls),
+		),
+	}
+
+	return append(messages, g.callTools(ctx, toolCalls)...)
+}
+
+// inlineDataToImage returns the image content of a generated image, nil if the data is
+// not an image.
+func inlineDataToImage(data *inlineData) *thread.Content {
+	if data == nil || !strings.HasPrefix(data.MimeType, "image/") {
+		return nil
+	}
+
+	return thread.NewImageContentFromURL("data:" + data.MimeType + ";base64," + data.Data)
+}
+
+func (g *Gemini) callTool(toolCall thread.ToolCallData) (any, string, error) {
+	fn, ok := g.functions[toolCall.Name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown function %s", toolCall.Name)
+	}
+
+	return fn.CallValue(toolCall.Arguments)
+}
+
+func (g *Gemini) callTools(ctx context.Context, toolCalls []thread.ToolCallData) []*thread.Message {
+	if len(g.functions) == 0 || len(toolCalls) == 0 {
+		return nil
+	}
+
+	var messages []*thread.Message
+	for _, toolCall := range toolCalls {
+		// skip pending tool calls if the generation has been cancelled
+		err := ctx.Err()
+		var value any
+		result := ""
+		if err == nil {
+			value, result, err = g.callTool(toolCall)
+		}
+		if err != nil {
+			result = fmt.Sprintf("error: %s", err)
+		}
+
+		messages = append(messages, thread.NewToolMessage().AddContent(
+			thread.NewToolResponseContent(
+				thread.ToolResponseData{
+					ID:     toolCall.ID,
+					Name:   toolCall.Name,
+					Result: result,
+					Value:  value,
+					Err:    err,
+				},
+			),
+		))
+	}
+
+	return messages
+}
+
+func (g *Gemini) contextWithObserver(ctx context.Context) context.Context {
+	if g.observer == nil || observer.ContextValueObserverInstance(ctx) != nil {
+		return ctx
+	}
+
+	ctx = observer.ContextWithObserverInstance(ctx, g.observer)
+	return observer.ContextWithTraceID(ctx, g.observerTraceID)
+}
+
+func (g *Gemini) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
+	return llmobserver.StartObserveGeneration(
+		ctx,
+		g.name,
+		g.model,
+		types.M{
+			"maxTokens":   g.maxTokens,
+			"temperature": g.temperature,
+		},
+		t,
+	)
+}
+
+func (g *Gemini) stopObserveGeneration(
+	ctx context.Context,
+	generation *observer.Generation,
+	messages []*thread.Message,
+) error {
+	return llmobserver.StopObserveGeneration(
+		ctx,
+		generation,
+		messages,
+	)
+}
@@ -0,0 +1,154 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/thread"
+)
+
+func newTestGemini(t *testing.T, handler http.HandlerFunc) *Gemini {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	g := New().WithAPIKey("key")
+	g.restClient.SetEndpoint(server.URL)
+
+	return g
+}
+
+func TestGenerate(t *testing.T) {
+	var req request
+	g := newTestGemini(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/"+ModelGemini15Pro+":generateContent" || r.Header.Get("x-goog-api-key") != "key" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"message":"not found"}}`))
+			return
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", jsonContentType)
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hello "},{"text":"there"}]}}]}`))
+	})
+	g.WithModel(ModelGemini15Pro)
+
+	th := thread.New().AddMessages(
+		thread.NewSystemMessage().AddContent(thread.NewTextContent("be kind")),
+		thread.NewUserMessage().AddContent(thread.NewTextContent("hi")),
+	)
+	err := g.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.SystemInstruction == nil || *req.SystemInstruction.Parts[0].Text != "be kind" {
+		t.Fatalf("expected the system instruction, got %+v", req.SystemInstruction)
+	}
+	if len(req.Contents) != 1 || req.Contents[0].Role != "user" {
+		t.Fatalf("unexpected contents %+v", req.Contents)
+	}
+	if got := th.LastMessage().Contents[0].AsString(); got != "Hello there" {
+		t.Fatalf("unexpected answer %q", got)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	g := newTestGemini(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"code":429,"message":"quota exceeded"}}`))
+	})
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := g.Generate(context.Background(), th)
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Fatalf("expected the API error, got %v", err)
+	}
+	if httpErr, ok := httperror.As(err); !ok || httpErr.RetryAfter != 7*time.Second {
+		t.Errorf("expected the Retry-After delay, got %v", err)
+	}
+}
+
+func TestGenerateTools(t *testing.T) {
+	var req request
+	g := newTestGemini(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", jsonContentType)
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[` +
+			`{"functionCall":{"name":"weather","args":{"city":"Rome"}}}]}}]}`))
+	})
+
+	type weatherInput struct {
+		City string `json:"city"`
+	}
+
+	auto := "auto"
+	g.WithToolChoice(&auto)
+	err := g.BindFunction(func(input weatherInput) string { return "sunny in " + input.City }, "weather", "get the weather")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("weather in Rome?")))
+	err = g.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(req.Tools) != 1 || req.Tools[0].FunctionDeclarations[0].Name != "weather" ||
+		req.ToolConfig.FunctionCallingConfig.Mode != "AUTO" {
+		t.Fatalf("unexpected tools %+v %+v", req.Tools, req.ToolConfig)
+	}
+
+	if len(th.Messages) != 3 {
+		t.Fatalf("expected the tool call and its response, got %d messages", len(th.Messages))
+	}
+	toolCalls := th.Messages[1].Contents[0].Data.([]thread.ToolCallData)
+	if toolCalls[0].Name != "weather" || toolCalls[0].Arguments != `{"city":"Rome"}` {
+		t.Fatalf("unexpected tool call %+v", toolCalls[0])
+	}
+	response := th.Messages[2].Contents[0].Data.(thread.ToolResponseData)
+	if response.ID != toolCalls[0].ID || response.Result != `"sunny in Rome"` || response.Value != "sunny in Rome" {
+		t.Fatalf("unexpected tool response %+v", response)
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	g := newTestGemini(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":streamGenerateContent") || r.URL.Query().Get("alt") != "sse" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", eventStreamContentType)
+		_, _ = w.Write([]byte(
+			"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Hel\"}]}}]}\n\n" +
+				"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"lo\"}]}}]}\n\n",
+		))
+	})
+
+	var chunks []string
+	g.WithStream(func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := g.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := th.LastMessage().Contents[0].AsString(); got != "Hello" {
+		t.Fatalf("unexpected answer %q", got)
+	}
+	if strings.Join(chunks, "") != "Hello"+EOS {
+		t.Fatalf("unexpected chunks %q", chunks)
+	}
+}
@@ -0,0 +1,151 @@
+package groq
+
+import (
+	"github.com/henomis/lingoose/thread"
+)
+
+var threadRoleToGroqRole = map[thread.Role]string{
+	thread.RoleSystem:    "system",
+	thread.RoleUser:      "user",
+	thread.RoleAssistant: "assistant",
+	thread.RoleTool:      "tool",
+}
+
+func (g *Groq) buildChatCompletionRequest(t *thread.Thread) *request {
+	req := &request{
+		Model:       g.model,
+		Messages:    threadToChatMessages(t),
+		Temperature: g.temperature,
+		MaxTokens:   g.maxTokens,
+		Stop:        g.stop,
+		Seed:        g.seed,
+	}
+
+	if g.jsonMode {
+		req.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+
+	if len(g.functions) > 0 {
+		req.Tools = g.getTools()
+		req.ToolChoice = g.getToolChoice()
+	}
+
+	return req
+}
+
+func (g *Groq) getTools() []tool {
+	tools := make([]tool, 0, len(g.functions))
+	for _, function := range g.functions {
+		tools = append(tools, tool{
+			Type: "function",
+			Function: functionDefinition{
+				Name:        function.Name,
+				Description: function.Description,
+				Parameters:  function.Parameters,
+			},
+		})
+	}
+
+	return tools
+}
+
+func (g *Groq) getToolChoice() any {
+	if g.toolChoice == nil {
+		return "none"
+	}
+
+	if *g.toolChoice == "auto" || *g.toolChoice == "required" {
+		return *g.toolChoice
+	}
+
+	return toolChoice{
+		Type: "function",
+		Function: toolChoiceFunction{
+			Name: *g.toolChoice,
+		},
+	}
+}
+
+//nolint:gocognit
+func threadToChatMessages(t *thread.Thread) []message {
+	var chatMessages []message
+	for _, m := range t.Messages {
+		switch m.Role {
+		case thread.RoleSystem, thread.RoleUser:
+			chatMessages = append(chatMessages, message{
+				Role:    threadRoleToGroqRole[m.Role],
+				Content: contentsToMessageContent(m.Contents),
+			})
+		case thread.RoleAssistant:
+			chatMessage := message{
+				Role:    threadRoleToGroqRole[m.Role],
+				Content: "",
+			}
+			for _, c := range m.Contents {
+				switch data := c.Data.(type) {
+				case string:
+					if c.Type == thread.ContentTypeText {
+						chatMessage.Content = chatMessage.Content.(string) + data
+					}
+				case []thread.ToolCallData:
+					for _, toolCallData := range data {
+						chatMessage.ToolCalls = append(chatMessage.ToolCalls, toolCall{
+							ID:   toolCallData.ID,
+							Type: "function",
+							Function: functionCall{
+								Name:      toolCallData.Name,
+								Arguments: toolCallData.Arguments,
+							},
+						})
+					}
+				}
+			}
+			chatMessages = append(chatMessages, chatMessage)
+		case thread.RoleTool:
+			for _, c := range m.Contents {
+				toolResponseData, ok := c.Data.(thread.ToolResponseData)
+				if !ok {
+					continue
+				}
+
+				chatMessages = append(chatMessages, message{
+					Role:       threadRoleToGroqRole[m.Role],
+					Content:    toolResponseData.Result,
+					ToolCallID: toolResponseData.ID,
+					Name:       toolResponseData.Name,
+				})
+			}
+		}
+	}
+
+	return chatMessages
+}
+
+// contentsToMessageContent returns a plain string for text only messages and a list of
+// content parts when the message contains images.
+func contentsToMessageContent(contents []*thread.Content) any {
+	var text string
+	var parts []contentPart
+	hasImages := false
+	for _, c := range contents {
+		contentData, ok := c.Data.(string)
+		if !ok {
+			continue
+		}
+
+		switch c.Type {
+		case thread.ContentTypeText:
+			text += contentData
+			parts = append(parts, contentPart{Type: "text", Text: contentData})
+		case thread.ContentTypeImage:
+			hasImages = true
+			parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: contentData}})
+		}
+	}
+
+	if hasImages {
+		return parts
+	}
+
+	return text
+}
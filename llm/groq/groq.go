@@ -1,28 +1,611 @@
+// Package groq provides the Groq LLM, a native client of the Groq chat completions API.
 package groq
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
-	"github.com/henomis/lingoose/llm/openai"
-	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/llm/function"
+	"github.com/henomis/lingoose/llm/httperror"
+	llmobserver "github.com/henomis/lingoose/llm/observer"
+	"github.com/henomis/lingoose/llm/retry"
+	"github.com/henomis/lingoose/llm/usagereport"
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/secret"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	eventStreamContentType = "text/event-stream"
+	jsonContentType        = "application/json"
+	defaultEndpoint        = "https://api.groq.com/openai/v1"
+	defaultMaxTokens       = 1024
+	defaultTemperature     = 0.7
+	defaultMaxRetries      = 3
+	defaultRetryDelay      = time.Second
+	EOS                    = "\x00"
 )
 
 const (
-	groqAPIEndpoint = "https://api.groq.com/openai/v1"
+	ModelLlama3Dot1405B = "llama-3.1-405b-reasoning"
+	ModelLlama3Dot170B  = "llama-3.1-70b-versatile"
+	ModelLlama3Dot18B   = "llama-3.1-8b-instant"
+	ModelLlama370B      = "llama3-70b-8192"
+	ModelLlama38B       = "llama3-8b-8192"
+	ModelMixtral8x7B    = "mixtral-8x7b-32768"
+	ModelGemma7B        = "gemma-7b-it"
+	ModelGemma29B       = "gemma2-9b-it"
+	defaultModel        = ModelLlama38B
+)
+
+var (
+	ErrGroqChat = fmt.Errorf("groq chat error")
 )
 
+// APIError is the error returned by the Groq API. It unwraps to the httperror.Error of
+// the response, carrying the status code and the Retry-After delay.
+type APIError struct {
+	Message string
+	Type    string
+	Code    string
+	err     *httperror.Error
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return e.err.Error()
+	}
+
+	return fmt.Sprintf("%d %s: %s", e.err.StatusCode, e.Type, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+type StreamCallbackFn func(string)
+
+type UsageCallback func(types.Meta)
+
+type Function = function.Function
+
+type Tool = function.Tool
+
 type Groq struct {
-	*openai.OpenAI
+	model            string
+	temperature      float64
+	maxTokens        int
+	stop             []string
+	seed             *int
+	jsonMode         bool
+	maxRetries       uint
+	restClient       *restclientgo.RestClient
+	apiKeyFunc       func(ctx context.Context) string
+	httpClient       *http.Client
+	streamCallbackFn StreamCallbackFn
+	usageCallback    UsageCallback
+	cache            *cache.Cache
+	functions        map[string]Function
+	toolChoice       *string
+	name             string
 }
 
+// New creates a Groq LLM using the Llama 3 8B model. The API key is read from the
+// GROQ_API_KEY environment variable. Rate limited requests are retried three times,
+// waiting for the delay suggested by Groq, see WithMaxRetries.
 func New() *Groq {
-	customConfig := goopenai.DefaultConfig(os.Getenv("GROQ_API_KEY"))
-	customConfig.BaseURL = groqAPIEndpoint
-	customClient := goopenai.NewClientWithConfig(customConfig)
+	g := &Groq{
+		restClient:  restclientgo.New(defaultEndpoint),
+		model:       defaultModel,
+		temperature: defaultTemperature,
+		maxTokens:   defaultMaxTokens,
+		maxRetries:  defaultMaxRetries,
+		functions:   make(map[string]Function),
+		name:        "groq",
+	}
+
+	return g.WithAPIKey(os.Getenv("GROQ_API_KEY"))
+}
+
+func (g *Groq) WithAPIKey(apiKey string) *Groq {
+	g.restClient.SetRequestModifier(
+		func(req *http.Request) *http.Request {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+			return req
+		},
+	)
+	return g
+}
+
+// WithAPIKeyFunc sets a function returning the API key of each request from its context,
+// e.g. the key of the tenant set with secret.WithTenant. An empty key keeps the default.
+func (g *Groq) WithAPIKeyFunc(apiKeyFunc func(ctx context.Context) string) *Groq {
+	g.apiKeyFunc = apiKeyFunc
+	return g.setHTTPClient()
+}
+
+// WithHTTPClient sets the http client to use for the LLM
+func (g *Groq) WithHTTPClient(httpClient *http.Client) *Groq {
+	g.httpClient = httpClient
+	return g.setHTTPClient()
+}
+
+// WithEndpoint sets the API endpoint, https://api.groq.com/openai/v1 by default.
+func (g *Groq) WithEndpoint(endpoint string) *Groq {
+	g.restClient.SetEndpoint(endpoint)
+	return g
+}
+
+func (g *Groq) WithModel(model string) *Groq {
+	g.model = model
+	return g
+}
+
+func (g *Groq) WithTemperature(temperature float64) *Groq {
+	g.temperature = temperature
+	return g
+}
+
+func (g *Groq) WithMaxTokens(maxTokens int) *Groq {
+	g.maxTokens = maxTokens
+	return g
+}
+
+func (g *Groq) WithStop(stop []string) *Groq {
+	g.stop = stop
+	return g
+}
+
+func (g *Groq) WithSeed(seed int) *Groq {
+	g.seed = &seed
+	return g
+}
+
+// WithJSONMode forces the model to answer with a valid JSON object.
+func (g *Groq) WithJSONMode(jsonMode bool) *Groq {
+	g.jsonMode = jsonMode
+	return g
+}
+
+// WithMaxRetries sets how many times a rate limited request is retried, waiting for the
+// delay of the Retry-After header or for an exponential backoff starting at one second.
+func (g *Groq) WithMaxRetries(maxRetries uint) *Groq {
+	g.maxRetries = maxRetries
+	return g
+}
+
+func (g *Groq) WithStream(callbackFn StreamCallbackFn) *Groq {
+	g.streamCallbackFn = callbackFn
+	return g
+}
+
+// WithUsageCallback sets a callback receiving the token usage of each generation, also
+// when streaming.
+func (g *Groq) WithUsageCallback(callback UsageCallback) *Groq {
+	g.usageCallback = callback
+	return g
+}
+
+func (g *Groq) WithCache(cache *cache.Cache) *Groq {
+	g.cache = cache
+	return g
+}
+
+// WithToolChoice sets the tool choice: nil disables tools, "auto" lets the model decide,
+// "required" forces the call of any tool, any other value forces the named function.
+func (g *Groq) WithToolChoice(toolChoice *string) *Groq {
+	g.toolChoice = toolChoice
+	return g
+}
+
+func (g *Groq) WithTools(tools ...Tool) *Groq {
+	for _, tool := range tools {
+		fn, err := function.NewFromTool(tool)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		g.functions[tool.Name()] = *fn
+	}
+
+	return g
+}
+
+func (g *Groq) BindFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...function.ParameterOption,
+) error {
+	f, err := function.New(fn, name, description, functionParameterOptions...)
+	if err != nil {
+		return err
+	}
+
+	g.functions[name] = *f
+
+	return nil
+}
+
+// setHTTPClient sets the http client of the rest client, injecting the API key of the
+// request when an API key function is set.
+func (g *Groq) setHTTPClient() *Groq {
+	httpClient := g.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	if g.apiKeyFunc != nil {
+		client := *httpClient
+		transport := client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		client.Transport = secret.NewKeyFuncTransport(g.apiKeyFunc, secret.BearerAuth).WithBase(transport)
+		httpClient = &client
+	}
+
+	g.restClient.SetHTTPClient(httpClient)
+	return g
+}
+
+func (g *Groq) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
+	messages := t.UserQuery()
+	cacheQuery := strings.Join(messages, "\n")
+	cacheResult, err := g.cache.Get(ctx, cacheQuery)
+	if err != nil {
+		return cacheResult, err
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(strings.Join(cacheResult.Answer, "\n")),
+	))
+
+	return cacheResult, nil
+}
+
+func (g *Groq) setCache(ctx context.Context, t *thread.Thread, cacheResult *cache.Result) error {
+	lastMessage := t.LastMessage()
+
+	if lastMessage.Role != thread.RoleAssistant || len(lastMessage.Contents) == 0 {
+		return nil
+	}
+
+	contents := make([]string, 0)
+	for _, content := range lastMessage.Contents {
+		if content.Type == thread.ContentTypeText {
+			contents = append(contents, content.Data.(string))
+		} else {
+			contents = make([]string, 0)
+			break
+		}
+	}
+
+	err := g.cache.Set(ctx, cacheResult.Embedding, strings.Join(contents, "\n"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (g *Groq) Generate(ctx context.Context, t *thread.Thread) error {
+	if t == nil {
+		return nil
+	}
+
+	var err error
+	var cacheResult *cache.Result
+	if g.cache != nil {
+		cacheResult, err = g.getCache(ctx, t)
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, cache.ErrCacheMiss) {
+			return fmt.Errorf("%w: %w", ErrGroqChat, err)
+		}
+	}
 
-	openaillm := openai.New().WithClient(customClient)
-	openaillm.Name = "groq"
-	return &Groq{
-		OpenAI: openaillm,
+	chatRequest := g.buildChatCompletionRequest(t)
+
+	generation, err := g.startObserveGeneration(ctx, t)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGroqChat, err)
+	}
+
+	nMessageBeforeGeneration := len(t.Messages)
+
+	if g.streamCallbackFn != nil {
+		err = g.stream(ctx, t, chatRequest)
+	} else {
+		err = g.generate(ctx, t, chatRequest)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGroqChat, err)
+	}
+
+	err = g.stopObserveGeneration(ctx, generation, t.Messages[nMessageBeforeGeneration:])
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGroqChat, err)
+	}
+
+	if g.cache != nil {
+		err = g.setCache(ctx, t, cacheResult)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrGroqChat, err)
+		}
+	}
+
+	return nil
+}
+
+func (g *Groq) generate(ctx context.Context, t *thread.Thread, chatRequest *request) error {
+	resp, err := g.post(ctx, chatRequest, func() *response { return &response{} })
+	if err != nil {
+		return err
+	}
+
+	if g.reportsUsage(ctx) {
+		g.setUsageMetadata(ctx, resp.Usage)
+	}
+
+	if len(resp.Choices) == 0 {
+		return errors.New("no choices returned")
 	}
+
+	t.AddMessages(g.responseToMessages(ctx, resp.Choices[0].Message)...)
+
+	return nil
+}
+
+func (g *Groq) stream(ctx context.Context, t *thread.Thread, chatRequest *request) error {
+	var assistantMessage responseMessage
+
+	newResponse := func() *response {
+		// a retried stream starts from scratch
+		assistantMessage = responseMessage{}
+
+		resp := &response{}
+		resp.SetAcceptContentType(eventStreamContentType)
+		resp.SetStreamCallback(
+			func(data []byte) error {
+				dataAsString := string(data)
+				if !strings.HasPrefix(dataAsString, "data: ") {
+					return nil
+				}
+
+				dataAsString = strings.TrimPrefix(dataAsString, "data: ")
+				if dataAsString == "[DONE]" {
+					g.streamCallbackFn(EOS)
+					return nil
+				}
+
+				var chunk response
+				_ = json.Unmarshal([]byte(dataAsString), &chunk)
+				if chunk.XGroq != nil && chunk.XGroq.Usage != nil && g.reportsUsage(ctx) {
+					g.setUsageMetadata(ctx, *chunk.XGroq.Usage)
+				}
+
+				if len(chunk.Choices) == 0 {
+					return nil
+				}
+
+				delta := chunk.Choices[0].Delta
+				assistantMessage.Content += delta.Content
+				assistantMessage.ToolCalls = mergeToolCalls(assistantMessage.ToolCalls, delta.ToolCalls)
+				if delta.Content != "" {
+					g.streamCallbackFn(delta.Content)
+				}
+
+				return nil
+			},
+		)
+		return resp
+	}
+
+	chatRequest.Stream = true
+
+	_, err := g.post(ctx, chatRequest, newResponse)
+	if err != nil {
+		return err
+	}
+
+	t.AddMessages(g.responseToMessages(ctx, assistantMessage)...)
+
+	return nil
+}
+
+// post sends the request retrying it when the API is rate limited, waiting for the delay
+// suggested by the Retry-After header. Error statuses are returned as an *APIError.
+func (g *Groq) post(ctx context.Context, chatRequest *request, newResponse func() *response) (*response, error) {
+	policy := retry.Policy{
+		MaxRetries:   g.maxRetries,
+		InitialDelay: defaultRetryDelay,
+		Retryable: func(err error) bool {
+			httpErr, ok := httperror.As(err)
+			return ok && httpErr.StatusCode == http.StatusTooManyRequests
+		},
+		Name: g.name,
+	}
+
+	var resp *response
+	err := retry.Do(ctx, policy, func(ctx context.Context) error {
+		resp = newResponse()
+		err := g.restClient.Post(ctx, chatRequest, resp)
+		if err != nil {
+			return err
+		}
+
+		if resp.HTTPStatusCode >= http.StatusBadRequest {
+			return newAPIError(resp)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func newAPIError(resp *response) *APIError {
+	apiErr := &APIError{
+		err: httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.headers),
+	}
+
+	var body struct {
+		Error *apiErrorBody `json:"error"`
+	}
+	if json.Unmarshal(resp.RawBody, &body) == nil && body.Error != nil {
+		apiErr.Message = body.Error.Message
+		apiErr.Type = body.Error.Type
+		apiErr.Code = body.Error.Code
+	}
+
+	return apiErr
+}
+
+// mergeToolCalls appends the streamed tool calls, concatenating the arguments of the
+// chunks that belong to the same call.
+func mergeToolCalls(toolCalls []toolCall, deltas []toolCall) []toolCall {
+	for _, delta := range deltas {
+		if delta.ID == "" && len(toolCalls) > 0 {
+			toolCalls[len(toolCalls)-1].Function.Arguments += delta.Function.Arguments
+			continue
+		}
+		toolCalls = append(toolCalls, delta)
+	}
+
+	return toolCalls
+}
+
+func (g *Groq) responseToMessages(ctx context.Context, responseMessage responseMessage) []*thread.Message {
+	if len(responseMessage.ToolCalls) == 0 {
+		return []*thread.Message{
+			thread.NewAssistantMessage().AddContent(
+				thread.NewTextContent(responseMessage.Content),
+			),
+		}
+	}
+
+	toolCalls := make([]thread.ToolCallData, 0, len(responseMessage.ToolCalls))
+	for _, tc := range responseMessage.ToolCalls {
+		toolCalls = append(toolCalls, thread.ToolCallData{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+
+	messages := []*thread.Message{
+		thread.NewAssistantMessage().AddContent(
+			thread.NewToolCallContent(toolCalls),
+		),
+	}
+
+	return append(messages, g.callTools(ctx, toolCalls)...)
+}
+
+func (g *Groq) callTool(toolCall thread.ToolCallData) (any, string, error) {
+	fn, ok := g.functions[toolCall.Name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown function %s", toolCall.Name)
+	}
+
+	return fn.CallValue(toolCall.Arguments)
+}
+
+func (g *Groq) callTools(ctx context.Context, toolCalls []thread.ToolCallData) []*thread.Message {
+	if len(g.functions) == 0 || len(toolCalls) == 0 {
+		return nil
+	}
+
+	var messages []*thread.Message
+	for _, toolCall := range toolCalls {
+		// skip pending tool calls if the generation has been cancelled
+		err := ctx.Err()
+		var value any
+		result := ""
+		if err == nil {
+			value, result, err = g.callTool(toolCall)
+		}
+		if err != nil {
+			result = fmt.Sprintf("error: %s", err)
+		}
+
+		messages = append(messages, thread.NewToolMessage().AddContent(
+			thread.NewToolResponseContent(
+				thread.ToolResponseData{
+					ID:     toolCall.ID,
+					Name:   toolCall.Name,
+					Result: result,
+					Value:  value,
+					Err:    err,
+				},
+			),
+		))
+	}
+
+	return messages
+}
+
+// reportsUsage reports whether the usage of the request is passed to the usage callback
+// or to the usage reporter of the context.
+func (g *Groq) reportsUsage(ctx context.Context) bool {
+	return g.usageCallback != nil || usagereport.Enabled(ctx)
+}
+
+// setUsageMetadata passes the usage to the usage callback and to the usage reporter of
+// the context, with the tenant of the request if any.
+func (g *Groq) setUsageMetadata(ctx context.Context, u usage) {
+	usageMetadata := types.Meta{
+		"PromptTokens":     u.PromptTokens,
+		"CompletionTokens": u.CompletionTokens,
+		"TotalTokens":      u.TotalTokens,
+	}
+
+	if tenant := secret.Tenant(ctx); tenant != "" {
+		usageMetadata[secret.UsageKeyTenant] = tenant
+	}
+
+	usagereport.Report(ctx, usageMetadata)
+	if g.usageCallback != nil {
+		g.usageCallback(usageMetadata)
+	}
+}
+
+func (g *Groq) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
+	return llmobserver.StartObserveGeneration(
+		ctx,
+		g.name,
+		g.model,
+		types.M{
+			"maxTokens":   g.maxTokens,
+			"temperature": g.temperature,
+		},
+		t,
+	)
+}
+
+func (g *Groq) stopObserveGeneration(
+	ctx context.Context,
+	generation *observer.Generation,
+	messages []*thread.Message,
+) error {
+	return llmobserver.StopObserveGeneration(
+		ctx,
+		generation,
+		messages,
+	)
 }
@@ -0,0 +1,137 @@
+package groq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/llm/usagereport"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+func TestGenerateRetriesRateLimited(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		w.Header().Set("Content-Type", jsonContentType)
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"rate limited","type":"tokens","code":"rate_limit_exceeded"}}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"model":"` + req.Model + `","choices":[{"message":{"role":"assistant","content":"hello"}}],` +
+			`"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	var usage types.Meta
+	llm := New().WithEndpoint(server.URL).WithAPIKey("key").WithModel(ModelLlama3Dot170B).
+		WithUsageCallback(func(meta types.Meta) {
+			usage = meta
+		})
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected the rate limited request to be retried, got %d calls", calls)
+	}
+	if got := th.LastMessage().Contents[0].AsString(); got != "hello" {
+		t.Fatalf("unexpected answer %q", got)
+	}
+	if usage["TotalTokens"] != 4 {
+		t.Fatalf("unexpected usage %v", usage)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"message":"rate limited","type":"tokens","code":"rate_limit_exceeded"}}`))
+	}))
+	defer server.Close()
+
+	llm := New().WithEndpoint(server.URL).WithMaxRetries(0)
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if !errors.Is(err, ErrGroqChat) {
+		t.Fatalf("expected a groq error, got %v", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "rate_limit_exceeded" || apiErr.Message != "rate limited" {
+		t.Fatalf("expected the API error, got %v", err)
+	}
+	if httpErr, ok := httperror.As(err); !ok || httpErr.RetryAfter != 7*time.Second {
+		t.Fatalf("expected the Retry-After delay, got %v", err)
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", eventStreamContentType)
+		_, _ = w.Write([]byte(
+			"data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"hel\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]," +
+				"\"x_groq\":{\"usage\":{\"total_tokens\":5}}}\n\n" +
+				"data: [DONE]\n\n",
+		))
+	}))
+	defer server.Close()
+
+	var chunks []string
+	var usage types.Meta
+	llm := New().WithEndpoint(server.URL).
+		WithStream(func(chunk string) {
+			chunks = append(chunks, chunk)
+		}).
+		WithUsageCallback(func(meta types.Meta) {
+			usage = meta
+		})
+
+	var reported types.Meta
+	ctx := usagereport.WithReporter(context.Background(), func(meta types.Meta) {
+		reported = meta
+	})
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(ctx, th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := th.LastMessage().Contents[0].AsString(); got != "hello" {
+		t.Fatalf("unexpected answer %q", got)
+	}
+	if got := strings.Join(chunks, ""); got != "hello"+EOS {
+		t.Fatalf("unexpected chunks %q", chunks)
+	}
+	if usage["TotalTokens"] != 5 || reported["TotalTokens"] != 5 {
+		t.Fatalf("unexpected usage %v, reported %v", usage, reported)
+	}
+}
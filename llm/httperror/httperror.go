@@ -0,0 +1,98 @@
+// Package httperror provides the error returned by the LLM providers when the API answers
+// with an HTTP error status, so that callers can tell transient failures apart.
+package httperror
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error is an HTTP error response. Its message is the response body, or the message of
+// the wrapped error.
+type Error struct {
+	StatusCode int
+	// RetryAfter is the delay suggested by the Retry-After header, zero if missing.
+	RetryAfter time.Duration
+	Body       []byte
+	// Err is the error of the provider client the status was read from, if any.
+	Err error
+}
+
+func New(statusCode int, body []byte) *Error {
+	return &Error{
+		StatusCode: statusCode,
+		Body:       body,
+	}
+}
+
+// Wrap returns the Error of a status reported by err, e.g. an error of a provider SDK
+// whose type doesn't carry the response headers. The result matches err too.
+func Wrap(statusCode int, err error) *Error {
+	return &Error{
+		StatusCode: statusCode,
+		Err:        err,
+	}
+}
+
+// WithRetryAfter parses the value of the Retry-After header, either in seconds or as an
+// HTTP date.
+func (e *Error) WithRetryAfter(retryAfter string) *Error {
+	e.RetryAfter = ParseRetryAfter(retryAfter, time.Now())
+	return e
+}
+
+// WithHeaders reads the Retry-After header of the response headers.
+func (e *Error) WithHeaders(headers map[string][]string) *Error {
+	return e.WithRetryAfter(http.Header(headers).Get("Retry-After"))
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return string(e.Body)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether the request can succeed if retried: rate limits, server
+// errors and timeouts.
+func (e *Error) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode >= http.StatusInternalServerError
+}
+
+// As returns the Error wrapped by err, if any.
+func As(err error) (*Error, bool) {
+	var httpErr *Error
+	if errors.As(err, &httpErr) {
+		return httpErr, true
+	}
+	return nil, false
+}
+
+func ParseRetryAfter(retryAfter string, now time.Time) time.Duration {
+	retryAfter = strings.TrimSpace(retryAfter)
+	if retryAfter == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseFloat(retryAfter, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if date, err := http.ParseTime(retryAfter); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+}
@@ -0,0 +1,417 @@
+package huggingface
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/llm/httperror"
+	llmobserver "github.com/henomis/lingoose/llm/observer"
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultTGIMaxNewTokens = 1024
+	EOS                    = "\x00"
+)
+
+var (
+	ErrTGIChat = errors.New("huggingface tgi chat error")
+)
+
+type StreamCallbackFn func(string)
+
+// PromptFormatter renders a thread into the prompt expected by the model chat template.
+type PromptFormatter func(t *thread.Thread) string
+
+// TGI is a thread based LLM for text-generation-inference servers: self-hosted TGI,
+// Inference Endpoints or the serverless Inference API (https://api-inference.huggingface.co/models/<model>).
+type TGI struct {
+	restClient        *restclientgo.RestClient
+	model             string
+	maxNewTokens      int
+	temperature       *float32
+	topK              *int
+	topP              *float32
+	typicalP          *float32
+	repetitionPenalty *float32
+	seed              *int
+	stop              []string
+	promptFormatter   PromptFormatter
+	streamCallbackFn  StreamCallbackFn
+	cache             *cache.Cache
+	name              string
+}
+
+// NewTGI creates a TGI LLM for the given endpoint. The token is read from the
+// HUGGING_FACE_HUB_TOKEN environment variable.
+func NewTGI(endpoint string) *TGI {
+	token := os.Getenv("HUGGING_FACE_HUB_TOKEN")
+
+	return &TGI{
+		restClient: restclientgo.New(endpoint).WithRequestModifier(
+			func(req *http.Request) *http.Request {
+				if token != "" {
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+				return req
+			},
+		),
+		maxNewTokens:    defaultTGIMaxNewTokens,
+		promptFormatter: ChatMLPromptFormatter,
+		name:            "huggingface",
+	}
+}
+
+// WithToken sets the Hugging Face token.
+func (h *TGI) WithToken(token string) *TGI {
+	h.restClient.SetRequestModifier(
+		func(req *http.Request) *http.Request {
+			req.Header.Set("Authorization", "Bearer "+token)
+			return req
+		},
+	)
+	return h
+}
+
+// WithModel sets the model name reported to the observer, the model is chosen by the endpoint.
+func (h *TGI) WithModel(model string) *TGI {
+	h.model = model
+	return h
+}
+
+func (h *TGI) WithMaxNewTokens(maxNewTokens int) *TGI {
+	h.maxNewTokens = maxNewTokens
+	return h
+}
+
+func (h *TGI) WithTemperature(temperature float32) *TGI {
+	h.temperature = &temperature
+	return h
+}
+
+func (h *TGI) WithTopK(topK int) *TGI {
+	h.topK = &topK
+	return h
+}
+
+func (h *TGI) WithTopP(topP float32) *TGI {
+	h.topP = &topP
+	return h
+}
+
+func (h *TGI) WithTypicalP(typicalP float32) *TGI {
+	h.typicalP = &typicalP
+	return h
+}
+
+// WithRepetitionPenalty penalizes repeated tokens, 1.0 means no penalty.
+func (h *TGI) WithRepetitionPenalty(repetitionPenalty float32) *TGI {
+	h.repetitionPenalty = &repetitionPenalty
+	return h
+}
+
+func (h *TGI) WithSeed(seed int) *TGI {
+	h.seed = &seed
+	return h
+}
+
+func (h *TGI) WithStop(stop []string) *TGI {
+	h.stop = stop
+	return h
+}
+
+// WithPromptFormatter sets the function rendering the thread into the model prompt,
+// ChatMLPromptFormatter by default.
+func (h *TGI) WithPromptFormatter(promptFormatter PromptFormatter) *TGI {
+	h.promptFormatter = promptFormatter
+	return h
+}
+
+func (h *TGI) WithStream(callbackFn StreamCallbackFn) *TGI {
+	h.streamCallbackFn = callbackFn
+	return h
+}
+
+func (h *TGI) WithCache(cache *cache.Cache) *TGI {
+	h.cache = cache
+	return h
+}
+
+// WithHTTPClient sets the http client to use for the LLM
+func (h *TGI) WithHTTPClient(httpClient *http.Client) *TGI {
+	h.restClient.SetHTTPClient(httpClient)
+	return h
+}
+
+func (h *TGI) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
+	messages := t.UserQuery()
+	cacheQuery := strings.Join(messages, "\n")
+	cacheResult, err := h.cache.Get(ctx, cacheQuery)
+	if err != nil {
+		return cacheResult, err
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(strings.Join(cacheResult.Answer, "\n")),
+	))
+
+	return cacheResult, nil
+}
+
+func (h *TGI) setCache(ctx context.Context, t *thread.Thread, cacheResult *cache.Result) error {
+	lastMessage := t.LastMessage()
+
+	if lastMessage.Role != thread.RoleAssistant || len(lastMessage.Contents) == 0 {
+		return nil
+	}
+
+	contents := make([]string, 0)
+	for _, content := range lastMessage.Contents {
+		if content.Type == thread.ContentTypeText {
+			contents = append(contents, content.Data.(string))
+		} else {
+			contents = make([]string, 0)
+			break
+		}
+	}
+
+	err := h.cache.Set(ctx, cacheResult.Embedding, strings.Join(contents, "\n"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (h *TGI) Generate(ctx context.Context, t *thread.Thread) error {
+	if t == nil {
+		return nil
+	}
+
+	var err error
+	var cacheResult *cache.Result
+	if h.cache != nil {
+		cacheResult, err = h.getCache(ctx, t)
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, cache.ErrCacheMiss) {
+			return fmt.Errorf("%w: %w", ErrTGIChat, err)
+		}
+	}
+
+	request := h.buildRequest(t)
+
+	generation, err := h.startObserveGeneration(ctx, t)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTGIChat, err)
+	}
+
+	var generatedText string
+	if h.streamCallbackFn != nil {
+		generatedText, err = h.stream(ctx, request)
+	} else {
+		generatedText, err = h.generate(ctx, request)
+	}
+	if err != nil {
+		return err
+	}
+
+	message := thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(h.trimStop(generatedText)),
+	)
+	t.AddMessage(message)
+
+	err = h.stopObserveGeneration(ctx, generation, []*thread.Message{message})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTGIChat, err)
+	}
+
+	if h.cache != nil {
+		err = h.setCache(ctx, t, cacheResult)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrTGIChat, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *TGI) buildRequest(t *thread.Thread) *tgiRequest {
+	return &tgiRequest{
+		Inputs: h.promptFormatter(t),
+		Parameters: tgiParameters{
+			MaxNewTokens:      h.maxNewTokens,
+			Temperature:       h.temperature,
+			TopK:              h.topK,
+			TopP:              h.topP,
+			TypicalP:          h.typicalP,
+			RepetitionPenalty: h.repetitionPenalty,
+			DoSample:          h.temperature != nil || h.topK != nil || h.topP != nil || h.typicalP != nil,
+			Seed:              h.seed,
+			Stop:              h.stop,
+		},
+	}
+}
+
+func (h *TGI) generate(ctx context.Context, request *tgiRequest) (string, error) {
+	var resp tgiResponse
+
+	err := h.restClient.Post(ctx, request, &resp)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTGIChat, err)
+	}
+
+	if resp.HTTPStatusCode >= http.StatusBadRequest {
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return "", fmt.Errorf("%w: %w", ErrTGIChat, httpErr)
+	}
+
+	if resp.Error != "" {
+		return "", fmt.Errorf("%w: %s", ErrTGIChat, resp.Error)
+	}
+
+	return resp.GeneratedText, nil
+}
+
+func (h *TGI) stream(ctx context.Context, request *tgiRequest) (string, error) {
+	var resp tgiResponse
+	var generatedText string
+	var streamErr error
+
+	resp.SetAcceptContentType(tgiEventStreamContentType)
+	resp.SetStreamCallback(
+		func(data []byte) error {
+			dataAsString := string(data)
+			if !strings.HasPrefix(dataAsString, "data:") {
+				return nil
+			}
+
+			var chunk tgiStreamResponse
+			err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(dataAsString, "data:"))), &chunk)
+			if err != nil {
+				return nil
+			}
+
+			if chunk.Error != "" {
+				streamErr = errors.New(chunk.Error)
+				return nil
+			}
+
+			if !chunk.Token.Special {
+				generatedText += chunk.Token.Text
+				h.streamCallbackFn(chunk.Token.Text)
+			}
+
+			if chunk.GeneratedText != nil {
+				h.streamCallbackFn(EOS)
+			}
+
+			return nil
+		},
+	)
+
+	request.Stream = true
+
+	err := h.restClient.Post(ctx, request, &resp)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTGIChat, err)
+	}
+
+	if resp.HTTPStatusCode >= http.StatusBadRequest {
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return "", fmt.Errorf("%w: %w", ErrTGIChat, httpErr)
+	}
+
+	if streamErr != nil {
+		return "", fmt.Errorf("%w: %w", ErrTGIChat, streamErr)
+	}
+
+	return generatedText, nil
+}
+
+// trimStop removes the stop sequence TGI includes at the end of the generated text.
+func (h *TGI) trimStop(text string) string {
+	for _, stop := range h.stop {
+		if strings.HasSuffix(text, stop) {
+			return strings.TrimSuffix(text, stop)
+		}
+	}
+
+	return text
+}
+
+func (h *TGI) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
+	return llmobserver.StartObserveGeneration(
+		ctx,
+		h.name,
+		h.model,
+		types.M{
+			"maxNewTokens":      h.maxNewTokens,
+			"temperature":       h.temperature,
+			"topK":              h.topK,
+			"repetitionPenalty": h.repetitionPenalty,
+		},
+		t,
+	)
+}
+
+func (h *TGI) stopObserveGeneration(
+	ctx context.Context,
+	generation *observer.Generation,
+	messages []*thread.Message,
+) error {
+	return llmobserver.StopObserveGeneration(
+		ctx,
+		generation,
+		messages,
+	)
+}
+
+// ChatMLPromptFormatter renders the thread with the ChatML template (Qwen, Hermes, Zephyr...).
+func ChatMLPromptFormatter(t *thread.Thread) string {
+	var prompt strings.Builder
+	for _, m := range t.Messages {
+		prompt.WriteString("<|im_start|>" + string(m.Role) + "\n" + messageText(m) + "<|im_end|>\n")
+	}
+	prompt.WriteString("<|im_start|>assistant\n")
+
+	return prompt.String()
+}
+
+// Llama3PromptFormatter renders the thread with the Llama 3 instruct template.
+func Llama3PromptFormatter(t *thread.Thread) string {
+	var prompt strings.Builder
+	prompt.WriteString("<|begin_of_text|>")
+	for _, m := range t.Messages {
+		prompt.WriteString("<|start_header_id|>" + string(m.Role) + "<|end_header_id|>\n\n" + messageText(m) + "<|eot_id|>")
+	}
+	prompt.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+
+	return prompt.String()
+}
+
+func messageText(m *thread.Message) string {
+	var text string
+	for _, c := range m.Contents {
+		switch data := c.Data.(type) {
+		case string:
+			if c.Type == thread.ContentTypeText {
+				text += data
+			}
+		case thread.ToolResponseData:
+			text += data.Result
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,142 @@
+package huggingface
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/henomis/restclientgo"
+)
+
+const (
+	tgiJSONContentType        = "application/json"
+	tgiEventStreamContentType = "text/event-stream"
+)
+
+type tgiRequest struct {
+	Inputs     string        `json:"inputs"`
+	Parameters tgiParameters `json:"parameters"`
+	Stream     bool          `json:"stream"`
+	Options    *options      `json:"options,omitempty"`
+}
+
+func (r *tgiRequest) Path() (string, error) {
+	return "", nil
+}
+
+func (r *tgiRequest) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *tgiRequest) ContentType() string {
+	return tgiJSONContentType
+}
+
+type tgiParameters struct {
+	MaxNewTokens      int      `json:"max_new_tokens,omitempty"`
+	Temperature       *float32 `json:"temperature,omitempty"`
+	TopK              *int     `json:"top_k,omitempty"`
+	TopP              *float32 `json:"top_p,omitempty"`
+	TypicalP          *float32 `json:"typical_p,omitempty"`
+	RepetitionPenalty *float32 `json:"repetition_penalty,omitempty"`
+	DoSample          bool     `json:"do_sample,omitempty"`
+	Seed              *int     `json:"seed,omitempty"`
+	Stop              []string `json:"stop,omitempty"`
+	ReturnFullText    bool     `json:"return_full_text"`
+	Details           bool     `json:"details"`
+}
+
+type tgiResponse struct {
+	HTTPStatusCode    int                  `json:"-"`
+	RawBody           []byte               `json:"-"`
+	Headers           restclientgo.Headers `json:"-"`
+	acceptContentType string
+	streamCallbackFn  restclientgo.StreamCallback
+	tgiGeneration
+}
+
+// tgiGeneration is the response of a non streaming request. The serverless Inference API
+// wraps it in a list, self-hosted TGI does not.
+type tgiGeneration struct {
+	GeneratedText string      `json:"generated_text"`
+	Details       *tgiDetails `json:"details"`
+	Error         string      `json:"error"`
+}
+
+type tgiDetails struct {
+	FinishReason    string `json:"finish_reason"`
+	GeneratedTokens int    `json:"generated_tokens"`
+}
+
+type tgiStreamResponse struct {
+	Token         tgiToken    `json:"token"`
+	GeneratedText *string     `json:"generated_text"`
+	Details       *tgiDetails `json:"details"`
+	Error         string      `json:"error"`
+}
+
+type tgiToken struct {
+	ID      int     `json:"id"`
+	Text    string  `json:"text"`
+	Logprob float64 `json:"logprob"`
+	Special bool    `json:"special"`
+}
+
+func (r *tgiResponse) SetAcceptContentType(contentType string) {
+	r.acceptContentType = contentType
+}
+
+func (r *tgiResponse) Decode(body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var generations []tgiGeneration
+		err = json.Unmarshal(data, &generations)
+		if err != nil || len(generations) == 0 {
+			return err
+		}
+
+		r.tgiGeneration = generations[0]
+		return nil
+	}
+
+	return json.Unmarshal(data, &r.tgiGeneration)
+}
+
+func (r *tgiResponse) SetBody(body io.Reader) error {
+	r.RawBody, _ = io.ReadAll(body)
+	return nil
+}
+
+func (r *tgiResponse) AcceptContentType() string {
+	if r.acceptContentType != "" {
+		return r.acceptContentType
+	}
+	return tgiJSONContentType
+}
+
+func (r *tgiResponse) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *tgiResponse) SetHeaders(headers restclientgo.Headers) error {
+	r.Headers = headers
+	return nil
+}
+
+func (r *tgiResponse) SetStreamCallback(fn restclientgo.StreamCallback) {
+	r.streamCallbackFn = fn
+}
+
+func (r *tgiResponse) StreamCallback() restclientgo.StreamCallback {
+	return r.streamCallbackFn
+}
@@ -0,0 +1,129 @@
+package llamacpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/henomis/restclientgo"
+)
+
+const (
+	healthCheckInterval = 250 * time.Millisecond
+)
+
+var (
+	ErrServerProcess = errors.New("llamacpp server process error")
+)
+
+// Process is a llama.cpp server started by the application, listening on the loopback
+// interface.
+type Process struct {
+	cmd      *exec.Cmd
+	endpoint string
+	exited   chan struct{}
+	err      error
+}
+
+// StartServer runs the llama-server binary at serverPath loading the GGUF model at
+// modelPath, and waits until the model is loaded. Additional server arguments (e.g.
+// "--ctx-size", "8192" or "--n-gpu-layers", "99") can be passed in args. Use the
+// Endpoint of the returned process with NewServer and Stop it when done.
+func StartServer(ctx context.Context, serverPath, modelPath string, args ...string) (*Process, error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrServerProcess, err)
+	}
+
+	serverArgs := append([]string{
+		"-m", modelPath,
+		"--host", "127.0.0.1",
+		"--port", strconv.Itoa(port),
+	}, args...)
+
+	//nolint:gosec
+	cmd := exec.Command(serverPath, serverArgs...)
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrServerProcess, err)
+	}
+
+	p := &Process{
+		cmd:      cmd,
+		endpoint: "http://127.0.0.1:" + strconv.Itoa(port),
+		exited:   make(chan struct{}),
+	}
+
+	go func() {
+		p.err = cmd.Wait()
+		close(p.exited)
+	}()
+
+	err = p.waitReady(ctx)
+	if err != nil {
+		_ = p.Stop()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Endpoint returns the server URL.
+func (p *Process) Endpoint() string {
+	return p.endpoint
+}
+
+// Stop kills the server and waits for it to exit.
+func (p *Process) Stop() error {
+	select {
+	case <-p.exited:
+		return nil
+	default:
+	}
+
+	err := p.cmd.Process.Kill()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrServerProcess, err)
+	}
+	<-p.exited
+
+	return nil
+}
+
+func (p *Process) waitReady(ctx context.Context) error {
+	restClient := restclientgo.New(p.endpoint)
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		var resp healthResponse
+		err := restClient.Get(ctx, &healthRequest{}, &resp)
+		if err == nil && resp.HTTPStatusCode == http.StatusOK {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrServerProcess, ctx.Err())
+		case <-p.exited:
+			return fmt.Errorf("%w: server exited: %v", ErrServerProcess, p.err)
+		case <-ticker.C:
+		}
+	}
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
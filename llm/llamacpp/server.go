@@ -0,0 +1,375 @@
+package llamacpp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/llm/httperror"
+	llmobserver "github.com/henomis/lingoose/llm/observer"
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultServerEndpoint  = "http://localhost:8080"
+	defaultServerMaxTokens = 1024
+	EOS                    = "\x00"
+)
+
+var (
+	ErrLlamaCppChat = errors.New("llamacpp chat error")
+)
+
+type StreamCallbackFn func(string)
+
+// PromptFormatter renders a thread into the prompt expected by the model chat template.
+type PromptFormatter func(t *thread.Thread) string
+
+// Server is a thread based LLM running GGUF models with the llama.cpp server, locally
+// and fully offline. The server can be started by the application with StartServer.
+type Server struct {
+	restClient       *restclientgo.RestClient
+	model            string
+	maxTokens        int
+	temperature      *float32
+	topK             *int
+	topP             *float32
+	repeatPenalty    *float32
+	seed             *int
+	stop             []string
+	grammar          string
+	promptFormatter  PromptFormatter
+	streamCallbackFn StreamCallbackFn
+	cache            *cache.Cache
+	name             string
+}
+
+// NewServer creates a llama.cpp LLM for the server listening on the given endpoint,
+// http://localhost:8080 if empty.
+func NewServer(endpoint string) *Server {
+	if endpoint == "" {
+		endpoint = defaultServerEndpoint
+	}
+
+	return &Server{
+		restClient:      restclientgo.New(strings.TrimSuffix(endpoint, "/")),
+		maxTokens:       defaultServerMaxTokens,
+		promptFormatter: ChatMLPromptFormatter,
+		name:            "llamacpp",
+	}
+}
+
+// WithModel sets the model name reported to the observer, the model is loaded by the server.
+func (s *Server) WithModel(model string) *Server {
+	s.model = model
+	return s
+}
+
+func (s *Server) WithMaxTokens(maxTokens int) *Server {
+	s.maxTokens = maxTokens
+	return s
+}
+
+func (s *Server) WithTemperature(temperature float32) *Server {
+	s.temperature = &temperature
+	return s
+}
+
+func (s *Server) WithTopK(topK int) *Server {
+	s.topK = &topK
+	return s
+}
+
+func (s *Server) WithTopP(topP float32) *Server {
+	s.topP = &topP
+	return s
+}
+
+func (s *Server) WithRepeatPenalty(repeatPenalty float32) *Server {
+	s.repeatPenalty = &repeatPenalty
+	return s
+}
+
+func (s *Server) WithSeed(seed int) *Server {
+	s.seed = &seed
+	return s
+}
+
+// WithStop sets the sequences stopping the generation, they are not included in the answer.
+func (s *Server) WithStop(stop []string) *Server {
+	s.stop = stop
+	return s
+}
+
+// WithGrammar constrains the answer to the given GBNF grammar.
+func (s *Server) WithGrammar(grammar string) *Server {
+	s.grammar = grammar
+	return s
+}
+
+// WithPromptFormatter sets the function rendering the thread into the model prompt,
+// ChatMLPromptFormatter by default.
+func (s *Server) WithPromptFormatter(promptFormatter PromptFormatter) *Server {
+	s.promptFormatter = promptFormatter
+	return s
+}
+
+func (s *Server) WithStream(callbackFn StreamCallbackFn) *Server {
+	s.streamCallbackFn = callbackFn
+	return s
+}
+
+func (s *Server) WithCache(cache *cache.Cache) *Server {
+	s.cache = cache
+	return s
+}
+
+// WithHTTPClient sets the http client to use for the LLM
+func (s *Server) WithHTTPClient(httpClient *http.Client) *Server {
+	s.restClient.SetHTTPClient(httpClient)
+	return s
+}
+
+func (s *Server) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
+	messages := t.UserQuery()
+	cacheQuery := strings.Join(messages, "\n")
+	cacheResult, err := s.cache.Get(ctx, cacheQuery)
+	if err != nil {
+		return cacheResult, err
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(strings.Join(cacheResult.Answer, "\n")),
+	))
+
+	return cacheResult, nil
+}
+
+func (s *Server) setCache(ctx context.Context, t *thread.Thread, cacheResult *cache.Result) error {
+	lastMessage := t.LastMessage()
+
+	if lastMessage.Role != thread.RoleAssistant || len(lastMessage.Contents) == 0 {
+		return nil
+	}
+
+	contents := make([]string, 0)
+	for _, content := range lastMessage.Contents {
+		if content.Type == thread.ContentTypeText {
+			contents = append(contents, content.Data.(string))
+		} else {
+			contents = make([]string, 0)
+			break
+		}
+	}
+
+	err := s.cache.Set(ctx, cacheResult.Embedding, strings.Join(contents, "\n"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Server) Generate(ctx context.Context, t *thread.Thread) error {
+	if t == nil {
+		return nil
+	}
+
+	var err error
+	var cacheResult *cache.Result
+	if s.cache != nil {
+		cacheResult, err = s.getCache(ctx, t)
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, cache.ErrCacheMiss) {
+			return fmt.Errorf("%w: %w", ErrLlamaCppChat, err)
+		}
+	}
+
+	request := s.buildRequest(t)
+
+	generation, err := s.startObserveGeneration(ctx, t)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrLlamaCppChat, err)
+	}
+
+	var content string
+	if s.streamCallbackFn != nil {
+		content, err = s.stream(ctx, request)
+	} else {
+		content, err = s.generate(ctx, request)
+	}
+	if err != nil {
+		return err
+	}
+
+	message := thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(content),
+	)
+	t.AddMessage(message)
+
+	err = s.stopObserveGeneration(ctx, generation, []*thread.Message{message})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrLlamaCppChat, err)
+	}
+
+	if s.cache != nil {
+		err = s.setCache(ctx, t, cacheResult)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrLlamaCppChat, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) buildRequest(t *thread.Thread) *completionRequest {
+	return &completionRequest{
+		Prompt:        s.promptFormatter(t),
+		NPredict:      s.maxTokens,
+		Temperature:   s.temperature,
+		TopK:          s.topK,
+		TopP:          s.topP,
+		RepeatPenalty: s.repeatPenalty,
+		Seed:          s.seed,
+		Stop:          s.stop,
+		Grammar:       s.grammar,
+		CachePrompt:   true,
+	}
+}
+
+func (s *Server) generate(ctx context.Context, request *completionRequest) (string, error) {
+	var resp completionResponse
+
+	err := s.restClient.Post(ctx, request, &resp)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrLlamaCppChat, err)
+	}
+
+	if resp.HTTPStatusCode >= http.StatusBadRequest {
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return "", fmt.Errorf("%w: %w", ErrLlamaCppChat, httpErr)
+	}
+
+	return resp.Content, nil
+}
+
+func (s *Server) stream(ctx context.Context, request *completionRequest) (string, error) {
+	var resp completionResponse
+	var content string
+
+	resp.SetAcceptContentType(eventStreamContentType)
+	resp.SetStreamCallback(
+		func(data []byte) error {
+			dataAsString := string(data)
+			if !strings.HasPrefix(dataAsString, "data:") {
+				return nil
+			}
+
+			var chunk completion
+			err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(dataAsString, "data:"))), &chunk)
+			if err != nil {
+				return nil
+			}
+
+			if chunk.Content != "" {
+				content += chunk.Content
+				s.streamCallbackFn(chunk.Content)
+			}
+
+			if chunk.Stop {
+				s.streamCallbackFn(EOS)
+			}
+
+			return nil
+		},
+	)
+
+	request.Stream = true
+
+	err := s.restClient.Post(ctx, request, &resp)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrLlamaCppChat, err)
+	}
+
+	if resp.HTTPStatusCode >= http.StatusBadRequest {
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return "", fmt.Errorf("%w: %w", ErrLlamaCppChat, httpErr)
+	}
+
+	return content, nil
+}
+
+func (s *Server) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
+	return llmobserver.StartObserveGeneration(
+		ctx,
+		s.name,
+		s.model,
+		types.M{
+			"maxTokens":   s.maxTokens,
+			"temperature": s.temperature,
+			"topK":        s.topK,
+			"topP":        s.topP,
+		},
+		t,
+	)
+}
+
+func (s *Server) stopObserveGeneration(
+	ctx context.Context,
+	generation *observer.Generation,
+	messages []*thread.Message,
+) error {
+	return llmobserver.StopObserveGeneration(
+		ctx,
+		generation,
+		messages,
+	)
+}
+
+// ChatMLPromptFormatter renders the thread with the ChatML template (Qwen, Hermes, Zephyr...).
+func ChatMLPromptFormatter(t *thread.Thread) string {
+	var prompt strings.Builder
+	for _, m := range t.Messages {
+		prompt.WriteString("<|im_start|>" + string(m.Role) + "\n" + messageText(m) + "<|im_end|>\n")
+	}
+	prompt.WriteString("<|im_start|>assistant\n")
+
+	return prompt.String()
+}
+
+// Llama3PromptFormatter renders the thread with the Llama 3 instruct template.
+func Llama3PromptFormatter(t *thread.Thread) string {
+	var prompt strings.Builder
+	prompt.WriteString("<|begin_of_text|>")
+	for _, m := range t.Messages {
+		prompt.WriteString("<|start_header_id|>" + string(m.Role) + "<|end_header_id|>\n\n" + messageText(m) + "<|eot_id|>")
+	}
+	prompt.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+
+	return prompt.String()
+}
+
+func messageText(m *thread.Message) string {
+	var text string
+	for _, c := range m.Contents {
+		switch data := c.Data.(type) {
+		case string:
+			if c.Type == thread.ContentTypeText {
+				text += data
+			}
+		case thread.ToolResponseData:
+			text += data.Result
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,138 @@
+package llamacpp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/henomis/restclientgo"
+)
+
+const (
+	jsonContentType        = "application/json"
+	eventStreamContentType = "text/event-stream"
+)
+
+type completionRequest struct {
+	Prompt        string   `json:"prompt"`
+	NPredict      int      `json:"n_predict"`
+	Temperature   *float32 `json:"temperature,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	TopP          *float32 `json:"top_p,omitempty"`
+	RepeatPenalty *float32 `json:"repeat_penalty,omitempty"`
+	Seed          *int     `json:"seed,omitempty"`
+	Stop          []string `json:"stop,omitempty"`
+	Grammar       string   `json:"grammar,omitempty"`
+	CachePrompt   bool     `json:"cache_prompt"`
+	Stream        bool     `json:"stream"`
+}
+
+func (r *completionRequest) Path() (string, error) {
+	return "/completion", nil
+}
+
+func (r *completionRequest) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *completionRequest) ContentType() string {
+	return jsonContentType
+}
+
+type completionResponse struct {
+	HTTPStatusCode    int                  `json:"-"`
+	RawBody           []byte               `json:"-"`
+	Headers           restclientgo.Headers `json:"-"`
+	acceptContentType string
+	streamCallbackFn  restclientgo.StreamCallback
+	completion
+}
+
+type completion struct {
+	Content         string `json:"content"`
+	Stop            bool   `json:"stop"`
+	StoppingWord    string `json:"stopping_word"`
+	TokensPredicted int    `json:"tokens_predicted"`
+	TokensEvaluated int    `json:"tokens_evaluated"`
+}
+
+func (r *completionResponse) SetAcceptContentType(contentType string) {
+	r.acceptContentType = contentType
+}
+
+func (r *completionResponse) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(&r.completion)
+}
+
+func (r *completionResponse) SetBody(body io.Reader) error {
+	r.RawBody, _ = io.ReadAll(body)
+	return nil
+}
+
+func (r *completionResponse) AcceptContentType() string {
+	if r.acceptContentType != "" {
+		return r.acceptContentType
+	}
+	return jsonContentType
+}
+
+func (r *completionResponse) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *completionResponse) SetHeaders(headers restclientgo.Headers) error {
+	r.Headers = headers
+	return nil
+}
+
+func (r *completionResponse) SetStreamCallback(fn restclientgo.StreamCallback) {
+	r.streamCallbackFn = fn
+}
+
+func (r *completionResponse) StreamCallback() restclientgo.StreamCallback {
+	return r.streamCallbackFn
+}
+
+type healthRequest struct{}
+
+func (r *healthRequest) Path() (string, error) {
+	return "/health", nil
+}
+
+func (r *healthRequest) Encode() (io.Reader, error) {
+	return nil, nil
+}
+
+func (r *healthRequest) ContentType() string {
+	return ""
+}
+
+type healthResponse struct {
+	HTTPStatusCode int    `json:"-"`
+	Status         string `json:"status"`
+}
+
+func (r *healthResponse) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(r)
+}
+
+func (r *healthResponse) SetBody(_ io.Reader) error {
+	return nil
+}
+
+func (r *healthResponse) AcceptContentType() string {
+	return jsonContentType
+}
+
+func (r *healthResponse) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *healthResponse) SetHeaders(_ restclientgo.Headers) error { return nil }
@@ -0,0 +1,49 @@
+package llamacpp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestServerStream(t *testing.T) {
+	var request completionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/completion" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+
+		w.Header().Set("Content-Type", eventStreamContentType)
+		fmt.Fprint(w, "data: {\"content\":\"Hello\",\"stop\":false}\n\n")
+		fmt.Fprint(w, "data: {\"content\":\" world\",\"stop\":false}\n\n")
+		fmt.Fprint(w, "data: {\"content\":\"\",\"stop\":true,\"stopping_word\":\"</s>\"}\n\n")
+	}))
+	defer server.Close()
+
+	var streamed []string
+	llm := NewServer(server.URL).
+		WithStop([]string{"</s>"}).
+		WithStream(func(s string) { streamed = append(streamed, s) })
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("Hi")))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !request.Stream || request.Stop[0] != "</s>" || request.Prompt != ChatMLPromptFormatter(thread.New().AddMessage(th.Messages[0])) {
+		t.Fatalf("unexpected request %+v", request)
+	}
+	if got := th.LastMessage().Contents[0].AsString(); got != "Hello world" {
+		t.Fatalf("unexpected answer %q", got)
+	}
+	if len(streamed) != 3 || streamed[2] != EOS {
+		t.Fatalf("unexpected stream %q", streamed)
+	}
+}
@@ -1,10 +1,7 @@
 package localai
 
 import (
-	"os"
-
 	"github.com/henomis/lingoose/llm/openai"
-	goopenai "github.com/sashabaranov/go-openai"
 )
 
 type LocalAI struct {
@@ -12,11 +9,7 @@ type LocalAI struct {
 }
 
 func New(endpoint string) *LocalAI {
-	customConfig := goopenai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
-	customConfig.BaseURL = endpoint
-	customClient := goopenai.NewClientWithConfig(customConfig)
-
-	openaillm := openai.New().WithClient(customClient)
+	openaillm := openai.New().WithBaseURL(endpoint)
 	openaillm.Name = "localai"
 	return &LocalAI{
 		OpenAI: openaillm,
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/thread"
+)
+
+// CacheMiddleware returns a middleware answering from the cache the questions similar to
+// a cached one, without calling the LLM, and caching the text answers of the other
+// generations. It works the same way as the WithCache option of the providers, for any
+// LLM.
+func CacheMiddleware(c *cache.Cache) Middleware {
+	return func(next LLM) LLM {
+		return GenerateFunc(func(ctx context.Context, t *thread.Thread) error {
+			if t == nil {
+				return next.Generate(ctx, t)
+			}
+
+			result, err := c.Get(ctx, strings.Join(t.UserQuery(), "\n"))
+			if err == nil {
+				t.AddMessage(thread.NewAssistantMessage().AddContent(
+					thread.NewTextContent(strings.Join(result.Answer, "\n")),
+				))
+				return nil
+			} else if !errors.Is(err, cache.ErrCacheMiss) {
+				return err
+			}
+
+			err = next.Generate(ctx, t)
+			if err != nil {
+				return err
+			}
+
+			answer, ok := textAnswer(t.LastMessage())
+			if !ok {
+				return nil
+			}
+
+			return c.Set(ctx, result.Embedding, answer)
+		})
+	}
+}
+
+// textAnswer returns the text of an assistant message made of text contents only.
+func textAnswer(message *thread.Message) (string, bool) {
+	if message == nil || message.Role != thread.RoleAssistant || len(message.Contents) == 0 {
+		return "", false
+	}
+
+	texts := make([]string, 0, len(message.Contents))
+	for _, content := range message.Contents {
+		if content.Type != thread.ContentTypeText {
+			return "", false
+		}
+		texts = append(texts, content.AsString())
+	}
+
+	return strings.Join(texts, "\n"), true
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/thread"
+)
+
+type constantEmbedder struct{}
+
+func (constantEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	embeddings := make([]embedder.Embedding, len(texts))
+	for i := range texts {
+		embeddings[i] = embedder.Embedding{1, 0, 0}
+	}
+	return embeddings, nil
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	calls := 0
+	llm := Chain(
+		GenerateFunc(func(_ context.Context, t *thread.Thread) error {
+			calls++
+			t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("Rome")))
+			return nil
+		}),
+		CacheMiddleware(cache.New(index.New(jsondb.New(), constantEmbedder{}))),
+	)
+
+	for i := 0; i < 2; i++ {
+		th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("capital of Italy?")))
+		err := llm.Generate(context.Background(), th)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := th.LastMessage().Contents[0].AsString(); got != "Rome" {
+			t.Fatalf("generation %d: expected Rome, got %q", i, got)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected the second answer from the cache, got %d calls", calls)
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+// Middleware wraps an LLM adding a behavior, such as logging, redaction, retries,
+// caching or metrics, the same way for any provider.
+type Middleware func(next LLM) LLM
+
+// GenerateFunc is a function implementing LLM, to write middlewares inline.
+type GenerateFunc func(ctx context.Context, t *thread.Thread) error
+
+func (f GenerateFunc) Generate(ctx context.Context, t *thread.Thread) error {
+	return f(ctx, t)
+}
+
+// Chain wraps llm with the middlewares. The first middleware is the outermost: it sees
+// the generation first and its result last.
+func Chain(llm LLM, middlewares ...Middleware) LLM {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		llm = middlewares[i](llm)
+	}
+
+	return llm
+}
+
+// RetryMiddleware returns a middleware retrying the generations with the policy, see Retry.
+func RetryMiddleware(policy RetryPolicy) Middleware {
+	return func(next LLM) LLM {
+		return Retry(next, policy)
+	}
+}
+
+// FallbackMiddleware returns a middleware degrading with the policy, see Fallback.
+func FallbackMiddleware(policy FallbackPolicy) Middleware {
+	return func(next LLM) LLM {
+		return Fallback(next, policy)
+	}
+}
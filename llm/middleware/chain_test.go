@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next LLM) LLM {
+			return GenerateFunc(func(ctx context.Context, t *thread.Thread) error {
+				calls = append(calls, name+" before")
+				err := next.Generate(ctx, t)
+				calls = append(calls, name+" after")
+				return err
+			})
+		}
+	}
+
+	llm := Chain(&failingLLM{}, trace("outer"), trace("inner"))
+
+	err := llm.Generate(context.Background(), thread.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"outer before", "inner before", "inner after", "outer after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+const (
+	// MetadataProvider is the metadata key of the messages generated through a
+	// FailoverLLM, holding the name of the LLM that generated them.
+	MetadataProvider = "provider"
+)
+
+var (
+	ErrFailover = errors.New("all the LLMs failed")
+)
+
+// FailoverPolicy configures how a FailoverLLM moves from an LLM to the next.
+type FailoverPolicy struct {
+	// Timeout bounds each attempt, an LLM not answering in time is abandoned for the
+	// next one. No limit if zero.
+	Timeout time.Duration
+	// Retryable decides which errors move to the next LLM, all of them if nil. Other
+	// errors, e.g. invalid requests that would fail everywhere, are returned immediately.
+	Retryable func(error) bool
+	// OnFailover is called with the error of each LLM that failed.
+	OnFailover func(ctx context.Context, name string, err error)
+}
+
+type namedLLM struct {
+	name string
+	llm  LLM
+}
+
+// FailoverLLM is an LLM trying its LLMs in order until one succeeds, e.g. OpenAI, then
+// Anthropic, then a local Ollama model, so that a pipeline keeps answering during a
+// provider outage. It is the failover used by Fallback with an Alternate LLM.
+type FailoverLLM struct {
+	llms   []namedLLM
+	policy FailoverPolicy
+}
+
+func Failover(policy FailoverPolicy) *FailoverLLM {
+	return &FailoverLLM{
+		policy: policy,
+	}
+}
+
+// WithLLM appends an LLM to the list. A non empty name is recorded in the
+// MetadataProvider metadata of the messages it generates and in its errors.
+func (f *FailoverLLM) WithLLM(name string, llm LLM) *FailoverLLM {
+	f.llms = append(f.llms, namedLLM{name: name, llm: llm})
+	return f
+}
+
+// Generate generates with the first LLM succeeding. The messages added by a failed LLM
+// are removed from the thread before trying the next one; when all fail the errors are
+// returned joined. Cancelled generations return the context error.
+func (f *FailoverLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	nMessagesBeforeGeneration := len(t.Messages)
+
+	var errs []error
+	for _, l := range f.llms {
+		err := f.generate(ctx, l.llm, t)
+		if err == nil {
+			if l.name != "" {
+				for _, message := range t.Messages[nMessagesBeforeGeneration:] {
+					message.AddMetadata(MetadataProvider, l.name)
+				}
+			}
+			return nil
+		}
+
+		t.Messages = t.Messages[:nMessagesBeforeGeneration]
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if l.name != "" {
+			err = fmt.Errorf("%s: %w", l.name, err)
+		}
+		if f.policy.Retryable != nil && !f.policy.Retryable(err) {
+			return err
+		}
+
+		if f.policy.OnFailover != nil {
+			f.policy.OnFailover(ctx, l.name, err)
+		}
+		errs = append(errs, err)
+	}
+
+	return fmt.Errorf("%w: %w", ErrFailover, errors.Join(errs...))
+}
+
+func (f *FailoverLLM) generate(ctx context.Context, llm LLM, t *thread.Thread) error {
+	if f.policy.Timeout <= 0 {
+		return llm.Generate(ctx, t)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.policy.Timeout)
+	defer cancel()
+
+	return llm.Generate(ctx, t)
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+type slowLLM struct {
+	err   error
+	delay time.Duration
+}
+
+func (s *slowLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("partial")))
+
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+
+	t.LastMessage().Contents[0].Data = "answer"
+	return nil
+}
+
+func TestFailover(t *testing.T) {
+	var failed []string
+	llm := Failover(FailoverPolicy{
+		Timeout: 50 * time.Millisecond,
+		OnFailover: func(_ context.Context, name string, _ error) {
+			failed = append(failed, name)
+		},
+	}).
+		WithLLM("down", &slowLLM{err: errors.New("503")}).
+		WithLLM("slow", &slowLLM{delay: time.Second}).
+		WithLLM("local", &slowLLM{})
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(th.Messages) != 2 || th.LastMessage().Contents[0].AsString() != "answer" {
+		t.Fatalf("unexpected thread %s", th)
+	}
+	if provider := th.LastMessage().Metadata[MetadataProvider]; provider != "local" {
+		t.Errorf("provider = %v, want local", provider)
+	}
+	if len(failed) != 2 || failed[0] != "down" || failed[1] != "slow" {
+		t.Errorf("failed = %v, want [down slow]", failed)
+	}
+
+	err = Failover(FailoverPolicy{}).WithLLM("down", &slowLLM{err: errors.New("503")}).
+		Generate(context.Background(), th)
+	if !errors.Is(err, ErrFailover) {
+		t.Errorf("expected ErrFailover, got %v", err)
+	}
+}
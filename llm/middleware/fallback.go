@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+const (
+	// MetadataFallback is the metadata key of the fallback answers, holding the FallbackReason.
+	MetadataFallback = "fallback"
+	// MetadataEscalate marks the fallback answers that should be handed over to a human.
+	MetadataEscalate = "escalate"
+)
+
+// ErrBlocked is the error guards wrap when they block an input or an output.
+var ErrBlocked = errors.New("blocked by guard")
+
+type FallbackReason string
+
+const (
+	FallbackReasonBlocked FallbackReason = "blocked"
+	FallbackReasonFailed  FallbackReason = "failed"
+)
+
+// FallbackPolicy configures how a FallbackLLM degrades. An empty response returns the
+// error instead of answering.
+type FallbackPolicy struct {
+	// Alternate is tried when the LLM fails, e.g. a model of another provider. It is not
+	// tried when a guard blocks the generation.
+	Alternate LLM
+	// BlockedResponse is the answer given when a guard blocks the generation.
+	BlockedResponse string
+	// FailedResponse is the answer given when all the LLMs fail.
+	FailedResponse string
+	// Escalate marks the fallback answers with MetadataEscalate, so that the
+	// application can hand the conversation over to a human.
+	Escalate bool
+	// Blocked decides whether an error comes from a guard, errors.Is(err, ErrBlocked) if nil.
+	Blocked func(error) bool
+	// OnFallback is called with the error that triggered the fallback.
+	OnFallback func(ctx context.Context, reason FallbackReason, err error)
+}
+
+// FallbackLLM answers with a canned response instead of returning an error when the
+// generation is blocked or fails.
+type FallbackLLM struct {
+	llm    LLM
+	policy FallbackPolicy
+}
+
+// Fallback wraps llm so that the assistants using it degrade gracefully instead of
+// returning raw errors to the end users. Cancelled generations still return their error.
+func Fallback(llm LLM, policy FallbackPolicy) *FallbackLLM {
+	if policy.Blocked == nil {
+		policy.Blocked = func(err error) bool {
+			return errors.Is(err, ErrBlocked)
+		}
+	}
+
+	return &FallbackLLM{
+		llm:    llm,
+		policy: policy,
+	}
+}
+
+func (f *FallbackLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	nMessageBeforeGeneration := len(t.Messages)
+
+	llm := f.llm
+	if f.policy.Alternate != nil {
+		// blocked generations are not tried again with the alternate LLM
+		llm = Failover(FailoverPolicy{
+			Retryable: func(err error) bool {
+				return !f.policy.Blocked(err)
+			},
+		}).WithLLM("", f.llm).WithLLM("", f.policy.Alternate)
+	}
+
+	err := llm.Generate(ctx, t)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	reason := FallbackReasonFailed
+	if f.policy.Blocked(err) {
+		reason = FallbackReasonBlocked
+	}
+
+	if f.policy.OnFallback != nil {
+		f.policy.OnFallback(ctx, reason, err)
+	}
+
+	response := f.policy.FailedResponse
+	if reason == FallbackReasonBlocked {
+		response = f.policy.BlockedResponse
+	}
+	if response == "" {
+		return err
+	}
+
+	t.Messages = t.Messages[:nMessageBeforeGeneration]
+	answer := thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(response),
+	).AddMetadata(MetadataFallback, reason)
+	if f.policy.Escalate {
+		answer.AddMetadata(MetadataEscalate, true)
+	}
+	t.AddMessage(answer)
+
+	return nil
+}
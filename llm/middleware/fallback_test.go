@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestFallback(t *testing.T) {
+	failed := errors.New("provider down")
+	blocked := fmt.Errorf("%w: unsafe content", ErrBlocked)
+
+	tests := []struct {
+		name         string
+		llm          LLM
+		alternate    LLM
+		wantAnswer   string
+		wantReason   FallbackReason
+		wantAltCalls int
+	}{
+		{"ok", &failingLLM{}, &failingLLM{}, "answer", "", 0},
+		{"alternate", &failingLLM{errs: []error{failed}}, &failingLLM{}, "answer", "", 1},
+		{"failed", &failingLLM{errs: []error{failed}}, &failingLLM{errs: []error{failed}}, "try later", FallbackReasonFailed, 1},
+		{"blocked", &failingLLM{errs: []error{blocked}}, &failingLLM{}, "can't help", FallbackReasonBlocked, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reasons []FallbackReason
+			llm := Fallback(tt.llm, FallbackPolicy{
+				Alternate:       tt.alternate,
+				BlockedResponse: "can't help",
+				FailedResponse:  "try later",
+				Escalate:        true,
+				OnFallback: func(_ context.Context, reason FallbackReason, _ error) {
+					reasons = append(reasons, reason)
+				},
+			})
+
+			th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+			err := llm.Generate(context.Background(), th)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			answer := th.LastMessage()
+			if len(th.Messages) != 2 || answer.Contents[0].AsString() != tt.wantAnswer {
+				t.Errorf("thread = %s, want the answer %q", th, tt.wantAnswer)
+			}
+			if tt.wantReason != "" && (answer.Metadata[MetadataFallback] != tt.wantReason || answer.Metadata[MetadataEscalate] != true) {
+				t.Errorf("metadata = %v, want reason %s and escalation", answer.Metadata, tt.wantReason)
+			}
+			if tt.wantReason != "" && len(reasons) != 1 {
+				t.Errorf("OnFallback called %d times, want 1", len(reasons))
+			}
+			if calls := tt.alternate.(*failingLLM).calls; calls != tt.wantAltCalls {
+				t.Errorf("alternate calls = %d, want %d", calls, tt.wantAltCalls)
+			}
+		})
+	}
+}
+
+func TestFallback_NoResponse(t *testing.T) {
+	failed := errors.New("provider down")
+	err := Fallback(&failingLLM{errs: []error{failed}}, FallbackPolicy{}).Generate(context.Background(), thread.New())
+	if !errors.Is(err, failed) {
+		t.Errorf("Generate() error = %v, want %v", err, failed)
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/henomis/lingoose/logger"
+	"github.com/henomis/lingoose/thread"
+)
+
+// LoggingMiddleware returns a middleware logging each generation at debug level: the
+// size of the thread, the duration and the size of the answer. A nil logger uses the
+// default logger of the logger package.
+func LoggingMiddleware(l *slog.Logger) Middleware {
+	return func(next LLM) LLM {
+		return GenerateFunc(func(ctx context.Context, t *thread.Thread) error {
+			log := logger.Or(l)
+			if !log.Enabled(ctx, slog.LevelDebug) || t == nil {
+				return next.Generate(ctx, t)
+			}
+
+			nMessageBeforeGeneration := len(t.Messages)
+			inputChars := threadChars(t.Messages)
+
+			start := time.Now()
+			err := next.Generate(ctx, t)
+
+			attrs := []any{
+				slog.Int("messages", nMessageBeforeGeneration),
+				slog.Int("input_chars", inputChars),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				log.DebugContext(ctx, "llm generation failed", append(attrs, slog.Any("error", err))...)
+				return err
+			}
+
+			var answer []*thread.Message
+			if len(t.Messages) > nMessageBeforeGeneration {
+				answer = t.Messages[nMessageBeforeGeneration:]
+			}
+			log.DebugContext(ctx, "llm generation", append(attrs,
+				slog.Int("output_messages", len(answer)),
+				slog.Int("output_chars", threadChars(answer)),
+			)...)
+
+			return nil
+		})
+	}
+}
+
+func threadChars(messages []*thread.Message) int {
+	chars := 0
+	for _, message := range messages {
+		for _, content := range message.Contents {
+			switch data := content.Data.(type) {
+			case string:
+				if content.Type == thread.ContentTypeText {
+					chars += len(data)
+				}
+			case []thread.ToolCallData:
+				for _, toolCall := range data {
+					chars += len(toolCall.Arguments)
+				}
+			case thread.ToolResponseData:
+				chars += len(data.Result)
+			}
+		}
+	}
+
+	return chars
+}
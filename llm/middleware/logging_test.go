@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/logger"
+	"github.com/henomis/lingoose/thread"
+)
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var record map[string]any
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	rateLimited := httperror.New(http.StatusTooManyRequests, nil)
+	rateLimited.RetryAfter = time.Millisecond
+
+	llm := Chain(&failingLLM{errs: []error{rateLimited}},
+		LoggingMiddleware(log),
+		RetryMiddleware(RetryPolicy{MaxRetries: 1, Logger: log}),
+	)
+
+	err := llm.Generate(context.Background(), thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("hello")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("records = %v, want the retry and the generation", records)
+	}
+
+	if records[0]["msg"] != "llm retry" || records[0]["attempt"] != float64(1) {
+		t.Errorf("retry record = %v", records[0])
+	}
+
+	generation := records[1]
+	if generation["msg"] != "llm generation" || generation["messages"] != float64(1) ||
+		generation["input_chars"] != float64(len("hello")) || generation["output_chars"] != float64(len("answer")) {
+		t.Errorf("generation record = %v", generation)
+	}
+}
+
+func TestLoggingMiddlewareDefaultLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer logger.SetDefault(nil)
+
+	llm := Chain(&failingLLM{}, LoggingMiddleware(nil))
+	err := llm.Generate(context.Background(), thread.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if records := logRecords(t, &buf); len(records) != 1 {
+		t.Errorf("records = %v, want 1", records)
+	}
+
+	logger.SetDefault(nil)
+	buf.Reset()
+
+	err = llm.Generate(context.Background(), thread.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("logged %q with logging disabled", buf.String())
+	}
+}
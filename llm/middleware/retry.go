@@ -0,0 +1,88 @@
+// Package middleware provides wrappers adding behaviors to any LLM.
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	goopenai "github.com/sashabaranov/go-openai"
+
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/llm/retry"
+	"github.com/henomis/lingoose/thread"
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// RetryPolicy configures the retries, see retry.Policy.
+type RetryPolicy = retry.Policy
+
+// DefaultRetryPolicy retries 3 times starting from 500ms, doubling the delay up to 30s.
+func DefaultRetryPolicy() RetryPolicy {
+	return retry.DefaultPolicy()
+}
+
+// RetryLLM retries the generations of an LLM failing with a transient error.
+type RetryLLM struct {
+	llm    LLM
+	policy RetryPolicy
+}
+
+// Retry wraps llm retrying on rate limits (429), server errors (5xx) and timeouts with a
+// jittered exponential backoff. When the provider suggests a delay with the Retry-After
+// header, it's used instead.
+func Retry(llm LLM, policy RetryPolicy) *RetryLLM {
+	if policy.Retryable == nil {
+		policy.Retryable = DefaultRetryable
+	}
+	if policy.Name == "" {
+		policy.Name = "llm"
+	}
+
+	return &RetryLLM{
+		llm:    llm,
+		policy: policy,
+	}
+}
+
+func (r *RetryLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	nMessageBeforeGeneration := 0
+	if t != nil {
+		nMessageBeforeGeneration = len(t.Messages)
+	}
+
+	return retry.Do(ctx, r.policy, func(ctx context.Context) error {
+		if t != nil {
+			// drop what a failed attempt may have added
+			t.Messages = t.Messages[:nMessageBeforeGeneration]
+		}
+
+		return r.llm.Generate(ctx, t)
+	})
+}
+
+// DefaultRetryable reports whether err is a rate limit, a server error or a timeout,
+// including the errors of the go-openai client.
+func DefaultRetryable(err error) bool {
+	if _, ok := httperror.As(err); ok {
+		return retry.Temporary(err)
+	}
+
+	var apiErr *goopenai.APIError
+	if errors.As(err, &apiErr) {
+		return isTemporaryStatus(apiErr.HTTPStatusCode)
+	}
+
+	var requestErr *goopenai.RequestError
+	if errors.As(err, &requestErr) {
+		return isTemporaryStatus(requestErr.HTTPStatusCode)
+	}
+
+	return retry.Temporary(err)
+}
+
+func isTemporaryStatus(statusCode int) bool {
+	return httperror.New(statusCode, nil).Temporary()
+}
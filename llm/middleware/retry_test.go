@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/thread"
+)
+
+type failingLLM struct {
+	errs  []error
+	calls int
+}
+
+func (f *failingLLM) Generate(_ context.Context, t *thread.Thread) error {
+	f.calls++
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("answer")))
+	if len(f.errs) == 0 {
+		return nil
+	}
+
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func TestRetry(t *testing.T) {
+	rateLimited := httperror.New(http.StatusTooManyRequests, []byte("slow down"))
+	rateLimited.RetryAfter = time.Millisecond
+
+	llm := &failingLLM{errs: []error{
+		rateLimited,
+		httperror.New(http.StatusBadGateway, nil),
+	}}
+
+	var retries []uint
+	policy := DefaultRetryPolicy()
+	policy.InitialDelay = time.Millisecond
+	policy.OnRetry = func(attempt uint, _ error, _ time.Duration) {
+		retries = append(retries, attempt)
+	}
+
+	th := thread.New()
+	err := Retry(llm, policy).Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.calls != 3 || len(retries) != 2 {
+		t.Fatalf("unexpected calls %d, retries %v", llm.calls, retries)
+	}
+	if len(th.Messages) != 1 {
+		t.Fatalf("failed attempts left %d messages", len(th.Messages))
+	}
+}
+
+func TestRetry_NotRetryable(t *testing.T) {
+	badRequest := httperror.New(http.StatusBadRequest, []byte("invalid model"))
+	llm := &failingLLM{errs: []error{badRequest}}
+
+	err := Retry(llm, DefaultRetryPolicy()).Generate(context.Background(), thread.New())
+	if !errors.Is(err, badRequest) || llm.calls != 1 {
+		t.Fatalf("unexpected err %v after %d calls", err, llm.calls)
+	}
+}
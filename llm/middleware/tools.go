@@ -0,0 +1,315 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/henomis/lingoose/jsonrepair"
+	"github.com/henomis/lingoose/llm/function"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultToolIterations = 1
+	toolResultPrefix      = "Tool result"
+
+	//nolint:lll
+	toolEmulationPrompt = `You can call the following tools:
+{{range .tools}}- {{.name}}: {{.description}}
+  Parameters JSON schema: {{.parameters}}
+{{end}}
+To call one or more tools, answer only with a JSON object like {"tool_calls": [{"name": "<tool name>", "arguments": {<arguments>}}]}, without any other text. Otherwise answer the user normally. Tool results are given in messages starting with "` + toolResultPrefix + `".`
+)
+
+var ErrToolEmulation = errors.New("tool emulation error")
+
+// ToolEmulatorLLM gives function calling to LLMs without native tools support, such as
+// local GGUF models: the tool schemas are described in the system prompt and the JSON
+// tool calls written by the model are parsed and executed. The tool calls and results
+// are added to the thread as with native tools, so the same agents run on any LLM.
+type ToolEmulatorLLM struct {
+	llm           LLM
+	functions     map[string]function.Function
+	maxIterations int
+}
+
+// EmulateTools wraps llm emulating function calling.
+func EmulateTools(llm LLM) *ToolEmulatorLLM {
+	return &ToolEmulatorLLM{
+		llm:           llm,
+		functions:     make(map[string]function.Function),
+		maxIterations: defaultToolIterations,
+	}
+}
+
+func (e *ToolEmulatorLLM) WithTools(tools ...function.Tool) *ToolEmulatorLLM {
+	for _, tool := range tools {
+		fn, err := function.NewFromTool(tool)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		e.functions[tool.Name()] = *fn
+	}
+
+	return e
+}
+
+func (e *ToolEmulatorLLM) BindFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...function.ParameterOption,
+) error {
+	f, err := function.New(fn, name, description, functionParameterOptions...)
+	if err != nil {
+		return err
+	}
+
+	e.functions[name] = *f
+
+	return nil
+}
+
+// WithMaxIterations sets how many rounds of tool calls Generate runs before returning.
+// By default Generate returns after the first round, like the LLMs with native tools,
+// leaving the loop to the caller (e.g. the assistant).
+func (e *ToolEmulatorLLM) WithMaxIterations(maxIterations int) *ToolEmulatorLLM {
+	e.maxIterations = maxIterations
+	return e
+}
+
+func (e *ToolEmulatorLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	if len(e.functions) == 0 {
+		return e.llm.Generate(ctx, t)
+	}
+
+	for iteration := 0; iteration < max(e.maxIterations, 1); iteration++ {
+		promptThread, err := e.promptThread(t)
+		if err != nil {
+			return err
+		}
+
+		err = e.llm.Generate(ctx, promptThread)
+		if err != nil {
+			return err
+		}
+
+		answer := promptThread.LastMessage()
+		if answer.Role != thread.RoleAssistant {
+			return fmt.Errorf("%w: no answer generated", ErrToolEmulation)
+		}
+
+		toolCalls := e.parseToolCalls(textOf(answer))
+		if len(toolCalls) == 0 {
+			t.AddMessage(answer)
+			return nil
+		}
+
+		t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewToolCallContent(toolCalls)))
+		for _, toolCall := range toolCalls {
+			value, result, toolErr := e.callTool(ctx, toolCall)
+			t.AddMessage(thread.NewToolMessage().AddContent(thread.NewToolResponseContent(
+				thread.ToolResponseData{
+					ID:     toolCall.ID,
+					Name:   toolCall.Name,
+					Result: result,
+					Value:  value,
+					Err:    toolErr,
+				},
+			)))
+		}
+	}
+
+	return nil
+}
+
+func (e *ToolEmulatorLLM) callTool(ctx context.Context, toolCall thread.ToolCallData) (any, string, error) {
+	// skip pending tool calls if the generation has been cancelled
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Sprintf("error: %s", err), err
+	}
+
+	fn := e.functions[toolCall.Name]
+	value, result, err := fn.CallValue(toolCall.Arguments)
+	if err != nil {
+		return nil, fmt.Sprintf("error: %s", err), err
+	}
+
+	return value, result, nil
+}
+
+// promptThread returns the thread sent to the LLM: the tools are described in the system
+// prompt, tool calls and results are turned into text messages.
+func (e *ToolEmulatorLLM) promptThread(t *thread.Thread) (*thread.Thread, error) {
+	toolsPrompt, err := e.toolsPrompt()
+	if err != nil {
+		return nil, err
+	}
+
+	promptThread := thread.New()
+	messages := t.Messages
+	if len(messages) > 0 && messages[0].Role == thread.RoleSystem {
+		toolsPrompt = textOf(messages[0]) + "\n\n" + toolsPrompt
+		messages = messages[1:]
+	}
+	promptThread.AddMessage(thread.NewSystemMessage().AddContent(thread.NewTextContent(toolsPrompt)))
+
+	for _, message := range messages {
+		promptThread.AddMessage(toolMessageAsText(message))
+	}
+
+	return promptThread, nil
+}
+
+func (e *ToolEmulatorLLM) toolsPrompt() (string, error) {
+	names := make([]string, 0, len(e.functions))
+	for name := range e.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tools := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		parameters, err := json.Marshal(e.functions[name].Parameters)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrToolEmulation, err)
+		}
+
+		tools = append(tools, map[string]any{
+			"name":        name,
+			"description": e.functions[name].Description,
+			"parameters":  string(parameters),
+		})
+	}
+
+	return thread.NewTextContent(toolEmulationPrompt).Format(types.M{"tools": tools}).AsString(), nil
+}
+
+// toolMessageAsText renders the tool calls as the JSON the model is asked to write and
+// the tool results as user messages.
+func toolMessageAsText(message *thread.Message) *thread.Message {
+	var calls []emulatedToolCall
+	var results []string
+	for _, content := range message.Contents {
+		switch content.Type {
+		case thread.ContentTypeToolCall:
+			for _, toolCall := range content.AsToolCallData() {
+				arguments := json.RawMessage(toolCall.Arguments)
+				if !json.Valid(arguments) {
+					arguments = json.RawMessage("{}")
+				}
+				calls = append(calls, emulatedToolCall{Name: toolCall.Name, Arguments: arguments})
+			}
+		case thread.ContentTypeToolResponse:
+			if toolResponse := content.AsToolResponseData(); toolResponse != nil {
+				results = append(results, fmt.Sprintf("%s %s: %s", toolResultPrefix, toolResponse.Name, toolResponse.Result))
+			}
+		}
+	}
+
+	switch {
+	case len(calls) > 0:
+		//nolint:errchkjson
+		data, _ := json.Marshal(emulatedToolCalls{ToolCalls: calls})
+		return thread.NewAssistantMessage().AddContent(thread.NewTextContent(string(data)))
+	case len(results) > 0:
+		return thread.NewUserMessage().AddContent(thread.NewTextContent(strings.Join(results, "\n")))
+	default:
+		return message
+	}
+}
+
+type emulatedToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type emulatedToolCalls struct {
+	ToolCalls []emulatedToolCall `json:"tool_calls"`
+}
+
+// parseToolCalls finds the tool calls in the model answer. Models often wrap the JSON in
+// code fences or text, call a single tool without the tool_calls list or encode the
+// arguments as a string, so the first JSON value naming a known tool is accepted.
+func (e *ToolEmulatorLLM) parseToolCalls(text string) []thread.ToolCallData {
+	for start := strings.IndexAny(text, "{["); start >= 0; {
+		var value json.RawMessage
+		err := json.NewDecoder(strings.NewReader(text[start:])).Decode(&value)
+		if err != nil {
+			// weaker models write trailing commas, single quotes or truncated objects
+			repaired, errRepair := jsonrepair.Repair(text[start:])
+			value, err = json.RawMessage(repaired), errRepair
+		}
+		if err == nil {
+			if toolCalls := e.decodeToolCalls(value); len(toolCalls) > 0 {
+				return toolCalls
+			}
+		}
+
+		next := strings.IndexAny(text[start+1:], "{[")
+		if next < 0 {
+			break
+		}
+		start += next + 1
+	}
+
+	return nil
+}
+
+func (e *ToolEmulatorLLM) decodeToolCalls(value json.RawMessage) []thread.ToolCallData {
+	var calls []emulatedToolCall
+
+	var wrapped emulatedToolCalls
+	var single emulatedToolCall
+	switch {
+	case json.Unmarshal(value, &wrapped) == nil && len(wrapped.ToolCalls) > 0:
+		calls = wrapped.ToolCalls
+	case json.Unmarshal(value, &calls) == nil:
+	case json.Unmarshal(value, &single) == nil && single.Name != "":
+		calls = []emulatedToolCall{single}
+	}
+
+	var toolCalls []thread.ToolCallData
+	for _, call := range calls {
+		if _, ok := e.functions[call.Name]; !ok {
+			return nil
+		}
+
+		arguments := string(call.Arguments)
+		var encoded string
+		if json.Unmarshal(call.Arguments, &encoded) == nil {
+			arguments = encoded
+		}
+		if strings.TrimSpace(arguments) == "" || arguments == "null" {
+			arguments = "{}"
+		}
+
+		toolCalls = append(toolCalls, thread.ToolCallData{
+			ID:        uuid.New().String(),
+			Name:      call.Name,
+			Arguments: arguments,
+		})
+	}
+
+	return toolCalls
+}
+
+func textOf(message *thread.Message) string {
+	var text string
+	for _, content := range message.Contents {
+		if content.Type == thread.ContentTypeText {
+			text += content.AsString()
+		}
+	}
+
+	return text
+}
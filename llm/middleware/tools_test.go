@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+type scriptedLLM struct {
+	answers []string
+	prompts []*thread.Thread
+}
+
+func (s *scriptedLLM) Generate(_ context.Context, t *thread.Thread) error {
+	s.prompts = append(s.prompts, t)
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent(s.answers[0])))
+	s.answers = s.answers[1:]
+	return nil
+}
+
+type weatherInput struct {
+	City string `json:"city"`
+}
+
+func TestToolEmulator(t *testing.T) {
+	llm := &scriptedLLM{answers: []string{
+		"Sure!\n```json\n{\"name\": \"weather\", \"arguments\": \"{\\\"city\\\": \\\"Rome\\\"}\"}\n```",
+		"It's sunny in Rome.",
+	}}
+
+	emulator := EmulateTools(llm).WithMaxIterations(2)
+	err := emulator.BindFunction(func(input weatherInput) string {
+		return "sunny in " + input.City
+	}, "weather", "Get the weather of a city")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("Weather in Rome?")))
+	err = emulator.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(th.Messages) != 4 {
+		t.Fatalf("expected 4 messages, got %s", th)
+	}
+	toolCalls := th.Messages[1].Contents[0].AsToolCallData()
+	if len(toolCalls) != 1 || toolCalls[0].Name != "weather" || toolCalls[0].Arguments != `{"city": "Rome"}` {
+		t.Fatalf("unexpected tool calls %+v", toolCalls)
+	}
+	if result := th.Messages[2].Contents[0].AsToolResponseData().Result; result != `"sunny in Rome"` {
+		t.Fatalf("unexpected tool result %s", result)
+	}
+	if th.LastMessage().Contents[0].AsString() != "It's sunny in Rome." {
+		t.Fatalf("unexpected answer %s", th.LastMessage().Contents[0].AsString())
+	}
+
+	second := llm.prompts[1]
+	if !strings.Contains(second.Messages[0].Contents[0].AsString(), "weather: Get the weather of a city") {
+		t.Errorf("tools not described in the system prompt: %s", second.Messages[0].Contents[0].AsString())
+	}
+	if got := second.Messages[3].Contents[0].AsString(); got != `Tool result weather: "sunny in Rome"` {
+		t.Errorf("unexpected tool result message %q", got)
+	}
+}
+
+func TestToolEmulatorRepairsToolCalls(t *testing.T) {
+	llm := &scriptedLLM{answers: []string{
+		"{'name': 'weather', arguments: {city: 'Rome',},",
+	}}
+
+	emulator := EmulateTools(llm)
+	err := emulator.BindFunction(func(input weatherInput) string {
+		return "sunny in " + input.City
+	}, "weather", "Get the weather of a city")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("Weather in Rome?")))
+	err = emulator.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	toolCalls := th.Messages[1].Contents[0].AsToolCallData()
+	if len(toolCalls) != 1 || toolCalls[0].Name != "weather" || toolCalls[0].Arguments != `{"city":"Rome"}` {
+		t.Fatalf("unexpected tool calls %+v", toolCalls)
+	}
+	if result := th.Messages[2].Contents[0].AsToolResponseData().Result; result != `"sunny in Rome"` {
+		t.Fatalf("unexpected tool result %s", result)
+	}
+}
@@ -0,0 +1,52 @@
+// Package mistral provides the Mistral AI LLM through its OpenAI compatible API.
+package mistral
+
+import (
+	"os"
+
+	"github.com/henomis/lingoose/llm/openai"
+)
+
+const (
+	defaultEndpoint = "https://api.mistral.ai/v1"
+)
+
+const (
+	ModelMistralLarge  openai.Model = "mistral-large-latest"
+	ModelMistralMedium openai.Model = "mistral-medium-latest"
+	ModelMistralSmall  openai.Model = "mistral-small-latest"
+	ModelOpenMistral7B openai.Model = "open-mistral-7b"
+	ModelOpenMixtral   openai.Model = "open-mixtral-8x7b"
+	ModelOpenMixtral22 openai.Model = "open-mixtral-8x22b"
+	ModelCodestral     openai.Model = "codestral-latest"
+)
+
+type Mistral struct {
+	*openai.OpenAI
+}
+
+// New creates a Mistral AI LLM using the Mistral Small model. The API key is read from
+// the MISTRAL_API_KEY environment variable.
+func New() *Mistral {
+	openaillm := openai.New().
+		WithBaseURL(defaultEndpoint).
+		WithAPIKey(os.Getenv("MISTRAL_API_KEY")).
+		WithModel(ModelMistralSmall)
+	openaillm.Name = "mistral"
+
+	return &Mistral{
+		OpenAI: openaillm,
+	}
+}
+
+// WithSafePrompt injects the Mistral safety prompt before the conversation.
+func (m *Mistral) WithSafePrompt(safePrompt bool) *Mistral {
+	m.OpenAI.WithBodyField("safe_prompt", safePrompt)
+	return m
+}
+
+// WithRandomSeed sets the seed of the sampling, Mistral ignores the OpenAI seed field.
+func (m *Mistral) WithRandomSeed(seed int) *Mistral {
+	m.OpenAI.WithBodyField("random_seed", seed)
+	return m
+}
@@ -0,0 +1,84 @@
+package mistral
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestGenerateSendsMistralFields(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"bonjour"}}]}`))
+	}))
+	defer server.Close()
+
+	llm := New().WithSafePrompt(true).WithRandomSeed(42)
+	llm.WithBaseURL(server.URL).WithModel(ModelMistralLarge)
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body["safe_prompt"] != true || body["random_seed"] != float64(42) || body["model"] != string(ModelMistralLarge) {
+		t.Fatalf("unexpected request body %v", body)
+	}
+	if got := th.LastMessage().Contents[0].AsString(); got != "bonjour" {
+		t.Fatalf("unexpected answer %q", got)
+	}
+}
+
+func TestGenerateToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Tools []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tools"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Tools) != 1 || req.Tools[0].Function.Name != "weather" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"finish_reason":"tool_calls","message":{"role":"assistant","tool_calls":[` +
+			`{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Rome\"}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	type weatherInput struct {
+		City string `json:"city"`
+	}
+
+	llm := New()
+	llm.WithBaseURL(server.URL)
+	err := llm.BindFunction(func(input weatherInput) string { return "sunny in " + input.City }, "weather", "get the weather")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("weather in Rome?")))
+	err = llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := th.LastMessage()
+	if last.Role != thread.RoleTool {
+		t.Fatalf("expected a tool message, got %s", last.Role)
+	}
+	if result := last.Contents[0].Data.(thread.ToolResponseData).Result; result != `"sunny in Rome"` {
+		t.Fatalf("unexpected tool result %q", result)
+	}
+}
@@ -0,0 +1,320 @@
+package llmmock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/henomis/lingoose/llm/function"
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/llm/usagereport"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	EOS = "\x00"
+)
+
+var (
+	ErrNoResponse = errors.New("no response enqueued")
+)
+
+type StreamCallbackFn func(string)
+
+type UsageCallback func(types.Meta)
+
+// Response is a canned LLM response. The answer is the text, or the tool calls if any;
+// Chunks are the streamed pieces of the text. When Err is set the generation fails
+// after Delay without answering.
+type Response struct {
+	Text      string
+	Chunks    []string
+	ToolCalls []thread.ToolCallData
+	Err       error
+	Delay     time.Duration
+	Usage     types.Meta
+}
+
+// Mock is a programmable LLM for deterministic tests, without network access. Each
+// Generate call consumes the next enqueued response, in order, and records the thread
+// it was called with.
+type Mock struct {
+	mu               sync.Mutex
+	responses        []Response
+	requests         []*thread.Thread
+	functions        map[string]function.Function
+	streamCallbackFn StreamCallbackFn
+	usageCallback    UsageCallback
+	chunkDelay       time.Duration
+	toolCalls        int
+}
+
+func New() *Mock {
+	return &Mock{
+		functions: make(map[string]function.Function),
+	}
+}
+
+// AddResponse enqueues a response.
+func (m *Mock) AddResponse(response Response) *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.responses = append(m.responses, response)
+	return m
+}
+
+// AddText enqueues a text answer.
+func (m *Mock) AddText(text string) *Mock {
+	return m.AddResponse(Response{Text: text})
+}
+
+// AddChunks enqueues a text answer streamed in the given chunks.
+func (m *Mock) AddChunks(chunks ...string) *Mock {
+	return m.AddResponse(Response{Text: strings.Join(chunks, ""), Chunks: chunks})
+}
+
+// AddToolCall enqueues an answer calling the named tool with the arguments, a JSON
+// string or any value encoded as JSON.
+func (m *Mock) AddToolCall(name string, arguments any) *Mock {
+	argumentsAsJSON, ok := arguments.(string)
+	if !ok {
+		data, err := json.Marshal(arguments)
+		if err != nil {
+			return m.AddError(err)
+		}
+		argumentsAsJSON = string(data)
+	}
+
+	m.mu.Lock()
+	m.toolCalls++
+	id := fmt.Sprintf("call_%d", m.toolCalls)
+	m.mu.Unlock()
+
+	return m.AddResponse(Response{ToolCalls: []thread.ToolCallData{{
+		ID:        id,
+		Name:      name,
+		Arguments: argumentsAsJSON,
+	}}})
+}
+
+// AddError enqueues a failed generation.
+func (m *Mock) AddError(err error) *Mock {
+	return m.AddResponse(Response{Err: err})
+}
+
+// AddRateLimit enqueues a generation failing with a 429 httperror.Error suggesting to
+// retry after the given delay, as returned by the providers.
+func (m *Mock) AddRateLimit(retryAfter time.Duration) *Mock {
+	err := httperror.New(http.StatusTooManyRequests, []byte(`{"error":"rate limit exceeded"}`)).
+		WithRetryAfter(strconv.FormatFloat(retryAfter.Seconds(), 'f', -1, 64))
+
+	return m.AddError(err)
+}
+
+// WithStream streams the text answers to the callback, ending the stream with EOS.
+func (m *Mock) WithStream(callbackFn StreamCallbackFn) *Mock {
+	m.streamCallbackFn = callbackFn
+	return m
+}
+
+// WithChunkDelay waits the given delay before each streamed chunk, to simulate the
+// timing of a real stream.
+func (m *Mock) WithChunkDelay(chunkDelay time.Duration) *Mock {
+	m.chunkDelay = chunkDelay
+	return m
+}
+
+func (m *Mock) WithUsageCallback(callback UsageCallback) *Mock {
+	m.usageCallback = callback
+	return m
+}
+
+// WithTools binds the tools, they are called when a response has tool calls.
+func (m *Mock) WithTools(tools ...function.Tool) *Mock {
+	for _, tool := range tools {
+		fn, err := function.NewFromTool(tool)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		m.functions[tool.Name()] = *fn
+	}
+
+	return m
+}
+
+func (m *Mock) BindFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...function.ParameterOption,
+) error {
+	f, err := function.New(fn, name, description, functionParameterOptions...)
+	if err != nil {
+		return err
+	}
+
+	m.functions[name] = *f
+
+	return nil
+}
+
+// Requests returns a copy of the threads of the Generate calls, in order.
+func (m *Mock) Requests() []*thread.Thread {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*thread.Thread{}, m.requests...)
+}
+
+// Pending returns the number of responses not consumed yet.
+func (m *Mock) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.responses)
+}
+
+func (m *Mock) Generate(ctx context.Context, t *thread.Thread) error {
+	if t == nil {
+		return nil
+	}
+
+	response, err := m.next(t)
+	if err != nil {
+		return err
+	}
+
+	err = sleep(ctx, response.Delay)
+	if err != nil {
+		return err
+	}
+
+	if response.Err != nil {
+		return response.Err
+	}
+
+	if response.Usage != nil {
+		usagereport.Report(ctx, response.Usage)
+		if m.usageCallback != nil {
+			m.usageCallback(response.Usage)
+		}
+	}
+
+	if len(response.ToolCalls) > 0 {
+		t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewToolCallContent(response.ToolCalls)))
+		t.AddMessages(m.callTools(ctx, response.ToolCalls)...)
+		return nil
+	}
+
+	if m.streamCallbackFn != nil {
+		err = m.stream(ctx, response)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent(response.Text)))
+
+	return nil
+}
+
+func (m *Mock) next(t *thread.Thread) (Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, thread.New().AddMessages(t.Messages...))
+
+	if len(m.responses) == 0 {
+		return Response{}, ErrNoResponse
+	}
+
+	response := m.responses[0]
+	m.responses = m.responses[1:]
+
+	return response, nil
+}
+
+func (m *Mock) stream(ctx context.Context, response Response) error {
+	chunks := response.Chunks
+	if len(chunks) == 0 {
+		chunks = []string{response.Text}
+	}
+
+	for _, chunk := range chunks {
+		err := sleep(ctx, m.chunkDelay)
+		if err != nil {
+			return err
+		}
+
+		m.streamCallbackFn(chunk)
+	}
+
+	m.streamCallbackFn(EOS)
+
+	return nil
+}
+
+func (m *Mock) callTools(ctx context.Context, toolCalls []thread.ToolCallData) []*thread.Message {
+	if len(m.functions) == 0 {
+		return nil
+	}
+
+	var messages []*thread.Message
+	for _, toolCall := range toolCalls {
+		var value any
+		result := ""
+		// skip pending tool calls if the generation has been cancelled
+		err := ctx.Err()
+		if err == nil {
+			fn, ok := m.functions[toolCall.Name]
+			if !ok {
+				err = fmt.Errorf("unknown function %s", toolCall.Name)
+			} else {
+				value, result, err = fn.CallValue(toolCall.Arguments)
+			}
+		}
+		if err != nil {
+			result = fmt.Sprintf("error: %s", err)
+		}
+
+		messages = append(messages, thread.NewToolMessage().AddContent(
+			thread.NewToolResponseContent(
+				thread.ToolResponseData{
+					ID:     toolCall.ID,
+					Name:   toolCall.Name,
+					Result: result,
+					Value:  value,
+					Err:    err,
+				},
+			),
+		))
+	}
+
+	return messages
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
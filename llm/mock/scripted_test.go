@@ -0,0 +1,134 @@
+package llmmock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/recovery"
+	"github.com/henomis/lingoose/thread"
+)
+
+type weatherInput struct {
+	City string `json:"city"`
+}
+
+func newUserThread(text string) *thread.Thread {
+	return thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent(text)))
+}
+
+func TestMock_Generate(t *testing.T) {
+	m := New().
+		AddToolCall("weather", weatherInput{City: "Rome"}).
+		AddText("It's sunny in Rome.")
+
+	err := m.BindFunction(func(input weatherInput) string { return "sunny in " + input.City }, "weather", "get the weather")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	th := newUserThread("Weather in Rome?")
+	for m.Pending() > 0 {
+		err = m.Generate(context.Background(), th)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(th.Messages) != 4 {
+		t.Fatalf("expected the tool call, its result and the answer, got %s", th)
+	}
+	toolCalls := th.Messages[1].Contents[0].AsToolCallData()
+	if len(toolCalls) != 1 || toolCalls[0].ID != "call_1" || toolCalls[0].Arguments != `{"city":"Rome"}` {
+		t.Fatalf("unexpected tool calls %+v", toolCalls)
+	}
+	if response := th.Messages[2].Contents[0].AsToolResponseData(); response.Value != "sunny in Rome" {
+		t.Fatalf("unexpected tool response %+v", response)
+	}
+	if th.LastMessage().Contents[0].AsString() != "It's sunny in Rome." {
+		t.Fatalf("unexpected answer %s", th)
+	}
+
+	requests := m.Requests()
+	if len(requests) != 2 || len(requests[0].Messages) != 1 || len(requests[1].Messages) != 3 {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	if err = m.Generate(context.Background(), th); !errors.Is(err, ErrNoResponse) {
+		t.Fatalf("expected ErrNoResponse, got %v", err)
+	}
+}
+
+func TestMock_ToolPanic(t *testing.T) {
+	m := New().
+		AddToolCall("weather", weatherInput{City: "Rome"}).
+		AddText("I can't tell.")
+
+	err := m.BindFunction(func(weatherInput) string { panic("weather service down") }, "weather", "get the weather")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	th := newUserThread("Weather in Rome?")
+	for m.Pending() > 0 {
+		err = m.Generate(context.Background(), th)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	response := th.Messages[2].Contents[0].AsToolResponseData()
+	if !errors.Is(response.Err, recovery.ErrPanic) || !strings.Contains(response.Result, "weather service down") {
+		t.Fatalf("unexpected tool response %+v", response)
+	}
+	if th.LastMessage().Contents[0].AsString() != "I can't tell." {
+		t.Fatalf("unexpected answer %s", th)
+	}
+}
+
+func TestMock_Stream(t *testing.T) {
+	var chunks []string
+	m := New().AddChunks("Hel", "lo").WithChunkDelay(time.Millisecond).WithStream(func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+
+	th := newUserThread("hi")
+	err := m.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(chunks, "|") != "Hel|lo|"+EOS || th.LastMessage().Contents[0].AsString() != "Hello" {
+		t.Fatalf("unexpected stream %q %s", chunks, th)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = New().AddChunks("Hel", "lo").WithChunkDelay(time.Hour).WithStream(func(string) {}).Generate(ctx, newUserThread("hi"))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the stream to be cancelled, got %v", err)
+	}
+}
+
+func TestMock_Errors(t *testing.T) {
+	errBoom := errors.New("boom")
+	m := New().AddError(errBoom).AddRateLimit(2 * time.Second)
+	th := newUserThread("hi")
+
+	if err := m.Generate(context.Background(), th); !errors.Is(err, errBoom) {
+		t.Fatalf("expected the enqueued error, got %v", err)
+	}
+
+	err := m.Generate(context.Background(), th)
+	httpErr, ok := httperror.As(err)
+	if !ok || !httpErr.Temporary() || httpErr.RetryAfter != 2*time.Second {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+
+	if len(th.Messages) != 1 {
+		t.Fatalf("expected the failed generations to leave the thread untouched, got %s", th)
+	}
+}
@@ -14,6 +14,11 @@ type LLMObserver interface {
 	GenerationEnd(*observer.Generation) (*observer.Generation, error)
 }
 
+// ArtifactObserver is implemented by the observers logging the images generated by the LLMs.
+type ArtifactObserver interface {
+	Artifact(*observer.Artifact) (*observer.Artifact, error)
+}
+
 func StartObserveGeneration(
 	ctx context.Context,
 	name string,
@@ -57,5 +62,46 @@ func StopObserveGeneration(
 
 	generation.Output = messages
 	_, err := o.GenerationEnd(generation)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return observeArtifacts(ctx, generation, messages)
+}
+
+// observeArtifacts sends the images of the assistant messages to the observer, if it
+// logs artifacts.
+func observeArtifacts(ctx context.Context, generation *observer.Generation, messages []*thread.Message) error {
+	o, ok := observer.ContextValueObserverInstance(ctx).(ArtifactObserver)
+	if !ok {
+		return nil
+	}
+
+	n := 0
+	for _, message := range messages {
+		if message.Role != thread.RoleAssistant {
+			continue
+		}
+
+		for _, image := range message.Images() {
+			data, mimeType, err := image.ImageData(ctx)
+			if err != nil {
+				return err
+			}
+
+			n++
+			_, err = o.Artifact(&observer.Artifact{
+				GenerationID: generation.ID,
+				TraceID:      generation.TraceID,
+				Name:         fmt.Sprintf("image-%d", n),
+				MIMEType:     mimeType,
+				Data:         data,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
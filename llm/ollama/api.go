@@ -13,10 +13,12 @@ import (
 )
 
 type request struct {
-	Model    string    `json:"model"`
-	Messages []message `json:"messages"`
-	Stream   bool      `json:"stream"`
-	Options  options   `json:"options"`
+	Model     string    `json:"model"`
+	Messages  []message `json:"messages"`
+	Stream    bool      `json:"stream"`
+	Options   options   `json:"options"`
+	Tools     []tool    `json:"tools,omitempty"`
+	KeepAlive *string   `json:"keep_alive,omitempty"`
 }
 
 func (r *request) Path() (string, error) {
@@ -44,12 +46,14 @@ type response[T any] struct {
 	Message           T      `json:"message"`
 	Done              bool   `json:"done"`
 	streamCallbackFn  restclientgo.StreamCallback
-	RawBody           []byte `json:"-"`
+	RawBody           []byte               `json:"-"`
+	Headers           restclientgo.Headers `json:"-"`
 }
 
 type assistantMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
 }
 
 func (r *response[T]) SetAcceptContentType(contentType string) {
@@ -77,7 +81,14 @@ func (r *response[T]) SetStatusCode(code int) error {
 	return nil
 }
 
-func (r *response[T]) SetHeaders(_ restclientgo.Headers) error { return nil }
+func (r *response[T]) statusCode() int {
+	return r.HTTPStatusCode
+}
+
+func (r *response[T]) SetHeaders(headers restclientgo.Headers) error {
+	r.Headers = headers
+	return nil
+}
 
 func (r *response[T]) SetStreamCallback(fn restclientgo.StreamCallback) {
 	r.streamCallbackFn = fn
@@ -88,9 +99,83 @@ func (r *response[T]) StreamCallback() restclientgo.StreamCallback {
 }
 
 type message struct {
-	Role    string   `json:"role"`
-	Content string   `json:"content,omitempty"`
-	Images  []string `json:"images,omitempty"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content,omitempty"`
+	Images    []string   `json:"images,omitempty"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+}
+
+type tool struct {
+	Type     string             `json:"type"`
+	Function functionDefinition `json:"function"`
+}
+
+type functionDefinition struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+type toolCall struct {
+	Function functionCall `json:"function"`
+}
+
+type functionCall struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+type pullRequest struct {
+	Name   string `json:"name"`
+	Stream bool   `json:"stream"`
+}
+
+func (r *pullRequest) Path() (string, error) {
+	return "/pull", nil
+}
+
+func (r *pullRequest) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *pullRequest) ContentType() string {
+	return jsonContentType
+}
+
+type pullResponse struct {
+	HTTPStatusCode int                  `json:"-"`
+	Status         string               `json:"status"`
+	Error          string               `json:"error"`
+	RawBody        []byte               `json:"-"`
+	Headers        restclientgo.Headers `json:"-"`
+}
+
+func (r *pullResponse) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(r)
+}
+
+func (r *pullResponse) SetBody(body io.Reader) error {
+	r.RawBody, _ = io.ReadAll(body)
+	return nil
+}
+
+func (r *pullResponse) AcceptContentType() string {
+	return jsonContentType
+}
+
+func (r *pullResponse) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *pullResponse) SetHeaders(headers restclientgo.Headers) error {
+	r.Headers = headers
+	return nil
 }
 
 type options struct {
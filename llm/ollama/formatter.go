@@ -1,6 +1,8 @@
 package ollama
 
 import (
+	"encoding/json"
+
 	"github.com/henomis/lingoose/thread"
 )
 
@@ -11,7 +13,35 @@ func (o *Ollama) buildChatCompletionRequest(t *thread.Thread) *request {
 		Options: options{
 			Temperature: o.temperature,
 		},
+		Tools:     o.getTools(),
+		KeepAlive: o.keepAlive,
+	}
+}
+
+// getTools returns the tools sent to the model: none if the tool choice is not set, all
+// the functions for "auto", only the named function otherwise.
+func (o *Ollama) getTools() []tool {
+	if o.toolChoice == nil || len(o.functions) == 0 {
+		return nil
+	}
+
+	var tools []tool
+	for _, function := range o.functions {
+		if *o.toolChoice != "auto" && *o.toolChoice != function.Name {
+			continue
+		}
+
+		tools = append(tools, tool{
+			Type: "function",
+			Function: functionDefinition{
+				Name:        function.Name,
+				Description: function.Description,
+				Parameters:  function.Parameters,
+			},
+		})
 	}
+
+	return tools
 }
 
 //nolint:gocognit
@@ -25,6 +55,16 @@ func threadToChatMessages(t *thread.Thread) []message {
 					Role: threadRoleToOllamaRole[m.Role],
 				}
 
+				if content.Type == thread.ContentTypeToolCall {
+					toolCallsData, ok := content.Data.([]thread.ToolCallData)
+					if !ok {
+						continue
+					}
+					chatMessage.ToolCalls = toolCallsDataToToolCalls(toolCallsData)
+					chatMessages = append(chatMessages, chatMessage)
+					continue
+				}
+
 				contentData, ok := content.Data.(string)
 				if !ok {
 					continue
@@ -45,9 +85,36 @@ func threadToChatMessages(t *thread.Thread) []message {
 				chatMessages = append(chatMessages, chatMessage)
 			}
 		case thread.RoleTool:
-			continue
+			for _, content := range m.Contents {
+				toolResponseData, ok := content.Data.(thread.ToolResponseData)
+				if !ok {
+					continue
+				}
+
+				chatMessages = append(chatMessages, message{
+					Role:    threadRoleToOllamaRole[m.Role],
+					Content: toolResponseData.Result,
+				})
+			}
 		}
 	}
 
 	return chatMessages
 }
+
+func toolCallsDataToToolCalls(toolCallsData []thread.ToolCallData) []toolCall {
+	toolCalls := make([]toolCall, 0, len(toolCallsData))
+	for _, toolCallData := range toolCallsData {
+		arguments := make(map[string]any)
+		_ = json.Unmarshal([]byte(toolCallData.Arguments), &arguments)
+
+		toolCalls = append(toolCalls, toolCall{
+			Function: functionCall{
+				Name:      toolCallData.Name,
+				Arguments: arguments,
+			},
+		})
+	}
+
+	return toolCalls
+}
@@ -7,10 +7,14 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/henomis/restclientgo"
 
 	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/llm/function"
+	"github.com/henomis/lingoose/llm/httperror"
 	llmobserver "github.com/henomis/lingoose/llm/observer"
 	"github.com/henomis/lingoose/observer"
 	"github.com/henomis/lingoose/thread"
@@ -32,16 +36,25 @@ var threadRoleToOllamaRole = map[thread.Role]string{
 	thread.RoleSystem:    "system",
 	thread.RoleUser:      "user",
 	thread.RoleAssistant: "assistant",
+	thread.RoleTool:      "tool",
 }
 
 type StreamCallbackFn func(string)
 
+type Function = function.Function
+
+type Tool = function.Tool
+
 type Ollama struct {
 	model            string
 	temperature      float64
+	keepAlive        *string
+	pullModel        bool
 	restClient       *restclientgo.RestClient
 	streamCallbackFn StreamCallbackFn
 	cache            *cache.Cache
+	functions        map[string]Function
+	toolChoice       *string
 	name             string
 }
 
@@ -49,6 +62,7 @@ func New() *Ollama {
 	return &Ollama{
 		restClient: restclientgo.New(defaultEndpoint),
 		model:      defaultModel,
+		functions:  make(map[string]Function),
 		name:       "ollama",
 	}
 }
@@ -78,6 +92,63 @@ func (o *Ollama) WithTemperature(temperature float64) *Ollama {
 	return o
 }
 
+// WithKeepAlive sets how long the model stays loaded in memory after the request.
+// A negative duration keeps it loaded indefinitely, zero unloads it immediately.
+func (o *Ollama) WithKeepAlive(keepAlive time.Duration) *Ollama {
+	keepAliveAsString := keepAlive.String()
+	o.keepAlive = &keepAliveAsString
+	return o
+}
+
+// WithPullModel pulls the model on first use when it is not available locally.
+func (o *Ollama) WithPullModel(pullModel bool) *Ollama {
+	o.pullModel = pullModel
+	return o
+}
+
+// WithToolChoice sets the tool choice: nil disables tools, "auto" lets the model decide,
+// any other value only offers the named function to the model.
+func (o *Ollama) WithToolChoice(toolChoice *string) *Ollama {
+	o.toolChoice = toolChoice
+	return o
+}
+
+func (o *Ollama) WithTools(tools ...Tool) *Ollama {
+	for _, tool := range tools {
+		fn, err := function.NewFromTool(tool)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		o.functions[tool.Name()] = *fn
+	}
+
+	return o
+}
+
+func (o *Ollama) BindFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...function.ParameterOption,
+) error {
+	f, err := function.New(fn, name, description, functionParameterOptions...)
+	if err != nil {
+		return err
+	}
+
+	o.functions[name] = *f
+
+	return nil
+}
+
+// WithHTTPClient sets the http client to use for the LLM
+func (o *Ollama) WithHTTPClient(httpClient *http.Client) *Ollama {
+	o.restClient.SetHTTPClient(httpClient)
+	return o
+}
+
 func (o *Ollama) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
 	messages := t.UserQuery()
 	cacheQuery := strings.Join(messages, "\n")
@@ -141,6 +212,8 @@ func (o *Ollama) Generate(ctx context.Context, t *thread.Thread) error {
 		return fmt.Errorf("%w: %w", ErrOllamaChat, err)
 	}
 
+	nMessageBeforeGeneration := len(t.Messages)
+
 	if o.streamCallbackFn != nil {
 		err = o.stream(ctx, t, chatRequest)
 	} else {
@@ -150,7 +223,7 @@ func (o *Ollama) Generate(ctx context.Context, t *thread.Thread) error {
 		return err
 	}
 
-	err = o.stopObserveGeneration(ctx, generation, []*thread.Message{t.LastMessage()})
+	err = o.stopObserveGeneration(ctx, generation, t.Messages[nMessageBeforeGeneration:])
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrOllamaChat, err)
 	}
@@ -168,22 +241,17 @@ func (o *Ollama) Generate(ctx context.Context, t *thread.Thread) error {
 func (o *Ollama) generate(ctx context.Context, t *thread.Thread, chatRequest *request) error {
 	var resp response[assistantMessage]
 
-	err := o.restClient.Post(
-		ctx,
-		chatRequest,
-		&resp,
-	)
+	err := o.postChat(ctx, chatRequest, &resp)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrOllamaChat, err)
 	}
 
 	if resp.HTTPStatusCode >= http.StatusBadRequest {
-		return fmt.Errorf("%w: %s", ErrOllamaChat, resp.RawBody)
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return fmt.Errorf("%w: %w", ErrOllamaChat, httpErr)
 	}
 
-	t.AddMessage(thread.NewAssistantMessage().AddContent(
-		thread.NewTextContent(resp.Message.Content),
-	))
+	t.AddMessages(o.responseToMessages(ctx, resp.Message.Content, resp.Message.ToolCalls)...)
 
 	return nil
 }
@@ -191,6 +259,7 @@ func (o *Ollama) generate(ctx context.Context, t *thread.Thread, chatRequest *re
 func (o *Ollama) stream(ctx context.Context, t *thread.Thread, chatRequest *request) error {
 	var resp response[message]
 	var assistantMessage string
+	var toolCalls []toolCall
 
 	resp.SetAcceptContentType(ndjsonContentType)
 	resp.SetStreamCallback(
@@ -203,6 +272,7 @@ func (o *Ollama) stream(ctx context.Context, t *thread.Thread, chatRequest *requ
 			}
 
 			assistantMessage += streamResponse.Message.Content
+			toolCalls = append(toolCalls, streamResponse.Message.ToolCalls...)
 			o.streamCallbackFn(streamResponse.Message.Content)
 
 			return nil
@@ -211,26 +281,129 @@ func (o *Ollama) stream(ctx context.Context, t *thread.Thread, chatRequest *requ
 
 	chatRequest.Stream = true
 
-	err := o.restClient.Post(
-		ctx,
-		chatRequest,
-		&resp,
-	)
+	err := o.postChat(ctx, chatRequest, &resp)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrOllamaChat, err)
 	}
 
 	if resp.HTTPStatusCode >= http.StatusBadRequest {
-		return fmt.Errorf("%w: %s", ErrOllamaChat, resp.RawBody)
+		httpErr := httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+		return fmt.Errorf("%w: %w", ErrOllamaChat, httpErr)
 	}
 
-	t.AddMessage(thread.NewAssistantMessage().AddContent(
-		thread.NewTextContent(assistantMessage),
-	))
+	t.AddMessages(o.responseToMessages(ctx, assistantMessage, toolCalls)...)
+
+	return nil
+}
+
+type chatResponse interface {
+	restclientgo.Response
+	statusCode() int
+}
+
+// postChat sends the chat request, pulling the model and retrying when it is missing
+// and model pulling is enabled.
+func (o *Ollama) postChat(ctx context.Context, chatRequest *request, resp chatResponse) error {
+	err := o.restClient.Post(ctx, chatRequest, resp)
+	if err != nil || resp.statusCode() != http.StatusNotFound || !o.pullModel {
+		return err
+	}
+
+	err = o.pull(ctx)
+	if err != nil {
+		return err
+	}
+
+	return o.restClient.Post(ctx, chatRequest, resp)
+}
+
+func (o *Ollama) pull(ctx context.Context) error {
+	var resp pullResponse
+
+	err := o.restClient.Post(ctx, &pullRequest{Name: o.model}, &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.HTTPStatusCode >= http.StatusBadRequest || resp.Error != "" {
+		return fmt.Errorf("unable to pull model %s: %s%s", o.model, resp.Error, resp.RawBody)
+	}
 
 	return nil
 }
 
+func (o *Ollama) responseToMessages(ctx context.Context, content string, toolCalls []toolCall) []*thread.Message {
+	if len(toolCalls) == 0 {
+		return []*thread.Message{
+			thread.NewAssistantMessage().AddContent(
+				thread.NewTextContent(content),
+			),
+		}
+	}
+
+	// Ollama does not assign ids to tool calls
+	toolCallsData := make([]thread.ToolCallData, 0, len(toolCalls))
+	for _, tc := range toolCalls {
+		arguments, _ := json.Marshal(tc.Function.Arguments)
+		toolCallsData = append(toolCallsData, thread.ToolCallData{
+			ID:        uuid.New().String(),
+			Name:      tc.Function.Name,
+			Arguments: string(arguments),
+		})
+	}
+
+	messages := []*thread.Message{
+		thread.NewAssistantMessage().AddContent(
+			thread.NewToolCallContent(toolCallsData),
+		),
+	}
+
+	return append(messages, o.callTools(ctx, toolCallsData)...)
+}
+
+func (o *Ollama) callTool(toolCall thread.ToolCallData) (any, string, error) {
+	fn, ok := o.functions[toolCall.Name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown function %s", toolCall.Name)
+	}
+
+	return fn.CallValue(toolCall.Arguments)
+}
+
+func (o *Ollama) callTools(ctx context.Context, toolCalls []thread.ToolCallData) []*thread.Message {
+	if len(o.functions) == 0 || len(toolCalls) == 0 {
+		return nil
+	}
+
+	var messages []*thread.Message
+	for _, toolCall := range toolCalls {
+		// skip pending tool calls if the generation has been cancelled
+		err := ctx.Err()
+		var value any
+		result := ""
+		if err == nil {
+			value, result, err = o.callTool(toolCall)
+		}
+		if err != nil {
+			result = fmt.Sprintf("error: %s", err)
+		}
+
+		messages = append(messages, thread.NewToolMessage().AddContent(
+			thread.NewToolResponseContent(
+				thread.ToolResponseData{
+					ID:     toolCall.ID,
+					Name:   toolCall.Name,
+					Result: result,
+					Value:  value,
+					Err:    err,
+				},
+			),
+		))
+	}
+
+	return messages
+}
+
 func (o *Ollama) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
 	return llmobserver.StartObserveGeneration(
 		ctx,
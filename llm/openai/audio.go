@@ -0,0 +1,297 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/thread"
+)
+
+const (
+	defaultBaseURL = "https://api.openai.com/v1"
+
+	GPT4oAudioPreview     Model = "gpt-4o-audio-preview"
+	GPT4oMiniAudioPreview Model = "gpt-4o-mini-audio-preview"
+)
+
+type AudioVoice string
+
+const (
+	AudioVoiceAlloy   AudioVoice = "alloy"
+	AudioVoiceAsh     AudioVoice = "ash"
+	AudioVoiceBallad  AudioVoice = "ballad"
+	AudioVoiceCoral   AudioVoice = "coral"
+	AudioVoiceEcho    AudioVoice = "echo"
+	AudioVoiceSage    AudioVoice = "sage"
+	AudioVoiceShimmer AudioVoice = "shimmer"
+	AudioVoiceVerse   AudioVoice = "verse"
+)
+
+type audioOutput struct {
+	Voice  AudioVoice         `json:"voice"`
+	Format thread.AudioFormat `json:"format"`
+}
+
+type audioChatCompletionResponse struct {
+	Choices []struct {
+		Message struct {
+			Audio *struct {
+				ID         string `json:"id"`
+				Data       string `json:"data"`
+				Transcript string `json:"transcript"`
+			} `json:"audio"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+// WithAudioOutput makes the model answer with speech, using an audio model such as
+// GPT4oAudioPreview. The answer contains the audio and its transcript as text.
+func (o *OpenAI) WithAudioOutput(voice AudioVoice, format thread.AudioFormat) *OpenAI {
+	o.audioOutput = &audioOutput{
+		Voice:  voice,
+		Format: format,
+	}
+	return o
+}
+
+func hasAudioContent(t *thread.Thread) bool {
+	for _, message := range t.Messages {
+		for _, content := range message.Contents {
+			if content.Type == thread.ContentTypeAudio {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// generateAudio sends the request to the chat completions endpoint directly, since the
+// client doesn't support audio inputs and outputs. Streaming is not supported.
+func (o *OpenAI) generateAudio(
+	ctx context.Context,
+	t *thread.Thread,
+	chatCompletionRequest openai.ChatCompletionRequest,
+) error {
+	if o.azure != nil {
+		return fmt.Errorf("%w: audio is not supported on Azure", ErrOpenAIChat)
+	}
+
+	body, err := o.buildAudioRequestBody(t, chatCompletionRequest)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
+
+	responseBody, err := o.postChatCompletion(ctx, body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
+
+	var response openai.ChatCompletionResponse
+	err = json.Unmarshal(responseBody, &response)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
+
+	var audioResponse audioChatCompletionResponse
+	err = json.Unmarshal(responseBody, &audioResponse)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
+
+	if o.reportsUsage(ctx) {
+		o.setUsageMetadata(ctx, response.Usage)
+	}
+
+	if len(response.Choices) == 0 {
+		return fmt.Errorf("%w: no choices returned", ErrOpenAIChat)
+	}
+
+	if response.Choices[0].Message.Refusal != "" {
+		return fmt.Errorf("%w: %s", ErrOpenAIRefusal, response.Choices[0].Message.Refusal)
+	}
+
+	var messages []*thread.Message
+	audio := audioResponse.Choices[0].Message.Audio
+	switch {
+	case len(response.Choices[0].Message.ToolCalls) > 0:
+		messages = append(messages, toolCallsToToolCallMessage(response.Choices[0].Message.ToolCalls))
+		messages = append(messages, o.callTools(ctx, response.Choices[0].Message.ToolCalls)...)
+	case audio == nil:
+		messages = append(messages, newAssistantMessage("", response.Choices[0].Message.Content))
+	default:
+		data, decodeErr := base64.StdEncoding.DecodeString(audio.Data)
+		if decodeErr != nil {
+			return fmt.Errorf("%w: %w", ErrOpenAIChat, decodeErr)
+		}
+
+		format := thread.AudioFormatWAV
+		if o.audioOutput != nil {
+			format = o.audioOutput.Format
+		}
+
+		messages = append(messages, thread.NewAssistantMessage().AddContent(
+			&thread.Content{
+				Type: thread.ContentTypeAudio,
+				Data: thread.AudioData{
+					ID:         audio.ID,
+					Format:     format,
+					Data:       data,
+					Transcript: audio.Transcript,
+				},
+			},
+		).AddContent(
+			thread.NewTextContent(audio.Transcript),
+		))
+	}
+
+	addChoiceMetadata(
+		messages,
+		response.Choices[0].FinishReason,
+		response.SystemFingerprint,
+		response.Choices[0].LogProbs,
+	)
+	t.AddMessages(messages...)
+
+	return nil
+}
+
+// buildAudioRequestBody adds the audio parameters and the user audio contents to the
+// request. Assistant audio answers are sent back as their transcript.
+func (o *OpenAI) buildAudioRequestBody(t *thread.Thread, chatCompletionRequest openai.ChatCompletionRequest) ([]byte, error) {
+	requestAsJSON, err := json.Marshal(chatCompletionRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	var request map[string]any
+	err = json.Unmarshal(requestAsJSON, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.audioOutput != nil {
+		request["modalities"] = []string{"text", "audio"}
+		request["audio"] = o.audioOutput
+	}
+
+	// the thread messages with nothing to send are skipped in the request, so the index
+	// of the request messages is tracked separately
+	messages, _ := request["messages"].([]any)
+	messageIndex := 0
+	for _, message := range t.Messages {
+		_, ok, convErr := threadMessageToChatCompletionMessage(message, o.imageDetail)
+		if convErr != nil {
+			return nil, convErr
+		} else if !ok {
+			continue
+		}
+
+		i := messageIndex
+		messageIndex++
+		if message.Role != thread.RoleUser || i >= len(messages) || !messageHasAudio(message) {
+			continue
+		}
+
+		chatMessage, isChatMessage := messages[i].(map[string]any)
+		if !isChatMessage {
+			continue
+		}
+
+		chatMessage["content"], err = audioMessageParts(message)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(request)
+}
+
+func messageHasAudio(message *thread.Message) bool {
+	for _, content := range message.Contents {
+		if content.Type == thread.ContentTypeAudio {
+			return true
+		}
+	}
+
+	return false
+}
+
+func audioMessageParts(message *thread.Message) ([]map[string]any, error) {
+	var parts []map[string]any
+	for _, content := range message.Contents {
+		switch content.Type {
+		case thread.ContentTypeText:
+			parts = append(parts, map[string]any{
+				"type": "text",
+				"text": content.AsString(),
+			})
+		case thread.ContentTypeAudio:
+			audioData := content.AsAudioData()
+			if audioData == nil {
+				continue
+			}
+
+			parts = append(parts, map[string]any{
+				"type": "input_audio",
+				"input_audio": map[string]any{
+					"data":   base64.StdEncoding.EncodeToString(audioData.Data),
+					"format": audioData.Format,
+				},
+			})
+		case thread.ContentTypeImage:
+			url, err := imageURL(content)
+			if err != nil {
+				return nil, err
+			}
+
+			parts = append(parts, map[string]any{
+				"type": "image_url",
+				"image_url": map[string]any{
+					"url": url,
+				},
+			})
+		}
+	}
+
+	return parts, nil
+}
+
+func (o *OpenAI) postChatCompletion(ctx context.Context, body []byte) ([]byte, error) {
+	baseURL := o.baseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+
+	resp, err := o.httpClient(nil).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, httperror.New(resp.StatusCode, responseBody).WithRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	return responseBody, nil
+}
@@ -0,0 +1,115 @@
+package openai
+
+import (
+	"context"
+	"os"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/henomis/lingoose/secret"
+)
+
+const (
+	DefaultAzureAPIVersion = "2024-02-01"
+	azureADTokenTTL        = 30 * time.Minute
+)
+
+// AzureADTokenFn returns a Microsoft Entra ID (Azure AD) access token for the
+// https://cognitiveservices.azure.com/.default scope.
+type AzureADTokenFn func(ctx context.Context) (string, error)
+
+type azureConfig struct {
+	endpoint    string
+	deployment  string
+	apiVersion  string
+	apiKey      string
+	deployments map[Model]string
+	tokenFn     AzureADTokenFn
+}
+
+// WithAzure configures the OpenAI instance to use an Azure OpenAI resource. Requests are
+// sent to the given deployment unless the model is mapped by WithAzureDeployments.
+// The API key is read from the AZURE_OPENAI_API_KEY environment variable.
+func (o *OpenAI) WithAzure(endpoint, deployment, apiVersion string) *OpenAI {
+	if apiVersion == "" {
+		apiVersion = DefaultAzureAPIVersion
+	}
+
+	o.azure = &azureConfig{
+		endpoint:   endpoint,
+		deployment: deployment,
+		apiVersion: apiVersion,
+		apiKey:     os.Getenv("AZURE_OPENAI_API_KEY"),
+	}
+
+	return o.withAzureClient()
+}
+
+// WithAzureAPIKey sets the Azure OpenAI API key. It must be called after WithAzure.
+func (o *OpenAI) WithAzureAPIKey(apiKey string) *OpenAI {
+	if o.azure == nil {
+		return o
+	}
+
+	o.azure.apiKey = apiKey
+	return o.withAzureClient()
+}
+
+// WithAzureADToken authenticates with Azure AD tokens instead of the API key. Tokens are
+// cached and fetched again when they expire or are rejected. It must be called after WithAzure.
+func (o *OpenAI) WithAzureADToken(tokenFn AzureADTokenFn) *OpenAI {
+	if o.azure == nil {
+		return o
+	}
+
+	o.azure.tokenFn = tokenFn
+	return o.withAzureClient()
+}
+
+// WithAzureDeployments maps models to the name of their Azure deployment. It must be
+// called after WithAzure.
+func (o *OpenAI) WithAzureDeployments(deployments map[Model]string) *OpenAI {
+	if o.azure == nil {
+		return o
+	}
+
+	o.azure.deployments = deployments
+	return o.withAzureClient()
+}
+
+func (o *OpenAI) withAzureClient() *OpenAI {
+	config := openai.DefaultAzureConfig(o.azure.apiKey, o.azure.endpoint)
+	config.APIVersion = o.azure.apiVersion
+	config.AzureModelMapperFunc = o.azure.deploymentForModel
+
+	if o.azure.tokenFn != nil {
+		tokenFn := o.azure.tokenFn
+		token := secret.New(
+			secret.Func(func(ctx context.Context, _ string) (string, error) {
+				return tokenFn(ctx)
+			}),
+			"",
+		).WithTTL(azureADTokenTTL)
+
+		config.APIType = openai.APITypeAzureAD
+		config.HTTPClient = o.httpClient(secret.NewTransport(token, secret.BearerAuth).WithBase(o.baseTransport()))
+	} else {
+		config.HTTPClient = o.httpClient(nil)
+	}
+
+	o.openAIClient = openai.NewClientWithConfig(config)
+	return o
+}
+
+func (a *azureConfig) deploymentForModel(model string) string {
+	if deployment, ok := a.deployments[Model(model)]; ok {
+		return deployment
+	}
+
+	if a.deployment != "" {
+		return a.deployment
+	}
+
+	return model
+}
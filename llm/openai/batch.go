@@ -0,0 +1,298 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+const (
+	defaultBatchPollingInterval = time.Minute
+	batchCustomIDPrefix         = "thread-"
+
+	BatchStatusValidating = "validating"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusFailed     = "failed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+// Batch generates the answers of many threads through the OpenAI Batch API, at half the
+// price of synchronous requests, within 24 hours. It is meant for offline workloads such
+// as evaluations and data enrichment. The requests use the settings of the OpenAI instance
+// that created the batch; tool calls are added to the threads but not run.
+type Batch struct {
+	openAI          *OpenAI
+	threads         []*thread.Thread
+	errors          []error
+	id              string
+	metadata        map[string]any
+	pollingInterval time.Duration
+}
+
+type BatchRequestCounts = openai.BatchRequestCounts
+
+type batchOutputLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewBatch returns a batch using the settings of the OpenAI instance.
+func (o *OpenAI) NewBatch() *Batch {
+	return &Batch{
+		openAI:          o,
+		pollingInterval: defaultBatchPollingInterval,
+	}
+}
+
+// WithPollingInterval sets how often Wait checks the batch status, 1 minute by default.
+func (b *Batch) WithPollingInterval(pollingInterval time.Duration) *Batch {
+	b.pollingInterval = pollingInterval
+	return b
+}
+
+func (b *Batch) WithMetadata(metadata map[string]any) *Batch {
+	b.metadata = metadata
+	return b
+}
+
+// WithID sets the ID of a submitted batch, to wait for it from another process. The
+// threads must be added in the same order they had when the batch was submitted.
+func (b *Batch) WithID(id string) *Batch {
+	b.id = id
+	return b
+}
+
+// Add adds the threads to the batch.
+func (b *Batch) Add(threads ...*thread.Thread) *Batch {
+	b.threads = append(b.threads, threads...)
+	b.errors = append(b.errors, make([]error, len(threads))...)
+	return b
+}
+
+func (b *Batch) ID() string {
+	return b.id
+}
+
+// Errors returns, for each thread, the error of its request, nil if it succeeded.
+func (b *Batch) Errors() []error {
+	return b.errors
+}
+
+// Run submits the batch and waits for its completion.
+func (b *Batch) Run(ctx context.Context) error {
+	_, err := b.Submit(ctx)
+	if err != nil {
+		return err
+	}
+
+	return b.Wait(ctx)
+}
+
+// Submit uploads the requests of the threads and creates the batch job, returning its ID.
+func (b *Batch) Submit(ctx context.Context) (string, error) {
+	if len(b.threads) == 0 {
+		return "", fmt.Errorf("%w: no threads to submit", ErrOpenAIBatch)
+	}
+
+	var upload openai.UploadBatchFileRequest
+	for i, t := range b.threads {
+		chatCompletionRequest, err := b.openAI.buildChatCompletionRequest(ctx, t)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrOpenAIBatch, err)
+		}
+		if len(b.openAI.functions) > 0 {
+			chatCompletionRequest.Tools = b.openAI.getChatCompletionRequestTools()
+			chatCompletionRequest.ToolChoice = b.openAI.getChatCompletionRequestToolChoice()
+		}
+
+		upload.AddChatCompletion(batchCustomIDPrefix+strconv.Itoa(i), chatCompletionRequest)
+	}
+
+	response, err := b.openAI.openAIClient.CreateBatchWithUploadFile(ctx, openai.CreateBatchWithUploadFileRequest{
+		Endpoint:               openai.BatchEndpointChatCompletions,
+		Metadata:               b.metadata,
+		UploadBatchFileRequest: upload,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrOpenAIBatch, err)
+	}
+
+	b.id = response.ID
+
+	return b.id, nil
+}
+
+// Status returns the status of the batch and the number of completed and failed requests.
+func (b *Batch) Status(ctx context.Context) (string, BatchRequestCounts, error) {
+	response, err := b.openAI.openAIClient.RetrieveBatch(ctx, b.id)
+	if err != nil {
+		return "", BatchRequestCounts{}, fmt.Errorf("%w: %w", ErrOpenAIBatch, err)
+	}
+
+	return response.Status, response.RequestCounts, nil
+}
+
+// Cancel cancels the batch, the requests completed so far can still be collected with Wait.
+func (b *Batch) Cancel(ctx context.Context) error {
+	_, err := b.openAI.openAIClient.CancelBatch(ctx, b.id)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenAIBatch, err)
+	}
+
+	return nil
+}
+
+// Wait polls the batch until it ends and adds the answers to the threads. It fails if the
+// batch failed as a whole; the errors of the single requests are returned by Errors.
+func (b *Batch) Wait(ctx context.Context) error {
+	if b.id == "" {
+		return fmt.Errorf("%w: batch not submitted", ErrOpenAIBatch)
+	}
+
+	ticker := time.NewTicker(b.pollingInterval)
+	defer ticker.Stop()
+
+	for {
+		response, err := b.openAI.openAIClient.RetrieveBatch(ctx, b.id)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrOpenAIBatch, err)
+		}
+
+		switch response.Status {
+		case BatchStatusCompleted, BatchStatusExpired, BatchStatusCancelled:
+			return b.collect(ctx, &response.Batch)
+		case BatchStatusFailed:
+			return fmt.Errorf("%w: batch %s failed: %s", ErrOpenAIBatch, b.id, batchErrors(&response.Batch))
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrOpenAIBatch, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect reads the output and error files of the batch. Requests not run because the
+// batch expired or was cancelled get an error.
+func (b *Batch) collect(ctx context.Context, batch *openai.Batch) error {
+	done := make([]bool, len(b.threads))
+	for _, fileID := range []*string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == nil || *fileID == "" {
+			continue
+		}
+
+		err := b.collectFile(ctx, *fileID, done)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range b.threads {
+		if !done[i] {
+			b.errors[i] = fmt.Errorf("%w: request not run, batch %s", ErrOpenAIBatch, batch.Status)
+		}
+	}
+
+	return nil
+}
+
+func (b *Batch) collectFile(ctx context.Context, fileID string, done []bool) error {
+	content, err := b.openAI.openAIClient.GetFileContent(ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenAIBatch, err)
+	}
+	defer content.Close()
+
+	decoder := json.NewDecoder(content)
+	for decoder.More() {
+		var line batchOutputLine
+		if err = decoder.Decode(&line); err != nil {
+			return fmt.Errorf("%w: %w", ErrOpenAIBatch, err)
+		}
+
+		i, err := strconv.Atoi(strings.TrimPrefix(line.CustomID, batchCustomIDPrefix))
+		if err != nil || i < 0 || i >= len(b.threads) {
+			return fmt.Errorf("%w: unknown request %q", ErrOpenAIBatch, line.CustomID)
+		}
+
+		done[i] = true
+		b.errors[i] = b.addResult(ctx, b.threads[i], &line)
+	}
+
+	return nil
+}
+
+func (b *Batch) addResult(ctx context.Context, t *thread.Thread, line *batchOutputLine) error {
+	if line.Error != nil {
+		return fmt.Errorf("%w: %s: %s", ErrOpenAIBatch, line.Error.Code, line.Error.Message)
+	}
+	if line.Response == nil {
+		return fmt.Errorf("%w: empty response", ErrOpenAIBatch)
+	}
+	if line.Response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d: %s", ErrOpenAIBatch, line.Response.StatusCode, line.Response.Body)
+	}
+
+	var response openai.ChatCompletionResponse
+	if err := json.Unmarshal(line.Response.Body, &response); err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenAIBatch, err)
+	}
+
+	if b.openAI.usageCallback != nil {
+		b.openAI.setUsageMetadata(ctx, response.Usage)
+	}
+
+	if len(response.Choices) == 0 {
+		return fmt.Errorf("%w: no choices returned", ErrOpenAIBatch)
+	}
+
+	choice := response.Choices[0]
+	if choice.Message.Refusal != "" {
+		return fmt.Errorf("%w: %s", ErrOpenAIRefusal, choice.Message.Refusal)
+	}
+
+	var message *thread.Message
+	if len(choice.Message.ToolCalls) > 0 {
+		message = toolCallsToToolCallMessage(choice.Message.ToolCalls)
+	} else {
+		message = newAssistantMessage(choice.Message.ReasoningContent, choice.Message.Content)
+	}
+
+	addChoiceMetadata([]*thread.Message{message}, choice.FinishReason, response.SystemFingerprint, choice.LogProbs)
+	t.AddMessage(message)
+
+	return nil
+}
+
+func batchErrors(batch *openai.Batch) string {
+	if batch.Errors == nil {
+		return "unknown error"
+	}
+
+	messages := make([]string, 0, len(batch.Errors.Data))
+	for _, e := range batch.Errors.Data {
+		messages = append(messages, e.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,119 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/henomis/lingoose/ratelimit"
+	"github.com/henomis/lingoose/thread"
+)
+
+// GenerateN requests n answers to the thread in a single completion and returns an
+// assistant message for each of them, without adding them to the thread. Answers asking
+// for tools are returned as tool call messages, the tools are not called. The prompt is
+// billed once, the completion tokens of every answer.
+func (o *OpenAI) GenerateN(ctx context.Context, t *thread.Thread, n int) ([]*thread.Message, error) {
+	if t == nil || n < 1 {
+		return nil, nil
+	}
+
+	chatCompletionRequest, err := o.buildChatCompletionRequest(ctx, t)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
+	chatCompletionRequest.N = n
+
+	if len(o.functions) > 0 {
+		chatCompletionRequest.Tools = o.getChatCompletionRequestTools()
+		chatCompletionRequest.ToolChoice = o.getChatCompletionRequestToolChoice()
+	}
+
+	if o.rateLimiter != nil {
+		err := o.rateLimiter.Wait(ctx, ratelimit.ThreadTokens(t)+completionTokens(chatCompletionRequest)*n)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+		}
+	}
+
+	generation, err := o.startObserveGeneration(ctx, t)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
+
+	response, err := o.openAIClient.CreateChatCompletion(ctx, chatCompletionRequest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
+
+	if o.reportsUsage(ctx) {
+		o.setUsageMetadata(ctx, response.Usage)
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("%w: no choices returned", ErrOpenAIChat)
+	}
+
+	messages := make([]*thread.Message, 0, len(response.Choices))
+	for _, choice := range response.Choices {
+		var message *thread.Message
+		switch {
+		case choice.Message.Refusal != "":
+			continue
+		case len(choice.Message.ToolCalls) > 0:
+			message = toolCallsToToolCallMessage(choice.Message.ToolCalls)
+		default:
+			message = newAssistantMessage(choice.Message.ReasoningContent, choice.Message.Content)
+		}
+
+		addChoiceMetadata([]*thread.Message{message}, choice.FinishReason, response.SystemFingerprint, choice.LogProbs)
+		messages = append(messages, message)
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrOpenAIRefusal, response.Choices[0].Message.Refusal)
+	}
+
+	err = o.stopObserveGeneration(ctx, generation, messages)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
+
+	return messages, nil
+}
+
+// MeanLogProb returns the mean log probability of the tokens of an answer generated with
+// WithLogProbs, a measure of the model confidence. It returns false if the answer has no
+// log probabilities.
+func MeanLogProb(message *thread.Message) (float64, bool) {
+	logProbs, ok := message.Metadata[MetadataLogProbs].([]LogProb)
+	if !ok || len(logProbs) == 0 {
+		return math.Inf(-1), false
+	}
+
+	var sum float64
+	for _, logProb := range logProbs {
+		sum += logProb.LogProb
+	}
+
+	return sum / float64(len(logProbs)), true
+}
+
+// LogProbSelector selects the answer with the highest mean log probability, for
+// bestofn.BestOfN. The answers must be generated with WithLogProbs.
+type LogProbSelector struct{}
+
+func (LogProbSelector) Select(_ context.Context, _ string, answers []*thread.Message) (int, float64, error) {
+	best, bestScore := -1, math.Inf(-1)
+	for i, answer := range answers {
+		if score, ok := MeanLogProb(answer); ok && (best < 0 || score > bestScore) {
+			best, bestScore = i, score
+		}
+	}
+
+	if best < 0 {
+		return 0, 0, fmt.Errorf("%w: answers without log probabilities", ErrOpenAIChat)
+	}
+
+	return best, bestScore, nil
+}
@@ -0,0 +1,131 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/henomis/lingoose/ratelimit"
+	"github.com/henomis/lingoose/thread"
+)
+
+func newChoicesServer(t *testing.T, body *map[string]any, response string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body != nil {
+			_ = json.NewDecoder(r.Body).Decode(body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGenerateN(t *testing.T) {
+	var body map[string]any
+	server := newChoicesServer(t, &body, `{"choices":[`+
+		`{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"first"},`+
+		`"logprobs":{"content":[{"token":"first","logprob":-2}]}},`+
+		`{"index":1,"finish_reason":"stop","message":{"role":"assistant","refusal":"no"}},`+
+		`{"index":2,"finish_reason":"tool_calls","message":{"role":"assistant","tool_calls":[`+
+		`{"id":"c1","type":"function","function":{"name":"weather","arguments":"{}"}}]}}]}`)
+
+	llm := New().WithBaseURL(server.URL).WithAPIKey("key")
+	th := newUserThread()
+
+	messages, err := llm.GenerateN(context.Background(), th, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body["n"] != float64(3) {
+		t.Fatalf("expected n=3 in the request, got %v", body["n"])
+	}
+	if len(th.Messages) != 1 {
+		t.Fatalf("expected the thread to be left untouched, got %d messages", len(th.Messages))
+	}
+
+	// the refused choice is skipped
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 answers, got %d", len(messages))
+	}
+	if messages[0].Contents[0].AsString() != "first" || messages[0].Metadata[MetadataFinishReason] != FinishReason("stop") {
+		t.Fatalf("unexpected first answer %+v", messages[0])
+	}
+	if score, ok := MeanLogProb(messages[0]); !ok || score != -2 {
+		t.Fatalf("unexpected mean log probability %v %v", score, ok)
+	}
+	toolCalls, ok := messages[1].Contents[0].Data.([]thread.ToolCallData)
+	if !ok || toolCalls[0].Name != "weather" {
+		t.Fatalf("expected a tool call answer, got %+v", messages[1].Contents[0])
+	}
+}
+
+func TestGenerateNRefusal(t *testing.T) {
+	server := newChoicesServer(t, nil, `{"choices":[`+
+		`{"index":0,"message":{"role":"assistant","refusal":"I can't help"}},`+
+		`{"index":1,"message":{"role":"assistant","refusal":"no"}}]}`)
+
+	llm := New().WithBaseURL(server.URL).WithAPIKey("key")
+
+	messages, err := llm.GenerateN(context.Background(), newUserThread(), 2)
+	if !errors.Is(err, ErrOpenAIRefusal) || messages != nil {
+		t.Fatalf("expected a refusal error, got %v %v", messages, err)
+	}
+}
+
+func TestGenerateNRateLimiter(t *testing.T) {
+	server := newChoicesServer(t, nil, `{"choices":[{"message":{"role":"assistant","content":"hello"}}]}`)
+
+	// without a max tokens limit each answer is estimated as DefaultOpenAIMaxTokens
+	llm := New().WithBaseURL(server.URL).WithAPIKey("key").WithMaxTokens(0).
+		WithRateLimiter(ratelimit.New(0, 2*DefaultOpenAIMaxTokens))
+
+	_, err := llm.GenerateN(context.Background(), newUserThread(), 3)
+	if !errors.Is(err, ratelimit.ErrTooManyTokens) {
+		t.Fatalf("expected the completion tokens of every answer to be reserved, got %v", err)
+	}
+
+	_, err = llm.GenerateN(context.Background(), newUserThread(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogProbSelector(t *testing.T) {
+	answer := func(logProbs ...float64) *thread.Message {
+		message := thread.NewAssistantMessage().AddContent(thread.NewTextContent("answer"))
+		if len(logProbs) == 0 {
+			return message
+		}
+
+		tokens := make([]LogProb, 0, len(logProbs))
+		for _, logProb := range logProbs {
+			tokens = append(tokens, LogProb{LogProb: logProb})
+		}
+		message.AddMetadata(MetadataLogProbs, tokens)
+
+		return message
+	}
+
+	best, score, err := LogProbSelector{}.Select(context.Background(), "query", []*thread.Message{
+		answer(-1, -3),
+		answer(),
+		answer(-0.5, -0.5),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if best != 2 || score != -0.5 {
+		t.Fatalf("expected the third answer, got %d with score %v", best, score)
+	}
+
+	_, _, err = LogProbSelector{}.Select(context.Background(), "query", []*thread.Message{answer()})
+	if err == nil {
+		t.Fatal("expected an error for answers without log probabilities")
+	}
+}
@@ -0,0 +1,306 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/llm/retry"
+	"github.com/henomis/lingoose/secret"
+)
+
+const (
+	defaultRetryDelay = time.Second
+)
+
+// WithBaseURL sets the base URL of an OpenAI compatible server (e.g. vLLM, LM Studio,
+// LiteLLM proxy, Together AI), such as http://localhost:8000/v1.
+func (o *OpenAI) WithBaseURL(baseURL string) *OpenAI {
+	o.baseURL = baseURL
+	return o.withCustomClient()
+}
+
+// WithAPIKey sets the API key, read from the OPENAI_API_KEY environment variable by default.
+func (o *OpenAI) WithAPIKey(apiKey string) *OpenAI {
+	o.apiKey = apiKey
+	return o.withCustomClient()
+}
+
+// WithAPIKeyFunc sets a function returning the API key of each request from its context,
+// so that multi-tenant applications send the requests of each tenant with its own key,
+// e.g. the key of the tenant set with secret.WithTenant. An empty key keeps the default.
+func (o *OpenAI) WithAPIKeyFunc(apiKeyFunc func(ctx context.Context) string) *OpenAI {
+	o.apiKeyFunc = apiKeyFunc
+	return o.withCustomClient()
+}
+
+// WithHeaders sets additional headers sent with every request.
+func (o *OpenAI) WithHeaders(headers map[string]string) *OpenAI {
+	o.headers = headers
+	return o.withCustomClient()
+}
+
+// WithHeader adds a header sent with every request, keeping the other headers.
+func (o *OpenAI) WithHeader(key, value string) *OpenAI {
+	headers := make(map[string]string, len(o.headers)+1)
+	for k, v := range o.headers {
+		headers[k] = v
+	}
+	headers[key] = value
+
+	return o.WithHeaders(headers)
+}
+
+// WithBodyFields sets additional fields sent in the body of every chat completion request,
+// for the parameters specific to an OpenAI compatible server. They override the fields
+// set by the client with the same name.
+func (o *OpenAI) WithBodyFields(fields map[string]any) *OpenAI {
+	o.bodyFields = fields
+	return o.withCustomClient()
+}
+
+// WithBodyField adds a field sent in the body of every chat completion request, keeping
+// the other fields.
+func (o *OpenAI) WithBodyField(key string, value any) *OpenAI {
+	fields := make(map[string]any, len(o.bodyFields)+1)
+	for k, v := range o.bodyFields {
+		fields[k] = v
+	}
+	fields[key] = value
+
+	return o.WithBodyFields(fields)
+}
+
+// WithResponseFields copies the given fields of the chat completion response, not part
+// of the OpenAI API, in the metadata of the assistant message, e.g. the citations of a
+// server searching the web. The metadata holds the decoded JSON value; when streaming,
+// the value of the last chunk having the field is used.
+func (o *OpenAI) WithResponseFields(fields ...string) *OpenAI {
+	o.responseFields = fields
+	return o.withCustomClient()
+}
+
+// WithMaxRetries retries the requests rate limited by the server (429) up to the given
+// number of times with retry.Do, waiting for the delay suggested by the Retry-After header
+// or for an exponential backoff starting at one second.
+func (o *OpenAI) WithMaxRetries(maxRetries uint) *OpenAI {
+	o.maxRetries = maxRetries
+	return o.withCustomClient()
+}
+
+// WithTransport sets the transport the requests are sent with, http.DefaultTransport by
+// default.
+func (o *OpenAI) WithTransport(transport http.RoundTripper) *OpenAI {
+	o.transport = transport
+	return o.withCustomClient()
+}
+
+func (o *OpenAI) withCustomClient() *OpenAI {
+	if o.azure != nil {
+		return o.withAzureClient()
+	}
+
+	config := openai.DefaultConfig(o.apiKey)
+	if o.baseURL != "" {
+		config.BaseURL = o.baseURL
+	}
+	config.HTTPClient = o.httpClient(nil)
+
+	o.openAIClient = openai.NewClientWithConfig(config)
+	return o
+}
+
+// baseTransport returns the transport set with WithTransport, http.DefaultTransport by
+// default.
+func (o *OpenAI) baseTransport() http.RoundTripper {
+	if o.transport == nil {
+		return http.DefaultTransport
+	}
+	return o.transport
+}
+
+// httpClient returns a client adding the retries, the body fields, the custom headers and
+// the API key of the request on top of the given transport, the base transport if nil.
+func (o *OpenAI) httpClient(transport http.RoundTripper) *http.Client {
+	if transport == nil {
+		transport = o.transport
+	}
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	if len(o.responseFields) > 0 {
+		transport = &responseRecorderTransport{
+			base: transport,
+		}
+	}
+
+	transport = &retryTransport{
+		base:       transport,
+		maxRetries: o.maxRetries,
+	}
+
+	if len(o.bodyFields) > 0 {
+		transport = &bodyFieldsTransport{
+			base:   transport,
+			fields: o.bodyFields,
+		}
+	}
+
+	if len(o.headers) > 0 {
+		transport = &headerTransport{
+			base:    transport,
+			headers: o.headers,
+		}
+	}
+
+	if o.apiKeyFunc != nil {
+		apply := secret.BearerAuth
+		if o.azure != nil && o.azure.tokenFn == nil {
+			apply = secret.HeaderAuth("api-key")
+		}
+		transport = secret.NewKeyFuncTransport(o.apiKeyFunc, apply).WithBase(transport)
+	}
+
+	return &http.Client{Transport: transport}
+}
+
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// retryTransport sends again the requests rate limited by the server, and records the
+// Retry-After header of the error responses for the retryAfterRecorder of the request.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries uint
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := retry.Policy{
+		MaxRetries:   t.maxRetries,
+		InitialDelay: defaultRetryDelay,
+		Retryable: func(err error) bool {
+			httpErr, ok := httperror.As(err)
+			return ok && httpErr.StatusCode == http.StatusTooManyRequests
+		},
+		Name: "openai",
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// the body is consumed by the first attempt and can't be sent again
+		policy.MaxRetries = 0
+	}
+
+	var resp *http.Response
+	attempts := 0
+	err := retry.Do(req.Context(), policy, func(context.Context) error {
+		attemptReq := req
+		if attempts > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+		attempts++
+
+		var err error
+		resp, err = t.base.RoundTrip(attemptReq)
+		if err != nil {
+			resp = nil
+			return err
+		}
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			recordRetryAfter(req.Context(), resp.Header.Get("Retry-After"))
+		}
+		if resp.StatusCode != http.StatusTooManyRequests {
+			return nil
+		}
+
+		// the body is kept for the client, in case the retries are exhausted
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			resp = nil
+			return err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		return httperror.New(resp.StatusCode, body).WithRetryAfter(resp.Header.Get("Retry-After"))
+	})
+	if ctxErr := req.Context().Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if _, ok := httperror.As(err); ok && resp != nil {
+		// the retries are exhausted, the client decodes the rate limit response
+		return resp, nil
+	}
+
+	return resp, err
+}
+
+type retryAfterContextKey struct{}
+
+// retryAfterRecorder keeps the Retry-After header of the last error response of a
+// generation, since the errors of the client don't carry the headers.
+type retryAfterRecorder struct {
+	mu         sync.Mutex
+	retryAfter string
+}
+
+func withRetryAfterRecorder(ctx context.Context) (context.Context, *retryAfterRecorder) {
+	recorder := &retryAfterRecorder{}
+	return context.WithValue(ctx, retryAfterContextKey{}, recorder), recorder
+}
+
+func recordRetryAfter(ctx context.Context, retryAfter string) {
+	recorder, ok := ctx.Value(retryAfterContextKey{}).(*retryAfterRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.retryAfter = retryAfter
+}
+
+// wrap returns the API errors of the client as an httperror.Error, carrying the delay
+// suggested by the server for the retry middleware. Other errors are returned as they are.
+func (r *retryAfterRecorder) wrap(err error) error {
+	statusCode := 0
+	var apiErr *openai.APIError
+	var requestErr *openai.RequestError
+	if errors.As(err, &apiErr) {
+		statusCode = apiErr.HTTPStatusCode
+	} else if errors.As(err, &requestErr) {
+		statusCode = requestErr.HTTPStatusCode
+	}
+	if statusCode == 0 {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return httperror.Wrap(statusCode, err).WithRetryAfter(r.retryAfter)
+}
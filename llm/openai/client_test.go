@@ -0,0 +1,147 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/thread"
+)
+
+func newUserThread() *thread.Thread {
+	return thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+}
+
+func TestWithBodyFields(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello"}}]}`))
+	}))
+	defer server.Close()
+
+	llm := New().WithBaseURL(server.URL).WithAPIKey("key").
+		WithBodyFields(map[string]any{"safe_prompt": true}).
+		WithBodyField("response_format", map[string]any{"type": "grammar"})
+
+	err := llm.Generate(context.Background(), newUserThread())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body["safe_prompt"] != true {
+		t.Fatalf("expected safe_prompt, got %v", body)
+	}
+	if !reflect.DeepEqual(body["response_format"], map[string]any{"type": "grammar"}) {
+		t.Fatalf("expected the overridden response_format, got %v", body["response_format"])
+	}
+	if body["model"] != string(GPT3Dot5Turbo) {
+		t.Fatalf("expected the client fields to be kept, got %v", body)
+	}
+}
+
+func TestWithResponseFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   bool
+		response string
+	}{
+		{
+			name:     "json",
+			response: `{"choices":[{"message":{"role":"assistant","content":"hello"}}],"citations":["https://a.com"]}`,
+		},
+		{
+			name:   "stream",
+			stream: true,
+			response: "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"hello\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}],\"citations\":[\"https://a.com\"]}\n\n" +
+				"data: [DONE]\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tt.stream {
+					w.Header().Set("Content-Type", "text/event-stream")
+				} else {
+					w.Header().Set("Content-Type", "application/json")
+				}
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			llm := New().WithBaseURL(server.URL).WithAPIKey("key").WithResponseFields("citations")
+			if tt.stream {
+				llm.WithStream(true, func(string) {})
+			}
+
+			th := newUserThread()
+			err := llm.Generate(context.Background(), th)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := th.LastMessage().Metadata["citations"]; !reflect.DeepEqual(got, []any{"https://a.com"}) {
+				t.Fatalf("expected the citations in the metadata, got %v", got)
+			}
+		})
+	}
+}
+
+func TestWithMaxRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["safe_prompt"] != true {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"rate limited","code":429}}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello"}}]}`))
+	}))
+	defer server.Close()
+
+	llm := New().WithBaseURL(server.URL).WithAPIKey("key").WithBodyField("safe_prompt", true)
+
+	err := llm.Generate(context.Background(), newUserThread())
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("expected a rate limit error without retries, got %v", err)
+	}
+	if httpErr, ok := httperror.As(err); !ok || httpErr.RetryAfter != 10*time.Millisecond {
+		t.Fatalf("expected the Retry-After delay in the error, got %v", err)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	llm.WithMaxRetries(2)
+
+	th := newUserThread()
+	err = llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+	if th.LastMessage().Contents[0].AsString() != "hello" {
+		t.Fatalf("unexpected answer %v", th.LastMessage())
+	}
+}
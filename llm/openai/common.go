@@ -2,7 +2,9 @@ package openai
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/henomis/lingoose/thread"
 	"github.com/henomis/lingoose/types"
 	"github.com/sashabaranov/go-openai"
 )
@@ -10,6 +12,9 @@ import (
 var (
 	ErrOpenAICompletion = fmt.Errorf("openai completion error")
 	ErrOpenAIChat       = fmt.Errorf("openai chat error")
+	// ErrOpenAIRefusal is returned when the model refuses to answer with structured outputs.
+	ErrOpenAIRefusal = fmt.Errorf("openai refusal")
+	ErrOpenAIBatch   = fmt.Errorf("openai batch error")
 )
 
 const (
@@ -23,6 +28,10 @@ const (
 type Model string
 
 const (
+	O1                    Model = openai.O1
+	O1Mini                Model = openai.O1Mini
+	O1Preview             Model = openai.O1Preview
+	O3Mini                Model = openai.O3Mini
 	GPT432K0613           Model = openai.GPT432K0613
 	GPT432K0314           Model = openai.GPT432K0314
 	GPT432K               Model = openai.GPT432K
@@ -55,9 +64,97 @@ const (
 	GPT3Babbage002        Model = openai.GPT3Babbage002
 )
 
+// isReasoningModel reports whether the model is an o-series or DeepSeek reasoning model.
+// These models expect max_completion_tokens and reject sampling parameters such as
+// temperature and top_p.
+func isReasoningModel(model Model) bool {
+	name := string(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	for _, prefix := range []string{"o1", "o3", "o4", "deepseek-reasoner", "deepseek-r1"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
 type UsageCallback func(types.Meta)
 type StreamCallback func(string)
 
+// StreamEventCallback receives the typed events of a streamed generation.
+type StreamEventCallback func(StreamEvent)
+
+type StreamEventType string
+
+const (
+	StreamEventText       StreamEventType = "text"
+	StreamEventReasoning  StreamEventType = "reasoning"
+	StreamEventToolCall   StreamEventType = "tool_call"
+	StreamEventToolResult StreamEventType = "tool_result"
+	StreamEventUsage      StreamEventType = "usage"
+	StreamEventEnd        StreamEventType = "end"
+)
+
+// StreamEvent is a streamed generation event. Text is set by text and reasoning deltas,
+// ToolCall by tool call deltas, ToolResult by tool results, Usage by the usage event sent
+// before the end, and FinishReason by the end event.
+type StreamEvent struct {
+	Type         StreamEventType
+	Text         string
+	ToolCall     *ToolCallDelta
+	ToolResult   *thread.ToolResponseData
+	Usage        types.Meta
+	FinishReason FinishReason
+}
+
+// ToolCallDelta is a fragment of a streamed tool call: ID and Name are set by the first
+// fragment of each call, Arguments holds the next piece of the JSON arguments.
+type ToolCallDelta struct {
+	Index     int
+	ID        string
+	Name      string
+	Arguments string
+}
+
+const (
+	// MetadataFinishReason is the assistant message metadata key holding the FinishReason.
+	MetadataFinishReason = "finish_reason"
+	// MetadataSystemFingerprint is the assistant message metadata key holding the
+	// fingerprint of the backend configuration that generated the answer.
+	MetadataSystemFingerprint = "system_fingerprint"
+	// MetadataLogProbs is the assistant message metadata key holding the []LogProb of the
+	// answer tokens, when requested with WithLogProbs.
+	MetadataLogProbs = "logprobs"
+	// MetadataTruncated is set to true in the assistant message metadata when the stream has
+	// been interrupted by the context cancellation, the message holds the partial answer.
+	MetadataTruncated = "truncated"
+)
+
+type FinishReason = openai.FinishReason
+
+const (
+	FinishReasonStop          FinishReason = openai.FinishReasonStop
+	FinishReasonLength        FinishReason = openai.FinishReasonLength
+	FinishReasonToolCalls     FinishReason = openai.FinishReasonToolCalls
+	FinishReasonContentFilter FinishReason = openai.FinishReasonContentFilter
+)
+
+type LogProb = openai.LogProb
+
+type TopLogProb = openai.TopLogProbs
+
+type ImageDetail = openai.ImageURLDetail
+
+const (
+	ImageDetailAuto ImageDetail = openai.ImageURLDetailAuto
+	ImageDetailLow  ImageDetail = openai.ImageURLDetailLow
+	ImageDetailHigh ImageDetail = openai.ImageURLDetailHigh
+)
+
 type ResponseFormat = openai.ChatCompletionResponseFormatType
 
 const (
@@ -0,0 +1,186 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+// bodyFieldsTransport merges the fields in the JSON body of the chat completion requests.
+type bodyFieldsTransport struct {
+	base   http.RoundTripper
+	fields map[string]any
+}
+
+func (t *bodyFieldsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(body, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range t.fields {
+		rawValue, errMarshal := json.Marshal(value)
+		if errMarshal != nil {
+			return nil, errMarshal
+		}
+		fields[key] = rawValue
+	}
+
+	body, err = json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+
+	return t.base.RoundTrip(req)
+}
+
+type responseRecorderContextKey struct{}
+
+// responseRecorder keeps the body of the last chat completion response, to read the
+// fields not decoded by the client.
+type responseRecorder struct {
+	mu   sync.Mutex
+	body bytes.Buffer
+}
+
+func withResponseRecorder(ctx context.Context) (context.Context, *responseRecorder) {
+	recorder := &responseRecorder{}
+	return context.WithValue(ctx, responseRecorderContextKey{}, recorder), recorder
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.body.Write(p)
+}
+
+func (r *responseRecorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.body.Reset()
+}
+
+// fields returns the given fields of the JSON response or, for a stream, of the last
+// chunk having them.
+func (r *responseRecorder) fields(keys []string) map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values := make(map[string]any)
+
+	var response map[string]json.RawMessage
+	if json.Unmarshal(r.body.Bytes(), &response) == nil {
+		addFields(values, response, keys)
+		return values
+	}
+
+	for _, line := range bytes.Split(r.body.Bytes(), []byte("\n")) {
+		data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+		if !ok {
+			continue
+		}
+
+		var chunk map[string]json.RawMessage
+		if json.Unmarshal(bytes.TrimSpace(data), &chunk) == nil {
+			addFields(values, chunk, keys)
+		}
+	}
+
+	return values
+}
+
+func addFields(values map[string]any, response map[string]json.RawMessage, keys []string) {
+	for _, key := range keys {
+		rawValue, ok := response[key]
+		if !ok {
+			continue
+		}
+
+		var value any
+		if json.Unmarshal(rawValue, &value) == nil && value != nil {
+			values[key] = value
+		}
+	}
+}
+
+// addResponseFields sets the recorded response fields in the metadata of the last
+// assistant message.
+func (o *OpenAI) addResponseFields(recorder *responseRecorder, messages []*thread.Message) {
+	values := recorder.fields(o.responseFields)
+	if len(values) == 0 {
+		return
+	}
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != thread.RoleAssistant {
+			continue
+		}
+
+		for key, value := range values {
+			messages[i].AddMetadata(key, value)
+		}
+		return
+	}
+}
+
+// responseRecorderTransport records the response bodies of the requests whose context
+// has a responseRecorder.
+type responseRecorderTransport struct {
+	base http.RoundTripper
+}
+
+func (t *responseRecorderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder, ok := req.Context().Value(responseRecorderContextKey{}).(*responseRecorder)
+	if !ok {
+		return resp, nil
+	}
+
+	// a retried request replaces the previous response
+	recorder.reset()
+	resp.Body = &recordingBody{
+		Reader: io.TeeReader(resp.Body, recorder),
+		body:   resp.Body,
+	}
+
+	return resp, nil
+}
+
+type recordingBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (b *recordingBody) Close() error {
+	return b.body.Close()
+}
@@ -1,67 +1,152 @@
 package openai
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/henomis/lingoose/thread"
 	"github.com/sashabaranov/go-openai"
 )
 
-//nolint:gocognit
-func threadToChatCompletionMessages(t *thread.Thread) []openai.ChatCompletionMessage {
-	chatCompletionMessages := make([]openai.ChatCompletionMessage, len(t.Messages))
-	for i, message := range t.Messages {
-		chatCompletionMessages[i] = openai.ChatCompletionMessage{
-			Role: threadRoleToOpenAIRole[message.Role],
+func threadToChatCompletionMessages(t *thread.Thread, imageDetail ImageDetail) ([]openai.ChatCompletionMessage, error) {
+	chatCompletionMessages := make([]openai.ChatCompletionMessage, 0, len(t.Messages))
+	for _, message := range t.Messages {
+		chatCompletionMessage, ok, err := threadMessageToChatCompletionMessage(message, imageDetail)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
 		}
 
-		if len(message.Contents) > 1 {
-			chatCompletionMessages[i].MultiContent = threadContentsToChatMessageParts(message)
-			continue
+		chatCompletionMessages = append(chatCompletionMessages, chatCompletionMessage)
+	}
+
+	return chatCompletionMessages, nil
+}
+
+// threadMessageToChatCompletionMessage converts a thread message, reporting false for the
+// messages that have nothing to send, e.g. thinking-only ones, which must be skipped.
+//
+//nolint:gocognit
+func threadMessageToChatCompletionMessage(
+	message *thread.Message,
+	imageDetail ImageDetail,
+) (openai.ChatCompletionMessage, bool, error) {
+	chatCompletionMessage := openai.ChatCompletionMessage{
+		Role: threadRoleToOpenAIRole[message.Role],
+	}
+
+	// reasoning is not sent back to the model
+	message = withoutThinking(message)
+	if len(message.Contents) == 0 {
+		return chatCompletionMessage, false, nil
+	}
+
+	// audio contents are added to the request body by buildAudioRequestBody
+	if len(message.Contents) > 1 || message.Contents[0].Type == thread.ContentTypeImage ||
+		(message.Role == thread.RoleUser && messageHasAudio(message)) {
+		multiContent, err := threadContentsToChatMessageParts(message, imageDetail)
+		if err != nil {
+			return chatCompletionMessage, false, err
 		}
+		chatCompletionMessage.MultiContent = multiContent
+		return chatCompletionMessage, true, nil
+	}
 
-		switch message.Role {
-		case thread.RoleUser, thread.RoleSystem:
-			if data, isUserTextData := message.Contents[0].Data.(string); isUserTextData {
-				chatCompletionMessages[i].Content = data
-			} else {
-				continue
-			}
-		case thread.RoleAssistant:
-			if data, isAssistantTextData := message.Contents[0].Data.(string); isAssistantTextData {
-				chatCompletionMessages[i].Content = data
-			} else if data, isTollCallData := message.Contents[0].Data.([]thread.ToolCallData); isTollCallData {
-				var toolCalls []openai.ToolCall
-				for _, toolCallData := range data {
-					toolCalls = append(toolCalls, openai.ToolCall{
-						ID:   toolCallData.ID,
-						Type: "function",
-						Function: openai.FunctionCall{
-							Name:      toolCallData.Name,
-							Arguments: toolCallData.Arguments,
-						},
-					})
-				}
-				chatCompletionMessages[i].ToolCalls = toolCalls
-			} else {
-				continue
-			}
-		case thread.RoleTool:
-			if data, isTollResponseData := message.Contents[0].Data.(thread.ToolResponseData); isTollResponseData {
-				chatCompletionMessages[i].ToolCallID = data.ID
-				chatCompletionMessages[i].Name = data.Name
-				chatCompletionMessages[i].Content = data.Result
-			} else {
-				continue
+	switch message.Role {
+	case thread.RoleUser, thread.RoleSystem:
+		data, isUserTextData := message.Contents[0].Data.(string)
+		if !isUserTextData {
+			return chatCompletionMessage, false, nil
+		}
+		chatCompletionMessage.Content = data
+	case thread.RoleAssistant:
+		if data, isAssistantTextData := message.Contents[0].Data.(string); isAssistantTextData {
+			chatCompletionMessage.Content = data
+		} else if data, isTollCallData := message.Contents[0].Data.([]thread.ToolCallData); isTollCallData {
+			var toolCalls []openai.ToolCall
+			for _, toolCallData := range data {
+				toolCalls = append(toolCalls, openai.ToolCall{
+					ID:   toolCallData.ID,
+					Type: "function",
+					Function: openai.FunctionCall{
+						Name:      toolCallData.Name,
+						Arguments: toolCallData.Arguments,
+					},
+				})
 			}
+			chatCompletionMessage.ToolCalls = toolCalls
+		} else {
+			return chatCompletionMessage, false, nil
+		}
+	case thread.RoleTool:
+		data, isTollResponseData := message.Contents[0].Data.(thread.ToolResponseData)
+		if !isTollResponseData {
+			return chatCompletionMessage, false, nil
+		}
+		chatCompletionMessage.ToolCallID = data.ID
+		chatCompletionMessage.Name = data.Name
+		chatCompletionMessage.Content = data.Result
+	default:
+		return chatCompletionMessage, false, nil
+	}
+
+	return chatCompletionMessage, true, nil
+}
+
+func withoutThinking(m *thread.Message) *thread.Message {
+	contents := make([]*thread.Content, 0, len(m.Contents))
+	for _, content := range m.Contents {
+		if content.Type != thread.ContentTypeThinking {
+			contents = append(contents, content)
 		}
 	}
 
-	return chatCompletionMessages
+	if len(contents) == len(m.Contents) {
+		return m
+	}
+
+	return &thread.Message{Role: m.Role, Contents: contents, Metadata: m.Metadata}
 }
 
-func threadContentsToChatMessageParts(m *thread.Message) []openai.ChatMessagePart {
-	chatMessageParts := make([]openai.ChatMessagePart, len(m.Contents))
+func newAssistantMessage(reasoning, content string) *thread.Message {
+	message := thread.NewAssistantMessage()
+	if reasoning != "" {
+		message.AddContent(thread.NewThinkingContent(reasoning))
+	}
 
-	for i, content := range m.Contents {
+	return message.AddContent(thread.NewTextContent(content))
+}
+
+// addChoiceMetadata sets the finish reason, the system fingerprint and the log
+// probabilities, if any, on the assistant messages.
+func addChoiceMetadata(
+	messages []*thread.Message,
+	finishReason FinishReason,
+	systemFingerprint string,
+	logProbs *openai.LogProbs,
+) {
+	for _, message := range messages {
+		if message == nil || message.Role != thread.RoleAssistant {
+			continue
+		}
+
+		if finishReason != "" {
+			message.AddMetadata(MetadataFinishReason, finishReason)
+		}
+		if systemFingerprint != "" {
+			message.AddMetadata(MetadataSystemFingerprint, systemFingerprint)
+		}
+		if logProbs != nil {
+			message.AddMetadata(MetadataLogProbs, logProbs.Content)
+		}
+	}
+}
+
+func threadContentsToChatMessageParts(m *thread.Message, imageDetail ImageDetail) ([]openai.ChatMessagePart, error) {
+	chatMessageParts := make([]openai.ChatMessagePart, 0, len(m.Contents))
+
+	for _, content := range m.Contents {
 		var chatMessagePart *openai.ChatMessagePart
 
 		switch content.Type {
@@ -76,16 +161,16 @@ func threadContentsToChatMessageParts(m *thread.Message) []openai.ChatMessagePar
 				Text: contentAsString,
 			}
 		case thread.ContentTypeImage:
-			contentAsString, ok := content.Data.(string)
-			if !ok {
-				continue
+			url, err := imageURL(content)
+			if err != nil {
+				return nil, err
 			}
 
 			chatMessagePart = &openai.ChatMessagePart{
 				Type: openai.ChatMessagePartTypeImageURL,
 				ImageURL: &openai.ChatMessageImageURL{
-					URL:    contentAsString,
-					Detail: openai.ImageURLDetailAuto,
+					URL:    url,
+					Detail: imageDetail,
 				},
 			}
 		case thread.ContentTypeToolCall, thread.ContentTypeToolResponse:
@@ -94,19 +179,37 @@ func threadContentsToChatMessageParts(m *thread.Message) []openai.ChatMessagePar
 			continue
 		}
 
-		chatMessageParts[i] = *chatMessagePart
+		chatMessageParts = append(chatMessageParts, *chatMessagePart)
+	}
+
+	return chatMessageParts, nil
+}
+
+// imageURL returns the URL of an image content. Only remote and data URLs are sent: local
+// images must be loaded explicitly with thread.NewImageContentFromFile.
+func imageURL(content *thread.Content) (string, error) {
+	image, ok := content.Data.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: image data is not a URL", thread.ErrImage)
+	}
+
+	if strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://") ||
+		strings.HasPrefix(image, "data:") {
+		return image, nil
 	}
 
-	return chatMessageParts
+	return "", fmt.Errorf("%w: unsupported image source, use a URL or thread.NewImageContentFromFile", thread.ErrImage)
 }
 
-func toolCallResultToThreadMessage(toolCall openai.ToolCall, result string) *thread.Message {
+func toolCallResultToThreadMessage(toolCall openai.ToolCall, result string, value any, err error) *thread.Message {
 	return thread.NewToolMessage().AddContent(
 		thread.NewToolResponseContent(
 			thread.ToolResponseData{
 				ID:     toolCall.ID,
 				Name:   toolCall.Function.Name,
 				Result: result,
+				Value:  value,
+				Err:    err,
 			},
 		),
 	)
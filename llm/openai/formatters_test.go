@@ -0,0 +1,112 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestThreadToChatCompletionMessagesImages(t *testing.T) {
+	dataURL := "data:image/png;base64,iVBORw0KGgo="
+
+	messages, err := threadToChatCompletionMessages(thread.New().AddMessage(
+		thread.NewUserMessage().
+			AddContent(thread.NewTextContent("describe")).
+			AddContent(thread.NewImageContentFromURL("https://example.com/image.png")).
+			AddContent(thread.NewImageContentFromURL(dataURL)),
+	), ImageDetailAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || len(messages[0].MultiContent) != 3 {
+		t.Fatalf("unexpected messages %+v", messages)
+	}
+	if url := messages[0].MultiContent[2].ImageURL.URL; url != dataURL {
+		t.Fatalf("got %s, want %s", url, dataURL)
+	}
+
+	for _, image := range []string{"/etc/passwd", "iVBORw0KGgo="} {
+		_, err = threadToChatCompletionMessages(thread.New().AddMessage(
+			thread.NewUserMessage().AddContent(thread.NewImageContentFromURL(image)),
+		), ImageDetailAuto)
+		if !errors.Is(err, thread.ErrImage) {
+			t.Fatalf("expected thread.ErrImage for %s, got %v", image, err)
+		}
+	}
+}
+
+func TestThreadToChatCompletionMessagesSkipsEmptyMessages(t *testing.T) {
+	messages, err := threadToChatCompletionMessages(thread.New().AddMessages(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("question")),
+		thread.NewAssistantMessage().AddContent(thread.NewThinkingContent("reasoning only")),
+		thread.NewAssistantMessage().AddContent(thread.NewThinkingContent("reasoning")).
+			AddContent(thread.NewTextContent("answer")),
+	), ImageDetailAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d: %+v", len(messages), messages)
+	}
+	for _, message := range messages {
+		if message.Role == "" || message.Content == "" {
+			t.Fatalf("unexpected empty message %+v", message)
+		}
+	}
+}
+
+func TestBuildAudioRequestBodySkippedMessages(t *testing.T) {
+	o := New()
+	th := thread.New().AddMessages(
+		thread.NewAssistantMessage().AddContent(thread.NewThinkingContent("reasoning only")),
+		thread.NewUserMessage().AddContent(thread.NewAudioContent([]byte("audio"), thread.AudioFormatWAV)),
+	)
+
+	chatCompletionRequest, err := o.buildChatCompletionRequest(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := o.buildAudioRequestBody(th, chatCompletionRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var request struct {
+		Messages []struct {
+			Role    string           `json:"role"`
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+	}
+	if err = json.Unmarshal(body, &request); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.Messages) != 1 || request.Messages[0].Role != "user" ||
+		len(request.Messages[0].Content) != 1 || request.Messages[0].Content[0]["type"] != "input_audio" {
+		t.Fatalf("unexpected request %s", body)
+	}
+}
+
+func TestBuildChatCompletionRequestReasoningModel(t *testing.T) {
+	request, err := New().WithModel(O3Mini).WithMaxTokens(100).WithTemperature(0.5).
+		WithTopP(0.9).WithPresencePenalty(1).WithFrequencyPenalty(1).WithLogProbs(3).
+		buildChatCompletionRequest(context.Background(), thread.New().AddMessage(
+			thread.NewUserMessage().AddContent(thread.NewTextContent("question")),
+		))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = openai.NewReasoningValidator().Validate(request)
+	if err != nil {
+		t.Fatalf("request rejected by the client: %v", err)
+	}
+	if request.MaxCompletionTokens != 100 {
+		t.Fatalf("expected 100 max completion tokens, got %d", request.MaxCompletionTokens)
+	}
+}
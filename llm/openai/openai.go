@@ -5,15 +5,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	openai "github.com/sashabaranov/go-openai"
 
 	"github.com/henomis/lingoose/llm/cache"
 	llmobserver "github.com/henomis/lingoose/llm/observer"
+	"github.com/henomis/lingoose/llm/usagereport"
 	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/ratelimit"
+	"github.com/henomis/lingoose/secret"
 	"github.com/henomis/lingoose/thread"
 	"github.com/henomis/lingoose/types"
 )
@@ -33,14 +39,37 @@ type OpenAI struct {
 	openAIClient     *openai.Client
 	model            Model
 	temperature      float32
+	topP             float32
+	frequencyPenalty float32
+	presencePenalty  float32
+	logitBias        map[string]int
 	maxTokens        int
 	stop             []string
 	usageCallback    UsageCallback
 	functions        map[string]Function
 	streamCallbackFn StreamCallback
+	streamEventFn    StreamEventCallback
 	responseFormat   *ResponseFormat
+	imageDetail      ImageDetail
+	audioOutput      *audioOutput
+	seed             *int
+	logProbs         bool
+	topLogProbs      int
+	jsonSchema       *openai.ChatCompletionResponseFormatJSONSchema
 	toolChoice       *string
 	cache            *cache.Cache
+	apiKey           string
+	apiKeyFunc       func(ctx context.Context) string
+	baseURL          string
+	headers          map[string]string
+	bodyFields       map[string]any
+	responseFields   []string
+	maxRetries       uint
+	transport        http.RoundTripper
+	azure            *azureConfig
+	rateLimiter      *ratelimit.Limiter
+	toolWorkers      int
+	toolTimeout      time.Duration
 	Name             string
 }
 
@@ -50,6 +79,22 @@ func (o *OpenAI) WithModel(model Model) *OpenAI {
 	return o
 }
 
+type modelContextKey struct{}
+
+// ContextWithModel returns a context generating with the given model in place of the
+// configured one, so that a single instance can serve calls to different models
+// concurrently.
+func ContextWithModel(ctx context.Context, model Model) context.Context {
+	return context.WithValue(ctx, modelContextKey{}, model)
+}
+
+func (o *OpenAI) modelFor(ctx context.Context) Model {
+	if model, ok := ctx.Value(modelContextKey{}).(Model); ok && model != "" {
+		return model
+	}
+	return o.model
+}
+
 // WithTemperature sets the temperature to use for the OpenAI instance.
 func (o *OpenAI) WithTemperature(temperature float32) *OpenAI {
 	o.temperature = temperature
@@ -85,6 +130,20 @@ func (o *OpenAI) WithToolChoice(toolChoice *string) *OpenAI {
 	return o
 }
 
+// WithParallelToolCalls runs up to workers tool calls of the same turn concurrently,
+// the tools must be safe for concurrent use. By default tool calls run sequentially.
+func (o *OpenAI) WithParallelToolCalls(workers int) *OpenAI {
+	o.toolWorkers = workers
+	return o
+}
+
+// WithToolTimeout sets the maximum duration of a tool call, after which the model gets a
+// timeout error as the tool result.
+func (o *OpenAI) WithToolTimeout(timeout time.Duration) *OpenAI {
+	o.toolTimeout = timeout
+	return o
+}
+
 func (o *OpenAI) WithStream(enable bool, callbackFn StreamCallback) *OpenAI {
 	if !enable {
 		o.streamCallbackFn = nil
@@ -95,11 +154,84 @@ func (o *OpenAI) WithStream(enable bool, callbackFn StreamCallback) *OpenAI {
 	return o
 }
 
+// WithStreamEvents streams the generation delivering typed events to the callback: text,
+// reasoning and tool call deltas, tool results, usage and the end of the stream. It can be
+// used alongside WithStream.
+func (o *OpenAI) WithStreamEvents(callbackFn StreamEventCallback) *OpenAI {
+	o.streamEventFn = callbackFn
+	return o
+}
+
 func (o *OpenAI) WithCache(cache *cache.Cache) *OpenAI {
 	o.cache = cache
 	return o
 }
 
+// WithRateLimit limits the requests and the estimated tokens per minute, counting the
+// max tokens of the answer too.
+func (o *OpenAI) WithRateLimit(rpm, tpm int) *OpenAI {
+	return o.WithRateLimiter(ratelimit.New(rpm, tpm))
+}
+
+// WithRateLimiter sets a rate limiter, it can be shared with other LLMs and embedders
+// using the same API key.
+func (o *OpenAI) WithRateLimiter(rateLimiter *ratelimit.Limiter) *OpenAI {
+	o.rateLimiter = rateLimiter
+	return o
+}
+
+// WithImageDetail sets the detail level used by the model to look at the images.
+func (o *OpenAI) WithImageDetail(imageDetail ImageDetail) *OpenAI {
+	o.imageDetail = imageDetail
+	return o
+}
+
+// WithTopP sets the nucleus sampling probability mass, 1 by default. Change either the
+// temperature or top_p, not both.
+func (o *OpenAI) WithTopP(topP float32) *OpenAI {
+	o.topP = topP
+	return o
+}
+
+// WithFrequencyPenalty penalizes the tokens (-2 to 2) proportionally to how often they
+// already appeared, positive values reduce repetitions.
+func (o *OpenAI) WithFrequencyPenalty(frequencyPenalty float32) *OpenAI {
+	o.frequencyPenalty = frequencyPenalty
+	return o
+}
+
+// WithPresencePenalty penalizes the tokens (-2 to 2) that already appeared, positive
+// values push the model to talk about new topics.
+func (o *OpenAI) WithPresencePenalty(presencePenalty float32) *OpenAI {
+	o.presencePenalty = presencePenalty
+	return o
+}
+
+// WithLogitBias adds a bias (-100 to 100) to the likelihood of the tokens, keyed by token
+// ID: -100 bans a token, 100 makes it the only choice.
+func (o *OpenAI) WithLogitBias(logitBias map[string]int) *OpenAI {
+	o.logitBias = logitBias
+	return o
+}
+
+// WithSeed makes the sampling deterministic on a best effort basis: repeated requests
+// with the same seed and parameters should return the same result. The backend
+// configuration is set in the MetadataSystemFingerprint metadata of the assistant
+// message, results can differ when it changes.
+func (o *OpenAI) WithSeed(seed int) *OpenAI {
+	o.seed = &seed
+	return o
+}
+
+// WithLogProbs requests the log probabilities of the answer tokens, with the topLogProbs
+// most likely alternatives (0 to 20) at each position. They are set in the MetadataLogProbs
+// metadata of the assistant message.
+func (o *OpenAI) WithLogProbs(topLogProbs int) *OpenAI {
+	o.logProbs = true
+	o.topLogProbs = topLogProbs
+	return o
+}
+
 func (o *OpenAI) WithResponseFormat(responseFormat ResponseFormat) *OpenAI {
 	o.responseFormat = &responseFormat
 	return o
@@ -110,7 +242,15 @@ func (o *OpenAI) SetStop(stop []string) {
 	o.stop = stop
 }
 
-func (o *OpenAI) setUsageMetadata(usage openai.Usage) {
+// reportsUsage reports whether the usage of the request is passed to the usage callback
+// or to the usage reporter of the context.
+func (o *OpenAI) reportsUsage(ctx context.Context) bool {
+	return o.usageCallback != nil || usagereport.Enabled(ctx)
+}
+
+// setUsageMetadata passes the usage to the usage callback and to the usage reporter of
+// the context, with the tenant of the request if any.
+func (o *OpenAI) setUsageMetadata(ctx context.Context, usage openai.Usage) {
 	callbackMetadata := make(types.Meta)
 
 	err := mapstructure.Decode(usage, &callbackMetadata)
@@ -118,20 +258,31 @@ func (o *OpenAI) setUsageMetadata(usage openai.Usage) {
 		return
 	}
 
-	o.usageCallback(callbackMetadata)
+	if tenant := secret.Tenant(ctx); tenant != "" {
+		callbackMetadata[secret.UsageKeyTenant] = tenant
+	}
+
+	usagereport.Report(ctx, callbackMetadata)
+	if o.usageCallback != nil {
+		o.usageCallback(callbackMetadata)
+	}
 }
 
 func New() *OpenAI {
 	openAIKey := os.Getenv("OPENAI_API_KEY")
 
-	return &OpenAI{
-		openAIClient: openai.NewClient(openAIKey),
-		model:        GPT3Dot5Turbo,
-		temperature:  DefaultOpenAITemperature,
-		maxTokens:    DefaultOpenAIMaxTokens,
-		functions:    make(map[string]Function),
-		Name:         "openai",
+	o := &OpenAI{
+		apiKey:      openAIKey,
+		model:       GPT3Dot5Turbo,
+		temperature: DefaultOpenAITemperature,
+		topP:        DefaultOpenAITopP,
+		maxTokens:   DefaultOpenAIMaxTokens,
+		functions:   make(map[string]Function),
+		imageDetail: ImageDetailAuto,
+		Name:        "openai",
 	}
+
+	return o.withCustomClient()
 }
 
 func (o *OpenAI) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
@@ -190,13 +341,23 @@ func (o *OpenAI) Generate(ctx context.Context, t *thread.Thread) error {
 		}
 	}
 
-	chatCompletionRequest := o.buildChatCompletionRequest(t)
+	chatCompletionRequest, err := o.buildChatCompletionRequest(ctx, t)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
 
 	if len(o.functions) > 0 {
 		chatCompletionRequest.Tools = o.getChatCompletionRequestTools()
 		chatCompletionRequest.ToolChoice = o.getChatCompletionRequestToolChoice()
 	}
 
+	if o.rateLimiter != nil {
+		err = o.rateLimiter.Wait(ctx, ratelimit.ThreadTokens(t)+completionTokens(chatCompletionRequest))
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+		}
+	}
+
 	generation, err := o.startObserveGeneration(ctx, t)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
@@ -204,13 +365,25 @@ func (o *OpenAI) Generate(ctx context.Context, t *thread.Thread) error {
 
 	nMessageBeforeGeneration := len(t.Messages)
 
-	if o.streamCallbackFn != nil {
-		err = o.stream(ctx, t, chatCompletionRequest)
+	generateCtx, retryAfter := withRetryAfterRecorder(ctx)
+	var recorder *responseRecorder
+	if len(o.responseFields) > 0 {
+		generateCtx, recorder = withResponseRecorder(generateCtx)
+	}
+
+	if o.audioOutput != nil || hasAudioContent(t) {
+		err = o.generateAudio(generateCtx, t, chatCompletionRequest)
+	} else if o.streamCallbackFn != nil || o.streamEventFn != nil {
+		err = o.stream(generateCtx, t, chatCompletionRequest)
 	} else {
-		err = o.generate(ctx, t, chatCompletionRequest)
+		err = o.generate(generateCtx, t, chatCompletionRequest)
 	}
 	if err != nil {
-		return err
+		return retryAfter.wrap(err)
+	}
+
+	if recorder != nil {
+		o.addResponseFields(recorder, t.Messages[nMessageBeforeGeneration:])
 	}
 
 	err = o.stopObserveGeneration(ctx, generation, t.Messages[nMessageBeforeGeneration:])
@@ -249,30 +422,73 @@ func handleStreamToolCallResponse(
 }
 
 func (o *OpenAI) handleEndOfStream(
+	ctx context.Context,
 	messages []*thread.Message,
+	reasoning string,
 	content string,
 	currentToolCall *openai.ToolCall,
 	allToolCalls []openai.ToolCall,
 ) []*thread.Message {
-	o.streamCallbackFn(EOS)
+	if o.streamCallbackFn != nil {
+		o.streamCallbackFn(EOS)
+	}
 	if len(content) > 0 {
-		messages = append(messages, thread.NewAssistantMessage().AddContent(
-			thread.NewTextContent(content),
-		))
+		messages = append(messages, newAssistantMessage(reasoning, content))
 	}
 	if currentToolCall.ID != "" {
 		allToolCalls = append(allToolCalls, *currentToolCall)
 		messages = append(messages, toolCallsToToolCallMessage(allToolCalls))
-		messages = append(messages, o.callTools(allToolCalls)...)
+		toolMessages := o.callTools(ctx, allToolCalls)
+		for _, toolMessage := range toolMessages {
+			for _, toolContent := range toolMessage.Contents {
+				o.emitStreamEvent(StreamEvent{Type: StreamEventToolResult, ToolResult: toolContent.AsToolResponseData()})
+			}
+		}
+		messages = append(messages, toolMessages...)
 	}
 	return messages
 }
 
+func (o *OpenAI) emitStreamEvent(event StreamEvent) {
+	if o.streamEventFn != nil {
+		o.streamEventFn(event)
+	}
+}
+
+// emitStreamDeltas sends the typed events of a stream chunk.
+func (o *OpenAI) emitStreamDeltas(delta openai.ChatCompletionStreamChoiceDelta) {
+	if delta.ReasoningContent != "" {
+		o.emitStreamEvent(StreamEvent{Type: StreamEventReasoning, Text: delta.ReasoningContent})
+	}
+	if delta.Content != "" {
+		o.emitStreamEvent(StreamEvent{Type: StreamEventText, Text: delta.Content})
+	}
+	for i, toolCall := range delta.ToolCalls {
+		index := i
+		if toolCall.Index != nil {
+			index = *toolCall.Index
+		}
+		o.emitStreamEvent(StreamEvent{
+			Type: StreamEventToolCall,
+			ToolCall: &ToolCallDelta{
+				Index:     index,
+				ID:        toolCall.ID,
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			},
+		})
+	}
+}
+
 func (o *OpenAI) stream(
 	ctx context.Context,
 	t *thread.Thread,
 	chatCompletionRequest openai.ChatCompletionRequest,
 ) error {
+	if o.streamEventFn != nil || usagereport.Enabled(ctx) {
+		chatCompletionRequest.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
+
 	stream, err := o.openAIClient.CreateChatCompletionStream(
 		ctx,
 		chatCompletionRequest,
@@ -280,22 +496,50 @@ func (o *OpenAI) stream(
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
 	}
+	defer stream.Close()
 
-	var content string
+	var reasoning, content string
 	var messages []*thread.Message
 	var allToolCalls []openai.ToolCall
 	var currentToolCall openai.ToolCall
+	var finishReason openai.FinishReason
+	var systemFingerprint string
 	for {
+		if ctx.Err() != nil {
+			return o.handleCancelledStream(ctx, t, reasoning, content)
+		}
+
 		response, errRecv := stream.Recv()
 		if errors.Is(errRecv, io.EOF) {
-			messages = o.handleEndOfStream(messages, content, &currentToolCall, allToolCalls)
+			messages = o.handleEndOfStream(ctx, messages, reasoning, content, &currentToolCall, allToolCalls)
+			o.emitStreamEvent(StreamEvent{Type: StreamEventEnd, FinishReason: finishReason})
 			break
 		}
+		if errRecv != nil {
+			if ctx.Err() != nil {
+				return o.handleCancelledStream(ctx, t, reasoning, content)
+			}
+			return fmt.Errorf("%w: %w", ErrOpenAIChat, errRecv)
+		}
 
+		// with usage included, the last chunk has the usage and no choices
+		if response.Usage != nil {
+			o.handleStreamUsage(ctx, *response.Usage)
+		}
 		if len(response.Choices) == 0 {
+			if response.Usage != nil {
+				continue
+			}
 			return fmt.Errorf("%w: no choices returned", ErrOpenAIChat)
 		}
 
+		if response.Choices[0].FinishReason != "" {
+			finishReason = response.Choices[0].FinishReason
+		}
+		if response.SystemFingerprint != "" {
+			systemFingerprint = response.SystemFingerprint
+		}
+
 		if isStreamToolCallResponse(&response) {
 			updatedToolCall, isNewTool := handleStreamToolCallResponse(&response, &currentToolCall)
 			if isNewTool {
@@ -305,17 +549,45 @@ func (o *OpenAI) stream(
 				currentToolCall = updatedToolCall
 			}
 		} else {
+			reasoning += response.Choices[0].Delta.ReasoningContent
 			content += response.Choices[0].Delta.Content
 		}
 
-		o.streamCallbackFn(response.Choices[0].Delta.Content)
+		if o.streamCallbackFn != nil {
+			o.streamCallbackFn(response.Choices[0].Delta.Content)
+		}
+		o.emitStreamDeltas(response.Choices[0].Delta)
 	}
 
+	addChoiceMetadata(messages, finishReason, systemFingerprint, nil)
 	t.AddMessages(messages...)
 
 	return nil
 }
 
+// handleCancelledStream adds the partial answer to the thread, flagged as truncated, and
+// returns the context error. Pending tool calls are not run.
+func (o *OpenAI) handleCancelledStream(ctx context.Context, t *thread.Thread, reasoning, content string) error {
+	if content != "" || reasoning != "" {
+		t.AddMessage(newAssistantMessage(reasoning, content).AddMetadata(MetadataTruncated, true))
+	}
+
+	return fmt.Errorf("%w: %w", ErrOpenAIChat, ctx.Err())
+}
+
+func (o *OpenAI) handleStreamUsage(ctx context.Context, usage openai.Usage) {
+	if o.reportsUsage(ctx) {
+		o.setUsageMetadata(ctx, usage)
+	}
+
+	if o.streamEventFn != nil {
+		usageMetadata := make(types.Meta)
+		if err := mapstructure.Decode(usage, &usageMetadata); err == nil {
+			o.emitStreamEvent(StreamEvent{Type: StreamEventUsage, Usage: usageMetadata})
+		}
+	}
+}
+
 func (o *OpenAI) generate(
 	ctx context.Context,
 	t *thread.Thread,
@@ -329,49 +601,101 @@ func (o *OpenAI) generate(
 		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
 	}
 
-	if o.usageCallback != nil {
-		o.setUsageMetadata(response.Usage)
+	if o.reportsUsage(ctx) {
+		o.setUsageMetadata(ctx, response.Usage)
 	}
 
 	if len(response.Choices) == 0 {
 		return fmt.Errorf("%w: no choices returned", ErrOpenAIChat)
 	}
 
+	if response.Choices[0].Message.Refusal != "" {
+		return fmt.Errorf("%w: %s", ErrOpenAIRefusal, response.Choices[0].Message.Refusal)
+	}
+
 	var messages []*thread.Message
 	if response.Choices[0].FinishReason == "tool_calls" || len(response.Choices[0].Message.ToolCalls) > 0 {
 		messages = append(messages, toolCallsToToolCallMessage(response.Choices[0].Message.ToolCalls))
-		messages = append(messages, o.callTools(response.Choices[0].Message.ToolCalls)...)
+		messages = append(messages, o.callTools(ctx, response.Choices[0].Message.ToolCalls)...)
 	} else {
 		messages = []*thread.Message{
-			thread.NewAssistantMessage().AddContent(
-				thread.NewTextContent(response.Choices[0].Message.Content),
-			),
+			newAssistantMessage(response.Choices[0].Message.ReasoningContent, response.Choices[0].Message.Content),
 		}
 	}
 
+	addChoiceMetadata(
+		messages,
+		response.Choices[0].FinishReason,
+		response.SystemFingerprint,
+		response.Choices[0].LogProbs,
+	)
 	t.Messages = append(t.Messages, messages...)
 
 	return nil
 }
 
-func (o *OpenAI) buildChatCompletionRequest(t *thread.Thread) openai.ChatCompletionRequest {
+// completionTokens estimates the tokens of an answer for the rate limiter: the requested
+// limit, or DefaultOpenAIMaxTokens if the request has none.
+func completionTokens(chatCompletionRequest openai.ChatCompletionRequest) int {
+	tokens := chatCompletionRequest.MaxTokens + chatCompletionRequest.MaxCompletionTokens
+	if tokens <= 0 {
+		return DefaultOpenAIMaxTokens
+	}
+
+	return tokens
+}
+
+func (o *OpenAI) buildChatCompletionRequest(
+	ctx context.Context,
+	t *thread.Thread,
+) (openai.ChatCompletionRequest, error) {
+	model := o.modelFor(ctx)
+
 	var responseFormat *openai.ChatCompletionResponseFormat
 	if o.responseFormat != nil {
 		responseFormat = &openai.ChatCompletionResponseFormat{
 			Type: *o.responseFormat,
 		}
+		if *o.responseFormat == ResponseFormatJSONSchema {
+			responseFormat.JSONSchema = o.jsonSchema
+		}
 	}
 
-	return openai.ChatCompletionRequest{
-		Model:          string(o.model),
-		Messages:       threadToChatCompletionMessages(t),
-		MaxTokens:      o.maxTokens,
-		Temperature:    o.temperature,
-		N:              DefaultOpenAINumResults,
-		TopP:           DefaultOpenAITopP,
-		Stop:           o.stop,
-		ResponseFormat: responseFormat,
-	}
+	messages, err := threadToChatCompletionMessages(t, o.imageDetail)
+	if err != nil {
+		return openai.ChatCompletionRequest{}, err
+	}
+
+	chatCompletionRequest := openai.ChatCompletionRequest{
+		Model:            string(model),
+		Messages:         messages,
+		MaxTokens:        o.maxTokens,
+		Temperature:      o.temperature,
+		N:                DefaultOpenAINumResults,
+		TopP:             o.topP,
+		FrequencyPenalty: o.frequencyPenalty,
+		PresencePenalty:  o.presencePenalty,
+		LogitBias:        o.logitBias,
+		Stop:             o.stop,
+		ResponseFormat:   responseFormat,
+		LogProbs:         o.logProbs,
+		TopLogProbs:      o.topLogProbs,
+		Seed:             o.seed,
+	}
+
+	// the client rejects these options for the o-series models since v1.36
+	if isReasoningModel(model) {
+		chatCompletionRequest.MaxCompletionTokens = chatCompletionRequest.MaxTokens
+		chatCompletionRequest.MaxTokens = 0
+		chatCompletionRequest.Temperature = 0
+		chatCompletionRequest.TopP = 0
+		chatCompletionRequest.PresencePenalty = 0
+		chatCompletionRequest.FrequencyPenalty = 0
+		chatCompletionRequest.LogProbs = false
+		chatCompletionRequest.TopLogProbs = 0
+	}
+
+	return chatCompletionRequest, nil
 }
 
 func (o *OpenAI) getChatCompletionRequestTools() []openai.Tool {
@@ -408,34 +732,78 @@ func (o *OpenAI) getChatCompletionRequestToolChoice() any {
 	}
 }
 
-func (o *OpenAI) callTool(toolCall openai.ToolCall) (string, error) {
+func (o *OpenAI) callTool(ctx context.Context, toolCall openai.ToolCall) (any, string, error) {
 	fn, ok := o.functions[toolCall.Function.Name]
 	if !ok {
-		return "", fmt.Errorf("unknown function %s", toolCall.Function.Name)
+		return nil, "", fmt.Errorf("unknown function %s", toolCall.Function.Name)
 	}
 
-	resultAsJSON, err := callFnWithArgumentAsJSON(fn.Fn, toolCall.Function.Arguments)
-	if err != nil {
-		return "", err
+	if o.toolTimeout <= 0 {
+		return fn.CallValue(toolCall.Function.Arguments)
+	}
+
+	type callResult struct {
+		value  any
+		result string
+		err    error
 	}
 
-	return resultAsJSON, nil
+	// tools don't accept a context: on timeout the call is abandoned, not stopped
+	done := make(chan callResult, 1)
+	go func() {
+		value, result, err := fn.CallValue(toolCall.Function.Arguments)
+		done <- callResult{value, result, err}
+	}()
+
+	timer := time.NewTimer(o.toolTimeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.value, r.result, r.err
+	case <-timer.C:
+		return nil, "", fmt.Errorf("function %s timed out after %s", toolCall.Function.Name, o.toolTimeout)
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
 }
 
-func (o *OpenAI) callTools(toolCalls []openai.ToolCall) []*thread.Message {
+// callTools runs the tool calls with up to toolWorkers calls at a time. The results are
+// returned in the order of the calls.
+func (o *OpenAI) callTools(ctx context.Context, toolCalls []openai.ToolCall) []*thread.Message {
 	if len(o.functions) == 0 || len(toolCalls) == 0 {
 		return nil
 	}
 
-	var messages []*thread.Message
-	for _, toolCall := range toolCalls {
-		result, err := o.callTool(toolCall)
-		if err != nil {
-			result = fmt.Sprintf("error: %s", err)
-		}
+	workers := max(o.toolWorkers, 1)
+	semaphore := make(chan struct{}, workers)
+	messages := make([]*thread.Message, len(toolCalls))
+
+	var wg sync.WaitGroup
+	for i, toolCall := range toolCalls {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(i int, toolCall openai.ToolCall) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			// skip pending tool calls if the generation has been cancelled
+			err := ctx.Err()
+			var value any
+			result := ""
+			if err == nil {
+				value, result, err = o.callTool(ctx, toolCall)
+			}
+			if err != nil {
+				result = fmt.Sprintf("error: %s", err)
+			}
 
-		messages = append(messages, toolCallResultToThreadMessage(toolCall, result))
+			messages[i] = toolCallResultToThreadMessage(toolCall, result, value, err)
+		}(i, toolCall)
 	}
+	wg.Wait()
 
 	return messages
 }
@@ -444,10 +812,13 @@ func (o *OpenAI) startObserveGeneration(ctx context.Context, t *thread.Thread) (
 	return llmobserver.StartObserveGeneration(
 		ctx,
 		o.Name,
-		string(o.model),
+		string(o.modelFor(ctx)),
 		types.M{
-			"maxTokens":   o.maxTokens,
-			"temperature": o.temperature,
+			"maxTokens":        o.maxTokens,
+			"temperature":      o.temperature,
+			"topP":             o.topP,
+			"frequencyPenalty": o.frequencyPenalty,
+			"presencePenalty":  o.presencePenalty,
 		},
 		t,
 	)
@@ -0,0 +1,91 @@
+package openai
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/invopop/jsonschema"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	ResponseFormatJSONSchema ResponseFormat = openai.ChatCompletionResponseFormatTypeJSONSchema
+)
+
+type schemaMap map[string]any
+
+func (s schemaMap) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any(s))
+}
+
+// WithJSONSchema constrains the output to a JSON document matching the schema. In strict
+// mode the model is guaranteed to follow the schema, which must then list every property
+// as required and disallow additional properties.
+func (o *OpenAI) WithJSONSchema(name string, schema map[string]any, strict bool) *OpenAI {
+	o.responseFormat = &[]ResponseFormat{ResponseFormatJSONSchema}[0]
+	o.jsonSchema = &openai.ChatCompletionResponseFormatJSONSchema{
+		Name:   name,
+		Schema: schemaMap(schema),
+		Strict: strict,
+	}
+	return o
+}
+
+// WithStructuredOutput constrains the output, in strict mode, to a JSON document that can
+// be unmarshaled into v. The schema is derived from the type of v, a struct or a pointer
+// to a struct; all the fields are required.
+func (o *OpenAI) WithStructuredOutput(v any) *OpenAI {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	o.responseFormat = &[]ResponseFormat{ResponseFormatJSONSchema}[0]
+	o.jsonSchema = &openai.ChatCompletionResponseFormatJSONSchema{
+		Name:   t.Name(),
+		Schema: StructJSONSchema(v),
+		Strict: true,
+	}
+	return o
+}
+
+// StructJSONSchema returns the JSON schema of the type of v, a struct or a pointer to a
+// struct, as required by the structured outputs strict mode.
+func StructJSONSchema(v any) *jsonschema.Schema {
+	r := &jsonschema.Reflector{
+		DoNotReference: true,
+		Anonymous:      true,
+	}
+	schema := r.Reflect(v)
+	schema.Version = ""
+	schema.Definitions = nil
+
+	makeStrict(schema)
+
+	return schema
+}
+
+func makeStrict(schema *jsonschema.Schema) {
+	if schema == nil {
+		return
+	}
+
+	if schema.Properties != nil {
+		keys := schema.Properties.Keys()
+		schema.Required = keys
+		schema.AdditionalProperties = jsonschema.FalseSchema
+		for _, key := range keys {
+			property, _ := schema.Properties.Get(key)
+			if propertySchema, ok := property.(*jsonschema.Schema); ok {
+				makeStrict(propertySchema)
+			}
+		}
+	}
+
+	makeStrict(schema.Items)
+	for _, schemas := range [][]*jsonschema.Schema{schema.AnyOf, schema.OneOf, schema.AllOf} {
+		for _, s := range schemas {
+			makeStrict(s)
+		}
+	}
+}
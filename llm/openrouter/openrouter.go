@@ -0,0 +1,108 @@
+package openrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	goopenai "github.com/sashabaranov/go-openai"
+
+	"github.com/henomis/lingoose/llm/openai"
+	"github.com/henomis/lingoose/thread"
+)
+
+const (
+	defaultEndpoint = "https://openrouter.ai/api/v1"
+	// MetadataModel is the assistant message metadata key holding the model that answered.
+	MetadataModel = "model"
+)
+
+var (
+	ErrOpenRouterChat = errors.New("openrouter chat error")
+)
+
+// OpenRouter is an OpenAI compatible LLM for https://openrouter.ai. Models are tried in
+// order: when a model is rate limited or unavailable (429 or 5xx) the next one is used.
+type OpenRouter struct {
+	*openai.OpenAI
+	models []string
+}
+
+// New creates an OpenRouter LLM trying the given models in order, e.g.
+// "anthropic/claude-3.5-sonnet", "openai/gpt-4o". The API key is read from the
+// OPENROUTER_API_KEY environment variable.
+func New(models ...string) *OpenRouter {
+	openaillm := openai.New().
+		WithBaseURL(defaultEndpoint).
+		WithAPIKey(os.Getenv("OPENROUTER_API_KEY"))
+	openaillm.Name = "openrouter"
+
+	if len(models) > 0 {
+		openaillm.WithModel(openai.Model(models[0]))
+	}
+
+	return &OpenRouter{
+		OpenAI: openaillm,
+		models: models,
+	}
+}
+
+// WithModels sets the ordered list of models to try.
+func (o *OpenRouter) WithModels(models ...string) *OpenRouter {
+	o.models = models
+	return o
+}
+
+// WithAppInfo sets the site URL and the name used by OpenRouter to identify the app,
+// keeping the headers set with WithHeaders.
+func (o *OpenRouter) WithAppInfo(siteURL, appName string) *OpenRouter {
+	o.OpenAI.WithHeader("HTTP-Referer", siteURL).WithHeader("X-Title", appName)
+	return o
+}
+
+// Generate generates the thread with the first available model. The model that answered
+// is set in the MetadataModel metadata of the assistant message.
+func (o *OpenRouter) Generate(ctx context.Context, t *thread.Thread) error {
+	if len(o.models) == 0 {
+		return fmt.Errorf("%w: no models", ErrOpenRouterChat)
+	}
+
+	var errs []error
+	for _, model := range o.models {
+		nMessageBeforeGeneration := len(t.Messages)
+
+		// the model is set per call, so that concurrent generations don't share it
+		err := o.OpenAI.Generate(openai.ContextWithModel(ctx, openai.Model(model)), t)
+		if err == nil {
+			for _, message := range t.Messages[nMessageBeforeGeneration:] {
+				if message.Role == thread.RoleAssistant {
+					message.AddMetadata(MetadataModel, model)
+				}
+			}
+			return nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", model, err))
+		if !isRetryable(err) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	return fmt.Errorf("%w: %w", ErrOpenRouterChat, errors.Join(errs...))
+}
+
+func isRetryable(err error) bool {
+	statusCode := 0
+
+	var apiErr *goopenai.APIError
+	var requestErr *goopenai.RequestError
+	if errors.As(err, &apiErr) {
+		statusCode = apiErr.HTTPStatusCode
+	} else if errors.As(err, &requestErr) {
+		statusCode = requestErr.HTTPStatusCode
+	}
+
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,67 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestGenerateFallsBackOnRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		w.Header().Set("Content-Type", "application/json")
+		if req.Model == "primary/model" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"rate limited","code":429}}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"model":"` + req.Model + `","choices":[{"message":{"role":"assistant","content":"hello"}}]}`))
+	}))
+	defer server.Close()
+
+	llm := New("primary/model", "fallback/model")
+	llm.OpenAI.WithBaseURL(server.URL)
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := th.LastMessage().Metadata[MetadataModel]; got != "fallback/model" {
+		t.Fatalf("expected fallback/model, got %v", got)
+	}
+}
+
+func TestWithAppInfoKeepsHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Custom") != "custom" || r.Header.Get("X-Title") != "app" ||
+			r.Header.Get("HTTP-Referer") != "https://example.com" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"missing headers","code":400}}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello"}}]}`))
+	}))
+	defer server.Close()
+
+	llm := New("model")
+	llm.OpenAI.WithBaseURL(server.URL).WithHeaders(map[string]string{"X-Custom": "custom"})
+	llm.WithAppInfo("https://example.com", "app")
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	if err := llm.Generate(context.Background(), th); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,193 @@
+package replicate
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/henomis/restclientgo"
+)
+
+const (
+	jsonContentType        = "application/json"
+	eventStreamContentType = "text/event-stream"
+)
+
+type createPredictionRequest struct {
+	model   string
+	Version string         `json:"version,omitempty"`
+	Input   map[string]any `json:"input"`
+	Stream  bool           `json:"stream,omitempty"`
+}
+
+// Path uses the model endpoint for official models ("owner/name") and the predictions
+// endpoint for a specific version ("owner/name:version").
+func (r *createPredictionRequest) Path() (string, error) {
+	if r.Version != "" {
+		return "/predictions", nil
+	}
+
+	return "/models/" + r.model + "/predictions", nil
+}
+
+func (r *createPredictionRequest) Encode() (io.Reader, error) {
+	jsonBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
+
+func (r *createPredictionRequest) ContentType() string {
+	return jsonContentType
+}
+
+type predictionRequest struct {
+	ID     string
+	action string
+}
+
+func (r *predictionRequest) Path() (string, error) {
+	return "/predictions/" + r.ID + r.action, nil
+}
+
+func (r *predictionRequest) Encode() (io.Reader, error) {
+	return nil, nil
+}
+
+func (r *predictionRequest) ContentType() string {
+	return ""
+}
+
+type predictionStatus string
+
+const (
+	predictionStatusStarting   predictionStatus = "starting"
+	predictionStatusProcessing predictionStatus = "processing"
+	predictionStatusSucceeded  predictionStatus = "succeeded"
+	predictionStatusFailed     predictionStatus = "failed"
+	predictionStatusCanceled   predictionStatus = "canceled"
+)
+
+func (s predictionStatus) terminated() bool {
+	return s == predictionStatusSucceeded || s == predictionStatusFailed || s == predictionStatusCanceled
+}
+
+type predictionResponse struct {
+	HTTPStatusCode int                  `json:"-"`
+	RawBody        []byte               `json:"-"`
+	Headers        restclientgo.Headers `json:"-"`
+	ID             string               `json:"id"`
+	Status         predictionStatus     `json:"status"`
+	Output         any                  `json:"output"`
+	Error          any                  `json:"error"`
+	URLs           predictionURLs       `json:"urls"`
+	Metrics        *metrics             `json:"metrics,omitempty"`
+}
+
+type predictionURLs struct {
+	Get    string `json:"get"`
+	Cancel string `json:"cancel"`
+	Stream string `json:"stream"`
+}
+
+type metrics struct {
+	InputTokenCount  int     `json:"input_token_count"`
+	OutputTokenCount int     `json:"output_token_count"`
+	PredictTime      float64 `json:"predict_time"`
+}
+
+// text returns the output of language models, either a string or a list of tokens.
+func (r *predictionResponse) text() string {
+	switch output := r.Output.(type) {
+	case string:
+		return output
+	case []any:
+		var text strings.Builder
+		for _, token := range output {
+			if tokenAsString, ok := token.(string); ok {
+				text.WriteString(tokenAsString)
+			}
+		}
+		return text.String()
+	}
+
+	return ""
+}
+
+func (r *predictionResponse) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(r)
+}
+
+func (r *predictionResponse) SetBody(body io.Reader) error {
+	r.RawBody, _ = io.ReadAll(body)
+	return nil
+}
+
+func (r *predictionResponse) AcceptContentType() string {
+	return jsonContentType
+}
+
+func (r *predictionResponse) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *predictionResponse) SetHeaders(headers restclientgo.Headers) error {
+	r.Headers = headers
+	return nil
+}
+
+type streamRequest struct{}
+
+func (r *streamRequest) Path() (string, error) {
+	return "", nil
+}
+
+func (r *streamRequest) Encode() (io.Reader, error) {
+	return nil, nil
+}
+
+func (r *streamRequest) ContentType() string {
+	return ""
+}
+
+type streamResponse struct {
+	HTTPStatusCode   int                  `json:"-"`
+	RawBody          []byte               `json:"-"`
+	Headers          restclientgo.Headers `json:"-"`
+	streamCallbackFn restclientgo.StreamCallback
+}
+
+func (r *streamResponse) Decode(_ io.Reader) error {
+	return nil
+}
+
+func (r *streamResponse) SetBody(body io.Reader) error {
+	r.RawBody, _ = io.ReadAll(body)
+	return nil
+}
+
+func (r *streamResponse) AcceptContentType() string {
+	return eventStreamContentType
+}
+
+func (r *streamResponse) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *streamResponse) SetHeaders(headers restclientgo.Headers) error {
+	r.Headers = headers
+	return nil
+}
+
+func (r *streamResponse) SetStreamCallback(fn restclientgo.StreamCallback) {
+	r.streamCallbackFn = fn
+}
+
+func (r *streamResponse) StreamCallback() restclientgo.StreamCallback {
+	return r.streamCallbackFn
+}
@@ -0,0 +1,526 @@
+// Package replicate provides LLMs hosted on https://replicate.com.
+package replicate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/llm/cache"
+	"github.com/henomis/lingoose/llm/httperror"
+	llmobserver "github.com/henomis/lingoose/llm/observer"
+	"github.com/henomis/lingoose/llm/usagereport"
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultEndpoint        = "https://api.replicate.com/v1"
+	defaultMaxTokens       = 1024
+	defaultPollingInterval = time.Second
+	EOS                    = "\x00"
+)
+
+const (
+	ModelLlama3Dot1405BInstruct = "meta/meta-llama-3.1-405b-instruct"
+	ModelLlama370BInstruct      = "meta/meta-llama-3-70b-instruct"
+	ModelLlama38BInstruct       = "meta/meta-llama-3-8b-instruct"
+	ModelMixtral8x7BInstruct    = "mistralai/mixtral-8x7b-instruct-v0.1"
+)
+
+var (
+	ErrReplicateChat = errors.New("replicate chat error")
+)
+
+type StreamCallbackFn func(string)
+
+type UsageCallback func(types.Meta)
+
+// PromptFormatter renders a thread into the model prompt.
+type PromptFormatter func(t *thread.Thread) string
+
+// Replicate runs language models as Replicate predictions. Predictions are asynchronous:
+// Generate creates the prediction and polls it until it's completed or, when streaming,
+// reads its server-sent events stream.
+type Replicate struct {
+	restClient       *restclientgo.RestClient
+	httpClient       *http.Client
+	model            string
+	maxTokens        int
+	temperature      *float32
+	topP             *float32
+	seed             *int
+	stop             []string
+	input            map[string]any
+	promptFormatter  PromptFormatter
+	pollingInterval  time.Duration
+	streamCallbackFn StreamCallbackFn
+	usageCallback    UsageCallback
+	cache            *cache.Cache
+	name             string
+}
+
+// New creates a Replicate LLM for the given model, either an official model
+// ("owner/name") or a specific version ("owner/name:version"). The API token is read
+// from the REPLICATE_API_TOKEN environment variable.
+func New(model string) *Replicate {
+	token := os.Getenv("REPLICATE_API_TOKEN")
+
+	return &Replicate{
+		restClient: restclientgo.New(defaultEndpoint).WithRequestModifier(
+			func(req *http.Request) *http.Request {
+				req.Header.Set("Authorization", "Bearer "+token)
+				return req
+			},
+		),
+		httpClient:      http.DefaultClient,
+		model:           model,
+		maxTokens:       defaultMaxTokens,
+		input:           make(map[string]any),
+		pollingInterval: defaultPollingInterval,
+		name:            "replicate",
+	}
+}
+
+// WithToken sets the Replicate API token.
+func (r *Replicate) WithToken(token string) *Replicate {
+	r.restClient.SetRequestModifier(
+		func(req *http.Request) *http.Request {
+			req.Header.Set("Authorization", "Bearer "+token)
+			return req
+		},
+	)
+	return r
+}
+
+func (r *Replicate) WithEndpoint(endpoint string) *Replicate {
+	r.restClient.SetEndpoint(endpoint)
+	return r
+}
+
+func (r *Replicate) WithModel(model string) *Replicate {
+	r.model = model
+	return r
+}
+
+func (r *Replicate) WithMaxTokens(maxTokens int) *Replicate {
+	r.maxTokens = maxTokens
+	return r
+}
+
+func (r *Replicate) WithTemperature(temperature float32) *Replicate {
+	r.temperature = &temperature
+	return r
+}
+
+func (r *Replicate) WithTopP(topP float32) *Replicate {
+	r.topP = &topP
+	return r
+}
+
+func (r *Replicate) WithSeed(seed int) *Replicate {
+	r.seed = &seed
+	return r
+}
+
+func (r *Replicate) WithStop(stop []string) *Replicate {
+	r.stop = stop
+	return r
+}
+
+// WithInput sets a model specific input, e.g. "presence_penalty" or "min_tokens".
+func (r *Replicate) WithInput(key string, value any) *Replicate {
+	r.input[key] = value
+	return r
+}
+
+// WithPromptFormatter sets the function rendering the whole thread into the prompt,
+// bypassing the model prompt template. By default the system messages are sent as
+// system_prompt and the conversation as prompt, letting the model apply its template.
+func (r *Replicate) WithPromptFormatter(promptFormatter PromptFormatter) *Replicate {
+	r.promptFormatter = promptFormatter
+	return r
+}
+
+// WithPollingInterval sets how often a running prediction is polled, 1 second by default.
+func (r *Replicate) WithPollingInterval(pollingInterval time.Duration) *Replicate {
+	r.pollingInterval = pollingInterval
+	return r
+}
+
+func (r *Replicate) WithStream(callbackFn StreamCallbackFn) *Replicate {
+	r.streamCallbackFn = callbackFn
+	return r
+}
+
+func (r *Replicate) WithUsageCallback(callback UsageCallback) *Replicate {
+	r.usageCallback = callback
+	return r
+}
+
+func (r *Replicate) WithCache(cache *cache.Cache) *Replicate {
+	r.cache = cache
+	return r
+}
+
+// WithHTTPClient sets the http client to use for the LLM
+func (r *Replicate) WithHTTPClient(httpClient *http.Client) *Replicate {
+	r.httpClient = httpClient
+	r.restClient.SetHTTPClient(httpClient)
+	return r
+}
+
+func (r *Replicate) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
+	messages := t.UserQuery()
+	cacheQuery := strings.Join(messages, "\n")
+	cacheResult, err := r.cache.Get(ctx, cacheQuery)
+	if err != nil {
+		return cacheResult, err
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(strings.Join(cacheResult.Answer, "\n")),
+	))
+
+	return cacheResult, nil
+}
+
+func (r *Replicate) setCache(ctx context.Context, t *thread.Thread, cacheResult *cache.Result) error {
+	lastMessage := t.LastMessage()
+
+	if lastMessage.Role != thread.RoleAssistant || len(lastMessage.Contents) == 0 {
+		return nil
+	}
+
+	contents := make([]string, 0)
+	for _, content := range lastMessage.Contents {
+		if content.Type == thread.ContentTypeText {
+			contents = append(contents, content.Data.(string))
+		} else {
+			contents = make([]string, 0)
+			break
+		}
+	}
+
+	err := r.cache.Set(ctx, cacheResult.Embedding, strings.Join(contents, "\n"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Replicate) Generate(ctx context.Context, t *thread.Thread) error {
+	if t == nil {
+		return nil
+	}
+
+	var err error
+	var cacheResult *cache.Result
+	if r.cache != nil {
+		cacheResult, err = r.getCache(ctx, t)
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, cache.ErrCacheMiss) {
+			return fmt.Errorf("%w: %w", ErrReplicateChat, err)
+		}
+	}
+
+	generation, err := r.startObserveGeneration(ctx, t)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrReplicateChat, err)
+	}
+
+	request := r.buildRequest(t)
+	request.Stream = r.streamCallbackFn != nil
+
+	prediction := &predictionResponse{}
+	err = r.restClient.Post(ctx, request, prediction)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrReplicateChat, err)
+	} else if prediction.HTTPStatusCode >= http.StatusBadRequest {
+		httpErr := httperror.New(prediction.HTTPStatusCode, prediction.RawBody).WithHeaders(prediction.Headers)
+		return fmt.Errorf("%w: %w", ErrReplicateChat, httpErr)
+	}
+
+	var generatedText string
+	if r.streamCallbackFn != nil && prediction.URLs.Stream != "" {
+		generatedText, err = r.stream(ctx, prediction)
+	} else {
+		generatedText, err = r.wait(ctx, prediction)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			r.cancel(ctx, prediction.ID)
+		}
+		return fmt.Errorf("%w: %w", ErrReplicateChat, err)
+	}
+
+	message := thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(generatedText),
+	)
+	t.AddMessage(message)
+
+	err = r.stopObserveGeneration(ctx, generation, []*thread.Message{message})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrReplicateChat, err)
+	}
+
+	if r.cache != nil {
+		err = r.setCache(ctx, t, cacheResult)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrReplicateChat, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Replicate) buildRequest(t *thread.Thread) *createPredictionRequest {
+	input := map[string]any{
+		"max_tokens": r.maxTokens,
+	}
+
+	if r.promptFormatter != nil {
+		input["prompt"] = r.promptFormatter(t)
+		input["prompt_template"] = "{prompt}"
+	} else {
+		systemPrompt, prompt := threadToPrompt(t)
+		input["prompt"] = prompt
+		if systemPrompt != "" {
+			input["system_prompt"] = systemPrompt
+		}
+	}
+
+	if r.temperature != nil {
+		input["temperature"] = *r.temperature
+	}
+	if r.topP != nil {
+		input["top_p"] = *r.topP
+	}
+	if r.seed != nil {
+		input["seed"] = *r.seed
+	}
+	if len(r.stop) > 0 {
+		input["stop_sequences"] = strings.Join(r.stop, ",")
+	}
+	for key, value := range r.input {
+		input[key] = value
+	}
+
+	model, version, _ := strings.Cut(r.model, ":")
+
+	return &createPredictionRequest{
+		model:   model,
+		Version: version,
+		Input:   input,
+	}
+}
+
+// wait polls the prediction until it's terminated.
+func (r *Replicate) wait(ctx context.Context, prediction *predictionResponse) (string, error) {
+	for !prediction.Status.terminated() {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(r.pollingInterval):
+		}
+
+		var err error
+		prediction, err = r.getPrediction(ctx, prediction.ID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if prediction.Status != predictionStatusSucceeded {
+		return "", fmt.Errorf("prediction %s: %v", prediction.Status, prediction.Error)
+	}
+
+	r.setUsageMetadata(ctx, prediction.Metrics)
+
+	return prediction.text(), nil
+}
+
+// stream reads the prediction server-sent events: output events carry the generated
+// tokens, while error and done events end the stream.
+func (r *Replicate) stream(ctx context.Context, prediction *predictionResponse) (string, error) {
+	var generatedText string
+	var streamErr error
+	var event string
+	var data []string
+
+	resp := &streamResponse{}
+	resp.SetStreamCallback(
+		func(line []byte) error {
+			lineAsString := string(line)
+			switch {
+			case strings.HasPrefix(lineAsString, "event:"):
+				event = strings.TrimSpace(strings.TrimPrefix(lineAsString, "event:"))
+			case strings.HasPrefix(lineAsString, "data:"):
+				data = append(data, strings.TrimPrefix(strings.TrimPrefix(lineAsString, "data:"), " "))
+			case lineAsString == "":
+				switch event {
+				case "output":
+					token := strings.Join(data, "\n")
+					generatedText += token
+					r.streamCallbackFn(token)
+				case "error":
+					streamErr = errors.New(strings.Join(data, "\n"))
+				case "done":
+					r.streamCallbackFn(EOS)
+				}
+				event, data = "", nil
+			}
+			return nil
+		},
+	)
+
+	streamClient := restclientgo.New(prediction.URLs.Stream).
+		WithHTTPClient(r.httpClient).
+		WithRequestModifier(
+			func(req *http.Request) *http.Request {
+				req.Header.Set("Accept", eventStreamContentType)
+				req.Header.Set("Cache-Control", "no-store")
+				return req
+			},
+		)
+
+	err := streamClient.Get(ctx, &streamRequest{}, resp)
+	if err != nil {
+		return "", err
+	} else if resp.HTTPStatusCode >= http.StatusBadRequest {
+		return "", httperror.New(resp.HTTPStatusCode, resp.RawBody).WithHeaders(resp.Headers)
+	} else if streamErr != nil {
+		return "", streamErr
+	}
+
+	if r.usageCallback != nil || usagereport.Enabled(ctx) {
+		// metrics are only available once the prediction is completed
+		if completed, errGet := r.getPrediction(ctx, prediction.ID); errGet == nil {
+			r.setUsageMetadata(ctx, completed.Metrics)
+		}
+	}
+
+	return generatedText, nil
+}
+
+func (r *Replicate) getPrediction(ctx context.Context, id string) (*predictionResponse, error) {
+	prediction := &predictionResponse{}
+	err := r.restClient.Get(ctx, &predictionRequest{ID: id}, prediction)
+	if err != nil {
+		return nil, err
+	} else if prediction.HTTPStatusCode >= http.StatusBadRequest {
+		return nil, httperror.New(prediction.HTTPStatusCode, prediction.RawBody).WithHeaders(prediction.Headers)
+	}
+
+	return prediction, nil
+}
+
+// cancel stops a prediction abandoned by the caller, so that it isn't billed further.
+func (r *Replicate) cancel(ctx context.Context, id string) {
+	if id == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	_ = r.restClient.Post(ctx, &predictionRequest{ID: id, action: "/cancel"}, &predictionResponse{})
+}
+
+func (r *Replicate) setUsageMetadata(ctx context.Context, m *metrics) {
+	if m == nil {
+		return
+	}
+
+	usageMetadata := types.Meta{
+		"PromptTokens":     m.InputTokenCount,
+		"CompletionTokens": m.OutputTokenCount,
+		"TotalTokens":      m.InputTokenCount + m.OutputTokenCount,
+		"PredictTime":      m.PredictTime,
+	}
+
+	usagereport.Report(ctx, usageMetadata)
+	if r.usageCallback != nil {
+		r.usageCallback(usageMetadata)
+	}
+}
+
+// threadToPrompt returns the system prompt and the conversation. A single user message
+// is sent as is, longer conversations are rendered as a transcript.
+func threadToPrompt(t *thread.Thread) (string, string) {
+	var systemPrompt []string
+	var messages []*thread.Message
+	for _, m := range t.Messages {
+		if m.Role == thread.RoleSystem {
+			systemPrompt = append(systemPrompt, messageText(m))
+		} else {
+			messages = append(messages, m)
+		}
+	}
+
+	if len(messages) == 1 && messages[0].Role == thread.RoleUser {
+		return strings.Join(systemPrompt, "\n"), messageText(messages[0])
+	}
+
+	var prompt strings.Builder
+	for _, m := range messages {
+		role := "User"
+		if m.Role == thread.RoleAssistant {
+			role = "Assistant"
+		}
+		prompt.WriteString(role + ": " + messageText(m) + "\n")
+	}
+	prompt.WriteString("Assistant:")
+
+	return strings.Join(systemPrompt, "\n"), prompt.String()
+}
+
+func messageText(m *thread.Message) string {
+	var text string
+	for _, c := range m.Contents {
+		switch data := c.Data.(type) {
+		case string:
+			if c.Type == thread.ContentTypeText {
+				text += data
+			}
+		case thread.ToolResponseData:
+			text += data.Result
+		}
+	}
+
+	return text
+}
+
+func (r *Replicate) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
+	return llmobserver.StartObserveGeneration(
+		ctx,
+		r.name,
+		r.model,
+		types.M{
+			"maxTokens":   r.maxTokens,
+			"temperature": r.temperature,
+		},
+		t,
+	)
+}
+
+func (r *Replicate) stopObserveGeneration(
+	ctx context.Context,
+	generation *observer.Generation,
+	messages []*thread.Message,
+) error {
+	return llmobserver.StopObserveGeneration(
+		ctx,
+		generation,
+		messages,
+	)
+}
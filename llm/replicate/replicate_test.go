@@ -0,0 +1,77 @@
+package replicate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestGeneratePollsPrediction(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/models/meta/meta-llama-3-8b-instruct/predictions":
+			_, _ = w.Write([]byte(`{"id":"p1","status":"starting"}`))
+		case "/predictions/p1":
+			polls++
+			if polls < 2 {
+				_, _ = w.Write([]byte(`{"id":"p1","status":"processing","output":["Hel"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"p1","status":"succeeded","output":["Hel","lo"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	llm := New(ModelLlama38BInstruct).WithEndpoint(server.URL).WithPollingInterval(time.Millisecond)
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := th.LastMessage().Contents[0].AsString(); got != "Hello" || polls != 2 {
+		t.Fatalf("unexpected output %q after %d polls", got, polls)
+	}
+}
+
+func TestGenerateStreamsPrediction(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/predictions", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"p1","status":"starting","urls":{"stream":"%s/stream/p1"}}`, server.URL)
+	})
+	mux.HandleFunc("/stream/p1", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: output\ndata: Hel\n\nevent: output\ndata: lo\n\nevent: done\ndata: {}\n\n")
+	})
+
+	var streamed string
+	llm := New("owner/model:v1").WithEndpoint(server.URL).WithStream(func(s string) {
+		if s != EOS {
+			streamed += s
+		}
+	})
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := th.LastMessage().Contents[0].AsString(); got != "Hello" || streamed != "Hello" {
+		t.Fatalf("unexpected output %q, streamed %q", got, streamed)
+	}
+}
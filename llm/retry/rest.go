@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/llm/httperror"
+)
+
+// Response is the pointer to a restclientgo response type R.
+type Response[R any] interface {
+	*R
+	restclientgo.Response
+}
+
+// Post sends the request with the rest client, retrying it as set by the policy, and
+// returns the response of the successful attempt. Error statuses are returned as an
+// httperror.Error, carrying the Retry-After delay suggested by the server. It's the
+// request loop of the REST embedders.
+func Post[R any, PR Response[R]](
+	ctx context.Context,
+	restClient *restclientgo.RestClient,
+	policy Policy,
+	req restclientgo.Request,
+) (PR, error) {
+	var resp PR
+	err := Do(ctx, policy, func(ctx context.Context) error {
+		resp = PR(new(R))
+		recorder := &responseRecorder{Response: resp}
+
+		err := restClient.Post(ctx, req, recorder)
+		if err != nil {
+			return err
+		}
+
+		if recorder.statusCode >= http.StatusBadRequest {
+			return httperror.New(recorder.statusCode, recorder.body).WithHeaders(recorder.headers)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// responseRecorder records the status code, the headers and the error body of the
+// response it wraps.
+type responseRecorder struct {
+	restclientgo.Response
+	statusCode int
+	headers    restclientgo.Headers
+	body       []byte
+}
+
+func (r *responseRecorder) SetStatusCode(code int) error {
+	r.statusCode = code
+	return r.Response.SetStatusCode(code)
+}
+
+func (r *responseRecorder) SetHeaders(headers restclientgo.Headers) error {
+	r.headers = headers
+	return r.Response.SetHeaders(headers)
+}
+
+func (r *responseRecorder) SetBody(body io.Reader) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	r.body = b
+	return r.Response.SetBody(bytes.NewReader(b))
+}
@@ -0,0 +1,158 @@
+// Package retry retries the calls failing with a transient error, such as a rate limit,
+// a server error or a timeout, with a jittered exponential backoff. It is the retry loop
+// of the LLM middleware, of the OpenAI client and of the embedders.
+package retry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/henomis/lingoose/llm/httperror"
+	"github.com/henomis/lingoose/logger"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultInitialDelay = 500 * time.Millisecond
+	defaultMaxDelay     = 30 * time.Second
+	defaultMultiplier   = 2
+	defaultJitter       = 0.2
+)
+
+// Policy configures the retries. Zero delays and multiplier take the defaults.
+type Policy struct {
+	MaxRetries   uint
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	// Jitter randomly shortens each delay by up to this fraction, so that clients
+	// failing together don't retry together.
+	Jitter float64
+	// AttemptTimeout bounds each attempt, a timed out attempt is retried. No limit if zero.
+	AttemptTimeout time.Duration
+	// Retryable decides whether an error is transient, Temporary if nil.
+	Retryable func(error) bool
+	// OnRetry is called before waiting for the next attempt.
+	OnRetry func(attempt uint, err error, delay time.Duration)
+	// Logger logs the retries at debug level, the default logger of the logger package
+	// if nil.
+	Logger *slog.Logger
+	// Name names the retried calls in the logs, e.g. "llm" logs "llm retry".
+	Name string
+}
+
+// DefaultPolicy retries 3 times starting from 500ms, doubling the delay up to 30s.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxRetries:   defaultMaxRetries,
+		InitialDelay: defaultInitialDelay,
+		MaxDelay:     defaultMaxDelay,
+		Multiplier:   defaultMultiplier,
+		Jitter:       defaultJitter,
+	}
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable or the
+// retries are exhausted, returning its last error. When the error is an httperror.Error
+// with a RetryAfter delay suggested by the server, it's used in place of the backoff.
+func Do(ctx context.Context, policy Policy, fn func(ctx context.Context) error) error {
+	policy = policy.withDefaults()
+
+	for attempt := uint(0); ; attempt++ {
+		err := policy.attempt(ctx, fn)
+		if err == nil || ctx.Err() != nil || attempt >= policy.MaxRetries || !policy.Retryable(err) {
+			return err
+		}
+
+		delay := policy.delay(attempt, err)
+		logger.Or(policy.Logger).DebugContext(ctx, policy.message(),
+			slog.Uint64("attempt", uint64(attempt+1)),
+			slog.Duration("delay", delay),
+			slog.Any("error", err),
+		)
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt+1, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = defaultInitialDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultMaxDelay
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = defaultMultiplier
+	}
+	if p.Retryable == nil {
+		p.Retryable = Temporary
+	}
+
+	return p
+}
+
+func (p Policy) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.AttemptTimeout <= 0 {
+		return fn(ctx)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, p.AttemptTimeout)
+	defer cancel()
+
+	return fn(attemptCtx)
+}
+
+func (p Policy) delay(attempt uint, err error) time.Duration {
+	if httpErr, ok := httperror.As(err); ok && httpErr.RetryAfter > 0 {
+		return httpErr.RetryAfter
+	}
+
+	delay := float64(p.InitialDelay)
+	for i := uint(0); i < attempt && delay < float64(p.MaxDelay); i++ {
+		delay *= p.Multiplier
+	}
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	//nolint:gosec
+	delay -= delay * p.Jitter * rand.Float64()
+
+	return time.Duration(delay)
+}
+
+func (p Policy) message() string {
+	if p.Name == "" {
+		return "retry"
+	}
+
+	return p.Name + " retry"
+}
+
+// Temporary reports whether err is a rate limit, a server error or a timeout.
+func Temporary(err error) bool {
+	if httpErr, ok := httperror.As(err); ok {
+		return httpErr.Temporary()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,155 @@
+package retry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/henomis/restclientgo"
+
+	"github.com/henomis/lingoose/llm/httperror"
+)
+
+func TestDo_RetryAfter(t *testing.T) {
+	rateLimited := httperror.New(http.StatusTooManyRequests, nil).
+		WithHeaders(map[string][]string{"Retry-After": {"0.02"}})
+
+	var delays []time.Duration
+	policy := DefaultPolicy()
+	policy.InitialDelay = time.Hour
+	policy.OnRetry = func(_ uint, _ error, delay time.Duration) {
+		delays = append(delays, delay)
+	}
+
+	calls := 0
+	err := Do(context.Background(), policy, func(context.Context) error {
+		calls++
+		if calls == 1 {
+			return rateLimited
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || len(delays) != 1 || delays[0] != 20*time.Millisecond {
+		t.Fatalf("unexpected calls %d, delays %v", calls, delays)
+	}
+}
+
+func TestDo_AttemptTimeout(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.InitialDelay = time.Millisecond
+	policy.AttemptTimeout = 10 * time.Millisecond
+
+	calls := 0
+	err := Do(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected the timed out attempt to be retried, got %d calls", calls)
+	}
+}
+
+func TestDo_NotRetryable(t *testing.T) {
+	badRequest := httperror.New(http.StatusBadRequest, []byte("bad request"))
+
+	calls := 0
+	err := Do(context.Background(), DefaultPolicy(), func(context.Context) error {
+		calls++
+		return badRequest
+	})
+	if !errors.Is(err, badRequest) || calls != 1 {
+		t.Fatalf("unexpected error %v after %d calls", err, calls)
+	}
+}
+
+func TestDo_Exhausted(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.MaxRetries = 2
+	policy.InitialDelay = time.Millisecond
+
+	calls := 0
+	err := Do(context.Background(), policy, func(context.Context) error {
+		calls++
+		return httperror.New(http.StatusServiceUnavailable, nil)
+	})
+	if httpErr, ok := httperror.As(err); !ok || httpErr.StatusCode != http.StatusServiceUnavailable || calls != 3 {
+		t.Fatalf("unexpected error %v after %d calls", err, calls)
+	}
+}
+
+type testRequest struct{}
+
+func (r *testRequest) Path() (string, error) { return "/embed", nil }
+
+func (r *testRequest) Encode() (io.Reader, error) { return strings.NewReader("{}"), nil }
+
+func (r *testRequest) ContentType() string { return "application/json" }
+
+type testResponse struct {
+	Value   string `json:"value"`
+	RawBody []byte `json:"-"`
+}
+
+func (r *testResponse) Decode(body io.Reader) error { return json.NewDecoder(body).Decode(r) }
+
+func (r *testResponse) SetBody(body io.Reader) error {
+	b, err := io.ReadAll(body)
+	r.RawBody = b
+	return err
+}
+
+func (r *testResponse) AcceptContentType() string { return "application/json" }
+
+func (r *testResponse) SetStatusCode(int) error { return nil }
+
+func (r *testResponse) SetHeaders(restclientgo.Headers) error { return nil }
+
+func TestPost(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte("slow down"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"value":"ok"}`))
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	policy := DefaultPolicy()
+	policy.OnRetry = func(_ uint, err error, delay time.Duration) {
+		if httpErr, ok := httperror.As(err); !ok || string(httpErr.Body) != "slow down" {
+			t.Errorf("unexpected error %v", err)
+		}
+		delays = append(delays, delay)
+	}
+
+	resp, err := Post[testResponse](context.Background(), restclientgo.New(server.URL), policy, &testRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Value != "ok" || len(delays) != 1 || delays[0] != 10*time.Millisecond {
+		t.Fatalf("unexpected response %+v, delays %v", resp, delays)
+	}
+}
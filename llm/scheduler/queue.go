@@ -0,0 +1,85 @@
+package scheduler
+
+type waiter struct {
+	ready    chan struct{}
+	admitted bool
+}
+
+// tenantQueues holds a FIFO queue per tenant and pops them round robin.
+type tenantQueues struct {
+	tenants []string
+	queues  map[string][]*waiter
+	next    int
+}
+
+func newTenantQueues() *tenantQueues {
+	return &tenantQueues{
+		queues: make(map[string][]*waiter),
+	}
+}
+
+func (q *tenantQueues) len() int {
+	n := 0
+	for _, waiters := range q.queues {
+		n += len(waiters)
+	}
+	return n
+}
+
+func (q *tenantQueues) push(tenant string, w *waiter) {
+	if _, ok := q.queues[tenant]; !ok {
+		q.tenants = append(q.tenants, tenant)
+	}
+	q.queues[tenant] = append(q.queues[tenant], w)
+}
+
+func (q *tenantQueues) pop() *waiter {
+	if len(q.tenants) == 0 {
+		return nil
+	}
+
+	if q.next >= len(q.tenants) {
+		q.next = 0
+	}
+
+	tenant := q.tenants[q.next]
+	waiters := q.queues[tenant]
+	w := waiters[0]
+
+	if len(waiters) == 1 {
+		q.removeTenant(q.next)
+	} else {
+		q.queues[tenant] = waiters[1:]
+		q.next++
+	}
+
+	return w
+}
+
+func (q *tenantQueues) remove(w *waiter) {
+	for i, tenant := range q.tenants {
+		waiters := q.queues[tenant]
+		for j := range waiters {
+			if waiters[j] != w {
+				continue
+			}
+
+			if len(waiters) == 1 {
+				q.removeTenant(i)
+				return
+			}
+
+			q.queues[tenant] = append(waiters[:j:j], waiters[j+1:]...)
+			return
+		}
+	}
+}
+
+// removeTenant drops the tenant at index i, the next tenant in the ring takes its place.
+func (q *tenantQueues) removeTenant(i int) {
+	delete(q.queues, q.tenants[i])
+	q.tenants = append(q.tenants[:i], q.tenants[i+1:]...)
+	if q.next > i {
+		q.next--
+	}
+}
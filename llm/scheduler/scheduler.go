@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/henomis/lingoose/secret"
+	"github.com/henomis/lingoose/thread"
+)
+
+var ErrQueueFull = errors.New("scheduler queue is full")
+
+type Priority int
+
+const (
+	PriorityBackground Priority = iota
+	PriorityNormal
+	PriorityInteractive
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+type priorityContextKey struct{}
+
+// WithPriority returns a context scheduling the calls with the given priority,
+// PriorityNormal by default.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, priority)
+}
+
+func priorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityContextKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityNormal
+}
+
+type Stats struct {
+	Running int
+	Queued  int
+}
+
+// Scheduler limits the number of concurrent calls. Calls exceeding the limit are queued
+// and admitted by priority first, then round robin across the tenants set with
+// secret.WithTenant, then in arrival order.
+type Scheduler struct {
+	mu             sync.Mutex
+	maxConcurrency int
+	maxQueueSize   int
+	running        int
+	queued         int
+	queues         map[Priority]*tenantQueues
+}
+
+// New returns a scheduler running at most maxConcurrency calls at a time. Values lower
+// than one mean unlimited concurrency, the calls are still counted in the stats.
+func New(maxConcurrency int) *Scheduler {
+	if maxConcurrency < 1 {
+		maxConcurrency = math.MaxInt
+	}
+
+	return &Scheduler{
+		maxConcurrency: maxConcurrency,
+		queues:         make(map[Priority]*tenantQueues),
+	}
+}
+
+// WithMaxQueueSize rejects calls with ErrQueueFull when the given number of calls is
+// already waiting. Zero means unbounded.
+func (s *Scheduler) WithMaxQueueSize(maxQueueSize int) *Scheduler {
+	s.maxQueueSize = maxQueueSize
+	return s
+}
+
+// LLM wraps the LLM so that its Generate calls go through the scheduler.
+func (s *Scheduler) LLM(llm LLM) *ScheduledLLM {
+	return &ScheduledLLM{
+		llm:       llm,
+		scheduler: s,
+	}
+}
+
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Stats{
+		Running: s.running,
+		Queued:  s.queued,
+	}
+}
+
+// Acquire waits until the call is admitted and returns the function to call when done.
+// Priority and tenant are read from the context.
+func (s *Scheduler) Acquire(ctx context.Context) (func(), error) {
+	s.mu.Lock()
+
+	if s.running < s.maxConcurrency && s.queued == 0 {
+		s.running++
+		s.mu.Unlock()
+		return s.releaseFunc(), nil
+	}
+
+	if s.maxQueueSize > 0 && s.queued >= s.maxQueueSize {
+		s.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	priority := priorityFromContext(ctx)
+	queue, ok := s.queues[priority]
+	if !ok {
+		queue = newTenantQueues()
+		s.queues[priority] = queue
+	}
+	queue.push(secret.Tenant(ctx), w)
+	s.queued++
+
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return s.releaseFunc(), nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// admitted while the context was being cancelled
+	if w.admitted {
+		s.running--
+		s.dispatch()
+		return nil, ctx.Err()
+	}
+
+	queue.remove(w)
+	s.queued--
+
+	return nil, ctx.Err()
+}
+
+func (s *Scheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			s.running--
+			s.dispatch()
+		})
+	}
+}
+
+func (s *Scheduler) dispatch() {
+	for s.running < s.maxConcurrency && s.queued > 0 {
+		w := s.next()
+		if w == nil {
+			return
+		}
+
+		w.admitted = true
+		close(w.ready)
+		s.running++
+		s.queued--
+	}
+}
+
+func (s *Scheduler) next() *waiter {
+	var next *tenantQueues
+	var nextPriority Priority
+	for priority, queue := range s.queues {
+		if queue.len() > 0 && (next == nil || priority > nextPriority) {
+			next, nextPriority = queue, priority
+		}
+	}
+
+	if next == nil {
+		return nil
+	}
+
+	return next.pop()
+}
+
+type ScheduledLLM struct {
+	llm       LLM
+	scheduler *Scheduler
+}
+
+func (l *ScheduledLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	release, err := l.scheduler.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return l.llm.Generate(ctx, t)
+}
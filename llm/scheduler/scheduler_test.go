@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/secret"
+)
+
+func TestSchedulerAdmitsByPriorityAndTenant(t *testing.T) {
+	s := New(1)
+
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admitted := make(chan string, 4)
+	enqueue := func(name string, priority Priority, tenant string) {
+		ctx := secret.WithTenant(WithPriority(context.Background(), priority), tenant)
+		go func() {
+			releaseFn, errAcquire := s.Acquire(ctx)
+			if errAcquire != nil {
+				t.Error(errAcquire)
+				return
+			}
+			admitted <- name
+			releaseFn()
+		}()
+	}
+
+	queued := 0
+	for _, c := range []struct {
+		name     string
+		priority Priority
+		tenant   string
+	}{
+		{"batch", PriorityBackground, "a"},
+		{"a1", PriorityInteractive, "a"},
+		{"a2", PriorityInteractive, "a"},
+		{"b1", PriorityInteractive, "b"},
+	} {
+		enqueue(c.name, c.priority, c.tenant)
+		queued++
+		// wait for the call to be queued to keep the arrival order deterministic
+		for s.Stats().Queued != queued {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	release()
+
+	expected := []string{"a1", "b1", "a2", "batch"}
+	for _, name := range expected {
+		if got := <-admitted; got != name {
+			t.Fatalf("expected %s, got %s", name, got)
+		}
+	}
+}
+
+func TestSchedulerUnlimitedConcurrency(t *testing.T) {
+	for _, maxConcurrency := range []int{0, -1} {
+		s := New(maxConcurrency)
+
+		var releases []func()
+		for i := 0; i < 3; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			release, err := s.Acquire(ctx)
+			cancel()
+			if err != nil {
+				t.Fatalf("New(%d): call %d not admitted: %v", maxConcurrency, i, err)
+			}
+			releases = append(releases, release)
+		}
+
+		if stats := s.Stats(); stats.Running != 3 || stats.Queued != 0 {
+			t.Fatalf("New(%d): unexpected stats %+v", maxConcurrency, stats)
+		}
+
+		for _, release := range releases {
+			release()
+		}
+	}
+}
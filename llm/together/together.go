@@ -0,0 +1,42 @@
+// Package together provides the Together AI LLM through its OpenAI compatible API.
+package together
+
+import (
+	"os"
+
+	"github.com/henomis/lingoose/llm/openai"
+)
+
+const (
+	defaultEndpoint = "https://api.together.xyz/v1"
+)
+
+const (
+	ModelLlama3Dot370BInstructTurbo  openai.Model = "meta-llama/Llama-3.3-70B-Instruct-Turbo"
+	ModelLlama3Dot1405BInstructTurbo openai.Model = "meta-llama/Meta-Llama-3.1-405B-Instruct-Turbo"
+	ModelLlama3Dot170BInstructTurbo  openai.Model = "meta-llama/Meta-Llama-3.1-70B-Instruct-Turbo"
+	ModelLlama3Dot18BInstructTurbo   openai.Model = "meta-llama/Meta-Llama-3.1-8B-Instruct-Turbo"
+	ModelLlama3Dot211BVisionInstruct openai.Model = "meta-llama/Llama-3.2-11B-Vision-Instruct-Turbo"
+	ModelLlama3Dot23BInstructTurbo   openai.Model = "meta-llama/Llama-3.2-3B-Instruct-Turbo"
+	ModelQwen2Dot572BInstructTurbo   openai.Model = "Qwen/Qwen2.5-72B-Instruct-Turbo"
+	ModelQwen2Dot57BInstructTurbo    openai.Model = "Qwen/Qwen2.5-7B-Instruct-Turbo"
+	ModelQwen2Dot5Coder32BInstruct   openai.Model = "Qwen/Qwen2.5-Coder-32B-Instruct"
+	ModelQwQ32B                      openai.Model = "Qwen/QwQ-32B"
+	ModelMixtral8x7BInstruct         openai.Model = "mistralai/Mixtral-8x7B-Instruct-v0.1"
+	ModelMixtral8x22BInstruct        openai.Model = "mistralai/Mixtral-8x22B-Instruct-v0.1"
+	ModelMistral7BInstruct           openai.Model = "mistralai/Mistral-7B-Instruct-v0.3"
+	ModelDeepSeekR1                  openai.Model = "deepseek-ai/DeepSeek-R1"
+	ModelDeepSeekV3                  openai.Model = "deepseek-ai/DeepSeek-V3"
+)
+
+// New creates a Together AI LLM using the Llama 3.3 70B instruct model. The API key is
+// read from the TOGETHER_API_KEY environment variable.
+func New() *openai.OpenAI {
+	openaillm := openai.New().
+		WithBaseURL(defaultEndpoint).
+		WithAPIKey(os.Getenv("TOGETHER_API_KEY")).
+		WithModel(ModelLlama3Dot370BInstructTurbo)
+	openaillm.Name = "together"
+
+	return openaillm
+}
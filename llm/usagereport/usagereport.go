@@ -0,0 +1,45 @@
+// Package usagereport passes the token usage reported by the LLM providers to the caller
+// of a request through its context, in addition to the usage callback of the LLM. Unlike
+// the callback, shared by all the requests of an LLM, the reporter only receives the usage
+// of the calls made with its context.
+package usagereport
+
+import (
+	"context"
+
+	"github.com/henomis/lingoose/types"
+)
+
+type contextKey struct{}
+
+// Reporter receives the usage of a call, with the same keys as the usage callbacks, e.g.
+// PromptTokens and CompletionTokens.
+type Reporter func(usage types.Meta)
+
+// WithReporter returns a context whose calls report their usage to the reporter, and to
+// the reporter of the parent context if any.
+func WithReporter(ctx context.Context, reporter Reporter) context.Context {
+	if parent, ok := ctx.Value(contextKey{}).(Reporter); ok {
+		next := reporter
+		reporter = func(usage types.Meta) {
+			next(usage)
+			parent(usage)
+		}
+	}
+
+	return context.WithValue(ctx, contextKey{}, reporter)
+}
+
+// Enabled reports whether the context has a reporter, so that providers only compute
+// the usage when someone receives it.
+func Enabled(ctx context.Context) bool {
+	_, ok := ctx.Value(contextKey{}).(Reporter)
+	return ok
+}
+
+// Report passes the usage to the reporter of the context, if any.
+func Report(ctx context.Context, usage types.Meta) {
+	if reporter, ok := ctx.Value(contextKey{}).(Reporter); ok {
+		reporter(usage)
+	}
+}
@@ -0,0 +1,84 @@
+// Package xai provides the xAI Grok LLM through its OpenAI compatible API.
+package xai
+
+import (
+	"os"
+
+	"github.com/henomis/lingoose/llm/openai"
+)
+
+const (
+	defaultEndpoint   = "https://api.x.ai/v1"
+	defaultMaxRetries = 3
+	// MetadataCitations is the assistant message metadata key holding the URLs of the
+	// sources used by live search.
+	MetadataCitations = "citations"
+)
+
+const (
+	ModelGrok3          openai.Model = "grok-3"
+	ModelGrok3Mini      openai.Model = "grok-3-mini"
+	ModelGrok2          openai.Model = "grok-2-1212"
+	ModelGrok2Vision    openai.Model = "grok-2-vision-1212"
+	ModelGrokBeta       openai.Model = "grok-beta"
+	ModelGrokVisionBeta openai.Model = "grok-vision-beta"
+)
+
+type SearchMode string
+
+const (
+	// SearchModeAuto lets the model decide whether to search.
+	SearchModeAuto SearchMode = "auto"
+	SearchModeOn   SearchMode = "on"
+	SearchModeOff  SearchMode = "off"
+)
+
+// SearchSource is a data source of live search. Type is one of "web", "x", "news" or
+// "rss"; the other fields apply to the matching source types only.
+type SearchSource struct {
+	Type             string   `json:"type"`
+	Country          string   `json:"country,omitempty"`
+	ExcludedWebsites []string `json:"excluded_websites,omitempty"`
+	XHandles         []string `json:"x_handles,omitempty"`
+	Links            []string `json:"links,omitempty"`
+	SafeSearch       *bool    `json:"safe_search,omitempty"`
+}
+
+// SearchParameters configures the Grok live search. Dates use the YYYY-MM-DD format.
+type SearchParameters struct {
+	Mode             SearchMode     `json:"mode"`
+	Sources          []SearchSource `json:"sources,omitempty"`
+	MaxSearchResults int            `json:"max_search_results,omitempty"`
+	FromDate         string         `json:"from_date,omitempty"`
+	ToDate           string         `json:"to_date,omitempty"`
+	ReturnCitations  bool           `json:"return_citations"`
+}
+
+type XAI struct {
+	*openai.OpenAI
+}
+
+// New creates a xAI Grok LLM using the Grok 3 model. The API key is read from the
+// XAI_API_KEY environment variable. Images in user messages are supported by the vision
+// models. Rate limited requests are retried three times, see WithMaxRetries.
+func New() *XAI {
+	openaillm := openai.New().
+		WithBaseURL(defaultEndpoint).
+		WithAPIKey(os.Getenv("XAI_API_KEY")).
+		WithModel(ModelGrok3).
+		WithMaxRetries(defaultMaxRetries).
+		WithResponseFields(MetadataCitations)
+	openaillm.Name = "xai"
+
+	return &XAI{
+		OpenAI: openaillm,
+	}
+}
+
+// WithLiveSearch enables the Grok live search on web, X and news sources. When citations
+// are requested, the source URLs are set in the MetadataCitations metadata of the
+// assistant message.
+func (x *XAI) WithLiveSearch(searchParameters SearchParameters) *XAI {
+	x.OpenAI.WithBodyField("search_parameters", searchParameters)
+	return x
+}
@@ -0,0 +1,88 @@
+package xai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestGenerateLiveSearchCitations(t *testing.T) {
+	var body struct {
+		SearchParameters SearchParameters `json:"search_parameters"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"news"}}],` +
+			`"citations":["https://x.com/1","https://example.com/2"]}`))
+	}))
+	defer server.Close()
+
+	searchParameters := SearchParameters{
+		Mode:            SearchModeOn,
+		Sources:         []SearchSource{{Type: "news", Country: "IT"}},
+		ReturnCitations: true,
+	}
+	llm := New().WithLiveSearch(searchParameters)
+	llm.WithBaseURL(server.URL)
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("latest news?")))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(body.SearchParameters, searchParameters) {
+		t.Fatalf("unexpected search parameters %+v", body.SearchParameters)
+	}
+
+	expected := []any{"https://x.com/1", "https://example.com/2"}
+	if got := th.LastMessage().Metadata[MetadataCitations]; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected citations %v, got %v", expected, got)
+	}
+}
+
+func TestGenerateImage(t *testing.T) {
+	var body struct {
+		Messages []struct {
+			Content []struct {
+				Type     string `json:"type"`
+				ImageURL struct {
+					URL string `json:"url"`
+				} `json:"image_url"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"a cat"}}]}`))
+	}))
+	defer server.Close()
+
+	llm := New()
+	llm.WithBaseURL(server.URL).WithModel(ModelGrok2Vision)
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(
+		thread.NewTextContent("what's this?"),
+	).AddContent(
+		thread.NewImageContentFromURL("https://example.com/cat.png"),
+	))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.Messages) != 1 || len(body.Messages[0].Content) != 2 ||
+		body.Messages[0].Content[1].ImageURL.URL != "https://example.com/cat.png" {
+		t.Fatalf("unexpected messages %+v", body.Messages)
+	}
+	if _, ok := th.LastMessage().Metadata[MetadataCitations]; ok {
+		t.Fatalf("unexpected citations without live search")
+	}
+}
@@ -0,0 +1,55 @@
+package loader
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/logger"
+)
+
+type SourceLoader interface {
+	LoadFromSource(ctx context.Context, source string) ([]document.Document, error)
+}
+
+// LoggingLoader wraps a loader logging each load at debug level with the source, the
+// number of documents, their size and the duration.
+type LoggingLoader struct {
+	loader SourceLoader
+	logger *slog.Logger
+}
+
+// WithLogging wraps the loader. A nil logger uses the default logger of the logger
+// package.
+func WithLogging(loader SourceLoader, logger *slog.Logger) *LoggingLoader {
+	return &LoggingLoader{
+		loader: loader,
+		logger: logger,
+	}
+}
+
+func (l *LoggingLoader) LoadFromSource(ctx context.Context, source string) ([]document.Document, error) {
+	start := time.Now()
+	documents, err := l.loader.LoadFromSource(ctx, source)
+
+	attrs := []any{
+		slog.String("source", source),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		logger.Or(l.logger).DebugContext(ctx, "loader failed", append(attrs, slog.Any("error", err))...)
+		return nil, err
+	}
+
+	chars := 0
+	for _, doc := range documents {
+		chars += len(doc.Content)
+	}
+	logger.Or(l.logger).DebugContext(ctx, "loader", append(attrs,
+		slog.Int("documents", len(documents)),
+		slog.Int("chars", chars),
+	)...)
+
+	return documents, nil
+}
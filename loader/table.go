@@ -0,0 +1,319 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	// TableCSVMetadataKey is the document metadata key holding the table as CSV.
+	TableCSVMetadataKey = "table_csv"
+	// TableJSONMetadataKey is the document metadata key holding the table rows as a JSON
+	// array of objects keyed by the header.
+	TableJSONMetadataKey = "table_json"
+	// TableIndexMetadataKey is the document metadata key holding the position of the
+	// table in the source.
+	TableIndexMetadataKey = "table_index"
+
+	minTableRows = 3
+)
+
+var (
+	ErrTableSource = fmt.Errorf("unsupported table source")
+
+	layoutColumnSeparator = regexp.MustCompile(`\s{2,}`)
+)
+
+// Table is a table extracted from a document, the first row is the header.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// TableLoader extracts the tables of HTML and PDF files. PDF tables are detected with
+// pdftotext -layout, as blocks of lines with the same number of columns separated by
+// two or more spaces.
+type TableLoader struct {
+	pdfToTextPath string
+	path          string
+}
+
+func NewTableLoader(path string) *TableLoader {
+	return &TableLoader{
+		pdfToTextPath: defaultPdfToTextPath,
+		path:          path,
+	}
+}
+
+func NewTable() *TableLoader {
+	return &TableLoader{
+		pdfToTextPath: defaultPdfToTextPath,
+	}
+}
+
+func (t *TableLoader) WithPDFToTextPath(pdfToTextPath string) *TableLoader {
+	t.pdfToTextPath = pdfToTextPath
+	return t
+}
+
+// Load returns a document per table, with the table in Markdown as content and as CSV
+// and JSON in the metadata.
+func (t *TableLoader) Load(ctx context.Context) ([]document.Document, error) {
+	err := isFile(t.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []Table
+	switch strings.ToLower(filepath.Ext(t.path)) {
+	case ".html", ".htm":
+		tables, err = t.htmlTables()
+	case ".pdf":
+		tables, err = t.pdfTables(ctx)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrTableSource, t.path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]document.Document, 0, len(tables))
+	for i, table := range tables {
+		tableJSON, errJSON := table.JSON()
+		if errJSON != nil {
+			return nil, errJSON
+		}
+
+		documents = append(documents, document.Document{
+			Content: table.Markdown(),
+			Metadata: types.Meta{
+				SourceMetadataKey:     t.path,
+				TableIndexMetadataKey: i,
+				TableCSVMetadataKey:   table.CSV(),
+				TableJSONMetadataKey:  tableJSON,
+			},
+		})
+	}
+
+	return documents, nil
+}
+
+func (t *TableLoader) LoadFromSource(ctx context.Context, source string) ([]document.Document, error) {
+	t.path = source
+	return t.Load(ctx)
+}
+
+func (t *TableLoader) htmlTables() ([]Table, error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	root, err := html.Parse(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []Table
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "table" {
+			if table, ok := newTable(htmlTableRows(n)); ok {
+				tables = append(tables, table)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	return tables, nil
+}
+
+// htmlTableRows returns the rows of the table, skipping the ones of nested tables.
+func htmlTableRows(table *html.Node) [][]string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || c.Data == "table" {
+				continue
+			}
+
+			if c.Data != "tr" {
+				walk(c)
+				continue
+			}
+
+			var row []string
+			for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+					row = append(row, strings.Join(strings.Fields(htmlText(cell)), " "))
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	walk(table)
+
+	return rows
+}
+
+func htmlText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(htmlText(c))
+		sb.WriteString(" ")
+	}
+
+	return sb.String()
+}
+
+func (t *TableLoader) pdfTables(ctx context.Context) ([]Table, error) {
+	//nolint:gosec
+	out, err := exec.CommandContext(ctx, t.pdfToTextPath, "-layout", t.path, "-").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return LayoutTables(string(out)), nil
+}
+
+// LayoutTables detects the tables of a text laid out with spaces, as blocks of at least
+// three consecutive lines with the same number of columns separated by two or more spaces.
+func LayoutTables(text string) []Table {
+	var tables []Table
+	var block [][]string
+
+	flush := func() {
+		if len(block) >= minTableRows {
+			if table, ok := newTable(block); ok {
+				tables = append(tables, table)
+			}
+		}
+		block = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.ReplaceAll(line, "\f", ""))
+		cells := layoutColumnSeparator.Split(line, -1)
+		if line == "" || len(cells) < 2 {
+			flush()
+			continue
+		}
+
+		if len(block) > 0 && len(cells) != len(block[0]) {
+			flush()
+		}
+		block = append(block, cells)
+	}
+	flush()
+
+	return tables
+}
+
+func newTable(rows [][]string) (Table, bool) {
+	var nonEmpty [][]string
+	for _, row := range rows {
+		if len(row) > 0 {
+			nonEmpty = append(nonEmpty, row)
+		}
+	}
+	if len(nonEmpty) < 2 {
+		return Table{}, false
+	}
+
+	columns := 0
+	for _, row := range nonEmpty {
+		columns = max(columns, len(row))
+	}
+
+	header := normalizeRow(nonEmpty[0], columns)
+	for i, name := range header {
+		if name == "" {
+			header[i] = fmt.Sprintf("column%d", i+1)
+		}
+	}
+
+	table := Table{Header: header}
+	for _, row := range nonEmpty[1:] {
+		table.Rows = append(table.Rows, normalizeRow(row, columns))
+	}
+
+	return table, true
+}
+
+func normalizeRow(row []string, columns int) []string {
+	normalized := make([]string, columns)
+	copy(normalized, row)
+	return normalized
+}
+
+// CSV returns the table as CSV, header included.
+func (t Table) CSV() string {
+	var buffer bytes.Buffer
+	w := csv.NewWriter(&buffer)
+	_ = w.Write(t.Header)
+	_ = w.WriteAll(t.Rows)
+
+	return buffer.String()
+}
+
+// JSON returns the rows as a JSON array of objects keyed by the header.
+func (t Table) JSON() (string, error) {
+	records := make([]map[string]string, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		record := make(map[string]string, len(t.Header))
+		for i, name := range t.Header {
+			record[name] = row[i]
+		}
+		records = append(records, record)
+	}
+
+	tableJSON, err := json.Marshal(records)
+	if err != nil {
+		return "", err
+	}
+
+	return string(tableJSON), nil
+}
+
+// Markdown returns the table as a Markdown table.
+func (t Table) Markdown() string {
+	var sb strings.Builder
+
+	writeRow := func(row []string) {
+		sb.WriteString("|")
+		for _, cell := range row {
+			sb.WriteString(" " + strings.ReplaceAll(cell, "|", "\\|") + " |")
+		}
+		sb.WriteString("\n")
+	}
+
+	writeRow(t.Header)
+	sb.WriteString("|" + strings.Repeat(" --- |", len(t.Header)) + "\n")
+	for _, row := range t.Rows {
+		writeRow(row)
+	}
+
+	return sb.String()
+}
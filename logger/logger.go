@@ -0,0 +1,47 @@
+// Package logger configures the structured logger the library emits its debug logs
+// with: request sizes, durations, cache hits and retries. Logging is disabled until a
+// logger is set, globally with SetDefault or per component with its WithLogger option.
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+var (
+	defaultLogger atomic.Pointer[slog.Logger]
+	discard       = slog.New(discardHandler{})
+)
+
+// SetDefault sets the logger of the components without their own logger. A nil logger
+// disables logging.
+func SetDefault(l *slog.Logger) {
+	defaultLogger.Store(l)
+}
+
+// Default returns the logger set with SetDefault, or a logger discarding the records.
+func Default() *slog.Logger {
+	if l := defaultLogger.Load(); l != nil {
+		return l
+	}
+
+	return discard
+}
+
+// Or returns l, or the default logger if l is nil. Components call it with the logger
+// set by their WithLogger option.
+func Or(l *slog.Logger) *slog.Logger {
+	if l != nil {
+		return l
+	}
+
+	return Default()
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
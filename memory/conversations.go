@@ -0,0 +1,221 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/option"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultChunkSize    = 4
+	defaultChunkOverlap = 1
+	// user filtering happens after the vector search, so more results are requested
+	userOversampling = 4
+
+	MetadataSessionID = "session_id"
+	MetadataUserID    = "user_id"
+	MetadataTime      = "session_time"
+)
+
+// Session identifies a completed conversation.
+type Session struct {
+	ID     string
+	UserID string
+	Time   time.Time
+}
+
+// Exchange is a chunk of a past conversation.
+type Exchange struct {
+	Session Session
+	Content string
+	Score   float64
+}
+
+// Conversations indexes completed threads, chunked in groups of consecutive user and
+// assistant messages, so that past exchanges can be retrieved in new conversations.
+type Conversations struct {
+	index        *index.Index
+	chunkSize    int
+	chunkOverlap int
+	topK         int
+}
+
+func NewConversations(index *index.Index) *Conversations {
+	return &Conversations{
+		index:        index,
+		chunkSize:    defaultChunkSize,
+		chunkOverlap: defaultChunkOverlap,
+		topK:         defaultTopK,
+	}
+}
+
+// WithChunkSize sets the number of messages of each chunk and how many of them are
+// repeated in the following chunk.
+func (c *Conversations) WithChunkSize(chunkSize, chunkOverlap int) *Conversations {
+	c.chunkSize = chunkSize
+	c.chunkOverlap = chunkOverlap
+	return c
+}
+
+func (c *Conversations) WithTopK(topK int) *Conversations {
+	c.topK = topK
+	return c
+}
+
+// Add indexes the user and assistant text messages of the thread.
+func (c *Conversations) Add(ctx context.Context, session Session, t *thread.Thread) error {
+	var lines []string
+	for _, message := range t.Messages {
+		if message.Role != thread.RoleUser && message.Role != thread.RoleAssistant {
+			continue
+		}
+
+		var text string
+		for _, content := range message.Contents {
+			if content.Type == thread.ContentTypeText {
+				text += content.AsString()
+			}
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		role := "User"
+		if message.Role == thread.RoleAssistant {
+			role = "Assistant"
+		}
+		lines = append(lines, role+": "+text)
+	}
+
+	if session.Time.IsZero() {
+		session.Time = time.Now()
+	}
+
+	var documents []document.Document
+	for _, chunk := range chunkLines(lines, c.chunkSize, c.chunkOverlap) {
+		documents = append(documents, document.Document{
+			Content: chunk,
+			Metadata: types.Meta{
+				index.DefaultKeyContent: chunk,
+				MetadataSessionID:       session.ID,
+				MetadataUserID:          session.UserID,
+				MetadataTime:            session.Time.UTC().Format(time.RFC3339),
+			},
+		})
+	}
+
+	if len(documents) == 0 {
+		return nil
+	}
+
+	err := c.index.LoadFromDocuments(ctx, documents)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMemory, err)
+	}
+
+	return nil
+}
+
+// Search returns the past exchanges most relevant to the query. If userID is not empty
+// only the conversations of that user are returned.
+func (c *Conversations) Search(ctx context.Context, query string, userID string) ([]Exchange, error) {
+	topK := c.topK
+	if userID != "" {
+		topK *= userOversampling
+	}
+
+	results, err := c.index.Query(ctx, query, option.WithTopK(topK))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMemory, err)
+	}
+
+	var exchanges []Exchange
+	for _, result := range results {
+		content, ok := result.Metadata[index.DefaultKeyContent].(string)
+		if !ok {
+			continue
+		}
+
+		exchange := Exchange{
+			Content: content,
+			Score:   result.Score,
+		}
+		exchange.Session.ID, _ = result.Metadata[MetadataSessionID].(string)
+		exchange.Session.UserID, _ = result.Metadata[MetadataUserID].(string)
+		if sessionTime, isString := result.Metadata[MetadataTime].(string); isString {
+			exchange.Session.Time, _ = time.Parse(time.RFC3339, sessionTime)
+		}
+
+		if userID != "" && exchange.Session.UserID != userID {
+			continue
+		}
+
+		exchanges = append(exchanges, exchange)
+		if len(exchanges) == c.topK {
+			break
+		}
+	}
+
+	return exchanges, nil
+}
+
+// Retriever returns a retriever of the past conversations of the user, that can be used
+// as the assistant RAG.
+func (c *Conversations) Retriever(userID string) *Retriever {
+	return &Retriever{
+		conversations: c,
+		userID:        userID,
+	}
+}
+
+type Retriever struct {
+	conversations *Conversations
+	userID        string
+}
+
+// Retrieve returns the past exchanges relevant to the query, prefixed by their date.
+func (r *Retriever) Retrieve(ctx context.Context, query string) ([]string, error) {
+	exchanges, err := r.conversations.Search(ctx, query, r.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]string, len(exchanges))
+	for i, exchange := range exchanges {
+		results[i] = fmt.Sprintf("[conversation of %s]\n%s", exchange.Session.Time.Format(time.DateOnly), exchange.Content)
+	}
+
+	return results, nil
+}
+
+func chunkLines(lines []string, chunkSize, chunkOverlap int) []string {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	step := chunkSize - chunkOverlap
+	if step <= 0 {
+		step = chunkSize
+	}
+
+	var chunks []string
+	for start := 0; start < len(lines); start += step {
+		end := start + chunkSize
+		if end > len(lines) {
+			end = len(lines)
+		}
+		chunks = append(chunks, strings.Join(lines[start:end], "\n"))
+		if end == len(lines) {
+			break
+		}
+	}
+
+	return chunks
+}
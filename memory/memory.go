@@ -0,0 +1,245 @@
+// Package memory provides a long-term agent memory backed by a vector index. Episodic
+// observations are stored as they happen and periodically distilled by an LLM into
+// higher-level facts (reflections); both are retrieved by semantic similarity.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/option"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	defaultTopK                = 5
+	defaultReflectionThreshold = 10
+
+	// MetadataKind is the index metadata key holding the memory kind.
+	MetadataKind = "memory_kind"
+	// MetadataCreatedAt is the index metadata key holding the memory creation time (RFC 3339).
+	MetadataCreatedAt = "memory_created_at"
+
+	//nolint:lll
+	reflectionPrompt = "Here are some observations recorded during past conversations:\n{{range .observations}}- {{.}}\n{{end}}\nDistill them into a short list of durable, high-level facts worth remembering about the user, their preferences and their goals. Skip small talk and anything only relevant to a single conversation. Write one fact per line, without numbering. If there is nothing worth remembering, answer with NONE."
+)
+
+var (
+	ErrMemory = errors.New("memory error")
+)
+
+type Kind string
+
+const (
+	KindObservation Kind = "observation"
+	KindReflection  Kind = "reflection"
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+type Item struct {
+	Content   string
+	Kind      Kind
+	CreatedAt time.Time
+	Score     float64
+}
+
+// Memory stores observations in the index and, every reflectionThreshold observations,
+// asks the LLM to reflect on them. Without an LLM no reflection is performed.
+type Memory struct {
+	index               *index.Index
+	llm                 LLM
+	topK                int
+	reflectionThreshold int
+
+	mu      sync.Mutex
+	pending []string
+}
+
+func New(index *index.Index) *Memory {
+	return &Memory{
+		index:               index,
+		topK:                defaultTopK,
+		reflectionThreshold: defaultReflectionThreshold,
+	}
+}
+
+// WithReflection enables the reflection pass, run with llm every threshold observations.
+func (m *Memory) WithReflection(llm LLM, threshold int) *Memory {
+	m.llm = llm
+	m.reflectionThreshold = threshold
+	return m
+}
+
+func (m *Memory) WithTopK(topK int) *Memory {
+	m.topK = topK
+	return m
+}
+
+// Observe stores an episodic observation, running a reflection when enough observations
+// have been collected since the last one.
+func (m *Memory) Observe(ctx context.Context, observation string) error {
+	observation = strings.TrimSpace(observation)
+	if observation == "" {
+		return nil
+	}
+
+	err := m.store(ctx, KindObservation, observation)
+	if err != nil {
+		return err
+	}
+
+	if m.llm == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	m.pending = append(m.pending, observation)
+	shouldReflect := len(m.pending) >= m.reflectionThreshold
+	m.mu.Unlock()
+
+	if !shouldReflect {
+		return nil
+	}
+
+	return m.Reflect(ctx)
+}
+
+// Reflect asks the LLM to distill the observations collected since the last reflection
+// into facts, which are stored as KindReflection memories.
+func (m *Memory) Reflect(ctx context.Context) error {
+	if m.llm == nil {
+		return fmt.Errorf("%w: no reflection LLM", ErrMemory)
+	}
+
+	m.mu.Lock()
+	observations := m.pending
+	m.pending = nil
+	m.mu.Unlock()
+
+	if len(observations) == 0 {
+		return nil
+	}
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent(reflectionPrompt).Format(
+				types.M{
+					"observations": observations,
+				},
+			),
+		),
+	)
+
+	err := m.llm.Generate(ctx, t)
+	if err != nil {
+		// keep the observations for the next reflection
+		m.mu.Lock()
+		m.pending = append(observations, m.pending...)
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %w", ErrMemory, err)
+	}
+
+	for _, fact := range parseFacts(t.LastMessage()) {
+		err = m.store(ctx, KindReflection, fact)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Search returns the memories most relevant to the query.
+func (m *Memory) Search(ctx context.Context, query string) ([]Item, error) {
+	results, err := m.index.Query(ctx, query, option.WithTopK(m.topK))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMemory, err)
+	}
+
+	items := make([]Item, 0, len(results))
+	for _, result := range results {
+		content, ok := result.Metadata[index.DefaultKeyContent].(string)
+		if !ok {
+			continue
+		}
+
+		item := Item{
+			Content: content,
+			Kind:    KindObservation,
+			Score:   result.Score,
+		}
+		if kind, isString := result.Metadata[MetadataKind].(string); isString {
+			item.Kind = Kind(kind)
+		}
+		if createdAt, isString := result.Metadata[MetadataCreatedAt].(string); isString {
+			item.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// Retrieve returns the contents of the memories most relevant to the query.
+func (m *Memory) Retrieve(ctx context.Context, query string) ([]string, error) {
+	items, err := m.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	memories := make([]string, len(items))
+	for i, item := range items {
+		memories[i] = item.Content
+	}
+
+	return memories, nil
+}
+
+func (m *Memory) store(ctx context.Context, kind Kind, content string) error {
+	err := m.index.LoadFromDocuments(ctx, []document.Document{
+		{
+			Content: content,
+			Metadata: types.Meta{
+				index.DefaultKeyContent: content,
+				MetadataKind:            string(kind),
+				MetadataCreatedAt:       time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMemory, err)
+	}
+
+	return nil
+}
+
+func parseFacts(message *thread.Message) []string {
+	var text string
+	for _, content := range message.Contents {
+		if content.Type == thread.ContentTypeText {
+			text += content.AsString()
+		}
+	}
+
+	var facts []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•"))
+		if line == "" || strings.EqualFold(line, "NONE") {
+			continue
+		}
+		facts = append(facts, line)
+	}
+
+	return facts
+}
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+	"github.com/henomis/lingoose/thread"
+)
+
+// keywordEmbedder embeds texts on two axes: food and work.
+type keywordEmbedder struct{}
+
+func (keywordEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	embeddings := make([]embedder.Embedding, len(texts))
+	for i, text := range texts {
+		embeddings[i] = embedder.Embedding{0.01, 0.01}
+		if strings.Contains(text, "pizza") {
+			embeddings[i][0] = 1
+		}
+		if strings.Contains(text, "job") {
+			embeddings[i][1] = 1
+		}
+	}
+	return embeddings, nil
+}
+
+type fakeLLM struct {
+	answer string
+	calls  int
+}
+
+func (f *fakeLLM) Generate(_ context.Context, t *thread.Thread) error {
+	f.calls++
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent(f.answer)))
+	return nil
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	llm := &fakeLLM{answer: "- The user loves pizza\nNONE\n"}
+	m := New(index.New(jsondb.New(), keywordEmbedder{})).
+		WithReflection(llm, 2).
+		WithTopK(2)
+
+	err := m.Observe(ctx, "User: I had pizza again tonight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.calls != 0 {
+		t.Fatal("unexpected reflection")
+	}
+
+	err = m.Observe(ctx, "User: my job is boring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.calls != 1 {
+		t.Fatalf("expected one reflection, got %d", llm.calls)
+	}
+
+	items, err := m.Search(ctx, "what about pizza?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 memories, got %d", len(items))
+	}
+
+	kinds := map[Kind]string{}
+	for _, item := range items {
+		kinds[item.Kind] = item.Content
+		if item.CreatedAt.IsZero() {
+			t.Fatal("missing creation time")
+		}
+	}
+	if kinds[KindReflection] != "The user loves pizza" {
+		t.Fatalf("unexpected reflection %q", kinds[KindReflection])
+	}
+	if kinds[KindObservation] != "User: I had pizza again tonight" {
+		t.Fatalf("unexpected observation %q", kinds[KindObservation])
+	}
+}
+
+func TestConversations(t *testing.T) {
+	ctx := context.Background()
+	conversations := NewConversations(index.New(jsondb.New(), keywordEmbedder{})).
+		WithChunkSize(2, 0).
+		WithTopK(1)
+
+	conversation := thread.New().AddMessages(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("Any pizza recipe?")),
+		thread.NewAssistantMessage().AddContent(thread.NewTextContent("Try a margherita.")),
+		thread.NewUserMessage().AddContent(thread.NewTextContent("I start a new job tomorrow")),
+		thread.NewAssistantMessage().AddContent(thread.NewTextContent("Good luck!")),
+	)
+
+	lastMonth := time.Date(2026, 9, 14, 10, 0, 0, 0, time.UTC)
+	err := conversations.Add(ctx, Session{ID: "s1", UserID: "alice", Time: lastMonth}, conversation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conversations.Add(ctx, Session{ID: "s2", UserID: "bob"}, conversation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := conversations.Retriever("alice").Retrieve(ctx, "what was that pizza?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[conversation of 2026-09-14]\nUser: Any pizza recipe?\nAssistant: Try a margherita."
+	if len(results) != 1 || results[0] != want {
+		t.Fatalf("unexpected results %q", results)
+	}
+}
@@ -0,0 +1,194 @@
+// Package moderation screens the inputs and outputs of any LLM with a content moderation
+// service, such as the OpenAI moderation endpoint, blocking flagged content.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/henomis/lingoose/llm/middleware"
+	"github.com/henomis/lingoose/thread"
+)
+
+var (
+	// ErrContentFlagged is wrapped by the FlaggedError returned for flagged content. It
+	// also wraps middleware.ErrBlocked, so flagged generations get the blocked answer of
+	// middleware.Fallback.
+	ErrContentFlagged = errors.New("content flagged")
+	ErrModeration     = errors.New("moderation error")
+)
+
+// Stage is where the flagged content was found.
+type Stage string
+
+const (
+	StageInput  Stage = "input"
+	StageOutput Stage = "output"
+)
+
+// Result is the moderation of a text. Categories lists the flagged categories, Scores the
+// score (0 to 1) of every category.
+type Result struct {
+	Flagged    bool
+	Categories []string
+	Scores     map[string]float64
+}
+
+// Moderator moderates a text.
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (*Result, error)
+}
+
+// FlaggedError is returned when the moderator flags the input or the output.
+type FlaggedError struct {
+	Stage  Stage
+	Result *Result
+}
+
+func (e *FlaggedError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrContentFlagged, e.Stage, strings.Join(e.Result.Categories, ", "))
+}
+
+func (e *FlaggedError) Unwrap() []error {
+	return []error{ErrContentFlagged, middleware.ErrBlocked}
+}
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// GuardedLLM moderates the user messages before sending them to the LLM and the answer
+// after the generation.
+type GuardedLLM struct {
+	llm         LLM
+	moderator   Moderator
+	checkInput  bool
+	checkOutput bool
+}
+
+// NewLLM wraps llm moderating both its inputs and outputs.
+func NewLLM(llm LLM, moderator Moderator) *GuardedLLM {
+	return &GuardedLLM{
+		llm:         llm,
+		moderator:   moderator,
+		checkInput:  true,
+		checkOutput: true,
+	}
+}
+
+// WithInput enables or disables the moderation of the user messages.
+func (g *GuardedLLM) WithInput(enabled bool) *GuardedLLM {
+	g.checkInput = enabled
+	return g
+}
+
+// WithOutput enables or disables the moderation of the answers.
+func (g *GuardedLLM) WithOutput(enabled bool) *GuardedLLM {
+	g.checkOutput = enabled
+	return g
+}
+
+// Generate returns a FlaggedError without calling the LLM when the last user messages
+// are flagged. A flagged answer is removed from the thread before returning the error.
+// User messages with contents but no text to moderate, e.g. only an image, fail closed.
+func (g *GuardedLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	if g.checkInput {
+		input, hasContents := userInput(t)
+		if hasContents && strings.TrimSpace(input) == "" {
+			return fmt.Errorf("%w: no text to moderate in the user messages", ErrModeration)
+		}
+
+		err := g.moderate(ctx, StageInput, input)
+		if err != nil {
+			return err
+		}
+	}
+
+	nMessagesBeforeGeneration := len(t.Messages)
+
+	err := g.llm.Generate(ctx, t)
+	if err != nil || !g.checkOutput {
+		return err
+	}
+
+	var output []string
+	for _, message := range t.Messages[nMessagesBeforeGeneration:] {
+		if message.Role != thread.RoleAssistant {
+			continue
+		}
+
+		for _, content := range message.Contents {
+			if content.Type == thread.ContentTypeText {
+				output = append(output, content.AsString())
+			}
+		}
+	}
+
+	err = g.moderate(ctx, StageOutput, strings.Join(output, "\n"))
+	if err != nil {
+		t.Messages = t.Messages[:nMessagesBeforeGeneration]
+		return err
+	}
+
+	return nil
+}
+
+// userInput returns the text of every content of the trailing user messages and whether
+// they have any content at all.
+func userInput(t *thread.Thread) (string, bool) {
+	first := len(t.Messages)
+	for first > 0 && t.Messages[first-1].Role == thread.RoleUser {
+		first--
+	}
+
+	var texts []string
+	hasContents := false
+	for _, message := range t.Messages[first:] {
+		for _, content := range message.Contents {
+			hasContents = true
+			switch content.Type {
+			case thread.ContentTypeText:
+				texts = append(texts, content.AsString())
+			case thread.ContentTypeAudio:
+				if audioData := content.AsAudioData(); audioData != nil && audioData.Transcript != "" {
+					texts = append(texts, audioData.Transcript)
+				}
+			}
+		}
+	}
+
+	return strings.Join(texts, "\n"), hasContents
+}
+
+func (g *GuardedLLM) moderate(ctx context.Context, stage Stage, text string) error {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	result, err := g.moderator.Moderate(ctx, text)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrModeration, err)
+	}
+
+	if result.Flagged {
+		return &FlaggedError{Stage: stage, Result: result}
+	}
+
+	return nil
+}
+
+// flaggedCategories returns, sorted, the categories whose score reaches their threshold.
+func flaggedCategories(scores map[string]float64, thresholds map[string]float64) []string {
+	var categories []string
+	for category, score := range scores {
+		if threshold, ok := thresholds[category]; ok && score >= threshold {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+
+	return categories
+}
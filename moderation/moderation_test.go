@@ -0,0 +1,100 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/henomis/lingoose/llm/middleware"
+	"github.com/henomis/lingoose/thread"
+)
+
+type keywordModerator struct {
+	keyword string
+}
+
+func (k *keywordModerator) Moderate(_ context.Context, text string) (*Result, error) {
+	if !strings.Contains(text, k.keyword) {
+		return &Result{}, nil
+	}
+
+	return &Result{Flagged: true, Categories: []string{"violence"}, Scores: map[string]float64{"violence": 0.9}}, nil
+}
+
+type echoLLM struct {
+	calls int
+}
+
+func (e *echoLLM) Generate(_ context.Context, t *thread.Thread) error {
+	e.calls++
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("you said " + t.UserQuery()[0])))
+	return nil
+}
+
+func TestGuardedLLM_Generate(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyword   string
+		wantStage Stage
+		wantCalls int
+	}{
+		{"allowed", "bomb", "", 1},
+		{"input", "hello", StageInput, 0},
+		{"output", "said", StageOutput, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &echoLLM{}
+			guarded := NewLLM(llm, &keywordModerator{keyword: tt.keyword})
+
+			th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hello")))
+			err := guarded.Generate(context.Background(), th)
+
+			if llm.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", llm.calls, tt.wantCalls)
+			}
+
+			if tt.wantStage == "" {
+				if err != nil || len(th.Messages) != 2 {
+					t.Fatalf("unexpected error %v or thread %s", err, th)
+				}
+				return
+			}
+
+			var flagged *FlaggedError
+			if !errors.As(err, &flagged) || flagged.Stage != tt.wantStage || flagged.Result.Scores["violence"] != 0.9 {
+				t.Fatalf("expected a %s FlaggedError, got %v", tt.wantStage, err)
+			}
+			if !errors.Is(err, ErrContentFlagged) || !errors.Is(err, middleware.ErrBlocked) {
+				t.Errorf("error %v doesn't wrap ErrContentFlagged and middleware.ErrBlocked", err)
+			}
+			if len(th.Messages) != 1 {
+				t.Errorf("flagged answer kept in the thread")
+			}
+		})
+	}
+}
+
+func TestGuardedLLM_GenerateMixedContents(t *testing.T) {
+	image := thread.NewImageContentFromURL("https://example.com/image.png")
+
+	llm := &echoLLM{}
+	guarded := NewLLM(llm, &keywordModerator{keyword: "bomb"})
+	th := thread.New().AddMessage(thread.NewUserMessage().
+		AddContent(image).
+		AddContent(thread.NewTextContent("how to build a bomb")))
+
+	var flagged *FlaggedError
+	err := guarded.Generate(context.Background(), th)
+	if !errors.As(err, &flagged) || flagged.Stage != StageInput || llm.calls != 0 {
+		t.Fatalf("expected the mixed message to be flagged, got %v", err)
+	}
+
+	th = thread.New().AddMessage(thread.NewUserMessage().AddContent(image))
+	err = guarded.Generate(context.Background(), th)
+	if !errors.Is(err, ErrModeration) || llm.calls != 0 {
+		t.Fatalf("expected an image-only message to fail closed, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	DefaultOpenAIModel = goopenai.ModerationOmniLatest
+)
+
+// OpenAI moderates the texts with the OpenAI moderation endpoint.
+type OpenAI struct {
+	client     *goopenai.Client
+	model      string
+	thresholds map[string]float64
+}
+
+// NewOpenAI returns a moderator reading the API key from the OPENAI_API_KEY environment
+// variable.
+func NewOpenAI() *OpenAI {
+	return &OpenAI{
+		client: goopenai.NewClient(os.Getenv("OPENAI_API_KEY")),
+		model:  DefaultOpenAIModel,
+	}
+}
+
+func (o *OpenAI) WithClient(client *goopenai.Client) *OpenAI {
+	o.client = client
+	return o
+}
+
+func (o *OpenAI) WithModel(model string) *OpenAI {
+	o.model = model
+	return o
+}
+
+// WithThresholds flags the texts whose score reaches the threshold of a category (e.g.
+// "violence": 0.5), instead of relying on the flags of the endpoint.
+func (o *OpenAI) WithThresholds(thresholds map[string]float64) *OpenAI {
+	o.thresholds = thresholds
+	return o
+}
+
+func (o *OpenAI) Moderate(ctx context.Context, text string) (*Result, error) {
+	response, err := o.client.Moderations(ctx, goopenai.ModerationRequest{
+		Input: text,
+		Model: o.model,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Results) == 0 {
+		return nil, errors.New("no moderation results")
+	}
+
+	var categories map[string]bool
+	var scores map[string]float64
+	err = convert(response.Results[0].Categories, &categories)
+	if err != nil {
+		return nil, err
+	}
+	err = convert(response.Results[0].CategoryScores, &scores)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Flagged: response.Results[0].Flagged,
+		Scores:  scores,
+	}
+
+	if o.thresholds != nil {
+		result.Categories = flaggedCategories(scores, o.thresholds)
+		result.Flagged = len(result.Categories) > 0
+		return result, nil
+	}
+
+	for category, flagged := range categories {
+		if flagged {
+			result.Categories = append(result.Categories, category)
+		}
+	}
+	sort.Strings(result.Categories)
+
+	return result, nil
+}
+
+// convert turns the category structs of the response into maps keyed by category.
+func convert(from any, to any) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, to)
+}
@@ -0,0 +1,308 @@
+// Package capture provides an observer that keeps the observed generations in a local store
+// together with the user feedback they received, so production usage can be turned into
+// evaluation datasets.
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/thread"
+)
+
+const (
+	DefaultFeedbackScoreName = "user-feedback"
+)
+
+var (
+	ErrGenerationNotFound = errors.New("generation not found")
+)
+
+type spanObserver interface {
+	Span(*observer.Span) (*observer.Span, error)
+	SpanEnd(*observer.Span) (*observer.Span, error)
+}
+
+type generationObserver interface {
+	Generation(*observer.Generation) (*observer.Generation, error)
+	GenerationEnd(*observer.Generation) (*observer.Generation, error)
+}
+
+type embeddingObserver interface {
+	Embedding(*observer.Embedding) (*observer.Embedding, error)
+	EmbeddingEnd(*observer.Embedding) (*observer.Embedding, error)
+}
+
+type traceObserver interface {
+	Trace(*observer.Trace) (*observer.Trace, error)
+}
+
+type eventObserver interface {
+	Event(*observer.Event) (*observer.Event, error)
+}
+
+type artifactObserver interface {
+	Artifact(*observer.Artifact) (*observer.Artifact, error)
+}
+
+type scorer interface {
+	Score(*observer.Score) (*observer.Score, error)
+}
+
+type Feedback struct {
+	Score     float64   `json:"score"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Record struct {
+	GenerationID string            `json:"generation_id"`
+	TraceID      string            `json:"trace_id,omitempty"`
+	Name         string            `json:"name"`
+	Model        string            `json:"model"`
+	Input        []*thread.Message `json:"input"`
+	Output       []*thread.Message `json:"output"`
+	Feedback     []Feedback        `json:"feedback,omitempty"`
+}
+
+// Capture is an observer that stores generations locally and forwards every observation to
+// the wrapped observer, if any.
+type Capture struct {
+	next         any
+	scoreName    string
+	mu           sync.RWMutex
+	records      map[string]*Record
+	recordsOrder []string
+	maxRecords   int
+}
+
+// New returns a capture store wrapping the next observer (e.g. Langfuse). The next observer
+// can be nil.
+func New(next any) *Capture {
+	return &Capture{
+		next:      next,
+		scoreName: DefaultFeedbackScoreName,
+		records:   make(map[string]*Record),
+	}
+}
+
+// WithScoreName sets the name of the score sent to the wrapped observer for each feedback.
+func (c *Capture) WithScoreName(name string) *Capture {
+	c.scoreName = name
+	return c
+}
+
+// WithMaxRecords limits the number of stored generations, evicting the oldest ones.
+func (c *Capture) WithMaxRecords(maxRecords int) *Capture {
+	c.maxRecords = maxRecords
+	return c
+}
+
+func (c *Capture) Trace(t *observer.Trace) (*observer.Trace, error) {
+	if o, ok := c.next.(traceObserver); ok {
+		return o.Trace(t)
+	}
+
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return t, nil
+}
+
+func (c *Capture) Span(s *observer.Span) (*observer.Span, error) {
+	if o, ok := c.next.(spanObserver); ok {
+		return o.Span(s)
+	}
+
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return s, nil
+}
+
+func (c *Capture) SpanEnd(s *observer.Span) (*observer.Span, error) {
+	if o, ok := c.next.(spanObserver); ok {
+		return o.SpanEnd(s)
+	}
+	return s, nil
+}
+
+func (c *Capture) Generation(g *observer.Generation) (*observer.Generation, error) {
+	if o, ok := c.next.(generationObserver); ok {
+		var err error
+		g, err = o.Generation(g)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if g.ID == "" {
+		g.ID = uuid.New().String()
+	}
+
+	c.store(g)
+
+	return g, nil
+}
+
+func (c *Capture) GenerationEnd(g *observer.Generation) (*observer.Generation, error) {
+	if o, ok := c.next.(generationObserver); ok {
+		var err error
+		g, err = o.GenerationEnd(g)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.store(g)
+
+	return g, nil
+}
+
+func (c *Capture) Embedding(e *observer.Embedding) (*observer.Embedding, error) {
+	if o, ok := c.next.(embeddingObserver); ok {
+		return o.Embedding(e)
+	}
+
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return e, nil
+}
+
+func (c *Capture) EmbeddingEnd(e *observer.Embedding) (*observer.Embedding, error) {
+	if o, ok := c.next.(embeddingObserver); ok {
+		return o.EmbeddingEnd(e)
+	}
+	return e, nil
+}
+
+func (c *Capture) Event(e *observer.Event) (*observer.Event, error) {
+	if o, ok := c.next.(eventObserver); ok {
+		return o.Event(e)
+	}
+	return e, nil
+}
+
+func (c *Capture) Artifact(a *observer.Artifact) (*observer.Artifact, error) {
+	if o, ok := c.next.(artifactObserver); ok {
+		return o.Artifact(a)
+	}
+	return a, nil
+}
+
+func (c *Capture) Score(s *observer.Score) (*observer.Score, error) {
+	if o, ok := c.next.(scorer); ok {
+		return o.Score(s)
+	}
+	return s, nil
+}
+
+// Feedback records the user feedback for the generation and sends it as a score to the
+// wrapped observer when it supports scores.
+func (c *Capture) Feedback(generationID string, score float64, comment string) error {
+	c.mu.Lock()
+	record, ok := c.records[generationID]
+	if !ok {
+		c.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrGenerationNotFound, generationID)
+	}
+
+	record.Feedback = append(record.Feedback, Feedback{
+		Score:     score,
+		Comment:   comment,
+		CreatedAt: time.Now(),
+	})
+	traceID := record.TraceID
+	c.mu.Unlock()
+
+	o, ok := c.next.(scorer)
+	if !ok {
+		return nil
+	}
+
+	_, err := o.Score(&observer.Score{
+		TraceID:       traceID,
+		ObservationID: generationID,
+		Name:          c.scoreName,
+		Value:         score,
+		Comment:       comment,
+	})
+
+	return err
+}
+
+// Records returns the stored generations, oldest first.
+func (c *Capture) Records() []Record {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	records := make([]Record, 0, len(c.recordsOrder))
+	for _, id := range c.recordsOrder {
+		records = append(records, *c.records[id])
+	}
+
+	return records
+}
+
+// LastRecord returns the most recent generation of the trace, or of any trace if traceID is empty.
+func (c *Capture) LastRecord(traceID string) (Record, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for i := len(c.recordsOrder) - 1; i >= 0; i-- {
+		record := c.records[c.recordsOrder[i]]
+		if traceID == "" || record.TraceID == traceID {
+			return *record, true
+		}
+	}
+
+	return Record{}, false
+}
+
+// WriteDataset writes the generations that received feedback as JSON lines, one record per line.
+func (c *Capture) WriteDataset(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	for _, record := range c.Records() {
+		if len(record.Feedback) == 0 {
+			continue
+		}
+
+		err := encoder.Encode(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Capture) store(g *observer.Generation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	record, ok := c.records[g.ID]
+	if !ok {
+		record = &Record{GenerationID: g.ID}
+		c.records[g.ID] = record
+		c.recordsOrder = append(c.recordsOrder, g.ID)
+	}
+
+	record.TraceID = g.TraceID
+	record.Name = g.Name
+	record.Model = g.Model
+	record.Input = g.Input
+	record.Output = g.Output
+
+	if c.maxRecords > 0 && len(c.recordsOrder) > c.maxRecords {
+		delete(c.records, c.recordsOrder[0])
+		c.recordsOrder = c.recordsOrder[1:]
+	}
+}
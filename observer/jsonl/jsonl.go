@@ -0,0 +1,449 @@
+// Package jsonl provides an observer exporting a machine-readable record of every
+// pipeline run as JSON lines, for offline analysis and replay without a hosted
+// observability vendor.
+package jsonl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/tokenizer"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	RecordTypeTrace      = "trace"
+	RecordTypeSpan       = "span"
+	RecordTypeGeneration = "generation"
+	RecordTypeEmbedding  = "embedding"
+	RecordTypeEvent      = "event"
+	RecordTypeArtifact   = "artifact"
+	RecordTypeScore      = "score"
+
+	errNotEnded = "observation not ended"
+)
+
+var (
+	ErrExport = errors.New("jsonl export error")
+)
+
+type spanObserver interface {
+	Span(*observer.Span) (*observer.Span, error)
+	SpanEnd(*observer.Span) (*observer.Span, error)
+}
+
+type generationObserver interface {
+	Generation(*observer.Generation) (*observer.Generation, error)
+	GenerationEnd(*observer.Generation) (*observer.Generation, error)
+}
+
+type embeddingObserver interface {
+	Embedding(*observer.Embedding) (*observer.Embedding, error)
+	EmbeddingEnd(*observer.Embedding) (*observer.Embedding, error)
+}
+
+type traceObserver interface {
+	Trace(*observer.Trace) (*observer.Trace, error)
+}
+
+type eventObserver interface {
+	Event(*observer.Event) (*observer.Event, error)
+}
+
+type artifactObserver interface {
+	Artifact(*observer.Artifact) (*observer.Artifact, error)
+}
+
+type scorer interface {
+	Score(*observer.Score) (*observer.Score, error)
+}
+
+// Usage is the token usage of a generation or embedding, estimated with the tokenizer
+// package.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Record is a line of the export. Spans, generations and embeddings are written when
+// they end, with their duration; the other observations when they happen.
+type Record struct {
+	Type            string     `json:"type"`
+	ID              string     `json:"id"`
+	TraceID         string     `json:"trace_id,omitempty"`
+	ParentID        string     `json:"parent_id,omitempty"`
+	Name            string     `json:"name,omitempty"`
+	Model           string     `json:"model,omitempty"`
+	ModelParameters types.M    `json:"model_parameters,omitempty"`
+	Input           any        `json:"input,omitempty"`
+	Output          any        `json:"output,omitempty"`
+	Metadata        types.M    `json:"metadata,omitempty"`
+	Usage           *Usage     `json:"usage,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	StartTime       time.Time  `json:"start_time"`
+	EndTime         *time.Time `json:"end_time,omitempty"`
+	DurationMS      int64      `json:"duration_ms,omitempty"`
+}
+
+// Exporter is an observer writing a Record for each observation and forwarding every
+// observation to the wrapped observer, if any.
+type Exporter struct {
+	next    any
+	closer  io.Closer
+	mu      sync.Mutex
+	encoder *json.Encoder
+	pending map[string]*Record
+	order   []string
+	now     func() time.Time
+}
+
+// New returns an exporter writing to w and wrapping the next observer (e.g. Langfuse),
+// which can be nil.
+func New(w io.Writer, next any) *Exporter {
+	return &Exporter{
+		next:    next,
+		encoder: json.NewEncoder(w),
+		pending: make(map[string]*Record),
+		now:     time.Now,
+	}
+}
+
+// NewFile returns an exporter appending to the file at path, created if missing.
+func NewFile(path string, next any) (*Exporter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+
+	e := New(file, next)
+	e.closer = file
+
+	return e, nil
+}
+
+// Close writes the observations started and not ended, e.g. generations that failed,
+// with an error, and closes the file opened by NewFile.
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var errs []error
+	for _, id := range e.order {
+		record := e.pending[id]
+		record.Error = errNotEnded
+		if err := e.encoder.Encode(record); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrExport, err))
+		}
+	}
+	e.pending = make(map[string]*Record)
+	e.order = nil
+
+	if e.closer != nil {
+		if err := e.closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrExport, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *Exporter) Trace(t *observer.Trace) (*observer.Trace, error) {
+	if o, ok := e.next.(traceObserver); ok {
+		var err error
+		t, err = o.Trace(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+
+	return t, e.write(&Record{
+		Type:      RecordTypeTrace,
+		ID:        t.ID,
+		Name:      t.Name,
+		StartTime: e.now(),
+	})
+}
+
+func (e *Exporter) Span(s *observer.Span) (*observer.Span, error) {
+	if o, ok := e.next.(spanObserver); ok {
+		var err error
+		s, err = o.Span(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+
+	e.start(&Record{
+		Type:      RecordTypeSpan,
+		ID:        s.ID,
+		TraceID:   s.TraceID,
+		ParentID:  s.ParentID,
+		Name:      s.Name,
+		Input:     s.Input,
+		StartTime: e.now(),
+	})
+
+	return s, nil
+}
+
+func (e *Exporter) SpanEnd(s *observer.Span) (*observer.Span, error) {
+	if o, ok := e.next.(spanObserver); ok {
+		var err error
+		s, err = o.SpanEnd(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, e.end(s.ID, func(record *Record) {
+		record.Output = s.Output
+	})
+}
+
+func (e *Exporter) Generation(g *observer.Generation) (*observer.Generation, error) {
+	if o, ok := e.next.(generationObserver); ok {
+		var err error
+		g, err = o.Generation(g)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if g.ID == "" {
+		g.ID = uuid.New().String()
+	}
+
+	e.start(&Record{
+		Type:            RecordTypeGeneration,
+		ID:              g.ID,
+		TraceID:         g.TraceID,
+		ParentID:        g.ParentID,
+		Name:            g.Name,
+		Model:           g.Model,
+		ModelParameters: g.ModelParameters,
+		Input:           g.Input,
+		Metadata:        g.Metadata,
+		StartTime:       e.now(),
+	})
+
+	return g, nil
+}
+
+func (e *Exporter) GenerationEnd(g *observer.Generation) (*observer.Generation, error) {
+	if o, ok := e.next.(generationObserver); ok {
+		var err error
+		g, err = o.GenerationEnd(g)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	usage := &Usage{}
+	for _, message := range g.Input {
+		usage.PromptTokens += tokenizer.CountMessageTokens(g.Model, message)
+	}
+	for _, message := range g.Output {
+		usage.CompletionTokens += tokenizer.CountMessageTokens(g.Model, message)
+	}
+
+	return g, e.end(g.ID, func(record *Record) {
+		record.Input = g.Input
+		record.Output = g.Output
+		record.Metadata = g.Metadata
+		record.Usage = usage
+	})
+}
+
+func (e *Exporter) Embedding(emb *observer.Embedding) (*observer.Embedding, error) {
+	if o, ok := e.next.(embeddingObserver); ok {
+		var err error
+		emb, err = o.Embedding(emb)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if emb.ID == "" {
+		emb.ID = uuid.New().String()
+	}
+
+	e.start(&Record{
+		Type:            RecordTypeEmbedding,
+		ID:              emb.ID,
+		TraceID:         emb.TraceID,
+		ParentID:        emb.ParentID,
+		Name:            emb.Name,
+		Model:           emb.Model,
+		ModelParameters: emb.ModelParameters,
+		Input:           emb.Input,
+		Metadata:        emb.Metadata,
+		StartTime:       e.now(),
+	})
+
+	return emb, nil
+}
+
+// EmbeddingEnd writes the embedding record without the vectors, only their count.
+func (e *Exporter) EmbeddingEnd(emb *observer.Embedding) (*observer.Embedding, error) {
+	if o, ok := e.next.(embeddingObserver); ok {
+		var err error
+		emb, err = o.EmbeddingEnd(emb)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	usage := &Usage{}
+	for _, text := range emb.Input {
+		usage.PromptTokens += tokenizer.CountString(emb.Model, text)
+	}
+
+	return emb, e.end(emb.ID, func(record *Record) {
+		record.Output = types.M{"embeddings": len(emb.Output)}
+		record.Metadata = emb.Metadata
+		record.Usage = usage
+	})
+}
+
+func (e *Exporter) Event(ev *observer.Event) (*observer.Event, error) {
+	if o, ok := e.next.(eventObserver); ok {
+		var err error
+		ev, err = o.Event(ev)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ev.ID == "" {
+		ev.ID = uuid.New().String()
+	}
+
+	return ev, e.write(&Record{
+		Type:      RecordTypeEvent,
+		ID:        ev.ID,
+		TraceID:   ev.TraceID,
+		ParentID:  ev.ParentID,
+		Name:      ev.Name,
+		Metadata:  ev.Metadata,
+		StartTime: e.now(),
+	})
+}
+
+// Artifact writes the artifact record without its data, only the MIME type and size.
+func (e *Exporter) Artifact(a *observer.Artifact) (*observer.Artifact, error) {
+	if o, ok := e.next.(artifactObserver); ok {
+		var err error
+		a, err = o.Artifact(a)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+
+	return a, e.write(&Record{
+		Type:      RecordTypeArtifact,
+		ID:        a.ID,
+		TraceID:   a.TraceID,
+		ParentID:  a.GenerationID,
+		Name:      a.Name,
+		Metadata:  types.M{"mime_type": a.MIMEType, "size": len(a.Data)},
+		StartTime: e.now(),
+	})
+}
+
+func (e *Exporter) Score(s *observer.Score) (*observer.Score, error) {
+	if o, ok := e.next.(scorer); ok {
+		var err error
+		s, err = o.Score(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+
+	return s, e.write(&Record{
+		Type:      RecordTypeScore,
+		ID:        s.ID,
+		TraceID:   s.TraceID,
+		ParentID:  s.ObservationID,
+		Name:      s.Name,
+		Output:    s.Value,
+		Metadata:  types.M{"comment": s.Comment},
+		StartTime: e.now(),
+	})
+}
+
+func (e *Exporter) start(record *Record) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.pending[record.ID]; !ok {
+		e.order = append(e.order, record.ID)
+	}
+	e.pending[record.ID] = record
+}
+
+// end completes the pending record of the observation and writes it. Observations not
+// started on this exporter are ignored.
+func (e *Exporter) end(id string, complete func(*Record)) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	record, ok := e.pending[id]
+	if !ok {
+		return nil
+	}
+
+	delete(e.pending, id)
+	for i, pendingID := range e.order {
+		if pendingID == id {
+			e.order = append(e.order[:i], e.order[i+1:]...)
+			break
+		}
+	}
+
+	endTime := e.now()
+	record.EndTime = &endTime
+	record.DurationMS = endTime.Sub(record.StartTime).Milliseconds()
+	complete(record)
+
+	return e.encode(record)
+}
+
+func (e *Exporter) write(record *Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.encode(record)
+}
+
+func (e *Exporter) encode(record *Record) error {
+	err := e.encoder.Encode(record)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrExport, err)
+	}
+
+	return nil
+}
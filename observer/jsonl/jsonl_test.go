@@ -0,0 +1,72 @@
+package jsonl
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestExporter(t *testing.T) {
+	var buf bytes.Buffer
+	e := New(&buf, nil)
+	now := time.Now()
+	e.now = func() time.Time { return now }
+
+	trace, err := e.Trace(&observer.Trace{Name: "run"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generation, err := e.Generation(&observer.Generation{
+		TraceID: trace.ID,
+		Name:    "llm-test",
+		Model:   "test-model",
+		Input:   []*thread.Message{thread.NewUserMessage().AddContent(thread.NewTextContent("hello there"))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	generation.Output = []*thread.Message{thread.NewAssistantMessage().AddContent(thread.NewTextContent("hi"))}
+	if _, err = e.GenerationEnd(generation); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = e.Span(&observer.Span{TraceID: trace.ID, Name: "failed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []Record
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record Record
+		if err = decoder.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+
+	g := records[1]
+	if g.Type != RecordTypeGeneration || g.TraceID != trace.ID || g.DurationMS != 1500 {
+		t.Errorf("unexpected generation record %+v", g)
+	}
+	if g.Usage == nil || g.Usage.PromptTokens == 0 || g.Usage.CompletionTokens == 0 {
+		t.Errorf("missing usage %+v", g.Usage)
+	}
+
+	if s := records[2]; s.Type != RecordTypeSpan || s.Error != errNotEnded {
+		t.Errorf("unexpected span record %+v", s)
+	}
+}
@@ -1,6 +1,9 @@
 package langfuse
 
 import (
+	"encoding/base64"
+	"strings"
+
 	"github.com/henomis/langfuse-go/model"
 	"github.com/henomis/lingoose/observer"
 	"github.com/henomis/lingoose/thread"
@@ -57,8 +60,8 @@ func threadMessagesToLangfuseMSlice(messages []*thread.Message) []model.M {
 func threadOutputMessagesToLangfuseOutput(messages []*thread.Message) any {
 	if len(messages) == 1 &&
 		messages[0].Role == thread.RoleAssistant &&
-		len(messages[0].Contents) == 1 &&
-		messages[0].Contents[0].Type == thread.ContentTypeText {
+		len(messages[0].Contents) > 0 &&
+		messages[0].Contents[0].Type != thread.ContentTypeToolCall {
 		return threadMessageToLangfuseM(messages[0])
 	}
 
@@ -104,6 +107,8 @@ func threadMessageToLangfuseM(message *thread.Message) model.M {
 	for _, content := range message.Contents {
 		if content.Type == thread.ContentTypeText {
 			messageContent += content.AsString()
+		} else if content.Type == thread.ContentTypeImage {
+			m = append(m, imageToLangfuseM(content))
 		} else if content.Type == thread.ContentTypeToolCall {
 			for _, data := range content.AsToolCallData() {
 				m = append(m, model.M{
@@ -121,12 +126,32 @@ func threadMessageToLangfuseM(message *thread.Message) model.M {
 	}
 
 	if len(m) > 0 {
+		if messageContent != "" {
+			m = append([]model.M{{"type": thread.ContentTypeText, "text": messageContent}}, m...)
+		}
 		output["content"] = m
 	}
 
 	return output
 }
 
+// imageToLangfuseM returns the URL of the image, or only the MIME type of inline images
+// which are logged as artifacts.
+func imageToLangfuseM(content *thread.Content) model.M {
+	image := content.AsString()
+	if header, _, ok := strings.Cut(image, ";base64,"); ok && strings.HasPrefix(header, "data:") {
+		return model.M{
+			"type":      content.Type,
+			"mime_type": strings.TrimPrefix(header, "data:"),
+		}
+	}
+
+	return model.M{
+		"type": content.Type,
+		"url":  image,
+	}
+}
+
 func observerGenerationToLangfuseGeneration(g *observer.Generation) *model.Generation {
 	return &model.Generation{
 		ID:                  g.ID,
@@ -165,11 +190,27 @@ func observerEventToLangfuseEvent(e *observer.Event) *model.Event {
 	}
 }
 
+func observerArtifactToLangfuseEvent(a *observer.Artifact) *model.Event {
+	return &model.Event{
+		ID:                  a.ID,
+		ParentObservationID: a.GenerationID,
+		TraceID:             a.TraceID,
+		Name:                a.Name,
+		Output:              "data:" + a.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(a.Data),
+		Metadata: model.M{
+			"mime_type": a.MIMEType,
+			"size":      len(a.Data),
+		},
+	}
+}
+
 func observerScoreToLangfuseScore(s *observer.Score) *model.Score {
 	return &model.Score{
-		ID:      s.ID,
-		TraceID: s.TraceID,
-		Name:    s.Name,
-		Value:   s.Value,
+		ID:            s.ID,
+		TraceID:       s.TraceID,
+		ObservationID: s.ObservationID,
+		Name:          s.Name,
+		Value:         s.Value,
+		Comment:       s.Comment,
 	}
 }
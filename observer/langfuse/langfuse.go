@@ -98,6 +98,18 @@ func (l *Langfuse) Event(e *observer.Event) (*observer.Event, error) {
 	return e, nil
 }
 
+// Artifact logs the artifact as an event of the generation, with the data as a base64
+// data URL that Langfuse renders as media.
+func (l *Langfuse) Artifact(a *observer.Artifact) (*observer.Artifact, error) {
+	langfuseEvent := observerArtifactToLangfuseEvent(a)
+	langfuseEvent, err := l.client.Event(langfuseEvent, nil)
+	if err != nil {
+		return nil, err
+	}
+	a.ID = langfuseEvent.ID
+	return a, nil
+}
+
 func (l *Langfuse) Score(s *observer.Score) (*observer.Score, error) {
 	langfuseScore := observerScoreToLangfuseScore(s)
 	langfuseScore, err := l.client.Score(langfuseScore)
@@ -62,11 +62,23 @@ type Event struct {
 	Metadata types.M
 }
 
+// Artifact is a binary output of a generation, such as a generated image.
+type Artifact struct {
+	ID           string
+	GenerationID string
+	TraceID      string
+	Name         string
+	MIMEType     string
+	Data         []byte
+}
+
 type Score struct {
-	ID      string
-	TraceID string
-	Name    string
-	Value   float64
+	ID            string
+	TraceID       string
+	ObservationID string
+	Name          string
+	Value         float64
+	Comment       string
 }
 
 func ContextValueParentID(ctx context.Context) string {
@@ -0,0 +1,126 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/henomis/lingoose/llm/usagereport"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/tokenizer"
+	"github.com/henomis/lingoose/types"
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// GuardedLLM reserves the estimated prompt tokens of the thread on the budget of the
+// request key before calling the LLM, and settles the reservation with the real usage
+// after the call. The real usage is the one reported by the provider through the context
+// (see the usagereport package), or the tokens of the thread and of the answer estimated
+// with the model tokenizer for providers that don't report it.
+type GuardedLLM struct {
+	llm           LLM
+	model         string
+	guard         *Guard
+	fallback      LLM
+	fallbackModel string
+}
+
+// NewLLM guards the calls of the LLM using the model, whose name is used to price and
+// count the tokens.
+func NewLLM(llm LLM, model string, guard *Guard) *GuardedLLM {
+	return &GuardedLLM{
+		llm:   llm,
+		model: model,
+		guard: guard,
+	}
+}
+
+// WithFallback downgrades the requests exceeding the budget to a cheaper LLM instead of
+// rejecting them. The fallback usage is charged to the key too, and it can be guarded
+// by its own GuardedLLM with a larger budget.
+func (l *GuardedLLM) WithFallback(fallback LLM, model string) *GuardedLLM {
+	l.fallback = fallback
+	l.fallbackModel = model
+	return l
+}
+
+func (l *GuardedLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	llm, model := l.llm, l.model
+	promptTokens := tokenizer.CountThreadTokens(model, t)
+
+	reservation, err := l.guard.Reserve(ctx, model, promptTokens)
+	if errors.Is(err, ErrBudgetExceeded) && l.fallback != nil {
+		llm, model = l.fallback, l.fallbackModel
+		promptTokens = tokenizer.CountThreadTokens(model, t)
+		reservation, err = l.guard.reserve(ctx, model, promptTokens, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	var reported providerUsage
+	messages := len(t.Messages)
+	err = llm.Generate(usagereport.WithReporter(ctx, reported.add), t)
+
+	answer := t.Messages[min(messages, len(t.Messages)):]
+	completionTokens := 0
+	for _, message := range answer {
+		completionTokens += tokenizer.CountMessageTokens(model, message)
+	}
+
+	if prompt, completion, ok := reported.get(); ok {
+		promptTokens, completionTokens = prompt, completion
+	} else if err != nil && len(answer) == 0 {
+		// a failed call without answer is not charged, a partial answer is
+		promptTokens = 0
+	}
+
+	settleErr := reservation.Settle(ctx, promptTokens, completionTokens)
+	if err != nil {
+		return err
+	}
+
+	return settleErr
+}
+
+// providerUsage sums the usage reported by the provider for the calls of a request.
+type providerUsage struct {
+	mu               sync.Mutex
+	reported         bool
+	promptTokens     int
+	completionTokens int
+}
+
+func (u *providerUsage) add(usage types.Meta) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.reported = true
+	u.promptTokens += metaInt(usage, "PromptTokens")
+	u.completionTokens += metaInt(usage, "CompletionTokens")
+}
+
+func (u *providerUsage) get() (int, int, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.promptTokens, u.completionTokens, u.reported
+}
+
+func metaInt(meta types.Meta, key string) int {
+	switch value := meta[key].(type) {
+	case int:
+		return value
+	case int32:
+		return int(value)
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	}
+
+	return 0
+}
@@ -0,0 +1,307 @@
+// Package quota enforces usage budgets, in tokens or dollars, per API key, user or
+// session. Requests are rejected with ErrBudgetExceeded, or downgraded to a cheaper LLM,
+// once the budget of their key is exhausted.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/henomis/lingoose/costtracker"
+	"github.com/henomis/lingoose/secret"
+)
+
+var ErrBudgetExceeded = errors.New("usage budget exceeded")
+
+// Usage is the cumulative usage of a key.
+type Usage struct {
+	Tokens int
+	USD    float64
+}
+
+func (u Usage) add(other Usage) Usage {
+	return Usage{
+		Tokens: u.Tokens + other.Tokens,
+		USD:    u.USD + other.USD,
+	}
+}
+
+func (u Usage) sub(other Usage) Usage {
+	return Usage{
+		Tokens: u.Tokens - other.Tokens,
+		USD:    u.USD - other.USD,
+	}
+}
+
+// Limit is the budget of a key. Zero values are not limited. With a period the usage is
+// reset at the start of each period, e.g. every day at midnight UTC, otherwise it never
+// resets.
+type Limit struct {
+	Tokens int
+	USD    float64
+	Period time.Duration
+}
+
+// exceeded reports whether the usage is beyond the limit.
+func (l Limit) exceeded(usage Usage) bool {
+	return (l.Tokens > 0 && usage.Tokens > l.Tokens) || (l.USD > 0 && usage.USD > l.USD)
+}
+
+// BudgetExceededError is the error of the requests rejected because the budget of their
+// key is exhausted. It matches ErrBudgetExceeded.
+type BudgetExceededError struct {
+	Key   string
+	Used  Usage
+	Limit Limit
+	// ResetAt is the start of the next period, zero if the usage never resets.
+	ResetAt time.Time
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf(
+		"%s for %q: used %d tokens and %.4f USD of %d tokens and %.4f USD",
+		ErrBudgetExceeded, e.Key, e.Used.Tokens, e.Used.USD, e.Limit.Tokens, e.Limit.USD,
+	)
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// Store persists the usage per key. Add must be atomic, returning the usage including the
+// added one, which can be negative to return a reservation.
+type Store interface {
+	Get(ctx context.Context, key string) (Usage, error)
+	Add(ctx context.Context, key string, usage Usage) (Usage, error)
+}
+
+// KeyFunc returns the key whose budget is charged for a request, e.g. the user or the
+// session ID stored in the context.
+type KeyFunc func(ctx context.Context) string
+
+// Guard tracks the usage of the keys and checks it against their limit.
+type Guard struct {
+	store   Store
+	limit   Limit
+	limits  map[string]Limit
+	keyFunc KeyFunc
+	pricing costtracker.Pricing
+	now     func() time.Time
+}
+
+// New creates a guard enforcing the limit on every key, with the usage kept in memory.
+// The key of a request is its tenant (see secret.WithTenant) by default.
+func New(limit Limit) *Guard {
+	return &Guard{
+		store:   NewMemoryStore(),
+		limit:   limit,
+		limits:  make(map[string]Limit),
+		keyFunc: secret.Tenant,
+		pricing: costtracker.DefaultPricing(),
+		now:     time.Now,
+	}
+}
+
+// WithStore sets the store of the usage, e.g. to share it among instances.
+func (g *Guard) WithStore(store Store) *Guard {
+	g.store = store
+	return g
+}
+
+// WithKeyLimit sets the limit of a key, in place of the default one.
+func (g *Guard) WithKeyLimit(key string, limit Limit) *Guard {
+	g.limits[key] = limit
+	return g
+}
+
+// WithKeyFunc sets the function returning the key of a request.
+func (g *Guard) WithKeyFunc(keyFunc KeyFunc) *Guard {
+	g.keyFunc = keyFunc
+	return g
+}
+
+// WithPricing adds or replaces the prices used to compute the cost of the tokens.
+func (g *Guard) WithPricing(pricing costtracker.Pricing) *Guard {
+	for model, price := range pricing {
+		g.pricing[model] = price
+	}
+	return g
+}
+
+// Usage returns the usage of the key in the current period.
+func (g *Guard) Usage(ctx context.Context, key string) (Usage, error) {
+	limit := g.keyLimit(key)
+	return g.store.Get(ctx, g.storeKey(key, limit))
+}
+
+// Check returns a *BudgetExceededError if the usage of the request key, plus the
+// estimated prompt tokens of the model, exceeds its limit. It doesn't charge anything, so
+// concurrent requests can all pass the check: use Reserve to admit a request.
+func (g *Guard) Check(ctx context.Context, model string, promptTokens int) error {
+	key := g.keyFunc(ctx)
+	limit := g.keyLimit(key)
+
+	used, err := g.store.Get(ctx, g.storeKey(key, limit))
+	if err != nil {
+		return err
+	}
+
+	if limit.exceeded(used.add(g.cost(model, promptTokens, 0))) {
+		return g.exceededError(key, used, limit)
+	}
+
+	return nil
+}
+
+// Reserve charges the estimated prompt tokens of the model to the request key before the
+// call, with an atomic Store.Add, so that concurrent requests can't overrun the budget
+// together. If the usage exceeds the limit the reservation is released and a
+// *BudgetExceededError returned. The reservation must be settled with the real usage, or
+// released if nothing was used.
+func (g *Guard) Reserve(ctx context.Context, model string, promptTokens int) (*Reservation, error) {
+	return g.reserve(ctx, model, promptTokens, true)
+}
+
+// reserve charges the estimated prompt tokens, checking the limit if enforce is true.
+func (g *Guard) reserve(ctx context.Context, model string, promptTokens int, enforce bool) (*Reservation, error) {
+	key := g.keyFunc(ctx)
+	limit := g.keyLimit(key)
+	storeKey := g.storeKey(key, limit)
+	reserved := g.cost(model, promptTokens, 0)
+
+	used, err := g.store.Add(ctx, storeKey, reserved)
+	if err != nil {
+		return nil, err
+	}
+
+	if enforce && limit.exceeded(used) {
+		_, err = g.store.Add(ctx, storeKey, Usage{}.sub(reserved))
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, g.exceededError(key, used.sub(reserved), limit)
+	}
+
+	return &Reservation{
+		guard:    g,
+		model:    model,
+		storeKey: storeKey,
+		reserved: reserved,
+	}, nil
+}
+
+// Record charges the tokens of the model to the request key, e.g. the exact usage
+// reported by the provider.
+func (g *Guard) Record(ctx context.Context, model string, promptTokens, completionTokens int) error {
+	key := g.keyFunc(ctx)
+	limit := g.keyLimit(key)
+
+	_, err := g.store.Add(ctx, g.storeKey(key, limit), g.cost(model, promptTokens, completionTokens))
+
+	return err
+}
+
+// Reservation is the usage charged by Reserve, in the period the request was admitted.
+type Reservation struct {
+	guard    *Guard
+	model    string
+	storeKey string
+	reserved Usage
+	settled  bool
+}
+
+// Settle replaces the reserved usage with the real one, e.g. the usage reported by the
+// provider. Further calls do nothing.
+func (r *Reservation) Settle(ctx context.Context, promptTokens, completionTokens int) error {
+	if r.settled {
+		return nil
+	}
+	r.settled = true
+
+	used := r.guard.cost(r.model, promptTokens, completionTokens)
+	_, err := r.guard.store.Add(ctx, r.storeKey, used.sub(r.reserved))
+
+	return err
+}
+
+// Release returns the reserved usage, e.g. when the call failed without using tokens.
+func (r *Reservation) Release(ctx context.Context) error {
+	return r.Settle(ctx, 0, 0)
+}
+
+func (g *Guard) exceededError(key string, used Usage, limit Limit) *BudgetExceededError {
+	return &BudgetExceededError{
+		Key:     key,
+		Used:    used,
+		Limit:   limit,
+		ResetAt: g.resetAt(limit),
+	}
+}
+
+func (g *Guard) cost(model string, promptTokens, completionTokens int) Usage {
+	price, _ := g.pricing.Price(model)
+
+	return Usage{
+		Tokens: promptTokens + completionTokens,
+		USD:    price.Cost(promptTokens, completionTokens),
+	}
+}
+
+func (g *Guard) keyLimit(key string) Limit {
+	if limit, ok := g.limits[key]; ok {
+		return limit
+	}
+
+	return g.limit
+}
+
+// storeKey returns the key of the usage in the current period.
+func (g *Guard) storeKey(key string, limit Limit) string {
+	if limit.Period <= 0 {
+		return key
+	}
+
+	return key + "@" + strconv.FormatInt(g.now().Truncate(limit.Period).Unix(), 10)
+}
+
+func (g *Guard) resetAt(limit Limit) time.Time {
+	if limit.Period <= 0 {
+		return time.Time{}
+	}
+
+	return g.now().Truncate(limit.Period).Add(limit.Period)
+}
+
+// MemoryStore keeps the usage in memory, for the lifetime of the process, including the
+// usage of the past periods.
+type MemoryStore struct {
+	mu    sync.Mutex
+	usage map[string]Usage
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		usage: make(map[string]Usage),
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.usage[key], nil
+}
+
+func (s *MemoryStore) Add(_ context.Context, key string, usage Usage) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usage[key] = s.usage[key].add(usage)
+
+	return s.usage[key], nil
+}
@@ -0,0 +1,214 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/costtracker"
+	"github.com/henomis/lingoose/llm/usagereport"
+	"github.com/henomis/lingoose/secret"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+// echoLLM answers with a fixed text, counting its calls.
+type echoLLM struct {
+	answer string
+	calls  int
+	err    error
+}
+
+func (e *echoLLM) Generate(_ context.Context, t *thread.Thread) error {
+	e.calls++
+	if e.err != nil {
+		return e.err
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent(e.answer)))
+
+	return nil
+}
+
+// reportingLLM answers reporting its usage through the context, as the providers do.
+type reportingLLM struct {
+	usage types.Meta
+}
+
+func (r *reportingLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	usagereport.Report(ctx, r.usage)
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("answer")))
+
+	return nil
+}
+
+func newThread() *thread.Thread {
+	return thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hello there")))
+}
+
+func TestGuardedLLM_Reject(t *testing.T) {
+	guard := New(Limit{Tokens: 50}).WithKeyLimit("vip", Limit{})
+	llm := &echoLLM{answer: "a short answer"}
+	guarded := NewLLM(llm, "gpt-4o", guard)
+	ctx := secret.WithTenant(context.Background(), "user-1")
+
+	calls := 0
+	var err error
+	for ; calls < 10; calls++ {
+		if err = guarded.Generate(ctx, newThread()); err != nil {
+			break
+		}
+	}
+
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected a BudgetExceededError, got %v", err)
+	}
+	if calls == 0 || llm.calls != calls || budgetErr.Key != "user-1" || budgetErr.Limit.Tokens != 50 {
+		t.Fatalf("rejected after %d calls with %+v", calls, budgetErr)
+	}
+	if !budgetErr.ResetAt.IsZero() {
+		t.Errorf("expected no reset without period, got %v", budgetErr.ResetAt)
+	}
+
+	// other keys have their own budget
+	vipCtx := secret.WithTenant(context.Background(), "vip")
+	for j := 0; j < 20; j++ {
+		if err = guarded.Generate(vipCtx, newThread()); err != nil {
+			t.Fatalf("unlimited key rejected: %v", err)
+		}
+	}
+	if err = guarded.Generate(secret.WithTenant(context.Background(), "user-2"), newThread()); err != nil {
+		t.Fatalf("new key rejected: %v", err)
+	}
+}
+
+func TestGuardedLLM_Fallback(t *testing.T) {
+	guard := New(Limit{USD: 0.001}).WithPricing(costtracker.Pricing{
+		"expensive": {Input: 100, Output: 100},
+		"cheap":     {Input: 0.01, Output: 0.01},
+	})
+	expensive := &echoLLM{answer: "expensive answer"}
+	cheap := &echoLLM{answer: "cheap answer"}
+	guarded := NewLLM(expensive, "expensive", guard).WithFallback(cheap, "cheap")
+
+	for j := 0; j < 3; j++ {
+		err := guarded.Generate(context.Background(), newThread())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if expensive.calls != 1 || cheap.calls != 2 {
+		t.Fatalf("expected 1 expensive and 2 cheap calls, got %d and %d", expensive.calls, cheap.calls)
+	}
+
+	usage, err := guard.Usage(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.USD < 0.001 || usage.Tokens == 0 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestGuardedLLM_FailedCall(t *testing.T) {
+	guard := New(Limit{Tokens: 10})
+	llm := &echoLLM{err: errors.New("unavailable")}
+
+	err := NewLLM(llm, "gpt-4o", guard).Generate(context.Background(), newThread())
+	if err == nil || errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected the LLM error, got %v", err)
+	}
+
+	usage, _ := guard.Usage(context.Background(), "")
+	if usage.Tokens != 0 {
+		t.Errorf("failed call charged %d tokens", usage.Tokens)
+	}
+}
+
+func TestGuard_Period(t *testing.T) {
+	now := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	guard := New(Limit{Tokens: 100, Period: 24 * time.Hour})
+	guard.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	err := guard.Record(ctx, "gpt-4o", 80, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var budgetErr *BudgetExceededError
+	if err = guard.Check(ctx, "gpt-4o", 0); !errors.As(err, &budgetErr) {
+		t.Fatalf("expected a BudgetExceededError, got %v", err)
+	}
+	if want := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC); !budgetErr.ResetAt.Equal(want) {
+		t.Errorf("ResetAt = %v, want %v", budgetErr.ResetAt, want)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if err = guard.Check(ctx, "gpt-4o", 50); err != nil {
+		t.Fatalf("expected the budget to be reset, got %v", err)
+	}
+}
+
+func TestGuardedLLM_ProviderUsage(t *testing.T) {
+	guard := New(Limit{})
+	llm := &reportingLLM{usage: types.Meta{"PromptTokens": 1200, "CompletionTokens": 34}}
+
+	err := NewLLM(llm, "gpt-4o", guard).Generate(context.Background(), newThread())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usage, _ := guard.Usage(context.Background(), "")
+	if usage.Tokens != 1234 {
+		t.Errorf("expected the provider usage of 1234 tokens, got %d", usage.Tokens)
+	}
+}
+
+func TestGuard_ReserveConcurrent(t *testing.T) {
+	guard := New(Limit{Tokens: 100})
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var reservations []*Reservation
+	var wg sync.WaitGroup
+	for j := 0; j < 20; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, err := guard.Reserve(ctx, "gpt-4o", 10)
+			if err == nil {
+				mu.Lock()
+				reservations = append(reservations, reservation)
+				mu.Unlock()
+			} else if !errors.Is(err, ErrBudgetExceeded) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(reservations) != 10 {
+		t.Fatalf("expected 10 requests to fit the budget, got %d", len(reservations))
+	}
+
+	if err := reservations[0].Settle(ctx, 4, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := reservations[1].Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// settling twice doesn't charge again
+	if err := reservations[0].Settle(ctx, 4, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, _ := guard.Usage(ctx, "")
+	if usage.Tokens != 86 {
+		t.Errorf("expected 86 tokens used, got %d", usage.Tokens)
+	}
+}
@@ -0,0 +1,94 @@
+package rag
+
+import (
+	"context"
+	"math"
+
+	"github.com/henomis/lingoose/index"
+)
+
+// WithDeduplication drops the retrieved chunks whose cosine similarity with a better ranked
+// chunk is at least threshold (e.g. 0.95), so near-duplicate chunks, common with
+// overlapping chunks and repeated documents, don't waste the prompt. The embeddings
+// returned by the vector database are used; when it doesn't return them the chunks are
+// embedded again. Zero disables the deduplication.
+func (r *RAG) WithDeduplication(threshold float64) *RAG {
+	r.dedupThreshold = threshold
+	return r
+}
+
+func (r *RAG) deduplicate(ctx context.Context, results index.SearchResults) (index.SearchResults, error) {
+	if r.dedupThreshold <= 0 || len(results) < 2 {
+		return results, nil
+	}
+
+	vectors, err := r.resultVectors(ctx, results)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept index.SearchResults
+	var keptVectors [][]float64
+	for i, result := range results {
+		duplicate := false
+		for _, keptVector := range keptVectors {
+			if cosineSimilarity(vectors[i], keptVector) >= r.dedupThreshold {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		kept = append(kept, result)
+		keptVectors = append(keptVectors, vectors[i])
+	}
+
+	return kept, nil
+}
+
+func (r *RAG) resultVectors(ctx context.Context, results index.SearchResults) ([][]float64, error) {
+	vectors := make([][]float64, len(results))
+	texts := make([]string, len(results))
+	missing := false
+	for i, result := range results {
+		vectors[i] = result.Values
+		texts[i] = result.Content()
+		missing = missing || len(result.Values) == 0
+	}
+
+	if !missing {
+		return vectors, nil
+	}
+
+	embeddings, err := r.index.Embedder().Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, embedding := range embeddings {
+		vectors[i] = embedding
+	}
+
+	return vectors, nil
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -35,11 +35,14 @@ type observer interface {
 }
 
 type RAG struct {
-	index        *index.Index
-	chunkSize    uint
-	chunkOverlap uint
-	topK         uint
-	loaders      map[*regexp.Regexp]Loader // this map a regexp as string to a loader
+	index          *index.Index
+	chunkSize      uint
+	chunkOverlap   uint
+	topK           uint
+	loaders        map[*regexp.Regexp]Loader // this map a regexp as string to a loader
+	chunkWindow    uint
+	docStore       DocStore
+	dedupThreshold float64
 }
 
 func New(index *index.Index) *RAG {
@@ -105,6 +108,13 @@ func (r *RAG) AddSources(ctx context.Context, sources ...string) error {
 		if errAddSource != nil {
 			return errAddSource
 		}
+
+		if r.chunkWindow > 0 {
+			errAddSource = r.docStore.Put(ctx, documents)
+			if errAddSource != nil {
+				return errAddSource
+			}
+		}
 	}
 
 	err = r.stopObserveSpan(ctx, span)
@@ -169,6 +179,13 @@ func (r *RAG) Retrieve(ctx context.Context, query string) ([]string, error) {
 
 func (r *RAG) retrieve(ctx context.Context, query string) ([]string, error) {
 	results, err := r.index.Query(ctx, query, option.WithTopK(int(r.topK)))
+	if err == nil {
+		results, err = r.deduplicate(ctx, results)
+	}
+	if err == nil && r.chunkWindow > 0 {
+		return r.expandWindows(ctx, results)
+	}
+
 	var resultsAsString []string
 	for _, result := range results {
 		resultsAsString = append(resultsAsString, result.Content())
@@ -194,10 +211,22 @@ func (r *RAG) addSource(ctx context.Context, source string) ([]document.Document
 		return nil, err
 	}
 
-	return textsplitter.NewRecursiveCharacterTextSplitter(
-		int(r.chunkSize),
-		int(r.chunkOverlap),
-	).SplitDocuments(documents), nil
+	if r.chunkWindow > 0 {
+		return r.splitAndLink(documents)
+	}
+
+	return r.textSplitter().SplitDocuments(documents), nil
+}
+
+// textSplitter returns the splitter of the sources, tagging the chunks with their
+// document when the embedder supports late chunking.
+func (r *RAG) textSplitter() *textsplitter.RecursiveCharacterTextSplitter {
+	splitter := textsplitter.NewRecursiveCharacterTextSplitter(int(r.chunkSize), int(r.chunkOverlap))
+	if _, ok := r.index.Embedder().(index.ChunkEmbedder); ok {
+		splitter = splitter.WithDocumentIDs()
+	}
+
+	return splitter
 }
 
 func (r *RAG) startObserveSpan(ctx context.Context, name string, input any) (context.Context, *obs.Span, error) {
@@ -59,6 +59,27 @@ func (r *Fusion) Retrieve(ctx context.Context, query string) ([]string, error) {
 }
 
 func (r *Fusion) retrieve(ctx context.Context, query string) ([]string, error) {
+	results, err := r.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var texts []string
+	for _, result := range results {
+		texts = append(texts, result.Content())
+	}
+
+	return texts, nil
+}
+
+// Query returns the results of the queries generated from the given one, fused by
+// reciprocal rank. With option.WithExplain their explanation holds the contribution of
+// each generated query.
+func (r *Fusion) Query(ctx context.Context, query string, opts ...option.Option) (index.SearchResults, error) {
+	return r.query(ctx, query, opts...)
+}
+
+func (r *Fusion) query(ctx context.Context, query string, opts ...option.Option) (index.SearchResults, error) {
 	if r.llm == nil {
 		return nil, fmt.Errorf("llm is not set")
 	}
@@ -91,31 +112,41 @@ func (r *Fusion) retrieve(ctx context.Context, query string) ([]string, error) {
 	content = strings.TrimSpace(content)
 	questions := strings.Split(content, "\n")
 
-	var results index.SearchResults
+	opts = append([]option.Option{option.WithTopK(int(r.topK))}, opts...)
+
+	resultLists := make([]index.SearchResults, 0, len(questions))
 	for _, question := range questions {
-		res, queryErr := r.index.Query(ctx, question, option.WithTopK(int(r.topK)))
+		res, queryErr := r.index.Query(ctx, question, opts...)
 		if queryErr != nil {
 			return nil, queryErr
 		}
 
-		results = append(results, res...)
+		resultLists = append(resultLists, res)
 	}
 
-	return reciprocalRankFusion(results), nil
+	return reciprocalRankFusion(questions, resultLists), nil
 }
 
-func reciprocalRankFusion(searchResults index.SearchResults) []string {
+// reciprocalRankFusion fuses the result lists of the queries, recording the fusion
+// components in the explanation of the explained results.
+func reciprocalRankFusion(queries []string, resultLists []index.SearchResults) index.SearchResults {
 	const k = 60.0
 	searchResultsScoreMap := make(map[string]float64)
-	for _, result := range searchResults {
-		if _, ok := searchResultsScoreMap[result.ID]; !ok {
-			searchResultsScoreMap[result.ID] = 0
+	components := make(map[string][]index.FusionComponent)
+	var searchResults index.SearchResults
+	for j, results := range resultLists {
+		for rank, result := range results {
+			contribution := 1 / (result.Score + k)
+			searchResultsScoreMap[result.ID] += contribution
+			components[result.ID] = append(components[result.ID], index.FusionComponent{
+				Source:       queries[j],
+				Rank:         rank,
+				Score:        result.Score,
+				Contribution: contribution,
+			})
 		}
-		searchResultsScoreMap[result.ID] += 1 / (result.Score + k)
-	}
 
-	for i, searchResult := range searchResults {
-		searchResults[i].Score = searchResultsScoreMap[searchResult.ID]
+		searchResults = append(searchResults, results...)
 	}
 
 	//remove duplicates
@@ -123,6 +154,13 @@ func reciprocalRankFusion(searchResults index.SearchResults) []string {
 	var uniqueSearchResults index.SearchResults
 	for _, searchResult := range searchResults {
 		if _, ok := seen[searchResult.Content()]; !ok {
+			searchResult.Score = searchResultsScoreMap[searchResult.ID]
+			if searchResult.Explanation != nil {
+				explanation := *searchResult.Explanation
+				explanation.Fusion = components[searchResult.ID]
+				searchResult.Explanation = &explanation
+			}
+
 			uniqueSearchResults = append(uniqueSearchResults, searchResult)
 			seen[searchResult.Content()] = true
 		}
@@ -133,10 +171,5 @@ func reciprocalRankFusion(searchResults index.SearchResults) []string {
 		return uniqueSearchResults[i].Score > uniqueSearchResults[j].Score
 	})
 
-	var results []string
-	for _, searchResult := range uniqueSearchResults {
-		results = append(results, searchResult.Content())
-	}
-
-	return results
+	return uniqueSearchResults
 }
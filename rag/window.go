@@ -0,0 +1,283 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/index"
+)
+
+const (
+	// MetadataChunkID is the chunk metadata key holding the chunk ID.
+	MetadataChunkID = "chunk_id"
+	// MetadataPrevChunkID is the chunk metadata key holding the ID of the previous chunk
+	// of the same document.
+	MetadataPrevChunkID = "chunk_prev_id"
+	// MetadataNextChunkID is the chunk metadata key holding the ID of the next chunk of
+	// the same document.
+	MetadataNextChunkID = "chunk_next_id"
+)
+
+// DocStore stores the chunks by their MetadataChunkID, so that the neighbors of a
+// retrieved chunk can be fetched.
+type DocStore interface {
+	Put(context.Context, []document.Document) error
+	// Get returns the chunk with the given ID, nil if it is not found.
+	Get(context.Context, string) (*document.Document, error)
+}
+
+// WithChunkWindow expands each retrieved chunk with up to window chunks before and after
+// it, merging overlapping windows, to give the LLM more complete context without
+// enlarging the indexed chunks. Sources must be added after enabling it. The chunks are
+// kept in an in-memory DocStore unless one is set with WithDocStore.
+func (r *RAG) WithChunkWindow(window uint) *RAG {
+	r.chunkWindow = window
+	if r.docStore == nil {
+		r.docStore = NewMemoryDocStore()
+	}
+	return r
+}
+
+func (r *RAG) WithDocStore(docStore DocStore) *RAG {
+	r.docStore = docStore
+	return r
+}
+
+// splitAndLink splits the documents, linking the chunks of each document to their
+// neighbors.
+func (r *RAG) splitAndLink(documents []document.Document) ([]document.Document, error) {
+	splitter := r.textSplitter()
+
+	var chunks []document.Document
+	for _, doc := range documents {
+		documentChunks := splitter.SplitDocuments([]document.Document{doc})
+		ids := make([]string, len(documentChunks))
+		for i := range documentChunks {
+			id, err := uuid.NewUUID()
+			if err != nil {
+				return nil, err
+			}
+			ids[i] = id.String()
+		}
+
+		for i := range documentChunks {
+			documentChunks[i].SetMetadata(MetadataChunkID, ids[i])
+			if i > 0 {
+				documentChunks[i].SetMetadata(MetadataPrevChunkID, ids[i-1])
+			}
+			if i < len(documentChunks)-1 {
+				documentChunks[i].SetMetadata(MetadataNextChunkID, ids[i+1])
+			}
+		}
+
+		chunks = append(chunks, documentChunks...)
+	}
+
+	return chunks, nil
+}
+
+// expandWindows replaces each result with its chunk window. Windows sharing chunks are
+// merged into the first one.
+func (r *RAG) expandWindows(ctx context.Context, results index.SearchResults) ([]string, error) {
+	var windows [][]*document.Document
+	for _, result := range results {
+		hit := &document.Document{
+			Content:  result.Content(),
+			Metadata: result.Metadata,
+		}
+
+		window, err := r.chunkWindowOf(ctx, hit)
+		if err != nil {
+			return nil, err
+		}
+
+		windows = mergeWindow(windows, window)
+	}
+
+	texts := make([]string, len(windows))
+	for i, window := range windows {
+		texts[i] = r.joinChunks(window)
+	}
+
+	return texts, nil
+}
+
+func (r *RAG) chunkWindowOf(ctx context.Context, hit *document.Document) ([]*document.Document, error) {
+	window := []*document.Document{hit}
+
+	for _, key := range []string{MetadataPrevChunkID, MetadataNextChunkID} {
+		current := hit
+		for i := uint(0); i < r.chunkWindow; i++ {
+			id, ok := current.Metadata[key].(string)
+			if !ok || id == "" {
+				break
+			}
+
+			neighbor, err := r.docStore.Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			if neighbor == nil {
+				break
+			}
+
+			if key == MetadataPrevChunkID {
+				window = append([]*document.Document{neighbor}, window...)
+			} else {
+				window = append(window, neighbor)
+			}
+			current = neighbor
+		}
+	}
+
+	return window, nil
+}
+
+// mergeWindow adds the window to the windows, merging it with the first window it
+// overlaps. Windows are contiguous ranges of the same chunk chain, so their union is
+// the part of the window before the other one, the other one and the part after.
+func mergeWindow(windows [][]*document.Document, window []*document.Document) [][]*document.Document {
+	for i, other := range windows {
+		first := indexOfChunk(window, chunkID(other[0]))
+		last := indexOfChunk(window, chunkID(other[len(other)-1]))
+		if first < 0 && last < 0 && indexOfChunk(other, chunkID(window[0])) < 0 {
+			continue
+		}
+
+		var merged []*document.Document
+		if first > 0 {
+			merged = append(merged, window[:first]...)
+		}
+		merged = append(merged, other...)
+		if last >= 0 {
+			merged = append(merged, window[last+1:]...)
+		}
+		windows[i] = merged
+
+		return windows
+	}
+
+	return append(windows, window)
+}
+
+func chunkID(chunk *document.Document) string {
+	id, _ := chunk.Metadata[MetadataChunkID].(string)
+	return id
+}
+
+func indexOfChunk(window []*document.Document, id string) int {
+	if id == "" {
+		return -1
+	}
+
+	for i, chunk := range window {
+		if chunkID(chunk) == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// joinChunks joins the chunks, removing the text repeated by the chunk overlap.
+func (r *RAG) joinChunks(chunks []*document.Document) string {
+	var sb strings.Builder
+	previous := ""
+	for i, chunk := range chunks {
+		content := chunk.Content
+		if i > 0 {
+			overlap := overlapLength(previous, content, int(r.chunkOverlap))
+			if overlap > 0 {
+				content = content[overlap:]
+			} else {
+				sb.WriteString("\n")
+			}
+		}
+
+		sb.WriteString(content)
+		previous = chunk.Content
+	}
+
+	return sb.String()
+}
+
+// overlapLength returns the length of the longest prefix of b, up to maxLength, that
+// a ends with.
+func overlapLength(a, b string, maxLength int) int {
+	maxLength = min(maxLength, len(a), len(b))
+	for length := maxLength; length > 0; length-- {
+		if strings.HasSuffix(a, b[:length]) {
+			return length
+		}
+	}
+
+	return 0
+}
+
+// MemoryDocStore is an in-memory DocStore, optionally persisted to a JSON file.
+type MemoryDocStore struct {
+	mu        sync.RWMutex
+	documents map[string]document.Document
+	path      string
+}
+
+func NewMemoryDocStore() *MemoryDocStore {
+	return &MemoryDocStore{
+		documents: make(map[string]document.Document),
+	}
+}
+
+// WithPersist persists the chunks to the file at path, loading them if it exists.
+func (m *MemoryDocStore) WithPersist(path string) *MemoryDocStore {
+	m.path = path
+
+	content, err := os.ReadFile(path)
+	if err == nil {
+		_ = json.Unmarshal(content, &m.documents)
+	}
+
+	return m
+}
+
+func (m *MemoryDocStore) Put(_ context.Context, documents []document.Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, doc := range documents {
+		id, ok := doc.Metadata[MetadataChunkID].(string)
+		if !ok {
+			return errors.New("document without chunk id")
+		}
+		m.documents[id] = doc
+	}
+
+	if m.path == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(m.documents)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(m.path, content, 0600)
+}
+
+func (m *MemoryDocStore) Get(_ context.Context, id string) (*document.Document, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	doc, ok := m.documents[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return &doc, nil
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/thread"
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error)
+}
+
+// LimitedLLM limits the calls of any LLM. The estimated tokens are those of the thread
+// plus the expected completion tokens.
+type LimitedLLM struct {
+	llm              LLM
+	limiter          *Limiter
+	completionTokens int
+}
+
+func NewLLM(llm LLM, limiter *Limiter) *LimitedLLM {
+	return &LimitedLLM{
+		llm:     llm,
+		limiter: limiter,
+	}
+}
+
+// WithCompletionTokens sets the tokens expected in the answer, usually the max tokens
+// of the LLM, since providers count them in the tokens per minute limit.
+func (l *LimitedLLM) WithCompletionTokens(completionTokens int) *LimitedLLM {
+	l.completionTokens = completionTokens
+	return l
+}
+
+func (l *LimitedLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	err := l.limiter.Wait(ctx, ThreadTokens(t)+l.completionTokens)
+	if err != nil {
+		return err
+	}
+
+	return l.llm.Generate(ctx, t)
+}
+
+// LimitedEmbedder limits the calls of any embedder.
+type LimitedEmbedder struct {
+	embedder Embedder
+	limiter  *Limiter
+}
+
+func NewEmbedder(embedder Embedder, limiter *Limiter) *LimitedEmbedder {
+	return &LimitedEmbedder{
+		embedder: embedder,
+		limiter:  limiter,
+	}
+}
+
+func (e *LimitedEmbedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	err := e.limiter.Wait(ctx, EstimateTokens(texts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return e.embedder.Embed(ctx, texts)
+}
+
+// ThreadTokens estimates the tokens of the text contents of the thread.
+func ThreadTokens(t *thread.Thread) int {
+	if t == nil {
+		return 0
+	}
+
+	var texts []string
+	for _, m := range t.Messages {
+		for _, c := range m.Contents {
+			if text, ok := c.Data.(string); ok && c.Type == thread.ContentTypeText {
+				texts = append(texts, text)
+			}
+		}
+	}
+
+	return EstimateTokens(texts...)
+}
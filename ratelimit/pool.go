@@ -0,0 +1,240 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/secret"
+	"github.com/henomis/lingoose/thread"
+)
+
+var ErrEmptyPool = errors.New("no members in the pool")
+
+// Balancing is how a pool chooses among its members the one serving a request.
+type Balancing int
+
+const (
+	// RoundRobin rotates across the members.
+	RoundRobin Balancing = iota
+	// LeastLoaded chooses the member with the fewest requests in flight.
+	LeastLoaded
+)
+
+// PoolStats reports the requests served by a pool member.
+type PoolStats struct {
+	Name     string
+	InFlight int
+	Requests int
+	Tokens   int
+	Errors   int
+}
+
+// PoolLLM balances the calls across instances of an LLM using different API keys or
+// endpoints, for quotas sharded across keys. Each member has its own limiter: members
+// whose quota is exhausted are skipped, and when all are exhausted the request waits
+// for the first one available.
+type PoolLLM struct {
+	pool             pool[LLM]
+	completionTokens int
+}
+
+func NewPoolLLM(balancing Balancing) *PoolLLM {
+	return &PoolLLM{
+		pool: pool[LLM]{balancing: balancing},
+	}
+}
+
+// WithLLM adds an LLM to the pool, limited by limiter if not nil. The LLM must not be
+// limited by the same limiter itself, otherwise its requests are counted twice.
+func (p *PoolLLM) WithLLM(name string, llm LLM, limiter *Limiter) *PoolLLM {
+	p.pool.add(name, llm, limiter)
+	return p
+}
+
+// WithCompletionTokens sets the tokens expected in the answer, usually the max tokens
+// of the LLMs.
+func (p *PoolLLM) WithCompletionTokens(completionTokens int) *PoolLLM {
+	p.completionTokens = completionTokens
+	return p
+}
+
+func (p *PoolLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	member, err := p.pool.acquire(ctx, ThreadTokens(t)+p.completionTokens)
+	if err != nil {
+		return err
+	}
+
+	err = member.client.Generate(ctx, t)
+	p.pool.release(member, err)
+
+	return err
+}
+
+func (p *PoolLLM) Stats() []PoolStats {
+	return p.pool.stats()
+}
+
+// PoolEmbedder balances the calls across instances of an embedder using different API
+// keys or endpoints, like PoolLLM.
+type PoolEmbedder struct {
+	pool pool[Embedder]
+}
+
+func NewPoolEmbedder(balancing Balancing) *PoolEmbedder {
+	return &PoolEmbedder{
+		pool: pool[Embedder]{balancing: balancing},
+	}
+}
+
+// WithEmbedder adds an embedder to the pool, limited by limiter if not nil.
+func (p *PoolEmbedder) WithEmbedder(name string, embedder Embedder, limiter *Limiter) *PoolEmbedder {
+	p.pool.add(name, embedder, limiter)
+	return p
+}
+
+func (p *PoolEmbedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	member, err := p.pool.acquire(ctx, EstimateTokens(texts...))
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings, err := member.client.Embed(ctx, texts)
+	p.pool.release(member, err)
+
+	return embeddings, err
+}
+
+func (p *PoolEmbedder) Stats() []PoolStats {
+	return p.pool.stats()
+}
+
+type poolMember[T any] struct {
+	index   int
+	client  T
+	limiter *Limiter
+	stats   PoolStats
+}
+
+type pool[T any] struct {
+	mu        sync.Mutex
+	balancing Balancing
+	members   []*poolMember[T]
+	next      int
+}
+
+func (p *pool[T]) add(name string, client T, limiter *Limiter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.members = append(p.members, &poolMember[T]{
+		index:   len(p.members),
+		client:  client,
+		limiter: limiter,
+		stats:   PoolStats{Name: name},
+	})
+}
+
+// acquire reserves the request on the first candidate member whose limiter allows it,
+// otherwise it waits for the member available first.
+func (p *pool[T]) acquire(ctx context.Context, tokens int) (*poolMember[T], error) {
+	p.mu.Lock()
+
+	if len(p.members) == 0 {
+		p.mu.Unlock()
+		return nil, ErrEmptyPool
+	}
+
+	tenant := secret.Tenant(ctx)
+
+	var waitFor *poolMember[T]
+	var minDelay time.Duration
+	for _, member := range p.candidates() {
+		if member.limiter == nil {
+			p.take(member, tokens)
+			p.mu.Unlock()
+			return member, nil
+		}
+
+		limiter := member.limiter.forTenant(tenant)
+		if limiter.tpm > 0 && float64(tokens) > limiter.tpm {
+			continue
+		}
+
+		delay := limiter.reserve(float64(tokens))
+		if delay == 0 {
+			p.take(member, tokens)
+			p.mu.Unlock()
+			return member, nil
+		}
+
+		if waitFor == nil || delay < minDelay {
+			waitFor, minDelay = member, delay
+		}
+	}
+
+	if waitFor == nil {
+		p.mu.Unlock()
+		return nil, ErrTooManyTokens
+	}
+
+	p.take(waitFor, tokens)
+	p.mu.Unlock()
+
+	err := waitFor.limiter.Wait(ctx, tokens)
+	if err != nil {
+		p.release(waitFor, err)
+		return nil, err
+	}
+
+	return waitFor, nil
+}
+
+func (p *pool[T]) release(member *poolMember[T], err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	member.stats.InFlight--
+	if err != nil {
+		member.stats.Errors++
+	}
+}
+
+// candidates returns the members in the order they are tried, starting from the one
+// after the last used.
+func (p *pool[T]) candidates() []*poolMember[T] {
+	start := p.next % len(p.members)
+	candidates := make([]*poolMember[T], 0, len(p.members))
+	candidates = append(candidates, p.members[start:]...)
+	candidates = append(candidates, p.members[:start]...)
+
+	if p.balancing == LeastLoaded {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].stats.InFlight < candidates[j].stats.InFlight
+		})
+	}
+
+	return candidates
+}
+
+func (p *pool[T]) take(member *poolMember[T], tokens int) {
+	member.stats.InFlight++
+	member.stats.Requests++
+	member.stats.Tokens += tokens
+	p.next = member.index + 1
+}
+
+func (p *pool[T]) stats() []PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]PoolStats, 0, len(p.members))
+	for _, member := range p.members {
+		stats = append(stats, member.stats)
+	}
+
+	return stats
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+type countingLLM struct {
+	calls int
+}
+
+func (c *countingLLM) Generate(context.Context, *thread.Thread) error {
+	c.calls++
+	return nil
+}
+
+func TestPoolLLM_Generate(t *testing.T) {
+	first, second, third := &countingLLM{}, &countingLLM{}, &countingLLM{}
+	pool := NewPoolLLM(RoundRobin).
+		WithLLM("first", first, New(1, 0)).
+		WithLLM("second", second, nil).
+		WithLLM("third", third, New(100, 0))
+
+	for i := 0; i < 6; i++ {
+		if err := pool.Generate(context.Background(), thread.New()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first key has a single request per minute, then it's skipped
+	if first.calls != 1 || second.calls != 3 || third.calls != 2 {
+		t.Fatalf("unexpected calls %d %d %d", first.calls, second.calls, third.calls)
+	}
+
+	stats := pool.Stats()
+	if stats[1].Name != "second" || stats[1].Requests != 3 || stats[1].InFlight != 0 {
+		t.Fatalf("unexpected stats %+v", stats[1])
+	}
+}
+
+func TestPool_LeastLoaded(t *testing.T) {
+	p := pool[LLM]{balancing: LeastLoaded}
+	p.add("first", &countingLLM{}, nil)
+	p.add("second", &countingLLM{}, nil)
+
+	busy, err := p.acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		member, err := p.acquire(context.Background(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if member == busy {
+			t.Fatalf("request %d sent to the busy member", i)
+		}
+		p.release(member, nil)
+	}
+}
@@ -0,0 +1,181 @@
+// Package ratelimit provides a client-side rate limiter for LLM and embedder calls,
+// limiting both the requests and the tokens per minute.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/henomis/lingoose/secret"
+)
+
+const (
+	// charsPerToken is the rough number of characters per token of English text.
+	charsPerToken = 4
+)
+
+var ErrTooManyTokens = errors.New("request exceeds the tokens per minute limit")
+
+// Limiter is a pair of token buckets refilled every minute with rpm requests and tpm
+// tokens. A limiter can be shared by many LLM and embedder instances using the same API
+// key, so that their calls are limited together. The calls of each tenant set with
+// secret.WithTenant have their own buckets, so a tenant can't exhaust the others' quota.
+type Limiter struct {
+	mu sync.Mutex
+
+	rpm      float64
+	tpm      float64
+	requests float64
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+	tenants  map[string]*Limiter
+}
+
+// New creates a limiter allowing rpm requests and tpm tokens per minute. A zero value
+// disables the related limit.
+func New(rpm, tpm int) *Limiter {
+	return &Limiter{
+		rpm:      float64(rpm),
+		tpm:      float64(tpm),
+		requests: float64(rpm),
+		tokens:   float64(tpm),
+		now:      time.Now,
+	}
+}
+
+// Wait blocks until a request of the given estimated tokens can be sent. Requests larger
+// than the tokens per minute limit fail with ErrTooManyTokens.
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	l = l.forTenant(secret.Tenant(ctx))
+	if l.tpm > 0 && float64(tokens) > l.tpm {
+		return ErrTooManyTokens
+	}
+
+	for {
+		delay := l.reserve(float64(tokens))
+		if delay == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Adjust corrects the tokens consumed by a request once the actual usage is known,
+// actual minus estimated tokens are taken from (or given back to) the bucket.
+func (l *Limiter) Adjust(ctx context.Context, estimated, actual int) {
+	l = l.forTenant(secret.Tenant(ctx))
+	if l.tpm == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	l.tokens -= float64(actual - estimated)
+	if l.tokens > l.tpm {
+		l.tokens = l.tpm
+	}
+}
+
+// forTenant returns the limiter of the tenant, created on first use with the same
+// quotas. The calls without a tenant use l itself.
+func (l *Limiter) forTenant(tenant string) *Limiter {
+	if tenant == "" {
+		return l
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tenantLimiter, ok := l.tenants[tenant]
+	if !ok {
+		if l.tenants == nil {
+			l.tenants = make(map[string]*Limiter)
+		}
+		tenantLimiter = New(int(l.rpm), int(l.tpm))
+		tenantLimiter.now = l.now
+		l.tenants[tenant] = tenantLimiter
+	}
+
+	return tenantLimiter
+}
+
+// reserve consumes the request if both buckets allow it, otherwise it returns how long
+// to wait before trying again.
+func (l *Limiter) reserve(tokens float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+
+	var delay time.Duration
+	if l.rpm > 0 && l.requests < 1 {
+		delay = maxDuration(delay, minutesToDuration((1-l.requests)/l.rpm))
+	}
+	if l.tpm > 0 && l.tokens < tokens {
+		delay = maxDuration(delay, minutesToDuration((tokens-l.tokens)/l.tpm))
+	}
+	if delay > 0 {
+		return delay
+	}
+
+	if l.rpm > 0 {
+		l.requests--
+	}
+	if l.tpm > 0 {
+		l.tokens -= tokens
+	}
+
+	return 0
+}
+
+func (l *Limiter) refill() {
+	now := l.now()
+	if !l.last.IsZero() {
+		elapsed := now.Sub(l.last).Minutes()
+		l.requests = minFloat(l.rpm, l.requests+elapsed*l.rpm)
+		l.tokens = minFloat(l.tpm, l.tokens+elapsed*l.tpm)
+	}
+	l.last = now
+}
+
+// EstimateTokens estimates the tokens of the texts from their length.
+func EstimateTokens(texts ...string) int {
+	chars := 0
+	for _, text := range texts {
+		chars += len(text)
+	}
+
+	return (chars + charsPerToken - 1) / charsPerToken
+}
+
+func minutesToDuration(minutes float64) time.Duration {
+	delay := time.Duration(minutes * float64(time.Minute))
+	if delay < time.Millisecond {
+		delay = time.Millisecond
+	}
+	return delay
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/henomis/lingoose/secret"
+)
+
+func TestLimiter_reserve(t *testing.T) {
+	now := time.Now()
+	l := New(2, 1000)
+	l.now = func() time.Time { return now }
+
+	if d := l.reserve(400); d != 0 {
+		t.Fatalf("first request delayed %v", d)
+	}
+	if d := l.reserve(400); d != 0 {
+		t.Fatalf("second request delayed %v", d)
+	}
+
+	// no requests left: one request is refilled in 30s
+	if d := l.reserve(100); d != 30*time.Second {
+		t.Fatalf("expected 30s delay, got %v", d)
+	}
+
+	now = now.Add(30 * time.Second)
+	// 700 tokens available, 800 requested: 100 tokens are refilled in 6s
+	if d := l.reserve(800); d != 6*time.Second {
+		t.Fatalf("expected 6s delay, got %v", d)
+	}
+	if d := l.reserve(700); d != 0 {
+		t.Fatalf("request delayed %v", d)
+	}
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	l := New(0, 100)
+
+	err := l.Wait(context.Background(), 101)
+	if !errors.Is(err, ErrTooManyTokens) {
+		t.Fatalf("expected ErrTooManyTokens, got %v", err)
+	}
+
+	err = l.Wait(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = l.Wait(ctx, 50)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestLimiter_WaitTenants(t *testing.T) {
+	l := New(1, 0)
+
+	acme := secret.WithTenant(context.Background(), "acme")
+	if err := l.Wait(acme, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// other tenants and the calls without a tenant have their own buckets
+	for _, ctx := range []context.Context{secret.WithTenant(context.Background(), "globex"), context.Background()} {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		err := l.Wait(ctx, 0)
+		cancel()
+		if err != nil {
+			t.Fatalf("request delayed by another tenant: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(acme, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+// Package recovery converts the panics of user provided code, like tools and decoders,
+// into errors, so that a single buggy function can't crash a long-running server.
+package recovery
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+var ErrPanic = errors.New("panic recovered")
+
+// PanicError is the error returned in place of a panic. It matches ErrPanic and, when
+// the panic value is an error, that error too.
+type PanicError struct {
+	// Op names the recovered operation, e.g. "tool get_weather".
+	Op string
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.Op, e.Value)
+}
+
+func (e *PanicError) Unwrap() []error {
+	if err, ok := e.Value.(error); ok {
+		return []error{ErrPanic, err}
+	}
+
+	return []error{ErrPanic}
+}
+
+// Do calls fn, returning a *PanicError if it panics.
+func Do(op string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{
+				Op:    op,
+				Value: r,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+
+	return fn()
+}
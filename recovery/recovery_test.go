@@ -0,0 +1,38 @@
+package recovery
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDo(t *testing.T) {
+	err := Do("tool", func() error {
+		var values []int
+		_ = values[1]
+		return nil
+	})
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || !errors.Is(err, ErrPanic) {
+		t.Fatalf("error = %v, want a panic error", err)
+	}
+	if panicErr.Op != "tool" || !strings.Contains(err.Error(), "index out of range") || len(panicErr.Stack) == 0 {
+		t.Errorf("panic error = %+v", panicErr)
+	}
+
+	err = Do("decoder", func() error {
+		panic(io.ErrUnexpectedEOF)
+	})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("error = %v, want to match the panic value", err)
+	}
+
+	err = Do("tool", func() error {
+		return io.EOF
+	})
+	if err != io.EOF {
+		t.Errorf("error = %v, want the returned error", err)
+	}
+}
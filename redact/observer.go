@@ -0,0 +1,219 @@
+package redact
+
+import (
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/types"
+)
+
+type spanObserver interface {
+	Span(*observer.Span) (*observer.Span, error)
+	SpanEnd(*observer.Span) (*observer.Span, error)
+}
+
+type generationObserver interface {
+	Generation(*observer.Generation) (*observer.Generation, error)
+	GenerationEnd(*observer.Generation) (*observer.Generation, error)
+}
+
+type embeddingObserver interface {
+	Embedding(*observer.Embedding) (*observer.Embedding, error)
+	EmbeddingEnd(*observer.Embedding) (*observer.Embedding, error)
+}
+
+type traceObserver interface {
+	Trace(*observer.Trace) (*observer.Trace, error)
+}
+
+type eventObserver interface {
+	Event(*observer.Event) (*observer.Event, error)
+}
+
+type artifactObserver interface {
+	Artifact(*observer.Artifact) (*observer.Artifact, error)
+}
+
+type scorer interface {
+	Score(*observer.Score) (*observer.Score, error)
+}
+
+// Observer forwards the observations to the wrapped observer (e.g. Langfuse or the JSON
+// lines exporter) redacted with the profile. The observations of the caller are left
+// untouched, only the IDs assigned by the wrapped observer are copied back.
+type Observer struct {
+	next    any
+	profile *Profile
+}
+
+func NewObserver(next any, profile *Profile) *Observer {
+	return &Observer{
+		next:    next,
+		profile: profile,
+	}
+}
+
+func (o *Observer) Trace(t *observer.Trace) (*observer.Trace, error) {
+	next, ok := o.next.(traceObserver)
+	if !ok {
+		return t, nil
+	}
+
+	return next.Trace(t)
+}
+
+func (o *Observer) Span(s *observer.Span) (*observer.Span, error) {
+	next, ok := o.next.(spanObserver)
+	if !ok {
+		return s, nil
+	}
+
+	redacted, err := next.Span(o.span(s))
+	if err != nil {
+		return nil, err
+	}
+	s.ID = redacted.ID
+
+	return s, nil
+}
+
+func (o *Observer) SpanEnd(s *observer.Span) (*observer.Span, error) {
+	next, ok := o.next.(spanObserver)
+	if !ok {
+		return s, nil
+	}
+
+	_, err := next.SpanEnd(o.span(s))
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (o *Observer) Generation(g *observer.Generation) (*observer.Generation, error) {
+	next, ok := o.next.(generationObserver)
+	if !ok {
+		return g, nil
+	}
+
+	redacted, err := next.Generation(o.generation(g))
+	if err != nil {
+		return nil, err
+	}
+	g.ID = redacted.ID
+
+	return g, nil
+}
+
+func (o *Observer) GenerationEnd(g *observer.Generation) (*observer.Generation, error) {
+	next, ok := o.next.(generationObserver)
+	if !ok {
+		return g, nil
+	}
+
+	_, err := next.GenerationEnd(o.generation(g))
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (o *Observer) Embedding(e *observer.Embedding) (*observer.Embedding, error) {
+	next, ok := o.next.(embeddingObserver)
+	if !ok {
+		return e, nil
+	}
+
+	redacted, err := next.Embedding(o.embedding(e))
+	if err != nil {
+		return nil, err
+	}
+	e.ID = redacted.ID
+
+	return e, nil
+}
+
+func (o *Observer) EmbeddingEnd(e *observer.Embedding) (*observer.Embedding, error) {
+	next, ok := o.next.(embeddingObserver)
+	if !ok {
+		return e, nil
+	}
+
+	_, err := next.EmbeddingEnd(o.embedding(e))
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (o *Observer) Event(e *observer.Event) (*observer.Event, error) {
+	next, ok := o.next.(eventObserver)
+	if !ok {
+		return e, nil
+	}
+
+	redacted := *e
+	redacted.Metadata = o.profile.Value(e.Metadata).(types.M)
+
+	result, err := next.Event(&redacted)
+	if err != nil {
+		return nil, err
+	}
+	e.ID = result.ID
+
+	return e, nil
+}
+
+// Artifact forwards the artifacts as they are, binary data can't be redacted.
+func (o *Observer) Artifact(a *observer.Artifact) (*observer.Artifact, error) {
+	next, ok := o.next.(artifactObserver)
+	if !ok {
+		return a, nil
+	}
+
+	return next.Artifact(a)
+}
+
+func (o *Observer) Score(s *observer.Score) (*observer.Score, error) {
+	next, ok := o.next.(scorer)
+	if !ok {
+		return s, nil
+	}
+
+	redacted := *s
+	redacted.Comment = o.profile.Text(s.Comment)
+
+	result, err := next.Score(&redacted)
+	if err != nil {
+		return nil, err
+	}
+	s.ID = result.ID
+
+	return s, nil
+}
+
+func (o *Observer) span(s *observer.Span) *observer.Span {
+	redacted := *s
+	redacted.Input = o.profile.Value(s.Input)
+	redacted.Output = o.profile.Value(s.Output)
+
+	return &redacted
+}
+
+func (o *Observer) generation(g *observer.Generation) *observer.Generation {
+	redacted := *g
+	redacted.Input = o.profile.Messages(g.Input)
+	redacted.Output = o.profile.Messages(g.Output)
+	redacted.Metadata = o.profile.Value(g.Metadata).(types.M)
+
+	return &redacted
+}
+
+func (o *Observer) embedding(e *observer.Embedding) *observer.Embedding {
+	redacted := *e
+	redacted.Input = o.profile.Value(e.Input).([]string)
+	redacted.Metadata = o.profile.Value(e.Metadata).(types.M)
+
+	return &redacted
+}
@@ -0,0 +1,242 @@
+// Package redact removes personal and sensitive data from the threads before they are
+// exported, logged or sent to the observers.
+package redact
+
+import (
+	"regexp"
+
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	// Placeholder replaces the dropped tool arguments and results.
+	Placeholder = "[REDACTED]"
+	// EmailPlaceholder replaces the email addresses.
+	EmailPlaceholder = "[EMAIL]"
+
+	minMaskedDigits = 4
+)
+
+var (
+	emailRegexp = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// numbers, possibly grouped by spaces, dashes or dots, such as phone or card numbers
+	numberRegexp = regexp.MustCompile(`\+?\d(?:[\d\-. ]*\d)?`)
+	digitRegexp  = regexp.MustCompile(`\d`)
+)
+
+type rule func(string) string
+
+// Profile is a set of redaction rules. Profiles copy the threads and messages they
+// redact, the originals are left untouched.
+type Profile struct {
+	rules             []rule
+	dropToolArguments bool
+	dropToolResults   bool
+}
+
+func New() *Profile {
+	return &Profile{}
+}
+
+// Default returns a profile removing the email addresses, masking the numbers and
+// dropping the tool arguments.
+func Default() *Profile {
+	return New().WithEmails().WithNumbers().WithoutToolArguments()
+}
+
+// WithEmails replaces the email addresses with EmailPlaceholder.
+func (p *Profile) WithEmails() *Profile {
+	return p.WithPattern(emailRegexp, EmailPlaceholder)
+}
+
+// WithNumbers masks the digits of the numbers of at least 4 digits, such as phone, card
+// or account numbers, keeping their separators.
+func (p *Profile) WithNumbers() *Profile {
+	p.rules = append(p.rules, func(text string) string {
+		return numberRegexp.ReplaceAllStringFunc(text, func(number string) string {
+			if len(digitRegexp.FindAllStringIndex(number, -1)) < minMaskedDigits {
+				return number
+			}
+			return digitRegexp.ReplaceAllString(number, "*")
+		})
+	})
+	return p
+}
+
+// WithPattern replaces the matches of the regular expression with replacement, which
+// can refer to the submatches as in regexp.Regexp.ReplaceAllString.
+func (p *Profile) WithPattern(pattern *regexp.Regexp, replacement string) *Profile {
+	p.rules = append(p.rules, func(text string) string {
+		return pattern.ReplaceAllString(text, replacement)
+	})
+	return p
+}
+
+// WithoutToolArguments replaces the arguments of the tool calls with Placeholder.
+func (p *Profile) WithoutToolArguments() *Profile {
+	p.dropToolArguments = true
+	return p
+}
+
+// WithoutToolResults replaces the results of the tool calls with Placeholder.
+func (p *Profile) WithoutToolResults() *Profile {
+	p.dropToolResults = true
+	return p
+}
+
+// Text applies the rules to the text.
+func (p *Profile) Text(text string) string {
+	for _, rule := range p.rules {
+		text = rule(text)
+	}
+
+	return text
+}
+
+// Thread returns a redacted copy of the thread, branches included.
+func (p *Profile) Thread(t *thread.Thread) *thread.Thread {
+	if t == nil {
+		return nil
+	}
+
+	redacted := thread.New().AddMessages(p.Messages(t.Messages)...)
+	for _, branch := range t.Branches {
+		redacted.Branches = append(redacted.Branches, &thread.Branch{
+			Index:    branch.Index,
+			Messages: p.Messages(branch.Messages),
+		})
+	}
+
+	return redacted
+}
+
+func (p *Profile) Messages(messages []*thread.Message) []*thread.Message {
+	if messages == nil {
+		return nil
+	}
+
+	redacted := make([]*thread.Message, 0, len(messages))
+	for _, message := range messages {
+		redacted = append(redacted, p.Message(message))
+	}
+
+	return redacted
+}
+
+// Message returns a redacted copy of the message. The typed values returned by the tools
+// can't be redacted and are dropped, their text result is kept.
+func (p *Profile) Message(message *thread.Message) *thread.Message {
+	if message == nil {
+		return nil
+	}
+
+	redacted := &thread.Message{
+		Role:     message.Role,
+		Contents: make([]*thread.Content, 0, len(message.Contents)),
+		Metadata: p.Meta(message.Metadata),
+	}
+
+	for _, content := range message.Contents {
+		redacted.Contents = append(redacted.Contents, p.content(content))
+	}
+
+	return redacted
+}
+
+// Meta returns a copy of the metadata with the rules applied to the string values.
+func (p *Profile) Meta(meta types.Meta) types.Meta {
+	if meta == nil {
+		return nil
+	}
+
+	redacted := make(types.Meta, len(meta))
+	for key, value := range meta {
+		redacted[key] = p.Value(value)
+	}
+
+	return redacted
+}
+
+// Value redacts strings, threads, messages and maps; other values are returned as they
+// are.
+func (p *Profile) Value(value any) any {
+	switch v := value.(type) {
+	case string:
+		return p.Text(v)
+	case []string:
+		if v == nil {
+			return v
+		}
+		redacted := make([]string, 0, len(v))
+		for _, text := range v {
+			redacted = append(redacted, p.Text(text))
+		}
+		return redacted
+	case *thread.Thread:
+		return p.Thread(v)
+	case *thread.Message:
+		return p.Message(v)
+	case []*thread.Message:
+		return p.Messages(v)
+	case types.Meta:
+		return p.Meta(v)
+	case types.M:
+		if v == nil {
+			return v
+		}
+		redacted := make(types.M, len(v))
+		for key, value := range v {
+			redacted[key] = p.Value(value)
+		}
+		return redacted
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for key, value := range v {
+			redacted[key] = p.Text(value)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+func (p *Profile) content(content *thread.Content) *thread.Content {
+	if content == nil {
+		return nil
+	}
+
+	redacted := &thread.Content{Type: content.Type, Data: content.Data}
+
+	switch data := content.Data.(type) {
+	case string:
+		if content.Type != thread.ContentTypeImage {
+			redacted.Data = p.Text(data)
+		}
+	case []thread.ToolCallData:
+		toolCalls := make([]thread.ToolCallData, 0, len(data))
+		for _, toolCall := range data {
+			toolCall.Arguments = p.toolText(toolCall.Arguments, p.dropToolArguments)
+			toolCalls = append(toolCalls, toolCall)
+		}
+		redacted.Data = toolCalls
+	case thread.ToolResponseData:
+		data.Result = p.toolText(data.Result, p.dropToolResults)
+		data.Value = nil
+		data.Err = nil
+		redacted.Data = data
+	case thread.AudioData:
+		data.Transcript = p.Text(data.Transcript)
+		redacted.Data = data
+	}
+
+	return redacted
+}
+
+func (p *Profile) toolText(text string, drop bool) string {
+	if drop {
+		return Placeholder
+	}
+
+	return p.Text(text)
+}
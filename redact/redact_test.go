@@ -0,0 +1,121 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/henomis/lingoose/observer"
+	"github.com/henomis/lingoose/observer/jsonl"
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestProfile_Text(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile *Profile
+		text    string
+		want    string
+	}{
+		{
+			name:    "emails",
+			profile: New().WithEmails(),
+			text:    "write to mario.rossi+work@example.co.uk or info@acme.it",
+			want:    "write to [EMAIL] or [EMAIL]",
+		},
+		{
+			name:    "numbers",
+			profile: New().WithNumbers(),
+			text:    "call +39 333 123-4567 about order 12, card 4111.1111.1111.1111",
+			want:    "call +** *** ***-**** about order 12, card ****.****.****.****",
+		},
+		{
+			name:    "pattern",
+			profile: New().WithPattern(regexp.MustCompile(`(IT)\d{2}[A-Z0-9]+`), "${1}[IBAN]"),
+			text:    "IBAN IT60X0542811101000000123456",
+			want:    "IBAN IT[IBAN]",
+		},
+		{
+			name:    "default",
+			profile: Default(),
+			text:    "I'm bob@example.com, my phone is 555 0100",
+			want:    "I'm [EMAIL], my phone is *** ****",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.Text(tt.text); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfile_Thread(t *testing.T) {
+	toolCall := thread.ToolCallData{ID: "1", Name: "lookup", Arguments: `{"email":"bob@example.com"}`}
+	th := thread.New().AddMessages(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("I'm bob@example.com")).
+			AddMetadata("user", "bob@example.com"),
+		thread.NewAssistantMessage().AddContent(thread.NewToolCallContent([]thread.ToolCallData{toolCall})),
+		thread.NewToolMessage().AddContent(thread.NewToolResponseContent(thread.ToolResponseData{
+			ID: "1", Name: "lookup", Result: `"bob@example.com is premium"`, Value: "bob@example.com is premium",
+		})),
+	)
+	th.Fork(2)
+
+	redacted := Default().Thread(th)
+
+	if got := redacted.Messages[0].Contents[0].AsString(); got != "I'm [EMAIL]" {
+		t.Fatalf("unexpected text %q", got)
+	}
+	if got := redacted.Messages[0].Metadata["user"]; got != EmailPlaceholder {
+		t.Fatalf("unexpected metadata %v", got)
+	}
+	if got := redacted.Messages[1].Contents[0].AsToolCallData()[0]; got.Arguments != Placeholder || got.Name != "lookup" {
+		t.Fatalf("unexpected tool call %+v", got)
+	}
+
+	response := redacted.Branches[0].Messages[0].Contents[0].AsToolResponseData()
+	if response.Result != `"[EMAIL] is premium"` || response.Value != nil {
+		t.Fatalf("unexpected tool response %+v", response)
+	}
+
+	if th.Messages[0].Contents[0].AsString() != "I'm bob@example.com" || th.Messages[1].Contents[0].AsToolCallData()[0] != toolCall {
+		t.Fatal("the original thread has been modified")
+	}
+}
+
+func TestObserver(t *testing.T) {
+	var buf bytes.Buffer
+	o := NewObserver(jsonl.New(&buf, nil), New().WithEmails().WithoutToolResults())
+
+	input := []*thread.Message{thread.NewUserMessage().AddContent(thread.NewTextContent("I'm bob@example.com"))}
+	g, err := o.Generation(&observer.Generation{Name: "chat", Input: input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.ID == "" || g.Input[0] != input[0] {
+		t.Fatalf("expected the ID of the wrapped observer and the original input, got %+v", g)
+	}
+
+	g.Output = []*thread.Message{thread.NewAssistantMessage().AddContent(thread.NewTextContent("Hi bob@example.com"))}
+	_, err = o.GenerationEnd(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "bob@example.com") {
+		t.Fatalf("the email has been exported: %s", buf.String())
+	}
+
+	var record jsonl.Record
+	if err = json.Unmarshal(buf.Bytes(), &record); err != nil || record.ID != g.ID {
+		t.Fatalf("unexpected record %s: %v", buf.String(), err)
+	}
+	if g.Output[0].Contents[0].AsString() != "Hi bob@example.com" {
+		t.Fatal("the original output has been modified")
+	}
+}
@@ -0,0 +1,186 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	awsService        = "secretsmanager"
+	awsContentType    = "application/x-amz-json-1.1"
+	awsTarget         = "secretsmanager.GetSecretValue"
+	awsSigningAlgo    = "AWS4-HMAC-SHA256"
+	awsDateTimeFormat = "20060102T150405Z"
+	awsDateFormat     = "20060102"
+)
+
+// AWSSecretsManager reads secrets from AWS Secrets Manager. Keys are secret IDs, optionally
+// followed by "#field" to extract a field from a JSON secret.
+type AWSSecretsManager struct {
+	httpClient      *http.Client
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// NewAWSSecretsManager returns a provider using the credentials from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+func NewAWSSecretsManager(region string) *AWSSecretsManager {
+	return &AWSSecretsManager{
+		httpClient:      http.DefaultClient,
+		region:          region,
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+func (a *AWSSecretsManager) WithCredentials(accessKeyID, secretAccessKey, sessionToken string) *AWSSecretsManager {
+	a.accessKeyID = accessKeyID
+	a.secretAccessKey = secretAccessKey
+	a.sessionToken = sessionToken
+	return a
+}
+
+// WithHTTPClient sets the http client to use for the provider
+func (a *AWSSecretsManager) WithHTTPClient(httpClient *http.Client) *AWSSecretsManager {
+	a.httpClient = httpClient
+	return a
+}
+
+func (a *AWSSecretsManager) Get(ctx context.Context, key string) (string, error) {
+	secretID, field, hasField := strings.Cut(key, "#")
+
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+
+	host := awsService + "." + a.region + ".amazonaws.com"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+	req.Header.Set("Content-Type", awsContentType)
+	req.Header.Set("X-Amz-Target", awsTarget)
+	a.sign(req, host, body, time.Now().UTC())
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		if strings.Contains(string(respBody), "ResourceNotFoundException") {
+			return "", fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+		}
+		return "", fmt.Errorf("%w: %s", ErrProvider, respBody)
+	}
+
+	var secretValue struct {
+		SecretString string `json:"SecretString"`
+	}
+	err = json.Unmarshal(respBody, &secretValue)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+
+	if !hasField {
+		return secretValue.SecretString, nil
+	}
+
+	var fields map[string]any
+	err = json.Unmarshal([]byte(secretValue.SecretString), &fields)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+	}
+
+	return fmt.Sprint(value), nil
+}
+
+// sign adds the AWS signature version 4 headers to the request.
+func (a *AWSSecretsManager) sign(req *http.Request, host string, body []byte, now time.Time) {
+	amzDate := now.Format(awsDateTimeFormat)
+	date := now.Format(awsDateFormat)
+
+	req.Header.Set("Host", host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.sessionToken)
+	}
+
+	signedHeaders := "content-type;host;x-amz-date;x-amz-target"
+	canonicalHeaders := "content-type:" + awsContentType + "\n" +
+		"host:" + host + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"x-amz-target:" + awsTarget + "\n"
+	if a.sessionToken != "" {
+		signedHeaders = "content-type;host;x-amz-date;x-amz-security-token;x-amz-target"
+		canonicalHeaders = "content-type:" + awsContentType + "\n" +
+			"host:" + host + "\n" +
+			"x-amz-date:" + amzDate + "\n" +
+			"x-amz-security-token:" + a.sessionToken + "\n" +
+			"x-amz-target:" + awsTarget + "\n"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		"/",
+		"",
+		canonicalHeaders,
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + a.region + "/" + awsService + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		awsSigningAlgo,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+a.secretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, a.region)
+	signingKey = hmacSHA256(signingKey, awsService)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigningAlgo, a.accessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
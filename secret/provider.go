@@ -0,0 +1,84 @@
+// Package secret loads API keys and endpoints from pluggable providers (environment,
+// files, Vault, AWS Secrets Manager or a user callback) and keeps them fresh.
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	ErrProvider       = errors.New("secret provider error")
+)
+
+// Provider returns the current value of the secret identified by key.
+type Provider interface {
+	Get(ctx context.Context, key string) (string, error)
+}
+
+// Func adapts a function to the Provider interface.
+type Func func(ctx context.Context, key string) (string, error)
+
+func (f Func) Get(ctx context.Context, key string) (string, error) {
+	return f(ctx, key)
+}
+
+// Env reads secrets from environment variables.
+type Env struct{}
+
+func NewEnv() *Env {
+	return &Env{}
+}
+
+func (e *Env) Get(_ context.Context, key string) (string, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+	}
+
+	return value, nil
+}
+
+// File reads each secret from a file named after its key inside a directory, as done by
+// Docker and Kubernetes secret mounts.
+type File struct {
+	dir string
+}
+
+func NewFile(dir string) *File {
+	return &File{
+		dir: dir,
+	}
+}
+
+func (f *File) Get(_ context.Context, key string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(f.dir, filepath.Base(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+	} else if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Chain returns the value from the first provider that knows the secret.
+type Chain []Provider
+
+func (c Chain) Get(ctx context.Context, key string) (string, error) {
+	for _, provider := range c {
+		value, err := provider.Get(ctx, key)
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		}
+
+		return value, err
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+}
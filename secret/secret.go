@@ -0,0 +1,63 @@
+package secret
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Secret caches the value of a secret read from a provider. The value is fetched
+// again when it expires or when Refresh is called (e.g. after an authentication failure).
+type Secret struct {
+	provider  Provider
+	key       string
+	ttl       time.Duration
+	mu        sync.Mutex
+	value     string
+	fetchedAt time.Time
+}
+
+func New(provider Provider, key string) *Secret {
+	return &Secret{
+		provider: provider,
+		key:      key,
+	}
+}
+
+// WithTTL sets how long the cached value is valid. Zero means until the next Refresh.
+func (s *Secret) WithTTL(ttl time.Duration) *Secret {
+	s.ttl = ttl
+	return s
+}
+
+// Value returns the cached value, fetching it from the provider if required.
+func (s *Secret) Value(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.fetchedAt.IsZero() && (s.ttl == 0 || time.Since(s.fetchedAt) < s.ttl) {
+		return s.value, nil
+	}
+
+	return s.fetch(ctx)
+}
+
+// Refresh fetches the value from the provider, discarding the cached one.
+func (s *Secret) Refresh(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fetch(ctx)
+}
+
+func (s *Secret) fetch(ctx context.Context) (string, error) {
+	value, err := s.provider.Get(ctx, s.key)
+	if err != nil {
+		return "", err
+	}
+
+	s.value = value
+	s.fetchedAt = time.Now()
+
+	return value, nil
+}
@@ -0,0 +1,66 @@
+package secret
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey string
+
+const (
+	contextKeyTenant contextKey = "secretTenant"
+
+	// UsageKeyTenant is the key of the tenant in the token usage passed to the LLM usage
+	// callbacks.
+	UsageKeyTenant = "Tenant"
+)
+
+// WithTenant returns a context for the requests of the tenant, so that the providers
+// configured with an API key function use the tenant credentials and attribute its usage.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, contextKeyTenant, tenant)
+}
+
+// Tenant returns the tenant set with WithTenant, or an empty string.
+func Tenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(contextKeyTenant).(string)
+	return tenant
+}
+
+// KeyFunc returns the API key of a request from its context, e.g. the key of the tenant.
+// An empty key keeps the default one.
+type KeyFunc func(ctx context.Context) string
+
+// KeyFuncTransport is an http.RoundTripper injecting the key returned by a KeyFunc in
+// each request.
+type KeyFuncTransport struct {
+	base    http.RoundTripper
+	keyFunc KeyFunc
+	apply   ApplyFn
+}
+
+func NewKeyFuncTransport(keyFunc KeyFunc, apply ApplyFn) *KeyFuncTransport {
+	return &KeyFuncTransport{
+		base:    http.DefaultTransport,
+		keyFunc: keyFunc,
+		apply:   apply,
+	}
+}
+
+// WithBase sets the underlying transport, http.DefaultTransport by default.
+func (t *KeyFuncTransport) WithBase(base http.RoundTripper) *KeyFuncTransport {
+	t.base = base
+	return t
+}
+
+func (t *KeyFuncTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := t.keyFunc(req.Context())
+	if key == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	clone := req.Clone(req.Context())
+	t.apply(clone, key)
+
+	return t.base.RoundTrip(clone)
+}
@@ -0,0 +1,92 @@
+package secret
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ApplyFn sets the secret value on the outgoing request.
+type ApplyFn func(req *http.Request, value string)
+
+// BearerAuth sets the secret as bearer token in the Authorization header.
+func BearerAuth(req *http.Request, value string) {
+	req.Header.Set("Authorization", "Bearer "+value)
+}
+
+// HeaderAuth sets the secret in the named header (e.g. x-api-key).
+func HeaderAuth(name string) ApplyFn {
+	return func(req *http.Request, value string) {
+		req.Header.Set(name, value)
+	}
+}
+
+// Transport is an http.RoundTripper that injects the secret in each request. When the
+// server answers 401 or 403 the secret is fetched again and the request retried once,
+// so rotated credentials are picked up without restarting the application.
+type Transport struct {
+	base   http.RoundTripper
+	secret *Secret
+	apply  ApplyFn
+}
+
+func NewTransport(secret *Secret, apply ApplyFn) *Transport {
+	return &Transport{
+		base:   http.DefaultTransport,
+		secret: secret,
+		apply:  apply,
+	}
+}
+
+// WithBase sets the underlying transport, http.DefaultTransport by default.
+func (t *Transport) WithBase(base http.RoundTripper) *Transport {
+	t.base = base
+	return t
+}
+
+// Client returns an http client using the transport, to be passed to WithHTTPClient.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	value, err := t.secret.Value(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(t.withSecret(req, value))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return resp, nil
+	}
+
+	if req.Body != nil && req.GetBody == nil {
+		// the body has been consumed and can't be sent again
+		return resp, nil
+	}
+
+	refreshed, err := t.secret.Refresh(req.Context())
+	if err != nil || refreshed == value {
+		return resp, nil //nolint:nilerr
+	}
+	resp.Body.Close()
+
+	retry := t.withSecret(req, refreshed)
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrProvider, err)
+		}
+	}
+
+	return t.base.RoundTrip(retry)
+}
+
+func (t *Transport) withSecret(req *http.Request, value string) *http.Request {
+	clone := req.Clone(req.Context())
+	t.apply(clone, value)
+	return clone
+}
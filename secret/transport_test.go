@@ -0,0 +1,79 @@
+package secret
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransportRefreshOnAuthFailure(t *testing.T) {
+	currentKey := "old"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer new" || string(body) != "payload" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	s := New(Func(func(_ context.Context, _ string) (string, error) {
+		return currentKey, nil
+	}), "API_KEY")
+
+	client := NewTransport(s, BearerAuth).Client()
+
+	// rotate the key after the first fetch
+	_, err := s.Value(context.Background())
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+	currentKey = "new"
+
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestKeyFuncTransport(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("x-api-key"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tenantKeys := map[string]string{"acme": "acme-key"}
+	transport := NewKeyFuncTransport(func(ctx context.Context) string {
+		return tenantKeys[Tenant(ctx)]
+	}, HeaderAuth("x-api-key"))
+	client := &http.Client{Transport: transport}
+
+	for _, ctx := range []context.Context{WithTenant(context.Background(), "acme"), context.Background()} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("x-api-key", "default-key")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(keys) != 2 || keys[0] != "acme-key" || keys[1] != "default-key" {
+		t.Errorf("keys = %v, want [acme-key default-key]", keys)
+	}
+}
@@ -0,0 +1,140 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/henomis/restclientgo"
+)
+
+const (
+	defaultVaultMount  = "secret"
+	defaultSecretField = "value"
+)
+
+// Vault reads secrets from a HashiCorp Vault KV version 2 engine. Keys are in the form
+// "path#field", the field defaults to "value".
+type Vault struct {
+	restClient *restclientgo.RestClient
+	mount      string
+}
+
+// NewVault returns a Vault provider configured by the VAULT_ADDR and VAULT_TOKEN
+// environment variables.
+func NewVault() *Vault {
+	return &Vault{
+		restClient: restclientgo.New(strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/") + "/v1").
+			WithRequestModifier(vaultTokenModifier(os.Getenv("VAULT_TOKEN"))),
+		mount: defaultVaultMount,
+	}
+}
+
+func (v *Vault) WithAddress(address string) *Vault {
+	v.restClient.SetEndpoint(strings.TrimSuffix(address, "/") + "/v1")
+	return v
+}
+
+func (v *Vault) WithToken(token string) *Vault {
+	v.restClient.SetRequestModifier(vaultTokenModifier(token))
+	return v
+}
+
+func (v *Vault) WithMount(mount string) *Vault {
+	v.mount = mount
+	return v
+}
+
+// WithHTTPClient sets the http client to use for the provider
+func (v *Vault) WithHTTPClient(httpClient *http.Client) *Vault {
+	v.restClient.SetHTTPClient(httpClient)
+	return v
+}
+
+func (v *Vault) Get(ctx context.Context, key string) (string, error) {
+	path, field := splitKey(key)
+
+	resp := &vaultResponse{}
+	err := v.restClient.Get(ctx, &vaultRequest{mount: v.mount, path: path}, resp)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+
+	if resp.HTTPStatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+	} else if resp.HTTPStatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("%w: %s", ErrProvider, resp.RawBody)
+	}
+
+	value, ok := resp.Data.Data[field]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+	}
+
+	return fmt.Sprint(value), nil
+}
+
+func vaultTokenModifier(token string) func(*http.Request) *http.Request {
+	return func(req *http.Request) *http.Request {
+		req.Header.Set("X-Vault-Token", token)
+		return req
+	}
+}
+
+func splitKey(key string) (string, string) {
+	path, field, found := strings.Cut(key, "#")
+	if !found {
+		field = defaultSecretField
+	}
+
+	return path, field
+}
+
+type vaultRequest struct {
+	mount string
+	path  string
+}
+
+func (r *vaultRequest) Path() (string, error) {
+	return "/" + r.mount + "/data/" + strings.TrimPrefix(r.path, "/"), nil
+}
+
+func (r *vaultRequest) Encode() (io.Reader, error) {
+	return nil, nil
+}
+
+func (r *vaultRequest) ContentType() string {
+	return ""
+}
+
+type vaultResponse struct {
+	HTTPStatusCode int    `json:"-"`
+	RawBody        []byte `json:"-"`
+	Data           struct {
+		Data map[string]any `json:"data"`
+	} `json:"data"`
+}
+
+func (r *vaultResponse) Decode(body io.Reader) error {
+	return json.NewDecoder(body).Decode(r)
+}
+
+func (r *vaultResponse) SetBody(body io.Reader) error {
+	r.RawBody, _ = io.ReadAll(body)
+	return nil
+}
+
+func (r *vaultResponse) AcceptContentType() string {
+	return "application/json"
+}
+
+func (r *vaultResponse) SetStatusCode(code int) error {
+	r.HTTPStatusCode = code
+	return nil
+}
+
+func (r *vaultResponse) SetHeaders(_ restclientgo.Headers) error { return nil }
@@ -3,6 +3,8 @@ package textsplitter
 import (
 	"strings"
 
+	"github.com/google/uuid"
+
 	"github.com/henomis/lingoose/document"
 	"github.com/henomis/lingoose/types"
 )
@@ -14,7 +16,8 @@ var (
 
 type RecursiveCharacterTextSplitter struct {
 	TextSplitter
-	separators []string
+	separators  []string
+	documentIDs bool
 }
 
 func NewRecursiveCharacterTextSplitter(chunkSize int, chunkOverlap int) *RecursiveCharacterTextSplitter {
@@ -40,16 +43,32 @@ func (r *RecursiveCharacterTextSplitter) WithLengthFunction(
 	return r
 }
 
+// WithDocumentIDs tags the chunks of each document with the same random ID in the
+// MetadataChunkDocument metadata, so that embedders supporting late chunking can embed
+// them together.
+func (r *RecursiveCharacterTextSplitter) WithDocumentIDs() *RecursiveCharacterTextSplitter {
+	r.documentIDs = true
+	return r
+}
+
 // AI-translated from https://github.com/hwchase17/langchain/blob/master/langchain/text_splitter.py
 func (r *RecursiveCharacterTextSplitter) SplitDocuments(documents []document.Document) []document.Document {
 	docs := make([]document.Document, 0)
 
 	for i, doc := range documents {
+		documentID := ""
+		if r.documentIDs {
+			documentID = uuid.New().String()
+		}
+
 		for _, chunk := range r.SplitText(doc.Content) {
 			metadata := make(types.Meta)
 			for k, v := range documents[i].Metadata {
 				metadata[k] = v
 			}
+			if documentID != "" {
+				metadata[MetadataChunkDocument] = documentID
+			}
 
 			docs = append(docs,
 				document.Document{
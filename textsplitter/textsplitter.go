@@ -5,6 +5,10 @@ import (
 	"strings"
 )
 
+// MetadataChunkDocument is the chunk metadata key holding the ID of the document the
+// chunk comes from, when enabled with WithDocumentIDs.
+const MetadataChunkDocument = "chunk_document"
+
 type LenFunction func(string) int
 
 type TextSplitter struct {
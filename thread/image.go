@@ -0,0 +1,154 @@
+package thread
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	dataURLPrefix   = "data:"
+	base64Marker    = ";base64,"
+	defaultMIMEType = "application/octet-stream"
+)
+
+var (
+	ErrImage = errors.New("invalid image content")
+
+	imageFetcher ImageFetcher
+)
+
+// ImageFetcher downloads the remote images.
+type ImageFetcher func(ctx context.Context, url string) ([]byte, error)
+
+// SetImageFetcher sets the function downloading the remote images, net/http by default.
+// TinyGo builds have no default: set a fetcher, e.g. one calling the browser fetch API.
+func SetImageFetcher(fetcher ImageFetcher) {
+	imageFetcher = fetcher
+}
+
+// NewImageContent returns an image from its data, e.g. an image generated by the LLM. The
+// image is stored as a base64 data URL, so it's sent back to the LLM as it is.
+func NewImageContent(data []byte) *Content {
+	return NewImageContentFromURL(imageDataURL(data, detectMIMEType(data)))
+}
+
+// NewImageContentFromFile reads an image file and returns it as a base64 data URL.
+// Images are never read from the file system implicitly: use this to send a local image.
+func NewImageContentFromFile(path string) (*Content, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImage, err)
+	}
+
+	return NewImageContent(data), nil
+}
+
+// ImageData returns the data and the MIME type of an image content. Remote images are
+// downloaded, data URLs are decoded. Any other source is rejected.
+func (c *Content) ImageData(ctx context.Context) ([]byte, string, error) {
+	image, ok := c.Data.(string)
+	if c.Type != ContentTypeImage || !ok {
+		return nil, "", fmt.Errorf("%w: %s content", ErrImage, c.Type)
+	}
+
+	var data []byte
+	var err error
+	switch {
+	case strings.HasPrefix(image, dataURLPrefix):
+		return parseImageDataURL(image)
+	case strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://"):
+		if imageFetcher == nil {
+			return nil, "", fmt.Errorf("%w: no image fetcher to download %s", ErrImage, image)
+		}
+		data, err = imageFetcher(ctx, image)
+	default:
+		return nil, "", fmt.Errorf("%w: unsupported image source, use a URL or NewImageContentFromFile", ErrImage)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrImage, err)
+	}
+
+	return data, detectMIMEType(data), nil
+}
+
+// ImageDataURL returns an image content as a base64 data URL, e.g. to embed it in HTML.
+func (c *Content) ImageDataURL(ctx context.Context) (string, error) {
+	if image, ok := c.Data.(string); ok && c.Type == ContentTypeImage && strings.HasPrefix(image, dataURLPrefix) {
+		return image, nil
+	}
+
+	data, mimeType, err := c.ImageData(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return imageDataURL(data, mimeType), nil
+}
+
+// SaveImage writes the data of an image content to the file at path.
+func (c *Content) SaveImage(ctx context.Context, path string) error {
+	data, _, err := c.ImageData(ctx)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// Images returns the image contents of the message.
+func (m *Message) Images() []*Content {
+	var images []*Content
+	for _, content := range m.Contents {
+		if content.Type == ContentTypeImage {
+			images = append(images, content)
+		}
+	}
+
+	return images
+}
+
+func imageDataURL(data []byte, mimeType string) string {
+	return dataURLPrefix + mimeType + base64Marker + base64.StdEncoding.EncodeToString(data)
+}
+
+func parseImageDataURL(dataURL string) ([]byte, string, error) {
+	header, encoded, ok := strings.Cut(strings.TrimPrefix(dataURL, dataURLPrefix), ",")
+	if !ok || !strings.HasSuffix(header, strings.TrimSuffix(base64Marker, ",")) {
+		return nil, "", fmt.Errorf("%w: malformed data URL", ErrImage)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrImage, err)
+	}
+
+	mimeType, _, _ := strings.Cut(header, ";")
+	if mimeType == "" {
+		mimeType = detectMIMEType(data)
+	}
+
+	return data, mimeType, nil
+}
+
+// detectMIMEType detects the common image formats from their signature.
+func detectMIMEType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return "image/jpeg"
+	case bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a")):
+		return "image/gif"
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return "image/webp"
+	case bytes.HasPrefix(data, []byte("BM")):
+		return "image/bmp"
+	default:
+		return defaultMIMEType
+	}
+}
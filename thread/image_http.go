@@ -0,0 +1,33 @@
+//go:build !tinygo
+
+package thread
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+func init() {
+	imageFetcher = downloadImage
+}
+
+func downloadImage(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d downloading %s", resp.StatusCode, url)
+	}
+
+	return io.ReadAll(resp.Body)
+}
@@ -2,6 +2,9 @@ package thread
 
 import (
 	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
 
@@ -10,6 +13,13 @@ import (
 
 type Thread struct {
 	Messages []*Message
+	Branches []*Branch
+}
+
+// Branch is an alternative sequence of messages that diverges from the thread at Index.
+type Branch struct {
+	Index    int
+	Messages []*Message
 }
 
 type ContentType string
@@ -19,6 +29,29 @@ const (
 	ContentTypeImage        ContentType = "image"
 	ContentTypeToolCall     ContentType = "tool_call"
 	ContentTypeToolResponse ContentType = "tool_response"
+	ContentTypeThinking     ContentType = "thinking"
+	ContentTypeAudio        ContentType = "audio"
+)
+
+const (
+	// MetadataCacheable marks the messages ending a prompt prefix that the providers
+	// supporting explicit prompt caching, such as Anthropic, should cache, e.g. a long
+	// system prompt or the RAG context.
+	MetadataCacheable = "cacheable"
+)
+
+var (
+	ErrAudioFormat = errors.New("unsupported audio format")
+)
+
+type AudioFormat string
+
+const (
+	AudioFormatWAV   AudioFormat = "wav"
+	AudioFormatMP3   AudioFormat = "mp3"
+	AudioFormatFLAC  AudioFormat = "flac"
+	AudioFormatOpus  AudioFormat = "opus"
+	AudioFormatPCM16 AudioFormat = "pcm16"
 )
 
 type Content struct {
@@ -38,12 +71,28 @@ const (
 type Message struct {
 	Role     Role
 	Contents []*Content
+	Metadata types.Meta
 }
 
+// ToolResponseData is the result of a tool call. Result is the text sent to the LLM,
+// Value is the typed value returned by the tool (e.g. a struct or an image *Content),
+// kept for the downstream steps so that they don't have to parse Result. Err is the
+// error of a failed call, e.g. a *recovery.PanicError, described to the LLM in Result.
 type ToolResponseData struct {
 	ID     string
 	Name   string
 	Result string
+	Value  any
+	Err    error
+}
+
+// AudioData is an audio clip. Audio generated by the LLM has an ID and the transcript
+// of the speech.
+type AudioData struct {
+	ID         string
+	Format     AudioFormat
+	Data       []byte
+	Transcript string
 }
 
 type ToolCallData struct {
@@ -59,6 +108,15 @@ func NewTextContent(text string) *Content {
 	}
 }
 
+// NewThinkingContent returns the reasoning produced by a model before its answer.
+// Thinking contents are kept in the thread but not sent back to the LLM.
+func NewThinkingContent(text string) *Content {
+	return &Content{
+		Type: ContentTypeThinking,
+		Data: text,
+	}
+}
+
 func NewImageContentFromURL(url string) *Content {
 	return &Content{
 		Type: ContentTypeImage,
@@ -66,6 +124,36 @@ func NewImageContentFromURL(url string) *Content {
 	}
 }
 
+func NewAudioContent(data []byte, format AudioFormat) *Content {
+	return &Content{
+		Type: ContentTypeAudio,
+		Data: AudioData{
+			Format: format,
+			Data:   data,
+		},
+	}
+}
+
+// NewAudioContentFromFile reads a WAV or MP3 file, detecting the format from the extension.
+func NewAudioContentFromFile(path string) (*Content, error) {
+	var format AudioFormat
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav":
+		format = AudioFormatWAV
+	case ".mp3":
+		format = AudioFormatMP3
+	default:
+		return nil, ErrAudioFormat
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAudioContent(data, format), nil
+}
+
 func NewToolResponseContent(toolResponseData ToolResponseData) *Content {
 	return &Content{
 		Type: ContentTypeToolResponse,
@@ -85,6 +173,22 @@ func (m *Message) AddContent(content *Content) *Message {
 	return m
 }
 
+// AddMetadata sets a metadata value on the message, e.g. details about the generation
+// reported by the LLM provider.
+func (m *Message) AddMetadata(key string, value any) *Message {
+	if m.Metadata == nil {
+		m.Metadata = make(types.Meta)
+	}
+	m.Metadata[key] = value
+	return m
+}
+
+// Cacheable reports whether the message is marked with MetadataCacheable.
+func (m *Message) Cacheable() bool {
+	cacheable, _ := m.Metadata[MetadataCacheable].(bool)
+	return cacheable
+}
+
 func NewUserMessage() *Message {
 	return &Message{
 		Role: RoleUser,
@@ -136,9 +240,23 @@ func (t *Thread) String() string {
 			switch content.Type {
 			case ContentTypeText:
 				str += "\tText: " + content.Data.(string) + "\n"
+			case ContentTypeThinking:
+				str += "\tThinking: " + content.Data.(string) + "\n"
 			case ContentTypeImage:
 				if contentAsString, ok := content.Data.(string); ok {
-					str += "\tImage URL: " + contentAsString + "\n"
+					if strings.HasPrefix(contentAsString, dataURLPrefix) {
+						header, _, _ := strings.Cut(contentAsString, ",")
+						str += "\tImage Data: " + strings.TrimPrefix(header, dataURLPrefix) + "\n"
+					} else {
+						str += "\tImage URL: " + contentAsString + "\n"
+					}
+				}
+			case ContentTypeAudio:
+				if audioData, ok := content.Data.(AudioData); ok {
+					str += "\tAudio Format: " + string(audioData.Format) + "\n"
+					if audioData.Transcript != "" {
+						str += "\tAudio Transcript: " + audioData.Transcript + "\n"
+					}
 				}
 			case ContentTypeToolCall:
 				for _, toolCallData := range content.Data.([]ToolCallData) {
@@ -189,6 +307,58 @@ func (t *Thread) UserQuery() []string {
 
 func (t *Thread) ClearMessages() *Thread {
 	t.Messages = make([]*Message, 0)
+	t.Branches = nil
+	return t
+}
+
+// Fork moves the messages starting at index into a new branch and truncates the thread,
+// so that an alternative sequence of messages can be generated.
+func (t *Thread) Fork(index int) *Branch {
+	if index < 0 || index > len(t.Messages) {
+		return nil
+	}
+
+	branch := &Branch{
+		Index:    index,
+		Messages: append([]*Message{}, t.Messages[index:]...),
+	}
+
+	t.Branches = append(t.Branches, branch)
+	t.Messages = t.Messages[:index]
+
+	return branch
+}
+
+// BranchesAt returns the alternative branches diverging from the thread at index.
+func (t *Thread) BranchesAt(index int) []*Branch {
+	var branches []*Branch
+	for _, branch := range t.Branches {
+		if branch.Index == index {
+			branches = append(branches, branch)
+		}
+	}
+
+	return branches
+}
+
+// SwitchBranch makes the branch the current sequence of messages. The replaced messages
+// are kept as a new branch.
+func (t *Thread) SwitchBranch(branch *Branch) *Thread {
+	for i, b := range t.Branches {
+		if b != branch || branch.Index > len(t.Messages) {
+			continue
+		}
+
+		current := &Branch{
+			Index:    branch.Index,
+			Messages: append([]*Message{}, t.Messages[branch.Index:]...),
+		}
+
+		t.Messages = append(t.Messages[:branch.Index], branch.Messages...)
+		t.Branches[i] = current
+		break
+	}
+
 	return t
 }
 
@@ -237,6 +407,13 @@ func (c *Content) AsToolResponseData() *ToolResponseData {
 	return nil
 }
 
+func (c *Content) AsAudioData() *AudioData {
+	if contentAsAudioData, ok := c.Data.(AudioData); ok {
+		return &contentAsAudioData
+	}
+	return nil
+}
+
 func (c *Content) AsToolCallData() []ToolCallData {
 	if contentAsToolCallData, ok := c.Data.([]ToolCallData); ok {
 		return contentAsToolCallData
@@ -1,6 +1,11 @@
 package thread
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -83,3 +88,101 @@ func TestContent_Format(t *testing.T) {
 		})
 	}
 }
+
+func TestThread_ForkAndSwitchBranch(t *testing.T) {
+	question := NewUserMessage().AddContent(NewTextContent("question"))
+	firstAnswer := NewAssistantMessage().AddContent(NewTextContent("first answer"))
+	secondAnswer := NewAssistantMessage().AddContent(NewTextContent("second answer"))
+
+	th := New().AddMessages(question, firstAnswer)
+
+	branch := th.Fork(1)
+	if branch == nil || th.CountMessages() != 1 {
+		t.Fatalf("Fork() = %v, messages = %d", branch, th.CountMessages())
+	}
+
+	th.AddMessage(secondAnswer)
+
+	branches := th.BranchesAt(1)
+	if len(branches) != 1 || branches[0].Messages[0] != firstAnswer {
+		t.Fatalf("BranchesAt() = %v", branches)
+	}
+
+	th.SwitchBranch(branches[0])
+	if th.LastMessage() != firstAnswer {
+		t.Errorf("SwitchBranch() last message = %v, want %v", th.LastMessage(), firstAnswer)
+	}
+
+	branches = th.BranchesAt(1)
+	if len(branches) != 1 || branches[0].Messages[0] != secondAnswer {
+		t.Errorf("SwitchBranch() branches = %v", branches)
+	}
+}
+
+func TestNewAudioContentFromFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "voice.MP3")
+	err := os.WriteFile(path, []byte("ID3"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := NewAudioContentFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &AudioData{Format: AudioFormatMP3, Data: []byte("ID3")}
+	if got := content.AsAudioData(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	_, err = NewAudioContentFromFile(filepath.Join(dir, "voice.ogg"))
+	if !errors.Is(err, ErrAudioFormat) {
+		t.Fatalf("expected ErrAudioFormat, got %v", err)
+	}
+}
+
+func TestNewImageContent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	content := NewImageContent(png)
+
+	data, mimeType, err := content.ImageData(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, png) || mimeType != "image/png" {
+		t.Fatalf("got %q %s", data, mimeType)
+	}
+
+	path := filepath.Join(t.TempDir(), "image.png")
+	if err = content.SaveImage(context.Background(), path); err != nil {
+		t.Fatal(err)
+	}
+
+	fromFile, err := NewImageContentFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataURL, err := fromFile.ImageDataURL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataURL != content.Data {
+		t.Fatalf("got %s, want %s", dataURL, content.Data)
+	}
+
+	// local paths and raw base64 are never read implicitly
+	for _, image := range []string{path, "iVBORw0KGgo="} {
+		_, _, err = NewImageContentFromURL(image).ImageData(context.Background())
+		if !errors.Is(err, ErrImage) {
+			t.Fatalf("expected ErrImage for %s, got %v", image, err)
+		}
+	}
+
+	_, _, err = NewTextContent("text").ImageData(context.Background())
+	if !errors.Is(err, ErrImage) {
+		t.Fatalf("expected ErrImage, got %v", err)
+	}
+}
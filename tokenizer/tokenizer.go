@@ -0,0 +1,294 @@
+// Package tokenizer counts the tokens of strings and threads for a model, to know in
+// advance whether a thread fits the model context and what it will cost.
+//
+// The o200k_base, cl100k_base and p50k_base encodings are counted exactly with the BPE
+// encoder of github.com/pkoukk/tiktoken-go. Its ranks are downloaded on first use and
+// cached in the TIKTOKEN_CACHE_DIR directory; offline applications can set an embedded
+// loader with tiktoken.SetBpeLoader, e.g. github.com/pkoukk/tiktoken-go-loader, and call
+// Load at startup to fail early. When the ranks can't be loaded, or for other encodings,
+// the tokens are estimated splitting the text with the tiktoken pre-tokenizer rules,
+// which is usually within 10% of the exact count for English text and code.
+//
+// Register replaces the encoder of an encoding, e.g. with the tokenizer of another
+// model family.
+package tokenizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkoukk/tiktoken-go"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+const (
+	EncodingO200K  Encoding = "o200k_base"
+	EncodingCL100K Encoding = "cl100k_base"
+	EncodingP50K   Encoding = "p50k_base"
+
+	// tokensPerMessage and tokensPerReply are the tokens added by the chat format to each
+	// message and to prime the reply.
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+	// tokensPerImage is the cost of an image in low detail.
+	tokensPerImage = 85
+	// lettersPerToken is the average length of the tokens of a long word.
+	lettersPerToken = 6
+)
+
+var (
+	// preTokenizer approximates the tiktoken split pattern, which uses lookaheads not
+	// supported by Go regexps.
+	preTokenizer = regexp.MustCompile(
+		`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`,
+	)
+
+	encodingPrefixes = []struct {
+		prefix   string
+		encoding Encoding
+	}{
+		{"gpt-4o", EncodingO200K},
+		{"gpt-4.1", EncodingO200K},
+		{"gpt-4.5", EncodingO200K},
+		{"gpt-5", EncodingO200K},
+		{"chatgpt-4o", EncodingO200K},
+		{"o1", EncodingO200K},
+		{"o3", EncodingO200K},
+		{"o4", EncodingO200K},
+		{"gpt-4", EncodingCL100K},
+		{"gpt-3.5", EncodingCL100K},
+		{"text-embedding-", EncodingCL100K},
+		{"text-davinci-", EncodingP50K},
+		{"code-davinci-", EncodingP50K},
+	}
+
+	encodersMu sync.RWMutex
+	encoders   = make(map[Encoding]Encoder)
+
+	bpeEncoders = map[Encoding]*bpeEncoder{
+		EncodingO200K:  {encoding: EncodingO200K},
+		EncodingCL100K: {encoding: EncodingCL100K},
+		EncodingP50K:   {encoding: EncodingP50K},
+	}
+)
+
+type Encoding string
+
+// Encoder counts the tokens of a text.
+type Encoder interface {
+	Count(text string) int
+}
+
+type EncoderFunc func(text string) int
+
+func (f EncoderFunc) Count(text string) int {
+	return f(text)
+}
+
+// bpeEncoder counts the tokens with the tiktoken BPE ranks of the encoding, loaded once.
+type bpeEncoder struct {
+	encoding Encoding
+	once     sync.Once
+	tiktoken *tiktoken.Tiktoken
+	err      error
+}
+
+func (e *bpeEncoder) load() error {
+	e.once.Do(func() {
+		e.tiktoken, e.err = tiktoken.GetEncoding(string(e.encoding))
+	})
+
+	return e.err
+}
+
+func (e *bpeEncoder) Count(text string) int {
+	if e.load() != nil {
+		return estimate(text)
+	}
+
+	return len(e.tiktoken.EncodeOrdinary(text))
+}
+
+// Load loads the BPE ranks of the encodings, all the built-in ones if none is given, so
+// that a missing network or cache fails at startup instead of falling back silently to
+// the estimate.
+func Load(encodings ...Encoding) error {
+	if len(encodings) == 0 {
+		encodings = []Encoding{EncodingO200K, EncodingCL100K, EncodingP50K}
+	}
+
+	for _, encoding := range encodings {
+		encoder, ok := bpeEncoders[encoding]
+		if !ok {
+			return fmt.Errorf("unknown encoding %s", encoding)
+		}
+
+		if err := encoder.load(); err != nil {
+			return fmt.Errorf("loading %s: %w", encoding, err)
+		}
+	}
+
+	return nil
+}
+
+// Register sets the encoder used to count the tokens of the models using the encoding,
+// in place of the built-in BPE encoder.
+func Register(encoding Encoding, encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	encoders[encoding] = encoder
+}
+
+// EncodingForModel returns the encoding of the model, cl100k_base if unknown.
+func EncodingForModel(model string) Encoding {
+	model = strings.ToLower(model)
+	for _, p := range encodingPrefixes {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.encoding
+		}
+	}
+
+	return EncodingCL100K
+}
+
+// CountString returns the tokens of the text for the model.
+func CountString(model string, text string) int {
+	if text == "" {
+		return 0
+	}
+
+	encoding := EncodingForModel(model)
+
+	encodersMu.RLock()
+	encoder, ok := encoders[encoding]
+	encodersMu.RUnlock()
+
+	if ok {
+		return encoder.Count(text)
+	}
+
+	if bpe, isBuiltin := bpeEncoders[encoding]; isBuiltin {
+		return bpe.Count(text)
+	}
+
+	return estimate(text)
+}
+
+// Counter returns a function counting the tokens of a text for the model, e.g. to be
+// used as a budget.TokenCounter.
+func Counter(model string) func(string) int {
+	return func(text string) int {
+		return CountString(model, text)
+	}
+}
+
+// CountMessageTokens returns the tokens of the message for the model, including the
+// chat format overhead.
+func CountMessageTokens(model string, message *thread.Message) int {
+	tokens := tokensPerMessage + CountString(model, string(message.Role))
+	for _, content := range message.Contents {
+		switch content.Type {
+		case thread.ContentTypeText, thread.ContentTypeThinking:
+			tokens += CountString(model, content.AsString())
+		case thread.ContentTypeImage:
+			tokens += tokensPerImage
+		case thread.ContentTypeToolCall:
+			for _, toolCall := range content.AsToolCallData() {
+				tokens += CountString(model, toolCall.Name) + CountString(model, toolCall.Arguments)
+			}
+		case thread.ContentTypeToolResponse:
+			if toolResponse := content.AsToolResponseData(); toolResponse != nil {
+				tokens += CountString(model, toolResponse.Result)
+			}
+		case thread.ContentTypeAudio:
+			if audio := content.AsAudioData(); audio != nil {
+				tokens += CountString(model, audio.Transcript)
+			}
+		}
+	}
+
+	return tokens
+}
+
+// CountThreadTokens returns the prompt tokens of the thread for the model, including the
+// tokens priming the reply.
+func CountThreadTokens(model string, t *thread.Thread) int {
+	if t == nil {
+		return 0
+	}
+
+	tokens := tokensPerReply
+	for _, message := range t.Messages {
+		tokens += CountMessageTokens(model, message)
+	}
+
+	return tokens
+}
+
+// TruncateThread returns a thread fitting maxTokens for the model, as counted by
+// CountThreadTokens: the system messages are kept and the oldest other messages are
+// dropped, along with the tool results whose tool call has been dropped. The last message
+// is always kept, so the result may still exceed maxTokens. The thread is not modified.
+func TruncateThread(model string, t *thread.Thread, maxTokens int) *thread.Thread {
+	if t == nil {
+		return nil
+	}
+
+	tokens := CountThreadTokens(model, t)
+	if tokens <= maxTokens {
+		return thread.New().AddMessages(t.Messages...)
+	}
+
+	dropped := make([]bool, len(t.Messages))
+	for i := 0; i < len(t.Messages)-1 && tokens > maxTokens; i++ {
+		if t.Messages[i].Role == thread.RoleSystem {
+			continue
+		}
+
+		dropped[i] = true
+		tokens -= CountMessageTokens(model, t.Messages[i])
+
+		// the tool results can't be sent without their tool call
+		for i+1 < len(t.Messages)-1 && t.Messages[i+1].Role == thread.RoleTool {
+			i++
+			dropped[i] = true
+			tokens -= CountMessageTokens(model, t.Messages[i])
+		}
+	}
+
+	truncated := thread.New()
+	for i, message := range t.Messages {
+		if !dropped[i] {
+			truncated.AddMessage(message)
+		}
+	}
+
+	return truncated
+}
+
+// estimate counts a token per pre-tokenizer piece, splitting long words and counting a
+// token per character of scripts without spaces.
+func estimate(text string) int {
+	tokens := 0
+	for _, piece := range preTokenizer.FindAllString(text, -1) {
+		letters, wide := 0, 0
+		for _, r := range piece {
+			switch {
+			case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+				wide++
+			case unicode.IsLetter(r):
+				letters++
+			}
+		}
+
+		pieceTokens := wide + (letters+lettersPerToken-1)/lettersPerToken
+		tokens += max(pieceTokens, 1)
+	}
+
+	return tokens
+}
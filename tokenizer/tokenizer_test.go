@@ -0,0 +1,118 @@
+package tokenizer
+
+import (
+	"testing"
+
+	"github.com/pkoukk/tiktoken-go"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestCountString(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		// exact cl100k_base counts
+		{"Hello, world!", 4},
+		{"The quick brown fox jumps over the lazy dog.", 10},
+		{"func main() {\n\tfmt.Println(42)\n}", 12},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		got := CountString("gpt-4", tt.text)
+		// the estimate must be close to the exact count
+		if diff := got - tt.want; diff < -2 || diff > 2 {
+			t.Errorf("CountString(%q) = %d, want about %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCountThreadTokens(t *testing.T) {
+	Register("test_base", EncoderFunc(func(text string) int { return len(text) }))
+	encodingPrefixes = append(encodingPrefixes, struct {
+		prefix   string
+		encoding Encoding
+	}{"test-model", "test_base"})
+
+	th := thread.New().AddMessages(
+		thread.NewSystemMessage().AddContent(thread.NewTextContent("abc")),
+		thread.NewUserMessage().AddContent(thread.NewTextContent("de")),
+	)
+
+	// reply 3 + (3 + "system" 6 + 3) + (3 + "user" 4 + 2)
+	if got := CountThreadTokens("test-model", th); got != 24 {
+		t.Errorf("CountThreadTokens() = %d, want 24", got)
+	}
+
+	if got := EncodingForModel("gpt-4o-mini"); got != EncodingO200K {
+		t.Errorf("EncodingForModel() = %s, want %s", got, EncodingO200K)
+	}
+}
+
+// testBpeLoader loads BPE ranks made of the single bytes and the merges of "hello".
+type testBpeLoader struct{}
+
+func (testBpeLoader) LoadTiktokenBpe(string) (map[string]int, error) {
+	ranks := make(map[string]int)
+	for b := 0; b < 256; b++ {
+		ranks[string([]byte{byte(b)})] = b
+	}
+	for i, merge := range []string{"he", "ll", "llo", "hello"} {
+		ranks[merge] = 256 + i
+	}
+
+	return ranks, nil
+}
+
+func TestCountStringBPE(t *testing.T) {
+	tiktoken.SetBpeLoader(testBpeLoader{})
+	builtin := bpeEncoders[EncodingCL100K]
+	bpeEncoders[EncodingCL100K] = &bpeEncoder{encoding: EncodingCL100K}
+	defer func() {
+		tiktoken.SetBpeLoader(tiktoken.NewDefaultBpeLoader())
+		bpeEncoders[EncodingCL100K] = builtin
+	}()
+
+	if err := Load(EncodingCL100K); err != nil {
+		t.Fatal(err)
+	}
+
+	// "hello" is merged into a single token, " world" is split into its 6 bytes
+	if got := CountString("gpt-4", "hello world"); got != 7 {
+		t.Errorf("CountString() = %d, want 7", got)
+	}
+}
+
+func TestTruncateThread(t *testing.T) {
+	Register("test_base", EncoderFunc(func(text string) int { return len(text) }))
+	encodingPrefixes = append(encodingPrefixes, struct {
+		prefix   string
+		encoding Encoding
+	}{"test-model", "test_base"})
+
+	th := thread.New().AddMessages(
+		thread.NewSystemMessage().AddContent(thread.NewTextContent("abc")),
+		thread.NewAssistantMessage().AddContent(thread.NewToolCallContent([]thread.ToolCallData{{Name: "f"}})),
+		thread.NewToolMessage().AddContent(thread.NewToolResponseContent(thread.ToolResponseData{Result: "r"})),
+		thread.NewUserMessage().AddContent(thread.NewTextContent("de")),
+	)
+
+	// reply 3 + system 12 + user 9
+	truncated := TruncateThread("test-model", th, 24)
+	if len(truncated.Messages) != 2 || truncated.Messages[0].Role != thread.RoleSystem ||
+		truncated.Messages[1].Role != thread.RoleUser {
+		t.Fatalf("unexpected truncated thread %v", truncated)
+	}
+	if got := CountThreadTokens("test-model", truncated); got != 24 {
+		t.Errorf("CountThreadTokens() = %d, want 24", got)
+	}
+	if len(th.Messages) != 4 {
+		t.Errorf("the thread has been modified")
+	}
+
+	if got := TruncateThread("test-model", th, 1000); len(got.Messages) != 4 {
+		t.Errorf("a thread fitting the tokens has been truncated")
+	}
+}
@@ -57,6 +57,12 @@ func (t *Tool) WithMaxResults(maxResults uint) *Tool {
 	return t
 }
 
+// WithHTTPClient sets the http client to use for the tool
+func (t *Tool) WithHTTPClient(httpClient *http.Client) *Tool {
+	t.restClient.SetHTTPClient(httpClient)
+	return t
+}
+
 func (t *Tool) Name() string {
 	return "duckduckgo"
 }
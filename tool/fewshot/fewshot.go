@@ -0,0 +1,150 @@
+// Package fewshot helps models with unreliable native function calling by injecting
+// worked tool-call examples into the system prompt and by recovering tool calls
+// emitted as plain JSON text.
+package fewshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+// Format is the tool-call syntax used in the examples and expected from the model.
+type Format string
+
+const (
+	// FormatJSON uses a bare JSON object, as emitted by OpenAI-like models.
+	FormatJSON Format = "json"
+	// FormatHermes wraps the JSON object in <tool_call></tool_call> tags, as expected
+	// by most open models served by Ollama or llama.cpp.
+	FormatHermes Format = "hermes"
+	// FormatXML uses Anthropic-like <tool_use> blocks.
+	FormatXML Format = "xml"
+)
+
+type Tool interface {
+	Description() string
+	Name() string
+	Fn() any
+}
+
+// Example is a worked tool-use example.
+type Example struct {
+	Query     string
+	ToolName  string
+	Arguments any
+	Result    string
+	Answer    string
+}
+
+type FewShot struct {
+	format   Format
+	examples []Example
+	tools    []Tool
+	selector Selector
+	topK     int
+}
+
+func New(format Format) *FewShot {
+	return &FewShot{
+		format: format,
+	}
+}
+
+func (f *FewShot) WithExamples(examples ...Example) *FewShot {
+	f.examples = append(f.examples, examples...)
+	return f
+}
+
+func (f *FewShot) WithTools(tools ...Tool) *FewShot {
+	f.tools = append(f.tools, tools...)
+	return f
+}
+
+// Prompt returns the system prompt section describing the tools and the examples.
+func (f *FewShot) Prompt() (string, error) {
+	return f.prompt(f.examples)
+}
+
+func (f *FewShot) prompt(examples []Example) (string, error) {
+	var b strings.Builder
+
+	b.WriteString("You can use the following tools:\n\n")
+	for _, tool := range f.tools {
+		b.WriteString("- " + tool.Name() + ": " + tool.Description() + "\n")
+	}
+
+	b.WriteString("\nTo use a tool reply ONLY with a tool call in this format:\n")
+	example, err := f.formatCall("tool_name", map[string]any{"argument": "value"})
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(example + "\n")
+
+	for i, e := range examples {
+		call, errFormat := f.formatCall(e.ToolName, e.Arguments)
+		if errFormat != nil {
+			return "", errFormat
+		}
+
+		b.WriteString(fmt.Sprintf("\nExample %d:\n", i+1))
+		b.WriteString("User: " + e.Query + "\n")
+		b.WriteString("Assistant: " + call + "\n")
+		if e.Result != "" {
+			b.WriteString("Tool result: " + e.Result + "\n")
+		}
+		if e.Answer != "" {
+			b.WriteString("Assistant: " + e.Answer + "\n")
+		}
+	}
+
+	return b.String(), nil
+}
+
+// Inject appends the prompt to the first system message of the thread, creating it if needed.
+func (f *FewShot) Inject(t *thread.Thread) error {
+	prompt, err := f.Prompt()
+	if err != nil {
+		return err
+	}
+
+	injectPrompt(t, prompt)
+	return nil
+}
+
+func injectPrompt(t *thread.Thread, prompt string) {
+	for _, message := range t.Messages {
+		if message.Role != thread.RoleSystem {
+			continue
+		}
+
+		for _, content := range message.Contents {
+			if content.Type == thread.ContentTypeText {
+				content.Data = content.AsString() + "\n\n" + prompt
+				return
+			}
+		}
+	}
+
+	systemMessage := thread.NewSystemMessage().AddContent(thread.NewTextContent(prompt))
+	t.Messages = append([]*thread.Message{systemMessage}, t.Messages...)
+}
+
+func (f *FewShot) formatCall(name string, arguments any) (string, error) {
+	argumentsAsJSON, err := json.Marshal(arguments)
+	if err != nil {
+		return "", err
+	}
+
+	switch f.format {
+	case FormatHermes:
+		return fmt.Sprintf("<tool_call>{\"name\": %q, \"arguments\": %s}</tool_call>", name, argumentsAsJSON), nil
+	case FormatXML:
+		return fmt.Sprintf("<tool_use><name>%s</name><input>%s</input></tool_use>", name, argumentsAsJSON), nil
+	case FormatJSON:
+	}
+
+	return fmt.Sprintf("{\"name\": %q, \"arguments\": %s}", name, argumentsAsJSON), nil
+}
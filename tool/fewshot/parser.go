@@ -0,0 +1,102 @@
+package fewshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+var xmlToolUseRegexp = regexp.MustCompile(`(?s)<tool_use>\s*<name>(.*?)</name>\s*<input>(.*?)</input>\s*</tool_use>`)
+
+type rawToolCall struct {
+	Name       string          `json:"name"`
+	Tool       string          `json:"tool"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Parameters json.RawMessage `json:"parameters"`
+	ToolInput  json.RawMessage `json:"tool_input"`
+	Function   *rawToolCall    `json:"function"`
+}
+
+// ParseToolCalls leniently extracts tool calls from a model text answer. It recognizes
+// JSON objects (optionally in code fences, arrays or <tool_call> tags) having a name and
+// arguments, OpenAI-like {"function": {...}} objects and <tool_use> XML blocks.
+func ParseToolCalls(text string) []thread.ToolCallData {
+	var toolCalls []thread.ToolCallData
+
+	for _, match := range xmlToolUseRegexp.FindAllStringSubmatch(text, -1) {
+		toolCalls = append(toolCalls, newToolCallData(len(toolCalls), strings.TrimSpace(match[1]), []byte(match[2])))
+	}
+	text = xmlToolUseRegexp.ReplaceAllString(text, "")
+
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' {
+			continue
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(text[i:]))
+		var raw rawToolCall
+		if err := decoder.Decode(&raw); err != nil {
+			continue
+		}
+
+		if toolCall, ok := raw.toToolCallData(len(toolCalls)); ok {
+			toolCalls = append(toolCalls, toolCall)
+			i += int(decoder.InputOffset()) - 1
+		}
+	}
+
+	return toolCalls
+}
+
+// ToolCallMessage converts an assistant text answer into a tool call message, if the
+// text contains any tool call.
+func ToolCallMessage(text string) (*thread.Message, bool) {
+	toolCalls := ParseToolCalls(text)
+	if len(toolCalls) == 0 {
+		return nil, false
+	}
+
+	return thread.NewAssistantMessage().AddContent(thread.NewToolCallContent(toolCalls)), true
+}
+
+func (r *rawToolCall) toToolCallData(index int) (thread.ToolCallData, bool) {
+	if r.Function != nil {
+		return r.Function.toToolCallData(index)
+	}
+
+	name := r.Name
+	if name == "" {
+		name = r.Tool
+	}
+
+	arguments := r.Arguments
+	if arguments == nil {
+		arguments = r.Parameters
+	}
+	if arguments == nil {
+		arguments = r.ToolInput
+	}
+
+	if name == "" || arguments == nil {
+		return thread.ToolCallData{}, false
+	}
+
+	return newToolCallData(index, name, arguments), true
+}
+
+func newToolCallData(index int, name string, arguments []byte) thread.ToolCallData {
+	// arguments may be a JSON encoded string, as in the OpenAI API
+	var argumentsAsString string
+	if err := json.Unmarshal(arguments, &argumentsAsString); err == nil {
+		arguments = []byte(argumentsAsString)
+	}
+
+	return thread.ToolCallData{
+		ID:        fmt.Sprintf("call_%d", index),
+		Name:      name,
+		Arguments: strings.TrimSpace(string(arguments)),
+	}
+}
@@ -0,0 +1,47 @@
+package fewshot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/henomis/lingoose/thread"
+)
+
+func TestParseToolCalls(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []thread.ToolCallData
+	}{
+		{
+			name: "Test 1",
+			text: "Sure!\n```json\n{\"name\": \"weather\", \"arguments\": {\"city\": \"Rome\"}}\n```",
+			want: []thread.ToolCallData{{ID: "call_0", Name: "weather", Arguments: `{"city": "Rome"}`}},
+		},
+		{
+			name: "Test 2",
+			text: `<tool_call>{"function": {"name": "python", "arguments": "{\"code\": \"1+1\"}"}}</tool_call>`,
+			want: []thread.ToolCallData{{ID: "call_0", Name: "python", Arguments: `{"code": "1+1"}`}},
+		},
+		{
+			name: "Test 3",
+			text: `<tool_use><name>search</name><input>{"q": "go"}</input></tool_use> and [{"tool": "llm", "tool_input": {}}]`,
+			want: []thread.ToolCallData{
+				{ID: "call_0", Name: "search", Arguments: `{"q": "go"}`},
+				{ID: "call_1", Name: "llm", Arguments: `{}`},
+			},
+		},
+		{
+			name: "Test 4",
+			text: `The answer is {"result": 42}`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseToolCalls(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseToolCalls() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
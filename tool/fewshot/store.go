@@ -0,0 +1,132 @@
+package fewshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/option"
+	"github.com/henomis/lingoose/thread"
+	"github.com/henomis/lingoose/types"
+)
+
+const (
+	// MetadataExample is the index metadata key holding the example as JSON.
+	MetadataExample = "fewshot_example"
+)
+
+var ErrExampleStore = errors.New("example store error")
+
+// Selector returns the k examples most relevant to the query.
+type Selector interface {
+	Select(ctx context.Context, query string, k int) ([]Example, error)
+}
+
+// Store keeps the examples in an index, embedding their query, so that large curated
+// example sets can be shared across services and updated without redeploying.
+type Store struct {
+	index *index.Index
+}
+
+func NewStore(index *index.Index) *Store {
+	return &Store{
+		index: index,
+	}
+}
+
+// Add stores the examples in the index.
+func (s *Store) Add(ctx context.Context, examples ...Example) error {
+	documents := make([]document.Document, 0, len(examples))
+	for _, example := range examples {
+		exampleAsJSON, err := json.Marshal(example)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrExampleStore, err)
+		}
+
+		documents = append(documents, document.Document{
+			Content: example.Query,
+			Metadata: types.Meta{
+				MetadataExample: string(exampleAsJSON),
+			},
+		})
+	}
+
+	err := s.index.LoadFromDocuments(ctx, documents)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrExampleStore, err)
+	}
+
+	return nil
+}
+
+// Select returns the k examples whose query is the most similar to the query.
+func (s *Store) Select(ctx context.Context, query string, k int) ([]Example, error) {
+	results, err := s.index.Query(ctx, query, option.WithTopK(k))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExampleStore, err)
+	}
+
+	examples := make([]Example, 0, len(results))
+	for _, result := range results {
+		exampleAsJSON, ok := result.Metadata[MetadataExample].(string)
+		if !ok {
+			continue
+		}
+
+		var example Example
+		err = json.Unmarshal([]byte(exampleAsJSON), &example)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrExampleStore, err)
+		}
+
+		examples = append(examples, example)
+	}
+
+	return examples, nil
+}
+
+// WithSelector adds to the prompt the topK examples of the selector most relevant to the
+// user query, after the static examples. Use PromptFor and InjectFor to select them.
+func (f *FewShot) WithSelector(selector Selector, topK int) *FewShot {
+	f.selector = selector
+	f.topK = topK
+	return f
+}
+
+// PromptFor returns the prompt with the examples selected for the query.
+func (f *FewShot) PromptFor(ctx context.Context, query string) (string, error) {
+	examples, err := f.selectExamples(ctx, query)
+	if err != nil {
+		return "", err
+	}
+
+	return f.prompt(examples)
+}
+
+// InjectFor injects the prompt with the examples selected for the user query of the thread.
+func (f *FewShot) InjectFor(ctx context.Context, t *thread.Thread) error {
+	prompt, err := f.PromptFor(ctx, strings.Join(t.UserQuery(), "\n"))
+	if err != nil {
+		return err
+	}
+
+	injectPrompt(t, prompt)
+	return nil
+}
+
+func (f *FewShot) selectExamples(ctx context.Context, query string) ([]Example, error) {
+	if f.selector == nil || query == "" {
+		return f.examples, nil
+	}
+
+	selected, err := f.selector.Select(ctx, query, f.topK)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]Example{}, f.examples...), selected...), nil
+}
@@ -0,0 +1,56 @@
+package fewshot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/henomis/lingoose/embedder"
+	"github.com/henomis/lingoose/index"
+	"github.com/henomis/lingoose/index/vectordb/jsondb"
+)
+
+// keywordEmbedder embeds the presence of a few keywords.
+type keywordEmbedder struct{}
+
+func (keywordEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	keywords := []string{"weather", "stock", "news"}
+
+	embeddings := make([]embedder.Embedding, len(texts))
+	for i, text := range texts {
+		embeddings[i] = make(embedder.Embedding, len(keywords)+1)
+		embeddings[i][len(keywords)] = 0.1
+		for j, keyword := range keywords {
+			if strings.Contains(strings.ToLower(text), keyword) {
+				embeddings[i][j] = 1
+			}
+		}
+	}
+
+	return embeddings, nil
+}
+
+func TestFewShot_PromptFor(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(index.New(jsondb.New(), keywordEmbedder{}))
+	err := store.Add(ctx,
+		Example{Query: "What's the weather in Rome?", ToolName: "weather", Arguments: map[string]any{"city": "Rome"}},
+		Example{Query: "What's the AAPL stock price?", ToolName: "stock", Arguments: map[string]any{"ticker": "AAPL"}},
+	)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	prompt, err := New(FormatJSON).WithSelector(store, 1).PromptFor(ctx, "weather in Paris tomorrow")
+	if err != nil {
+		t.Fatalf("PromptFor() error = %v", err)
+	}
+
+	if !strings.Contains(prompt, `{"name": "weather", "arguments": {"city":"Rome"}}`) {
+		t.Errorf("PromptFor() = %q, want the weather example", prompt)
+	}
+	if strings.Contains(prompt, "AAPL") {
+		t.Errorf("PromptFor() = %q, want only the most relevant example", prompt)
+	}
+}
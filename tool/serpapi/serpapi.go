@@ -3,6 +3,7 @@ package serpapi
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"time"
 
@@ -64,6 +65,12 @@ func (t *Tool) WithAPIKey(apiKey string) *Tool {
 	return t
 }
 
+// WithHTTPClient sets the http client to use for the tool
+func (t *Tool) WithHTTPClient(httpClient *http.Client) *Tool {
+	t.restClient.SetHTTPClient(httpClient)
+	return t
+}
+
 func (t *Tool) Name() string {
 	return "google"
 }
@@ -0,0 +1,143 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+)
+
+// detectDialect guesses the SQL dialect from the database driver type.
+func detectDialect(db *sql.DB) (Dialect, error) {
+	driverType := strings.ToLower(fmt.Sprintf("%T", db.Driver()))
+	switch {
+	case strings.Contains(driverType, "sqlite"):
+		return DialectSQLite, nil
+	case strings.Contains(driverType, "mysql"):
+		return DialectMySQL, nil
+	case strings.Contains(driverType, "pq."), strings.Contains(driverType, "pgx"), strings.Contains(driverType, "postgres"):
+		return DialectPostgres, nil
+	default:
+		return "", fmt.Errorf("unsupported database driver %s", driverType)
+	}
+}
+
+type column struct {
+	name     string
+	dataType string
+	nullable bool
+}
+
+// introspect returns the CREATE TABLE statements of the tables, all of them if tables is empty.
+func introspect(ctx context.Context, db *sql.DB, dialect Dialect, tables []string) (string, error) {
+	if dialect == DialectSQLite {
+		return sqliteSchema(ctx, db, tables)
+	}
+
+	var query string
+	switch dialect {
+	case DialectPostgres:
+		query = "SELECT table_name, column_name, data_type, is_nullable FROM information_schema.columns " +
+			"WHERE table_schema = current_schema() ORDER BY table_name, ordinal_position"
+	case DialectMySQL:
+		query = "SELECT table_name, column_name, column_type, is_nullable FROM information_schema.columns " +
+			"WHERE table_schema = DATABASE() ORDER BY table_name, ordinal_position"
+	default:
+		return "", fmt.Errorf("unsupported dialect %s", dialect)
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var tableNames []string
+	columns := make(map[string][]column)
+	for rows.Next() {
+		var tableName, nullable string
+		var c column
+		err = rows.Scan(&tableName, &c.name, &c.dataType, &nullable)
+		if err != nil {
+			return "", err
+		}
+		if !selected(tableName, tables) {
+			continue
+		}
+
+		c.nullable = nullable == "YES"
+		if _, ok := columns[tableName]; !ok {
+			tableNames = append(tableNames, tableName)
+		}
+		columns[tableName] = append(columns[tableName], c)
+	}
+	if err = rows.Err(); err != nil {
+		return "", err
+	}
+
+	var schema strings.Builder
+	for _, tableName := range tableNames {
+		schema.WriteString("CREATE TABLE " + tableName + " (\n")
+		for i, c := range columns[tableName] {
+			schema.WriteString("  " + c.name + " " + c.dataType)
+			if !c.nullable {
+				schema.WriteString(" NOT NULL")
+			}
+			if i < len(columns[tableName])-1 {
+				schema.WriteString(",")
+			}
+			schema.WriteString("\n")
+		}
+		schema.WriteString(");\n")
+	}
+
+	return schema.String(), nil
+}
+
+func sqliteSchema(ctx context.Context, db *sql.DB, tables []string) (string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name, sql FROM sqlite_schema WHERE type IN ('table', 'view') AND sql IS NOT NULL")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var schema strings.Builder
+	for rows.Next() {
+		var name, statement string
+		err = rows.Scan(&name, &statement)
+		if err != nil {
+			return "", err
+		}
+		if !selected(name, tables) {
+			continue
+		}
+		schema.WriteString(statement + ";\n")
+	}
+	if err = rows.Err(); err != nil {
+		return "", err
+	}
+
+	return schema.String(), nil
+}
+
+func selected(table string, tables []string) bool {
+	if len(tables) == 0 {
+		return true
+	}
+
+	for _, t := range tables {
+		if strings.EqualFold(t, table) {
+			return true
+		}
+	}
+
+	return false
+}
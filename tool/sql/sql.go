@@ -0,0 +1,351 @@
+// Package sql provides agent tools to explore a database safely: SchemaTool describes the
+// tables, Tool runs read-only parameterized queries returning a limited number of rows.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxRows = 50
+	defaultTimeout = 30 * time.Second
+)
+
+var (
+	ErrNotReadOnly = errors.New("only read-only queries are allowed")
+
+	readOnlyStatementRegexp = regexp.MustCompile(`(?i)^\s*(select|with|explain|show|describe|values)\b`)
+	writeKeywordRegexp      = regexp.MustCompile(
+		`(?i)\b(insert|update|delete|merge|upsert|create|alter|drop|truncate|grant|revoke|attach|detach|vacuum|copy|call|exec|execute)\b`,
+	)
+)
+
+// SchemaTool returns the schema of the database tables, as CREATE TABLE statements.
+type SchemaTool struct {
+	db      *sql.DB
+	dialect Dialect
+	tables  []string
+	timeout time.Duration
+}
+
+// NewSchemaTool creates the schema tool. The dialect is detected from the driver, use
+// WithDialect when the detection fails.
+func NewSchemaTool(db *sql.DB) *SchemaTool {
+	dialect, _ := detectDialect(db)
+
+	return &SchemaTool{
+		db:      db,
+		dialect: dialect,
+		timeout: defaultTimeout,
+	}
+}
+
+func (s *SchemaTool) WithDialect(dialect Dialect) *SchemaTool {
+	s.dialect = dialect
+	return s
+}
+
+// WithTables restricts the schema to the given tables.
+func (s *SchemaTool) WithTables(tables ...string) *SchemaTool {
+	s.tables = tables
+	return s
+}
+
+// Schema returns the schema of the tables, e.g. to add it to the system prompt.
+func (s *SchemaTool) Schema(ctx context.Context) (string, error) {
+	return introspect(ctx, s.db, s.dialect, s.tables)
+}
+
+type SchemaInput struct {
+	Tables []string `json:"tables,omitempty" jsonschema:"description=names of the tables to describe, all tables if empty"`
+}
+
+type SchemaOutput struct {
+	Error  string `json:"error,omitempty"`
+	Result string `json:"result,omitempty"`
+}
+
+func (s *SchemaTool) Name() string {
+	return "sql_schema"
+}
+
+func (s *SchemaTool) Description() string {
+	return "A tool that returns the schema of the " + string(s.dialect) + " database tables. " +
+		"Use it before writing a query to know the available tables and columns."
+}
+
+func (s *SchemaTool) Fn() any {
+	return s.fn
+}
+
+func (s *SchemaTool) fn(i SchemaInput) SchemaOutput {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	tables := s.tables
+	if len(i.Tables) > 0 {
+		tables = nil
+		for _, table := range i.Tables {
+			if selected(table, s.tables) {
+				tables = append(tables, table)
+			}
+		}
+		if len(tables) == 0 {
+			return SchemaOutput{Error: "none of the requested tables is available"}
+		}
+	}
+
+	schema, err := introspect(ctx, s.db, s.dialect, tables)
+	if err != nil {
+		return SchemaOutput{Error: fmt.Sprintf("failed to read the schema: %v", err)}
+	}
+
+	return SchemaOutput{Result: schema}
+}
+
+// Tool runs read-only SQL queries. Only single SELECT-like statements are accepted and
+// they are executed in a read-only transaction that is always rolled back; at most
+// maxRows rows are returned.
+type Tool struct {
+	db      *sql.DB
+	dialect Dialect
+	maxRows int
+	timeout time.Duration
+}
+
+func New(db *sql.DB) *Tool {
+	dialect, _ := detectDialect(db)
+
+	return &Tool{
+		db:      db,
+		dialect: dialect,
+		maxRows: defaultMaxRows,
+		timeout: defaultTimeout,
+	}
+}
+
+func (t *Tool) WithDialect(dialect Dialect) *Tool {
+	t.dialect = dialect
+	return t
+}
+
+// WithMaxRows sets the maximum number of rows returned, 50 by default.
+func (t *Tool) WithMaxRows(maxRows int) *Tool {
+	t.maxRows = maxRows
+	return t
+}
+
+// WithTimeout sets the query timeout, 30 seconds by default.
+func (t *Tool) WithTimeout(timeout time.Duration) *Tool {
+	t.timeout = timeout
+	return t
+}
+
+type Input struct {
+	Query string `json:"query" jsonschema:"description=read-only SQL query, use placeholders for the values"`
+	//nolint:lll
+	Parameters []string `json:"parameters,omitempty" jsonschema:"description=values of the query placeholders, in order"`
+}
+
+type Output struct {
+	Error     string   `json:"error,omitempty"`
+	Columns   []string `json:"columns,omitempty"`
+	Rows      [][]any  `json:"rows,omitempty"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+type FnPrototype = func(Input) Output
+
+func (t *Tool) Name() string {
+	return "sql_query"
+}
+
+func (t *Tool) Description() string {
+	return fmt.Sprintf(
+		"A tool that runs a read-only %s query and returns at most %d rows. "+
+			"Pass the values as parameters using placeholders instead of writing them in the query.",
+		t.dialect, t.maxRows,
+	)
+}
+
+func (t *Tool) Fn() any {
+	return t.fn
+}
+
+func (t *Tool) fn(i Input) Output {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	output, err := t.Query(ctx, i.Query, i.Parameters...)
+	if err != nil {
+		return Output{Error: err.Error()}
+	}
+
+	return *output
+}
+
+// Query runs the read-only query.
+func (t *Tool) Query(ctx context.Context, query string, parameters ...string) (*Output, error) {
+	err := checkReadOnly(query)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := t.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: t.dialect != DialectSQLite})
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	if t.dialect == DialectSQLite {
+		// SQLite has no read-only transactions: the connection refuses any write until
+		// the flag is reset, before the connection goes back to the pool
+		_, err = tx.ExecContext(ctx, "PRAGMA query_only = ON")
+		if err != nil {
+			return nil, err
+		}
+		//nolint:errcheck
+		defer tx.ExecContext(context.WithoutCancel(ctx), "PRAGMA query_only = OFF")
+	}
+
+	args := make([]any, 0, len(parameters))
+	for _, parameter := range parameters {
+		args = append(args, parameter)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	output := &Output{Columns: columns}
+	for rows.Next() {
+		if len(output.Rows) >= t.maxRows {
+			output.Truncated = true
+			break
+		}
+
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, value := range values {
+			if b, ok := value.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		output.Rows = append(output.Rows, values)
+	}
+
+	return output, rows.Err()
+}
+
+// checkReadOnly accepts a single statement starting with a read-only keyword and not
+// containing any write keyword outside string literals. Backslashes escape quotes in
+// MySQL strings but not in standard SQL ones, so the query must be read-only with both
+// interpretations.
+func checkReadOnly(query string) error {
+	for _, backslashEscapes := range []bool{false, true} {
+		err := checkReadOnlyStatement(query, backslashEscapes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkReadOnlyStatement(query string, backslashEscapes bool) error {
+	statement, err := stripLiterals(query, backslashEscapes)
+	if err != nil {
+		return err
+	}
+
+	statement = strings.TrimSpace(statement)
+	statement = strings.TrimSuffix(statement, ";")
+
+	if strings.Contains(statement, ";") {
+		return fmt.Errorf("%w: multiple statements", ErrNotReadOnly)
+	}
+
+	if !readOnlyStatementRegexp.MatchString(statement) {
+		return ErrNotReadOnly
+	}
+
+	if keyword := writeKeywordRegexp.FindString(statement); keyword != "" {
+		return fmt.Errorf("%w: %s not allowed", ErrNotReadOnly, strings.ToUpper(keyword))
+	}
+
+	return nil
+}
+
+// stripLiterals removes quoted strings and comments, so their content is not mistaken
+// for keywords. Unterminated strings and comments are rejected, as the part of the query
+// they hide can't be checked.
+func stripLiterals(query string, backslashEscapes bool) (string, error) {
+	var stripped strings.Builder
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := literalEnd(query[i+1:], c, backslashEscapes)
+			if end < 0 {
+				return "", fmt.Errorf("%w: unterminated string", ErrNotReadOnly)
+			}
+			stripped.WriteString("''")
+			i += end + 1
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return stripped.String(), nil
+			}
+			i += end - 1
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return "", fmt.Errorf("%w: unterminated comment", ErrNotReadOnly)
+			}
+			stripped.WriteByte(' ')
+			i += end + 3
+		default:
+			stripped.WriteByte(c)
+		}
+	}
+
+	return stripped.String(), nil
+}
+
+// literalEnd returns the index of the quote closing the literal, -1 if missing.
+func literalEnd(literal string, quote byte, backslashEscapes bool) int {
+	for i := 0; i < len(literal); i++ {
+		switch literal[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			return i
+		}
+	}
+
+	return -1
+}
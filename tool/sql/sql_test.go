@@ -0,0 +1,133 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+func Test_checkReadOnly(t *testing.T) {
+	tests := []struct {
+		query    string
+		readOnly bool
+	}{
+		{query: "SELECT name FROM users WHERE id = ?", readOnly: true},
+		{query: "  with t as (select 1) select * from t;", readOnly: true},
+		{query: "SELECT 'drop table users' AS note -- delete everything", readOnly: true},
+		{query: "SELECT \"update\" FROM /* insert */ logs", readOnly: true},
+		{query: "DELETE FROM users", readOnly: false},
+		{query: "SELECT 1; DROP TABLE users", readOnly: false},
+		{query: "WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d", readOnly: false},
+		{query: "SELECT * FROM users FOR UPDATE", readOnly: false},
+		{query: "SELECT 'unterminated", readOnly: false},
+		{query: "SELECT 'it''s' AS quote", readOnly: true},
+		{query: "SELECT 'C:\\\\' AS path", readOnly: true},
+		// unterminated when backslashes escape quotes
+		{query: "SELECT 'C:\\' AS path", readOnly: false},
+		// standard SQL: the backslash is a character and the string ends before the DELETE
+		{query: "SELECT 'a\\'; DELETE FROM users; --'", readOnly: false},
+		// MySQL: the backslash escapes the quote and the string ends before the DELETE
+		{query: "SELECT 'a\\'' ; DELETE FROM users", readOnly: false},
+		{query: "SELECT \"\\\"\"; DROP TABLE users; -- \"", readOnly: false},
+		{query: "SELECT 1 /* unterminated; DROP TABLE users", readOnly: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			err := checkReadOnly(tt.query)
+			if tt.readOnly && err != nil {
+				t.Errorf("checkReadOnly() error = %v", err)
+			}
+			if !tt.readOnly && !errors.Is(err, ErrNotReadOnly) {
+				t.Errorf("checkReadOnly() accepted %q", tt.query)
+			}
+		})
+	}
+}
+
+// sqliteDriver records the statements, its name makes the dialect detected as SQLite.
+type sqliteDriver struct {
+	statements []string
+}
+
+func (d *sqliteDriver) Open(string) (driver.Conn, error) {
+	return &sqliteConn{driver: d}, nil
+}
+
+type sqliteConn struct {
+	driver *sqliteDriver
+}
+
+func (c *sqliteConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *sqliteConn) Close() error {
+	return nil
+}
+
+func (c *sqliteConn) Begin() (driver.Tx, error) {
+	c.driver.statements = append(c.driver.statements, "BEGIN")
+	return c, nil
+}
+
+func (c *sqliteConn) Commit() error {
+	return nil
+}
+
+func (c *sqliteConn) Rollback() error {
+	c.driver.statements = append(c.driver.statements, "ROLLBACK")
+	return nil
+}
+
+func (c *sqliteConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.driver.statements = append(c.driver.statements, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *sqliteConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.driver.statements = append(c.driver.statements, query)
+	return &emptyRows{}, nil
+}
+
+type emptyRows struct{}
+
+func (r *emptyRows) Columns() []string {
+	return []string{"name"}
+}
+
+func (r *emptyRows) Close() error {
+	return nil
+}
+
+func (r *emptyRows) Next([]driver.Value) error {
+	return io.EOF
+}
+
+func TestQuerySQLiteQueryOnly(t *testing.T) {
+	d := &sqliteDriver{}
+	sql.Register("sqlite-test", d)
+	db, err := sql.Open("sqlite-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = New(db).Query(context.Background(), "SELECT name FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"BEGIN", "PRAGMA query_only = ON", "SELECT name FROM users", "PRAGMA query_only = OFF", "ROLLBACK"}
+	if len(d.statements) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, d.statements)
+	}
+	for i := range expected {
+		if d.statements[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, d.statements)
+		}
+	}
+}
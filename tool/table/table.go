@@ -0,0 +1,487 @@
+// Package table provides a dataframe-like agent tool to filter, aggregate and sort
+// tables, e.g. extracted from documents by loader.TableLoader. Numeric questions on
+// tables are answered far better by computing on the cells than by text retrieval.
+package table
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/henomis/lingoose/document"
+	"github.com/henomis/lingoose/loader"
+)
+
+const (
+	defaultMaxRows = 50
+	sampleRows     = 2
+)
+
+var (
+	ErrTable = errors.New("table error")
+
+	numberCleaner = regexp.MustCompile(`[^0-9eE+\-.]`)
+)
+
+type Table struct {
+	Name    string
+	Columns []string
+	Rows    [][]string
+}
+
+type Tool struct {
+	tables  []Table
+	maxRows int
+}
+
+func New() *Tool {
+	return &Tool{
+		maxRows: defaultMaxRows,
+	}
+}
+
+// WithMaxRows sets the maximum number of rows returned, 50 by default.
+func (t *Tool) WithMaxRows(maxRows int) *Tool {
+	t.maxRows = maxRows
+	return t
+}
+
+// AddTable adds a table. Rows shorter than the columns are padded with empty cells.
+func (t *Tool) AddTable(name string, columns []string, rows [][]string) *Tool {
+	table := Table{
+		Name:    name,
+		Columns: columns,
+	}
+	for _, row := range rows {
+		normalized := make([]string, len(columns))
+		copy(normalized, row)
+		table.Rows = append(table.Rows, normalized)
+	}
+
+	t.tables = append(t.tables, table)
+	return t
+}
+
+// AddCSV adds a table from CSV text, the first record is the header.
+func (t *Tool) AddCSV(name, csvText string) error {
+	r := csv.NewReader(strings.NewReader(csvText))
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTable, err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%w: empty table %s", ErrTable, name)
+	}
+
+	t.AddTable(name, records[0], records[1:])
+	return nil
+}
+
+// AddDocuments adds the tables of the documents returned by loader.TableLoader, named
+// after their source and position.
+func (t *Tool) AddDocuments(documents []document.Document) error {
+	for _, d := range documents {
+		csvText, ok := d.Metadata[loader.TableCSVMetadataKey].(string)
+		if !ok {
+			continue
+		}
+
+		source, _ := d.Metadata[loader.SourceMetadataKey].(string)
+		index, _ := d.Metadata[loader.TableIndexMetadataKey].(int)
+
+		err := t.AddCSV(fmt.Sprintf("%s#%d", source, index+1), csvText)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Tables returns the available tables.
+func (t *Tool) Tables() []Table {
+	return t.tables
+}
+
+// Schema describes the tables, with their columns and a few sample rows.
+func (t *Tool) Schema() string {
+	var sb strings.Builder
+	for _, table := range t.tables {
+		sb.WriteString(fmt.Sprintf("Table %q (%d rows), columns: %s\n",
+			table.Name, len(table.Rows), strings.Join(quote(table.Columns), ", ")))
+		for i := 0; i < len(table.Rows) && i < sampleRows; i++ {
+			sb.WriteString("  sample: " + strings.Join(quote(table.Rows[i]), ", ") + "\n")
+		}
+	}
+
+	return sb.String()
+}
+
+type Filter struct {
+	Column   string `json:"column"`
+	Operator string `json:"operator" jsonschema:"enum=eq,enum=ne,enum=gt,enum=gte,enum=lt,enum=lte,enum=contains"`
+	Value    string `json:"value"`
+}
+
+type Aggregation struct {
+	Function string `json:"function" jsonschema:"enum=count,enum=sum,enum=avg,enum=min,enum=max"`
+	Column   string `json:"column,omitempty" jsonschema:"description=column to aggregate, not needed for count"`
+}
+
+type Input struct {
+	Table   string   `json:"table" jsonschema:"description=name of the table"`
+	Columns []string `json:"columns,omitempty" jsonschema:"description=columns to return, all if empty"`
+	Filters []Filter `json:"filters,omitempty" jsonschema:"description=conditions the rows must all satisfy"`
+	//nolint:lll
+	GroupBy      string        `json:"group_by,omitempty" jsonschema:"description=column to group the rows by before aggregating"`
+	Aggregations []Aggregation `json:"aggregations,omitempty"`
+	OrderBy      string        `json:"order_by,omitempty" jsonschema:"description=result column to sort by"`
+	Descending   bool          `json:"descending,omitempty"`
+	Limit        int           `json:"limit,omitempty"`
+}
+
+type Output struct {
+	Error     string     `json:"error,omitempty"`
+	Columns   []string   `json:"columns,omitempty"`
+	Rows      [][]string `json:"rows,omitempty"`
+	Truncated bool       `json:"truncated,omitempty"`
+}
+
+type FnPrototype = func(Input) Output
+
+func (t *Tool) Name() string {
+	return "table_query"
+}
+
+func (t *Tool) Description() string {
+	return "A tool that queries tables like a dataframe: it filters the rows, groups and aggregates them " +
+		"(count, sum, avg, min, max), sorts and limits the result. Use it for any question about figures " +
+		"in tables. The available tables are:\n" + t.Schema()
+}
+
+func (t *Tool) Fn() any {
+	return t.fn
+}
+
+func (t *Tool) fn(i Input) Output {
+	output, err := t.Query(i)
+	if err != nil {
+		return Output{Error: err.Error()}
+	}
+
+	return *output
+}
+
+// Query runs the query on the table.
+func (t *Tool) Query(i Input) (*Output, error) {
+	table, err := t.table(i.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := filterRows(table, i.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var output *Output
+	if len(i.Aggregations) > 0 {
+		output, err = aggregate(table, rows, i.GroupBy, i.Aggregations)
+	} else {
+		output, err = project(table, rows, i.Columns)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if i.OrderBy != "" {
+		column := indexOf(output.Columns, i.OrderBy)
+		if column < 0 {
+			return nil, fmt.Errorf("%w: unknown order by column %q", ErrTable, i.OrderBy)
+		}
+		sort.SliceStable(output.Rows, func(a, b int) bool {
+			if i.Descending {
+				return less(output.Rows[b][column], output.Rows[a][column])
+			}
+			return less(output.Rows[a][column], output.Rows[b][column])
+		})
+	}
+
+	limit := t.maxRows
+	if i.Limit > 0 && i.Limit < limit {
+		limit = i.Limit
+	}
+	if len(output.Rows) > limit {
+		output.Rows = output.Rows[:limit]
+		output.Truncated = i.Limit <= 0 || i.Limit > t.maxRows
+	}
+
+	return output, nil
+}
+
+func (t *Tool) table(name string) (*Table, error) {
+	for i := range t.tables {
+		if t.tables[i].Name == name {
+			return &t.tables[i], nil
+		}
+	}
+
+	if name == "" && len(t.tables) == 1 {
+		return &t.tables[0], nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown table %q", ErrTable, name)
+}
+
+func filterRows(table *Table, filters []Filter) ([][]string, error) {
+	columns := make([]int, len(filters))
+	for i, filter := range filters {
+		columns[i] = indexOf(table.Columns, filter.Column)
+		if columns[i] < 0 {
+			return nil, fmt.Errorf("%w: unknown filter column %q", ErrTable, filter.Column)
+		}
+	}
+
+	var rows [][]string
+	for _, row := range table.Rows {
+		match := true
+		for i, filter := range filters {
+			ok, err := compare(row[columns[i]], filter.Operator, filter.Value)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				match = false
+				break
+			}
+		}
+		if match {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+func compare(cell, operator, value string) (bool, error) {
+	switch operator {
+	case "eq", "=", "==":
+		return equal(cell, value), nil
+	case "ne", "!=":
+		return !equal(cell, value), nil
+	case "gt", ">":
+		return less(value, cell), nil
+	case "gte", ">=":
+		return !less(cell, value), nil
+	case "lt", "<":
+		return less(cell, value), nil
+	case "lte", "<=":
+		return !less(value, cell), nil
+	case "contains":
+		return strings.Contains(strings.ToLower(cell), strings.ToLower(value)), nil
+	}
+
+	return false, fmt.Errorf("%w: unknown operator %q", ErrTable, operator)
+}
+
+func project(table *Table, rows [][]string, columns []string) (*Output, error) {
+	if len(columns) == 0 {
+		columns = table.Columns
+	}
+
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		indexes[i] = indexOf(table.Columns, column)
+		if indexes[i] < 0 {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrTable, column)
+		}
+	}
+
+	output := &Output{Columns: columns}
+	for _, row := range rows {
+		projected := make([]string, len(indexes))
+		for i, index := range indexes {
+			projected[i] = row[index]
+		}
+		output.Rows = append(output.Rows, projected)
+	}
+
+	return output, nil
+}
+
+func aggregate(table *Table, rows [][]string, groupBy string, aggregations []Aggregation) (*Output, error) {
+	groupColumn := -1
+	output := &Output{}
+	if groupBy != "" {
+		groupColumn = indexOf(table.Columns, groupBy)
+		if groupColumn < 0 {
+			return nil, fmt.Errorf("%w: unknown group by column %q", ErrTable, groupBy)
+		}
+		output.Columns = append(output.Columns, groupBy)
+	}
+
+	columns := make([]int, len(aggregations))
+	for i, aggregation := range aggregations {
+		columns[i] = -1
+		if aggregation.Column != "" {
+			columns[i] = indexOf(table.Columns, aggregation.Column)
+			if columns[i] < 0 {
+				return nil, fmt.Errorf("%w: unknown aggregation column %q", ErrTable, aggregation.Column)
+			}
+		} else if aggregation.Function != "count" {
+			return nil, fmt.Errorf("%w: %s needs a column", ErrTable, aggregation.Function)
+		}
+		output.Columns = append(output.Columns, fmt.Sprintf("%s(%s)", aggregation.Function, aggregation.Column))
+	}
+
+	var groups []string
+	groupRows := make(map[string][][]string)
+	for _, row := range rows {
+		key := ""
+		if groupColumn >= 0 {
+			key = row[groupColumn]
+		}
+		if _, ok := groupRows[key]; !ok {
+			groups = append(groups, key)
+		}
+		groupRows[key] = append(groupRows[key], row)
+	}
+	if groupColumn < 0 && len(groups) == 0 {
+		groups = []string{""}
+	}
+
+	for _, group := range groups {
+		var result []string
+		if groupColumn >= 0 {
+			result = append(result, group)
+		}
+
+		for i, aggregation := range aggregations {
+			value, err := aggregateColumn(groupRows[group], columns[i], aggregation.Function)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+
+		output.Rows = append(output.Rows, result)
+	}
+
+	return output, nil
+}
+
+func aggregateColumn(rows [][]string, column int, function string) (string, error) {
+	if function == "count" {
+		if column < 0 {
+			return strconv.Itoa(len(rows)), nil
+		}
+		count := 0
+		for _, row := range rows {
+			if strings.TrimSpace(row[column]) != "" {
+				count++
+			}
+		}
+		return strconv.Itoa(count), nil
+	}
+
+	var values []float64
+	for _, row := range rows {
+		if value, ok := parseNumber(row[column]); ok {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	var result float64
+	switch function {
+	case "sum", "avg":
+		for _, value := range values {
+			result += value
+		}
+		if function == "avg" {
+			result /= float64(len(values))
+		}
+	case "min":
+		result = math.Inf(1)
+		for _, value := range values {
+			result = math.Min(result, value)
+		}
+	case "max":
+		result = math.Inf(-1)
+		for _, value := range values {
+			result = math.Max(result, value)
+		}
+	default:
+		return "", fmt.Errorf("%w: unknown aggregation function %q", ErrTable, function)
+	}
+
+	return strconv.FormatFloat(result, 'f', -1, 64), nil
+}
+
+// parseNumber parses numbers formatted for humans, e.g. "$ 1,200.50" or "12%".
+func parseNumber(cell string) (float64, bool) {
+	cleaned := numberCleaner.ReplaceAllString(cell, "")
+	if cleaned == "" {
+		return 0, false
+	}
+
+	value, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(cell), "(") && strings.HasSuffix(strings.TrimSpace(cell), ")") {
+		// accounting notation for negative numbers
+		value = -value
+	}
+
+	return value, true
+}
+
+func equal(a, b string) bool {
+	aNumber, aOK := parseNumber(a)
+	bNumber, bOK := parseNumber(b)
+	if aOK && bOK {
+		return aNumber == bNumber
+	}
+
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+func less(a, b string) bool {
+	aNumber, aOK := parseNumber(a)
+	bNumber, bOK := parseNumber(b)
+	if aOK && bOK {
+		return aNumber < bNumber
+	}
+
+	return strings.ToLower(a) < strings.ToLower(b)
+}
+
+func indexOf(columns []string, column string) int {
+	for i, c := range columns {
+		if strings.EqualFold(c, column) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func quote(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+
+	return quoted
+}
@@ -0,0 +1,77 @@
+package table
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestTool() *Tool {
+	return New().AddTable("sales", []string{"Region", "Product", "Revenue"}, [][]string{
+		{"North", "A", "$ 1,200"},
+		{"South", "A", "800"},
+		{"North", "B", "(100)"},
+		{"South", "B", "2,000.50"},
+	})
+}
+
+func TestTool_Query(t *testing.T) {
+	tests := []struct {
+		name  string
+		input Input
+		want  [][]string
+	}{
+		{
+			name: "filter and project",
+			input: Input{
+				Table:   "sales",
+				Columns: []string{"product"},
+				Filters: []Filter{{Column: "Revenue", Operator: "gt", Value: "900"}},
+			},
+			want: [][]string{{"A"}, {"B"}},
+		},
+		{
+			name: "group and aggregate",
+			input: Input{
+				Table:        "sales",
+				GroupBy:      "Region",
+				Aggregations: []Aggregation{{Function: "sum", Column: "Revenue"}, {Function: "count"}},
+				OrderBy:      "sum(Revenue)",
+				Descending:   true,
+			},
+			want: [][]string{{"South", "2800.5", "2"}, {"North", "1100", "2"}},
+		},
+		{
+			name: "sort and limit",
+			input: Input{
+				Table:   "sales",
+				Columns: []string{"Region", "Revenue"},
+				OrderBy: "Revenue",
+				Limit:   1,
+			},
+			want: [][]string{{"North", "(100)"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := newTestTool().Query(tt.input)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if !reflect.DeepEqual(output.Rows, tt.want) {
+				t.Errorf("Query() rows = %v, want %v", output.Rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestTool_QueryErrors(t *testing.T) {
+	tool := newTestTool()
+
+	if output := tool.fn(Input{Table: "missing"}); output.Error == "" {
+		t.Error("expected an error for an unknown table")
+	}
+	if output := tool.fn(Input{Table: "sales", Filters: []Filter{{Column: "Revenue", Operator: "like"}}}); output.Error == "" {
+		t.Error("expected an error for an unknown operator")
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !lingoose_no_cohere
+
 package transformer
 
 import (
@@ -17,7 +19,6 @@ type CohereRerankModel = model.RerankModel
 const (
 	defaultCohereRerankMaxChunksPerDoc = 10
 	defaultCohereRerankTopN            = -1
-	CohereRerankScoreMetdataKey        = "cohere-rerank-score"
 
 	CohereRerankModelEnglishV20      CohereRerankModel = model.RerankModelEnglishV20
 	CohereRerankModelMultilingualV20 CohereRerankModel = model.RerankModelMultilingualV20
@@ -83,7 +83,7 @@ func (h *HFTextToImage) hfTextToImageHTTPCall(ctx context.Context, input string)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", hfBearerPrefix+h.token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := h.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
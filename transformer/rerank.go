@@ -0,0 +1,6 @@
+package transformer
+
+// CohereRerankScoreMetdataKey is the metadata key of the relevance scores set by
+// CohereRerank. It's defined even when CohereRerank is left out by the lingoose_no_cohere
+// build tag, for the code reading the scores of any reranker.
+const CohereRerankScoreMetdataKey = "cohere-rerank-score"
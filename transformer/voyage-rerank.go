@@ -42,6 +42,12 @@ func (v *VoyageRerank) WithModel(model string) *VoyageRerank {
 	return v
 }
 
+// WithHTTPClient sets the http client to use for the reranker
+func (v *VoyageRerank) WithHTTPClient(httpClient *http.Client) *VoyageRerank {
+	v.restClient.SetHTTPClient(httpClient)
+	return v
+}
+
 func (v *VoyageRerank) Rerank(
 	ctx context.Context,
 	query string,
@@ -0,0 +1,309 @@
+// Package vcr provides an HTTP transport recording the provider responses to fixture
+// files and replaying them, for deterministic integration tests running without API keys.
+package vcr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// ModeEnv is the environment variable setting the default mode: "record", "replay"
+	// or "auto".
+	ModeEnv = "LINGOOSE_VCR_MODE"
+
+	fixtureExtension = ".json"
+	keyLength        = 16
+	redactedValue    = "REDACTED"
+)
+
+var sensitiveParams = map[string]bool{
+	"key":          true,
+	"api_key":      true,
+	"apikey":       true,
+	"access_token": true,
+	"token":        true,
+}
+
+var (
+	ErrFixtureNotFound = errors.New("vcr fixture not found")
+	ErrVCR             = errors.New("vcr error")
+)
+
+type Mode string
+
+const (
+	// ModeAuto replays the recorded responses and records the missing ones.
+	ModeAuto Mode = "auto"
+	// ModeRecord sends every request and records the responses, overwriting the fixtures.
+	ModeRecord Mode = "record"
+	// ModeReplay only replays the recorded responses, missing fixtures are errors. Use it
+	// in CI so that tests never reach the providers.
+	ModeReplay Mode = "replay"
+)
+
+// Fixture is a recorded exchange. Request headers are not recorded and the credentials
+// passed in the query string are redacted, so API keys don't end up in the fixtures.
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+type FixtureRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// FixtureResponse holds the body as text, or base64 encoded in BinaryBody when it is
+// not valid UTF-8.
+type FixtureResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BinaryBody []byte      `json:"binary_body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper keyed by a hash of the request method, URL and body.
+// Identical requests sent more than once in a test, e.g. retries, get their own
+// fixtures, replayed in the same order.
+type Recorder struct {
+	dir   string
+	mode  Mode
+	base  http.RoundTripper
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// New returns a recorder storing the fixtures in dir. The mode is read from the
+// LINGOOSE_VCR_MODE environment variable, ModeAuto if not set.
+func New(dir string) *Recorder {
+	mode := Mode(os.Getenv(ModeEnv))
+	if mode != ModeRecord && mode != ModeReplay {
+		mode = ModeAuto
+	}
+
+	return &Recorder{
+		dir:   dir,
+		mode:  mode,
+		base:  http.DefaultTransport,
+		calls: make(map[string]int),
+	}
+}
+
+func (r *Recorder) WithMode(mode Mode) *Recorder {
+	r.mode = mode
+	return r
+}
+
+// WithTransport sets the transport sending the recorded requests, http.DefaultTransport
+// by default.
+func (r *Recorder) WithTransport(base http.RoundTripper) *Recorder {
+	r.base = base
+	return r
+}
+
+// Client returns an HTTP client using the recorder, to be passed to WithHTTPClient.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrVCR, err)
+		}
+	}
+
+	path := r.fixturePath(req, body)
+
+	if r.mode != ModeRecord {
+		fixture, err := readFixture(path)
+		if err == nil {
+			return fixture.Response.toHTTPResponse(req), nil
+		}
+		if !errors.Is(err, ErrFixtureNotFound) || r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", err, req.Method, fixtureURL(req.URL))
+		}
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrVCR, err)
+	}
+
+	fixture := &Fixture{
+		Request: FixtureRequest{
+			Method: req.Method,
+			URL:    fixtureURL(req.URL),
+			Body:   string(body),
+		},
+		Response: newFixtureResponse(resp, respBody),
+	}
+
+	err = writeFixture(path, fixture)
+	if err != nil {
+		return nil, err
+	}
+
+	return fixture.Response.toHTTPResponse(req), nil
+}
+
+// fixturePath returns the path of the fixture of the request, numbering the repeated
+// requests.
+func (r *Recorder) fixturePath(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + fixtureURL(req.URL) + "\n"))
+	hash.Write(canonicalBody(body))
+	key := hex.EncodeToString(hash.Sum(nil))[:keyLength]
+
+	r.mu.Lock()
+	call := r.calls[key]
+	r.calls[key]++
+	r.mu.Unlock()
+
+	if call > 0 {
+		key = fmt.Sprintf("%s-%d", key, call)
+	}
+
+	return filepath.Join(r.dir, key+fixtureExtension)
+}
+
+// canonicalBody re-encodes JSON bodies, sorting the object keys, so that the key doesn't
+// depend on the order of the fields.
+func canonicalBody(body []byte) []byte {
+	var value any
+	if json.Unmarshal(body, &value) != nil {
+		return body
+	}
+
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+
+	return canonical
+}
+
+func readFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFixtureNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrVCR, err)
+	}
+
+	var fixture Fixture
+	err = json.Unmarshal(data, &fixture)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrVCR, path, err)
+	}
+
+	return &fixture, nil
+}
+
+func writeFixture(path string, fixture *Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrVCR, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrVCR, err)
+	}
+
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrVCR, err)
+	}
+
+	return nil
+}
+
+func newFixtureResponse(resp *http.Response, body []byte) FixtureResponse {
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	header.Del("Content-Length")
+
+	fixtureResponse := FixtureResponse{
+		StatusCode: resp.StatusCode,
+		Header:     header,
+	}
+	if utf8.Valid(body) {
+		fixtureResponse.Body = string(body)
+	} else {
+		fixtureResponse.BinaryBody = body
+	}
+
+	return fixtureResponse
+}
+
+func (f FixtureResponse) toHTTPResponse(req *http.Request) *http.Response {
+	body := f.BinaryBody
+	if body == nil {
+		body = []byte(f.Body)
+	}
+
+	header := f.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode)),
+		StatusCode:    f.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// fixtureURL returns the URL without the values of the query parameters carrying
+// credentials, so that the fixtures don't depend on them nor store them.
+func fixtureURL(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for name := range query {
+		if sensitiveParams[strings.ToLower(name)] {
+			query.Set(name, redactedValue)
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return u.String()
+	}
+
+	clean := *u
+	clean.RawQuery = query.Encode()
+
+	return clean.String()
+}
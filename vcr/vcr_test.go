@@ -0,0 +1,152 @@
+package vcr
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newTestServer(t *testing.T, hits *int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(hits, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = w.Write([]byte(`{"echo":` + string(body) + `,"hit":` + strconv.Itoa(int(n)) + `}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func post(t *testing.T, client *http.Client, url, body string) (string, error) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer sk-secret")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data), nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+	dir := t.TempDir()
+
+	recorder := New(dir).WithMode(ModeRecord)
+	recorded, err := post(t, recorder.Client(), server.URL+"/chat?key=secret", `{"a":1,"b":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keys in a different order hit the same fixture
+	replayer := New(dir).WithMode(ModeReplay)
+	replayed, err := post(t, replayer.Client(), server.URL+"/chat?key=other", `{"b":2,"a":1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replayed != recorded {
+		t.Errorf("replayed %q, recorded %q", replayed, recorded)
+	}
+	if hits := atomic.LoadInt32(&hits); hits != 1 {
+		t.Errorf("server hit %d times, want 1", hits)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("fixtures = %v, %v", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"sk-secret", "key=secret", "session=secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("fixture contains %q:\n%s", secret, data)
+		}
+	}
+}
+
+func TestReplayMissingFixture(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+
+	_, err := post(t, New(t.TempDir()).WithMode(ModeReplay).Client(), server.URL, `{}`)
+	if !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("error = %v, want %v", err, ErrFixtureNotFound)
+	}
+	if hits := atomic.LoadInt32(&hits); hits != 0 {
+		t.Errorf("server hit %d times, want 0", hits)
+	}
+}
+
+func TestAutoModeRepeatedRequests(t *testing.T) {
+	var hits int32
+	server := newTestServer(t, &hits)
+	dir := t.TempDir()
+
+	var recorded []string
+	recorder := New(dir).WithMode(ModeAuto)
+	for i := 0; i < 2; i++ {
+		body, err := post(t, recorder.Client(), server.URL, `{"q":"same"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorded = append(recorded, body)
+	}
+
+	if recorded[0] == recorded[1] {
+		t.Fatalf("repeated requests recorded the same response %q", recorded[0])
+	}
+
+	replayer := New(dir).WithMode(ModeAuto)
+	for i := 0; i < 2; i++ {
+		body, err := post(t, replayer.Client(), server.URL, `{"q":"same"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body != recorded[i] {
+			t.Errorf("replay %d = %q, want %q", i, body, recorded[i])
+		}
+	}
+
+	if hits := atomic.LoadInt32(&hits); hits != 2 {
+		t.Errorf("server hit %d times, want 2", hits)
+	}
+}
+
+func TestModeFromEnv(t *testing.T) {
+	t.Setenv(ModeEnv, string(ModeReplay))
+	if mode := New(t.TempDir()).mode; mode != ModeReplay {
+		t.Errorf("mode = %q, want %q", mode, ModeReplay)
+	}
+
+	t.Setenv(ModeEnv, "")
+	if mode := New(t.TempDir()).mode; mode != ModeAuto {
+		t.Errorf("mode = %q, want %q", mode, ModeAuto)
+	}
+}